@@ -60,8 +60,80 @@ func BearerFromContext(ctx context.Context) (result string, err error) {
 	return
 }
 
+// Subject contains the identity of the caller of a request, extracted from the claims of the
+// authentication token.
+type Subject struct {
+	// ID is the value of the `sub` claim.
+	ID string
+
+	// Username is the value of the `username` claim, or of the `preferred_username` claim if
+	// the first doesn't exist.
+	Username string
+
+	// AccountID is the value of the `account_id` claim, if it exists.
+	AccountID string
+}
+
+// ContextWithSubject creates a new context containing the given subject. The authentication
+// handler calls this automatically, but it can also be used by other authentication middleware so
+// that handlers can use SubjectFromContext regardless of how the request was authenticated.
+func ContextWithSubject(parent context.Context, subject *Subject) context.Context {
+	return context.WithValue(parent, subjectKeyValue, subject)
+}
+
+// SubjectFromContext extracts the subject that sent the request from the context. If the context
+// doesn't contain a subject but contains a token then the subject will be calculated from the
+// claims of the token. If there is neither a subject nor a token then the result will be nil.
+func SubjectFromContext(ctx context.Context) (result *Subject, err error) {
+	switch subject := ctx.Value(subjectKeyValue).(type) {
+	case nil:
+	case *Subject:
+		result = subject
+		return
+	default:
+		err = fmt.Errorf(
+			"expected a subject in the '%s' context value, but got '%T'",
+			subjectKeyValue, subject,
+		)
+		return
+	}
+	token, err := TokenFromContext(ctx)
+	if err != nil || token == nil {
+		return
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		err = fmt.Errorf(
+			"expected map claims in the token, but got '%T'",
+			token.Claims,
+		)
+		return
+	}
+	result = subjectFromClaims(claims)
+	return
+}
+
+// subjectFromClaims creates a subject from the given token claims. Claims that don't exist or
+// that aren't strings are ignored.
+func subjectFromClaims(claims jwt.MapClaims) *Subject {
+	result := &Subject{}
+	result.ID, _ = claims["sub"].(string)
+	result.Username, _ = claims["username"].(string)
+	if result.Username == "" {
+		result.Username, _ = claims["preferred_username"].(string)
+	}
+	result.AccountID, _ = claims["account_id"].(string)
+	return result
+}
+
 // tokenKeyType is the type of the key used to store the token in the context.
 type tokenKeyType string
 
 // tokenKeyValue is the key used to store the token in the context:
 const tokenKeyValue tokenKeyType = "token"
+
+// subjectKeyType is the type of the key used to store the subject in the context.
+type subjectKeyType string
+
+// subjectKeyValue is the key used to store the subject in the context:
+const subjectKeyValue subjectKeyType = "subject"
@@ -22,6 +22,8 @@ package authentication
 import (
 	"context"
 
+	"github.com/golang-jwt/jwt/v4"
+
 	. "github.com/onsi/ginkgo/v2/dsl/core"             // nolint
 	. "github.com/onsi/gomega"                         // nolint
 	. "github.com/openshift-online/ocm-sdk-go/testing" // nolint
@@ -70,3 +72,58 @@ var _ = Describe("Get bearer from context", func() {
 		Expect(extracted).To(BeEmpty())
 	})
 })
+
+var _ = Describe("Get subject from context", func() {
+	It("Succeeds if there is a subject", func() {
+		subject := &Subject{
+			ID:       "123",
+			Username: "myuser",
+		}
+		ctx := ContextWithSubject(context.TODO(), subject)
+		extracted, err := SubjectFromContext(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(extracted).To(BeIdenticalTo(subject))
+	})
+
+	It("Calculates the subject from the token", func() {
+		token := MakeTokenObject(jwt.MapClaims{
+			"sub":      "123",
+			"username": "myuser",
+		})
+		ctx := ContextWithToken(context.TODO(), token)
+		extracted, err := SubjectFromContext(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(extracted).ToNot(BeNil())
+		Expect(extracted.ID).To(Equal("123"))
+		Expect(extracted.Username).To(Equal("myuser"))
+		Expect(extracted.AccountID).To(BeEmpty())
+	})
+
+	It("Prefers the subject to the token", func() {
+		token := MakeTokenObject(jwt.MapClaims{
+			"sub": "123",
+		})
+		subject := &Subject{
+			ID: "456",
+		}
+		ctx := ContextWithToken(context.TODO(), token)
+		ctx = ContextWithSubject(ctx, subject)
+		extracted, err := SubjectFromContext(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(extracted.ID).To(Equal("456"))
+	})
+
+	It("Succeeds if there is no subject or token", func() {
+		extracted, err := SubjectFromContext(context.TODO())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(extracted).To(BeNil())
+	})
+
+	It("Fails if the value has the wrong type", func() {
+		ctx := context.WithValue(context.TODO(), subjectKeyValue, "junk")
+		extracted, err := SubjectFromContext(ctx)
+		Expect(err).To(HaveOccurred())
+		Expect(extracted).To(BeNil())
+		Expect(err.Error()).To(ContainSubstring("string"))
+	})
+})
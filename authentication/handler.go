@@ -503,8 +503,9 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Add the token to the context:
+	// Add the token and the subject to the context:
 	ctx = ContextWithToken(ctx, token.object)
+	ctx = ContextWithSubject(ctx, subjectFromClaims(claims))
 	r = r.WithContext(ctx)
 
 	// Call the next handler:
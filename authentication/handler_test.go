@@ -747,6 +747,45 @@ var _ = Describe("Handler", func() {
 		Expect(recorder.Code).To(Equal(http.StatusOK))
 	})
 
+	It("Adds subject to the request context", func() {
+		// Prepare the token:
+		token := MakeTokenObject(jwt.MapClaims{
+			"typ":                "Bearer",
+			"sub":                "f:b3f7b485-7184-43c8-8169-37bd6d1fe4aa:myuser",
+			"preferred_username": "myuser",
+			"account_id":         "123",
+		})
+		bearer := token.Raw
+
+		// Prepare the next handler:
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			subject, err := SubjectFromContext(r.Context())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(subject).ToNot(BeNil())
+			Expect(subject.ID).To(Equal("f:b3f7b485-7184-43c8-8169-37bd6d1fe4aa:myuser"))
+			Expect(subject.Username).To(Equal("myuser"))
+			Expect(subject.AccountID).To(Equal("123"))
+			w.WriteHeader(http.StatusOK)
+		})
+
+		// Prepare the handler:
+		handler, err := NewHandler().
+			Logger(logger).
+			KeysFile(keysFile).
+			Next(next).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Send the request:
+		request := httptest.NewRequest(http.MethodGet, "/api/clusters_mgmt/v1/private", nil)
+		request.Header.Set("Authorization", "Bearer "+bearer)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		// Verify the response:
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+	})
+
 	It("Doesn't require authorization header for public URL", func() {
 		// Prepare the next handler:
 		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
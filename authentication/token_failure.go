@@ -0,0 +1,112 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the functions used to classify the failures to obtain tokens, so that they
+// can be reported in the metrics.
+
+package authentication
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Values of the `reason` label of the token acquisition failure metric:
+const (
+	// tokenFailureNetwork is used when it wasn't possible to send the request to the token
+	// server or to receive the response, for example when the connection can't be opened.
+	tokenFailureNetwork = "network"
+
+	// tokenFailureInvalidGrant is used when the token server rejected the grant, for example
+	// because the client secret is wrong or because the refresh token has been revoked.
+	tokenFailureInvalidGrant = "invalid_grant"
+
+	// tokenFailureExpiredRefresh is used when the refresh token is expired and there are no
+	// credentials to request a new one.
+	tokenFailureExpiredRefresh = "expired_refresh"
+
+	// tokenFailureServer is used when the token server responded with a 5xx status code.
+	tokenFailureServer = "server"
+
+	// tokenFailureOther is used for the rest of the failures, for example when the response
+	// of the token server can't be parsed.
+	tokenFailureOther = "other"
+)
+
+// TokenExpiredError is the error returned when the access and refresh tokens are unavailable or
+// expired and there are no credentials to request new ones. In that situation requests fail before
+// being sent to the server, instead of being sent and rejected with 401 responses. Use errors.As to
+// check for it.
+type TokenExpiredError struct {
+	// Expiry is the time when the access token expired. It will be zero if there is no access
+	// token.
+	Expiry time.Time
+}
+
+// Make sure that we implement the interface:
+var _ error = (*TokenExpiredError)(nil)
+
+// Error is the implementation of the error interface.
+func (e *TokenExpiredError) Error() string {
+	if e.Expiry.IsZero() {
+		return "access and refresh tokens are unavailable or expired, and there are no " +
+			"password or client secret to request new ones"
+	}
+	return fmt.Sprintf(
+		"access token expired at %s, and there are no refresh token, password or client "+
+			"secret to request a new one",
+		e.Expiry.UTC().Format(time.RFC3339),
+	)
+}
+
+// tokenResponseError is the error returned when the token server responds with an OAuth error,
+// like `invalid_grant`.
+type tokenResponseError struct {
+	code        string
+	description string
+}
+
+// Error is the implementation of the error interface.
+func (e *tokenResponseError) Error() string {
+	if e.description != "" {
+		return fmt.Sprintf("%s: %s", e.code, e.description)
+	}
+	return e.code
+}
+
+// tokenFailureReason calculates the value of the `reason` label for the given token request
+// status code and error.
+func tokenFailureReason(code int, err error) string {
+	var expiredErr *TokenExpiredError
+	if errors.As(err, &expiredErr) {
+		return tokenFailureExpiredRefresh
+	}
+	var responseErr *tokenResponseError
+	if errors.As(err, &responseErr) && responseErr.code == "invalid_grant" {
+		return tokenFailureInvalidGrant
+	}
+	if code >= http.StatusInternalServerError {
+		return tokenFailureServer
+	}
+	var urlErr *url.Error
+	if code == 0 && errors.As(err, &urlErr) {
+		return tokenFailureNetwork
+	}
+	return tokenFailureOther
+}
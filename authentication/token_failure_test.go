@@ -0,0 +1,213 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains tests for the metric that counts the failures to obtain tokens.
+
+package authentication
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"             // nolint
+	. "github.com/onsi/gomega"                         // nolint
+	. "github.com/onsi/gomega/ghttp"                   // nolint
+	. "github.com/openshift-online/ocm-sdk-go/testing" // nolint
+)
+
+var _ = Describe("Token acquisition failures", func() {
+	var (
+		ctx           context.Context
+		server        *Server
+		ca            string
+		metricsServer *MetricsServer
+	)
+
+	BeforeEach(func() {
+		// Create the context:
+		ctx = context.Background()
+
+		// Create the servers:
+		server, ca = MakeTCPTLSServer()
+		metricsServer = NewMetricsServer()
+	})
+
+	AfterEach(func() {
+		// Stop the servers:
+		server.Close()
+		metricsServer.Close()
+
+		// Remove the temporary CA file:
+		err := os.Remove(ca)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Counts invalid grants", func() {
+		// Configure the server:
+		server.AppendHandlers(
+			CombineHandlers(
+				VerifyClientCredentialsGrant("myclient", "badsecret"),
+				RespondWithTokenError("invalid_grant", "Bad secret"),
+			),
+		)
+
+		// Create the wrapper:
+		wrapper, err := NewTransportWrapper().
+			Logger(logger).
+			TokenURL(server.URL()).
+			TrustedCA(ca).
+			Client("myclient", "badsecret").
+			MetricsSubsystem("my").
+			MetricsRegisterer(metricsServer.Registry()).
+			Build(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err = wrapper.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+
+		// Get the tokens:
+		_, _, err = wrapper.Tokens(ctx)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("invalid_grant: Bad secret"))
+
+		// Verify the metrics:
+		metrics := metricsServer.Metrics()
+		Expect(metrics).To(MatchLine(
+			`^my_token_acquisition_failure_total\{reason="invalid_grant"\} 1$`,
+		))
+	})
+
+	It("Counts expired refresh tokens", func() {
+		// Create the wrapper:
+		refreshToken := MakeTokenString("Refresh", -5*time.Second)
+		wrapper, err := NewTransportWrapper().
+			Logger(logger).
+			TokenURL(server.URL()).
+			TrustedCA(ca).
+			Tokens(refreshToken).
+			MetricsSubsystem("my").
+			MetricsRegisterer(metricsServer.Registry()).
+			Build(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err = wrapper.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+
+		// Get the tokens:
+		_, _, err = wrapper.Tokens(ctx)
+		Expect(err).To(HaveOccurred())
+
+		// Verify the metrics:
+		metrics := metricsServer.Metrics()
+		Expect(metrics).To(MatchLine(
+			`^my_token_acquisition_failure_total\{reason="expired_refresh"\} 1$`,
+		))
+	})
+
+	It("Counts network failures", func() {
+		// Stop the server so that connections are rejected:
+		tokenURL := server.URL()
+		server.Close()
+
+		// Create the wrapper:
+		wrapper, err := NewTransportWrapper().
+			Logger(logger).
+			TokenURL(tokenURL).
+			TrustedCA(ca).
+			Client("myclient", "mysecret").
+			MetricsSubsystem("my").
+			MetricsRegisterer(metricsServer.Registry()).
+			Build(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err = wrapper.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+
+		// Get the tokens:
+		_, _, err = wrapper.Tokens(ctx)
+		Expect(err).To(HaveOccurred())
+
+		// Verify the metrics:
+		metrics := metricsServer.Metrics()
+		Expect(metrics).To(MatchLine(
+			`^my_token_acquisition_failure_total\{reason="network"\} 1$`,
+		))
+	})
+
+	It("Counts server failures once after the retries", func() {
+		// Configure the server so that it always fails:
+		server.SetAllowUnhandledRequests(true)
+		server.SetUnhandledRequestStatusCode(http.StatusServiceUnavailable)
+
+		// Create the wrapper:
+		wrapper, err := NewTransportWrapper().
+			Logger(logger).
+			TokenURL(server.URL()).
+			TrustedCA(ca).
+			Client("myclient", "mysecret").
+			MetricsSubsystem("my").
+			MetricsRegisterer(metricsServer.Registry()).
+			Build(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err = wrapper.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+
+		// Get the tokens, with a short timeout so that the retries don't take too long:
+		timeoutCtx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		_, _, err = wrapper.Tokens(timeoutCtx)
+		Expect(err).To(HaveOccurred())
+
+		// Verify the metrics:
+		metrics := metricsServer.Metrics()
+		Expect(metrics).To(MatchLine(
+			`^my_token_acquisition_failure_total\{reason="server"\} 1$`,
+		))
+	})
+
+	It("Doesn't count successful requests", func() {
+		// Create the wrapper:
+		accessToken := MakeTokenString("Bearer", 5*time.Minute)
+		wrapper, err := NewTransportWrapper().
+			Logger(logger).
+			TokenURL(server.URL()).
+			TrustedCA(ca).
+			Tokens(accessToken).
+			MetricsSubsystem("my").
+			MetricsRegisterer(metricsServer.Registry()).
+			Build(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err = wrapper.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+
+		// Get the tokens:
+		_, _, err = wrapper.Tokens(ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		// Verify the metrics:
+		metrics := metricsServer.Metrics()
+		Expect(metrics).ToNot(MatchLine(`^my_token_acquisition_failure_total.*$`))
+	})
+})
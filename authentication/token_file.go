@@ -0,0 +1,101 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the methods that load the access token from a file and reload it when the
+// file changes.
+
+package authentication
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// loadTokenFile reads the token file and replaces the current access token with its content. It
+// returns an error if the file can't be read or if it is empty.
+func (w *TransportWrapper) loadTokenFile(ctx context.Context) error {
+	info, err := os.Stat(w.tokenFile)
+	if err != nil {
+		return fmt.Errorf("can't check token file '%s': %w", w.tokenFile, err)
+	}
+	data, err := os.ReadFile(w.tokenFile)
+	if err != nil {
+		return fmt.Errorf("can't read token file '%s': %w", w.tokenFile, err)
+	}
+	text := strings.TrimSpace(string(data))
+	if text == "" {
+		return fmt.Errorf("token file '%s' is empty", w.tokenFile)
+	}
+
+	// The content of the file can be a JSON web token, a pull secret access token or an opaque
+	// token. Opaque tokens are assumed to never expire.
+	token := &tokenInfo{
+		text: text,
+	}
+	object, _, err := w.tokenParser.ParseUnverified(text, jwt.MapClaims{})
+	if err == nil {
+		token.object = object
+	}
+	if token.object == nil && parsePullSecretAccessToken(text) == nil {
+		w.accessToken = nil
+		w.pullSecretAccessToken = token
+	} else {
+		w.accessToken = token
+		w.pullSecretAccessToken = nil
+	}
+
+	// Remember the modification time and size, so that we can later detect changes:
+	w.tokenFileTime = info.ModTime()
+	w.tokenFileSize = info.Size()
+	w.logger.Debug(ctx, "Loaded token from file '%s'", w.tokenFile)
+
+	return nil
+}
+
+// reloadTokenFile checks if the token file has changed since it was last loaded and loads it again
+// if needed. Errors are written to the log and otherwise ignored, so that the last good token will
+// still be used. Note that this must be called with the token mutex locked.
+func (w *TransportWrapper) reloadTokenFile(ctx context.Context) {
+	info, err := os.Stat(w.tokenFile)
+	if err != nil {
+		w.logger.Warn(
+			ctx,
+			"Can't check token file '%s', will use the last good token: %v",
+			w.tokenFile, err,
+		)
+		return
+	}
+	if info.ModTime().Equal(w.tokenFileTime) && info.Size() == w.tokenFileSize {
+		return
+	}
+	err = w.loadTokenFile(ctx)
+	if err != nil {
+		w.logger.Warn(
+			ctx,
+			"Can't reload token file, will use the last good token: %v",
+			err,
+		)
+
+		// Remember the modification time and size anyhow, so that we don't try again, and
+		// write the warning again, till the file changes again:
+		w.tokenFileTime = info.ModTime()
+		w.tokenFileSize = info.Size()
+	}
+}
@@ -0,0 +1,142 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains tests for the loading of tokens from files.
+
+package authentication
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"             // nolint
+	. "github.com/onsi/gomega"                         // nolint
+	. "github.com/openshift-online/ocm-sdk-go/testing" // nolint
+)
+
+var _ = Describe("Token file", func() {
+	var ctx context.Context
+	var dir string
+	var file string
+	var writes int
+
+	// Write writes the given text to the token file, making sure that the modification time
+	// changes even if the file system has low resolution timestamps.
+	var Write = func(text string) {
+		err := os.WriteFile(file, []byte(text), 0600)
+		Expect(err).ToNot(HaveOccurred())
+		writes++
+		mtime := time.Now().Add(time.Duration(writes) * time.Second)
+		err = os.Chtimes(file, mtime, mtime)
+		Expect(err).ToNot(HaveOccurred())
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		dir = GinkgoT().TempDir()
+		file = filepath.Join(dir, "token")
+	})
+
+	It("Loads the token from the file", func() {
+		token := MakeTokenString("Bearer", 5*time.Minute)
+		Write(token + "\n")
+		wrapper, err := NewTransportWrapper().
+			Logger(logger).
+			TokenFile(file).
+			Build(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err = wrapper.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+		access, _, err := wrapper.Tokens(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(access).To(Equal(token))
+	})
+
+	It("Reloads the token when the file changes", func() {
+		first := MakeTokenString("Bearer", 5*time.Minute)
+		Write(first)
+		wrapper, err := NewTransportWrapper().
+			Logger(logger).
+			TokenFile(file).
+			Build(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err = wrapper.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+		access, _, err := wrapper.Tokens(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(access).To(Equal(first))
+
+		// Replace the token and check that the new one is used:
+		second := MakeTokenString("Bearer", 10*time.Minute)
+		Write(second)
+		access, _, err = wrapper.Tokens(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(access).To(Equal(second))
+	})
+
+	It("Keeps the last good token if the file is removed", func() {
+		token := MakeTokenString("Bearer", 5*time.Minute)
+		Write(token)
+		wrapper, err := NewTransportWrapper().
+			Logger(logger).
+			TokenFile(file).
+			Build(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err = wrapper.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+		err = os.Remove(file)
+		Expect(err).ToNot(HaveOccurred())
+		access, _, err := wrapper.Tokens(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(access).To(Equal(token))
+	})
+
+	It("Keeps the last good token if the file is emptied", func() {
+		token := MakeTokenString("Bearer", 5*time.Minute)
+		Write(token)
+		wrapper, err := NewTransportWrapper().
+			Logger(logger).
+			TokenFile(file).
+			Build(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err = wrapper.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+		Write("")
+		access, _, err := wrapper.Tokens(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(access).To(Equal(token))
+	})
+
+	It("Fails if the file doesn't exist", func() {
+		wrapper, err := NewTransportWrapper().
+			Logger(logger).
+			TokenFile(file).
+			Build(ctx)
+		Expect(err).To(HaveOccurred())
+		Expect(wrapper).To(BeNil())
+		Expect(err.Error()).To(ContainSubstring(file))
+	})
+})
@@ -0,0 +1,177 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the interface for pluggable sources of access tokens, the implementations of
+// that interface provided by the SDK, and the methods of the wrapper that use it.
+
+package authentication
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// TokenSource is the interface of the objects that know how to obtain access tokens, for example
+// from a secrets vault or from the metadata service of a cloud provider.
+//
+// The Token method returns the access token and the time when it expires. A zero expiration time
+// means that the expiration isn't known, and then the token will be used till the Token method is
+// called again for some other reason. The wrapper caches the token and calls the Token method
+// again only when it is about to expire, so implementations don't need to do their own caching.
+type TokenSource interface {
+	Token(ctx context.Context) (token string, expiry time.Time, err error)
+}
+
+// TokenSourceFunc is an adapter that allows the use of ordinary functions as token sources.
+type TokenSourceFunc func(ctx context.Context) (token string, expiry time.Time, err error)
+
+// Make sure that we implement the interfaces:
+var (
+	_ TokenSource = TokenSourceFunc(nil)
+	_ TokenSource = (*staticTokenSource)(nil)
+	_ TokenSource = (*fileTokenSource)(nil)
+	_ TokenSource = (*TransportWrapper)(nil)
+)
+
+// Token is the implementation of the TokenSource interface.
+func (f TokenSourceFunc) Token(ctx context.Context) (token string, expiry time.Time, err error) {
+	return f(ctx)
+}
+
+// staticTokenSource is a token source that always returns the same token.
+type staticTokenSource struct {
+	token  string
+	expiry time.Time
+}
+
+// NewStaticTokenSource creates a token source that always returns the given token. If the token is
+// a JSON web token the expiration time is taken from the `exp` claim.
+func NewStaticTokenSource(token string) TokenSource {
+	return &staticTokenSource{
+		token:  token,
+		expiry: tokenExpiration(token),
+	}
+}
+
+// Token is the implementation of the TokenSource interface.
+func (s *staticTokenSource) Token(ctx context.Context) (token string, expiry time.Time, err error) {
+	token = s.token
+	expiry = s.expiry
+	return
+}
+
+// fileTokenSource is a token source that reads the token from a file.
+type fileTokenSource struct {
+	file string
+}
+
+// NewFileTokenSource creates a token source that reads the token from the given file each time
+// that a new token is needed. If the token is a JSON web token the expiration time is taken from
+// the `exp` claim.
+func NewFileTokenSource(file string) TokenSource {
+	return &fileTokenSource{
+		file: file,
+	}
+}
+
+// Token is the implementation of the TokenSource interface.
+func (s *fileTokenSource) Token(ctx context.Context) (token string, expiry time.Time, err error) {
+	data, err := os.ReadFile(s.file)
+	if err != nil {
+		err = fmt.Errorf("can't read token file '%s': %w", s.file, err)
+		return
+	}
+	token = strings.TrimSpace(string(data))
+	if token == "" {
+		err = fmt.Errorf("token file '%s' is empty", s.file)
+		return
+	}
+	expiry = tokenExpiration(token)
+	return
+}
+
+// Token is the implementation of the TokenSource interface. It returns the access token obtained
+// with the configured tokens or credentials, requesting a new one if needed. This allows using a
+// wrapper configured, for example, with client credentials as the token source of another one.
+func (w *TransportWrapper) Token(ctx context.Context) (token string, expiry time.Time, err error) {
+	token, _, err = w.Tokens(ctx)
+	if err != nil {
+		return
+	}
+	expiry = tokenExpiration(token)
+	return
+}
+
+// sourceToken returns the access token obtained from the token source, asking the source for a new
+// one if it isn't available yet or if it is about to expire. If the source fails but the previous
+// token hasn't expired yet it writes a warning to the log and returns the previous token. Note
+// that this must be called with the token mutex locked.
+func (w *TransportWrapper) sourceToken(ctx context.Context,
+	minRemaining time.Duration) (access string, err error) {
+	now := time.Now()
+	if w.sourceText != "" {
+		if w.sourceExpiry.IsZero() || w.sourceExpiry.Sub(now) >= minRemaining {
+			access = w.sourceText
+			return
+		}
+	}
+	text, expiry, err := w.tokenSource.Token(ctx)
+	if err == nil && text == "" {
+		err = fmt.Errorf("token source returned an empty token")
+	}
+	if err != nil {
+		if w.sourceText != "" && w.sourceExpiry.After(now) {
+			w.logger.Warn(
+				ctx,
+				"Can't get token from source, will use the previous one that expires "+
+					"in %s: %v",
+				w.sourceExpiry.Sub(now), err,
+			)
+			access = w.sourceText
+			err = nil
+			return
+		}
+		err = fmt.Errorf("can't get token from source: %w", err)
+		return
+	}
+	w.sourceText = text
+	w.sourceExpiry = expiry
+	w.logger.Debug(ctx, "Got token from source")
+	access = text
+	return
+}
+
+// tokenExpiration returns the expiration time of the given token. It returns the zero time if the
+// token isn't a JSON web token or if it doesn't have an `exp` claim.
+func tokenExpiration(text string) (result time.Time) {
+	parser := &jwt.Parser{}
+	object, _, err := parser.ParseUnverified(text, jwt.MapClaims{})
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	expires, remaining, err := tokenRemaining(&tokenInfo{text: text, object: object}, now)
+	if err != nil || !expires {
+		return
+	}
+	result = now.Add(remaining)
+	return
+}
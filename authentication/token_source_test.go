@@ -0,0 +1,282 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains tests for the token sources.
+
+package authentication
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/onsi/gomega/ghttp"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"             // nolint
+	. "github.com/onsi/gomega"                         // nolint
+	. "github.com/openshift-online/ocm-sdk-go/testing" // nolint
+)
+
+var _ = Describe("Token source", func() {
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.Background()
+	})
+
+	It("Uses the token returned by the source", func() {
+		token := MakeTokenString("Bearer", 5*time.Minute)
+		wrapper, err := NewTransportWrapper().
+			Logger(logger).
+			TokenSource(NewStaticTokenSource(token)).
+			Build(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err = wrapper.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+		access, refresh, err := wrapper.Tokens(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(access).To(Equal(token))
+		Expect(refresh).To(BeEmpty())
+	})
+
+	It("Doesn't call the source while the token is valid", func() {
+		token := MakeTokenString("Bearer", 5*time.Minute)
+		calls := 0
+		source := TokenSourceFunc(func(ctx context.Context) (string, time.Time, error) {
+			calls++
+			return token, time.Now().Add(5 * time.Minute), nil
+		})
+		wrapper, err := NewTransportWrapper().
+			Logger(logger).
+			TokenSource(source).
+			Build(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err = wrapper.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+		for i := 0; i < 3; i++ {
+			access, _, err := wrapper.Tokens(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(access).To(Equal(token))
+		}
+		Expect(calls).To(Equal(1))
+	})
+
+	It("Calls the source again when the token is about to expire", func() {
+		first := MakeTokenString("Bearer", 30*time.Second)
+		second := MakeTokenString("Bearer", 5*time.Minute)
+		tokens := []string{first, second}
+		calls := 0
+		source := TokenSourceFunc(func(ctx context.Context) (string, time.Time, error) {
+			token := tokens[calls]
+			calls++
+			return token, tokenExpiration(token), nil
+		})
+		wrapper, err := NewTransportWrapper().
+			Logger(logger).
+			TokenSource(source).
+			Build(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err = wrapper.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+		access, _, err := wrapper.Tokens(ctx, 10*time.Second)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(access).To(Equal(first))
+		access, _, err = wrapper.Tokens(ctx, time.Minute)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(access).To(Equal(second))
+		Expect(calls).To(Equal(2))
+	})
+
+	It("Keeps the previous token if the source fails", func() {
+		token := MakeTokenString("Bearer", 30*time.Second)
+		calls := 0
+		source := TokenSourceFunc(func(ctx context.Context) (string, time.Time, error) {
+			calls++
+			if calls > 1 {
+				return "", time.Time{}, errors.New("vault is sealed")
+			}
+			return token, tokenExpiration(token), nil
+		})
+		wrapper, err := NewTransportWrapper().
+			Logger(logger).
+			TokenSource(source).
+			Build(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err = wrapper.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+		_, _, err = wrapper.Tokens(ctx, 10*time.Second)
+		Expect(err).ToNot(HaveOccurred())
+		access, _, err := wrapper.Tokens(ctx, time.Minute)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(access).To(Equal(token))
+	})
+
+	It("Fails if the source fails and there is no previous token", func() {
+		source := TokenSourceFunc(func(ctx context.Context) (string, time.Time, error) {
+			return "", time.Time{}, errors.New("vault is sealed")
+		})
+		wrapper, err := NewTransportWrapper().
+			Logger(logger).
+			TokenSource(source).
+			Build(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err = wrapper.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+		_, _, err = wrapper.Tokens(ctx)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("vault is sealed"))
+	})
+
+	It("Fails if the source returns an empty token", func() {
+		wrapper, err := NewTransportWrapper().
+			Logger(logger).
+			TokenSource(NewStaticTokenSource("")).
+			Build(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err = wrapper.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+		_, _, err = wrapper.Tokens(ctx)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("empty token"))
+	})
+
+	It("Can't be combined with tokens", func() {
+		token := MakeTokenString("Bearer", 5*time.Minute)
+		_, err := NewTransportWrapper().
+			Logger(logger).
+			Tokens(token).
+			TokenSource(NewStaticTokenSource(token)).
+			Build(ctx)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("token source"))
+	})
+
+	It("Can't be combined with anonymous access", func() {
+		token := MakeTokenString("Bearer", 5*time.Minute)
+		_, err := NewTransportWrapper().
+			Logger(logger).
+			Anonymous(true).
+			TokenSource(NewStaticTokenSource(token)).
+			Build(ctx)
+		Expect(err).To(HaveOccurred())
+	})
+
+	Describe("Static", func() {
+		It("Returns the expiration of JSON web tokens", func() {
+			token := MakeTokenString("Bearer", 5*time.Minute)
+			text, expiry, err := NewStaticTokenSource(token).Token(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(text).To(Equal(token))
+			Expect(expiry).To(BeTemporally("~", time.Now().Add(5*time.Minute), 5*time.Second))
+		})
+
+		It("Returns zero expiration for opaque tokens", func() {
+			text, expiry, err := NewStaticTokenSource("my_token").Token(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(text).To(Equal("my_token"))
+			Expect(expiry.IsZero()).To(BeTrue())
+		})
+	})
+
+	Describe("File", func() {
+		var file string
+
+		BeforeEach(func() {
+			file = filepath.Join(GinkgoT().TempDir(), "token")
+		})
+
+		It("Reads the token from the file", func() {
+			token := MakeTokenString("Bearer", 5*time.Minute)
+			err := os.WriteFile(file, []byte(token+"\n"), 0600)
+			Expect(err).ToNot(HaveOccurred())
+			text, expiry, err := NewFileTokenSource(file).Token(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(text).To(Equal(token))
+			Expect(expiry).To(BeTemporally("~", time.Now().Add(5*time.Minute), 5*time.Second))
+		})
+
+		It("Fails if the file doesn't exist", func() {
+			_, _, err := NewFileTokenSource(file).Token(ctx)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("Fails if the file is empty", func() {
+			err := os.WriteFile(file, []byte("\n"), 0600)
+			Expect(err).ToNot(HaveOccurred())
+			_, _, err = NewFileTokenSource(file).Token(ctx)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("empty"))
+		})
+	})
+
+	Describe("Wrapper", func() {
+		var server *ghttp.Server
+
+		BeforeEach(func() {
+			server = MakeTCPServer()
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("Uses client credentials of another wrapper", func() {
+			accessToken := MakeTokenString("Bearer", 5*time.Minute)
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					VerifyClientCredentialsGrant("my_client", "my_secret"),
+					RespondWithAccessToken(accessToken),
+				),
+			)
+			source, err := NewTransportWrapper().
+				Logger(logger).
+				TokenURL(server.URL()).
+				Client("my_client", "my_secret").
+				Build(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			defer func() {
+				err = source.Close()
+				Expect(err).ToNot(HaveOccurred())
+			}()
+			wrapper, err := NewTransportWrapper().
+				Logger(logger).
+				TokenSource(source).
+				Build(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			defer func() {
+				err = wrapper.Close()
+				Expect(err).ToNot(HaveOccurred())
+			}()
+			access, _, err := wrapper.Tokens(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(access).To(Equal(accessToken))
+		})
+	})
+})
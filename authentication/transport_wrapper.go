@@ -66,10 +66,15 @@ type TransportWrapperBuilder struct {
 	user              string
 	password          string
 	tokens            []string
+	tokenFile         string
+	tokenSource       TokenSource
+	anonymous         bool
 	scopes            []string
 	agent             string
 	trustedCAs        []interface{}
 	insecure          bool
+	minTLSVersion     uint16
+	cipherSuites      []uint16
 	transportWrappers []func(http.RoundTripper) http.RoundTripper
 
 	// Fields used for metrics:
@@ -96,12 +101,20 @@ type TransportWrapper struct {
 	accessToken           *tokenInfo
 	refreshToken          *tokenInfo
 	pullSecretAccessToken *tokenInfo
+	tokenFile             string
+	tokenFileTime         time.Time
+	tokenFileSize         int64
+	tokenSource           TokenSource
+	sourceText            string
+	sourceExpiry          time.Time
+	anonymous             bool
 
 	// Fields used for metrics:
 	metricsSubsystem    string
 	metricsRegisterer   prometheus.Registerer
 	tokenCountMetric    *prometheus.CounterVec
 	tokenDurationMetric *prometheus.HistogramVec
+	tokenFailureMetric  *prometheus.CounterVec
 }
 
 // roundTripper is a round tripper that adds authorization tokens to requests.
@@ -221,6 +234,41 @@ func (b *TransportWrapperBuilder) Tokens(tokens ...string) *TransportWrapperBuil
 	return b
 }
 
+// TokenFile sets the name of a file that contains the access token that will be used to
+// authenticate. The file will be checked before each request, and if it has changed the token
+// will be loaded again. This is intended for long lived processes where some other component
+// periodically writes a new token to the file, for example a Kubernetes projected service account
+// token.
+//
+// The file must exist and contain a token when the Build method is called. If later it can't be
+// read, or it is empty, the wrapper will write a warning to the log and will keep using the last
+// token that it loaded successfully.
+func (b *TransportWrapperBuilder) TokenFile(value string) *TransportWrapperBuilder {
+	b.tokenFile = value
+	return b
+}
+
+// TokenSource sets an object that will be used to obtain the access tokens, for example from a
+// secrets vault or from the metadata service of a cloud provider. The wrapper calls the source
+// when it needs the first token, and again when the token is about to expire. If the source fails
+// but the previous token hasn't expired yet, the wrapper writes a warning to the log and keeps
+// using it. Note that it isn't possible to use a token source and provide tokens or credentials at
+// the same time.
+func (b *TransportWrapperBuilder) TokenSource(value TokenSource) *TransportWrapperBuilder {
+	b.tokenSource = value
+	return b
+}
+
+// Anonymous indicates that the wrapper will not add any authorization header to requests. By
+// default the Build method returns an error when no token or credentials have been provided,
+// because in most cases that is a mistake that would result in all requests being rejected. Use
+// this for the rare cases where the server doesn't require authentication. Note that it isn't
+// possible to enable this and provide tokens or credentials at the same time.
+func (b *TransportWrapperBuilder) Anonymous(flag bool) *TransportWrapperBuilder {
+	b.anonymous = flag
+	return b
+}
+
 // Agent sets the `User-Agent` header that the round trippers will use in all the HTTP requests. The
 // default is `OCM-SDK` followed by an slash and the version of the SDK, for example `OCM/0.0.0`.
 func (b *TransportWrapperBuilder) Agent(agent string) *TransportWrapperBuilder {
@@ -258,6 +306,21 @@ func (b *TransportWrapperBuilder) Insecure(flag bool) *TransportWrapperBuilder {
 	return b
 }
 
+// MinTLSVersion sets the minimum TLS version that will be accepted when connecting to the OpenID
+// server, for example tls.VersionTLS13. It can't be less than TLS 1.2. The default is to use the
+// default of the Go TLS library.
+func (b *TransportWrapperBuilder) MinTLSVersion(value uint16) *TransportWrapperBuilder {
+	b.minTLSVersion = value
+	return b
+}
+
+// CipherSuites sets the list of cipher suites that will be used for TLS 1.2 connections to the
+// OpenID server. The default is to use the cipher suites selected by the Go TLS library.
+func (b *TransportWrapperBuilder) CipherSuites(values ...uint16) *TransportWrapperBuilder {
+	b.cipherSuites = append(b.cipherSuites, values...)
+	return b
+}
+
 // TransportWrapper adds a function that will be used to wrap the transports of the HTTP client used
 // to request tokens. If used multiple times the transport wrappers will be called in the same order
 // that they are added.
@@ -288,6 +351,7 @@ func (b *TransportWrapperBuilder) TransportWrappers(
 //	api_outbound_token_request_duration_sum - Total time to send token requests, in seconds.
 //	api_outbound_token_request_duration_count - Total number of token requests measured.
 //	api_outbound_token_request_duration_bucket - Number of token requests organized in buckets.
+//	api_outbound_token_acquisition_failure_total - Number of failures to obtain a token.
 //
 // The duration buckets metrics contain an `le` label that indicates the upper bound. For example if
 // the `le` label is `1` then the value will be the number of requests that were processed in less
@@ -299,6 +363,13 @@ func (b *TransportWrapperBuilder) TransportWrappers(
 // code, for example if it wasn't possible to open the connection, or if there was a timeout waiting
 // for the response.
 //
+// The token acquisition failure metric is incremented once each time that it isn't possible to
+// obtain a token, after all the retries, and contains the following label:
+//
+//	reason - One of `network`, `invalid_grant`, `expired_refresh`, `server` or `other`.
+//
+// That way failures of authentication can be distinguished from failures of the API servers.
+//
 // Note that setting this attribute is not enough to have metrics published, you also need to
 // create and start a metrics server, as described in the documentation of the Prometheus library.
 func (b *TransportWrapperBuilder) MetricsSubsystem(value string) *TransportWrapperBuilder {
@@ -328,13 +399,30 @@ func (b *TransportWrapperBuilder) Build(ctx context.Context) (result *TransportW
 	}
 
 	// Check that we have some kind of credentials or a token:
-	haveTokens := len(b.tokens) > 0
+	haveTokens := len(b.tokens) > 0 || b.tokenFile != ""
 	havePassword := b.user != "" && b.password != ""
 	haveSecret := b.clientID != "" && b.clientSecret != ""
-	if !haveTokens && !havePassword && !haveSecret {
+	haveSource := b.tokenSource != nil
+	if haveSource && (haveTokens || havePassword || haveSecret) {
+		err = fmt.Errorf(
+			"a token source has been provided, but tokens or credentials have also " +
+				"been provided",
+		)
+		return
+	}
+	if b.anonymous {
+		if haveTokens || havePassword || haveSecret || haveSource {
+			err = fmt.Errorf(
+				"anonymous access has been requested, but tokens or credentials have " +
+					"also been provided",
+			)
+			return
+		}
+	} else if !haveTokens && !havePassword && !haveSecret && !haveSource {
 		err = fmt.Errorf(
 			"either a token, an user name and password or a client identifier and secret are " +
-				"necessary, but none has been provided",
+				"necessary, but none has been provided; if the server doesn't require " +
+				"authentication explicitly request anonymous access",
 		)
 		return
 	}
@@ -493,6 +581,8 @@ func (b *TransportWrapperBuilder) Build(ctx context.Context) (result *TransportW
 		Logger(b.logger).
 		TrustedCAs(b.trustedCAs...).
 		Insecure(b.insecure).
+		MinTLSVersion(b.minTLSVersion).
+		CipherSuites(b.cipherSuites...).
 		TransportWrappers(b.transportWrappers...).
 		Build(ctx)
 	if err != nil {
@@ -502,6 +592,7 @@ func (b *TransportWrapperBuilder) Build(ctx context.Context) (result *TransportW
 	// Register the metrics:
 	var tokenCountMetric *prometheus.CounterVec
 	var tokenDurationMetric *prometheus.HistogramVec
+	var tokenFailureMetric *prometheus.CounterVec
 	if b.metricsSubsystem != "" && b.metricsRegisterer != nil {
 		tokenCountMetric = prometheus.NewCounterVec(
 			prometheus.CounterOpts{
@@ -511,15 +602,13 @@ func (b *TransportWrapperBuilder) Build(ctx context.Context) (result *TransportW
 			},
 			tokenMetricsLabels,
 		)
-		err = b.metricsRegisterer.Register(tokenCountMetric)
+		tokenCountMetric, err = internal.RegisterCounterVec(
+			b.metricsRegisterer,
+			b.metricsSubsystem+"_token_request_count",
+			tokenCountMetric,
+		)
 		if err != nil {
-			registered, ok := err.(prometheus.AlreadyRegisteredError)
-			if ok {
-				tokenCountMetric = registered.ExistingCollector.(*prometheus.CounterVec)
-				err = nil
-			} else {
-				return
-			}
+			return
 		}
 
 		tokenDurationMetric = prometheus.NewHistogramVec(
@@ -536,15 +625,30 @@ func (b *TransportWrapperBuilder) Build(ctx context.Context) (result *TransportW
 			},
 			tokenMetricsLabels,
 		)
-		err = b.metricsRegisterer.Register(tokenDurationMetric)
+		tokenDurationMetric, err = internal.RegisterHistogramVec(
+			b.metricsRegisterer,
+			b.metricsSubsystem+"_token_request_duration",
+			tokenDurationMetric,
+		)
 		if err != nil {
-			registered, ok := err.(prometheus.AlreadyRegisteredError)
-			if ok {
-				tokenDurationMetric = registered.ExistingCollector.(*prometheus.HistogramVec)
-				err = nil
-			} else {
-				return
-			}
+			return
+		}
+
+		tokenFailureMetric = prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Subsystem: b.metricsSubsystem,
+				Name:      "token_acquisition_failure_total",
+				Help:      "Number of failures to obtain a token.",
+			},
+			tokenFailureMetricsLabels,
+		)
+		tokenFailureMetric, err = internal.RegisterCounterVec(
+			b.metricsRegisterer,
+			b.metricsSubsystem+"_token_acquisition_failure_total",
+			tokenFailureMetric,
+		)
+		if err != nil {
+			return
 		}
 	}
 
@@ -565,10 +669,24 @@ func (b *TransportWrapperBuilder) Build(ctx context.Context) (result *TransportW
 		accessToken:           accessToken,
 		refreshToken:          refreshToken,
 		pullSecretAccessToken: pullSecretAccessToken,
+		tokenFile:             b.tokenFile,
+		tokenSource:           b.tokenSource,
+		anonymous:             b.anonymous,
 		metricsSubsystem:      b.metricsSubsystem,
 		metricsRegisterer:     b.metricsRegisterer,
 		tokenCountMetric:      tokenCountMetric,
 		tokenDurationMetric:   tokenDurationMetric,
+		tokenFailureMetric:    tokenFailureMetric,
+	}
+
+	// Load the initial token from the file:
+	if result.tokenFile != "" {
+		err = result.loadTokenFile(ctx)
+		if err != nil {
+			clientSelector.Close()
+			result = nil
+			return
+		}
 	}
 
 	return
@@ -683,9 +801,9 @@ func (w *TransportWrapper) Tokens(ctx context.Context, expiresIn ...time.Duratio
 	}
 
 	attempt := 0
+	var code int
 	operation := func() error {
 		attempt++
-		var code int
 		code, access, refresh, err = w.tokens(ctx, attempt, expiresDuration)
 		if err != nil {
 			if code >= http.StatusInternalServerError {
@@ -714,6 +832,14 @@ func (w *TransportWrapper) Tokens(ctx context.Context, expiresIn ...time.Duratio
 
 	// nolint
 	backoff.Retry(operation, backoffMethod)
+
+	// Update the metrics:
+	if err != nil && w.tokenFailureMetric != nil {
+		w.tokenFailureMetric.With(map[string]string{
+			metricsReasonLabel: tokenFailureReason(code, err),
+		}).Inc()
+	}
+
 	return access, refresh, err
 }
 
@@ -724,6 +850,22 @@ func (w *TransportWrapper) tokens(ctx context.Context, attempt int,
 	w.tokenMutex.Lock()
 	defer w.tokenMutex.Unlock()
 
+	// Anonymous access doesn't use tokens:
+	if w.anonymous {
+		return
+	}
+
+	// If the token is obtained from a token source then it is the only source of tokens:
+	if w.tokenSource != nil {
+		access, err = w.sourceToken(ctx, minRemaining)
+		return
+	}
+
+	// If the token is loaded from a file then check if it has changed:
+	if w.tokenFile != "" {
+		w.reloadTokenFile(ctx)
+	}
+
 	// A pull-secret access token can just be used as-is
 	if w.pullSecretAccessToken != nil {
 		access = w.pullSecretAccessToken.text
@@ -834,10 +976,11 @@ func (w *TransportWrapper) tokens(ctx context.Context, attempt int,
 	}
 
 	// There is no way to get a valid access token, so all we can do is report the failure:
-	err = fmt.Errorf(
-		"access and refresh tokens are unavailable or expired, and there are no " +
-			"password or client secret to request new ones",
-	)
+	expiredErr := &TokenExpiredError{}
+	if w.accessToken != nil && accessExpires {
+		expiredErr.Expiry = now.Add(accessRemaining)
+	}
+	err = expiredErr
 
 	return
 }
@@ -982,11 +1125,13 @@ func (w *TransportWrapper) sendFormTimed(ctx context.Context, form url.Values, h
 		return
 	}
 	if result.Error != nil {
+		responseErr := &tokenResponseError{
+			code: *result.Error,
+		}
 		if result.ErrorDescription != nil {
-			err = fmt.Errorf("%s: %s", *result.Error, *result.ErrorDescription)
-			return
+			responseErr.description = *result.ErrorDescription
 		}
-		err = fmt.Errorf("%s", *result.Error)
+		err = responseErr
 		return
 	}
 	if response.StatusCode != http.StatusOK {
@@ -1135,6 +1280,7 @@ const (
 const (
 	metricsAttemptLabel = "attempt"
 	metricsCodeLabel    = "code"
+	metricsReasonLabel  = "reason"
 )
 
 // Array of labels added to token metrics:
@@ -1142,3 +1288,8 @@ var tokenMetricsLabels = []string{
 	metricsAttemptLabel,
 	metricsCodeLabel,
 }
+
+// Array of labels added to the token acquisition failure metric:
+var tokenFailureMetricsLabels = []string{
+	metricsReasonLabel,
+}
@@ -360,6 +360,39 @@ var _ = Describe("Tokens", func() {
 			// Get the tokens:
 			_, _, err = wrapper.Tokens(ctx)
 			Expect(err).To(HaveOccurred())
+			var expiredErr *TokenExpiredError
+			Expect(errors.As(err, &expiredErr)).To(BeTrue())
+			expectedExpiry := time.Now().Add(-5 * time.Second)
+			Expect(expiredErr.Expiry).To(BeTemporally("~", expectedExpiry, time.Second))
+			Expect(err.Error()).To(ContainSubstring("access token expired at"))
+		})
+
+		It("Fails before sending the request if the access token is expired", func() {
+			// Generate the tokens:
+			accessToken := MakeTokenString("Bearer", -5*time.Second)
+
+			// Create the wrapper:
+			wrapper, err := NewTransportWrapper().
+				Logger(logger).
+				TokenURL(server.URL()).
+				TrustedCA(ca).
+				Tokens(accessToken).
+				Build(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			defer func() {
+				err = wrapper.Close()
+				Expect(err).ToNot(HaveOccurred())
+			}()
+
+			// Send the request:
+			client := &http.Client{
+				Transport: wrapper.Wrap(http.DefaultTransport),
+			}
+			_, err = client.Get(server.URL() + "/api")
+			Expect(err).To(HaveOccurred())
+			var expiredErr *TokenExpiredError
+			Expect(errors.As(err, &expiredErr)).To(BeTrue())
+			Expect(server.ReceivedRequests()).To(BeEmpty())
 		})
 
 		It("Succeeds if access token expires soon and there is no refresh token", func() {
@@ -404,6 +437,9 @@ var _ = Describe("Tokens", func() {
 			// Get the tokens:
 			_, _, err = wrapper.Tokens(ctx)
 			Expect(err).To(HaveOccurred())
+			var expiredErr *TokenExpiredError
+			Expect(errors.As(err, &expiredErr)).To(BeTrue())
+			Expect(expiredErr.Expiry.IsZero()).To(BeTrue())
 		})
 
 		When("The server doesn't return JSON content type", func() {
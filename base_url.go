@@ -0,0 +1,128 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the implementation of the transport wrapper that overrides the base URL of
+// individual requests.
+
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// WithBaseURL creates a new context that tells the connection to send the request to the given
+// base URL instead of the one selected according to the URL and alternative URLs of the connection.
+// Only the scheme and the host of the given URL are used, the path and the query of the request,
+// as well as the authentication, aren't modified. For example:
+//
+//	ctx = sdk.WithBaseURL(ctx, "https://my.example.com")
+//	response, err := connection.ClustersMgmt().V1().Clusters().List().SendContext(ctx)
+//
+// This is intended for tests and for one-off redirects where creating a separate connection isn't
+// convenient.
+func WithBaseURL(parent context.Context, value string) context.Context {
+	return context.WithValue(parent, baseURLKeyValue, value)
+}
+
+// BaseURLFromContext extracts the base URL that was stored in the context with the WithBaseURL
+// function. If there is no such base URL the result will be the empty string.
+func BaseURLFromContext(ctx context.Context) string {
+	value, _ := ctx.Value(baseURLKeyValue).(string)
+	return value
+}
+
+// baseURLKeyType is the type of the key used to store the base URL in the context.
+type baseURLKeyType string
+
+// baseURLKeyValue is the key used to store the base URL in the context:
+const baseURLKeyValue baseURLKeyType = "baseURL"
+
+// baseURLTransportWrapper is a transport wrapper that creates round trippers that replace the
+// scheme and host of requests with the ones of the base URL stored in the context.
+type baseURLTransportWrapper struct {
+}
+
+// baseURLRoundTripper is a round tripper that replaces the scheme and host of requests with the
+// ones of the base URL stored in the context.
+type baseURLRoundTripper struct {
+	next http.RoundTripper
+}
+
+// Make sure that we implement the http.RoundTripper interface:
+var _ http.RoundTripper = &baseURLRoundTripper{}
+
+// Wrap creates a round tripper on top of the given one that replaces the scheme and host of the
+// requests.
+func (w *baseURLTransportWrapper) Wrap(transport http.RoundTripper) http.RoundTripper {
+	return &baseURLRoundTripper{
+		next: transport,
+	}
+}
+
+// RoundTrip is the implementation of the http.RoundTripper interface.
+func (t *baseURLRoundTripper) RoundTrip(request *http.Request) (response *http.Response,
+	err error) {
+	// Do nothing if there is no base URL in the context:
+	ctx := request.Context()
+	value := BaseURLFromContext(ctx)
+	if value == "" {
+		response, err = t.next.RoundTrip(request)
+		return
+	}
+
+	// Parse and check the base URL:
+	base, err := url.Parse(value)
+	if err != nil {
+		err = fmt.Errorf("can't parse base URL '%s': %w", value, err)
+		closeRequestBody(request)
+		return
+	}
+	if base.Scheme != "http" && base.Scheme != "https" {
+		err = fmt.Errorf(
+			"base URL '%s' should use the 'http' or 'https' scheme",
+			value,
+		)
+		closeRequestBody(request)
+		return
+	}
+	if base.Host == "" {
+		err = fmt.Errorf("base URL '%s' should contain a host", value)
+		closeRequestBody(request)
+		return
+	}
+
+	// Round trippers shouldn't modify the request, so we need to replace it with a copy that
+	// has the new scheme and host:
+	request = request.Clone(ctx)
+	request.URL.Scheme = base.Scheme
+	request.URL.Host = base.Host
+	request.Host = ""
+
+	// Send the modified request:
+	response, err = t.next.RoundTrip(request)
+	return
+}
+
+// closeRequestBody closes the body of the request, if any, as round trippers are required to do
+// even when they return an error.
+func closeRequestBody(request *http.Request) {
+	if request.Body != nil {
+		request.Body.Close()
+	}
+}
@@ -0,0 +1,176 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains tests for the per request base URL support.
+
+package sdk
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core" // nolint
+	. "github.com/onsi/gomega"             // nolint
+
+	"github.com/onsi/gomega/ghttp"
+
+	. "github.com/openshift-online/ocm-sdk-go/testing" // nolint
+)
+
+var _ = Describe("Base URL", func() {
+	var ctx context.Context
+	var token string
+	var defaultServer *ghttp.Server
+	var otherServer *ghttp.Server
+	var connection *Connection
+
+	BeforeEach(func() {
+		var err error
+
+		// Create the context:
+		ctx = context.Background()
+
+		// Create the token:
+		token = MakeTokenString("Bearer", 5*time.Minute)
+
+		// Create the servers:
+		defaultServer = MakeTCPServer()
+		otherServer = MakeTCPServer()
+
+		// Create the connection:
+		connection, err = NewConnectionBuilder().
+			Logger(logger).
+			URL(defaultServer.URL()).
+			Tokens(token).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		// Close the connection:
+		err := connection.Close()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Stop the servers:
+		defaultServer.Close()
+		otherServer.Close()
+	})
+
+	It("Uses the default URL when there is no base URL in the context", func() {
+		// Prepare the server:
+		defaultServer.AppendHandlers(
+			RespondWithJSON(http.StatusOK, "{}"),
+		)
+
+		// Send the request:
+		response, err := connection.Get().
+			Path("/api/clusters_mgmt/v1/clusters").
+			SendContext(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.Status()).To(Equal(http.StatusOK))
+		Expect(defaultServer.ReceivedRequests()).To(HaveLen(1))
+		Expect(otherServer.ReceivedRequests()).To(BeEmpty())
+	})
+
+	It("Sends the request to the base URL from the context", func() {
+		// Prepare the server:
+		otherServer.AppendHandlers(
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest(
+					http.MethodGet,
+					"/api/clusters_mgmt/v1/clusters",
+					"search=name='my'",
+				),
+				ghttp.VerifyHeaderKV("Authorization", "Bearer "+token),
+				RespondWithJSON(http.StatusOK, "{}"),
+			),
+		)
+
+		// Send the request:
+		response, err := connection.Get().
+			Path("/api/clusters_mgmt/v1/clusters").
+			Parameter("search", "name='my'").
+			SendContext(WithBaseURL(ctx, otherServer.URL()))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.Status()).To(Equal(http.StatusOK))
+		Expect(defaultServer.ReceivedRequests()).To(BeEmpty())
+		Expect(otherServer.ReceivedRequests()).To(HaveLen(1))
+	})
+
+	It("Ignores the path of the base URL", func() {
+		// Prepare the server:
+		otherServer.AppendHandlers(
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest(http.MethodGet, "/api/clusters_mgmt/v1/clusters"),
+				RespondWithJSON(http.StatusOK, "{}"),
+			),
+		)
+
+		// Send the request:
+		response, err := connection.Get().
+			Path("/api/clusters_mgmt/v1/clusters").
+			SendContext(WithBaseURL(ctx, otherServer.URL()+"/junk"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.Status()).To(Equal(http.StatusOK))
+	})
+
+	It("Doesn't affect other requests", func() {
+		// Prepare the servers:
+		otherServer.AppendHandlers(
+			RespondWithJSON(http.StatusOK, "{}"),
+		)
+		defaultServer.AppendHandlers(
+			RespondWithJSON(http.StatusOK, "{}"),
+		)
+
+		// Send the first request to the other server:
+		_, err := connection.Get().
+			Path("/api/clusters_mgmt/v1/clusters").
+			SendContext(WithBaseURL(ctx, otherServer.URL()))
+		Expect(err).ToNot(HaveOccurred())
+
+		// Send the second request without base URL:
+		_, err = connection.Get().
+			Path("/api/clusters_mgmt/v1/clusters").
+			SendContext(ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		// Check that each server received one request:
+		Expect(otherServer.ReceivedRequests()).To(HaveLen(1))
+		Expect(defaultServer.ReceivedRequests()).To(HaveLen(1))
+	})
+
+	It("Rejects base URL without host", func() {
+		_, err := connection.Get().
+			Path("/api/clusters_mgmt/v1/clusters").
+			SendContext(WithBaseURL(ctx, "http://"))
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("host"))
+	})
+
+	It("Rejects base URL with unsupported scheme", func() {
+		_, err := connection.Get().
+			Path("/api/clusters_mgmt/v1/clusters").
+			SendContext(WithBaseURL(ctx, "ftp://my.example.com"))
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("scheme"))
+	})
+
+	It("Returns empty string when there is no base URL in the context", func() {
+		Expect(BaseURLFromContext(ctx)).To(BeEmpty())
+	})
+})
@@ -0,0 +1,132 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the implementation of the transport wrapper that limits the total number of
+// bytes transferred by a connection.
+
+package sdk
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/openshift-online/ocm-sdk-go/metrics/core"
+)
+
+// ByteLimitError is the error returned when sending a request with a connection that has already
+// transferred the number of bytes allowed by the limit set with the ByteLimit method of the
+// connection builder. Use errors.As to check for it.
+type ByteLimitError struct {
+	// Limit is the maximum number of bytes allowed.
+	Limit int64
+
+	// Count is the number of bytes transferred when the request was rejected.
+	Count int64
+}
+
+// Error is the implementation of the error interface.
+func (e *ByteLimitError) Error() string {
+	return fmt.Sprintf(
+		"connection has transferred %d bytes, which reaches the limit of %d bytes",
+		e.Count, e.Limit,
+	)
+}
+
+// byteLimitTransportWrapper is a transport wrapper that counts the bytes of the bodies of requests
+// and responses, and that rejects requests when the count reaches the limit.
+type byteLimitTransportWrapper struct {
+	limit int64
+	count int64
+}
+
+// byteLimitRoundTripper is a round tripper that counts the bytes of the bodies of requests and
+// responses, and that rejects requests when the count reaches the limit.
+type byteLimitRoundTripper struct {
+	owner *byteLimitTransportWrapper
+	next  http.RoundTripper
+}
+
+// Make sure that we implement the http.RoundTripper interface:
+var _ http.RoundTripper = &byteLimitRoundTripper{}
+
+// Wrap creates a round tripper on top of the given one that counts and limits the bytes
+// transferred.
+func (w *byteLimitTransportWrapper) Wrap(transport http.RoundTripper) http.RoundTripper {
+	return &byteLimitRoundTripper{
+		owner: w,
+		next:  transport,
+	}
+}
+
+// Count returns the number of bytes transferred since the wrapper was created or reset.
+func (w *byteLimitTransportWrapper) Count() int64 {
+	return atomic.LoadInt64(&w.count)
+}
+
+// Reset sets the number of bytes transferred to zero.
+func (w *byteLimitTransportWrapper) Reset() {
+	atomic.StoreInt64(&w.count, 0)
+}
+
+// add adds the given number of bytes to the count.
+func (w *byteLimitTransportWrapper) add(count int64) {
+	atomic.AddInt64(&w.count, count)
+}
+
+// RoundTrip is the implementation of the http.RoundTripper interface.
+func (t *byteLimitRoundTripper) RoundTrip(request *http.Request) (response *http.Response,
+	err error) {
+	// Reject the request if the limit has already been reached:
+	count := t.owner.Count()
+	if count >= t.owner.limit {
+		err = &ByteLimitError{
+			Limit: t.owner.limit,
+			Count: count,
+		}
+		closeRequestBody(request)
+		return
+	}
+
+	// Round trippers shouldn't modify the request, so we need to replace it with a copy that
+	// counts the bytes of the body:
+	if request.Body != nil && request.Body != http.NoBody {
+		original := request
+		request = original.Clone(original.Context())
+		request.Body = core.WrapBody(original.Body, t.owner.add)
+		if original.GetBody != nil {
+			request.GetBody = func() (body io.ReadCloser, err error) {
+				body, err = original.GetBody()
+				if err != nil {
+					return
+				}
+				body = core.WrapBody(body, t.owner.add)
+				return
+			}
+		}
+	}
+
+	// Send the request and count the bytes of the response body:
+	response, err = t.next.RoundTrip(request)
+	if err != nil {
+		return
+	}
+	if response.Body != nil {
+		response.Body = core.WrapBody(response.Body, t.owner.add)
+	}
+	return
+}
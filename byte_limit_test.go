@@ -0,0 +1,151 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains tests for the limit of bytes transferred by a connection.
+
+package sdk
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core" // nolint
+	. "github.com/onsi/gomega"             // nolint
+
+	"github.com/onsi/gomega/ghttp"
+
+	. "github.com/openshift-online/ocm-sdk-go/testing" // nolint
+)
+
+var _ = Describe("Byte limit", func() {
+	var server *ghttp.Server
+	var connection *Connection
+
+	BeforeEach(func() {
+		var err error
+
+		// Create the token:
+		token := MakeTokenString("Bearer", 5*time.Minute)
+
+		// Create the server:
+		server = MakeTCPServer()
+
+		// Create the connection:
+		connection, err = NewConnectionBuilder().
+			Logger(logger).
+			URL(server.URL()).
+			Tokens(token).
+			ByteLimit(100).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		// Close the connection:
+		err := connection.Close()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Stop the server:
+		server.Close()
+	})
+
+	It("Counts bytes of request and response bodies", func() {
+		// Prepare the server:
+		server.AppendHandlers(RespondWithJSON(http.StatusOK, `{"id":"123"}`))
+
+		// Send the request:
+		_, err := connection.Post().
+			Path("/api/clusters_mgmt/v1/clusters").
+			String(`{"name":"my"}`).
+			Send()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(connection.ByteCount()).To(BeNumerically("==", 13+12))
+	})
+
+	It("Rejects requests once the limit is reached", func() {
+		// Prepare the server:
+		body := `{"name":"` + strings.Repeat("x", 100) + `"}`
+		server.AppendHandlers(RespondWithJSON(http.StatusOK, body))
+
+		// The first request should succeed and exceed the limit:
+		_, err := connection.Get().
+			Path("/api/clusters_mgmt/v1/clusters/123").
+			Send()
+		Expect(err).ToNot(HaveOccurred())
+
+		// The second request should fail without reaching the server:
+		_, err = connection.Get().
+			Path("/api/clusters_mgmt/v1/clusters/123").
+			Send()
+		Expect(err).To(HaveOccurred())
+		var limitErr *ByteLimitError
+		Expect(errors.As(err, &limitErr)).To(BeTrue())
+		Expect(limitErr.Limit).To(BeNumerically("==", 100))
+		Expect(limitErr.Count).To(BeNumerically("==", len(body)))
+		Expect(server.ReceivedRequests()).To(HaveLen(1))
+	})
+
+	It("Accepts requests again after reset", func() {
+		// Prepare the server:
+		body := `{"name":"` + strings.Repeat("x", 100) + `"}`
+		server.AppendHandlers(
+			RespondWithJSON(http.StatusOK, body),
+			RespondWithJSON(http.StatusOK, `{}`),
+		)
+
+		// Exceed the limit:
+		_, err := connection.Get().
+			Path("/api/clusters_mgmt/v1/clusters/123").
+			Send()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Reset the count and send another request:
+		connection.ResetByteCount()
+		Expect(connection.ByteCount()).To(BeZero())
+		_, err = connection.Get().
+			Path("/api/clusters_mgmt/v1/clusters/123").
+			Send()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(connection.ByteCount()).To(BeNumerically("==", 2))
+	})
+
+	It("Doesn't count bytes without limit", func() {
+		// Create a connection without limit:
+		token := MakeTokenString("Bearer", 5*time.Minute)
+		unlimited, err := NewConnectionBuilder().
+			Logger(logger).
+			URL(server.URL()).
+			Tokens(token).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err := unlimited.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+
+		// Prepare the server:
+		server.AppendHandlers(RespondWithJSON(http.StatusOK, `{"id":"123"}`))
+
+		// Send the request:
+		_, err = unlimited.Get().
+			Path("/api/clusters_mgmt/v1/clusters/123").
+			Send()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(unlimited.ByteCount()).To(BeZero())
+	})
+})
@@ -0,0 +1,174 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains tests for the support for response bodies sent with chunked transfer
+// encoding.
+
+package sdk
+
+import (
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core" // nolint
+	. "github.com/onsi/gomega"             // nolint
+
+	"github.com/onsi/gomega/ghttp"
+
+	. "github.com/openshift-online/ocm-sdk-go/testing" // nolint
+)
+
+var _ = Describe("Chunked transfer encoding", func() {
+	var (
+		server     *ghttp.Server
+		connection *Connection
+		chunked    bool
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		// Create the tokens:
+		token := MakeTokenString("Bearer", 5*time.Minute)
+
+		// Create the server:
+		server = MakeTCPServer()
+
+		// Create the connection, with a transport wrapper that checks that the responses
+		// are really using chunked encoding and don't have a content length:
+		chunked = false
+		connection, err = NewConnectionBuilder().
+			Logger(logger).
+			URL(server.URL()).
+			Tokens(token).
+			TransportWrapper(func(transport http.RoundTripper) http.RoundTripper {
+				return TransportFunc(func(request *http.Request) (*http.Response, error) {
+					response, err := transport.RoundTrip(request)
+					if err == nil {
+						chunked = response.ContentLength == -1 &&
+							len(response.TransferEncoding) == 1 &&
+							response.TransferEncoding[0] == "chunked"
+					}
+					return response, err
+				})
+			}).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		// Close the connection:
+		err := connection.Close()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Stop the server:
+		server.Close()
+	})
+
+	// RespondWithChunks creates a handler that sends the given chunks, flushing after each of
+	// them so that the server uses chunked encoding.
+	var RespondWithChunks = func(code int, chunks ...string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(code)
+			w.(http.Flusher).Flush()
+			for _, chunk := range chunks {
+				_, err := w.Write([]byte(chunk))
+				Expect(err).ToNot(HaveOccurred())
+				w.(http.Flusher).Flush()
+			}
+		}
+	}
+
+	It("Reads list response sent in multiple chunks", func() {
+		// Prepare the server:
+		server.AppendHandlers(
+			RespondWithChunks(
+				http.StatusOK,
+				`{
+					"kind": "ClusterList",
+					"page": 1,
+					"size": 2,
+					"total": 2,
+					"items": [`,
+				`{
+						"kind": "Cluster",
+						"id": "123",
+						"name": "my"
+					},`,
+				`{
+						"kind": "Cluster",
+						"id": "456",
+						"name": "your"
+					}`,
+				`]
+				}`,
+			),
+		)
+
+		// Send the request:
+		response, err := connection.ClustersMgmt().V1().Clusters().List().Send()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(chunked).To(BeTrue())
+		Expect(response.Page()).To(Equal(1))
+		Expect(response.Size()).To(Equal(2))
+		Expect(response.Total()).To(Equal(2))
+		items := response.Items().Slice()
+		Expect(items).To(HaveLen(2))
+		Expect(items[0].ID()).To(Equal("123"))
+		Expect(items[0].Name()).To(Equal("my"))
+		Expect(items[1].ID()).To(Equal("456"))
+		Expect(items[1].Name()).To(Equal("your"))
+	})
+
+	It("Reads error response sent in multiple chunks", func() {
+		// Prepare the server:
+		server.AppendHandlers(
+			RespondWithChunks(
+				http.StatusNotFound,
+				`{
+					"kind": "Error",
+					"id": "404",`,
+				`
+					"reason": "Cluster '123' not found"
+				}`,
+			),
+		)
+
+		// Send the request:
+		response, err := connection.ClustersMgmt().V1().Clusters().Cluster("123").Get().
+			Send()
+		Expect(err).To(HaveOccurred())
+		Expect(chunked).To(BeTrue())
+		Expect(response.Status()).To(Equal(http.StatusNotFound))
+		Expect(response.Error()).ToNot(BeNil())
+		Expect(response.Error().Reason()).To(Equal("Cluster '123' not found"))
+	})
+
+	It("Accepts empty response sent with chunked encoding", func() {
+		// Prepare the server:
+		server.AppendHandlers(
+			RespondWithChunks(http.StatusOK),
+		)
+
+		// Send the request:
+		response, err := connection.ClustersMgmt().V1().Clusters().List().Send()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(chunked).To(BeTrue())
+		Expect(response.Status()).To(Equal(http.StatusOK))
+		Expect(response.Items()).To(BeNil())
+	})
+})
@@ -0,0 +1,69 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the definition of the clock interface and its default implementation.
+
+package clock
+
+import (
+	"time"
+)
+
+// Clock is the interface used by the SDK to get the current time, to measure durations and to
+// wait. The default implementation uses the real time of the system. Tests can replace it with an
+// implementation that allows them to control time, like the one provided by the testing package.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// Since returns the time elapsed since the given time.
+	Since(t time.Time) time.Duration
+
+	// Sleep pauses the calling goroutine for the given duration.
+	Sleep(d time.Duration)
+
+	// After returns a channel that receives the current time when the given duration has
+	// elapsed. This is intended for waits that also need to select on other channels, for
+	// example on the one that indicates that a context has been cancelled.
+	After(d time.Duration) <-chan time.Time
+}
+
+// Real is the clock that uses the real time of the system.
+var Real Clock = realClock{}
+
+// realClock is the implementation of the clock interface that uses the functions of the time
+// package.
+type realClock struct{}
+
+// Now is part of the implementation of the Clock interface.
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// Since is part of the implementation of the Clock interface.
+func (realClock) Since(t time.Time) time.Duration {
+	return time.Since(t)
+}
+
+// Sleep is part of the implementation of the Clock interface.
+func (realClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+// After is part of the implementation of the Clock interface.
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
@@ -19,7 +19,9 @@ limitations under the License.
 package sdk
 
 import (
+	"bytes"
 	"compress/gzip"
+	"io"
 	"net/http"
 	"time"
 
@@ -98,4 +100,103 @@ var _ = Describe("Compression", func() {
 		Expect(result.HREF()).To(Equal("/api/clusters_mgmt/v1/clusters/123"))
 		Expect(result.Name()).To(Equal("mycluster"))
 	})
+
+	It("Decompresses response body when a wrapper adds headers", func() {
+		// Create a connection with a wrapper that replaces the headers of the request:
+		token := MakeTokenString("Bearer", 5*time.Minute)
+		connection, err := NewConnectionBuilder().
+			Logger(logger).
+			URL(server.URL()).
+			Tokens(token).
+			TransportWrapper(func(next http.RoundTripper) http.RoundTripper {
+				return TransportFunc(func(request *http.Request) (*http.Response,
+					error) {
+					request = request.Clone(request.Context())
+					request.Header = request.Header.Clone()
+					request.Header.Set("X-My", "value")
+					return next.RoundTrip(request)
+				})
+			}).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err := connection.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+
+		// Prepare the server:
+		server.AppendHandlers(RespondWithGzip(`{"kind": "Cluster", "id": "123"}`))
+
+		// Send the request:
+		response, err := connection.ClustersMgmt().V1().Clusters().Cluster("123").Get().
+			Send()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.Body().ID()).To(Equal("123"))
+	})
+
+	It("Doesn't request compression when disabled", func() {
+		// Create a connection with compression disabled:
+		token := MakeTokenString("Bearer", 5*time.Minute)
+		connection, err := NewConnectionBuilder().
+			Logger(logger).
+			URL(server.URL()).
+			Tokens(token).
+			AcceptGzip(false).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err := connection.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+
+		// Prepare the server:
+		server.AppendHandlers(
+			ghttp.CombineHandlers(
+				func(w http.ResponseWriter, r *http.Request) {
+					Expect(r.Header.Values("Accept-Encoding")).To(BeEmpty())
+				},
+				RespondWithJSON(http.StatusOK, `{"kind": "Cluster", "id": "123"}`),
+			),
+		)
+
+		// Send the request:
+		response, err := connection.ClustersMgmt().V1().Clusters().Cluster("123").Get().
+			Send()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.Body().ID()).To(Equal("123"))
+	})
+
+	It("Doesn't decompress when caller sets the encoding explicitly", func() {
+		// Prepare the server:
+		server.AppendHandlers(RespondWithGzip(`{"kind": "Cluster", "id": "123"}`))
+
+		// Send the request:
+		response, err := connection.Get().
+			Path("/api/clusters_mgmt/v1/clusters/123").
+			Header("Accept-Encoding", "gzip").
+			Send()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.Header("Content-Encoding")).To(Equal("gzip"))
+		reader, err := gzip.NewReader(bytes.NewReader(response.Bytes()))
+		Expect(err).ToNot(HaveOccurred())
+		body, err := io.ReadAll(reader)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(body).To(MatchJSON(`{"kind": "Cluster", "id": "123"}`))
+	})
 })
+
+// RespondWithGzip creates a handler that responds with the given JSON body compressed with gzip.
+func RespondWithGzip(body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer GinkgoRecover()
+		Expect(r.Header.Get("Accept-Encoding")).To(Equal("gzip"))
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.WriteHeader(http.StatusOK)
+		compressor := gzip.NewWriter(w)
+		_, err := compressor.Write([]byte(body))
+		Expect(err).ToNot(HaveOccurred())
+		err = compressor.Close()
+		Expect(err).ToNot(HaveOccurred())
+	}
+}
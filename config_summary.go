@@ -0,0 +1,301 @@
+/*
+Copyright (c) 2018 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the implementation of the summary of the configuration of a connection.
+
+package sdk
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+)
+
+// Authentication types used in the configuration summary:
+const (
+	AuthTypeAnonymous         = "anonymous"
+	AuthTypeTokenSource       = "token_source"
+	AuthTypeTokenFile         = "token_file"
+	AuthTypeClientCredentials = "client_credentials"
+	AuthTypePassword          = "password"
+	AuthTypeTokens            = "tokens"
+	AuthTypeNone              = "none"
+)
+
+// ConfigSummary contains a summary of the effective configuration of a connection, intended for
+// diagnostics, for example to write it to the log when an application starts. It never contains
+// secrets: passwords, client secrets, tokens and the values of default headers are never included,
+// and user information is removed from URLs.
+type ConfigSummary struct {
+	// URL is the base URL of the API server.
+	URL string `json:"url"`
+
+	// AlternativeURLs contains the alternative URLs, indexed by path prefix.
+	AlternativeURLs map[string]string `json:"alternative_urls,omitempty"`
+
+	// TokenURL is the URL of the OpenID token endpoint.
+	TokenURL string `json:"token_url,omitempty"`
+
+	// Agent is the value of the `User-Agent` header.
+	Agent string `json:"agent"`
+
+	// AuthType is the mechanism used to obtain tokens. It is one of the AuthType... constants.
+	AuthType string `json:"auth_type"`
+
+	// ClientID is the OpenID client identifier, when the client credentials grant is used. The
+	// client secret is never included.
+	ClientID string `json:"client_id,omitempty"`
+
+	// Scopes is the list of OpenID scopes.
+	Scopes []string `json:"scopes,omitempty"`
+
+	// Insecure indicates if verification of TLS certificates is disabled.
+	Insecure bool `json:"insecure"`
+
+	// TrustedCAs is the number of trusted certificate authorities that were explicitly added.
+	TrustedCAs int `json:"trusted_cas,omitempty"`
+
+	// MinTLSVersion is the name of the minimum TLS version, or empty if the default is used.
+	MinTLSVersion string `json:"min_tls_version,omitempty"`
+
+	// CipherSuites contains the names of the TLS cipher suites, or nil if the default is used.
+	CipherSuites []string `json:"cipher_suites,omitempty"`
+
+	// DisableKeepAlives indicates if HTTP keep alives are disabled.
+	DisableKeepAlives bool `json:"disable_keep_alives"`
+
+	// AcceptGzip indicates if compressed responses are requested.
+	AcceptGzip bool `json:"accept_gzip"`
+
+	// MaxConnsPerHost is the maximum number of connections to each API server, or zero if there
+	// is no limit.
+	MaxConnsPerHost int `json:"max_conns_per_host,omitempty"`
+
+	// ByteLimit is the maximum number of bytes transferred, or zero if there is no limit.
+	ByteLimit int64 `json:"byte_limit,omitempty"`
+
+	// RetryLimit is the maximum number of retries of a request.
+	RetryLimit int `json:"retry_limit"`
+
+	// RetryInterval is the initial retry interval, for example `2s`.
+	RetryInterval string `json:"retry_interval"`
+
+	// RetryJitter is the retry interval jitter factor.
+	RetryJitter float64 `json:"retry_jitter"`
+
+	// RetryMaintenanceInterval is the retry interval used when the server is in maintenance
+	// mode, for example `30s`.
+	RetryMaintenanceInterval string `json:"retry_maintenance_interval"`
+
+	// IdempotencyKeys indicates if idempotency keys are added to requests.
+	IdempotencyKeys bool `json:"idempotency_keys"`
+
+	// DefaultHeaders contains the names of the headers added to all the requests. The values
+	// are never included.
+	DefaultHeaders []string `json:"default_headers,omitempty"`
+
+	// PathRewrites contains the path rewrite rules, in `old -> new` form.
+	PathRewrites []string `json:"path_rewrites,omitempty"`
+
+	// TransportWrappers is the number of transport wrappers added by the application.
+	TransportWrappers int `json:"transport_wrappers,omitempty"`
+
+	// WarningHandler indicates if a custom warning handler is used instead of the log.
+	WarningHandler bool `json:"warning_handler"`
+
+	// LogOnError is the status code threshold of the mode that sends to the log the details of
+	// failed requests, or zero if that mode is disabled.
+	LogOnError int `json:"log_on_error,omitempty"`
+
+	// LogOperationID indicates if the operation identifiers returned by the server are written
+	// to the log.
+	LogOperationID bool `json:"log_operation_id"`
+
+	// MetricsSubsystem is the name of the metrics subsystem, or empty if metrics are disabled.
+	MetricsSubsystem string `json:"metrics_subsystem,omitempty"`
+
+	// MetricsOptions contains the names of the optional metrics that are enabled, for example
+	// `attempts` or `caller`.
+	MetricsOptions []string `json:"metrics_options,omitempty"`
+}
+
+// ConfigSummary returns a summary of the effective configuration of the connection. It never
+// contains secrets, so it is safe to write it to the log. For example:
+//
+//	logger.Info(ctx, "Connection configuration: %s", connection.ConfigSummary())
+//
+// Note that the returned object is a copy, so changing it has no effect on the connection.
+func (c *Connection) ConfigSummary() *ConfigSummary {
+	return c.summary.copy()
+}
+
+// String returns a JSON representation of the summary, in one line.
+func (s *ConfigSummary) String() string {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Sprintf("%+v", *s)
+	}
+	return string(data)
+}
+
+// copy creates a deep copy of the summary.
+func (s *ConfigSummary) copy() *ConfigSummary {
+	result := *s
+	if s.AlternativeURLs != nil {
+		result.AlternativeURLs = make(map[string]string, len(s.AlternativeURLs))
+		for prefix, value := range s.AlternativeURLs {
+			result.AlternativeURLs[prefix] = value
+		}
+	}
+	result.Scopes = copyStrings(s.Scopes)
+	result.CipherSuites = copyStrings(s.CipherSuites)
+	result.DefaultHeaders = copyStrings(s.DefaultHeaders)
+	result.PathRewrites = copyStrings(s.PathRewrites)
+	result.MetricsOptions = copyStrings(s.MetricsOptions)
+	return &result
+}
+
+// configSummary calculates the summary of the configuration of the given connection, using also
+// the settings of the builder that aren't stored in the connection.
+func (b *ConnectionBuilder) configSummary(connection *Connection) *ConfigSummary {
+	result := &ConfigSummary{
+		URL:                      redactURL(connection.URL()),
+		TokenURL:                 redactURL(connection.TokenURL()),
+		Agent:                    connection.Agent(),
+		AuthType:                 b.authType(),
+		Scopes:                   copyStrings(connection.Scopes()),
+		Insecure:                 b.insecure,
+		TrustedCAs:               len(b.trustedCAs),
+		DisableKeepAlives:        connection.DisableKeepAlives(),
+		AcceptGzip:               b.acceptGzip,
+		MaxConnsPerHost:          connection.MaxConnsPerHost(),
+		ByteLimit:                b.byteLimit,
+		RetryLimit:               connection.RetryLimit(),
+		RetryInterval:            connection.RetryInterval().String(),
+		RetryJitter:              connection.RetryJitter(),
+		RetryMaintenanceInterval: connection.RetryMaintenanceInterval().String(),
+		IdempotencyKeys:          b.idempotencyKeys,
+		TransportWrappers:        len(b.transportWrappers),
+		WarningHandler:           b.warningHandler != nil,
+		LogOnError:               b.logOnError,
+		LogOperationID:           b.logOperationID,
+		MetricsSubsystem:         b.metricsSubsystem,
+	}
+	if result.AuthType == AuthTypeClientCredentials {
+		result.ClientID, _ = connection.Client()
+	}
+	alternativeURLs := connection.AlternativeURLs()
+	if len(alternativeURLs) > 0 {
+		result.AlternativeURLs = map[string]string{}
+		for prefix, value := range alternativeURLs {
+			result.AlternativeURLs[prefix] = redactURL(value)
+		}
+	}
+	if b.minTLSVersion != 0 {
+		result.MinTLSVersion = tls.VersionName(b.minTLSVersion)
+	}
+	for _, suite := range b.cipherSuites {
+		result.CipherSuites = append(result.CipherSuites, tls.CipherSuiteName(suite))
+	}
+	for _, header := range b.defaultHeaders {
+		result.DefaultHeaders = append(result.DefaultHeaders, header[0])
+	}
+	for _, rule := range b.pathRewrites {
+		result.PathRewrites = append(result.PathRewrites, rule[0]+" -> "+rule[1])
+	}
+	if b.metricsSubsystem != "" {
+		result.MetricsOptions = b.metricsOptions()
+	}
+	return result
+}
+
+// authType returns the mechanism that will be used to obtain tokens, checking the settings in the
+// same order that the authentication wrapper does.
+func (b *ConnectionBuilder) authType() string {
+	switch {
+	case b.anonymous:
+		return AuthTypeAnonymous
+	case b.tokenSource != nil:
+		return AuthTypeTokenSource
+	case b.tokenFile != "":
+		return AuthTypeTokenFile
+	case b.clientID != "" && b.clientSecret != "":
+		return AuthTypeClientCredentials
+	case b.user != "" && b.password != "":
+		return AuthTypePassword
+	case len(b.tokens) > 0:
+		return AuthTypeTokens
+	default:
+		return AuthTypeNone
+	}
+}
+
+// metricsOptions returns the sorted names of the optional metrics that are enabled.
+func (b *ConnectionBuilder) metricsOptions() []string {
+	flags := map[string]bool{
+		"redirects":      b.metricsRedirects,
+		"redirect_hops":  b.metricsRedirectHops,
+		"attempts":       b.metricsAttempts,
+		"body_read":      b.metricsBodyRead,
+		"open_metrics":   b.metricsOpenMetrics,
+		"outcome":        b.metricsOutcome,
+		"caller":         b.metricsCaller,
+		"idempotent":     b.metricsIdempotent,
+		"dns":            b.metricsDNS,
+		"tls_resumption": b.metricsTLS || b.metricsTLSThreshold > 0,
+		"bytes":          b.metricsBytes,
+		"connections":    b.metricsConnections,
+		"decode_errors":  b.metricsDecodeErrors,
+		"organization":   b.metricsOrg,
+		"exclusive":      b.metricsExclusive,
+		"series_count":   b.metricsSeriesCount,
+		"flush_interval": b.metricsFlush > 0,
+	}
+	var result []string
+	for name, enabled := range flags {
+		if enabled {
+			result = append(result, name)
+		}
+	}
+	for _, label := range b.metricsLabels {
+		result = append(result, "label:"+label)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// redactURL removes the user information from the given URL. If the URL can't be parsed it is returned
+// unchanged, as in that case the connection would have failed to build.
+func redactURL(text string) string {
+	parsed, err := url.Parse(text)
+	if err != nil || parsed.User == nil {
+		return text
+	}
+	parsed.User = nil
+	return parsed.String()
+}
+
+// copyStrings returns a copy of the given slice, or nil if it is empty.
+func copyStrings(values []string) []string {
+	if len(values) == 0 {
+		return nil
+	}
+	result := make([]string, len(values))
+	copy(result, values)
+	return result
+}
@@ -36,12 +36,14 @@ import (
 	"github.com/openshift-online/ocm-sdk-go/authorizations"
 	"github.com/openshift-online/ocm-sdk-go/clustersmgmt"
 	"github.com/openshift-online/ocm-sdk-go/configuration"
+	"github.com/openshift-online/ocm-sdk-go/headers"
 	"github.com/openshift-online/ocm-sdk-go/internal"
 	"github.com/openshift-online/ocm-sdk-go/jobqueue"
 	"github.com/openshift-online/ocm-sdk-go/logging"
 	"github.com/openshift-online/ocm-sdk-go/metrics"
 	"github.com/openshift-online/ocm-sdk-go/osdfleetmgmt"
 	"github.com/openshift-online/ocm-sdk-go/retry"
+	"github.com/openshift-online/ocm-sdk-go/rewrite"
 	"github.com/openshift-online/ocm-sdk-go/servicelogs"
 	"github.com/openshift-online/ocm-sdk-go/servicemgmt"
 	"github.com/openshift-online/ocm-sdk-go/statusboard"
@@ -71,7 +73,12 @@ type ConnectionBuilder struct {
 	logger            logging.Logger
 	trustedCAs        []interface{}
 	insecure          bool
+	minTLSVersion     uint16
+	cipherSuites      []uint16
 	disableKeepAlives bool
+	acceptGzip        bool
+	byteLimit         int64
+	maxConnsPerHost   int
 	tokenURL          string
 	clientID          string
 	clientSecret      string
@@ -80,15 +87,46 @@ type ConnectionBuilder struct {
 	user              string
 	password          string
 	tokens            []string
+	tokenFile         string
+	tokenSource       authentication.TokenSource
+	anonymous         bool
 	scopes            []string
 	retryLimit        int
 	retryInterval     time.Duration
 	retryJitter       float64
+	retryMaintenance  retry.MaintenanceMatcher
+	retryPause        time.Duration
+	idempotencyKeys   bool
+	defaultHeaders    [][2]string
+	pathRewrites      [][2]string
 	transportWrappers []func(http.RoundTripper) http.RoundTripper
+	warningHandler    WarningHandler
+	logOnError        int
+	logOperationID    bool
 
 	// Metrics:
-	metricsSubsystem  string
-	metricsRegisterer prometheus.Registerer
+	metricsSubsystem    string
+	metricsRegisterer   prometheus.Registerer
+	metricsRedirects    bool
+	metricsRedirectHops bool
+	metricsAttempts     bool
+	metricsBodyRead     bool
+	metricsBodyTimeout  time.Duration
+	metricsOpenMetrics  bool
+	metricsOutcome      bool
+	metricsCaller       bool
+	metricsIdempotent   bool
+	metricsDNS          bool
+	metricsTLS          bool
+	metricsTLSThreshold float64
+	metricsLabels       []string
+	metricsBytes        bool
+	metricsDecodeErrors bool
+	metricsOrg          bool
+	metricsExclusive    bool
+	metricsConnections  bool
+	metricsSeriesCount  bool
+	metricsFlush        time.Duration
 
 	// Error detected while populating the builder. Once set calls to methods to
 	// set other builder parameters will be ignored and the Build method will
@@ -111,10 +149,13 @@ type Connection struct {
 	clientSelector *internal.ClientSelector
 	urlTable       []urlTableEntry
 	agent          string
+	byteLimit      *byteLimitTransportWrapper
+	summary        *ConfigSummary
 
 	// Metrics:
 	metricsSubsystem  string
 	metricsRegisterer prometheus.Registerer
+	metricsWrapper    *metrics.TransportWrapper
 }
 
 // urlTableEntry is used to store one entry of the table that contains the correspondence between
@@ -135,7 +176,9 @@ func NewConnectionBuilder() *ConnectionBuilder {
 		retryLimit:        retry.DefaultLimit,
 		retryInterval:     retry.DefaultInterval,
 		retryJitter:       retry.DefaultJitter,
+		retryPause:        retry.DefaultMaintenanceInterval,
 		metricsRegisterer: prometheus.DefaultRegisterer,
+		acceptGzip:        true,
 	}
 }
 
@@ -354,6 +397,56 @@ func (b *ConnectionBuilder) Tokens(tokens ...string) *ConnectionBuilder {
 	return b
 }
 
+// TokenFile sets the name of a file that contains the access token that will be used to
+// authenticate. The connection checks the file before sending each request and loads the token
+// again when the file changes, so that long lived connections pick up rotated tokens without
+// having to be created again. If the file can't be read later, or it is empty, the connection will
+// keep using the last token that it loaded successfully.
+func (b *ConnectionBuilder) TokenFile(value string) *ConnectionBuilder {
+	if b.err != nil {
+		return b
+	}
+	b.tokenFile = value
+	return b
+}
+
+// TokenSource sets an object that will be used to obtain the access tokens, for example from a
+// secrets vault or from the metadata service of a cloud provider. The connection calls the source
+// when it needs the first token, and again when the token is about to expire. It isn't possible to
+// use a token source and provide tokens or credentials at the same time.
+func (b *ConnectionBuilder) TokenSource(value authentication.TokenSource) *ConnectionBuilder {
+	if b.err != nil {
+		return b
+	}
+	b.tokenSource = value
+	return b
+}
+
+// Anonymous indicates that the connection will not send any authorization header. By default the
+// Build method returns an error when no token or credentials have been provided, because in most
+// cases that is a mistake that would result in all requests being rejected by the server. Use this
+// for the rare cases where the server doesn't require authentication. It isn't possible to enable
+// this and provide tokens or credentials at the same time.
+func (b *ConnectionBuilder) Anonymous(flag bool) *ConnectionBuilder {
+	if b.err != nil {
+		return b
+	}
+	b.anonymous = flag
+	return b
+}
+
+// WarningHandler sets the function that will be called when the server sends warnings in the
+// `Warning` or `Deprecation` headers of responses. The server uses these headers to give advance
+// notice of deprecated endpoints and fields, so it is good practice to pay attention to them. The
+// default is to write these warnings to the log.
+func (b *ConnectionBuilder) WarningHandler(value WarningHandler) *ConnectionBuilder {
+	if b.err != nil {
+		return b
+	}
+	b.warningHandler = value
+	return b
+}
+
 // TrustedCAs sets the certificate pool that contains the certificate authorities that will be
 // trusted by the connection. If this isn't explicitly specified then the client will trust the
 // certificate authorities trusted by default by the system.
@@ -377,7 +470,9 @@ func (b *ConnectionBuilder) TrustedCAFile(value string) *ConnectionBuilder {
 }
 
 // Insecure enables insecure communication with the server. This disables verification of TLS
-// certificates and host names and it isn't recommended for a production environment.
+// certificates and host names and it isn't recommended for a production environment. To make sure
+// that this isn't enabled accidentally a warning will be written to the log each time that a
+// connection is created with this enabled.
 func (b *ConnectionBuilder) Insecure(flag bool) *ConnectionBuilder {
 	if b.err != nil {
 		return b
@@ -386,6 +481,32 @@ func (b *ConnectionBuilder) Insecure(flag bool) *ConnectionBuilder {
 	return b
 }
 
+// MinTLSVersion sets the minimum TLS version that will be accepted when connecting to the API and
+// OpenID servers, for example tls.VersionTLS13. To prevent insecure configurations the Build
+// method returns an error if it is less than TLS 1.2. The default is to use the default of the Go
+// TLS library.
+func (b *ConnectionBuilder) MinTLSVersion(value uint16) *ConnectionBuilder {
+	if b.err != nil {
+		return b
+	}
+	b.minTLSVersion = value
+	return b
+}
+
+// CipherSuites sets the list of cipher suites that will be used for TLS 1.2 connections to the API
+// and OpenID servers. Note that the Go TLS library doesn't allow configuring the cipher suites of
+// TLS 1.3, so use MinTLSVersion(tls.VersionTLS12) together with this to make sure that only the
+// given cipher suites can be used. The Build method returns an error if any of the cipher suites
+// isn't supported or is considered insecure by the Go TLS library. The default is to use the
+// cipher suites selected by the Go TLS library.
+func (b *ConnectionBuilder) CipherSuites(values ...uint16) *ConnectionBuilder {
+	if b.err != nil {
+		return b
+	}
+	b.cipherSuites = append(b.cipherSuites, values...)
+	return b
+}
+
 // DisableKeepAlives disables HTTP keep-alives with the server. This is unrelated to similarly
 // named TCP keep-alives.
 func (b *ConnectionBuilder) DisableKeepAlives(flag bool) *ConnectionBuilder {
@@ -396,6 +517,83 @@ func (b *ConnectionBuilder) DisableKeepAlives(flag bool) *ConnectionBuilder {
 	return b
 }
 
+// AcceptGzip enables or disables compression of responses. When enabled the connection adds the
+// `Accept-Encoding: gzip` header to requests and transparently decompresses the responses that the
+// server compresses. When disabled that header isn't added and responses are never decompressed.
+// The default is to enable compression.
+//
+// This is done by a wrapper placed immediately before the HTTP transport, after the wrappers added
+// with the TransportWrapper method, so it works the same regardless of what those wrappers do.
+// Note that if the request already has an `Accept-Encoding` header when it reaches that point,
+// for example because it was added explicitly by the caller or by a wrapper, then it is assumed
+// that the caller wants to handle the encoding, and the response is returned as is, without
+// decompressing it.
+func (b *ConnectionBuilder) AcceptGzip(flag bool) *ConnectionBuilder {
+	if b.err != nil {
+		return b
+	}
+	b.acceptGzip = flag
+	return b
+}
+
+// ByteLimit sets the maximum number of bytes that the connection can transfer in the bodies of
+// requests and responses. Once the limit is reached requests will fail with a *ByteLimitError
+// till the count is set to zero with the ResetByteCount method of the connection. Requests that
+// are in progress when the limit is reached aren't interrupted, so the total can be a bit larger
+// than the limit. The bytes are counted as they are sent and received, before decompressing the
+// responses, and include the bytes of retries. The headers aren't included. The default is zero,
+// which means that there is no limit and that the bytes aren't counted.
+func (b *ConnectionBuilder) ByteLimit(value int64) *ConnectionBuilder {
+	if b.err != nil {
+		return b
+	}
+	b.byteLimit = value
+	return b
+}
+
+// MaxConnsPerHost sets the maximum number of connections that the connection will open to each API
+// server, including the connections that are being dialed, in use and idle. When the limit is
+// reached new requests block till one of the connections is available, instead of opening more
+// connections. This is independent of the idle connection pool settings. Note that the connections
+// used to request tokens aren't limited. The default is zero, which means that there is no limit.
+func (b *ConnectionBuilder) MaxConnsPerHost(value int) *ConnectionBuilder {
+	if b.err != nil {
+		return b
+	}
+	b.maxConnsPerHost = value
+	return b
+}
+
+// LogOnError enables a logging mode that collects the details of each request and response in
+// memory, and sends them to the log, in warning level, only when the request fails. A request
+// fails when there is a transport error, for example when the server can't be reached, or when the
+// response status code is greater than or equal to the given threshold. For successful requests
+// the details are discarded, and the response body isn't read. This is intended for production
+// environments where debug logging is too verbose but detail is needed when something goes wrong.
+// Note that when the debug level of the logger is enabled the details of all requests are already
+// sent to the log, so this has no effect. The default is zero, which disables this mode.
+func (b *ConnectionBuilder) LogOnError(threshold int) *ConnectionBuilder {
+	if b.err != nil {
+		return b
+	}
+	b.logOnError = threshold
+	return b
+}
+
+// LogOperationID enables a mode that writes to the log, in info level, the operation identifier
+// that the server returns in the `X-Operation-ID` header of each response, together with the
+// method, URL and status code of the request. This is useful for applications that need to give
+// the support team the server side identifier of failed requests. To get the identifier of a
+// specific request use the CaptureOperationID function instead. The default is to not write
+// these identifiers to the log.
+func (b *ConnectionBuilder) LogOperationID(flag bool) *ConnectionBuilder {
+	if b.err != nil {
+		return b
+	}
+	b.logOperationID = flag
+	return b
+}
+
 // RetryLimit sets the maximum number of retries for a request. When this is zero no retries will be
 // performed. The default value is two.
 func (b *ConnectionBuilder) RetryLimit(value int) *ConnectionBuilder {
@@ -430,6 +628,74 @@ func (b *ConnectionBuilder) RetryJitter(value float64) *ConnectionBuilder {
 	return b
 }
 
+// RetryMaintenanceMatcher sets the function used to detect the responses that indicate that the
+// server is in maintenance mode, for example retry.DefaultMaintenanceMatcher, which detects 503
+// responses whose body mentions maintenance. GET requests and requests that have an idempotency
+// key are then retried waiting the maintenance interval instead of the normal one, and if the
+// server is still in maintenance after the last retry the request fails with an error that wraps
+// retry.ErrMaintenance. Other requests are retried as usual for the status code of the response.
+// The default is to not detect maintenance mode.
+func (b *ConnectionBuilder) RetryMaintenanceMatcher(
+	value retry.MaintenanceMatcher) *ConnectionBuilder {
+	if b.err != nil {
+		return b
+	}
+	b.retryMaintenance = value
+	return b
+}
+
+// RetryMaintenanceInterval sets the time to wait before retrying a request that failed because
+// the server is in maintenance mode. This is intended to avoid adding load to the server during
+// planned maintenance. The default value is 30 seconds.
+func (b *ConnectionBuilder) RetryMaintenanceInterval(value time.Duration) *ConnectionBuilder {
+	if b.err != nil {
+		return b
+	}
+	b.retryPause = value
+	return b
+}
+
+// IdempotencyKeys enables adding the `Idempotency-Key` header to POST requests, so that the server
+// can detect and discard duplicates. The key is generated randomly for each request, unless one is
+// provided explicitly with the headers.ContextWithIdempotencyKey function or in the header of the
+// request. The key stays the same when the request is retried, and requests that have a key are
+// retried also when they fail with any 5xx response code, not only 429 and 503. The default is
+// to not add idempotency keys.
+func (b *ConnectionBuilder) IdempotencyKeys(flag bool) *ConnectionBuilder {
+	if b.err != nil {
+		return b
+	}
+	b.idempotencyKeys = flag
+	return b
+}
+
+// DefaultHeader adds a header that will be added to all the requests sent by the connection. The
+// header is only added when the request doesn't already contain a header with the same name, so
+// the value can be overridden for specific requests using the Header method of the request. This
+// method can be called multiple times to add multiple headers, or multiple values for the same
+// header. Headers managed by the SDK, like `Authorization` or `Idempotency-Key`, can't be used.
+func (b *ConnectionBuilder) DefaultHeader(name, value string) *ConnectionBuilder {
+	if b.err != nil {
+		return b
+	}
+	b.defaultHeaders = append(b.defaultHeaders, [2]string{name, value})
+	return b
+}
+
+// RewritePath adds a rule that replaces the given old path prefix with the given new one in all
+// the requests sent by the connection. This is intended to keep working code that still uses
+// deprecated paths while it is migrated. The method and the query of the requests aren't changed,
+// and each rewrite is written to the log. Note that the alternative URL used for a request is
+// selected according to the original path, before the rewrite. See the documentation of the
+// rewrite package for details.
+func (b *ConnectionBuilder) RewritePath(from, to string) *ConnectionBuilder {
+	if b.err != nil {
+		return b
+	}
+	b.pathRewrites = append(b.pathRewrites, [2]string{from, to})
+	return b
+}
+
 // TransportWrapper allows setting a transport layer into the connection for capturing and
 // manipulating the request or response.
 func (b *ConnectionBuilder) TransportWrapper(value TransportWrapper) *ConnectionBuilder {
@@ -453,6 +719,7 @@ func (b *ConnectionBuilder) TransportWrapper(value TransportWrapper) *Connection
 //	api_outbound_token_request_duration_sum - Total time to send token requests, in seconds.
 //	api_outbound_token_request_duration_count - Total number of token requests measured.
 //	api_outbound_token_request_duration_bucket - Number of token requests organized in buckets.
+//	api_outbound_token_acquisition_failure_total - Number of failures to obtain a token.
 //
 // The duration buckets metrics contain an `le` label that indicates the upper bound. For example if
 // the `le` label is `1` then the value will be the number of requests that were processed in less
@@ -487,6 +754,11 @@ func (b *ConnectionBuilder) TransportWrapper(value TransportWrapper) *Connection
 // code, for example if it wasn't possible to open the connection, or if there was a timeout waiting
 // for the response.
 //
+// The token acquisition failure metric contains a `reason` label that is `network` when the token
+// server can't be reached, `invalid_grant` when it rejects the credentials, `expired_refresh`
+// when the refresh token is expired and there are no credentials to request a new one, `server`
+// when it responds with a 5xx code and `other` for the rest of the failures.
+//
 // Note that setting this attribute is not enough to have metrics published, you also need to
 // create and start a metrics server, as described in the documentation of the Prometheus library.
 func (b *ConnectionBuilder) MetricsSubsystem(value string) *ConnectionBuilder {
@@ -512,6 +784,325 @@ func (b *ConnectionBuilder) MetricsRegisterer(value prometheus.Registerer) *Conn
 	return b
 }
 
+// MetricsRedirects enables the metric that counts the redirects followed by the connection. For
+// example, if the subsystem is `api_outbound` then the following metric will be registered:
+//
+//	api_outbound_redirect_count - Number of redirects followed.
+//
+// This metric has the same labels than the API request metrics, calculated from the original
+// request, except the `code` label that contains the code of the redirect response. The default is
+// to not generate this metric. Note that this has no effect unless the metrics subsystem is set.
+func (b *ConnectionBuilder) MetricsRedirects(flag bool) *ConnectionBuilder {
+	if b.err != nil {
+		return b
+	}
+	b.metricsRedirects = flag
+	return b
+}
+
+// MetricsRedirectHops adds to the redirect count metric a `hops` label that contains the position
+// of the redirect in the chain of redirects followed for the original request. Note that this has
+// no effect unless the redirect count metric is enabled with the MetricsRedirects method.
+func (b *ConnectionBuilder) MetricsRedirectHops(flag bool) *ConnectionBuilder {
+	if b.err != nil {
+		return b
+	}
+	b.metricsRedirectHops = flag
+	return b
+}
+
+// MetricsAttempts adds to the request count and duration metrics an `attempt` label that contains
+// the attempt number, 1 for the first attempt, 2 for the first retry, and so on. Note that when
+// this is enabled each attempt is measured separately, so retried requests will be counted once
+// for each attempt, instead of once in total. This is useful to distinguish the latency of first
+// attempts from the latency of retries.
+func (b *ConnectionBuilder) MetricsAttempts(flag bool) *ConnectionBuilder {
+	if b.err != nil {
+		return b
+	}
+	b.metricsAttempts = flag
+	return b
+}
+
+// MetricsBodyReadDuration enables the metric that measures the time spent reading response
+// bodies, from the moment that the response headers are received till the moment that the body is
+// closed. For example, if the subsystem is `api_outbound` then the following metric will be
+// registered:
+//
+//	api_outbound_body_read_duration - Response body read duration in seconds.
+//
+// This metric has the same labels as the request duration metric. The request duration metric
+// only covers the time till the response headers are received, so this is useful to find out if
+// latency is caused by the server or by the code that consumes the responses. The default is to
+// not generate this metric. Note that this has no effect unless the metrics subsystem is set.
+func (b *ConnectionBuilder) MetricsBodyReadDuration(flag bool) *ConnectionBuilder {
+	if b.err != nil {
+		return b
+	}
+	b.metricsBodyRead = flag
+	return b
+}
+
+// MetricsBodyReadTimeout sets the maximum time that the metrics will wait for response bodies to be
+// closed. Bodies that aren't closed in time are counted in the following metric, assuming that the
+// subsystem is `api_outbound`:
+//
+//	api_outbound_body_read_timeout_total - Number of response bodies that weren't closed in time.
+//
+// When the body read duration metric is enabled the timeout is recorded as the duration of those
+// bodies. The default is zero, which means that there is no timeout. Note that this has no effect
+// unless the metrics subsystem is set.
+func (b *ConnectionBuilder) MetricsBodyReadTimeout(value time.Duration) *ConnectionBuilder {
+	if b.err != nil {
+		return b
+	}
+	b.metricsBodyTimeout = value
+	return b
+}
+
+// MetricsOutcome adds to the request count and duration metrics an `outcome` label that contains
+// `success`, `client_error`, `server_error` or `transport_error`. Requests that fail without a
+// response, for example because it isn't possible to connect to the server, have the
+// `transport_error` outcome. This simplifies the calculation of success rates. For example, if
+// the subsystem is `api_outbound` then the success rate for each API service can be calculated
+// with a Prometheus expression like this:
+//
+//	sum by (apiservice) (rate(api_outbound_request_count{outcome="success"}[10m])) /
+//	sum by (apiservice) (rate(api_outbound_request_count[10m]))
+//
+// The default is to not add this label. Note that this has no effect unless the metrics subsystem
+// is set.
+func (b *ConnectionBuilder) MetricsOutcome(flag bool) *ConnectionBuilder {
+	if b.err != nil {
+		return b
+	}
+	b.metricsOutcome = flag
+	return b
+}
+
+// MetricsCaller adds to the request count and duration metrics a `caller` label that contains the
+// package of the program that sent the request, calculated walking the stack. This is useful to
+// find out which part of a large program sends most of the requests. The default is to not add
+// this label, because walking the stack for each request has a cost. Note that this has no effect
+// unless the metrics subsystem is set.
+func (b *ConnectionBuilder) MetricsCaller(flag bool) *ConnectionBuilder {
+	if b.err != nil {
+		return b
+	}
+	b.metricsCaller = flag
+	return b
+}
+
+// MetricsIdempotent adds to the request count and duration metrics an `idempotent` label that is
+// `true` when the method of the request is idempotent, like GET, HEAD, PUT and DELETE, and `false`
+// otherwise, like POST and PATCH. This is useful to know how much of the write traffic can't be
+// safely retried. The default is to not add this label. Note that this has no effect unless the
+// metrics subsystem is set.
+func (b *ConnectionBuilder) MetricsIdempotent(flag bool) *ConnectionBuilder {
+	if b.err != nil {
+		return b
+	}
+	b.metricsIdempotent = flag
+	return b
+}
+
+// MetricsDNS enables the metrics that measure the DNS lookups done to open new connections. For
+// example, if the subsystem is `api_outbound` then the following metrics will be generated:
+//
+//	api_outbound_dns_lookup_count - Number of new connections, with a `cached` label.
+//	api_outbound_dns_lookup_duration - Histogram of the time spent in DNS lookups.
+//
+// The `cached` label is `true` when a new connection was opened without a DNS lookup done by the
+// transport, for example because the dialer uses an in-process DNS cache. This is useful to find
+// out if such a cache is effective. The default is to not generate these metrics. Note that this
+// has no effect unless the metrics subsystem is set.
+func (b *ConnectionBuilder) MetricsDNS(flag bool) *ConnectionBuilder {
+	if b.err != nil {
+		return b
+	}
+	b.metricsDNS = flag
+	return b
+}
+
+// MetricsTLSResumption enables the metric that counts the TLS handshakes done to open new
+// connections. For example, if the subsystem is `api_outbound` then the following metric will be
+// generated:
+//
+//	api_outbound_tls_handshake_count - Number of TLS handshakes, with a `resumed` label.
+//
+// The `resumed` label is `true` when the handshake resumed a previous TLS session. This is useful
+// to detect regressions that disable session resumption and increase latency. The default is to
+// not generate this metric. Note that this has no effect unless the metrics subsystem is set.
+func (b *ConnectionBuilder) MetricsTLSResumption(flag bool) *ConnectionBuilder {
+	if b.err != nil {
+		return b
+	}
+	b.metricsTLS = flag
+	return b
+}
+
+// MetricsTLSResumptionThreshold sets the minimum ratio, between zero and one, of TLS handshakes
+// that are expected to resume a session. When the ratio of a window of 100 handshakes is below
+// this value a warning will be written to the log. This implies the MetricsTLSResumption option.
+// The default is zero, which means that there is no threshold. Note that this has no effect
+// unless the metrics subsystem is set.
+func (b *ConnectionBuilder) MetricsTLSResumptionThreshold(value float64) *ConnectionBuilder {
+	if b.err != nil {
+		return b
+	}
+	b.metricsTLSThreshold = value
+	return b
+}
+
+// MetricsSeriesCount enables a gauge that contains the number of series generated by the request
+// metrics. For example, if the subsystem is `api_outbound` then the following metric will be
+// generated:
+//
+//	api_outbound_metric_series_count - Number of series generated by the request metrics.
+//
+// This is useful to budget the memory used by Prometheus and to detect unexpected growth of the
+// cardinality of the labels. The default is to not generate this metric. Note that this has no
+// effect unless the metrics subsystem is set.
+func (b *ConnectionBuilder) MetricsSeriesCount(flag bool) *ConnectionBuilder {
+	if b.err != nil {
+		return b
+	}
+	b.metricsSeriesCount = flag
+	return b
+}
+
+// MetricsFlushInterval enables a mode where the request count and duration metrics are accumulated
+// in memory and added to the Prometheus collectors periodically with the given interval, instead
+// of once per request. This reduces contention in applications that send a very high number of
+// concurrent requests, at the cost of metrics that can be up to one interval behind. The pending
+// metrics are added when the connection is closed. See the FlushInterval method of the metrics
+// transport wrapper for details. The default is zero, which means that metrics are updated
+// directly. Note that this has no effect unless the metrics subsystem is set.
+func (b *ConnectionBuilder) MetricsFlushInterval(value time.Duration) *ConnectionBuilder {
+	if b.err != nil {
+		return b
+	}
+	b.metricsFlush = value
+	return b
+}
+
+// MetricsConnections enables a gauge that contains the number of connections currently open to the
+// API servers. For example, if the subsystem is `api_outbound` then the following metric will be
+// generated:
+//
+//	api_outbound_open_connections - Number of open connections, with a `host` label.
+//
+// The `host` label contains the host and port of the server, or the path of the socket when using
+// Unix sockets. This is useful together with the MaxConnsPerHost method, to find out if the limit
+// is reached. The default is to not generate this metric. Note that this has no effect unless the
+// metrics subsystem is set.
+func (b *ConnectionBuilder) MetricsConnections(flag bool) *ConnectionBuilder {
+	if b.err != nil {
+		return b
+	}
+	b.metricsConnections = flag
+	return b
+}
+
+// MetricsContextLabels declares labels that will be added to the request count and duration
+// metrics, with the values taken from the context of each request. The values are set with the
+// metrics.WithLabels function. For example:
+//
+//	connection, err := sdk.NewConnectionBuilder().
+//		...
+//		MetricsSubsystem("api_outbound").
+//		MetricsContextLabels("job").
+//		Build()
+//	...
+//	ctx = metrics.WithLabels(ctx, map[string]string{
+//		"job": "sync",
+//	})
+//	response, err := connection.ClustersMgmt().V1().Clusters().List().SendContext(ctx)
+//
+// Labels in the context that haven't been declared with this method are ignored. The default is
+// to not add any of these labels. Note that this has no effect unless the metrics subsystem is
+// set.
+func (b *ConnectionBuilder) MetricsContextLabels(names ...string) *ConnectionBuilder {
+	if b.err != nil {
+		return b
+	}
+	b.metricsLabels = append(b.metricsLabels, names...)
+	return b
+}
+
+// MetricsOpenMetrics selects the naming convention of the OpenMetrics specification for the
+// metrics. When enabled the names of counters will have the `_total` suffix, for example
+// `api_outbound_request_count_total`, and the names of duration histograms will always have the
+// unit suffix, for example `api_outbound_request_duration_seconds`. The default is to use the
+// original names. Note that this has no effect unless the metrics subsystem is set.
+func (b *ConnectionBuilder) MetricsOpenMetrics(flag bool) *ConnectionBuilder {
+	if b.err != nil {
+		return b
+	}
+	b.metricsOpenMetrics = flag
+	return b
+}
+
+// MetricsBytes enables the metrics that count the bytes transferred in the bodies of requests and
+// responses. For example, if the subsystem is `api_outbound` then the following metrics will be
+// generated:
+//
+//	api_outbound_bytes_sent_total - Number of bytes sent in request bodies.
+//	api_outbound_bytes_received_total - Number of bytes received in response bodies.
+//
+// Note that the bytes received are counted after decompressing the responses. The default is to
+// not generate these metrics. Note that this has no effect unless the metrics subsystem is set.
+func (b *ConnectionBuilder) MetricsBytes(flag bool) *ConnectionBuilder {
+	if b.err != nil {
+		return b
+	}
+	b.metricsBytes = flag
+	return b
+}
+
+// MetricsDecodeErrors enables the metric that counts the responses whose body can't be decoded.
+// For example, if the subsystem is `api_outbound` then the following metric will be generated:
+//
+//	api_outbound_response_decode_error_total - Number of response bodies that couldn't be decoded.
+//
+// The metric has the `apiservice` label and a `reason` label that is `eof`, `syntax` or `type`.
+// This is intended to detect changes in the schema of the server. The default is to not generate
+// this metric. Note that this has no effect unless the metrics subsystem is set.
+func (b *ConnectionBuilder) MetricsDecodeErrors(flag bool) *ConnectionBuilder {
+	if b.err != nil {
+		return b
+	}
+	b.metricsDecodeErrors = flag
+	return b
+}
+
+// MetricsOrganization adds to the request count and duration metrics an `organization` label
+// containing the identifier of the organization stored in the context of the request with the
+// tenancy.WithOrganization function, or `unknown` if there is no such organization. The number of
+// distinct values is limited to 100, requests from additional organizations are counted with the
+// value `other`. The default is to not add this label. Note that this has no effect unless the
+// metrics subsystem is set.
+func (b *ConnectionBuilder) MetricsOrganization(flag bool) *ConnectionBuilder {
+	if b.err != nil {
+		return b
+	}
+	b.metricsOrg = flag
+	return b
+}
+
+// MetricsExclusive makes the Build method fail if the request metrics are already registered, for
+// example by another connection that uses the same subsystem and registerer. The default is to
+// share the metrics that are already registered, which silently mixes the series of connections
+// that use the same subsystem by mistake. Note that this has no effect unless the metrics
+// subsystem is set.
+func (b *ConnectionBuilder) MetricsExclusive(flag bool) *ConnectionBuilder {
+	if b.err != nil {
+		return b
+	}
+	b.metricsExclusive = flag
+	return b
+}
+
 // Metrics sets the name of the subsystem that will be used by the connection to register metrics
 // with Prometheus.
 //
@@ -698,6 +1289,17 @@ func (b *ConnectionBuilder) BuildContext(ctx context.Context) (connection *Conne
 		b.logger.Debug(ctx, "Logger wasn't provided, will use Go log")
 	}
 
+	// Insecure communication is handy for test environments, but dangerous anywhere else, so
+	// make sure that it is visible in the log:
+	if b.insecure {
+		b.logger.Warn(
+			ctx,
+			"Verification of TLS certificates and host names is DISABLED, "+
+				"communication with the server is INSECURE, don't use this "+
+				"in production environments",
+		)
+	}
+
 	// Create the URL table:
 	urlTable, err := b.createURLTable(ctx)
 	if err != nil {
@@ -710,33 +1312,74 @@ func (b *ConnectionBuilder) BuildContext(ctx context.Context) (connection *Conne
 		agent = DefaultAgent
 	}
 
-	// Create the metrics wrapper:
+	// Create the metrics wrapper. Note that it may have started a goroutine to flush the
+	// metrics, so it needs to be closed if something else fails.
 	var metricsWrapper func(http.RoundTripper) http.RoundTripper
+	var metricsObject *metrics.TransportWrapper
+	defer func() {
+		if err != nil && metricsObject != nil {
+			metricsObject.Close()
+		}
+	}()
 	if b.metricsSubsystem != "" {
 		var parsed *url.URL
 		parsed, err = url.Parse(b.tokenURL)
 		if err != nil {
 			return
 		}
-		var wrapper *metrics.TransportWrapper
-		wrapper, err = metrics.NewTransportWrapper().
+		metricsObject, err = metrics.NewTransportWrapper().
 			Path(parsed.Path).
 			Subsystem(b.metricsSubsystem).
 			Registerer(b.metricsRegisterer).
+			Redirects(b.metricsRedirects).
+			RedirectHops(b.metricsRedirectHops).
+			Attempts(b.metricsAttempts).
+			BodyReadDuration(b.metricsBodyRead).
+			BodyReadTimeout(b.metricsBodyTimeout).
+			OpenMetrics(b.metricsOpenMetrics).
+			Outcome(b.metricsOutcome).
+			Caller(b.metricsCaller).
+			Idempotent(b.metricsIdempotent).
+			DNS(b.metricsDNS).
+			TLSResumption(b.metricsTLS).
+			TLSResumptionThreshold(b.metricsTLSThreshold).
+			Logger(b.logger).
+			ContextLabels(b.metricsLabels...).
+			Bytes(b.metricsBytes).
+			DecodeErrors(b.metricsDecodeErrors).
+			Organization(b.metricsOrg).
+			Exclusive(b.metricsExclusive).
+			SeriesCount(b.metricsSeriesCount).
+			FlushInterval(b.metricsFlush).
 			Build()
 		if err != nil {
 			return
 		}
-		metricsWrapper = wrapper.Wrap
+		metricsWrapper = metricsObject.Wrap
 	}
 
 	// Create the logging wrapper:
+	if b.logOnError != 0 && (b.logOnError < 100 || b.logOnError > 599) {
+		err = fmt.Errorf(
+			"log on error status threshold %d isn't valid, it should be zero or "+
+				"a status code between 100 and 599",
+			b.logOnError,
+		)
+		return
+	}
 	var loggingWrapper func(http.RoundTripper) http.RoundTripper
-	if b.logger.DebugEnabled() {
+	switch {
+	case b.logger.DebugEnabled():
 		wrapper := &dumpTransportWrapper{
 			logger: b.logger,
 		}
 		loggingWrapper = wrapper.Wrap
+	case b.logOnError > 0 && b.logger.WarnEnabled():
+		wrapper := &errorDumpTransportWrapper{
+			logger:    b.logger,
+			threshold: b.logOnError,
+		}
+		loggingWrapper = wrapper.Wrap
 	}
 
 	// Create the authentication wrapper:
@@ -746,9 +1389,14 @@ func (b *ConnectionBuilder) BuildContext(ctx context.Context) (connection *Conne
 		User(b.user, b.password).
 		Client(b.clientID, b.clientSecret).
 		Tokens(b.tokens...).
+		TokenFile(b.tokenFile).
+		TokenSource(b.tokenSource).
+		Anonymous(b.anonymous).
 		Scopes(b.scopes...).
 		TrustedCAs(b.trustedCAs...).
 		Insecure(b.insecure).
+		MinTLSVersion(b.minTLSVersion).
+		CipherSuites(b.cipherSuites...).
 		TransportWrapper(metricsWrapper).
 		TransportWrapper(loggingWrapper).
 		TransportWrappers(b.transportWrappers...).
@@ -765,21 +1413,153 @@ func (b *ConnectionBuilder) BuildContext(ctx context.Context) (connection *Conne
 		Limit(b.retryLimit).
 		Interval(b.retryInterval).
 		Jitter(b.retryJitter).
+		MaintenanceMatcher(b.retryMaintenance).
+		MaintenanceInterval(b.retryPause).
 		Build(ctx)
 	if err != nil {
 		return
 	}
 
+	// When attempts are measured separately the metrics wrapper needs to be wrapped by the
+	// retry wrapper, so that it can see each attempt:
+	outerMetricsWrapper := metricsWrapper
+	var innerMetricsWrapper func(http.RoundTripper) http.RoundTripper
+	if b.metricsAttempts {
+		outerMetricsWrapper, innerMetricsWrapper = nil, metricsWrapper
+	}
+
+	// Create the wrapper that extracts warnings from responses:
+	warningHandler := b.warningHandler
+	if warningHandler == nil {
+		warningHandler = logWarningHandler(b.logger)
+	}
+	warningWrapper := &warningTransportWrapper{
+		handler: warningHandler,
+	}
+
+	// Create the wrapper that extracts the operation identifier from responses. Note that it
+	// needs to be outside of the retry wrapper, so that it sees only the last attempt.
+	operationIDWrapper := &operationIDTransportWrapper{}
+	if b.logOperationID {
+		operationIDWrapper.logger = b.logger
+	}
+
+	// Create the wrapper that overrides the base URL of requests:
+	baseURLWrapper := &baseURLTransportWrapper{}
+
+	// Create the wrapper that checks the fields required by the caller. Note that it needs to be
+	// outside of the retry wrapper, as retrying wouldn't help.
+	requiredFieldsWrapper := &requiredFieldsTransportWrapper{}
+
+	// Create the wrapper that requests and decompresses compressed responses. Note that the
+	// compression support of the transport is disabled because this replaces it.
+	gzipWrapper := &gzipTransportWrapper{
+		enabled: b.acceptGzip,
+	}
+
+	// Create the wrapper that limits the number of bytes transferred. Note that it needs to be
+	// the closest to the transport so that it sees the compressed bodies.
+	var byteLimitWrapper *byteLimitTransportWrapper
+	var byteLimitWrap func(http.RoundTripper) http.RoundTripper
+	if b.byteLimit > 0 {
+		byteLimitWrapper = &byteLimitTransportWrapper{
+			limit: b.byteLimit,
+		}
+		byteLimitWrap = byteLimitWrapper.Wrap
+	}
+
+	// Create the wrapper that adds idempotency keys. Note that it needs to be outside of the
+	// retry wrapper so that the key stays the same for all the attempts.
+	var idempotencyWrapper func(http.RoundTripper) http.RoundTripper
+	if b.idempotencyKeys {
+		var wrapper *headers.IdempotencyTransportWrapper
+		wrapper, err = headers.NewIdempotencyTransportWrapper().
+			Build()
+		if err != nil {
+			return
+		}
+		idempotencyWrapper = wrapper.Wrap
+	}
+
+	// Create the wrapper that adds the default headers. Note that it needs to be outside of the
+	// authentication wrapper so that the headers managed by it can't be changed.
+	var defaultHeadersWrapper func(http.RoundTripper) http.RoundTripper
+	if len(b.defaultHeaders) > 0 {
+		builder := headers.NewDefaultTransportWrapper()
+		for _, header := range b.defaultHeaders {
+			builder.Header(header[0], header[1])
+		}
+		var wrapper *headers.DefaultTransportWrapper
+		wrapper, err = builder.Build()
+		if err != nil {
+			return
+		}
+		defaultHeadersWrapper = wrapper.Wrap
+	}
+
+	// Create the wrapper that rewrites legacy paths. Note that it needs to be outside of the
+	// metrics wrapper so that the metrics contain the new paths.
+	var rewriteWrapper func(http.RoundTripper) http.RoundTripper
+	if len(b.pathRewrites) > 0 {
+		builder := rewrite.NewTransportWrapper().
+			Logger(b.logger)
+		for _, rule := range b.pathRewrites {
+			builder.Rule(rule[0], rule[1])
+		}
+		var wrapper *rewrite.TransportWrapper
+		wrapper, err = builder.Build(ctx)
+		if err != nil {
+			return
+		}
+		rewriteWrapper = wrapper.Wrap
+	}
+
+	// Create the gauge that counts open connections:
+	var connectionsGauge *prometheus.GaugeVec
+	if b.metricsSubsystem != "" && b.metricsConnections {
+		connectionsGauge = prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Subsystem: b.metricsSubsystem,
+				Name:      "open_connections",
+				Help:      "Number of open connections.",
+			},
+			internal.ConnectionsGaugeLabels,
+		)
+		connectionsGauge, err = internal.RegisterGaugeVec(
+			b.metricsRegisterer,
+			b.metricsSubsystem+"_open_connections",
+			connectionsGauge,
+		)
+		if err != nil {
+			return
+		}
+	}
+
 	// Create the client selector:
 	clientSelector, err := internal.NewClientSelector().
 		Logger(b.logger).
 		TrustedCAs(b.trustedCAs...).
 		Insecure(b.insecure).
+		MinTLSVersion(b.minTLSVersion).
+		CipherSuites(b.cipherSuites...).
+		DisableCompression(true).
+		MaxConnsPerHost(b.maxConnsPerHost).
+		ConnectionsGauge(connectionsGauge).
+		TransportWrapper(baseURLWrapper.Wrap).
+		TransportWrapper(requiredFieldsWrapper.Wrap).
+		TransportWrapper(rewriteWrapper).
+		TransportWrapper(defaultHeadersWrapper).
 		TransportWrapper(authnWrapper.Wrap).
-		TransportWrapper(metricsWrapper).
+		TransportWrapper(warningWrapper.Wrap).
+		TransportWrapper(operationIDWrapper.Wrap).
+		TransportWrapper(outerMetricsWrapper).
+		TransportWrapper(idempotencyWrapper).
 		TransportWrapper(retryWrapper.Wrap).
+		TransportWrapper(innerMetricsWrapper).
 		TransportWrapper(loggingWrapper).
 		TransportWrappers(b.transportWrappers...).
+		TransportWrapper(gzipWrapper.Wrap).
+		TransportWrapper(byteLimitWrap).
 		Build(ctx)
 	if err != nil {
 		return
@@ -793,9 +1573,12 @@ func (b *ConnectionBuilder) BuildContext(ctx context.Context) (connection *Conne
 		clientSelector:    clientSelector,
 		urlTable:          urlTable,
 		agent:             agent,
+		byteLimit:         byteLimitWrapper,
 		metricsSubsystem:  b.metricsSubsystem,
 		metricsRegisterer: b.metricsRegisterer,
+		metricsWrapper:    metricsObject,
 	}
+	connection.summary = b.configSummary(connection)
 
 	return
 }
@@ -925,11 +1708,47 @@ func (c *Connection) Insecure() bool {
 	return c.clientSelector.Insecure()
 }
 
+// MinTLSVersion returns the minimum TLS version, or zero if the default of the Go TLS library is
+// used.
+func (c *Connection) MinTLSVersion() uint16 {
+	return c.clientSelector.MinTLSVersion()
+}
+
+// CipherSuites returns the list of TLS cipher suites, or nil if the default of the Go TLS library
+// is used.
+func (c *Connection) CipherSuites() []uint16 {
+	return c.clientSelector.CipherSuites()
+}
+
 // DisableKeepAlives returns the flag that indicates if HTTP keep alive is disabled.
 func (c *Connection) DisableKeepAlives() bool {
 	return c.clientSelector.DisableKeepAlives()
 }
 
+// MaxConnsPerHost returns the maximum number of connections to each API server, or zero if there
+// is no limit.
+func (c *Connection) MaxConnsPerHost() int {
+	return c.clientSelector.MaxConnsPerHost()
+}
+
+// ByteCount returns the number of bytes transferred in the bodies of requests and responses since
+// the connection was created or since the last call to the ResetByteCount method. It is always
+// zero if no limit was set with the ByteLimit method of the builder.
+func (c *Connection) ByteCount() int64 {
+	if c.byteLimit == nil {
+		return 0
+	}
+	return c.byteLimit.Count()
+}
+
+// ResetByteCount sets to zero the number of bytes transferred, so that requests rejected because
+// of the limit set with the ByteLimit method of the builder are accepted again.
+func (c *Connection) ResetByteCount() {
+	if c.byteLimit != nil {
+		c.byteLimit.Reset()
+	}
+}
+
 // RetryLimit gets the maximum number of retries for a request.
 func (c *Connection) RetryLimit() int {
 	return c.retryWrapper.Limit()
@@ -945,6 +1764,11 @@ func (c *Connection) RetryJitter() float64 {
 	return c.retryWrapper.Jitter()
 }
 
+// RetryMaintenanceInterval returns the retry interval used when the server is in maintenance mode.
+func (c *Connection) RetryMaintenanceInterval() time.Duration {
+	return c.retryWrapper.MaintenanceInterval()
+}
+
 // MetricsSubsystem returns the name of the subsystem that is used by the connection to register
 // metrics with Prometheus. An empty string means that no metrics are registered.
 func (c *Connection) MetricsSubsystem() string {
@@ -1039,6 +1863,14 @@ func (c *Connection) Close() error {
 		return err
 	}
 
+	// Close the metrics wrapper, so that the pending metrics are flushed:
+	if c.metricsWrapper != nil {
+		err = c.metricsWrapper.Close()
+		if err != nil {
+			return err
+		}
+	}
+
 	// Mark the connection as closed, so that further attempts to use it will fail:
 	c.closed = true
 	return nil
@@ -19,16 +19,21 @@ limitations under the License.
 package sdk
 
 import (
+	"bytes"
 	"context"
 	"net/http"
 	"os"
 	"path/filepath"
 	"time"
 
+	"github.com/onsi/gomega/ghttp"
+
 	. "github.com/onsi/ginkgo/v2/dsl/core" // nolint
 	. "github.com/onsi/gomega"             // nolint
 	. "github.com/onsi/gomega/gbytes"      // nolint
 
+	"github.com/openshift-online/ocm-sdk-go/logging"
+
 	. "github.com/openshift-online/ocm-sdk-go/testing" // nolint
 )
 
@@ -44,6 +49,97 @@ var _ = Describe("Connection", func() {
 		Expect(connection).ToNot(BeNil())
 	})
 
+	It("Can't be created without credentials", func() {
+		connection, err := NewConnectionBuilder().
+			Logger(logger).
+			Build()
+		Expect(err).To(HaveOccurred())
+		Expect(connection).To(BeNil())
+		Expect(err.Error()).To(ContainSubstring("anonymous"))
+	})
+
+	It("Can't be created anonymous with credentials", func() {
+		connection, err := NewConnectionBuilder().
+			Logger(logger).
+			Tokens(MakeTokenString("Bearer", 5*time.Minute)).
+			Anonymous(true).
+			Build()
+		Expect(err).To(HaveOccurred())
+		Expect(connection).To(BeNil())
+		Expect(err.Error()).To(ContainSubstring("anonymous"))
+	})
+
+	It("Doesn't send authorization header when anonymous", func() {
+		// Create the API server:
+		apiServer := MakeTCPServer()
+		defer apiServer.Close()
+		apiServer.AppendHandlers(
+			ghttp.CombineHandlers(
+				func(w http.ResponseWriter, r *http.Request) {
+					Expect(r.Header).ToNot(HaveKey("Authorization"))
+				},
+				RespondWithJSON(http.StatusOK, "{}"),
+			),
+		)
+
+		// Create the connection:
+		connection, err := NewConnectionBuilder().
+			Logger(logger).
+			URL(apiServer.URL()).
+			Anonymous(true).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		defer connection.Close()
+
+		// Send the request:
+		response, err := connection.Get().
+			Path("/api/clusters_mgmt/v1/clusters").
+			Send()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.Status()).To(Equal(http.StatusOK))
+	})
+
+	It("Writes warning when insecure communication is enabled", func() {
+		// Create a logger that allows us to inspect the messages written to the log:
+		var buffer bytes.Buffer
+		logger, err := logging.NewStdLoggerBuilder().
+			Streams(&buffer, &buffer).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Create the connection:
+		connection, err := NewConnectionBuilder().
+			Logger(logger).
+			Tokens(MakeTokenString("Bearer", 5*time.Minute)).
+			Insecure(true).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		defer connection.Close()
+
+		// Check the log:
+		Expect(buffer.String()).To(ContainSubstring("INSECURE"))
+	})
+
+	It("Doesn't write warning when insecure communication is disabled", func() {
+		// Create a logger that allows us to inspect the messages written to the log:
+		var buffer bytes.Buffer
+		logger, err := logging.NewStdLoggerBuilder().
+			Streams(&buffer, &buffer).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Create the connection:
+		connection, err := NewConnectionBuilder().
+			Logger(logger).
+			Tokens(MakeTokenString("Bearer", 5*time.Minute)).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		defer connection.Close()
+
+		// Check the log:
+		Expect(buffer.String()).ToNot(ContainSubstring("INSECURE"))
+	})
+
 	It("Can be created with refresh token", func() {
 		refreshToken := MakeTokenString("Refresh", 10*time.Hour)
 		connection, err := NewConnectionBuilder().
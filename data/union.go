@@ -0,0 +1,243 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the implementation of an object that decodes objects whose type is
+// indicated by the `kind` field.
+
+package data
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// UnmarshalFunc is a function that reads an object from a source that can be a slice of bytes, a
+// string or a reader. Use the Variant function to adapt the generated Unmarshal... functions.
+type UnmarshalFunc func(source interface{}) (object interface{}, err error)
+
+// Variant converts a typed unmarshal function, like the Unmarshal... functions generated for the
+// types of the model, into an UnmarshalFunc that can be registered in a union decoder.
+func Variant[T any](function func(source interface{}) (T, error)) UnmarshalFunc {
+	return func(source interface{}) (object interface{}, err error) {
+		object, err = function(source)
+		return
+	}
+}
+
+// UnionDecoderBuilder contains the data and logic needed to create a decoder for objects whose
+// type is indicated by the `kind` field. For example, to decode an object that can be a cluster
+// or a subscription:
+//
+//	decoder, err := data.NewUnionDecoder().
+//		Variant(cmv1.ClusterKind, data.Variant(cmv1.UnmarshalCluster)).
+//		Variant(amv1.SubscriptionKind, data.Variant(amv1.UnmarshalSubscription)).
+//		Build(ctx)
+//	if err != nil {
+//		...
+//	}
+//	object, err := decoder.Decode(body)
+//	if err != nil {
+//		...
+//	}
+//	switch typed := object.(type) {
+//	case *cmv1.Cluster:
+//		...
+//	case *amv1.Subscription:
+//		...
+//	}
+//
+// Links and nil references, where the kind is the name of the type followed by `Link` or `Nil`,
+// are decoded with the function registered for the type, unless a different function has been
+// explicitly registered for them.
+//
+// Don't create objects of this type directly; use the NewUnionDecoder function instead.
+type UnionDecoderBuilder struct {
+	variants map[string]UnmarshalFunc
+}
+
+// UnionDecoder knows how to decode objects whose type is indicated by the `kind` field. Don't
+// create objects of this type directly; use the NewUnionDecoder function instead.
+type UnionDecoder struct {
+	variants map[string]UnmarshalFunc
+}
+
+// NewUnionDecoder creates a builder that can then be used to configure and create a union decoder.
+func NewUnionDecoder() *UnionDecoderBuilder {
+	return &UnionDecoderBuilder{
+		variants: map[string]UnmarshalFunc{},
+	}
+}
+
+// Variant registers the function that will be used to decode objects of the given kind.
+func (b *UnionDecoderBuilder) Variant(kind string, function UnmarshalFunc) *UnionDecoderBuilder {
+	b.variants[kind] = function
+	return b
+}
+
+// Build uses the configuration stored in the builder to create a new union decoder.
+func (b *UnionDecoderBuilder) Build(ctx context.Context) (result *UnionDecoder, err error) {
+	// Check parameters:
+	if len(b.variants) == 0 {
+		err = fmt.Errorf("at least one variant is required")
+		return
+	}
+	for kind, function := range b.variants {
+		if kind == "" {
+			err = fmt.Errorf("kind of variant is mandatory")
+			return
+		}
+		if function == nil {
+			err = fmt.Errorf("function for kind '%s' is mandatory", kind)
+			return
+		}
+	}
+
+	// Create and populate the object:
+	variants := make(map[string]UnmarshalFunc, len(b.variants))
+	for kind, function := range b.variants {
+		variants[kind] = function
+	}
+	result = &UnionDecoder{
+		variants: variants,
+	}
+
+	return
+}
+
+// Decode reads the `kind` field of the object contained in the given source, which can be a slice
+// of bytes, a string or a reader, and then uses the function registered for that kind to decode
+// it. It returns an error if the object doesn't have a `kind` field or if there is no function
+// registered for it.
+func (d *UnionDecoder) Decode(source interface{}) (result interface{}, err error) {
+	data, err := unionBytes(source)
+	if err != nil {
+		return
+	}
+	result, err = d.decode(data)
+	return
+}
+
+// DecodeList decodes a list of objects of different kinds. The source can be a JSON array, or an
+// object with an `items` field that contains the array, like the list responses returned by the
+// server. Each item is decoded as explained for the Decode method.
+func (d *UnionDecoder) DecodeList(source interface{}) (results []interface{}, err error) {
+	data, err := unionBytes(source)
+	if err != nil {
+		return
+	}
+	if jsoniter.Get(data).ValueType() == jsoniter.ObjectValue {
+		items := jsoniter.Get(data, "items")
+		if items.ValueType() != jsoniter.ArrayValue {
+			err = fmt.Errorf("list object doesn't have an 'items' array")
+			return
+		}
+		data = []byte(items.ToString())
+	}
+	iterator := jsoniter.ParseBytes(jsoniter.ConfigDefault, data)
+	if iterator.WhatIsNext() != jsoniter.ArrayValue {
+		err = fmt.Errorf("expected array or list object")
+		return
+	}
+	results = []interface{}{}
+	for i := 0; iterator.ReadArray(); i++ {
+		item := iterator.SkipAndReturnBytes()
+		if iterator.Error != nil {
+			break
+		}
+		var result interface{}
+		result, err = d.decode(item)
+		if err != nil {
+			err = fmt.Errorf("can't decode item %d: %w", i, err)
+			results = nil
+			return
+		}
+		results = append(results, result)
+	}
+	if iterator.Error != nil && iterator.Error != io.EOF {
+		err = fmt.Errorf("can't read list: %w", iterator.Error)
+		results = nil
+	}
+	return
+}
+
+// decode decodes a single object using the function registered for its kind.
+func (d *UnionDecoder) decode(data []byte) (result interface{}, err error) {
+	value := jsoniter.Get(data, "kind")
+	if value.ValueType() != jsoniter.StringValue {
+		err = fmt.Errorf("object doesn't have a 'kind' string field")
+		return
+	}
+	kind := value.ToString()
+	function := d.lookup(kind)
+	if function == nil {
+		err = fmt.Errorf("kind '%s' isn't supported", kind)
+		return
+	}
+	result, err = function(data)
+	if err != nil {
+		err = fmt.Errorf("can't decode object of kind '%s': %w", kind, err)
+	}
+	return
+}
+
+// lookup returns the function for the given kind, falling back to the function of the type for
+// links and nil references.
+func (d *UnionDecoder) lookup(kind string) UnmarshalFunc {
+	function, ok := d.variants[kind]
+	if ok {
+		return function
+	}
+	for _, suffix := range unionSuffixes {
+		base := strings.TrimSuffix(kind, suffix)
+		if base != kind && base != "" {
+			return d.variants[base]
+		}
+	}
+	return nil
+}
+
+// unionSuffixes are the suffixes added to the name of the type to build the kind of links and nil
+// references.
+var unionSuffixes = []string{
+	"Link",
+	"Nil",
+}
+
+// unionBytes reads all the bytes of the given source, which can be a slice of bytes, a string or a
+// reader.
+func unionBytes(source interface{}) (result []byte, err error) {
+	switch typed := source.(type) {
+	case []byte:
+		result = typed
+	case string:
+		result = []byte(typed)
+	case io.Reader:
+		result, err = io.ReadAll(typed)
+		if err != nil {
+			err = fmt.Errorf("can't read source: %w", err)
+		}
+	default:
+		err = fmt.Errorf(
+			"expected slice of bytes, string or reader but got '%T'",
+			source,
+		)
+	}
+	return
+}
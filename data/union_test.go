@@ -0,0 +1,192 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains tests for the union decoder.
+
+package data
+
+import (
+	"context"
+	"strings"
+
+	amv1 "github.com/openshift-online/ocm-sdk-go/accountsmgmt/v1"
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core" // nolint
+	. "github.com/onsi/gomega"             // nolint
+)
+
+var _ = Describe("Union decoder", func() {
+	var ctx context.Context
+	var decoder *UnionDecoder
+
+	BeforeEach(func() {
+		var err error
+
+		// Create a context:
+		ctx = context.Background()
+
+		// Create the decoder:
+		decoder, err = NewUnionDecoder().
+			Variant(cmv1.ClusterKind, Variant(cmv1.UnmarshalCluster)).
+			Variant(amv1.SubscriptionKind, Variant(amv1.UnmarshalSubscription)).
+			Build(ctx)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Can't be created without variants", func() {
+		_, err := NewUnionDecoder().Build(ctx)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("variant"))
+	})
+
+	It("Can't be created with nil function", func() {
+		_, err := NewUnionDecoder().
+			Variant(cmv1.ClusterKind, nil).
+			Build(ctx)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Cluster"))
+	})
+
+	It("Decodes object according to kind", func() {
+		object, err := decoder.Decode(`{
+			"kind": "Cluster",
+			"id": "123",
+			"name": "my_cluster"
+		}`)
+		Expect(err).ToNot(HaveOccurred())
+		cluster, ok := object.(*cmv1.Cluster)
+		Expect(ok).To(BeTrue())
+		Expect(cluster.ID()).To(Equal("123"))
+		Expect(cluster.Name()).To(Equal("my_cluster"))
+
+		object, err = decoder.Decode([]byte(`{
+			"kind": "Subscription",
+			"id": "456"
+		}`))
+		Expect(err).ToNot(HaveOccurred())
+		subscription, ok := object.(*amv1.Subscription)
+		Expect(ok).To(BeTrue())
+		Expect(subscription.ID()).To(Equal("456"))
+	})
+
+	It("Decodes object from reader", func() {
+		object, err := decoder.Decode(strings.NewReader(`{
+			"kind": "Cluster",
+			"id": "123"
+		}`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(object).To(BeAssignableToTypeOf(&cmv1.Cluster{}))
+	})
+
+	It("Decodes link using the function of the type", func() {
+		object, err := decoder.Decode(`{
+			"kind": "ClusterLink",
+			"id": "123",
+			"href": "/api/clusters_mgmt/v1/clusters/123"
+		}`)
+		Expect(err).ToNot(HaveOccurred())
+		cluster, ok := object.(*cmv1.Cluster)
+		Expect(ok).To(BeTrue())
+		Expect(cluster.Link()).To(BeTrue())
+		Expect(cluster.HREF()).To(Equal("/api/clusters_mgmt/v1/clusters/123"))
+	})
+
+	It("Fails if kind is missing", func() {
+		_, err := decoder.Decode(`{
+			"id": "123"
+		}`)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("kind"))
+	})
+
+	It("Fails if kind isn't supported", func() {
+		_, err := decoder.Decode(`{
+			"kind": "Junk"
+		}`)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Junk"))
+	})
+
+	It("Fails if source isn't supported", func() {
+		_, err := decoder.Decode(42)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Decodes array of mixed objects", func() {
+		objects, err := decoder.DecodeList(`[
+			{
+				"kind": "Cluster",
+				"id": "123"
+			},
+			{
+				"kind": "SubscriptionLink",
+				"id": "456"
+			}
+		]`)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(objects).To(HaveLen(2))
+		Expect(objects[0]).To(BeAssignableToTypeOf(&cmv1.Cluster{}))
+		Expect(objects[1]).To(BeAssignableToTypeOf(&amv1.Subscription{}))
+	})
+
+	It("Decodes items of list object", func() {
+		objects, err := decoder.DecodeList(`{
+			"kind": "List",
+			"page": 1,
+			"size": 1,
+			"total": 1,
+			"items": [
+				{
+					"kind": "Subscription",
+					"id": "456"
+				}
+			]
+		}`)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(objects).To(HaveLen(1))
+		subscription, ok := objects[0].(*amv1.Subscription)
+		Expect(ok).To(BeTrue())
+		Expect(subscription.ID()).To(Equal("456"))
+	})
+
+	It("Decodes empty list", func() {
+		objects, err := decoder.DecodeList(`[]`)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(objects).To(BeEmpty())
+	})
+
+	It("Reports index of item that can't be decoded", func() {
+		_, err := decoder.DecodeList(`[
+			{
+				"kind": "Cluster"
+			},
+			{
+				"kind": "Junk"
+			}
+		]`)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("item 1"))
+	})
+
+	It("Fails if list object doesn't have items", func() {
+		_, err := decoder.DecodeList(`{
+			"kind": "List"
+		}`)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("items"))
+	})
+})
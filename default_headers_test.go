@@ -0,0 +1,91 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains tests for the default headers added by the connection.
+
+package sdk
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"             // nolint
+	. "github.com/onsi/gomega"                         // nolint
+	. "github.com/openshift-online/ocm-sdk-go/testing" // nolint
+)
+
+var _ = Describe("Default headers", func() {
+	var ctx context.Context
+	var token string
+	var received http.Header
+	var connection *Connection
+
+	BeforeEach(func() {
+		var err error
+
+		// Create a context:
+		ctx = context.Background()
+
+		// Create a token:
+		token = MakeTokenString("Bearer", 15*time.Minute)
+
+		// Create a connection with a transport wrapper that remembers the headers:
+		connection, err = NewConnectionBuilder().
+			Logger(logger).
+			Tokens(token).
+			DefaultHeader("X-Source", "my-operator").
+			TransportWrapper(func(_ http.RoundTripper) http.RoundTripper {
+				return TransportFunc(func(request *http.Request) (*http.Response, error) {
+					received = request.Header.Clone()
+					return JSONTransport(http.StatusOK, "{}").RoundTrip(request)
+				})
+			}).
+			BuildContext(ctx)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		err := connection.Close()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Adds default header", func() {
+		_, err := connection.Get().Path("/mypath").SendContext(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(received.Get("X-Source")).To(Equal("my-operator"))
+		Expect(received.Get("Authorization")).To(Equal("Bearer " + token))
+	})
+
+	It("Allows overriding default header in request", func() {
+		_, err := connection.Get().
+			Path("/mypath").
+			Header("X-Source", "your-operator").
+			SendContext(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(received.Values("X-Source")).To(Equal([]string{"your-operator"}))
+	})
+
+	It("Can't be used for the authorization header", func() {
+		_, err := NewConnectionBuilder().
+			Logger(logger).
+			Tokens(token).
+			DefaultHeader("Authorization", "Bearer junk").
+			BuildContext(ctx)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Authorization"))
+	})
+})
@@ -0,0 +1,127 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the implementation of the method of the connection that sends raw requests.
+
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// Do sends the given request using the connection and returns the response. The request goes
+// through the same wrappers used for requests sent with the generated clients, so it will be
+// authenticated with the tokens of the connection, retried if it fails and included in the
+// metrics. This is intended as an escape hatch for calling endpoints that the generated clients
+// don't support yet. For example:
+//
+//	request, err := http.NewRequest(http.MethodGet, "/api/my_service/v1/my_things", nil)
+//	if err != nil {
+//		return err
+//	}
+//	response, err := connection.Do(ctx, request)
+//	if err != nil {
+//		return err
+//	}
+//	defer response.Body.Close()
+//
+// The URL of the request can contain only the path and the query, and then the server is selected
+// as for the rest of the requests, or it can be absolute, and then the scheme and host must be the
+// ones of the server that the connection would use for the path. Requests for other servers are
+// rejected, so that the tokens aren't sent to them.
+//
+// Unlike the generated clients this doesn't restrict the method, doesn't add the `Content-Type`
+// and `Accept` headers and doesn't check the content type of the response, so it can be used with
+// endpoints that don't use JSON. The `User-Agent` header is added only if the request doesn't
+// already have it. The given request isn't modified, and the caller is responsible for closing the
+// body of the response.
+func (c *Connection) Do(ctx context.Context, request *http.Request) (response *http.Response,
+	err error) {
+	// Check if the connection is closed:
+	err = c.checkClosed()
+	if err != nil {
+		closeRequestBody(request)
+		return
+	}
+
+	// Use the context of the request if no other context has been given:
+	if ctx == nil {
+		ctx = request.Context()
+	}
+
+	// Check the request URL:
+	if request.URL.Path == "" {
+		err = fmt.Errorf("request path is mandatory")
+		closeRequestBody(request)
+		return
+	}
+	if !path.IsAbs(request.URL.Path) {
+		err = fmt.Errorf("request path '%s' isn't absolute", request.URL.Path)
+		closeRequestBody(request)
+		return
+	}
+
+	// Select the target server and check that it matches the one of the request, if any:
+	server, err := c.selectServer(ctx, request)
+	if err != nil {
+		closeRequestBody(request)
+		return
+	}
+	if request.URL.Scheme != "" || request.URL.Host != "" {
+		if !strings.EqualFold(request.URL.Scheme, server.URL.Scheme) ||
+			!strings.EqualFold(request.URL.Host, server.URL.Host) {
+			err = fmt.Errorf(
+				"request URL '%s' doesn't match the server '%s' of the connection "+
+					"for path '%s'",
+				request.URL, server.URL, request.URL.Path,
+			)
+			closeRequestBody(request)
+			return
+		}
+	}
+
+	// Replace the request with a copy that has the context and the URL of the server, so that
+	// the request of the caller isn't modified:
+	request = request.Clone(ctx)
+	request.URL.Scheme = ""
+	request.URL.Host = ""
+	request.URL.User = nil
+	request.Host = ""
+	request.URL = server.URL.ResolveReference(request.URL)
+
+	// Add the agent, unless the caller already did:
+	if request.Header == nil {
+		request.Header = http.Header{}
+	}
+	if c.agent != "" && request.Header.Get("User-Agent") == "" {
+		request.Header.Set("User-Agent", c.agent)
+	}
+
+	// Select the client:
+	client, err := c.clientSelector.Select(ctx, server)
+	if err != nil {
+		closeRequestBody(request)
+		return
+	}
+
+	// Send the request:
+	response, err = client.Do(request)
+	return
+}
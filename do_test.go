@@ -0,0 +1,221 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains tests for the method of the connection that sends raw requests.
+
+package sdk
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core" // nolint
+	. "github.com/onsi/gomega"             // nolint
+
+	"github.com/onsi/gomega/ghttp"
+
+	. "github.com/openshift-online/ocm-sdk-go/testing" // nolint
+)
+
+var _ = Describe("Do", func() {
+	var ctx context.Context
+	var token string
+	var server *ghttp.Server
+	var connection *Connection
+
+	BeforeEach(func() {
+		var err error
+
+		// Create the context:
+		ctx = context.Background()
+
+		// Create the token:
+		token = MakeTokenString("Bearer", 5*time.Minute)
+
+		// Create the server:
+		server = MakeTCPServer()
+
+		// Create the connection:
+		connection, err = NewConnectionBuilder().
+			Logger(logger).
+			URL(server.URL()).
+			Tokens(token).
+			RetryInterval(10 * time.Millisecond).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		// Close the connection:
+		err := connection.Close()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Stop the server:
+		server.Close()
+	})
+
+	It("Sends authenticated request with relative URL", func() {
+		// Prepare the server:
+		server.AppendHandlers(
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest(http.MethodGet, "/api/my_service/v1/my_things", "page=2"),
+				ghttp.VerifyHeaderKV("Authorization", "Bearer "+token),
+				ghttp.VerifyHeaderKV("User-Agent", DefaultAgent),
+				RespondWithJSON(http.StatusOK, `{"items":[]}`),
+			),
+		)
+
+		// Send the request:
+		request, err := http.NewRequest(http.MethodGet, "/api/my_service/v1/my_things?page=2", nil)
+		Expect(err).ToNot(HaveOccurred())
+		response, err := connection.Do(ctx, request)
+		Expect(err).ToNot(HaveOccurred())
+		defer response.Body.Close()
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+		body, err := io.ReadAll(response.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(body).To(MatchJSON(`{"items":[]}`))
+
+		// Verify that the original request hasn't been modified:
+		Expect(request.URL.String()).To(Equal("/api/my_service/v1/my_things?page=2"))
+		Expect(request.Header).To(BeEmpty())
+	})
+
+	It("Sends request with absolute URL of the connection", func() {
+		// Prepare the server:
+		server.AppendHandlers(
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest(http.MethodGet, "/api/my_service/v1/my_things"),
+				ghttp.VerifyHeaderKV("Authorization", "Bearer "+token),
+				RespondWithJSON(http.StatusOK, `{}`),
+			),
+		)
+
+		// Send the request:
+		request, err := http.NewRequest(
+			http.MethodGet,
+			server.URL()+"/api/my_service/v1/my_things",
+			nil,
+		)
+		Expect(err).ToNot(HaveOccurred())
+		response, err := connection.Do(ctx, request)
+		Expect(err).ToNot(HaveOccurred())
+		defer response.Body.Close()
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+	})
+
+	It("Doesn't restrict method, headers or content type", func() {
+		// Prepare the server:
+		server.AppendHandlers(
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest(http.MethodOptions, "/api/my_service/v1/my_things"),
+				ghttp.VerifyHeaderKV("Content-Type", "text/plain"),
+				ghttp.VerifyHeaderKV("Accept", "text/plain"),
+				ghttp.VerifyHeaderKV("User-Agent", "my-agent"),
+				ghttp.VerifyBody([]byte("my body")),
+				ghttp.RespondWith(
+					http.StatusOK,
+					"my response",
+					http.Header{
+						"Content-Type": []string{"text/plain"},
+					},
+				),
+			),
+		)
+
+		// Send the request:
+		request, err := http.NewRequest(
+			http.MethodOptions,
+			"/api/my_service/v1/my_things",
+			strings.NewReader("my body"),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		request.Header.Set("Content-Type", "text/plain")
+		request.Header.Set("Accept", "text/plain")
+		request.Header.Set("User-Agent", "my-agent")
+		response, err := connection.Do(ctx, request)
+		Expect(err).ToNot(HaveOccurred())
+		defer response.Body.Close()
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+		body, err := io.ReadAll(response.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(body)).To(Equal("my response"))
+	})
+
+	It("Retries failed request", func() {
+		// Prepare the server:
+		server.AppendHandlers(
+			RespondWithJSON(http.StatusServiceUnavailable, `{}`),
+			RespondWithJSON(http.StatusOK, `{}`),
+		)
+
+		// Send the request:
+		request, err := http.NewRequest(http.MethodGet, "/api/my_service/v1/my_things", nil)
+		Expect(err).ToNot(HaveOccurred())
+		response, err := connection.Do(ctx, request)
+		Expect(err).ToNot(HaveOccurred())
+		defer response.Body.Close()
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+		Expect(server.ReceivedRequests()).To(HaveLen(2))
+	})
+
+	It("Uses the given context", func() {
+		// Send the request with a cancelled context:
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		request, err := http.NewRequest(http.MethodGet, "/api/my_service/v1/my_things", nil)
+		Expect(err).ToNot(HaveOccurred())
+		response, err := connection.Do(cancelled, request)
+		Expect(err).To(HaveOccurred())
+		Expect(response).To(BeNil())
+		Expect(err).To(MatchError(context.Canceled))
+		Expect(server.ReceivedRequests()).To(BeEmpty())
+	})
+
+	It("Rejects request for other server", func() {
+		request, err := http.NewRequest(
+			http.MethodGet,
+			"https://example.com/api/my_service/v1/my_things",
+			nil,
+		)
+		Expect(err).ToNot(HaveOccurred())
+		response, err := connection.Do(ctx, request)
+		Expect(err).To(HaveOccurred())
+		Expect(response).To(BeNil())
+		Expect(err.Error()).To(ContainSubstring("doesn't match"))
+		Expect(server.ReceivedRequests()).To(BeEmpty())
+	})
+
+	It("Rejects request after the connection is closed", func() {
+		connection, err := NewConnectionBuilder().
+			Logger(logger).
+			URL(server.URL()).
+			Tokens(token).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		err = connection.Close()
+		Expect(err).ToNot(HaveOccurred())
+		request, err := http.NewRequest(http.MethodGet, "/api/my_service/v1/my_things", nil)
+		Expect(err).ToNot(HaveOccurred())
+		response, err := connection.Do(ctx, request)
+		Expect(err).To(HaveOccurred())
+		Expect(response).To(BeNil())
+		Expect(err.Error()).To(ContainSubstring("closed"))
+	})
+})
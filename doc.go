@@ -15,4 +15,25 @@ limitations under the License.
 */
 
 // Package sdk contains a set of objects that simplify usage of `api.openshift.com`.
+//
+// The methods of the connection that have the names of the services, like ClustersMgmt or
+// AccountsMgmt, return clients that are scoped to those services. These clients share the
+// transport, authentication, retry and metrics configuration of the connection, but they only
+// give access to the resources of their service. When a component only needs one service it is
+// good practice to pass it one of these clients instead of the complete connection. For example:
+//
+//	// Create the connection once:
+//	connection, err := sdk.NewConnectionBuilder().
+//		Tokens(token).
+//		Build()
+//	if err != nil {
+//		...
+//	}
+//	defer connection.Close()
+//
+//	// Give the component only the clusters management client:
+//	component := NewMyComponent(connection.ClustersMgmt().V1())
+//
+// Creating these clients is cheap, and they don't need to be closed; closing the connection is
+// enough.
 package sdk
@@ -33,6 +33,8 @@ import (
 
 	"github.com/openshift-online/ocm-sdk-go/helpers"
 	"github.com/openshift-online/ocm-sdk-go/logging"
+	"github.com/openshift-online/ocm-sdk-go/retry"
+	"github.com/openshift-online/ocm-sdk-go/tenancy"
 )
 
 // dumpTransportWrapper is a transport wrapper that creates round trippers that dump the details of
@@ -131,6 +133,14 @@ var redactFields = map[string]bool{
 
 // dumpRequest dumps to the log, in debug level, the details of the given HTTP request.
 func (d *dumpRoundTripper) dumpRequest(ctx context.Context, request *http.Request, body []byte) {
+	attempt := retry.AttemptFromContext(ctx)
+	if attempt > 1 {
+		d.logger.Debug(ctx, "Request attempt is %d", attempt)
+	}
+	org := tenancy.OrganizationFromContext(ctx)
+	if org != tenancy.UnknownOrganization {
+		d.logger.Debug(ctx, "Request organization is '%s'", org)
+	}
 	d.logger.Debug(ctx, "Request method is %s", request.Method)
 	d.logger.Debug(ctx, "Request URL is '%s'", request.URL)
 	if request.Host != "" {
@@ -271,7 +281,7 @@ func (d *dumpRoundTripper) dumpJSON(ctx context.Context, data []byte) {
 		d.logger.Debug(ctx, "%s", data)
 	} else {
 		var buf bytes.Buffer
-		str := helpers.NewStream(&buf)
+		str := helpers.NewRawStream(&buf)
 
 		// remove sensitive information
 		d.redactSensitive(it, str)
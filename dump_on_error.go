@@ -0,0 +1,193 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the transport wrapper that dumps the details of HTTP requests and responses
+// to the log only when the request fails.
+
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/openshift-online/ocm-sdk-go/logging"
+)
+
+// errorDumpTransportWrapper is a transport wrapper that creates round trippers that collect the
+// details of requests and responses in memory, and send them to the log only when the request
+// fails.
+type errorDumpTransportWrapper struct {
+	logger    logging.Logger
+	threshold int
+}
+
+// Wrap creates a round tripper on top of the given one that sends to the log the details of
+// failed requests and responses.
+func (w *errorDumpTransportWrapper) Wrap(transport http.RoundTripper) http.RoundTripper {
+	return &errorDumpRoundTripper{
+		logger:    w.logger,
+		threshold: w.threshold,
+		next:      transport,
+	}
+}
+
+// errorDumpRoundTripper is a round tripper that dumps the details of the requests and the
+// responses to the log when the request fails.
+type errorDumpRoundTripper struct {
+	logger    logging.Logger
+	threshold int
+	next      http.RoundTripper
+}
+
+// Make sure that we implement the http.RoundTripper interface:
+var _ http.RoundTripper = &errorDumpRoundTripper{}
+
+// RoundTrip is the implementation of the http.RoundTripper interface.
+func (d *errorDumpRoundTripper) RoundTrip(request *http.Request) (response *http.Response,
+	err error) {
+	// Get the context:
+	ctx := request.Context()
+
+	// Create the dumper that writes to the buffer instead of writing to the log:
+	buffer := &bufferLogger{}
+	dumper := &dumpRoundTripper{
+		logger: buffer,
+	}
+
+	// Read the complete request body in memory, in order to be able to send it to the log
+	// later, and replace it with a reader that reads it from memory:
+	if request.Body != nil {
+		var body []byte
+		body, err = io.ReadAll(request.Body)
+		if err != nil {
+			return
+		}
+		err = request.Body.Close()
+		if err != nil {
+			return
+		}
+		dumper.dumpRequest(ctx, request, body)
+		request.Body = io.NopCloser(bytes.NewBuffer(body))
+	} else {
+		dumper.dumpRequest(ctx, request, nil)
+	}
+
+	// Call the next round tripper, and if the request fails send the buffer to the log:
+	response, err = d.next.RoundTrip(request)
+	if err != nil {
+		buffer.Debug(ctx, "Request failed: %v", err)
+		d.flush(ctx, buffer)
+		return
+	}
+
+	// If the response is successful discard the buffer, and don't touch the body, so that it
+	// can still be streamed:
+	if response.StatusCode < d.threshold {
+		return
+	}
+
+	// The response failed, so read the complete response body in memory, add it to the buffer
+	// and replace it with a reader that reads it from memory:
+	if response.Body != nil {
+		var body []byte
+		body, err = io.ReadAll(response.Body)
+		if err != nil {
+			return
+		}
+		err = response.Body.Close()
+		if err != nil {
+			return
+		}
+		dumper.dumpResponse(ctx, response, body)
+		response.Body = io.NopCloser(bytes.NewBuffer(body))
+	} else {
+		dumper.dumpResponse(ctx, response, nil)
+	}
+	d.flush(ctx, buffer)
+
+	return
+}
+
+// flush sends to the log, in warning level, the messages collected in the given buffer.
+func (d *errorDumpRoundTripper) flush(ctx context.Context, buffer *bufferLogger) {
+	d.logger.Warn(ctx, "Request failed, details follow")
+	for _, message := range buffer.messages {
+		d.logger.Warn(ctx, "%s", message)
+	}
+}
+
+// bufferLogger is a logger that saves the messages in memory instead of sending them to the log.
+// All the levels are enabled, and all the messages are saved regardless of the level.
+type bufferLogger struct {
+	messages []string
+}
+
+// Make sure that we implement the logging.Logger interface:
+var _ logging.Logger = &bufferLogger{}
+
+// DebugEnabled is part of the implementation of the logging.Logger interface.
+func (l *bufferLogger) DebugEnabled() bool {
+	return true
+}
+
+// InfoEnabled is part of the implementation of the logging.Logger interface.
+func (l *bufferLogger) InfoEnabled() bool {
+	return true
+}
+
+// WarnEnabled is part of the implementation of the logging.Logger interface.
+func (l *bufferLogger) WarnEnabled() bool {
+	return true
+}
+
+// ErrorEnabled is part of the implementation of the logging.Logger interface.
+func (l *bufferLogger) ErrorEnabled() bool {
+	return true
+}
+
+// Debug is part of the implementation of the logging.Logger interface.
+func (l *bufferLogger) Debug(ctx context.Context, format string, args ...interface{}) {
+	l.save(format, args)
+}
+
+// Info is part of the implementation of the logging.Logger interface.
+func (l *bufferLogger) Info(ctx context.Context, format string, args ...interface{}) {
+	l.save(format, args)
+}
+
+// Warn is part of the implementation of the logging.Logger interface.
+func (l *bufferLogger) Warn(ctx context.Context, format string, args ...interface{}) {
+	l.save(format, args)
+}
+
+// Error is part of the implementation of the logging.Logger interface.
+func (l *bufferLogger) Error(ctx context.Context, format string, args ...interface{}) {
+	l.save(format, args)
+}
+
+// Fatal is part of the implementation of the logging.Logger interface. Note that it doesn't exit,
+// it only saves the message like the other levels.
+func (l *bufferLogger) Fatal(ctx context.Context, format string, args ...interface{}) {
+	l.save(format, args)
+}
+
+// save formats the message and appends it to the buffer.
+func (l *bufferLogger) save(format string, args []interface{}) {
+	l.messages = append(l.messages, fmt.Sprintf(format, args...))
+}
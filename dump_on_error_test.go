@@ -0,0 +1,208 @@
+/*
+Copyright (c) 2021 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains tests for the mode that sends to the log the details of failed requests.
+
+package sdk
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/onsi/gomega/ghttp"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"             // nolint
+	. "github.com/onsi/gomega"                         // nolint
+	. "github.com/openshift-online/ocm-sdk-go/testing" // nolint
+)
+
+var _ = Describe("Log on error", func() {
+	var (
+		stdOut    bytes.Buffer
+		stdErr    bytes.Buffer
+		apiServer *ghttp.Server
+		builder   *ConnectionBuilder
+	)
+
+	BeforeEach(func() {
+		// Clear the buffers:
+		stdOut.Reset()
+		stdErr.Reset()
+
+		// Create a logger that has the debug level disabled and writes to the buffers:
+		stdLogger, err := NewStdLoggerBuilder().
+			Streams(&stdOut, &stdErr).
+			Debug(false).
+			Warn(true).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Create the API server:
+		apiServer = MakeTCPServer()
+
+		// Prepare the connection builder:
+		builder = NewConnectionBuilder().
+			Logger(stdLogger).
+			URL(apiServer.URL()).
+			Tokens(MakeTokenString("Bearer", 5*time.Minute)).
+			RetryLimit(0)
+	})
+
+	AfterEach(func() {
+		apiServer.Close()
+	})
+
+	It("Doesn't write details of successful requests", func() {
+		// Create the connection:
+		connection, err := builder.LogOnError(http.StatusBadRequest).Build()
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err = connection.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+
+		// Send the request:
+		apiServer.AppendHandlers(RespondWithJSON(http.StatusOK, `{"id":"123"}`))
+		response, err := connection.Get().
+			Path("/api/clusters_mgmt/v1/clusters/123").
+			Send()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.String()).To(Equal(`{"id":"123"}`))
+
+		// Verify the log:
+		Expect(stdOut.String() + stdErr.String()).To(BeEmpty())
+	})
+
+	It("Writes details of requests that fail with status above threshold", func() {
+		// Create the connection:
+		connection, err := builder.LogOnError(http.StatusBadRequest).Build()
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err = connection.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+
+		// Send the request:
+		apiServer.AppendHandlers(RespondWithJSON(http.StatusNotFound, `{"kind":"Error"}`))
+		response, err := connection.Get().
+			Path("/api/clusters_mgmt/v1/clusters/123").
+			Send()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.Status()).To(Equal(http.StatusNotFound))
+
+		// Verify that the body can still be read:
+		Expect(response.String()).To(Equal(`{"kind":"Error"}`))
+
+		// Verify the log:
+		log := stdOut.String() + stdErr.String()
+		Expect(log).To(ContainSubstring("Request failed, details follow"))
+		Expect(log).To(ContainSubstring("Request method is GET"))
+		Expect(log).To(ContainSubstring("/api/clusters_mgmt/v1/clusters/123"))
+		Expect(log).To(ContainSubstring("Request header 'Authorization' is omitted"))
+		Expect(log).To(ContainSubstring("Response status is '404 Not Found'"))
+		Expect(log).To(ContainSubstring(`"kind": "Error"`))
+	})
+
+	It("Doesn't write details of requests that fail with status below threshold", func() {
+		// Create the connection:
+		connection, err := builder.LogOnError(http.StatusInternalServerError).Build()
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err = connection.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+
+		// Send the request:
+		apiServer.AppendHandlers(RespondWithJSON(http.StatusNotFound, `{"kind":"Error"}`))
+		response, err := connection.Get().
+			Path("/api/clusters_mgmt/v1/clusters/123").
+			Send()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.Status()).To(Equal(http.StatusNotFound))
+
+		// Verify the log:
+		Expect(stdOut.String() + stdErr.String()).To(BeEmpty())
+	})
+
+	It("Writes details of requests that fail with transport error", func() {
+		// Create the connection:
+		connection, err := builder.LogOnError(http.StatusInternalServerError).Build()
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err = connection.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+
+		// Make the server close the connection without sending a response:
+		apiServer.AppendHandlers(func(w http.ResponseWriter, r *http.Request) {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			Expect(err).ToNot(HaveOccurred())
+			err = conn.Close()
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		// Send the request:
+		_, err = connection.Post().
+			Path("/api/clusters_mgmt/v1/clusters").
+			String(`{"name":"mycluster"}`).
+			Send()
+		Expect(err).To(HaveOccurred())
+
+		// Verify the log:
+		log := stdOut.String() + stdErr.String()
+		Expect(log).To(ContainSubstring("Request failed, details follow"))
+		Expect(log).To(ContainSubstring("Request method is POST"))
+		Expect(log).To(ContainSubstring(`"name": "mycluster"`))
+		Expect(log).To(MatchRegexp(`Request failed: .*EOF`))
+	})
+
+	It("Sends the complete request body", func() {
+		// Create the connection:
+		connection, err := builder.LogOnError(http.StatusBadRequest).Build()
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err = connection.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+
+		// Send the request:
+		apiServer.AppendHandlers(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(body)).To(Equal(`{"name":"mycluster"}`))
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			_, err = w.Write([]byte(`{}`))
+			Expect(err).ToNot(HaveOccurred())
+		})
+		response, err := connection.Post().
+			Path("/api/clusters_mgmt/v1/clusters").
+			String(`{"name":"mycluster"}`).
+			Send()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.Status()).To(Equal(http.StatusCreated))
+	})
+
+	It("Rejects invalid threshold", func() {
+		connection, err := builder.LogOnError(42).Build()
+		Expect(err).To(HaveOccurred())
+		Expect(connection).To(BeNil())
+		Expect(err.Error()).To(ContainSubstring("42"))
+		Expect(err.Error()).To(ContainSubstring("isn't valid"))
+	})
+})
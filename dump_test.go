@@ -19,6 +19,10 @@ package sdk
 import (
 	"bytes"
 	"context"
+	"net/http"
+
+	"github.com/openshift-online/ocm-sdk-go/retry"
+	"github.com/openshift-online/ocm-sdk-go/tenancy"
 
 	. "github.com/onsi/ginkgo/v2/dsl/core" // nolint
 	. "github.com/onsi/gomega"             // nolint
@@ -108,4 +112,35 @@ var _ = Describe("DumpRoundTripper", func() {
 		expectedJSON := "{\n  \n}\n"
 		Expect(stdOut.String()).To(Equal(expectedJSON))
 	})
+
+	It("dumpRequest includes retry attempt", func() {
+		request, err := http.NewRequest(http.MethodGet, "http://api.example.com/mypath", nil)
+		Expect(err).ToNot(HaveOccurred())
+		ctx := retry.ContextWithAttempt(context.Background(), 2)
+		d.dumpRequest(ctx, request, nil)
+		Expect(stdOut.String()).To(ContainSubstring("Request attempt is 2"))
+	})
+
+	It("dumpRequest doesn't include first attempt", func() {
+		request, err := http.NewRequest(http.MethodGet, "http://api.example.com/mypath", nil)
+		Expect(err).ToNot(HaveOccurred())
+		ctx := retry.ContextWithAttempt(context.Background(), 1)
+		d.dumpRequest(ctx, request, nil)
+		Expect(stdOut.String()).ToNot(ContainSubstring("attempt"))
+	})
+
+	It("dumpRequest includes organization", func() {
+		request, err := http.NewRequest(http.MethodGet, "http://api.example.com/mypath", nil)
+		Expect(err).ToNot(HaveOccurred())
+		ctx := tenancy.WithOrganization(context.Background(), "123")
+		d.dumpRequest(ctx, request, nil)
+		Expect(stdOut.String()).To(ContainSubstring("Request organization is '123'"))
+	})
+
+	It("dumpRequest doesn't include unknown organization", func() {
+		request, err := http.NewRequest(http.MethodGet, "http://api.example.com/mypath", nil)
+		Expect(err).ToNot(HaveOccurred())
+		d.dumpRequest(context.Background(), request, nil)
+		Expect(stdOut.String()).ToNot(ContainSubstring("organization"))
+	})
 })
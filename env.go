@@ -0,0 +1,152 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the methods that configure connections from environment variables.
+
+package sdk
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Names of the environment variables used to configure connections:
+const (
+	EnvURL          = "OCM_URL"
+	EnvTokenURL     = "OCM_TOKEN_URL"
+	EnvToken        = "OCM_TOKEN"
+	EnvClientID     = "OCM_CLIENT_ID"
+	EnvClientSecret = "OCM_CLIENT_SECRET"
+	EnvScopes       = "OCM_SCOPES"
+	EnvInsecure     = "OCM_INSECURE"
+	EnvAgent        = "OCM_AGENT"
+)
+
+// NewConnectionFromEnv creates a new connection using the configuration from the environment
+// variables described in the documentation of the Env method of the builder. It is equivalent to
+// this:
+//
+//	connection, err := sdk.NewConnectionBuilder().
+//		Env().
+//		Build()
+//
+// Use the builder directly if you need to change other settings.
+func NewConnectionFromEnv() (connection *Connection, err error) {
+	return NewConnectionBuilder().
+		Env().
+		Build()
+}
+
+// Env loads the connection configuration from the following environment variables:
+//
+//	OCM_URL - Base URL of the API gateway.
+//	OCM_TOKEN_URL - URL used to request OpenID access tokens.
+//	OCM_TOKEN - Access or refresh token.
+//	OCM_CLIENT_ID - OpenID client identifier.
+//	OCM_CLIENT_SECRET - OpenID client secret.
+//	OCM_SCOPES - Space separated list of OpenID scopes.
+//	OCM_INSECURE - Disables verification of TLS certificates if set to `true`.
+//	OCM_AGENT - Value of the `User-Agent` header.
+//
+// Setting any of these variables has the same effect that calling the corresponding method of the
+// builder. Variables that aren't set, or that are empty, are ignored.
+//
+// Credentials are mandatory, so either OCM_TOKEN or both OCM_CLIENT_ID and OCM_CLIENT_SECRET need to
+// be set. If they aren't then the Build method will return an error listing the missing variables.
+//
+// There are no specific variables for the proxy because the connection already uses the proxy
+// configured with the standard HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+func (b *ConnectionBuilder) Env() *ConnectionBuilder {
+	if b.err != nil {
+		return b
+	}
+
+	// URLs:
+	value, ok := lookupEnv(EnvURL)
+	if ok {
+		b.URL(value)
+	}
+	value, ok = lookupEnv(EnvTokenURL)
+	if ok {
+		b.TokenURL(value)
+	}
+
+	// Credentials:
+	token, hasToken := lookupEnv(EnvToken)
+	clientID, hasClientID := lookupEnv(EnvClientID)
+	clientSecret, hasClientSecret := lookupEnv(EnvClientSecret)
+	var missing []string
+	switch {
+	case hasToken:
+	case hasClientID && !hasClientSecret:
+		missing = append(missing, EnvClientSecret)
+	case !hasClientID && hasClientSecret:
+		missing = append(missing, EnvClientID)
+	case !hasClientID && !hasClientSecret:
+		missing = append(missing, EnvToken+" or "+EnvClientID+" and "+EnvClientSecret)
+	}
+	if len(missing) > 0 {
+		b.err = fmt.Errorf(
+			"can't configure connection from the environment, the following "+
+				"variables are missing: %s",
+			strings.Join(missing, ", "),
+		)
+		return b
+	}
+	if hasToken {
+		b.Tokens(token)
+	}
+	if hasClientID || hasClientSecret {
+		b.Client(clientID, clientSecret)
+	}
+	value, ok = lookupEnv(EnvScopes)
+	if ok {
+		b.Scopes(strings.Fields(value)...)
+	}
+
+	// Insecure:
+	value, ok = lookupEnv(EnvInsecure)
+	if ok {
+		var insecure bool
+		insecure, b.err = strconv.ParseBool(value)
+		if b.err != nil {
+			b.err = fmt.Errorf(
+				"value '%s' of environment variable '%s' isn't a valid boolean: %w",
+				value, EnvInsecure, b.err,
+			)
+			return b
+		}
+		b.Insecure(insecure)
+	}
+
+	// Agent:
+	value, ok = lookupEnv(EnvAgent)
+	if ok {
+		b.Agent(value)
+	}
+
+	return b
+}
+
+// lookupEnv returns the value of the given environment variable, and a flag indicating if it is
+// set and not empty.
+func lookupEnv(name string) (value string, ok bool) {
+	value = strings.TrimSpace(os.Getenv(name))
+	ok = value != ""
+	return
+}
@@ -0,0 +1,157 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains tests for the configuration of connections from environment variables.
+
+package sdk
+
+import (
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"  // nolint
+	. "github.com/onsi/ginkgo/v2/dsl/table" // nolint
+	. "github.com/onsi/gomega"              // nolint
+
+	. "github.com/openshift-online/ocm-sdk-go/testing" // nolint
+)
+
+var _ = Describe("Environment", func() {
+	// names contains the names of all the environment variables used by the connection, so that
+	// we can make sure that they are all cleared before and after each test.
+	names := []string{
+		EnvURL,
+		EnvTokenURL,
+		EnvToken,
+		EnvClientID,
+		EnvClientSecret,
+		EnvScopes,
+		EnvInsecure,
+		EnvAgent,
+	}
+
+	// Clear removes all the environment variables.
+	var Clear = func() {
+		for _, name := range names {
+			err := os.Unsetenv(name)
+			Expect(err).ToNot(HaveOccurred())
+		}
+	}
+
+	// Set sets the given environment variables.
+	var Set = func(variables map[string]string) {
+		for name, value := range variables {
+			err := os.Setenv(name, value)
+			Expect(err).ToNot(HaveOccurred())
+		}
+	}
+
+	BeforeEach(Clear)
+	AfterEach(Clear)
+
+	It("Can be created with token", func() {
+		// Set the environment:
+		Set(map[string]string{
+			EnvURL:      "https://my.server.com",
+			EnvTokenURL: "https://my.sso.com/token",
+			EnvToken:    MakeTokenString("Bearer", 5*time.Minute),
+			EnvScopes:   "openid myscope",
+			EnvInsecure: "true",
+			EnvAgent:    "myagent",
+		})
+
+		// Create the connection:
+		connection, err := NewConnectionBuilder().
+			Logger(logger).
+			Env().
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		defer connection.Close()
+
+		// Verify the configuration:
+		Expect(connection.URL()).To(Equal("https://my.server.com"))
+		Expect(connection.TokenURL()).To(Equal("https://my.sso.com/token"))
+		Expect(connection.Scopes()).To(ConsistOf("openid", "myscope"))
+		Expect(connection.Insecure()).To(BeTrue())
+		Expect(connection.Agent()).To(Equal("myagent"))
+	})
+
+	It("Can be created with client credentials", func() {
+		// Set the environment:
+		Set(map[string]string{
+			EnvClientID:     "myclient",
+			EnvClientSecret: "mysecret",
+		})
+
+		// Create the connection:
+		connection, err := NewConnectionFromEnv()
+		Expect(err).ToNot(HaveOccurred())
+		defer connection.Close()
+
+		// Verify the configuration:
+		id, secret := connection.Client()
+		Expect(id).To(Equal("myclient"))
+		Expect(secret).To(Equal("mysecret"))
+		Expect(connection.URL()).To(Equal(DefaultURL))
+	})
+
+	DescribeTable(
+		"Reports missing variables",
+		func(variables map[string]string, expected string) {
+			Set(variables)
+			connection, err := NewConnectionFromEnv()
+			Expect(err).To(HaveOccurred())
+			Expect(connection).To(BeNil())
+			message := err.Error()
+			Expect(message).To(ContainSubstring("missing"))
+			Expect(message).To(ContainSubstring(expected))
+		},
+		Entry(
+			"No credentials",
+			map[string]string{
+				EnvURL: "https://my.server.com",
+			},
+			"OCM_TOKEN or OCM_CLIENT_ID and OCM_CLIENT_SECRET",
+		),
+		Entry(
+			"No client secret",
+			map[string]string{
+				EnvClientID: "myclient",
+			},
+			"OCM_CLIENT_SECRET",
+		),
+		Entry(
+			"No client identifier",
+			map[string]string{
+				EnvClientSecret: "mysecret",
+			},
+			"OCM_CLIENT_ID",
+		),
+	)
+
+	It("Rejects invalid insecure flag", func() {
+		Set(map[string]string{
+			EnvToken:    MakeTokenString("Bearer", 5*time.Minute),
+			EnvInsecure: "maybe",
+		})
+		connection, err := NewConnectionFromEnv()
+		Expect(err).To(HaveOccurred())
+		Expect(connection).To(BeNil())
+		message := err.Error()
+		Expect(message).To(ContainSubstring("maybe"))
+		Expect(message).To(ContainSubstring(EnvInsecure))
+	})
+})
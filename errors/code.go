@@ -0,0 +1,51 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the functions that check the codes of errors.
+
+package errors
+
+// HasCode checks if the given error, or any of the errors that it wraps, is an OCM error with the
+// given code. This is intended for code that needs to branch on the code of the errors returned
+// by the server, for example:
+//
+//	if errors.HasCode(err, "CLUSTERS-MGMT-404") {
+//		...
+//	}
+//
+// Errors that aggregate several errors, like MultiError, match if any of them has the code. The
+// result is false if the error is nil.
+func HasCode(err error, code string) bool {
+	switch typed := err.(type) {
+	case nil:
+		return false
+	case *Error:
+		if typed != nil && typed.bitmap_&8 != 0 && typed.code == code {
+			return true
+		}
+	}
+	switch typed := err.(type) {
+	case interface{ Unwrap() error }:
+		return HasCode(typed.Unwrap(), code)
+	case interface{ Unwrap() []error }:
+		for _, item := range typed.Unwrap() {
+			if HasCode(item, code) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains tests for the functions that check the codes of errors.
+
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core" // nolint
+	. "github.com/onsi/gomega"             // nolint
+)
+
+var _ = Describe("Has code", func() {
+	var notFound *Error
+
+	BeforeEach(func() {
+		var err error
+		notFound, err = NewError().
+			Status(http.StatusNotFound).
+			Code("CLUSTERS-MGMT-404").
+			Reason("Cluster '123' not found").
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Returns true for an error with the code", func() {
+		Expect(HasCode(notFound, "CLUSTERS-MGMT-404")).To(BeTrue())
+	})
+
+	It("Returns false for an error with a different code", func() {
+		Expect(HasCode(notFound, "CLUSTERS-MGMT-400")).To(BeFalse())
+	})
+
+	It("Returns false for an error without code", func() {
+		object, err := NewError().
+			Reason("my reason").
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(HasCode(object, "")).To(BeFalse())
+	})
+
+	It("Returns false for nil", func() {
+		Expect(HasCode(nil, "CLUSTERS-MGMT-404")).To(BeFalse())
+	})
+
+	It("Returns false for a nil error object", func() {
+		var object *Error
+		Expect(HasCode(object, "CLUSTERS-MGMT-404")).To(BeFalse())
+	})
+
+	It("Returns false for an error of other type", func() {
+		err := errors.New("CLUSTERS-MGMT-404")
+		Expect(HasCode(err, "CLUSTERS-MGMT-404")).To(BeFalse())
+	})
+
+	It("Unwraps wrapped errors", func() {
+		err := fmt.Errorf("can't get cluster: %w", notFound)
+		Expect(HasCode(err, "CLUSTERS-MGMT-404")).To(BeTrue())
+	})
+
+	It("Checks all the errors of a multi error", func() {
+		other, err := NewError().
+			Code("CLUSTERS-MGMT-400").
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		multi := NewMultiError(other, notFound)
+		Expect(HasCode(multi, "CLUSTERS-MGMT-404")).To(BeTrue())
+		Expect(HasCode(multi, "CLUSTERS-MGMT-400")).To(BeTrue())
+		Expect(HasCode(multi, "CLUSTERS-MGMT-500")).To(BeFalse())
+	})
+})
@@ -0,0 +1,28 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package errors contains the type used to represent the errors returned by the server, and the
+// functions used to read and send them.
+//
+// The builder returned by the NewError function can also be used to create errors in tests of code
+// that handles the errors returned by the SDK. For example:
+//
+//	notFound, err := errors.NewError().
+//		Status(http.StatusNotFound).
+//		Code("CLUSTERS-MGMT-404").
+//		Reason("Cluster '123' not found").
+//		Build()
+package errors
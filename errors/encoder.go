@@ -0,0 +1,68 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the hook that can be used to change the format of the bodies of error
+// responses.
+
+package errors
+
+import (
+	"io"
+	"sync"
+)
+
+// ErrorEncoder is a function that writes the body of an error response. It is used by the
+// SendError function and by the rest of the functions that send errors, so that servers that need
+// a different format, for example for compatibility with legacy clients, can change it without
+// changing the way errors are sent. The status code and the headers of the response are still set
+// by those functions.
+type ErrorEncoder func(object *Error, writer io.Writer) error
+
+// SetErrorEncoder sets the function that will be used to write the bodies of error responses. For
+// example, to send only the reason of the error inside a `message` field:
+//
+//	errors.SetErrorEncoder(func(object *errors.Error, writer io.Writer) error {
+//		return json.NewEncoder(writer).Encode(map[string]string{
+//			"message": object.Reason(),
+//		})
+//	})
+//
+// Passing nil restores the default encoder, which is the MarshalError function. This affects all
+// the errors sent by the process, so it should usually be called only once, during
+// initialization.
+func SetErrorEncoder(value ErrorEncoder) {
+	errorEncoderLock.Lock()
+	defer errorEncoderLock.Unlock()
+	if value == nil {
+		value = MarshalError
+	}
+	errorEncoder = value
+}
+
+// encodeError writes the given error using the encoder that has been set with the SetErrorEncoder
+// function.
+func encodeError(object *Error, writer io.Writer) error {
+	errorEncoderLock.RLock()
+	encoder := errorEncoder
+	errorEncoderLock.RUnlock()
+	return encoder(object, writer)
+}
+
+// errorEncoder is the function currently used to write the bodies of error responses.
+var errorEncoder ErrorEncoder = MarshalError
+
+// errorEncoderLock protects the errorEncoder variable.
+var errorEncoderLock sync.RWMutex
@@ -0,0 +1,97 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains tests for the error encoder.
+
+package errors
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core" // nolint
+	. "github.com/onsi/gomega"             // nolint
+)
+
+var _ = Describe("Error encoder", func() {
+	AfterEach(func() {
+		SetErrorEncoder(nil)
+	})
+
+	// Send sends a not found error and returns the recorded response.
+	var Send = func() *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodGet, "/api/junk", nil)
+		SendNotFound(recorder, request)
+		return recorder
+	}
+
+	It("Uses the default format", func() {
+		recorder := Send()
+		Expect(recorder.Code).To(Equal(http.StatusNotFound))
+		Expect(recorder.Header().Get("Content-Type")).To(Equal("application/json"))
+		Expect(recorder.Body.String()).To(MatchJSON(`{
+			"kind": "Error",
+			"id": "404",
+			"reason": "Can't find resource for path '/api/junk'"
+		}`))
+	})
+
+	It("Uses the custom encoder", func() {
+		SetErrorEncoder(func(object *Error, writer io.Writer) error {
+			return json.NewEncoder(writer).Encode(map[string]string{
+				"message": object.Reason(),
+			})
+		})
+		recorder := Send()
+		Expect(recorder.Code).To(Equal(http.StatusNotFound))
+		Expect(recorder.Header().Get("Content-Type")).To(Equal("application/json"))
+		Expect(recorder.Body.String()).To(MatchJSON(`{
+			"message": "Can't find resource for path '/api/junk'"
+		}`))
+	})
+
+	It("Uses the custom encoder for panics", func() {
+		SetErrorEncoder(func(object *Error, writer io.Writer) error {
+			return json.NewEncoder(writer).Encode(map[string]string{
+				"message": object.Reason(),
+			})
+		})
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodGet, "/api", nil)
+		SendPanic(recorder, request)
+		var body map[string]string
+		err := json.Unmarshal(recorder.Body.Bytes(), &body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(body).To(HaveKey("message"))
+	})
+
+	It("Restores the default encoder when nil is given", func() {
+		SetErrorEncoder(func(object *Error, writer io.Writer) error {
+			_, err := writer.Write([]byte("{}"))
+			return err
+		})
+		SetErrorEncoder(nil)
+		recorder := Send()
+		Expect(recorder.Body.String()).To(MatchJSON(`{
+			"kind": "Error",
+			"id": "404",
+			"reason": "Can't find resource for path '/api/junk'"
+		}`))
+	})
+})
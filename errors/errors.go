@@ -23,10 +23,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"strconv"
 	"strings"
 
-	"github.com/golang/glog"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/openshift-online/ocm-sdk-go/helpers"
 )
@@ -59,6 +57,9 @@ type Error struct {
 	reason      string
 	details     interface{}
 	operationID string
+
+	// This isn't part of the model, it is set and read by the functions in rate_limit.go.
+	rateLimit *RateLimitInfo
 }
 
 // NewError creates a new builder that can then be used to create error objects.
@@ -332,17 +333,6 @@ func UnmarshalError(source interface{}) (object *Error, err error) {
 	return
 }
 
-// UnmarshalErrorStatus reads an error from the given source and sets
-// the given status code.
-func UnmarshalErrorStatus(source interface{}, status int) (object *Error, err error) {
-	object, err = UnmarshalError(source)
-	if err != nil {
-		return
-	}
-	object.status = status
-	object.bitmap_ |= 1
-	return
-}
 func readError(iterator *jsoniter.Iterator) *Error {
 	object := &Error{}
 	for {
@@ -381,7 +371,7 @@ func readError(iterator *jsoniter.Iterator) *Error {
 
 // MarshalError writes an error to the given writer.
 func MarshalError(e *Error, writer io.Writer) error {
-	stream := helpers.NewStream(writer)
+	stream := helpers.NewRawStream(writer)
 	writeError(e, stream)
 	err := stream.Flush()
 	if err != nil {
@@ -438,38 +428,6 @@ var panicError, _ = NewError().
 		"for details").
 	Build()
 
-// SendError writes a given error and status code to a response writer.
-// if an error occurred it will log the error and exit.
-// This methods is used internaly and no backwards compatibily is guaranteed.
-func SendError(w http.ResponseWriter, r *http.Request, object *Error) {
-	status, err := strconv.Atoi(object.ID())
-	if err != nil {
-		SendPanic(w, r)
-		return
-	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	err = MarshalError(object, w)
-	if err != nil {
-		glog.Errorf("Can't send response body for request '%s'", r.URL.Path)
-		return
-	}
-}
-
-// SendPanic sends a panic error response to the client, but it doesn't end the process.
-// This methods is used internaly and no backwards compatibily is guaranteed.
-func SendPanic(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	err := MarshalError(panicError, w)
-	if err != nil {
-		glog.Errorf(
-			"Can't send panic response for request '%s': %s",
-			r.URL.Path,
-			err.Error(),
-		)
-	}
-}
-
 // SendNotFound sends a generic 404 error.
 func SendNotFound(w http.ResponseWriter, r *http.Request) {
 	reason := fmt.Sprintf(
@@ -0,0 +1,94 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains tests for the error builder.
+
+package errors
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core" // nolint
+	. "github.com/onsi/gomega"             // nolint
+)
+
+var _ = Describe("Error builder", func() {
+	It("Creates an error with the given attributes", func() {
+		object, err := NewError().
+			Status(http.StatusNotFound).
+			ID("404").
+			Code("CLUSTERS-MGMT-404").
+			Reason("Cluster '123' not found").
+			OperationID("456").
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(object.Status()).To(Equal(http.StatusNotFound))
+		Expect(object.ID()).To(Equal("404"))
+		Expect(object.Code()).To(Equal("CLUSTERS-MGMT-404"))
+		Expect(object.Reason()).To(Equal("Cluster '123' not found"))
+		Expect(object.OperationID()).To(Equal("456"))
+		Expect(object.Error()).To(ContainSubstring("404"))
+		Expect(object.Error()).To(ContainSubstring("CLUSTERS-MGMT-404"))
+		Expect(object.Error()).To(ContainSubstring("Cluster '123' not found"))
+	})
+
+	It("Leaves unset attributes empty", func() {
+		object, err := NewError().
+			Reason("my reason").
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		_, ok := object.GetStatus()
+		Expect(ok).To(BeFalse())
+		_, ok = object.GetCode()
+		Expect(ok).To(BeFalse())
+		Expect(object.Status()).To(BeZero())
+		Expect(object.Code()).To(BeEmpty())
+	})
+
+	It("Creates an error that can be found in a chain", func() {
+		object, err := NewError().
+			Status(http.StatusConflict).
+			Reason("my reason").
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		wrapped := fmt.Errorf("can't create cluster: %w", object)
+		var target *Error
+		Expect(errors.As(wrapped, &target)).To(BeTrue())
+		Expect(target.Status()).To(Equal(http.StatusConflict))
+	})
+
+	It("Creates an error that is the same after a round trip", func() {
+		object, err := NewError().
+			Status(http.StatusBadRequest).
+			ID("400").
+			Code("CLUSTERS-MGMT-400").
+			Reason("my reason").
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		buffer := &bytes.Buffer{}
+		err = MarshalError(object, buffer)
+		Expect(err).ToNot(HaveOccurred())
+		parsed, err := UnmarshalErrorStatus(buffer.Bytes(), http.StatusBadRequest)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(parsed.Status()).To(Equal(object.Status()))
+		Expect(parsed.ID()).To(Equal(object.ID()))
+		Expect(parsed.Code()).To(Equal(object.Code()))
+		Expect(parsed.Reason()).To(Equal(object.Reason()))
+	})
+})
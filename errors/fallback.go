@@ -0,0 +1,105 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the functions that build errors from responses that don't contain a valid
+// JSON error, for example when they are generated by a proxy. The UnmarshalErrorStatus function is
+// here instead of in the generated errors.go file because it falls back to those errors.
+
+package errors
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/openshift-online/ocm-sdk-go/internal"
+)
+
+// FromResponse reads the body of the given error response and returns the corresponding error.
+// If the body isn't a valid JSON error the result will contain the status code of the response
+// and a summary of the raw body as the reason, so that the status is never lost. Note that this
+// consumes the body of the response, but doesn't close it. The rate limit information contained
+// in the headers of the response, if any, is also added to the error.
+func FromResponse(response *http.Response) (object *Error, err error) {
+	object, err = UnmarshalErrorStatusHeader(
+		response.Body,
+		response.StatusCode,
+		response.Header,
+	)
+	return
+}
+
+// UnmarshalErrorStatus reads an error from the given source and sets the given status code. If the
+// source isn't a valid JSON error the result will contain only the status code and a summary of
+// the raw content as the reason.
+func UnmarshalErrorStatus(source interface{}, status int) (object *Error, err error) {
+	raw, err := rawErrorSource(source)
+	if err != nil {
+		return
+	}
+	if raw != nil {
+		source = raw
+	}
+	object, err = UnmarshalError(source)
+	if err != nil {
+		if raw == nil {
+			return
+		}
+		object = fallbackError(status, raw)
+		err = nil
+		return
+	}
+	object.status = status
+	object.bitmap_ |= 1
+	return
+}
+
+// rawErrorSource reads the content of the given source, if it is a reader, so that it can be used
+// again to build the fallback error when it isn't valid JSON. It returns nil if the source
+// isn't a reader, a slice of bytes or a string.
+func rawErrorSource(source interface{}) (result []byte, err error) {
+	switch typed := source.(type) {
+	case []byte:
+		result = typed
+	case string:
+		result = []byte(typed)
+	case io.Reader:
+		result, err = io.ReadAll(typed)
+		if err != nil {
+			err = fmt.Errorf("can't read error: %w", err)
+			return
+		}
+		if result == nil {
+			result = []byte{}
+		}
+	}
+	return
+}
+
+// fallbackError creates an error containing the given status code and a summary of the given raw
+// content as the reason.
+func fallbackError(status int, raw []byte) *Error {
+	object := &Error{
+		status:  status,
+		bitmap_: 1,
+	}
+	summary := internal.ContentSummary("", raw)
+	if summary != "" {
+		object.reason = summary
+		object.bitmap_ |= 16
+	}
+	return object
+}
@@ -0,0 +1,86 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains tests for the functions that build errors from malformed responses.
+
+package errors
+
+import (
+	"io"
+	"net/http"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core" // nolint
+	. "github.com/onsi/gomega"             // nolint
+)
+
+var _ = Describe("Malformed errors", func() {
+	It("Reads valid error and sets status", func() {
+		object, err := UnmarshalErrorStatus(
+			strings.NewReader(`{"kind": "Error", "reason": "My reason"}`),
+			http.StatusNotFound,
+		)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(object.Status()).To(Equal(http.StatusNotFound))
+		Expect(object.Reason()).To(Equal("My reason"))
+	})
+
+	It("Uses raw content when reader isn't JSON", func() {
+		object, err := UnmarshalErrorStatus(
+			strings.NewReader("Bad gateway"),
+			http.StatusBadGateway,
+		)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(object.Status()).To(Equal(http.StatusBadGateway))
+		Expect(object.Reason()).To(Equal("Bad gateway"))
+	})
+
+	It("Uses raw content when string isn't JSON", func() {
+		object, err := UnmarshalErrorStatus("{junk", http.StatusBadGateway)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(object.Status()).To(Equal(http.StatusBadGateway))
+		Expect(object.Reason()).To(Equal("{junk"))
+	})
+
+	It("Truncates long raw content", func() {
+		content := strings.Repeat("x", 1000)
+		object, err := UnmarshalErrorStatus(content, http.StatusBadGateway)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(object.Status()).To(Equal(http.StatusBadGateway))
+		Expect(object.Reason()).To(HaveLen(253))
+		Expect(object.Reason()).To(HaveSuffix("..."))
+	})
+
+	It("Doesn't set reason when content is empty", func() {
+		object, err := UnmarshalErrorStatus("", http.StatusBadGateway)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(object.Status()).To(Equal(http.StatusBadGateway))
+		_, ok := object.GetReason()
+		Expect(ok).To(BeFalse())
+		Expect(object.Error()).To(Equal("status is 502"))
+	})
+
+	It("Builds error from response", func() {
+		response := &http.Response{
+			StatusCode: http.StatusServiceUnavailable,
+			Body:       io.NopCloser(strings.NewReader("<html>Unavailable</html>")),
+		}
+		object, err := FromResponse(response)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(object.Status()).To(Equal(http.StatusServiceUnavailable))
+		Expect(object.Reason()).To(Equal("<html>Unavailable</html>"))
+	})
+})
@@ -0,0 +1,60 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the logger used by the functions that send error responses.
+
+package errors
+
+import (
+	"sync"
+
+	"github.com/openshift-online/ocm-sdk-go/logging"
+)
+
+// SetLogger sets the logger that will be used by the SendError function, and by the rest of the
+// functions that send errors, to report the problems that happen while sending the responses.
+// These functions are used by the generated server adapters, so this is the way to send their
+// error messages to the logger of the server instead of directly to the `glog` package:
+//
+//	errors.SetLogger(logger)
+//
+// Passing nil restores the default logger, which is based on the `glog` package and writes the
+// messages with the error level. This affects all the errors sent by the process, so it should
+// usually be called only once, during initialization.
+func SetLogger(value logging.Logger) {
+	serverLoggerLock.Lock()
+	defer serverLoggerLock.Unlock()
+	if value == nil {
+		value = defaultServerLogger
+	}
+	serverLogger = value
+}
+
+// currentLogger returns the logger that has been set with the SetLogger function.
+func currentLogger() logging.Logger {
+	serverLoggerLock.RLock()
+	defer serverLoggerLock.RUnlock()
+	return serverLogger
+}
+
+// defaultServerLogger is the logger used when no other has been set with the SetLogger function.
+var defaultServerLogger, _ = logging.NewGlogLoggerBuilder().Build()
+
+// serverLogger is the logger currently used by the functions that send errors.
+var serverLogger logging.Logger = defaultServerLogger
+
+// serverLoggerLock protects the serverLogger variable.
+var serverLoggerLock sync.RWMutex
@@ -0,0 +1,82 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains tests for the logger used by the functions that send errors.
+
+package errors
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core" // nolint
+	. "github.com/onsi/gomega"             // nolint
+
+	"github.com/openshift-online/ocm-sdk-go/logging"
+)
+
+var _ = Describe("Logger", func() {
+	var buffer *bytes.Buffer
+
+	BeforeEach(func() {
+		// Create a logger that writes to a buffer:
+		buffer = &bytes.Buffer{}
+		logger, err := logging.NewStdLoggerBuilder().
+			Streams(buffer, buffer).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		SetLogger(logger)
+
+		// Use an encoder that always fails, so that there is something to log:
+		SetErrorEncoder(func(object *Error, writer io.Writer) error {
+			return errors.New("my error")
+		})
+	})
+
+	AfterEach(func() {
+		SetLogger(nil)
+		SetErrorEncoder(nil)
+	})
+
+	It("Uses the custom logger when the body can't be sent", func() {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodGet, "/api/junk", nil)
+		SendNotFound(recorder, request)
+		Expect(buffer.String()).To(ContainSubstring(
+			"Can't send response body for request '/api/junk'",
+		))
+	})
+
+	It("Uses the custom logger when the panic can't be sent", func() {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodGet, "/api", nil)
+		SendPanic(recorder, request)
+		Expect(buffer.String()).To(ContainSubstring(
+			"Can't send panic response for request '/api': my error",
+		))
+	})
+
+	It("Restores the default logger when nil is given", func() {
+		SetLogger(nil)
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodGet, "/api/junk", nil)
+		SendNotFound(recorder, request)
+		Expect(buffer.String()).To(BeEmpty())
+	})
+})
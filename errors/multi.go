@@ -0,0 +1,111 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the type that aggregates multiple errors.
+
+package errors
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MultiError aggregates several errors, for example the errors returned by the server for the
+// different items of a bulk operation. It implements the error interface, with a message that
+// combines the messages of the individual errors, and it supports the Is and As functions of the
+// standard errors package, which will check each of the individual errors. Don't create objects
+// of this type directly; use the NewMultiError function instead.
+type MultiError struct {
+	items []*Error
+}
+
+// Make sure that we implement the interface:
+var _ error = (*MultiError)(nil)
+
+// NewMultiError creates a new error that aggregates the given errors. Nil errors are ignored.
+func NewMultiError(items ...*Error) *MultiError {
+	result := &MultiError{}
+	for _, item := range items {
+		result.Add(item)
+	}
+	return result
+}
+
+// Add adds the given error. Nil errors are ignored.
+func (e *MultiError) Add(item *Error) {
+	if item != nil {
+		e.items = append(e.items, item)
+	}
+}
+
+// Len returns the number of errors.
+func (e *MultiError) Len() int {
+	if e == nil {
+		return 0
+	}
+	return len(e.items)
+}
+
+// Errors returns a copy of the list of errors.
+func (e *MultiError) Errors() []*Error {
+	if e == nil {
+		return nil
+	}
+	result := make([]*Error, len(e.items))
+	copy(result, e.items)
+	return result
+}
+
+// ErrorOrNil returns this error if it contains at least one error, and nil otherwise. This is
+// convenient to return the result of a bulk operation where all the items may have succeeded:
+//
+//	failures := errors.NewMultiError()
+//	for _, id := range ids {
+//		...
+//		failures.Add(err)
+//	}
+//	return failures.ErrorOrNil()
+func (e *MultiError) ErrorOrNil() error {
+	if e.Len() == 0 {
+		return nil
+	}
+	return e
+}
+
+// Error is the implementation of the error interface.
+func (e *MultiError) Error() string {
+	switch e.Len() {
+	case 0:
+		return "no errors"
+	case 1:
+		return e.items[0].Error()
+	}
+	messages := make([]string, len(e.items))
+	for i, item := range e.items {
+		messages[i] = item.Error()
+	}
+	return fmt.Sprintf("%d errors: %s", len(e.items), strings.Join(messages, "; "))
+}
+
+// Unwrap returns the individual errors, so that the Is and As functions of the standard errors
+// package can check them.
+func (e *MultiError) Unwrap() []error {
+	result := make([]error, e.Len())
+	for i := range result {
+		result[i] = e.items[i]
+	}
+	return result
+}
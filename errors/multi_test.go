@@ -0,0 +1,90 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains tests for the type that aggregates multiple errors.
+
+package errors
+
+import (
+	"errors"
+	"net/http"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core" // nolint
+	. "github.com/onsi/gomega"             // nolint
+)
+
+var _ = Describe("Multi error", func() {
+	// MakeError creates an error with the given status and reason.
+	var MakeError = func(status int, reason string) *Error {
+		result, err := NewError().
+			Status(status).
+			Reason(reason).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		return result
+	}
+
+	It("Ignores nil errors", func() {
+		multi := NewMultiError(nil, MakeError(http.StatusNotFound, "Not found"), nil)
+		multi.Add(nil)
+		Expect(multi.Len()).To(Equal(1))
+	})
+
+	It("Returns nil when empty", func() {
+		multi := NewMultiError()
+		Expect(multi.ErrorOrNil()).To(BeNil())
+	})
+
+	It("Returns itself when not empty", func() {
+		multi := NewMultiError(MakeError(http.StatusNotFound, "Not found"))
+		Expect(multi.ErrorOrNil()).To(BeIdenticalTo(multi))
+	})
+
+	It("Uses message of single error", func() {
+		multi := NewMultiError(MakeError(http.StatusNotFound, "Not found"))
+		Expect(multi.Error()).To(Equal("status is 404: Not found"))
+	})
+
+	It("Combines messages of multiple errors", func() {
+		multi := NewMultiError(
+			MakeError(http.StatusNotFound, "Not found"),
+			MakeError(http.StatusConflict, "Already exists"),
+		)
+		Expect(multi.Error()).To(Equal(
+			"2 errors: status is 404: Not found; status is 409: Already exists",
+		))
+	})
+
+	It("Returns copy of errors", func() {
+		first := MakeError(http.StatusNotFound, "Not found")
+		second := MakeError(http.StatusConflict, "Already exists")
+		multi := NewMultiError(first, second)
+		items := multi.Errors()
+		Expect(items).To(Equal([]*Error{first, second}))
+		items[0] = nil
+		Expect(multi.Errors()[0]).To(BeIdenticalTo(first))
+	})
+
+	It("Supports standard functions", func() {
+		first := MakeError(http.StatusNotFound, "Not found")
+		second := MakeError(http.StatusConflict, "Already exists")
+		var err error = NewMultiError(first, second)
+		Expect(errors.Is(err, second)).To(BeTrue())
+		var target *Error
+		Expect(errors.As(err, &target)).To(BeTrue())
+		Expect(target).To(BeIdenticalTo(first))
+	})
+})
@@ -0,0 +1,227 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the type and functions that extract rate limit information from the headers
+// of responses.
+
+package errors
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Names of the rate limit headers:
+const (
+	retryAfterHeader         = "Retry-After"
+	rateLimitLimitHeader     = "X-RateLimit-Limit"
+	rateLimitRemainingHeader = "X-RateLimit-Remaining"
+	rateLimitResetHeader     = "X-RateLimit-Reset"
+)
+
+// rateLimitEpoch is the value above which the `X-RateLimit-Reset` header is interpreted as a Unix
+// time instead of a number of seconds. Servers use both conventions, but a number of seconds this
+// large would mean more than thirty years.
+const rateLimitEpoch = 1000000000
+
+// RateLimitInfo contains the rate limit information extracted from the headers of a response with
+// the 429 status code, or with the 503 status code and the `Retry-After` header. The generated
+// clients return these responses as usual, so the information can be extracted from the headers of
+// the response:
+//
+//	response, err := connection.ClustersMgmt().V1().Clusters().Cluster(id).Get().Send()
+//	if response != nil && response.Status() == http.StatusTooManyRequests {
+//		info := errors.ParseRateLimit(response.Header())
+//		if info != nil && info.HasReset {
+//			fmt.Printf("Try again in %s\n", info.RetryIn().Round(time.Second))
+//		}
+//	}
+//
+// Errors created from complete responses, with the FromResponse or UnmarshalErrorStatusHeader
+// functions, and the errors that the connection returns when the body of the response isn't JSON,
+// also contain this information. Use the RateLimit method of the error to get it.
+type RateLimitInfo struct {
+	// Limit is the maximum number of requests allowed in the current window, from the
+	// `X-RateLimit-Limit` header. It is only meaningful when HasLimit is true.
+	Limit int
+
+	// HasLimit indicates if the limit is known.
+	HasLimit bool
+
+	// Remaining is the number of requests remaining in the current window, from the
+	// `X-RateLimit-Remaining` header. It is only meaningful when HasRemaining is true.
+	Remaining int
+
+	// HasRemaining indicates if the number of remaining requests is known.
+	HasRemaining bool
+
+	// Reset is the time when it will be possible to send requests again. It is calculated from
+	// the `Retry-After` header, or from the `X-RateLimit-Reset` header if the first isn't
+	// present. It is only meaningful when HasReset is true.
+	Reset time.Time
+
+	// HasReset indicates if the reset time is known.
+	HasReset bool
+}
+
+// RetryIn returns the time that remains till the reset time. It returns zero if the reset time
+// isn't known or if it has already passed.
+func (i *RateLimitInfo) RetryIn() time.Duration {
+	if i == nil || !i.HasReset {
+		return 0
+	}
+	result := time.Until(i.Reset)
+	if result < 0 {
+		result = 0
+	}
+	return result
+}
+
+// RateLimit returns the rate limit information extracted from the headers of the response that
+// contained the error, or nil if it wasn't added. See the WithRateLimit function for details.
+func (e *Error) RateLimit() *RateLimitInfo {
+	if e != nil && e.bitmap_&128 != 0 {
+		return e.rateLimit
+	}
+	return nil
+}
+
+// GetRateLimit returns the rate limit information and a flag indicating if it was
+// added to the error.
+func (e *Error) GetRateLimit() (value *RateLimitInfo, ok bool) {
+	ok = e != nil && e.bitmap_&128 != 0
+	if ok {
+		value = e.rateLimit
+	}
+	return
+}
+
+// ParseRateLimit extracts the rate limit information from the given response header. It returns
+// nil if the header doesn't contain any of the `Retry-After`, `X-RateLimit-Limit`,
+// `X-RateLimit-Remaining` or `X-RateLimit-Reset` headers with a valid value.
+func ParseRateLimit(header http.Header) *RateLimitInfo {
+	return parseRateLimit(header, time.Now())
+}
+
+// UnmarshalErrorStatusHeader reads an error from the given source and sets the given status code,
+// like UnmarshalErrorStatus. In addition it adds the rate limit information extracted from the
+// given response header, like the WithRateLimit function.
+func UnmarshalErrorStatusHeader(source interface{}, status int,
+	header http.Header) (object *Error, err error) {
+	object, err = UnmarshalErrorStatus(source, status)
+	if err != nil {
+		return
+	}
+	object = WithRateLimit(object, header)
+	return
+}
+
+// WithRateLimit returns a copy of the given error that also contains the rate limit information
+// extracted from the given response header. The information is only added if the status of the
+// error is 429, or 503 and the header contains `Retry-After`, because gateways may add rate limit
+// headers to all the responses. Otherwise the error is returned unchanged.
+func WithRateLimit(object *Error, header http.Header) *Error {
+	if object == nil || !isRateLimitStatus(object.Status(), header) {
+		return object
+	}
+	rateLimit := ParseRateLimit(header)
+	if rateLimit == nil {
+		return object
+	}
+	result := *object
+	result.rateLimit = rateLimit
+	result.bitmap_ |= 128
+	return &result
+}
+
+// isRateLimitStatus checks if the given status code and header correspond to a response that was
+// rejected because of rate limiting.
+func isRateLimitStatus(status int, header http.Header) bool {
+	switch status {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusServiceUnavailable:
+		return header.Get(retryAfterHeader) != ""
+	default:
+		return false
+	}
+}
+
+func parseRateLimit(header http.Header, now time.Time) *RateLimitInfo {
+	if header == nil {
+		return nil
+	}
+	result := &RateLimitInfo{}
+	result.Limit, result.HasLimit = parseRateLimitInt(header.Get(rateLimitLimitHeader))
+	result.Remaining, result.HasRemaining = parseRateLimitInt(
+		header.Get(rateLimitRemainingHeader),
+	)
+	result.Reset, result.HasReset = parseRetryAfter(header.Get(retryAfterHeader), now)
+	if !result.HasReset {
+		result.Reset, result.HasReset = parseRateLimitReset(
+			header.Get(rateLimitResetHeader), now,
+		)
+	}
+	if !result.HasLimit && !result.HasRemaining && !result.HasReset {
+		return nil
+	}
+	return result
+}
+
+// parseRetryAfter parses the value of the `Retry-After` header, which can be a number of seconds
+// or an HTTP date.
+func parseRetryAfter(value string, now time.Time) (result time.Time, ok bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return
+	}
+	seconds, ok := parseRateLimitInt(value)
+	if ok {
+		result = now.Add(time.Duration(seconds) * time.Second)
+		return
+	}
+	result, err := http.ParseTime(value)
+	ok = err == nil
+	return
+}
+
+// parseRateLimitReset parses the value of the `X-RateLimit-Reset` header, which can be a number of
+// seconds or a Unix time.
+func parseRateLimitReset(value string, now time.Time) (result time.Time, ok bool) {
+	number, ok := parseRateLimitInt(value)
+	if !ok {
+		return
+	}
+	if number >= rateLimitEpoch {
+		result = time.Unix(int64(number), 0)
+	} else {
+		result = now.Add(time.Duration(number) * time.Second)
+	}
+	return
+}
+
+// parseRateLimitInt parses a non negative integer header value.
+func parseRateLimitInt(value string) (result int, ok bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return
+	}
+	result, err := strconv.Atoi(value)
+	ok = err == nil && result >= 0
+	return
+}
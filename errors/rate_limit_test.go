@@ -0,0 +1,165 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains tests for the rate limit information.
+
+package errors
+
+import (
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core" // nolint
+	. "github.com/onsi/gomega"             // nolint
+)
+
+var _ = Describe("Rate limit", func() {
+	var now time.Time
+
+	BeforeEach(func() {
+		now = time.Date(2023, time.March, 1, 10, 0, 0, 0, time.UTC)
+	})
+
+	It("Returns nil if there are no headers", func() {
+		Expect(parseRateLimit(nil, now)).To(BeNil())
+		Expect(parseRateLimit(http.Header{}, now)).To(BeNil())
+	})
+
+	It("Returns nil if headers are invalid", func() {
+		header := http.Header{}
+		header.Set("Retry-After", "junk")
+		header.Set("X-RateLimit-Limit", "-1")
+		Expect(parseRateLimit(header, now)).To(BeNil())
+	})
+
+	It("Parses limit and remaining", func() {
+		header := http.Header{}
+		header.Set("X-RateLimit-Limit", "100")
+		header.Set("X-RateLimit-Remaining", "42")
+		info := parseRateLimit(header, now)
+		Expect(info).ToNot(BeNil())
+		Expect(info.HasLimit).To(BeTrue())
+		Expect(info.Limit).To(Equal(100))
+		Expect(info.HasRemaining).To(BeTrue())
+		Expect(info.Remaining).To(Equal(42))
+		Expect(info.HasReset).To(BeFalse())
+		Expect(info.RetryIn()).To(BeZero())
+	})
+
+	It("Parses retry after in seconds", func() {
+		header := http.Header{}
+		header.Set("Retry-After", "120")
+		info := parseRateLimit(header, now)
+		Expect(info).ToNot(BeNil())
+		Expect(info.HasReset).To(BeTrue())
+		Expect(info.Reset).To(Equal(now.Add(2 * time.Minute)))
+	})
+
+	It("Parses retry after as date", func() {
+		header := http.Header{}
+		header.Set("Retry-After", "Wed, 01 Mar 2023 10:05:00 GMT")
+		info := parseRateLimit(header, now)
+		Expect(info).ToNot(BeNil())
+		Expect(info.HasReset).To(BeTrue())
+		Expect(info.Reset.Equal(now.Add(5 * time.Minute))).To(BeTrue())
+	})
+
+	It("Parses reset in seconds", func() {
+		header := http.Header{}
+		header.Set("X-RateLimit-Reset", "60")
+		info := parseRateLimit(header, now)
+		Expect(info).ToNot(BeNil())
+		Expect(info.HasReset).To(BeTrue())
+		Expect(info.Reset).To(Equal(now.Add(time.Minute)))
+	})
+
+	It("Parses reset as Unix time", func() {
+		header := http.Header{}
+		header.Set("X-RateLimit-Reset", "1677665100")
+		info := parseRateLimit(header, now)
+		Expect(info).ToNot(BeNil())
+		Expect(info.HasReset).To(BeTrue())
+		Expect(info.Reset.Equal(now.Add(5 * time.Minute))).To(BeTrue())
+	})
+
+	It("Prefers retry after to reset", func() {
+		header := http.Header{}
+		header.Set("Retry-After", "10")
+		header.Set("X-RateLimit-Reset", "60")
+		info := parseRateLimit(header, now)
+		Expect(info).ToNot(BeNil())
+		Expect(info.Reset).To(Equal(now.Add(10 * time.Second)))
+	})
+
+	It("Returns zero retry time if reset has passed", func() {
+		info := &RateLimitInfo{
+			Reset:    time.Now().Add(-time.Minute),
+			HasReset: true,
+		}
+		Expect(info.RetryIn()).To(BeZero())
+	})
+
+	It("Adds information to unmarshalled error", func() {
+		header := http.Header{}
+		header.Set("Retry-After", "5")
+		object, err := UnmarshalErrorStatusHeader(
+			`{"kind": "Error", "reason": "Slow down"}`,
+			http.StatusTooManyRequests,
+			header,
+		)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(object.Status()).To(Equal(http.StatusTooManyRequests))
+		info, ok := object.GetRateLimit()
+		Expect(ok).To(BeTrue())
+		Expect(info.HasReset).To(BeTrue())
+	})
+
+	It("Adds information to error with retry after and 503", func() {
+		header := http.Header{}
+		header.Set("Retry-After", "5")
+		original, err := NewError().
+			Status(http.StatusServiceUnavailable).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		object := WithRateLimit(original, header)
+		Expect(object.RateLimit()).ToNot(BeNil())
+		Expect(original.RateLimit()).To(BeNil())
+	})
+
+	It("Doesn't add information to other errors", func() {
+		header := http.Header{}
+		header.Set("X-RateLimit-Remaining", "10")
+		header.Set("Retry-After", "5")
+		original, err := NewError().
+			Status(http.StatusNotFound).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		object := WithRateLimit(original, header)
+		Expect(object).To(BeIdenticalTo(original))
+		Expect(object.RateLimit()).To(BeNil())
+	})
+
+	It("Doesn't add information to 503 without retry after", func() {
+		header := http.Header{}
+		header.Set("X-RateLimit-Remaining", "0")
+		original, err := NewError().
+			Status(http.StatusServiceUnavailable).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		object := WithRateLimit(original, header)
+		Expect(object.RateLimit()).To(BeNil())
+	})
+})
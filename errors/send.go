@@ -0,0 +1,63 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the functions that write the bodies of error responses. They aren't in the
+// generated errors.go file because they use the encoder set with the SetErrorEncoder function and
+// the logger set with the SetLogger function.
+
+package errors
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// SendError writes a given error and status code to a response writer.
+// if an error occurred it will log the error and exit.
+// This methods is used internaly and no backwards compatibily is guaranteed.
+func SendError(w http.ResponseWriter, r *http.Request, object *Error) {
+	status, err := strconv.Atoi(object.ID())
+	if err != nil {
+		SendPanic(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err = encodeError(object, w)
+	if err != nil {
+		currentLogger().Error(
+			r.Context(),
+			"Can't send response body for request '%s'",
+			r.URL.Path,
+		)
+		return
+	}
+}
+
+// SendPanic sends a panic error response to the client, but it doesn't end the process.
+// This methods is used internaly and no backwards compatibily is guaranteed.
+func SendPanic(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	err := encodeError(panicError, w)
+	if err != nil {
+		currentLogger().Error(
+			r.Context(),
+			"Can't send panic response for request '%s': %s",
+			r.URL.Path,
+			err.Error(),
+		)
+	}
+}
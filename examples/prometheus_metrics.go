@@ -21,25 +21,19 @@ package main
 import (
 	"context"
 	"fmt"
-	"net/http"
 	"os"
 	"time"
 
-	"github.com/prometheus/client_golang/prometheus/promhttp"
-
 	sdk "github.com/openshift-online/ocm-sdk-go"
 	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
 	"github.com/openshift-online/ocm-sdk-go/logging"
+	"github.com/openshift-online/ocm-sdk-go/metrics"
 )
 
 func main() {
 	// Create a context:
 	ctx := context.Background()
 
-	// Create and start a Prometheus metric server that will respond to any request in port
-	// 8000, regardless of the request path.
-	go http.ListenAndServe(":8000", promhttp.Handler())
-
 	// Create a logger that has the debug level enabled:
 	logger, err := logging.NewGoLoggerBuilder().
 		Debug(true).
@@ -49,6 +43,18 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Create and start a Prometheus metric server that will publish the metrics in the
+	// `/metrics` path of port 8000.
+	server, err := metrics.NewHTTPServer().
+		Logger(logger).
+		Address(":8000").
+		Build(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Can't build metrics server: %v\n", err)
+		os.Exit(1)
+	}
+	defer server.Shutdown(ctx)
+
 	// Create the connection, specifying the `api_outbound` subsystem so that metrics are
 	// enabled and available with the `api_outbound_` prefix.
 	token := os.Getenv("OCM_TOKEN")
@@ -68,7 +74,7 @@ func main() {
 
 	// Send requests to retrieve the first page of clusters, the details of the cluster, the
 	// logs, and the credentials, in a loop, to accumulate metrics. To see the metrics point
-	// your browser to http://localhost:8000.
+	// your browser to http://localhost:8000/metrics.
 	for {
 		// Get the list of clusters:
 		clustersListResponse, err := clustersCollection.List().SendContext(ctx)
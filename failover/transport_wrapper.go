@@ -0,0 +1,262 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the implementation of a transport wrapper that sends requests to a secondary
+// round tripper when the primary one fails.
+
+package failover
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/openshift-online/ocm-sdk-go/internal"
+	"github.com/openshift-online/ocm-sdk-go/logging"
+)
+
+// Names of the backends, used as values of the `backend` label of the metrics:
+const (
+	PrimaryBackend   = "primary"
+	SecondaryBackend = "secondary"
+)
+
+// backendLabelName is the name of the label that contains the name of the backend.
+const backendLabelName = "backend"
+
+// TransportWrapperBuilder contains the data and logic needed to create a new failover transport
+// wrapper. The round trippers created by the wrapper send requests first to the round tripper that
+// they wrap, the primary, and if that fails with an error that isn't an HTTP response, for example
+// if it isn't possible to connect to the server, then they send the same request to the secondary
+// round tripper. Responses with HTTP error codes are returned to the caller as they are, without
+// trying the secondary.
+//
+// Note that the wrapper doesn't modify the URL of the request, so the secondary round tripper is
+// responsible for sending it to the secondary server.
+//
+// When the subsystem is set the wrapper will also generate the following Prometheus metric:
+//
+//	<subsystem>_backend_request_count - Number of requests served by each backend.
+//
+// This metric has a `backend` label that contains `primary` or `secondary`.
+//
+// Don't create objects of this type directly; use the NewTransportWrapper function instead.
+type TransportWrapperBuilder struct {
+	logger     logging.Logger
+	secondary  http.RoundTripper
+	subsystem  string
+	registerer prometheus.Registerer
+}
+
+// TransportWrapper contains the data and logic needed to wrap an HTTP round tripper with another
+// one that sends requests to a secondary round tripper when the primary fails.
+type TransportWrapper struct {
+	logger       logging.Logger
+	secondary    http.RoundTripper
+	requestCount *prometheus.CounterVec
+}
+
+// roundTripper is a round tripper that sends requests to a secondary round tripper when the
+// primary fails.
+type roundTripper struct {
+	owner   *TransportWrapper
+	primary http.RoundTripper
+}
+
+// Make sure that we implement the interface:
+var _ http.RoundTripper = (*roundTripper)(nil)
+
+// NewTransportWrapper creates a new builder that can then be used to configure and create a new
+// failover round tripper.
+func NewTransportWrapper() *TransportWrapperBuilder {
+	return &TransportWrapperBuilder{
+		registerer: prometheus.DefaultRegisterer,
+	}
+}
+
+// Logger sets the logger that will be used by the wrapper and by the round trippers that it
+// creates. This is mandatory.
+func (b *TransportWrapperBuilder) Logger(value logging.Logger) *TransportWrapperBuilder {
+	b.logger = value
+	return b
+}
+
+// Secondary sets the round tripper that will be used to send requests when the primary fails.
+// This is mandatory.
+func (b *TransportWrapperBuilder) Secondary(value http.RoundTripper) *TransportWrapperBuilder {
+	b.secondary = value
+	return b
+}
+
+// Subsystem sets the name of the subsystem that will be used to register the metrics with
+// Prometheus. For example, if the value is `api_outbound` then the following metric will be
+// registered:
+//
+//	api_outbound_backend_request_count - Number of requests served by each backend.
+//
+// The default is to not generate metrics.
+func (b *TransportWrapperBuilder) Subsystem(value string) *TransportWrapperBuilder {
+	b.subsystem = value
+	return b
+}
+
+// Registerer sets the Prometheus registerer that will be used to register the metrics. The default
+// is to use the default Prometheus registerer and there is usually no need to change that. This is
+// intended for unit tests, where it is convenient to have a registerer that doesn't interfere with
+// the rest of the system. Passing nil restores the default.
+func (b *TransportWrapperBuilder) Registerer(value prometheus.Registerer) *TransportWrapperBuilder {
+	if value == nil {
+		value = prometheus.DefaultRegisterer
+	}
+	b.registerer = value
+	return b
+}
+
+// Build uses the information stored in the builder to create a new transport wrapper.
+func (b *TransportWrapperBuilder) Build(ctx context.Context) (result *TransportWrapper, err error) {
+	// Check parameters:
+	if b.logger == nil {
+		err = fmt.Errorf("logger is mandatory")
+		return
+	}
+	if b.secondary == nil {
+		err = fmt.Errorf("secondary round tripper is mandatory")
+		return
+	}
+
+	// Register the request count metric:
+	var requestCount *prometheus.CounterVec
+	if b.subsystem != "" {
+		requestCount = prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Subsystem: b.subsystem,
+				Name:      "backend_request_count",
+				Help:      "Number of requests served by each backend.",
+			},
+			[]string{
+				backendLabelName,
+			},
+		)
+		requestCount, err = internal.RegisterCounterVec(
+			b.registerer,
+			b.subsystem+"_backend_request_count",
+			requestCount,
+		)
+		if err != nil {
+			return
+		}
+	}
+
+	// Create and populate the object:
+	result = &TransportWrapper{
+		logger:       b.logger,
+		secondary:    b.secondary,
+		requestCount: requestCount,
+	}
+
+	return
+}
+
+// Wrap creates a new round tripper that uses the given one as the primary and that sends the
+// requests to the secondary when it fails.
+func (w *TransportWrapper) Wrap(transport http.RoundTripper) http.RoundTripper {
+	return &roundTripper{
+		owner:   w,
+		primary: transport,
+	}
+}
+
+// Close releases all the resources used by the wrapper.
+func (w *TransportWrapper) Close() error {
+	return nil
+}
+
+// RoundTrip is the implementation of the round tripper interface.
+func (t *roundTripper) RoundTrip(request *http.Request) (response *http.Response, err error) {
+	// Get the context:
+	ctx := request.Context()
+
+	// If the request has a body then we need to read it fully and copy it in memory, so that we
+	// can later use that copy to send the request to the secondary. That isn't necessary if the
+	// request has a function to get a new copy of the body, as that is used instead. We also need
+	// to restore the old body before returning because the caller my rely on the type of body
+	// that it passed, for example.
+	originalBody := request.Body
+	defer func() {
+		request.Body = originalBody
+	}()
+	var bodyCopy []byte
+	if originalBody != nil && request.GetBody == nil {
+		bodyCopy, err = io.ReadAll(originalBody)
+		if err != nil {
+			return
+		}
+		request.Body = io.NopCloser(bytes.NewBuffer(bodyCopy))
+	}
+
+	// Try the primary first:
+	response, err = t.primary.RoundTrip(request)
+	if err == nil {
+		t.owner.count(PrimaryBackend)
+		return
+	}
+
+	// Don't try the secondary if the request was cancelled or timed out, as it would fail in
+	// the same way:
+	if ctx.Err() != nil {
+		return
+	}
+	t.owner.logger.Warn(
+		ctx,
+		"Request for method %s and URL '%s' failed with primary backend, "+
+			"will try secondary backend: %v",
+		request.Method, request.URL, err,
+	)
+
+	// Rewind the body and try the secondary:
+	switch {
+	case bodyCopy != nil:
+		request.Body = io.NopCloser(bytes.NewBuffer(bodyCopy))
+	case originalBody != nil && request.GetBody != nil:
+		request.Body, err = request.GetBody()
+		if err != nil {
+			err = fmt.Errorf("can't rewind request body: %w", err)
+			return
+		}
+	}
+	response, err = t.owner.secondary.RoundTrip(request)
+	if err != nil {
+		err = fmt.Errorf("can't send request with secondary backend: %w", err)
+		return
+	}
+	t.owner.count(SecondaryBackend)
+
+	return
+}
+
+// count increases the request count metric for the given backend, if enabled.
+func (w *TransportWrapper) count(backend string) {
+	if w.requestCount == nil {
+		return
+	}
+	w.requestCount.With(prometheus.Labels{
+		backendLabelName: backend,
+	}).Inc()
+}
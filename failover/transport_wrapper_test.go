@@ -0,0 +1,232 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains tests for the failover transport wrapper.
+
+package failover
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"             // nolint
+	. "github.com/onsi/gomega"                         // nolint
+	. "github.com/openshift-online/ocm-sdk-go/testing" // nolint
+)
+
+var _ = Describe("Creation", func() {
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.Background()
+	})
+
+	It("Can't be created without a logger", func() {
+		wrapper, err := NewTransportWrapper().
+			Secondary(JSONTransport(http.StatusOK, "{}")).
+			Build(ctx)
+		Expect(err).To(HaveOccurred())
+		Expect(wrapper).To(BeNil())
+		message := err.Error()
+		Expect(message).To(ContainSubstring("logger"))
+		Expect(message).To(ContainSubstring("mandatory"))
+	})
+
+	It("Can't be created without a secondary", func() {
+		wrapper, err := NewTransportWrapper().
+			Logger(logger).
+			Build(ctx)
+		Expect(err).To(HaveOccurred())
+		Expect(wrapper).To(BeNil())
+		message := err.Error()
+		Expect(message).To(ContainSubstring("secondary"))
+		Expect(message).To(ContainSubstring("mandatory"))
+	})
+
+	It("Can be created with logger and secondary", func() {
+		wrapper, err := NewTransportWrapper().
+			Logger(logger).
+			Secondary(JSONTransport(http.StatusOK, "{}")).
+			Build(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(wrapper).ToNot(BeNil())
+		err = wrapper.Close()
+		Expect(err).ToNot(HaveOccurred())
+	})
+})
+
+var _ = Describe("Failover", func() {
+	var ctx context.Context
+	var metricsServer *MetricsServer
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		metricsServer = NewMetricsServer()
+	})
+
+	AfterEach(func() {
+		metricsServer.Close()
+	})
+
+	// Client creates a client that uses the given primary and secondary transports.
+	var Client = func(primary, secondary http.RoundTripper) *http.Client {
+		wrapper, err := NewTransportWrapper().
+			Logger(logger).
+			Secondary(secondary).
+			Subsystem("my").
+			Registerer(metricsServer.Registry()).
+			Build(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		return &http.Client{
+			Transport: wrapper.Wrap(primary),
+		}
+	}
+
+	// Post sends a POST request with the given body and returns the response body.
+	var Post = func(client *http.Client, body string) (result string, err error) {
+		request, err := http.NewRequestWithContext(
+			ctx,
+			http.MethodPost,
+			"http://api.example.com/api/clusters_mgmt/v1/clusters",
+			strings.NewReader(body),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		response, err := client.Do(request)
+		if err != nil {
+			return
+		}
+		defer response.Body.Close()
+		data, err := io.ReadAll(response.Body)
+		Expect(err).ToNot(HaveOccurred())
+		result = string(data)
+		return
+	}
+
+	It("Uses the primary when it succeeds", func() {
+		client := Client(
+			JSONTransport(http.StatusOK, `{ "backend": "primary" }`),
+			ErrorTransport(errors.New("secondary shouldn't be used")),
+		)
+		body, err := Post(client, "{}")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(body).To(ContainSubstring("primary"))
+		metrics := metricsServer.Metrics()
+		Expect(metrics).To(MatchLine(`^my_backend_request_count\{backend="primary"\} 1$`))
+		Expect(metrics).ToNot(MatchLine(`^my_backend_request_count\{backend="secondary"\}.*$`))
+	})
+
+	It("Uses the secondary when the primary fails to connect", func() {
+		client := Client(
+			ErrorTransport(errors.New("connection refused")),
+			JSONTransport(http.StatusOK, `{ "backend": "secondary" }`),
+		)
+		body, err := Post(client, "{}")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(body).To(ContainSubstring("secondary"))
+		metrics := metricsServer.Metrics()
+		Expect(metrics).To(MatchLine(`^my_backend_request_count\{backend="secondary"\} 1$`))
+		Expect(metrics).ToNot(MatchLine(`^my_backend_request_count\{backend="primary"\}.*$`))
+	})
+
+	It("Doesn't use the secondary when the primary returns an error code", func() {
+		client := Client(
+			JSONTransport(http.StatusServiceUnavailable, `{ "backend": "primary" }`),
+			ErrorTransport(errors.New("secondary shouldn't be used")),
+		)
+		body, err := Post(client, "{}")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(body).To(ContainSubstring("primary"))
+	})
+
+	It("Sends the same body to the secondary", func() {
+		primary := TransportFunc(func(request *http.Request) (*http.Response, error) {
+			_, err := io.ReadAll(request.Body)
+			Expect(err).ToNot(HaveOccurred())
+			return nil, errors.New("connection reset by peer")
+		})
+		var received string
+		secondary := TransportFunc(func(request *http.Request) (*http.Response, error) {
+			data, err := io.ReadAll(request.Body)
+			Expect(err).ToNot(HaveOccurred())
+			received = string(data)
+			return JSONTransport(http.StatusOK, "{}").RoundTrip(request)
+		})
+		client := Client(primary, secondary)
+		_, err := Post(client, `{ "name": "my" }`)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(received).To(Equal(`{ "name": "my" }`))
+	})
+
+	It("Sends the same body to the secondary without get body function", func() {
+		primary := TransportFunc(func(request *http.Request) (*http.Response, error) {
+			_, err := io.ReadAll(request.Body)
+			Expect(err).ToNot(HaveOccurred())
+			return nil, errors.New("connection reset by peer")
+		})
+		var received string
+		secondary := TransportFunc(func(request *http.Request) (*http.Response, error) {
+			data, err := io.ReadAll(request.Body)
+			Expect(err).ToNot(HaveOccurred())
+			received = string(data)
+			return JSONTransport(http.StatusOK, "{}").RoundTrip(request)
+		})
+		client := Client(primary, secondary)
+		request, err := http.NewRequestWithContext(
+			ctx,
+			http.MethodPost,
+			"http://api.example.com/api/clusters_mgmt/v1/clusters",
+			io.LimitReader(strings.NewReader(`{ "name": "my" }`), 16),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(request.GetBody).To(BeNil())
+		response, err := client.Do(request)
+		Expect(err).ToNot(HaveOccurred())
+		err = response.Body.Close()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(received).To(Equal(`{ "name": "my" }`))
+	})
+
+	It("Returns the error of the secondary when both fail", func() {
+		client := Client(
+			ErrorTransport(errors.New("primary failed")),
+			ErrorTransport(errors.New("secondary failed")),
+		)
+		_, err := Post(client, "{}")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("secondary failed"))
+		metrics := metricsServer.Metrics()
+		Expect(metrics).ToNot(MatchLine(`^my_backend_request_count.*$`))
+	})
+
+	It("Doesn't use the secondary when the context is cancelled", func() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		primary := TransportFunc(func(request *http.Request) (*http.Response, error) {
+			cancel()
+			return nil, context.Canceled
+		})
+		client := Client(
+			primary,
+			ErrorTransport(errors.New("secondary shouldn't be used")),
+		)
+		_, err := Post(client, "{}")
+		Expect(err).To(HaveOccurred())
+		Expect(errors.Is(err, context.Canceled)).To(BeTrue())
+	})
+})
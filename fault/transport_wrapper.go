@@ -0,0 +1,427 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the implementation of a transport wrapper that injects faults into requests,
+// intended for chaos testing.
+
+package fault
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openshift-online/ocm-sdk-go/clock"
+	"github.com/openshift-online/ocm-sdk-go/logging"
+)
+
+// Rule describes a fault and the requests that it applies to.
+type Rule struct {
+	// Name identifies the rule, so that it can be enabled, disabled or removed later. It is
+	// mandatory and it must be unique.
+	Name string
+
+	// Method is the HTTP method of the requests that the rule applies to. If empty the rule
+	// applies to all methods.
+	Method string
+
+	// Path is the prefix of the paths of the requests that the rule applies to, for example
+	// `/api/clusters_mgmt`. If empty the rule applies to all paths.
+	Path string
+
+	// Probability is the probability, between zero and one, that the rule is applied to a
+	// matching request. Zero means that it is always applied.
+	Probability float64
+
+	// Delay is the time to wait before applying the rest of the fault, or before sending the
+	// request to the server if there is no other fault. The wait stops when the context of the
+	// request is cancelled, and then the round tripper returns the error of the context.
+	Delay time.Duration
+
+	// Status is the status code of the response that will be returned instead of sending the
+	// request to the server. If zero the request will be sent to the server.
+	Status int
+
+	// Error is the error that will be returned instead of sending the request to the server. It
+	// takes precedence over the status.
+	Error error
+
+	// Disabled indicates that the rule is initially disabled. It can be enabled later with the
+	// Enable method of the wrapper.
+	Disabled bool
+}
+
+// TransportWrapperBuilder contains the data and logic needed to create a new transport wrapper
+// that injects faults into requests. The faults are described by rules that are checked in order
+// for each request, and the first one that matches the request is applied. For example, to make
+// half of the requests to the clusters service fail with a 503 status:
+//
+//	wrapper, err := fault.NewTransportWrapper().
+//		Logger(logger).
+//		Rule(fault.Rule{
+//			Name:        "unavailable",
+//			Path:        "/api/clusters_mgmt",
+//			Probability: 0.5,
+//			Status:      http.StatusServiceUnavailable,
+//		}).
+//		Build(ctx)
+//	if err != nil {
+//		...
+//	}
+//	connection, err := sdk.NewConnectionBuilder().
+//		...
+//		TransportWrapper(wrapper.Wrap).
+//		Build()
+//
+// Rules can also be added, removed, enabled and disabled while the wrapper is in use. When there
+// are no enabled rules requests are sent directly to the wrapped transport.
+//
+// Don't create objects of this type directly; use the NewTransportWrapper function instead.
+type TransportWrapperBuilder struct {
+	logger logging.Logger
+	clock  clock.Clock
+	seed   int64
+	rules  []Rule
+}
+
+// TransportWrapper contains the data and logic needed to wrap an HTTP round tripper with another
+// one that injects faults. Don't create objects of this type directly; use the NewTransportWrapper
+// function instead.
+type TransportWrapper struct {
+	logger logging.Logger
+	clock  clock.Clock
+	lock   *sync.Mutex
+	random *rand.Rand
+	rules  []*ruleState
+}
+
+// ruleState contains a rule and its current state.
+type ruleState struct {
+	rule    Rule
+	enabled bool
+}
+
+// roundTripper is a round tripper that injects faults.
+type roundTripper struct {
+	owner     *TransportWrapper
+	transport http.RoundTripper
+}
+
+// Make sure that we implement the interface:
+var _ http.RoundTripper = (*roundTripper)(nil)
+
+// NewTransportWrapper creates a new builder that can then be used to configure and create a new
+// fault injection round tripper.
+func NewTransportWrapper() *TransportWrapperBuilder {
+	return &TransportWrapperBuilder{
+		clock: clock.Real,
+		seed:  time.Now().UnixNano(),
+	}
+}
+
+// Logger sets the logger that will be used by the wrapper and by the round trippers that it
+// creates.
+func (b *TransportWrapperBuilder) Logger(value logging.Logger) *TransportWrapperBuilder {
+	b.logger = value
+	return b
+}
+
+// Clock sets the clock that will be used to wait for the delays of the rules. The default is to use
+// the real clock of the system.
+func (b *TransportWrapperBuilder) Clock(value clock.Clock) *TransportWrapperBuilder {
+	if value == nil {
+		value = clock.Real
+	}
+	b.clock = value
+	return b
+}
+
+// Seed sets the seed of the random number generator used to decide if rules are applied according
+// to their probabilities. Using the same seed and the same sequence of requests produces the same
+// faults, which is useful to reproduce failures. The default is to use the current time.
+func (b *TransportWrapperBuilder) Seed(value int64) *TransportWrapperBuilder {
+	b.seed = value
+	return b
+}
+
+// Rule adds a rule.
+func (b *TransportWrapperBuilder) Rule(value Rule) *TransportWrapperBuilder {
+	b.rules = append(b.rules, value)
+	return b
+}
+
+// Rules adds a list of rules.
+func (b *TransportWrapperBuilder) Rules(values ...Rule) *TransportWrapperBuilder {
+	b.rules = append(b.rules, values...)
+	return b
+}
+
+// Build uses the information stored in the builder to create a new transport wrapper.
+func (b *TransportWrapperBuilder) Build(ctx context.Context) (result *TransportWrapper, err error) {
+	// Check parameters:
+	if b.logger == nil {
+		err = fmt.Errorf("logger is mandatory")
+		return
+	}
+	names := map[string]bool{}
+	for _, rule := range b.rules {
+		err = checkRule(rule)
+		if err != nil {
+			return
+		}
+		if names[rule.Name] {
+			err = fmt.Errorf("rule name '%s' is duplicated", rule.Name)
+			return
+		}
+		names[rule.Name] = true
+	}
+
+	// Create the states of the rules:
+	rules := make([]*ruleState, len(b.rules))
+	for i, rule := range b.rules {
+		rules[i] = &ruleState{
+			rule:    rule,
+			enabled: !rule.Disabled,
+		}
+	}
+
+	// Create and populate the object:
+	result = &TransportWrapper{
+		logger: b.logger,
+		clock:  b.clock,
+		lock:   &sync.Mutex{},
+		random: rand.New(rand.NewSource(b.seed)),
+		rules:  rules,
+	}
+
+	return
+}
+
+// checkRule checks that the given rule is valid.
+func checkRule(rule Rule) error {
+	if rule.Name == "" {
+		return fmt.Errorf("rule name is mandatory")
+	}
+	if rule.Probability < 0 || rule.Probability > 1 {
+		return fmt.Errorf(
+			"probability %f of rule '%s' isn't valid, it should be between zero and one",
+			rule.Probability, rule.Name,
+		)
+	}
+	if rule.Delay < 0 {
+		return fmt.Errorf(
+			"delay %s of rule '%s' isn't valid, it should be zero or positive",
+			rule.Delay, rule.Name,
+		)
+	}
+	if rule.Status != 0 && (rule.Status < 100 || rule.Status > 599) {
+		return fmt.Errorf(
+			"status %d of rule '%s' isn't valid, it should be between 100 and 599",
+			rule.Status, rule.Name,
+		)
+	}
+	return nil
+}
+
+// Wrap creates a new round tripper that wraps the given one and injects faults.
+func (w *TransportWrapper) Wrap(transport http.RoundTripper) http.RoundTripper {
+	return &roundTripper{
+		owner:     w,
+		transport: transport,
+	}
+}
+
+// AddRule adds a rule after the existing ones. It returns an error if the rule isn't valid or if
+// there is already a rule with the same name.
+func (w *TransportWrapper) AddRule(rule Rule) error {
+	err := checkRule(rule)
+	if err != nil {
+		return err
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.find(rule.Name) != nil {
+		return fmt.Errorf("rule name '%s' is duplicated", rule.Name)
+	}
+	w.rules = append(w.rules, &ruleState{
+		rule:    rule,
+		enabled: !rule.Disabled,
+	})
+	return nil
+}
+
+// RemoveRule removes the rule with the given name. It returns an error if there is no such rule.
+func (w *TransportWrapper) RemoveRule(name string) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	for i, state := range w.rules {
+		if state.rule.Name == name {
+			w.rules = append(w.rules[:i:i], w.rules[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("rule '%s' doesn't exist", name)
+}
+
+// Enable enables the rule with the given name. It returns an error if there is no such rule.
+func (w *TransportWrapper) Enable(name string) error {
+	return w.setEnabled(name, true)
+}
+
+// Disable disables the rule with the given name, so that it will not be applied till it is enabled
+// again. It returns an error if there is no such rule.
+func (w *TransportWrapper) Disable(name string) error {
+	return w.setEnabled(name, false)
+}
+
+// Rules returns a copy of the rules, including the disabled ones.
+func (w *TransportWrapper) Rules() []Rule {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	result := make([]Rule, len(w.rules))
+	for i, state := range w.rules {
+		result[i] = state.rule
+		result[i].Disabled = !state.enabled
+	}
+	return result
+}
+
+func (w *TransportWrapper) setEnabled(name string, value bool) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	state := w.find(name)
+	if state == nil {
+		return fmt.Errorf("rule '%s' doesn't exist", name)
+	}
+	state.enabled = value
+	return nil
+}
+
+// find returns the state of the rule with the given name, or nil if there is no such rule. Note
+// that this must be called with the lock acquired.
+func (w *TransportWrapper) find(name string) *ruleState {
+	for _, state := range w.rules {
+		if state.rule.Name == name {
+			return state
+		}
+	}
+	return nil
+}
+
+// match returns the first enabled rule that matches the given request and that is selected
+// according to its probability. The second result will be false if there is no such rule.
+func (w *TransportWrapper) match(request *http.Request) (result Rule, ok bool) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	for _, state := range w.rules {
+		if !state.enabled {
+			continue
+		}
+		rule := state.rule
+		if rule.Method != "" && !strings.EqualFold(rule.Method, request.Method) {
+			continue
+		}
+		if rule.Path != "" && !strings.HasPrefix(request.URL.Path, rule.Path) {
+			continue
+		}
+		if rule.Probability > 0 && w.random.Float64() >= rule.Probability {
+			continue
+		}
+		result = rule
+		ok = true
+		return
+	}
+	return
+}
+
+// RoundTrip is the implementation of the round tripper interface.
+func (t *roundTripper) RoundTrip(request *http.Request) (response *http.Response, err error) {
+	// Find the rule to apply, if any:
+	rule, ok := t.owner.match(request)
+	if !ok {
+		response, err = t.transport.RoundTrip(request)
+		return
+	}
+	ctx := request.Context()
+	t.owner.logger.Debug(
+		ctx,
+		"Injecting fault '%s' into %s request for '%s'",
+		rule.Name, request.Method, request.URL.Path,
+	)
+
+	// Wait for the delay, or till the context is cancelled. Note that the round tripper is
+	// responsible for closing the request body even when it returns an error.
+	if rule.Delay > 0 {
+		select {
+		case <-ctx.Done():
+			closeBody(request)
+			err = ctx.Err()
+			return
+		case <-t.owner.clock.After(rule.Delay):
+		}
+	}
+
+	// Return the error or the status, or send the request to the server:
+	switch {
+	case rule.Error != nil:
+		closeBody(request)
+		err = rule.Error
+	case rule.Status != 0:
+		closeBody(request)
+		response = makeResponse(request, rule)
+	default:
+		response, err = t.transport.RoundTrip(request)
+	}
+	return
+}
+
+// closeBody closes the body of a request that won't be sent to the server.
+func closeBody(request *http.Request) {
+	if request.Body != nil {
+		request.Body.Close()
+	}
+}
+
+// makeResponse creates the response returned for a rule that has a status code. The body contains
+// an error object like the ones returned by the server, so that it can be parsed by the SDK.
+func makeResponse(request *http.Request, rule Rule) *http.Response {
+	body, _ := json.Marshal(map[string]string{
+		"kind":   "Error",
+		"id":     strconv.Itoa(rule.Status),
+		"reason": fmt.Sprintf("Fault injected by rule '%s'", rule.Name),
+	})
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", rule.Status, http.StatusText(rule.Status)),
+		StatusCode:    rule.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       request,
+	}
+}
@@ -0,0 +1,365 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains tests for the fault injection transport wrapper.
+
+package fault
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"             // nolint
+	. "github.com/onsi/gomega"                         // nolint
+	. "github.com/openshift-online/ocm-sdk-go/testing" // nolint
+)
+
+var _ = Describe("Fault transport wrapper", func() {
+	var ctx context.Context
+	var calls int
+	var backend http.RoundTripper
+
+	// Send sends a request with the given method and path using a transport created with the
+	// given wrapper.
+	var Send = func(wrapper *TransportWrapper, method, path string) (*http.Response, error) {
+		request, err := http.NewRequestWithContext(
+			ctx, method, "http://api.example.com"+path, nil,
+		)
+		Expect(err).ToNot(HaveOccurred())
+		return wrapper.Wrap(backend).RoundTrip(request)
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		calls = 0
+		backend = TransportFunc(func(request *http.Request) (*http.Response, error) {
+			calls++
+			return JSONTransport(http.StatusOK, `{}`).RoundTrip(request)
+		})
+	})
+
+	Describe("Build", func() {
+		It("Can't be created without a logger", func() {
+			_, err := NewTransportWrapper().Build(ctx)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("logger"))
+		})
+
+		It("Can't be created with a rule without name", func() {
+			_, err := NewTransportWrapper().
+				Logger(logger).
+				Rule(Rule{
+					Status: http.StatusServiceUnavailable,
+				}).
+				Build(ctx)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("name"))
+		})
+
+		It("Can't be created with duplicated rule names", func() {
+			_, err := NewTransportWrapper().
+				Logger(logger).
+				Rules(
+					Rule{Name: "my_rule", Status: http.StatusServiceUnavailable},
+					Rule{Name: "my_rule", Status: http.StatusBadGateway},
+				).
+				Build(ctx)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("duplicated"))
+		})
+
+		It("Can't be created with invalid probability", func() {
+			_, err := NewTransportWrapper().
+				Logger(logger).
+				Rule(Rule{
+					Name:        "my_rule",
+					Probability: 1.5,
+				}).
+				Build(ctx)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("probability"))
+		})
+
+		It("Can't be created with invalid status", func() {
+			_, err := NewTransportWrapper().
+				Logger(logger).
+				Rule(Rule{
+					Name:   "my_rule",
+					Status: 42,
+				}).
+				Build(ctx)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("status"))
+		})
+	})
+
+	It("Sends requests to the server when there are no rules", func() {
+		wrapper, err := NewTransportWrapper().
+			Logger(logger).
+			Build(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		response, err := Send(wrapper, http.MethodGet, "/api")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+		Expect(calls).To(Equal(1))
+	})
+
+	It("Injects status", func() {
+		wrapper, err := NewTransportWrapper().
+			Logger(logger).
+			Rule(Rule{
+				Name:   "my_rule",
+				Status: http.StatusServiceUnavailable,
+			}).
+			Build(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		response, err := Send(wrapper, http.MethodGet, "/api")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusServiceUnavailable))
+		Expect(response.Header.Get("Content-Type")).To(Equal("application/json"))
+		body, err := io.ReadAll(response.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(body).To(MatchJSON(`{
+			"kind": "Error",
+			"id": "503",
+			"reason": "Fault injected by rule 'my_rule'"
+		}`))
+		Expect(calls).To(BeZero())
+	})
+
+	It("Injects error", func() {
+		injected := errors.New("connection reset")
+		wrapper, err := NewTransportWrapper().
+			Logger(logger).
+			Rule(Rule{
+				Name:  "my_rule",
+				Error: injected,
+			}).
+			Build(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = Send(wrapper, http.MethodGet, "/api")
+		Expect(err).To(MatchError(injected))
+		Expect(calls).To(BeZero())
+	})
+
+	It("Injects delay and then sends the request", func() {
+		start := time.Now()
+		clock := NewFakeClock(start)
+		wrapper, err := NewTransportWrapper().
+			Logger(logger).
+			Clock(clock).
+			Rule(Rule{
+				Name:  "my_rule",
+				Delay: 5 * time.Second,
+			}).
+			Build(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		response, err := Send(wrapper, http.MethodGet, "/api")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+		Expect(clock.Since(start)).To(Equal(5 * time.Second))
+		Expect(calls).To(Equal(1))
+	})
+
+	It("Stops waiting for the delay when the context is cancelled", func() {
+		wrapper, err := NewTransportWrapper().
+			Logger(logger).
+			Clock(blockingClock{NewFakeClock(time.Now())}).
+			Rule(Rule{
+				Name:  "my_rule",
+				Delay: 5 * time.Second,
+			}).
+			Build(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		var cancel func()
+		ctx, cancel = context.WithCancel(ctx)
+		cancel()
+		body := &closeRecorder{
+			Reader: strings.NewReader(`{}`),
+		}
+		request, err := http.NewRequestWithContext(
+			ctx, http.MethodPost, "http://api.example.com/api", body,
+		)
+		Expect(err).ToNot(HaveOccurred())
+		response, err := wrapper.Wrap(backend).RoundTrip(request)
+		Expect(err).To(MatchError(context.Canceled))
+		Expect(response).To(BeNil())
+		Expect(body.closed).To(BeTrue())
+		Expect(calls).To(BeZero())
+	})
+
+	It("Only applies to matching method and path", func() {
+		wrapper, err := NewTransportWrapper().
+			Logger(logger).
+			Rule(Rule{
+				Name:   "my_rule",
+				Method: http.MethodPost,
+				Path:   "/api/clusters_mgmt",
+				Status: http.StatusInternalServerError,
+			}).
+			Build(ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		// Different method:
+		response, err := Send(wrapper, http.MethodGet, "/api/clusters_mgmt/v1/clusters")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+		// Different path:
+		response, err = Send(wrapper, http.MethodPost, "/api/accounts_mgmt/v1/accounts")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+		// Matching method and path:
+		response, err = Send(wrapper, http.MethodPost, "/api/clusters_mgmt/v1/clusters")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusInternalServerError))
+	})
+
+	It("Applies the first matching rule", func() {
+		wrapper, err := NewTransportWrapper().
+			Logger(logger).
+			Rules(
+				Rule{Name: "first", Status: http.StatusBadGateway},
+				Rule{Name: "second", Status: http.StatusServiceUnavailable},
+			).
+			Build(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		response, err := Send(wrapper, http.MethodGet, "/api")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusBadGateway))
+	})
+
+	It("Applies rules according to probability deterministically", func() {
+		// Count the faults produced by a wrapper with the given seed:
+		count := func(seed int64) (result []int) {
+			wrapper, err := NewTransportWrapper().
+				Logger(logger).
+				Seed(seed).
+				Rule(Rule{
+					Name:        "my_rule",
+					Probability: 0.5,
+					Status:      http.StatusServiceUnavailable,
+				}).
+				Build(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			for i := 0; i < 100; i++ {
+				response, err := Send(wrapper, http.MethodGet, "/api")
+				Expect(err).ToNot(HaveOccurred())
+				if response.StatusCode == http.StatusServiceUnavailable {
+					result = append(result, i)
+				}
+			}
+			return
+		}
+		first := count(42)
+		second := count(42)
+		Expect(first).To(Equal(second))
+		Expect(len(first)).To(BeNumerically(">", 20))
+		Expect(len(first)).To(BeNumerically("<", 80))
+	})
+
+	It("Can enable and disable rules at runtime", func() {
+		wrapper, err := NewTransportWrapper().
+			Logger(logger).
+			Rule(Rule{
+				Name:     "my_rule",
+				Status:   http.StatusServiceUnavailable,
+				Disabled: true,
+			}).
+			Build(ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		// Initially disabled:
+		response, err := Send(wrapper, http.MethodGet, "/api")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+		// Enabled:
+		err = wrapper.Enable("my_rule")
+		Expect(err).ToNot(HaveOccurred())
+		response, err = Send(wrapper, http.MethodGet, "/api")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusServiceUnavailable))
+
+		// Disabled again:
+		err = wrapper.Disable("my_rule")
+		Expect(err).ToNot(HaveOccurred())
+		response, err = Send(wrapper, http.MethodGet, "/api")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+		Expect(wrapper.Rules()[0].Disabled).To(BeTrue())
+	})
+
+	It("Can add and remove rules at runtime", func() {
+		wrapper, err := NewTransportWrapper().
+			Logger(logger).
+			Build(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		err = wrapper.AddRule(Rule{
+			Name:   "my_rule",
+			Status: http.StatusTooManyRequests,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		response, err := Send(wrapper, http.MethodGet, "/api")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusTooManyRequests))
+		err = wrapper.RemoveRule("my_rule")
+		Expect(err).ToNot(HaveOccurred())
+		response, err = Send(wrapper, http.MethodGet, "/api")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+		Expect(wrapper.Rules()).To(BeEmpty())
+	})
+
+	It("Fails to change rules that don't exist", func() {
+		wrapper, err := NewTransportWrapper().
+			Logger(logger).
+			Build(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(wrapper.Enable("junk")).To(HaveOccurred())
+		Expect(wrapper.Disable("junk")).To(HaveOccurred())
+		Expect(wrapper.RemoveRule("junk")).To(HaveOccurred())
+	})
+})
+
+// blockingClock is a fake clock where waits never finish, used to check that the round tripper
+// stops waiting when the context is cancelled.
+type blockingClock struct {
+	*FakeClock
+}
+
+// After is part of the implementation of the clock interface. It returns a channel that never
+// receives anything.
+func (c blockingClock) After(d time.Duration) <-chan time.Time {
+	return nil
+}
+
+// closeRecorder is a request body that records if it has been closed.
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+// Close is the implementation of the io.Closer interface.
+func (r *closeRecorder) Close() error {
+	r.closed = true
+	return nil
+}
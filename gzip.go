@@ -0,0 +1,126 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the implementation of the transport wrapper that requests compressed
+// responses and decompresses them.
+
+package sdk
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// gzipTransportWrapper is a transport wrapper that creates round trippers that request gzip
+// compressed responses and decompress them. This replaces the transparent compression support of
+// the HTTP transport, which is disabled by the connection, because the transport only does that
+// when the request doesn't already have an `Accept-Encoding` header, and that depends on what the
+// rest of the wrappers do.
+type gzipTransportWrapper struct {
+	enabled bool
+}
+
+// gzipRoundTripper is a round tripper that requests gzip compressed responses and decompresses
+// them.
+type gzipRoundTripper struct {
+	next http.RoundTripper
+}
+
+// Make sure that we implement the http.RoundTripper interface:
+var _ http.RoundTripper = &gzipRoundTripper{}
+
+// Wrap creates a round tripper on top of the given one that requests and decompresses gzip
+// compressed responses. If compression is disabled it returns the given round tripper unchanged.
+func (w *gzipTransportWrapper) Wrap(transport http.RoundTripper) http.RoundTripper {
+	if !w.enabled {
+		return transport
+	}
+	return &gzipRoundTripper{
+		next: transport,
+	}
+}
+
+// RoundTrip is the implementation of the http.RoundTripper interface.
+func (t *gzipRoundTripper) RoundTrip(request *http.Request) (response *http.Response,
+	err error) {
+	// Don't do anything if the request already has an `Accept-Encoding` header, as that means
+	// that the caller wants to handle the encoding. Requests for ranges are also excluded, as
+	// the range would apply to the compressed content.
+	if request.Header.Get("Accept-Encoding") != "" || request.Header.Get("Range") != "" {
+		response, err = t.next.RoundTrip(request)
+		return
+	}
+
+	// Round trippers shouldn't modify the request, so we need to replace it with a copy that
+	// has the additional header:
+	request = request.Clone(request.Context())
+	request.Header.Set("Accept-Encoding", "gzip")
+
+	// Send the modified request:
+	response, err = t.next.RoundTrip(request)
+	if err != nil {
+		return
+	}
+
+	// Replace the body with one that decompresses it, and remove the headers that describe the
+	// compressed content, as the transport does:
+	if strings.EqualFold(response.Header.Get("Content-Encoding"), "gzip") {
+		response.Body = &gzipBody{
+			body: response.Body,
+		}
+		response.Header.Del("Content-Encoding")
+		response.Header.Del("Content-Length")
+		response.ContentLength = -1
+		response.Uncompressed = true
+	}
+	return
+}
+
+// gzipBody is a response body that decompresses the content of the original body. The gzip reader
+// is created lazily, on the first call to the Read method, so that bodies that are never read,
+// like the ones of responses to HEAD requests, don't cause errors.
+type gzipBody struct {
+	body   io.ReadCloser
+	reader *gzip.Reader
+	err    error
+}
+
+// Make sure that we implement the io.ReadCloser interface:
+var _ io.ReadCloser = &gzipBody{}
+
+// Read is the implementation of the io.Reader interface.
+func (b *gzipBody) Read(p []byte) (n int, err error) {
+	if b.err != nil {
+		err = b.err
+		return
+	}
+	if b.reader == nil {
+		b.reader, b.err = gzip.NewReader(b.body)
+		if b.err != nil {
+			err = b.err
+			return
+		}
+	}
+	n, err = b.reader.Read(p)
+	return
+}
+
+// Close is the implementation of the io.Closer interface.
+func (b *gzipBody) Close() error {
+	return b.body.Close()
+}
@@ -0,0 +1,160 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the implementation of a transport wrapper that adds default headers to
+// requests.
+
+package headers
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// DefaultTransportWrapperBuilder contains the data and logic needed to build a new transport
+// wrapper that adds a set of default headers to requests. A default header is only added when the
+// request doesn't already contain a header with the same name, so it is possible to override the
+// default value for specific requests. For example, to send the `X-Source` header in all
+// requests:
+//
+//	wrapper, err := headers.NewDefaultTransportWrapper().
+//		Header("X-Source", "my-operator").
+//		Build()
+//
+// Headers that are managed by the HTTP client or by the authentication and idempotency wrappers of
+// the SDK, like `Authorization`, can't be used as default headers.
+//
+// Don't create objects of this type directly; use the NewDefaultTransportWrapper function instead.
+type DefaultTransportWrapperBuilder struct {
+	header http.Header
+}
+
+// DefaultTransportWrapper contains the data and logic needed to wrap an HTTP round tripper with
+// another one that adds default headers to requests.
+type DefaultTransportWrapper struct {
+	header http.Header
+}
+
+// defaultRoundTripper is a round tripper that adds default headers to requests.
+type defaultRoundTripper struct {
+	owner     *DefaultTransportWrapper
+	transport http.RoundTripper
+}
+
+// Make sure that we implement the interface:
+var _ http.RoundTripper = (*defaultRoundTripper)(nil)
+
+// NewDefaultTransportWrapper creates a new builder that can then be used to configure and create
+// a new default headers transport wrapper.
+func NewDefaultTransportWrapper() *DefaultTransportWrapperBuilder {
+	return &DefaultTransportWrapperBuilder{
+		header: http.Header{},
+	}
+}
+
+// Header adds a default header. If called multiple times with the same name all the values will
+// be added.
+func (b *DefaultTransportWrapperBuilder) Header(name,
+	value string) *DefaultTransportWrapperBuilder {
+	b.header[name] = append(b.header[name], value)
+	return b
+}
+
+// Build uses the information stored in the builder to create a new transport wrapper.
+func (b *DefaultTransportWrapperBuilder) Build() (result *DefaultTransportWrapper, err error) {
+	// Check parameters:
+	header := make(http.Header, len(b.header))
+	for name, values := range b.header {
+		if name == "" {
+			err = fmt.Errorf("header name can't be empty")
+			return
+		}
+		key := http.CanonicalHeaderKey(name)
+		if reservedNames[key] {
+			err = fmt.Errorf(
+				"header '%s' is managed by the HTTP client and can't have a "+
+					"default value",
+				name,
+			)
+			return
+		}
+		if protectedNames[key] {
+			err = fmt.Errorf(
+				"header '%s' is managed by the SDK and can't have a default value",
+				name,
+			)
+			return
+		}
+		header[key] = append(header[key], values...)
+	}
+
+	// Create and populate the object:
+	result = &DefaultTransportWrapper{
+		header: header,
+	}
+
+	return
+}
+
+// Wrap creates a new round tripper that wraps the given one and adds the default headers to
+// requests.
+func (w *DefaultTransportWrapper) Wrap(transport http.RoundTripper) http.RoundTripper {
+	return &defaultRoundTripper{
+		owner:     w,
+		transport: transport,
+	}
+}
+
+// RoundTrip is the implementation of the round tripper interface.
+func (t *defaultRoundTripper) RoundTrip(request *http.Request) (response *http.Response,
+	err error) {
+	// Check if there is any default header missing, so that we don't need to copy the request if
+	// there is nothing to add:
+	missing := false
+	for name := range t.owner.header {
+		if len(request.Header.Values(name)) == 0 {
+			missing = true
+			break
+		}
+	}
+	if !missing {
+		response, err = t.transport.RoundTrip(request)
+		return
+	}
+
+	// Round trippers shouldn't modify the request, so we need to work with a copy:
+	request = request.Clone(request.Context())
+	if request.Header == nil {
+		request.Header = http.Header{}
+	}
+	for name, values := range t.owner.header {
+		if len(request.Header.Values(name)) == 0 {
+			request.Header[name] = append([]string{}, values...)
+		}
+	}
+
+	// Send the modified request:
+	response, err = t.transport.RoundTrip(request)
+	return
+}
+
+// protectedNames contains the canonical names of the headers that are managed by the SDK and
+// that can't have default values.
+var protectedNames = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	IdempotencyKeyHeader:  true,
+}
@@ -0,0 +1,119 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains tests for the default headers transport wrapper.
+
+package headers
+
+import (
+	"net/http"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"  // nolint
+	. "github.com/onsi/ginkgo/v2/dsl/table" // nolint
+	. "github.com/onsi/gomega"              // nolint
+
+	. "github.com/openshift-online/ocm-sdk-go/testing" // nolint
+)
+
+var _ = Describe("Default headers", func() {
+	// Send sends a request with the given headers using a client created with the given wrapper
+	// and returns the headers received by the transport.
+	var Send = func(wrapper *DefaultTransportWrapper, header http.Header) (received http.Header) {
+		transport := TransportFunc(func(request *http.Request) (*http.Response, error) {
+			received = request.Header.Clone()
+			return JSONTransport(http.StatusOK, `{}`).RoundTrip(request)
+		})
+		client := &http.Client{
+			Transport: wrapper.Wrap(transport),
+		}
+		request, err := http.NewRequest(http.MethodGet, "http://api.example.com/mypath", nil)
+		Expect(err).ToNot(HaveOccurred())
+		request.Header = header
+		response, err := client.Do(request)
+		Expect(err).ToNot(HaveOccurred())
+		response.Body.Close()
+		return
+	}
+
+	It("Rejects empty name", func() {
+		wrapper, err := NewDefaultTransportWrapper().
+			Header("", "my-value").
+			Build()
+		Expect(err).To(HaveOccurred())
+		Expect(wrapper).To(BeNil())
+		Expect(err.Error()).To(ContainSubstring("empty"))
+	})
+
+	DescribeTable(
+		"Rejects headers managed by the SDK or the HTTP client",
+		func(name string) {
+			wrapper, err := NewDefaultTransportWrapper().
+				Header(name, "my-value").
+				Build()
+			Expect(err).To(HaveOccurred())
+			Expect(wrapper).To(BeNil())
+			Expect(err.Error()).To(ContainSubstring(name))
+		},
+		Entry("Authorization", "Authorization"),
+		Entry("Authorization in lower case", "authorization"),
+		Entry("Proxy-Authorization", "Proxy-Authorization"),
+		Entry("Idempotency-Key", "Idempotency-Key"),
+		Entry("Content-Length", "Content-Length"),
+		Entry("Host", "Host"),
+	)
+
+	It("Adds missing header", func() {
+		wrapper, err := NewDefaultTransportWrapper().
+			Header("X-Source", "my-operator").
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		received := Send(wrapper, http.Header{})
+		Expect(received.Get("X-Source")).To(Equal("my-operator"))
+	})
+
+	It("Adds multiple values", func() {
+		wrapper, err := NewDefaultTransportWrapper().
+			Header("X-Tag", "first").
+			Header("x-tag", "second").
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		received := Send(wrapper, http.Header{})
+		Expect(received.Values("X-Tag")).To(ConsistOf("first", "second"))
+	})
+
+	It("Doesn't override header of the request", func() {
+		wrapper, err := NewDefaultTransportWrapper().
+			Header("X-Source", "my-operator").
+			Header("X-Other", "my-other").
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		received := Send(wrapper, http.Header{
+			"X-Source": []string{"your-operator"},
+		})
+		Expect(received.Values("X-Source")).To(Equal([]string{"your-operator"}))
+		Expect(received.Get("X-Other")).To(Equal("my-other"))
+	})
+
+	It("Doesn't modify the original request", func() {
+		wrapper, err := NewDefaultTransportWrapper().
+			Header("X-Source", "my-operator").
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		header := http.Header{}
+		Send(wrapper, header)
+		Expect(header).To(BeEmpty())
+	})
+})
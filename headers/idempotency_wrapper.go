@@ -0,0 +1,205 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the implementation of a transport wrapper that adds idempotency keys to
+// requests.
+
+package headers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// IdempotencyKeyHeader is the name of the header that contains the idempotency key.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// maxIdempotencyKeyLength is the maximum length of idempotency keys.
+const maxIdempotencyKeyLength = 255
+
+// IdempotencyTransportWrapperBuilder contains the data and logic needed to build a new transport
+// wrapper that adds the `Idempotency-Key` header to requests, so that the server can detect and
+// discard duplicates. By default this is done only for POST requests. The key is taken from the
+// header of the request if it is already present, otherwise from the context if it has been
+// stored there with the ContextWithIdempotencyKey function, and otherwise a new random key is
+// generated. For example, to explicitly use a key for a request:
+//
+//	ctx = headers.ContextWithIdempotencyKey(ctx, "my-key")
+//	response, err := connection.ClustersMgmt().V1().Clusters().Add().
+//		Body(cluster).
+//		SendContext(ctx)
+//
+// The key is also stored in the context of the request passed to the wrapped round tripper, so
+// that it stays the same when the request is retried. For that to work this wrapper needs to wrap
+// the retry wrapper, not the other way around.
+//
+// Keys provided explicitly are validated: they can't be longer than 255 characters and can only
+// contain visible ASCII characters. Requests with invalid keys aren't sent.
+//
+// Don't create objects of this type directly; use the NewIdempotencyTransportWrapper function
+// instead.
+type IdempotencyTransportWrapperBuilder struct {
+	methods []string
+}
+
+// IdempotencyTransportWrapper contains the data and logic needed to wrap an HTTP round tripper
+// with another one that adds idempotency keys to requests.
+type IdempotencyTransportWrapper struct {
+	methods map[string]bool
+}
+
+// idempotencyRoundTripper is a round tripper that adds idempotency keys to requests.
+type idempotencyRoundTripper struct {
+	owner     *IdempotencyTransportWrapper
+	transport http.RoundTripper
+}
+
+// Make sure that we implement the interface:
+var _ http.RoundTripper = (*idempotencyRoundTripper)(nil)
+
+// NewIdempotencyTransportWrapper creates a new builder that can then be used to configure and
+// create a new idempotency key transport wrapper.
+func NewIdempotencyTransportWrapper() *IdempotencyTransportWrapperBuilder {
+	return &IdempotencyTransportWrapperBuilder{}
+}
+
+// Method adds an HTTP method for which requests will have idempotency keys. If no method is added
+// the default is to add keys only to POST requests.
+func (b *IdempotencyTransportWrapperBuilder) Method(
+	value string) *IdempotencyTransportWrapperBuilder {
+	b.methods = append(b.methods, value)
+	return b
+}
+
+// Methods adds a list of HTTP methods for which requests will have idempotency keys. This is
+// equivalent to calling the Method method for each of them.
+func (b *IdempotencyTransportWrapperBuilder) Methods(
+	values ...string) *IdempotencyTransportWrapperBuilder {
+	b.methods = append(b.methods, values...)
+	return b
+}
+
+// Build uses the information stored in the builder to create a new transport wrapper.
+func (b *IdempotencyTransportWrapperBuilder) Build() (result *IdempotencyTransportWrapper,
+	err error) {
+	// Check parameters:
+	methods := map[string]bool{}
+	for _, method := range b.methods {
+		if method == "" {
+			err = fmt.Errorf("method can't be empty")
+			return
+		}
+		methods[strings.ToUpper(method)] = true
+	}
+	if len(methods) == 0 {
+		methods[http.MethodPost] = true
+	}
+
+	// Create and populate the object:
+	result = &IdempotencyTransportWrapper{
+		methods: methods,
+	}
+
+	return
+}
+
+// Wrap creates a new round tripper that wraps the given one and adds idempotency keys to
+// requests.
+func (w *IdempotencyTransportWrapper) Wrap(transport http.RoundTripper) http.RoundTripper {
+	return &idempotencyRoundTripper{
+		owner:     w,
+		transport: transport,
+	}
+}
+
+// RoundTrip is the implementation of the round tripper interface.
+func (t *idempotencyRoundTripper) RoundTrip(request *http.Request) (response *http.Response,
+	err error) {
+	// Do nothing if the method doesn't need keys:
+	if !t.owner.methods[request.Method] {
+		response, err = t.transport.RoundTrip(request)
+		return
+	}
+
+	// Find the key, or generate a new one:
+	ctx := request.Context()
+	key := request.Header.Get(IdempotencyKeyHeader)
+	if key == "" {
+		key = IdempotencyKeyFromContext(ctx)
+	}
+	if key == "" {
+		key = uuid.NewString()
+	}
+	err = checkIdempotencyKey(key)
+	if err != nil {
+		if request.Body != nil {
+			request.Body.Close()
+		}
+		return
+	}
+
+	// Round trippers shouldn't modify the request, so we need to work with a copy that has the
+	// key in the header and in the context:
+	request = request.Clone(ContextWithIdempotencyKey(ctx, key))
+	request.Header.Set(IdempotencyKeyHeader, key)
+
+	// Send the modified request:
+	response, err = t.transport.RoundTrip(request)
+	return
+}
+
+// checkIdempotencyKey checks that the given key is valid.
+func checkIdempotencyKey(key string) error {
+	if len(key) > maxIdempotencyKeyLength {
+		return fmt.Errorf(
+			"idempotency key has %d characters, but it should have at most %d",
+			len(key), maxIdempotencyKeyLength,
+		)
+	}
+	for _, char := range key {
+		if char < '!' || char > '~' {
+			return fmt.Errorf(
+				"idempotency key '%s' contains character %q, but only visible ASCII "+
+					"characters are allowed",
+				key, char,
+			)
+		}
+	}
+	return nil
+}
+
+// ContextWithIdempotencyKey creates a new context containing the given idempotency key. The
+// idempotency transport wrapper will use it for the requests sent with that context.
+func ContextWithIdempotencyKey(parent context.Context, key string) context.Context {
+	return context.WithValue(parent, idempotencyKeyValue, key)
+}
+
+// IdempotencyKeyFromContext extracts the idempotency key from the context. If the context doesn't
+// contain a key the result will be the empty string.
+func IdempotencyKeyFromContext(ctx context.Context) string {
+	key, _ := ctx.Value(idempotencyKeyValue).(string)
+	return key
+}
+
+// idempotencyKeyType is the type of the key used to store the idempotency key in the context.
+type idempotencyKeyType string
+
+// idempotencyKeyValue is the key used to store the idempotency key in the context:
+const idempotencyKeyValue idempotencyKeyType = "idempotencyKey"
@@ -0,0 +1,177 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains tests for the idempotency key transport wrapper.
+
+package headers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core" // nolint
+	. "github.com/onsi/gomega"             // nolint
+
+	. "github.com/openshift-online/ocm-sdk-go/testing" // nolint
+)
+
+var _ = Describe("Idempotency keys", func() {
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.Background()
+	})
+
+	// Send sends a request with the given method and headers using a client created with the
+	// given wrapper and returns the key received by the transport, both in the header and in the
+	// context, and the number of requests that reached the transport.
+	var Send = func(wrapper *IdempotencyTransportWrapper, method string,
+		header http.Header) (headerKey, contextKey string, sent int, err error) {
+		transport := TransportFunc(func(request *http.Request) (*http.Response, error) {
+			sent++
+			headerKey = request.Header.Get(IdempotencyKeyHeader)
+			contextKey = IdempotencyKeyFromContext(request.Context())
+			return JSONTransport(http.StatusOK, `{}`).RoundTrip(request)
+		})
+		client := &http.Client{
+			Transport: wrapper.Wrap(transport),
+		}
+		request, err := http.NewRequestWithContext(
+			ctx,
+			method,
+			"http://api.example.com/mypath",
+			strings.NewReader("{}"),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		if header != nil {
+			request.Header = header
+		}
+		response, err := client.Do(request)
+		if err == nil {
+			response.Body.Close()
+		}
+		return
+	}
+
+	It("Rejects empty method", func() {
+		wrapper, err := NewIdempotencyTransportWrapper().
+			Method("").
+			Build()
+		Expect(err).To(HaveOccurred())
+		Expect(wrapper).To(BeNil())
+		Expect(err.Error()).To(ContainSubstring("empty"))
+	})
+
+	It("Generates key for POST request", func() {
+		wrapper, err := NewIdempotencyTransportWrapper().
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		headerKey, contextKey, sent, err := Send(wrapper, http.MethodPost, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(sent).To(Equal(1))
+		Expect(headerKey).ToNot(BeEmpty())
+		Expect(contextKey).To(Equal(headerKey))
+	})
+
+	It("Generates different keys for different requests", func() {
+		wrapper, err := NewIdempotencyTransportWrapper().
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		first, _, _, err := Send(wrapper, http.MethodPost, nil)
+		Expect(err).ToNot(HaveOccurred())
+		second, _, _, err := Send(wrapper, http.MethodPost, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(first).ToNot(Equal(second))
+	})
+
+	It("Doesn't add key to GET request", func() {
+		wrapper, err := NewIdempotencyTransportWrapper().
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		headerKey, contextKey, sent, err := Send(wrapper, http.MethodGet, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(sent).To(Equal(1))
+		Expect(headerKey).To(BeEmpty())
+		Expect(contextKey).To(BeEmpty())
+	})
+
+	It("Uses key from the context", func() {
+		wrapper, err := NewIdempotencyTransportWrapper().
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		ctx = ContextWithIdempotencyKey(ctx, "my-key")
+		headerKey, contextKey, _, err := Send(wrapper, http.MethodPost, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(headerKey).To(Equal("my-key"))
+		Expect(contextKey).To(Equal("my-key"))
+	})
+
+	It("Uses key from the header", func() {
+		wrapper, err := NewIdempotencyTransportWrapper().
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		ctx = ContextWithIdempotencyKey(ctx, "your-key")
+		headerKey, contextKey, _, err := Send(wrapper, http.MethodPost, http.Header{
+			IdempotencyKeyHeader: []string{"my-key"},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(headerKey).To(Equal("my-key"))
+		Expect(contextKey).To(Equal("my-key"))
+	})
+
+	It("Adds key for configured methods", func() {
+		wrapper, err := NewIdempotencyTransportWrapper().
+			Methods("patch", http.MethodDelete).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		headerKey, _, _, err := Send(wrapper, http.MethodPatch, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(headerKey).ToNot(BeEmpty())
+		headerKey, _, _, err = Send(wrapper, http.MethodDelete, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(headerKey).ToNot(BeEmpty())
+		headerKey, _, _, err = Send(wrapper, http.MethodPost, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(headerKey).To(BeEmpty())
+	})
+
+	It("Rejects key that is too long", func() {
+		wrapper, err := NewIdempotencyTransportWrapper().
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		ctx = ContextWithIdempotencyKey(ctx, strings.Repeat("x", 256))
+		_, _, sent, err := Send(wrapper, http.MethodPost, nil)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("256"))
+		Expect(sent).To(BeZero())
+	})
+
+	It("Rejects key with invalid characters", func() {
+		wrapper, err := NewIdempotencyTransportWrapper().
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		ctx = ContextWithIdempotencyKey(ctx, "my key")
+		_, _, sent, err := Send(wrapper, http.MethodPost, nil)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("my key"))
+		Expect(sent).To(BeZero())
+	})
+
+	It("Returns empty key when context doesn't have it", func() {
+		Expect(IdempotencyKeyFromContext(ctx)).To(BeEmpty())
+	})
+})
@@ -0,0 +1,178 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the implementation of a transport wrapper that checks that requests contain
+// a set of required headers.
+
+package headers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// RequiredTransportWrapperBuilder contains the data and logic needed to build a new transport
+// wrapper that checks that requests contain a set of required headers before sending them. If any
+// of those headers is missing, or is empty, the request isn't sent and the round tripper returns a
+// *MissingHeadersError. For example, to require the `X-Tenant-ID` header:
+//
+//	wrapper, err := headers.NewRequiredTransportWrapper().
+//		Name("X-Tenant-ID").
+//		Build()
+//
+// Note that the HTTP client wraps errors returned by round trippers, so use the errors.As function
+// to check for this error:
+//
+//	var missing *headers.MissingHeadersError
+//	if errors.As(err, &missing) {
+//		...
+//	}
+//
+// Don't create objects of this type directly; use the NewRequiredTransportWrapper function
+// instead.
+type RequiredTransportWrapperBuilder struct {
+	names []string
+}
+
+// RequiredTransportWrapper contains the data and logic needed to wrap an HTTP round tripper with
+// another one that checks that requests contain a set of required headers.
+type RequiredTransportWrapper struct {
+	names []string
+}
+
+// requiredRoundTripper is a round tripper that checks that requests contain a set of required
+// headers.
+type requiredRoundTripper struct {
+	owner     *RequiredTransportWrapper
+	transport http.RoundTripper
+}
+
+// Make sure that we implement the interface:
+var _ http.RoundTripper = (*requiredRoundTripper)(nil)
+
+// MissingHeadersError is the error returned by the round trippers created by the required headers
+// transport wrapper when a request doesn't contain some of the required headers.
+type MissingHeadersError struct {
+	// Method is the HTTP method of the request.
+	Method string
+
+	// URL is the URL of the request.
+	URL string
+
+	// Names contains the names of the missing headers, in the order that they were added to
+	// the builder.
+	Names []string
+}
+
+// Make sure that we implement the interface:
+var _ error = (*MissingHeadersError)(nil)
+
+// Error is the implementation of the error interface.
+func (e *MissingHeadersError) Error() string {
+	return fmt.Sprintf(
+		"request for method %s and URL '%s' doesn't contain the required headers '%s'",
+		e.Method, e.URL, strings.Join(e.Names, "', '"),
+	)
+}
+
+// NewRequiredTransportWrapper creates a new builder that can then be used to configure and create
+// a new required headers transport wrapper.
+func NewRequiredTransportWrapper() *RequiredTransportWrapperBuilder {
+	return &RequiredTransportWrapperBuilder{}
+}
+
+// Name adds the name of a header that requests must contain.
+func (b *RequiredTransportWrapperBuilder) Name(value string) *RequiredTransportWrapperBuilder {
+	b.names = append(b.names, value)
+	return b
+}
+
+// Names adds a list of names of headers that requests must contain. This is equivalent to calling
+// the Name method for each of them.
+func (b *RequiredTransportWrapperBuilder) Names(values ...string) *RequiredTransportWrapperBuilder {
+	b.names = append(b.names, values...)
+	return b
+}
+
+// Build uses the information stored in the builder to create a new transport wrapper.
+func (b *RequiredTransportWrapperBuilder) Build() (result *RequiredTransportWrapper, err error) {
+	// Check parameters:
+	if len(b.names) == 0 {
+		err = fmt.Errorf("at least one required header is mandatory")
+		return
+	}
+	names := make([]string, 0, len(b.names))
+	seen := map[string]bool{}
+	for _, name := range b.names {
+		if name == "" {
+			err = fmt.Errorf("header name can't be empty")
+			return
+		}
+		key := http.CanonicalHeaderKey(name)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		names = append(names, name)
+	}
+
+	// Create and populate the object:
+	result = &RequiredTransportWrapper{
+		names: names,
+	}
+
+	return
+}
+
+// Wrap creates a new round tripper that wraps the given one and checks that requests contain the
+// required headers.
+func (w *RequiredTransportWrapper) Wrap(transport http.RoundTripper) http.RoundTripper {
+	return &requiredRoundTripper{
+		owner:     w,
+		transport: transport,
+	}
+}
+
+// RoundTrip is the implementation of the round tripper interface.
+func (t *requiredRoundTripper) RoundTrip(request *http.Request) (response *http.Response,
+	err error) {
+	// Find the missing headers:
+	var missing []string
+	for _, name := range t.owner.names {
+		if request.Header.Get(name) == "" {
+			missing = append(missing, name)
+		}
+	}
+
+	// Fail without sending the request if some are missing. Note that round trippers must
+	// always close the request body, even on errors.
+	if len(missing) > 0 {
+		if request.Body != nil {
+			request.Body.Close()
+		}
+		err = &MissingHeadersError{
+			Method: request.Method,
+			URL:    request.URL.String(),
+			Names:  missing,
+		}
+		return
+	}
+
+	// Send the request:
+	response, err = t.transport.RoundTrip(request)
+	return
+}
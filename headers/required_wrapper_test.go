@@ -0,0 +1,111 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains tests for the required headers transport wrapper.
+
+package headers
+
+import (
+	"errors"
+	"net/http"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core" // nolint
+	. "github.com/onsi/gomega"             // nolint
+
+	. "github.com/openshift-online/ocm-sdk-go/testing" // nolint
+)
+
+var _ = Describe("Required headers", func() {
+	// Send sends a request with the given headers using a client created with the given wrapper
+	// and returns the error and the number of requests that reached the transport.
+	var Send = func(wrapper *RequiredTransportWrapper, header http.Header) (sent int, err error) {
+		transport := TransportFunc(func(request *http.Request) (*http.Response, error) {
+			sent++
+			return JSONTransport(http.StatusOK, `{}`).RoundTrip(request)
+		})
+		client := &http.Client{
+			Transport: wrapper.Wrap(transport),
+		}
+		request, err := http.NewRequest(http.MethodGet, "http://api.example.com/mypath", nil)
+		Expect(err).ToNot(HaveOccurred())
+		request.Header = header
+		response, err := client.Do(request)
+		if err == nil {
+			response.Body.Close()
+		}
+		return
+	}
+
+	It("Can't be created without names", func() {
+		wrapper, err := NewRequiredTransportWrapper().
+			Build()
+		Expect(err).To(HaveOccurred())
+		Expect(wrapper).To(BeNil())
+		Expect(err.Error()).To(ContainSubstring("mandatory"))
+	})
+
+	It("Rejects empty name", func() {
+		wrapper, err := NewRequiredTransportWrapper().
+			Name("").
+			Build()
+		Expect(err).To(HaveOccurred())
+		Expect(wrapper).To(BeNil())
+		Expect(err.Error()).To(ContainSubstring("empty"))
+	})
+
+	It("Sends request that contains the required headers", func() {
+		wrapper, err := NewRequiredTransportWrapper().
+			Names("X-Tenant-ID", "X-Other").
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		sent, err := Send(wrapper, http.Header{
+			"X-Tenant-Id": []string{"123"},
+			"X-Other":     []string{"abc"},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(sent).To(Equal(1))
+	})
+
+	It("Fails without sending request if headers are missing", func() {
+		wrapper, err := NewRequiredTransportWrapper().
+			Names("X-Tenant-ID", "X-Other", "X-Present").
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		sent, err := Send(wrapper, http.Header{
+			"X-Other":   []string{""},
+			"X-Present": []string{"yes"},
+		})
+		Expect(err).To(HaveOccurred())
+		Expect(sent).To(BeZero())
+		var missing *MissingHeadersError
+		Expect(errors.As(err, &missing)).To(BeTrue())
+		Expect(missing.Method).To(Equal(http.MethodGet))
+		Expect(missing.URL).To(Equal("http://api.example.com/mypath"))
+		Expect(missing.Names).To(Equal([]string{"X-Tenant-ID", "X-Other"}))
+		Expect(err.Error()).To(ContainSubstring("'X-Tenant-ID', 'X-Other'"))
+	})
+
+	It("Ignores duplicated names", func() {
+		wrapper, err := NewRequiredTransportWrapper().
+			Names("X-Tenant-ID", "x-tenant-id").
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = Send(wrapper, http.Header{})
+		var missing *MissingHeadersError
+		Expect(errors.As(err, &missing)).To(BeTrue())
+		Expect(missing.Names).To(Equal([]string{"X-Tenant-ID"}))
+	})
+})
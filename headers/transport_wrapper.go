@@ -0,0 +1,174 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the implementation of a transport wrapper that changes the spelling of the
+// names of request headers.
+
+package headers
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// TransportWrapperBuilder contains the data and logic needed to build a new transport wrapper that
+// changes the exact spelling of the names of some request headers just before sending them. This
+// is intended for intermediaries that don't comply with the HTTP specification, where header names
+// are case insensitive, and require specific casing. For example, to send the `X-My-Header` header
+// always in lower case:
+//
+//	wrapper, err := headers.NewTransportWrapper().
+//		Name("x-my-header").
+//		Build()
+//
+// Note that this only makes a difference for HTTP/1.x, as HTTP/2 always sends header names in
+// lower case.
+//
+// Don't create objects of this type directly; use the NewTransportWrapper function instead.
+type TransportWrapperBuilder struct {
+	names []string
+}
+
+// TransportWrapper contains the data and logic needed to wrap an HTTP round tripper with another
+// one that changes the spelling of the names of request headers.
+type TransportWrapper struct {
+	names map[string]string
+}
+
+// roundTripper is a round tripper that changes the spelling of the names of request headers.
+type roundTripper struct {
+	owner     *TransportWrapper
+	transport http.RoundTripper
+}
+
+// Make sure that we implement the interface:
+var _ http.RoundTripper = (*roundTripper)(nil)
+
+// NewTransportWrapper creates a new builder that can then be used to configure and create a new
+// header names transport wrapper.
+func NewTransportWrapper() *TransportWrapperBuilder {
+	return &TransportWrapperBuilder{}
+}
+
+// Name adds a header name with the exact spelling that should be used when sending it. Headers
+// whose names are equal to this ignoring case will be renamed to this.
+func (b *TransportWrapperBuilder) Name(value string) *TransportWrapperBuilder {
+	b.names = append(b.names, value)
+	return b
+}
+
+// Names adds a list of header names with the exact spelling that should be used when sending
+// them. This is equivalent to calling the Name method for each of them.
+func (b *TransportWrapperBuilder) Names(values ...string) *TransportWrapperBuilder {
+	b.names = append(b.names, values...)
+	return b
+}
+
+// Build uses the information stored in the builder to create a new transport wrapper.
+func (b *TransportWrapperBuilder) Build() (result *TransportWrapper, err error) {
+	// Check parameters:
+	names := map[string]string{}
+	for _, name := range b.names {
+		if name == "" {
+			err = fmt.Errorf("header name can't be empty")
+			return
+		}
+		key := http.CanonicalHeaderKey(name)
+		if reservedNames[key] {
+			err = fmt.Errorf(
+				"header '%s' is managed by the HTTP client and can't be renamed",
+				name,
+			)
+			return
+		}
+		previous, ok := names[key]
+		if ok && previous != name {
+			err = fmt.Errorf(
+				"header name '%s' conflicts with previously added '%s'",
+				name, previous,
+			)
+			return
+		}
+		names[key] = name
+	}
+
+	// Create and populate the object:
+	result = &TransportWrapper{
+		names: names,
+	}
+
+	return
+}
+
+// Wrap creates a new round tripper that wraps the given one and changes the spelling of the names
+// of request headers.
+func (w *TransportWrapper) Wrap(transport http.RoundTripper) http.RoundTripper {
+	return &roundTripper{
+		owner:     w,
+		transport: transport,
+	}
+}
+
+// RoundTrip is the implementation of the round tripper interface.
+func (t *roundTripper) RoundTrip(request *http.Request) (response *http.Response, err error) {
+	// Check if there is any header that needs to be renamed, so that we don't need to copy the
+	// request if there is nothing to change:
+	renames := false
+	for name := range request.Header {
+		spelling, ok := t.owner.names[http.CanonicalHeaderKey(name)]
+		if ok && spelling != name {
+			renames = true
+			break
+		}
+	}
+	if !renames {
+		response, err = t.transport.RoundTrip(request)
+		return
+	}
+
+	// Round trippers shouldn't modify the request, so we need to work with a copy. Note that we
+	// can't use the Set or Add methods of the header because they canonicalize the name, so we
+	// need to modify the map directly.
+	request = request.Clone(request.Context())
+	header := make(http.Header, len(request.Header))
+	for name, values := range request.Header {
+		spelling, ok := t.owner.names[http.CanonicalHeaderKey(name)]
+		if ok {
+			name = spelling
+		}
+		header[name] = append(header[name], values...)
+	}
+	request.Header = header
+
+	// Send the modified request:
+	response, err = t.transport.RoundTrip(request)
+	return
+}
+
+// reservedNames contains the canonical names of the headers that are managed by the HTTP client
+// and can't be renamed.
+var reservedNames = map[string]bool{
+	"Accept-Encoding":   true,
+	"Connection":        true,
+	"Content-Length":    true,
+	"Host":              true,
+	"Keep-Alive":        true,
+	"Proxy-Connection":  true,
+	"Te":                true,
+	"Trailer":           true,
+	"Transfer-Encoding": true,
+	"Upgrade":           true,
+}
@@ -0,0 +1,125 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains tests for the header names transport wrapper.
+
+package headers
+
+import (
+	"net/http"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"  // nolint
+	. "github.com/onsi/ginkgo/v2/dsl/table" // nolint
+	. "github.com/onsi/gomega"              // nolint
+
+	. "github.com/openshift-online/ocm-sdk-go/testing" // nolint
+)
+
+var _ = Describe("Create", func() {
+	DescribeTable(
+		"Rejects headers managed by the HTTP client",
+		func(name string) {
+			wrapper, err := NewTransportWrapper().
+				Name(name).
+				Build()
+			Expect(err).To(HaveOccurred())
+			Expect(wrapper).To(BeNil())
+			Expect(err.Error()).To(ContainSubstring("can't be renamed"))
+		},
+		Entry("Host", "host"),
+		Entry("Content length", "content-length"),
+		Entry("Transfer encoding", "TRANSFER-ENCODING"),
+	)
+
+	It("Rejects empty name", func() {
+		wrapper, err := NewTransportWrapper().
+			Name("").
+			Build()
+		Expect(err).To(HaveOccurred())
+		Expect(wrapper).To(BeNil())
+		Expect(err.Error()).To(ContainSubstring("empty"))
+	})
+
+	It("Rejects conflicting names", func() {
+		wrapper, err := NewTransportWrapper().
+			Names("x-my-header", "X-MY-HEADER").
+			Build()
+		Expect(err).To(HaveOccurred())
+		Expect(wrapper).To(BeNil())
+		message := err.Error()
+		Expect(message).To(ContainSubstring("x-my-header"))
+		Expect(message).To(ContainSubstring("X-MY-HEADER"))
+	})
+})
+
+var _ = Describe("Round trip", func() {
+	var (
+		original *http.Request
+		received *http.Request
+		client   *http.Client
+	)
+
+	BeforeEach(func() {
+		// Create a transport that saves the received request:
+		transport := TransportFunc(func(request *http.Request) (*http.Response, error) {
+			received = request
+			return JSONTransport(http.StatusOK, `{}`).RoundTrip(request)
+		})
+
+		// Wrap the transport:
+		wrapper, err := NewTransportWrapper().
+			Names("x-my-header", "X-YOUR-Header").
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		client = &http.Client{
+			Transport: wrapper.Wrap(transport),
+		}
+
+		// Prepare the request:
+		original, err = http.NewRequest(http.MethodGet, "http://api.example.com", nil)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Renames headers preserving values", func() {
+		// Send the request:
+		original.Header.Add("X-My-Header", "a")
+		original.Header.Add("X-My-Header", "b")
+		original.Header.Set("X-Your-Header", "c")
+		original.Header.Set("X-Other-Header", "d")
+		response, err := client.Do(original)
+		Expect(err).ToNot(HaveOccurred())
+		defer response.Body.Close()
+
+		// Check the received headers:
+		Expect(received.Header).To(HaveKeyWithValue("x-my-header", []string{"a", "b"}))
+		Expect(received.Header).To(HaveKeyWithValue("X-YOUR-Header", []string{"c"}))
+		Expect(received.Header).To(HaveKeyWithValue("X-Other-Header", []string{"d"}))
+		Expect(received.Header).ToNot(HaveKey("X-My-Header"))
+		Expect(received.Header).ToNot(HaveKey("X-Your-Header"))
+	})
+
+	It("Doesn't modify the original request", func() {
+		// Send the request:
+		original.Header.Set("X-My-Header", "a")
+		response, err := client.Do(original)
+		Expect(err).ToNot(HaveOccurred())
+		defer response.Body.Close()
+
+		// Check the original request:
+		Expect(original.Header).To(HaveKeyWithValue("X-My-Header", []string{"a"}))
+		Expect(original.Header).ToNot(HaveKey("x-my-header"))
+	})
+})
@@ -0,0 +1,54 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the functions used to report errors decoding the bodies of responses.
+
+package helpers
+
+import (
+	"context"
+	"net/http"
+)
+
+// DecodeErrorReporter is a function that is called when the body of a response can't be decoded.
+type DecodeErrorReporter func(err error)
+
+// ContextWithDecodeErrorReporter creates a new context that contains the given function to report
+// errors decoding the bodies of responses. This is intended for transport wrappers that need to
+// know about decode errors, for example the metrics wrapper.
+func ContextWithDecodeErrorReporter(ctx context.Context,
+	reporter DecodeErrorReporter) context.Context {
+	return context.WithValue(ctx, reporterKeyValue, reporter)
+}
+
+// ReportDecodeError calls the decode error reporter stored in the context of the request that
+// generated the given response, if any. It does nothing if the error is nil or if there is no
+// reporter.
+func ReportDecodeError(response *http.Response, err error) {
+	if err == nil || response == nil || response.Request == nil {
+		return
+	}
+	reporter, ok := response.Request.Context().Value(reporterKeyValue).(DecodeErrorReporter)
+	if ok && reporter != nil {
+		reporter(err)
+	}
+}
+
+// reporterKeyType is the type of the key used to store the decode error reporter in the context.
+type reporterKeyType string
+
+// reporterKeyValue is the key used to store the decode error reporter in the context.
+const reporterKeyValue reporterKeyType = "decodeErrorReporter"
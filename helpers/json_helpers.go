@@ -52,8 +52,15 @@ func NewIterator(source interface{}) (iterator *jsoniter.Iterator, err error) {
 	return
 }
 
-// NewStream creates a new JSON stream that will write to the given writer.
+// NewStream creates a new JSON stream that will write to the given writer, applying the transform
+// set with the SetMarshalTransform function, if any.
 func NewStream(writer io.Writer) *jsoniter.Stream {
+	return NewRawStream(transformedWriter(writer))
+}
+
+// NewRawStream creates a new JSON stream that will write to the given writer, without applying
+// the transform set with the SetMarshalTransform function.
+func NewRawStream(writer io.Writer) *jsoniter.Stream {
 	config := jsoniter.Config{
 		IndentionStep: 2,
 	}
@@ -87,59 +94,6 @@ func NewDate(value time.Time) *time.Time {
 	return &value
 }
 
-// ParseInteger reads a string and parses it to integer,
-// if an error occurred it returns a non-nil error.
-func ParseInteger(query url.Values, parameterName string) (*int, error) {
-	values := query[parameterName]
-	count := len(values)
-	if count == 0 {
-		return nil, nil
-	}
-	if count > 1 {
-		err := fmt.Errorf(
-			"expected at most one value for parameter '%s' but got %d",
-			parameterName, count,
-		)
-		return nil, err
-	}
-	value := values[0]
-	parsedInt64, err := strconv.ParseInt(value, 10, 64)
-	if err != nil {
-		return nil, fmt.Errorf(
-			"value '%s' isn't valid for the '%s' parameter because it isn't an integer: %v",
-			value, parameterName, err,
-		)
-	}
-	parsedInt := int(parsedInt64)
-	return &parsedInt, nil
-}
-
-// ParseFloat reads a string and parses it to float,
-// if an error occurred it returns a non-nil error.
-func ParseFloat(query url.Values, parameterName string) (*float64, error) {
-	values := query[parameterName]
-	count := len(values)
-	if count == 0 {
-		return nil, nil
-	}
-	if count > 1 {
-		err := fmt.Errorf(
-			"expected at most one value for parameter '%s' but got %d",
-			parameterName, count,
-		)
-		return nil, err
-	}
-	value := values[0]
-	parsedFloat, err := strconv.ParseFloat(value, 64)
-	if err != nil {
-		return nil, fmt.Errorf(
-			"value '%s' isn't valid for the '%s' parameter because it isn't a float: %v",
-			value, parameterName, err,
-		)
-	}
-	return &parsedFloat, nil
-}
-
 // ParseString returns a pointer to the string and nil error.
 func ParseString(query url.Values, parameterName string) (*string, error) {
 	values := query[parameterName]
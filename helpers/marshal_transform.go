@@ -0,0 +1,171 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the hook that can be used to change the JSON documents generated by the
+// marshal functions.
+
+package helpers
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// MarshalTransform is a function that transforms the JSON documents written by the generated
+// marshal functions, for example MarshalCluster. It receives the complete document and returns
+// the document that will be written instead.
+type MarshalTransform func(data []byte) ([]byte, error)
+
+// SetMarshalTransform sets the function that will be used to transform the JSON documents written
+// by the generated marshal functions. This is intended for servers that need a wire format that
+// is slightly different to the one of the API, for example wrapping the documents in an envelope:
+//
+//	helpers.SetMarshalTransform(func(data []byte) ([]byte, error) {
+//		result := []byte(`{"data":`)
+//		result = append(result, data...)
+//		result = append(result, '}')
+//		return result, nil
+//	})
+//
+// Use the RenameFields function to create a transform that changes the names of the fields.
+// Passing nil restores the default, which is to write the documents unchanged. This affects all
+// the documents written by the process, including the bodies of the requests sent by the generated
+// clients, so it should usually be called only once, during initialization. Error bodies aren't
+// affected; use the errors.SetErrorEncoder function to change them.
+func SetMarshalTransform(value MarshalTransform) {
+	marshalTransformLock.Lock()
+	defer marshalTransformLock.Unlock()
+	marshalTransform = value
+}
+
+// RenameFields creates a transform that replaces the name of each field of each object of the
+// document with the result of calling the given function. For example, to use upper case names:
+//
+//	helpers.SetMarshalTransform(helpers.RenameFields(strings.ToUpper))
+//
+// The order of the fields and the rest of the content of the document are preserved.
+func RenameFields(namer func(name string) string) MarshalTransform {
+	return func(data []byte) (result []byte, err error) {
+		iterator, err := NewIterator(data)
+		if err != nil {
+			return
+		}
+		buffer := &bytes.Buffer{}
+		stream := NewRawStream(buffer)
+		renameFields(iterator, stream, namer)
+		if iterator.Error != nil && iterator.Error != io.EOF {
+			err = iterator.Error
+			return
+		}
+		err = stream.Flush()
+		if err != nil {
+			return
+		}
+		result = buffer.Bytes()
+		return
+	}
+}
+
+// renameFields copies the next value from the iterator to the stream, replacing the names of the
+// fields of the objects.
+func renameFields(iterator *jsoniter.Iterator, stream *jsoniter.Stream,
+	namer func(string) string) {
+	switch iterator.WhatIsNext() {
+	case jsoniter.ObjectValue:
+		count := 0
+		stream.WriteObjectStart()
+		for field := iterator.ReadObject(); field != ""; field = iterator.ReadObject() {
+			if count > 0 {
+				stream.WriteMore()
+			}
+			stream.WriteObjectField(namer(field))
+			renameFields(iterator, stream, namer)
+			count++
+		}
+		stream.WriteObjectEnd()
+	case jsoniter.ArrayValue:
+		count := 0
+		stream.WriteArrayStart()
+		for iterator.ReadArray() {
+			if count > 0 {
+				stream.WriteMore()
+			}
+			renameFields(iterator, stream, namer)
+			count++
+		}
+		stream.WriteArrayEnd()
+	case jsoniter.StringValue:
+		stream.WriteString(iterator.ReadString())
+	case jsoniter.NumberValue:
+		stream.WriteRaw(string(iterator.ReadNumber()))
+	case jsoniter.BoolValue:
+		stream.WriteBool(iterator.ReadBool())
+	case jsoniter.NilValue:
+		iterator.ReadNil()
+		stream.WriteNil()
+	default:
+		iterator.ReportError("rename", "unexpected value type")
+	}
+}
+
+// transformWriter applies the marshal transform to the documents written by the generated marshal
+// functions. Those functions write the complete document with a single call to the Write method,
+// when the stream is flushed.
+type transformWriter struct {
+	transform MarshalTransform
+	writer    io.Writer
+}
+
+// Write is the implementation of the io.Writer interface.
+func (w *transformWriter) Write(data []byte) (count int, err error) {
+	transformed, err := w.transform(data)
+	if err != nil {
+		err = fmt.Errorf("can't transform JSON document: %w", err)
+		return
+	}
+	_, err = w.writer.Write(transformed)
+	if err != nil {
+		return
+	}
+	count = len(data)
+	return
+}
+
+// transformedWriter returns a writer that applies the current marshal transform to the given
+// writer, or the given writer unchanged if there is no transform.
+func transformedWriter(writer io.Writer) io.Writer {
+	marshalTransformLock.RLock()
+	transform := marshalTransform
+	marshalTransformLock.RUnlock()
+	if transform == nil {
+		return writer
+	}
+	return &transformWriter{
+		transform: transform,
+		writer:    writer,
+	}
+}
+
+// marshalTransform is the function currently used to transform the documents written by the
+// generated marshal functions.
+var marshalTransform MarshalTransform
+
+// marshalTransformLock protects the marshalTransform variable.
+var marshalTransformLock sync.RWMutex
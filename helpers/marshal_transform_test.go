@@ -0,0 +1,164 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains tests for the hook that transforms the JSON documents written by the marshal
+// functions.
+
+package helpers
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core" // nolint
+	. "github.com/onsi/gomega"             // nolint
+)
+
+var _ = Describe("Marshal transform", func() {
+	AfterEach(func() {
+		SetMarshalTransform(nil)
+	})
+
+	// Marshal writes a document in the same way that the generated marshal functions do it,
+	// creating the stream, writing the values and then flushing it.
+	var Marshal = func() (result string, err error) {
+		buffer := &bytes.Buffer{}
+		stream := NewStream(buffer)
+		stream.WriteObjectStart()
+		stream.WriteObjectField("kind")
+		stream.WriteString("Cluster")
+		stream.WriteMore()
+		stream.WriteObjectField("node_count")
+		stream.WriteInt(3)
+		stream.WriteMore()
+		stream.WriteObjectField("labels")
+		stream.WriteArrayStart()
+		stream.WriteObjectStart()
+		stream.WriteObjectField("key")
+		stream.WriteString("my")
+		stream.WriteMore()
+		stream.WriteObjectField("enabled")
+		stream.WriteBool(true)
+		stream.WriteMore()
+		stream.WriteObjectField("value")
+		stream.WriteNil()
+		stream.WriteObjectEnd()
+		stream.WriteArrayEnd()
+		stream.WriteObjectEnd()
+		err = stream.Flush()
+		if err != nil {
+			return
+		}
+		result = buffer.String()
+		return
+	}
+
+	It("Reproduces the document exactly when the names don't change", func() {
+		original, err := Marshal()
+		Expect(err).ToNot(HaveOccurred())
+		SetMarshalTransform(RenameFields(func(name string) string {
+			return name
+		}))
+		renamed, err := Marshal()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(renamed).To(Equal(original))
+	})
+
+	It("Wraps the document in an envelope", func() {
+		SetMarshalTransform(func(data []byte) ([]byte, error) {
+			result := []byte(`{"data":`)
+			result = append(result, data...)
+			result = append(result, '}')
+			return result, nil
+		})
+		result, err := Marshal()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(MatchJSON(`{
+			"data": {
+				"kind": "Cluster",
+				"node_count": 3,
+				"labels": [
+					{
+						"key": "my",
+						"enabled": true,
+						"value": null
+					}
+				]
+			}
+		}`))
+	})
+
+	It("Renames the fields", func() {
+		SetMarshalTransform(RenameFields(strings.ToUpper))
+		result, err := Marshal()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(MatchJSON(`{
+			"KIND": "Cluster",
+			"NODE_COUNT": 3,
+			"LABELS": [
+				{
+					"KEY": "my",
+					"ENABLED": true,
+					"VALUE": null
+				}
+			]
+		}`))
+	})
+
+	It("Preserves the order of the fields", func() {
+		SetMarshalTransform(RenameFields(strings.ToUpper))
+		result, err := Marshal()
+		Expect(err).ToNot(HaveOccurred())
+		kind := strings.Index(result, "KIND")
+		count := strings.Index(result, "NODE_COUNT")
+		labels := strings.Index(result, "LABELS")
+		Expect(kind).To(BeNumerically("<", count))
+		Expect(count).To(BeNumerically("<", labels))
+	})
+
+	It("Returns the error of the transform", func() {
+		SetMarshalTransform(func(data []byte) ([]byte, error) {
+			return nil, errors.New("my error")
+		})
+		_, err := Marshal()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("my error"))
+	})
+
+	It("Restores the default when nil is given", func() {
+		original, err := Marshal()
+		Expect(err).ToNot(HaveOccurred())
+		SetMarshalTransform(RenameFields(strings.ToUpper))
+		SetMarshalTransform(nil)
+		result, err := Marshal()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(Equal(original))
+	})
+
+	It("Doesn't transform raw streams", func() {
+		SetMarshalTransform(RenameFields(strings.ToUpper))
+		buffer := &bytes.Buffer{}
+		stream := NewRawStream(buffer)
+		stream.WriteObjectStart()
+		stream.WriteObjectField("kind")
+		stream.WriteString("Cluster")
+		stream.WriteObjectEnd()
+		err := stream.Flush()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(buffer.String()).To(MatchJSON(`{"kind": "Cluster"}`))
+	})
+})
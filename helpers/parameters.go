@@ -0,0 +1,160 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the functions that parse the integer and floating point query parameters,
+// and the parameters used for pagination. The integer and floating point functions aren't in the
+// generated json_helpers.go file because they reject values that don't fit in the int type and
+// values that aren't finite.
+
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+)
+
+// ParseInteger reads a string and parses it to integer,
+// if an error occurred it returns a non-nil error.
+func ParseInteger(query url.Values, parameterName string) (*int, error) {
+	value, err := singleValue(query, parameterName)
+	if value == nil || err != nil {
+		return nil, err
+	}
+	parsedInt, err := parseInteger(*value)
+	if errors.Is(err, strconv.ErrRange) {
+		return nil, fmt.Errorf(
+			"value '%s' isn't valid for the '%s' parameter because it is out of range, "+
+				"it must be between %d and %d",
+			*value, parameterName, math.MinInt, math.MaxInt,
+		)
+	}
+	if err != nil {
+		return nil, fmt.Errorf(
+			"value '%s' isn't valid for the '%s' parameter because it isn't an integer: %v",
+			*value, parameterName, err,
+		)
+	}
+	return &parsedInt, nil
+}
+
+// ParseIntegerRange reads a string and parses it to an integer that must be between the given
+// minimum and maximum, both included. If an error occurred, or the value is out of that range, it
+// returns a non-nil error.
+func ParseIntegerRange(query url.Values, parameterName string, min, max int) (*int, error) {
+	parsedInt, err := ParseInteger(query, parameterName)
+	if parsedInt == nil || err != nil {
+		return nil, err
+	}
+	if *parsedInt < min || *parsedInt > max {
+		return nil, fmt.Errorf(
+			"value '%d' isn't valid for the '%s' parameter because it is out of range, "+
+				"it must be between %d and %d",
+			*parsedInt, parameterName, min, max,
+		)
+	}
+	return parsedInt, nil
+}
+
+// Default values of the pagination parameters:
+const (
+	DefaultPage = 1
+	DefaultSize = 100
+)
+
+// ParsePagination reads the `page` and `size` parameters used for paginated lists. If a parameter
+// isn't present the corresponding default value, DefaultPage or DefaultSize, is returned. Values
+// that aren't positive integers are rejected, as they are always invalid for pagination.
+func ParsePagination(query url.Values) (page, size int, err error) {
+	pageValue, err := ParseIntegerRange(query, "page", 1, math.MaxInt)
+	if err != nil {
+		return
+	}
+	sizeValue, err := ParseIntegerRange(query, "size", 1, math.MaxInt)
+	if err != nil {
+		return
+	}
+	page = DefaultPage
+	if pageValue != nil {
+		page = *pageValue
+	}
+	size = DefaultSize
+	if sizeValue != nil {
+		size = *sizeValue
+	}
+	return
+}
+
+// ParseFloat reads a string and parses it to float,
+// if an error occurred it returns a non-nil error.
+func ParseFloat(query url.Values, parameterName string) (*float64, error) {
+	value, err := singleValue(query, parameterName)
+	if value == nil || err != nil {
+		return nil, err
+	}
+	parsedFloat, err := parseFloat(*value)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"value '%s' isn't valid for the '%s' parameter because it isn't a float: %v",
+			*value, parameterName, err,
+		)
+	}
+	return &parsedFloat, nil
+}
+
+// singleValue returns the value of the given query parameter. It returns nil if the parameter
+// isn't present, and an error if it is present more than once.
+func singleValue(query url.Values, parameterName string) (*string, error) {
+	values := query[parameterName]
+	count := len(values)
+	if count == 0 {
+		return nil, nil
+	}
+	if count > 1 {
+		err := fmt.Errorf(
+			"expected at most one value for parameter '%s' but got %d",
+			parameterName, count,
+		)
+		return nil, err
+	}
+	return &values[0], nil
+}
+
+// parseInteger parses the given text as a decimal integer. Note that the bit size used is the size
+// of the int type of the platform, so values that don't fit are rejected instead of silently
+// truncated.
+func parseInteger(text string) (int, error) {
+	parsed, err := strconv.ParseInt(text, 10, strconv.IntSize)
+	if err != nil {
+		return 0, err
+	}
+	return int(parsed), nil
+}
+
+// parseFloat parses the given text as a floating point number. Values that aren't finite, like
+// `NaN` or `Inf`, are rejected.
+func parseFloat(text string) (float64, error) {
+	parsed, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(parsed) || math.IsInf(parsed, 0) {
+		return 0, fmt.Errorf("value '%s' isn't a finite number", text)
+	}
+	return parsed, nil
+}
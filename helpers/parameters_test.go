@@ -0,0 +1,242 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains tests and fuzz targets for the functions that parse query parameters. To run
+// the fuzz targets use a command like this:
+//
+//	go test -fuzz=FuzzParseInteger ./helpers
+
+package helpers
+
+import (
+	"math"
+	"net/url"
+	"strconv"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"  // nolint
+	. "github.com/onsi/ginkgo/v2/dsl/table" // nolint
+	. "github.com/onsi/gomega"              // nolint
+)
+
+var _ = Describe("Parse integer", func() {
+	It("Returns nil if the parameter isn't present", func() {
+		result, err := ParseInteger(url.Values{}, "size")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(BeNil())
+	})
+
+	It("Rejects multiple values", func() {
+		query := url.Values{
+			"size": []string{"1", "2"},
+		}
+		result, err := ParseInteger(query, "size")
+		Expect(err).To(HaveOccurred())
+		Expect(result).To(BeNil())
+		Expect(err.Error()).To(ContainSubstring("at most one value"))
+	})
+
+	DescribeTable(
+		"Accepts valid values",
+		func(text string, expected int) {
+			query := url.Values{
+				"size": []string{text},
+			}
+			result, err := ParseInteger(query, "size")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).ToNot(BeNil())
+			Expect(*result).To(Equal(expected))
+		},
+		Entry("Zero", "0", 0),
+		Entry("Positive", "42", 42),
+		Entry("Negative", "-42", -42),
+		Entry("Maximum", strconv.Itoa(math.MaxInt), math.MaxInt),
+		Entry("Minimum", strconv.Itoa(math.MinInt), math.MinInt),
+	)
+
+	DescribeTable(
+		"Rejects out of range values",
+		func(text string) {
+			query := url.Values{
+				"size": []string{text},
+			}
+			result, err := ParseInteger(query, "size")
+			Expect(err).To(HaveOccurred())
+			Expect(result).To(BeNil())
+			message := err.Error()
+			Expect(message).To(ContainSubstring("'size'"))
+			Expect(message).To(ContainSubstring("out of range"))
+			Expect(message).To(ContainSubstring(strconv.Itoa(math.MaxInt)))
+		},
+		Entry("Too large", "99999999999999999999"),
+		Entry("Too small", "-99999999999999999999"),
+	)
+
+	It("Rejects values that aren't integers", func() {
+		query := url.Values{
+			"size": []string{"1.5"},
+		}
+		result, err := ParseInteger(query, "size")
+		Expect(err).To(HaveOccurred())
+		Expect(result).To(BeNil())
+		Expect(err.Error()).To(ContainSubstring("isn't an integer"))
+	})
+})
+
+var _ = Describe("Parse integer range", func() {
+	DescribeTable(
+		"Accepts values inside the range",
+		func(text string, expected int) {
+			query := url.Values{
+				"page": []string{text},
+			}
+			result, err := ParseIntegerRange(query, "page", 1, 100)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).ToNot(BeNil())
+			Expect(*result).To(Equal(expected))
+		},
+		Entry("Minimum", "1", 1),
+		Entry("Middle", "50", 50),
+		Entry("Maximum", "100", 100),
+	)
+
+	DescribeTable(
+		"Rejects values outside the range",
+		func(text string) {
+			query := url.Values{
+				"page": []string{text},
+			}
+			result, err := ParseIntegerRange(query, "page", 1, 100)
+			Expect(err).To(HaveOccurred())
+			Expect(result).To(BeNil())
+			message := err.Error()
+			Expect(message).To(ContainSubstring("'page'"))
+			Expect(message).To(ContainSubstring("between 1 and 100"))
+		},
+		Entry("Zero", "0"),
+		Entry("Negative", "-1"),
+		Entry("Above maximum", "101"),
+	)
+
+	It("Returns nil if the parameter isn't present", func() {
+		result, err := ParseIntegerRange(url.Values{}, "page", 1, 100)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(BeNil())
+	})
+})
+
+var _ = Describe("Parse pagination", func() {
+	It("Applies defaults", func() {
+		page, size, err := ParsePagination(url.Values{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(page).To(Equal(DefaultPage))
+		Expect(size).To(Equal(DefaultSize))
+	})
+
+	It("Returns the given values", func() {
+		query := url.Values{
+			"page": []string{"3"},
+			"size": []string{"10"},
+		}
+		page, size, err := ParsePagination(query)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(page).To(Equal(3))
+		Expect(size).To(Equal(10))
+	})
+
+	DescribeTable(
+		"Rejects values that aren't positive",
+		func(name, value string) {
+			query := url.Values{
+				name: []string{value},
+			}
+			_, _, err := ParsePagination(query)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("'" + name + "'"))
+		},
+		Entry("Negative page", "page", "-1"),
+		Entry("Zero page", "page", "0"),
+		Entry("Negative size", "size", "-1"),
+		Entry("Zero size", "size", "0"),
+		Entry("Invalid size", "size", "ten"),
+	)
+})
+
+func FuzzParseInteger(f *testing.F) {
+	f.Add("0")
+	f.Add("-1")
+	f.Add("123")
+	f.Add("+42")
+	f.Add("99999999999999999999")
+	f.Add("-99999999999999999999")
+	f.Add(strconv.Itoa(math.MaxInt))
+	f.Add(strconv.Itoa(math.MinInt))
+	f.Add("1e3")
+	f.Add("0x10")
+	f.Add("")
+	f.Fuzz(func(t *testing.T, text string) {
+		query := url.Values{
+			"size": []string{text},
+		}
+		result, err := ParseInteger(query, "size")
+		if err != nil {
+			if result != nil {
+				t.Fatalf("expected nil result for '%s' when there is an error", text)
+			}
+			return
+		}
+		if result == nil {
+			t.Fatalf("expected result for '%s' when there is no error", text)
+		}
+		expected, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			t.Fatalf("accepted '%s' but it isn't a valid integer: %v", text, err)
+		}
+		if int64(*result) != expected {
+			t.Fatalf("value '%s' was parsed as %d", text, *result)
+		}
+	})
+}
+
+func FuzzParseFloat(f *testing.F) {
+	f.Add("0")
+	f.Add("-1.5")
+	f.Add("1e308")
+	f.Add("1e309")
+	f.Add("-1e309")
+	f.Add("NaN")
+	f.Add("Inf")
+	f.Add("0x1p-2")
+	f.Add("")
+	f.Fuzz(func(t *testing.T, text string) {
+		query := url.Values{
+			"ratio": []string{text},
+		}
+		result, err := ParseFloat(query, "ratio")
+		if err != nil {
+			if result != nil {
+				t.Fatalf("expected nil result for '%s' when there is an error", text)
+			}
+			return
+		}
+		if result == nil {
+			t.Fatalf("expected result for '%s' when there is no error", text)
+		}
+		if math.IsNaN(*result) || math.IsInf(*result, 0) {
+			t.Fatalf("value '%s' was parsed as non finite %f", text, *result)
+		}
+	})
+}
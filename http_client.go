@@ -0,0 +1,90 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the implementation of the HTTP client that sends requests using a connection.
+
+package sdk
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// HTTPClient returns an HTTP client that sends requests using this connection. The requests will
+// be authenticated with the tokens of the connection, which will be refreshed when needed, and
+// will go through the same wrappers used for requests sent with the generated clients, for
+// example the ones that retry failed requests and generate metrics. This is intended for code
+// that accepts an *http.Client but doesn't know about the SDK. For example:
+//
+//	client := connection.HTTPClient()
+//	response, err := client.Get("https://api.openshift.com/api/clusters_mgmt/v1/clusters")
+//
+// The URLs of the requests must be absolute, and their scheme and host must be the ones of the
+// URL that the connection would use for the path, as configured with the URL and AlternativeURL
+// methods of the builder. Requests for other servers are rejected, so that the tokens aren't sent
+// to them. Note that, as for the rest of the requests sent with the connection, responses whose
+// content type isn't JSON are reported as errors. The connection is still owned by the caller, and
+// closing it makes the client unusable.
+func (c *Connection) HTTPClient() *http.Client {
+	return &http.Client{
+		Transport: &connectionRoundTripper{
+			connection: c,
+		},
+	}
+}
+
+// connectionRoundTripper is a round tripper that sends requests with absolute URLs using a
+// connection, which only accepts relative URLs.
+type connectionRoundTripper struct {
+	connection *Connection
+}
+
+// Make sure that we implement the http.RoundTripper interface:
+var _ http.RoundTripper = &connectionRoundTripper{}
+
+// RoundTrip is the implementation of the http.RoundTripper interface.
+func (t *connectionRoundTripper) RoundTrip(request *http.Request) (response *http.Response,
+	err error) {
+	// Check that the request is for the server that the connection would use for the path:
+	server, err := t.connection.selectServer(request.Context(), request)
+	if err != nil {
+		closeRequestBody(request)
+		return
+	}
+	if !strings.EqualFold(request.URL.Scheme, server.URL.Scheme) ||
+		!strings.EqualFold(request.URL.Host, server.URL.Host) {
+		err = fmt.Errorf(
+			"request URL '%s' doesn't match the server '%s' of the connection for "+
+				"path '%s'",
+			request.URL, server.URL, request.URL.Path,
+		)
+		closeRequestBody(request)
+		return
+	}
+
+	// Round trippers shouldn't modify the request, so we need to replace it with a copy that
+	// has only the path and query, as that is what the connection expects:
+	request = request.Clone(request.Context())
+	request.URL.Scheme = ""
+	request.URL.Host = ""
+	request.URL.User = nil
+	request.Host = ""
+
+	// Send the modified request:
+	response, err = t.connection.RoundTrip(request)
+	return
+}
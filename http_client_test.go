@@ -0,0 +1,126 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains tests for the HTTP client that sends requests using a connection.
+
+package sdk
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core" // nolint
+	. "github.com/onsi/gomega"             // nolint
+
+	"github.com/onsi/gomega/ghttp"
+
+	. "github.com/openshift-online/ocm-sdk-go/testing" // nolint
+)
+
+var _ = Describe("HTTP client", func() {
+	var token string
+	var server *ghttp.Server
+	var connection *Connection
+	var client *http.Client
+
+	BeforeEach(func() {
+		var err error
+
+		// Create the token:
+		token = MakeTokenString("Bearer", 5*time.Minute)
+
+		// Create the server:
+		server = MakeTCPServer()
+
+		// Create the connection:
+		connection, err = NewConnectionBuilder().
+			Logger(logger).
+			URL(server.URL()).
+			Tokens(token).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Create the client:
+		client = connection.HTTPClient()
+	})
+
+	AfterEach(func() {
+		// Close the connection:
+		err := connection.Close()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Stop the server:
+		server.Close()
+	})
+
+	It("Sends authenticated request", func() {
+		// Prepare the server:
+		server.AppendHandlers(
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest(
+					http.MethodGet,
+					"/api/clusters_mgmt/v1/clusters",
+					"search=name='my'",
+				),
+				ghttp.VerifyHeaderKV("Authorization", "Bearer "+token),
+				RespondWithJSON(http.StatusOK, `{"items":[]}`),
+			),
+		)
+
+		// Send the request:
+		response, err := client.Get(
+			server.URL() + "/api/clusters_mgmt/v1/clusters?search=name='my'",
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer response.Body.Close()
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+		body, err := io.ReadAll(response.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(body).To(MatchJSON(`{"items":[]}`))
+	})
+
+	It("Sends request with body", func() {
+		// Prepare the server:
+		server.AppendHandlers(
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest(http.MethodPost, "/api/clusters_mgmt/v1/clusters"),
+				ghttp.VerifyJSON(`{"name":"my"}`),
+				ghttp.VerifyHeaderKV("Authorization", "Bearer "+token),
+				RespondWithJSON(http.StatusCreated, `{"id":"123"}`),
+			),
+		)
+
+		// Send the request:
+		response, err := client.Post(
+			server.URL()+"/api/clusters_mgmt/v1/clusters",
+			"application/json",
+			strings.NewReader(`{"name":"my"}`),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer response.Body.Close()
+		Expect(response.StatusCode).To(Equal(http.StatusCreated))
+	})
+
+	It("Rejects request for other server", func() {
+		response, err := client.Get("https://example.com/api/clusters_mgmt/v1/clusters")
+		Expect(err).To(HaveOccurred())
+		Expect(response).To(BeNil())
+		Expect(err.Error()).To(ContainSubstring("doesn't match"))
+		Expect(server.ReceivedRequests()).To(BeEmpty())
+	})
+})
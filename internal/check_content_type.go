@@ -75,6 +75,14 @@ func contentSummary(mediaType string, response *http.Response) (summary string,
 	if err != nil {
 		return
 	}
+	summary = ContentSummary(mediaType, body)
+	return
+}
+
+// ContentSummary returns a summary of the given content. The summary will be the complete content
+// if it isn't too long. If it is too long then the summary will be the beginning of the content
+// followed by ellipsis. If the media type is HTML the tags are removed first.
+func ContentSummary(mediaType string, body []byte) string {
 	limit := 250
 	runes := []rune(string(body))
 	if strings.EqualFold(mediaType, "text/html") && len(runes) > limit {
@@ -83,9 +91,7 @@ func contentSummary(mediaType string, response *http.Response) (summary string,
 		runes = []rune(content)
 	}
 	if len(runes) > limit {
-		summary = fmt.Sprintf("%s...", string(runes[:limit]))
-	} else {
-		summary = string(runes)
+		return fmt.Sprintf("%s...", string(runes[:limit]))
 	}
-	return
+	return string(runes)
 }
@@ -30,6 +30,7 @@ import (
 	"os"
 	"sync"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"golang.org/x/net/http2"
 
 	"github.com/openshift-online/ocm-sdk-go/logging"
@@ -38,24 +39,34 @@ import (
 // ClientSelectorBuilder contains the information and logic needed to create an HTTP client
 // selector. Don't create instances of this type directly, use the NewClientSelector function.
 type ClientSelectorBuilder struct {
-	logger            logging.Logger
-	trustedCAs        []interface{}
-	insecure          bool
-	disableKeepAlives bool
-	transportWrappers []func(http.RoundTripper) http.RoundTripper
+	logger             logging.Logger
+	trustedCAs         []interface{}
+	insecure           bool
+	minTLSVersion      uint16
+	cipherSuites       []uint16
+	disableKeepAlives  bool
+	disableCompression bool
+	maxConnsPerHost    int
+	connectionsGauge   *prometheus.GaugeVec
+	transportWrappers  []func(http.RoundTripper) http.RoundTripper
 }
 
 // ClientSelector contains the information needed to create select the HTTP client to use to connect
 // to servers using TCP or Unix sockets.
 type ClientSelector struct {
-	logger            logging.Logger
-	trustedCAs        *x509.CertPool
-	insecure          bool
-	disableKeepAlives bool
-	transportWrappers []func(http.RoundTripper) http.RoundTripper
-	cookieJar         http.CookieJar
-	clientsMutex      *sync.Mutex
-	clientsTable      map[string]*http.Client
+	logger             logging.Logger
+	trustedCAs         *x509.CertPool
+	insecure           bool
+	minTLSVersion      uint16
+	cipherSuites       []uint16
+	disableKeepAlives  bool
+	disableCompression bool
+	maxConnsPerHost    int
+	connectionsGauge   *prometheus.GaugeVec
+	transportWrappers  []func(http.RoundTripper) http.RoundTripper
+	cookieJar          http.CookieJar
+	clientsMutex       *sync.Mutex
+	clientsTable       map[string]*http.Client
 }
 
 // NewClientSelector creates a builder that can then be used to configure and create an HTTP client
@@ -101,6 +112,22 @@ func (b *ClientSelectorBuilder) Insecure(flag bool) *ClientSelectorBuilder {
 	return b
 }
 
+// MinTLSVersion sets the minimum TLS version that will be accepted, for example tls.VersionTLS13.
+// It can't be less than TLS 1.2. The default is zero, which means that the default of the Go TLS
+// library will be used.
+func (b *ClientSelectorBuilder) MinTLSVersion(value uint16) *ClientSelectorBuilder {
+	b.minTLSVersion = value
+	return b
+}
+
+// CipherSuites sets the list of cipher suites that will be used for TLS 1.2 connections. Note that
+// the Go TLS library doesn't allow configuring the cipher suites of TLS 1.3. The default is to use
+// the cipher suites selected by the Go TLS library.
+func (b *ClientSelectorBuilder) CipherSuites(values ...uint16) *ClientSelectorBuilder {
+	b.cipherSuites = append(b.cipherSuites, values...)
+	return b
+}
+
 // DisableKeepAlives disables HTTP keep-alives with the serviers. This is unrelated to similarly
 // named TCP keep-alives.
 func (b *ClientSelectorBuilder) DisableKeepAlives(flag bool) *ClientSelectorBuilder {
@@ -108,6 +135,32 @@ func (b *ClientSelectorBuilder) DisableKeepAlives(flag bool) *ClientSelectorBuil
 	return b
 }
 
+// DisableCompression disables the transparent compression support of the HTTP transports, so that
+// they don't add the `Accept-Encoding: gzip` header to requests and don't decompress responses.
+func (b *ClientSelectorBuilder) DisableCompression(flag bool) *ClientSelectorBuilder {
+	b.disableCompression = flag
+	return b
+}
+
+// MaxConnsPerHost sets the maximum number of connections, including the ones that are being
+// dialed, in use and idle, that each HTTP client will open to its server. When the limit is reached
+// new requests block till a connection is available. Note that this has no effect for h2c servers,
+// as all their requests use a single connection. The default is zero, which means no limit.
+func (b *ClientSelectorBuilder) MaxConnsPerHost(value int) *ClientSelectorBuilder {
+	b.maxConnsPerHost = value
+	return b
+}
+
+// ConnectionsGauge sets the gauge that will be incremented when connections are opened and
+// decremented when they are closed. It should have the labels in the ConnectionsGaugeLabels
+// variable. The `host` label will contain the host and port for TCP servers, or the path of the
+// socket for Unix sockets. The default is to not count connections.
+func (b *ClientSelectorBuilder) ConnectionsGauge(
+	value *prometheus.GaugeVec) *ClientSelectorBuilder {
+	b.connectionsGauge = value
+	return b
+}
+
 // TransportWrapper adds a function that will be used to wrap the transports of the HTTP clients. If
 // used multiple times the transport wrappers will be called in the same order that they are added.
 func (b *ClientSelectorBuilder) TransportWrapper(
@@ -134,6 +187,22 @@ func (b *ClientSelectorBuilder) Build(ctx context.Context) (result *ClientSelect
 		err = fmt.Errorf("logger is mandatory")
 		return
 	}
+	if b.maxConnsPerHost < 0 {
+		err = fmt.Errorf(
+			"maximum number of connections per host should be zero or positive, "+
+				"but it is %d",
+			b.maxConnsPerHost,
+		)
+		return
+	}
+	err = checkTLSVersion(b.minTLSVersion)
+	if err != nil {
+		return
+	}
+	err = checkCipherSuites(b.cipherSuites)
+	if err != nil {
+		return
+	}
 
 	// Create the cookie jar:
 	cookieJar, err := b.createCookieJar()
@@ -149,19 +218,71 @@ func (b *ClientSelectorBuilder) Build(ctx context.Context) (result *ClientSelect
 
 	// Create and populate the object:
 	result = &ClientSelector{
-		logger:            b.logger,
-		trustedCAs:        trustedCAs,
-		insecure:          b.insecure,
-		disableKeepAlives: b.disableKeepAlives,
-		transportWrappers: b.transportWrappers,
-		cookieJar:         cookieJar,
-		clientsMutex:      &sync.Mutex{},
-		clientsTable:      map[string]*http.Client{},
+		logger:             b.logger,
+		trustedCAs:         trustedCAs,
+		insecure:           b.insecure,
+		minTLSVersion:      b.minTLSVersion,
+		cipherSuites:       b.cipherSuites,
+		disableKeepAlives:  b.disableKeepAlives,
+		disableCompression: b.disableCompression,
+		maxConnsPerHost:    b.maxConnsPerHost,
+		connectionsGauge:   b.connectionsGauge,
+		transportWrappers:  b.transportWrappers,
+		cookieJar:          cookieJar,
+		clientsMutex:       &sync.Mutex{},
+		clientsTable:       map[string]*http.Client{},
 	}
 
 	return
 }
 
+// checkTLSVersion checks that the given minimum TLS version is zero, meaning that the default
+// should be used, or at least TLS 1.2.
+func checkTLSVersion(value uint16) error {
+	if value == 0 {
+		return nil
+	}
+	if value < tls.VersionTLS12 {
+		return fmt.Errorf(
+			"minimum TLS version 0x%04x isn't valid, it should be at least TLS 1.2",
+			value,
+		)
+	}
+	if value > tls.VersionTLS13 {
+		return fmt.Errorf(
+			"minimum TLS version 0x%04x isn't valid, it isn't a known TLS version",
+			value,
+		)
+	}
+	return nil
+}
+
+// checkCipherSuites checks that the given cipher suites are known by the Go TLS library and that
+// they aren't considered insecure.
+func checkCipherSuites(values []uint16) error {
+	secure := map[uint16]bool{}
+	for _, suite := range tls.CipherSuites() {
+		secure[suite.ID] = true
+	}
+	insecure := map[uint16]string{}
+	for _, suite := range tls.InsecureCipherSuites() {
+		insecure[suite.ID] = suite.Name
+	}
+	for _, value := range values {
+		name, ok := insecure[value]
+		if ok {
+			return fmt.Errorf("cipher suite '%s' isn't valid, it is insecure", name)
+		}
+		if !secure[value] {
+			return fmt.Errorf(
+				"cipher suite 0x%04x isn't valid, it isn't supported",
+				value,
+			)
+		}
+	}
+	return nil
+}
+
 func (b *ClientSelectorBuilder) loadTrustedCAs(ctx context.Context) (result *x509.CertPool,
 	err error) {
 	result, err = loadSystemCAs()
@@ -326,6 +447,8 @@ func (s *ClientSelector) createTransport(ctx context.Context,
 		ServerName:         address.Host,
 		InsecureSkipVerify: s.insecure,
 		RootCAs:            s.trustedCAs,
+		MinVersion:         s.minTLSVersion,
+		CipherSuites:       s.cipherSuites,
 	}
 
 	// Create the transport:
@@ -336,26 +459,39 @@ func (s *ClientSelector) createTransport(ctx context.Context,
 			TLSClientConfig:    config,
 			Proxy:              http.ProxyFromEnvironment,
 			DisableKeepAlives:  s.disableKeepAlives,
-			DisableCompression: false,
+			DisableCompression: s.disableCompression,
+			MaxConnsPerHost:    s.maxConnsPerHost,
 			ForceAttemptHTTP2:  true,
 		}
 
 		// In order to use Unix sockets we need to explicitly set dialers that use `unix` as
 		// network and the socket file as address, otherwise the HTTP client will always use
-		// `tcp` as the network and the host name from the request as the address:
+		// `tcp` as the network and the host name from the request as the address. Note that
+		// the TLS connection is created on top of the dialed one, so that the dialed
+		// connection can be counted and the transport still gets a *tls.Conn.
 		if address.Network == UnixNetwork {
-			transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn,
-				error) {
+			dial := s.trackDial(func(ctx context.Context, _, _ string) (net.Conn, error) {
 				dialer := net.Dialer{}
 				return dialer.DialContext(ctx, UnixNetwork, address.Socket)
-			}
-			transport.DialTLSContext = func(ctx context.Context, _, _ string) (net.Conn,
-				error) {
-				dialer := tls.Dialer{
-					Config: config,
+			}, address.Socket)
+			transport.DialContext = dial
+			transport.DialTLSContext = func(ctx context.Context, network,
+				addr string) (net.Conn, error) {
+				conn, err := dial(ctx, network, addr)
+				if err != nil {
+					return nil, err
 				}
-				return dialer.DialContext(ctx, UnixNetwork, address.Socket)
+				tlsConn := tls.Client(conn, config)
+				err = tlsConn.HandshakeContext(ctx)
+				if err != nil {
+					conn.Close()
+					return nil, err
+				}
+				return tlsConn, nil
 			}
+		} else if s.connectionsGauge != nil {
+			dialer := &net.Dialer{}
+			transport.DialContext = s.trackDial(dialer.DialContext, "")
 		}
 
 		// Prepare the result:
@@ -364,19 +500,27 @@ func (s *ClientSelector) createTransport(ctx context.Context,
 		// In order to use h2c we need to tell the transport to allow the `http` scheme:
 		transport := &http2.Transport{
 			AllowHTTP:          true,
-			DisableCompression: false,
+			DisableCompression: s.disableCompression,
 		}
 
 		// We also need to ignore TLS configuration when dialing, and explicitly set the
 		// network and socket when using Unix sockets:
 		if address.Network == UnixNetwork {
-			transport.DialTLS = func(_, _ string, cfg *tls.Config) (net.Conn, error) {
+			dial := s.trackDial(func(_ context.Context, _, _ string) (net.Conn, error) {
 				return net.Dial(UnixNetwork, address.Socket)
+			}, address.Socket)
+			transport.DialTLS = func(network, addr string, cfg *tls.Config) (net.Conn,
+				error) {
+				return dial(context.Background(), network, addr)
 			}
 		} else {
-			transport.DialTLS = func(network, addr string, cfg *tls.Config) (net.Conn,
+			dial := s.trackDial(func(_ context.Context, network, addr string) (net.Conn,
 				error) {
 				return net.Dial(network, addr)
+			}, "")
+			transport.DialTLS = func(network, addr string, cfg *tls.Config) (net.Conn,
+				error) {
+				return dial(context.Background(), network, addr)
 			}
 		}
 
@@ -393,6 +537,15 @@ func (s *ClientSelector) createTransport(ctx context.Context,
 	return
 }
 
+// trackDial wraps the given dial function so that the opened connections are counted, if there is a
+// connections gauge. If there is no gauge it returns the dial function unchanged.
+func (s *ClientSelector) trackDial(dial DialFunc, label string) DialFunc {
+	if s.connectionsGauge == nil {
+		return dial
+	}
+	return trackDial(dial, s.connectionsGauge, label)
+}
+
 // TrustedCAs sets returns the certificate pool that contains the certificate authorities that are
 // trusted by the HTTP clients.
 func (s *ClientSelector) TrustedCAs() *x509.CertPool {
@@ -404,11 +557,39 @@ func (s *ClientSelector) Insecure() bool {
 	return s.insecure
 }
 
+// MinTLSVersion returns the minimum TLS version, or zero if the default of the Go TLS library is
+// used.
+func (s *ClientSelector) MinTLSVersion() uint16 {
+	return s.minTLSVersion
+}
+
+// CipherSuites returns the list of TLS cipher suites, or nil if the default of the Go TLS library
+// is used.
+func (s *ClientSelector) CipherSuites() []uint16 {
+	if s.cipherSuites == nil {
+		return nil
+	}
+	result := make([]uint16, len(s.cipherSuites))
+	copy(result, s.cipherSuites)
+	return result
+}
+
 // DisableKeepAlives retursnt the flag that indicates if HTTP keep alive is disabled.
 func (s *ClientSelector) DisableKeepAlives() bool {
 	return s.disableKeepAlives
 }
 
+// MaxConnsPerHost returns the maximum number of connections per host, or zero if there is no limit.
+func (s *ClientSelector) MaxConnsPerHost() int {
+	return s.maxConnsPerHost
+}
+
+// DisableCompression returns the flag that indicates if the transparent compression support of the
+// HTTP transports is disabled.
+func (s *ClientSelector) DisableCompression() bool {
+	return s.disableCompression
+}
+
 // Close closes all the connections used by all the clients created by the selector.
 func (s *ClientSelector) Close() error {
 	for _, client := range s.clientsTable {
@@ -18,6 +18,7 @@ package internal
 
 import (
 	"context"
+	"crypto/tls"
 
 	. "github.com/onsi/ginkgo/v2/dsl/core" // nolint
 	. "github.com/onsi/gomega"             // nolint
@@ -32,6 +33,86 @@ var _ = Describe("Create client selector", func() {
 		Expect(message).To(ContainSubstring("logger"))
 		Expect(message).To(ContainSubstring("mandatory"))
 	})
+
+	It("Uses the Go defaults when TLS options aren't set", func() {
+		selector, err := NewClientSelector().
+			Logger(logger).
+			Build(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err = selector.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+		Expect(selector.MinTLSVersion()).To(BeZero())
+		Expect(selector.CipherSuites()).To(BeNil())
+	})
+
+	It("Can be created with minimum TLS version and cipher suites", func() {
+		selector, err := NewClientSelector().
+			Logger(logger).
+			MinTLSVersion(tls.VersionTLS12).
+			CipherSuites(
+				tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+				tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			).
+			Build(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err = selector.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+		Expect(selector.MinTLSVersion()).To(BeNumerically("==", tls.VersionTLS12))
+		Expect(selector.CipherSuites()).To(ConsistOf(
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+		))
+	})
+
+	It("Can't be created with minimum TLS version less than 1.2", func() {
+		selector, err := NewClientSelector().
+			Logger(logger).
+			MinTLSVersion(tls.VersionTLS11).
+			Build(context.Background())
+		Expect(err).To(HaveOccurred())
+		Expect(selector).To(BeNil())
+		message := err.Error()
+		Expect(message).To(ContainSubstring("0x0302"))
+		Expect(message).To(ContainSubstring("TLS 1.2"))
+	})
+
+	It("Can't be created with an insecure cipher suite", func() {
+		selector, err := NewClientSelector().
+			Logger(logger).
+			CipherSuites(tls.TLS_RSA_WITH_RC4_128_SHA).
+			Build(context.Background())
+		Expect(err).To(HaveOccurred())
+		Expect(selector).To(BeNil())
+		message := err.Error()
+		Expect(message).To(ContainSubstring("TLS_RSA_WITH_RC4_128_SHA"))
+		Expect(message).To(ContainSubstring("insecure"))
+	})
+
+	It("Can't be created with negative maximum connections per host", func() {
+		selector, err := NewClientSelector().
+			Logger(logger).
+			MaxConnsPerHost(-1).
+			Build(context.Background())
+		Expect(err).To(HaveOccurred())
+		Expect(selector).To(BeNil())
+		Expect(err.Error()).To(ContainSubstring("-1"))
+	})
+
+	It("Can't be created with an unknown cipher suite", func() {
+		selector, err := NewClientSelector().
+			Logger(logger).
+			CipherSuites(0xffff).
+			Build(context.Background())
+		Expect(err).To(HaveOccurred())
+		Expect(selector).To(BeNil())
+		message := err.Error()
+		Expect(message).To(ContainSubstring("0xffff"))
+		Expect(message).To(ContainSubstring("supported"))
+	})
 })
 
 var _ = Describe("Select client", func() {
@@ -0,0 +1,181 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains functions that register Prometheus metrics.
+
+package internal
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// RegisterCounterVec registers the given counter. If an identical counter is already registered
+// then it returns that existing counter instead, so that multiple objects using the same subsystem
+// can share the same registry. If the registration fails for any other reason, for example
+// because there is already a metric with the same name but different labels, it returns an error
+// explaining it.
+func RegisterCounterVec(registerer prometheus.Registerer, name string,
+	collector *prometheus.CounterVec) (result *prometheus.CounterVec, err error) {
+	existing, err := register(registerer, name, collector)
+	if err != nil {
+		return
+	}
+	result, ok := existing.(*prometheus.CounterVec)
+	if !ok {
+		err = fmt.Errorf(
+			"can't register metric '%s' because it is already registered as a '%T' "+
+				"instead of a counter",
+			name, existing,
+		)
+	}
+	return
+}
+
+// RegisterHistogramVec registers the given histogram. See the RegisterCounterVec function for
+// details.
+func RegisterHistogramVec(registerer prometheus.Registerer, name string,
+	collector *prometheus.HistogramVec) (result *prometheus.HistogramVec, err error) {
+	existing, err := register(registerer, name, collector)
+	if err != nil {
+		return
+	}
+	result, ok := existing.(*prometheus.HistogramVec)
+	if !ok {
+		err = fmt.Errorf(
+			"can't register metric '%s' because it is already registered as a '%T' "+
+				"instead of a histogram",
+			name, existing,
+		)
+	}
+	return
+}
+
+// RegisterGaugeVec registers the given gauge. See the RegisterCounterVec function for details.
+func RegisterGaugeVec(registerer prometheus.Registerer, name string,
+	collector *prometheus.GaugeVec) (result *prometheus.GaugeVec, err error) {
+	existing, err := register(registerer, name, collector)
+	if err != nil {
+		return
+	}
+	result, ok := existing.(*prometheus.GaugeVec)
+	if !ok {
+		err = fmt.Errorf(
+			"can't register metric '%s' because it is already registered as a '%T' "+
+				"instead of a gauge",
+			name, existing,
+		)
+	}
+	return
+}
+
+// RegisterGaugeFunc registers the given gauge function. See the RegisterCounterVec function for
+// details.
+func RegisterGaugeFunc(registerer prometheus.Registerer, name string,
+	collector prometheus.GaugeFunc) (result prometheus.GaugeFunc, err error) {
+	existing, err := register(registerer, name, collector)
+	if err != nil {
+		return
+	}
+	result, ok := existing.(prometheus.GaugeFunc)
+	if !ok {
+		err = fmt.Errorf(
+			"can't register metric '%s' because it is already registered as a '%T' "+
+				"instead of a gauge",
+			name, existing,
+		)
+	}
+	return
+}
+
+// ExclusiveRegisterer wraps the given registerer so that registering a metric that is already
+// registered is an error, instead of returning the existing metric. This is intended for objects
+// that need to make sure that they don't share their metrics with other objects that use the same
+// subsystem, as that would silently mix the series of both.
+func ExclusiveRegisterer(registerer prometheus.Registerer) prometheus.Registerer {
+	return &exclusiveRegisterer{
+		Registerer: registerer,
+	}
+}
+
+// exclusiveRegisterer is the implementation of the registerer returned by the ExclusiveRegisterer
+// function.
+type exclusiveRegisterer struct {
+	prometheus.Registerer
+}
+
+// duplicateError is the error returned by the exclusive registerer when the metric is already
+// registered.
+type duplicateError struct {
+	cause error
+}
+
+// Error is the implementation of the error interface.
+func (e *duplicateError) Error() string {
+	return e.cause.Error()
+}
+
+// Unwrap returns the original error returned by the registry.
+func (e *duplicateError) Unwrap() error {
+	return e.cause
+}
+
+// Register is the implementation of the prometheus.Registerer interface.
+func (r *exclusiveRegisterer) Register(collector prometheus.Collector) error {
+	err := r.Registerer.Register(collector)
+	var registered prometheus.AlreadyRegisteredError
+	if errors.As(err, &registered) {
+		err = &duplicateError{
+			cause: err,
+		}
+	}
+	return err
+}
+
+// register registers the given collector, and returns either that collector or the one that was
+// already registered with the same description.
+func register(registerer prometheus.Registerer, name string,
+	collector prometheus.Collector) (result prometheus.Collector, err error) {
+	err = registerer.Register(collector)
+	if err == nil {
+		result = collector
+		return
+	}
+	var duplicate *duplicateError
+	if errors.As(err, &duplicate) {
+		err = fmt.Errorf(
+			"can't register metric '%s' because it is already registered, probably "+
+				"by another object that uses the same subsystem; use a different "+
+				"subsystem or registerer",
+			name,
+		)
+		return
+	}
+	var registered prometheus.AlreadyRegisteredError
+	if errors.As(err, &registered) {
+		result = registered.ExistingCollector
+		err = nil
+		return
+	}
+	err = fmt.Errorf(
+		"can't register metric '%s', there is probably already a metric with the same "+
+			"name but different labels, help or type: %w",
+		name, err,
+	)
+	return
+}
@@ -0,0 +1,131 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains tests for the functions that register metrics.
+
+package internal
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core" // nolint
+	. "github.com/onsi/gomega"             // nolint
+)
+
+var _ = Describe("Metrics registration", func() {
+	var registry *prometheus.Registry
+
+	BeforeEach(func() {
+		registry = prometheus.NewRegistry()
+	})
+
+	// MakeCounter creates a counter with the given label names.
+	var MakeCounter = func(labels ...string) *prometheus.CounterVec {
+		return prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Subsystem: "my",
+				Name:      "count",
+				Help:      "My count.",
+			},
+			labels,
+		)
+	}
+
+	It("Registers new metric", func() {
+		counter := MakeCounter("code")
+		result, err := RegisterCounterVec(registry, "my_count", counter)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(BeIdenticalTo(counter))
+	})
+
+	It("Reuses existing metric", func() {
+		first, err := RegisterCounterVec(registry, "my_count", MakeCounter("code"))
+		Expect(err).ToNot(HaveOccurred())
+		second, err := RegisterCounterVec(registry, "my_count", MakeCounter("code"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(second).To(BeIdenticalTo(first))
+	})
+
+	It("Rejects existing metric with exclusive registerer", func() {
+		_, err := RegisterCounterVec(registry, "my_count", MakeCounter("code"))
+		Expect(err).ToNot(HaveOccurred())
+		exclusive := ExclusiveRegisterer(registry)
+		result, err := RegisterCounterVec(exclusive, "my_count", MakeCounter("code"))
+		Expect(err).To(HaveOccurred())
+		Expect(result).To(BeNil())
+		message := err.Error()
+		Expect(message).To(ContainSubstring("'my_count'"))
+		Expect(message).To(ContainSubstring("already registered"))
+	})
+
+	It("Registers new metric with exclusive registerer", func() {
+		counter := MakeCounter("code")
+		exclusive := ExclusiveRegisterer(registry)
+		result, err := RegisterCounterVec(exclusive, "my_count", counter)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(BeIdenticalTo(counter))
+	})
+
+	It("Rejects metric with different labels", func() {
+		_, err := RegisterCounterVec(registry, "my_count", MakeCounter("code"))
+		Expect(err).ToNot(HaveOccurred())
+		result, err := RegisterCounterVec(registry, "my_count", MakeCounter("code", "path"))
+		Expect(err).To(HaveOccurred())
+		Expect(result).To(BeNil())
+		message := err.Error()
+		Expect(message).To(ContainSubstring("'my_count'"))
+		Expect(message).To(ContainSubstring("different labels"))
+	})
+
+	It("Rejects metric with different type", func() {
+		_, err := RegisterCounterVec(registry, "my_count", MakeCounter("code"))
+		Expect(err).ToNot(HaveOccurred())
+		gauge := prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Subsystem: "my",
+				Name:      "count",
+				Help:      "My count.",
+			},
+			[]string{"code"},
+		)
+		result, err := RegisterGaugeVec(registry, "my_count", gauge)
+		Expect(err).To(HaveOccurred())
+		Expect(result).To(BeNil())
+		message := err.Error()
+		Expect(message).To(ContainSubstring("'my_count'"))
+		Expect(message).To(ContainSubstring("instead of a gauge"))
+	})
+
+	It("Reuses existing gauge function", func() {
+		MakeGauge := func(value float64) prometheus.GaugeFunc {
+			return prometheus.NewGaugeFunc(
+				prometheus.GaugeOpts{
+					Subsystem: "my",
+					Name:      "gauge",
+					Help:      "My gauge.",
+				},
+				func() float64 {
+					return value
+				},
+			)
+		}
+		first, err := RegisterGaugeFunc(registry, "my_gauge", MakeGauge(1))
+		Expect(err).ToNot(HaveOccurred())
+		second, err := RegisterGaugeFunc(registry, "my_gauge", MakeGauge(2))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(second).To(BeIdenticalTo(first))
+	})
+})
@@ -0,0 +1,77 @@
+/*
+Copyright (c) 2021 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the implementation of the connections that update a gauge when they are
+// opened and closed.
+
+package internal
+
+import (
+	"context"
+	"net"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DialFunc is the type of the functions used by the transports to open connections.
+type DialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// trackDial wraps the given dial function so that the connections that it opens increment the
+// given gauge, and decrement it when they are closed. The label is the value of the `host` label
+// of the gauge, if it is empty the address passed to the dial function is used.
+func trackDial(dial DialFunc, gauge *prometheus.GaugeVec, label string) DialFunc {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		conn, err := dial(ctx, network, address)
+		if err != nil {
+			return nil, err
+		}
+		host := label
+		if host == "" {
+			host = address
+		}
+		gauge := gauge.With(map[string]string{
+			hostLabelName: host,
+		})
+		gauge.Inc()
+		return &trackedConn{
+			Conn:  conn,
+			gauge: gauge,
+		}, nil
+	}
+}
+
+// trackedConn is a connection that decrements a gauge when it is closed.
+type trackedConn struct {
+	net.Conn
+	gauge prometheus.Gauge
+	once  sync.Once
+}
+
+// Close is the implementation of the net.Conn interface. It decrements the gauge only the first
+// time that it is called.
+func (c *trackedConn) Close() error {
+	c.once.Do(c.gauge.Dec)
+	return c.Conn.Close()
+}
+
+// hostLabelName is the name of the label of the connections gauge that contains the host.
+const hostLabelName = "host"
+
+// ConnectionsGaugeLabels is the list of labels of the gauge that counts open connections.
+var ConnectionsGaugeLabels = []string{
+	hostLabelName,
+}
@@ -0,0 +1,94 @@
+/*
+Copyright (c) 2021 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains tests for the connections that update a gauge.
+
+package internal
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core" // nolint
+	. "github.com/onsi/gomega"             // nolint
+)
+
+var _ = Describe("Tracked connections", func() {
+	var gauge *prometheus.GaugeVec
+
+	BeforeEach(func() {
+		gauge = prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "open_connections",
+			},
+			ConnectionsGaugeLabels,
+		)
+	})
+
+	// pipeDial is a dial function that returns one side of an in-memory connection.
+	pipeDial := func(ctx context.Context, network, address string) (net.Conn, error) {
+		client, server := net.Pipe()
+		server.Close()
+		return client, nil
+	}
+
+	It("Counts open connections by address", func() {
+		dial := trackDial(pipeDial, gauge, "")
+		first, err := dial(context.Background(), "tcp", "my.server.com:443")
+		Expect(err).ToNot(HaveOccurred())
+		second, err := dial(context.Background(), "tcp", "my.server.com:443")
+		Expect(err).ToNot(HaveOccurred())
+		third, err := dial(context.Background(), "tcp", "your.server.com:443")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(testutil.ToFloat64(gauge.WithLabelValues("my.server.com:443"))).To(Equal(2.0))
+		Expect(testutil.ToFloat64(gauge.WithLabelValues("your.server.com:443"))).To(Equal(1.0))
+
+		// Close the connections, twice the first one, to check that it is decremented once:
+		first.Close()
+		first.Close()
+		Expect(testutil.ToFloat64(gauge.WithLabelValues("my.server.com:443"))).To(Equal(1.0))
+		second.Close()
+		third.Close()
+		Expect(testutil.ToFloat64(gauge.WithLabelValues("my.server.com:443"))).To(BeZero())
+		Expect(testutil.ToFloat64(gauge.WithLabelValues("your.server.com:443"))).To(BeZero())
+	})
+
+	It("Uses the given label instead of the address", func() {
+		dial := trackDial(pipeDial, gauge, "/my/socket")
+		conn, err := dial(context.Background(), "tcp", "ignored:80")
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.Close()
+		Expect(testutil.ToFloat64(gauge.WithLabelValues("/my/socket"))).To(Equal(1.0))
+	})
+
+	It("Doesn't count failed dials", func() {
+		failure := errors.New("my error")
+		dial := trackDial(
+			func(ctx context.Context, network, address string) (net.Conn, error) {
+				return nil, failure
+			},
+			gauge, "",
+		)
+		conn, err := dial(context.Background(), "tcp", "my.server.com:443")
+		Expect(err).To(MatchError(failure))
+		Expect(conn).To(BeNil())
+		Expect(testutil.CollectAndCount(gauge)).To(BeZero())
+	})
+})
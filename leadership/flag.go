@@ -27,6 +27,8 @@ import (
 	"github.com/openshift-online/ocm-sdk-go/database"
 	"github.com/openshift-online/ocm-sdk-go/logging"
 	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/openshift-online/ocm-sdk-go/internal"
 )
 
 // FlagBuilder contains the data and logic needed to build leadership flags.
@@ -222,15 +224,13 @@ func (b *FlagBuilder) Build(ctx context.Context) (result *Flag, err error) {
 			},
 			flagMetricsLabels,
 		)
-		err = b.metricsRegisterer.Register(stateMetric)
+		stateMetric, err = internal.RegisterGaugeVec(
+			b.metricsRegisterer,
+			b.metricsSubsystem+"_leadership_flag_state",
+			stateMetric,
+		)
 		if err != nil {
-			registered, ok := err.(prometheus.AlreadyRegisteredError)
-			if ok {
-				stateMetric = registered.ExistingCollector.(*prometheus.GaugeVec)
-				err = nil
-			} else {
-				return
-			}
+			return
 		}
 	}
 
@@ -0,0 +1,131 @@
+/*
+Copyright (c) 2018 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains tests for the limit of connections per host.
+
+package sdk
+
+import (
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/onsi/gomega/ghttp"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"             // nolint
+	. "github.com/onsi/gomega"                         // nolint
+	. "github.com/openshift-online/ocm-sdk-go/testing" // nolint
+)
+
+var _ = Describe("Maximum connections per host", func() {
+	var (
+		accessToken   string
+		apiServer     *ghttp.Server
+		metricsServer *MetricsServer
+	)
+
+	BeforeEach(func() {
+		// Create the token:
+		accessToken = MakeTokenString("Bearer", 5*time.Minute)
+
+		// Create the servers:
+		apiServer = MakeTCPServer()
+		metricsServer = NewMetricsServer()
+	})
+
+	AfterEach(func() {
+		// Stop the servers:
+		apiServer.Close()
+		metricsServer.Close()
+	})
+
+	It("Doesn't open more connections than the limit", func() {
+		// Configure the server so that it remembers the maximum number of requests that it
+		// processes at the same time:
+		var active, peak int32
+		handler := func(w http.ResponseWriter, r *http.Request) {
+			current := atomic.AddInt32(&active, 1)
+			defer atomic.AddInt32(&active, -1)
+			for {
+				previous := atomic.LoadInt32(&peak)
+				if current <= previous ||
+					atomic.CompareAndSwapInt32(&peak, previous, current) {
+					break
+				}
+			}
+			time.Sleep(50 * time.Millisecond)
+			RespondWithJSON(http.StatusOK, "{}")(w, r)
+		}
+		const count = 3
+		for i := 0; i < count; i++ {
+			apiServer.AppendHandlers(handler)
+		}
+
+		// Create the connection:
+		connection, err := NewConnectionBuilder().
+			Logger(logger).
+			Tokens(accessToken).
+			URL(apiServer.URL()).
+			MaxConnsPerHost(1).
+			MetricsSubsystem("my").
+			MetricsRegisterer(metricsServer.Registry()).
+			MetricsConnections(true).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err = connection.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+		Expect(connection.MaxConnsPerHost()).To(Equal(1))
+
+		// Send the requests at the same time:
+		var group sync.WaitGroup
+		group.Add(count)
+		for i := 0; i < count; i++ {
+			go func() {
+				defer GinkgoRecover()
+				defer group.Done()
+				_, err := connection.Get().
+					Path("/api/clusters_mgmt/v1/clusters").
+					Send()
+				Expect(err).ToNot(HaveOccurred())
+			}()
+		}
+		group.Wait()
+		Expect(atomic.LoadInt32(&peak)).To(BeNumerically("==", 1))
+
+		// Verify the metrics:
+		address, err := url.Parse(apiServer.URL())
+		Expect(err).ToNot(HaveOccurred())
+		metrics := metricsServer.Metrics()
+		Expect(metrics).To(MatchLine(
+			`^my_open_connections\{host="%s"\} 1$`, address.Host,
+		))
+	})
+
+	It("Can't be created with a negative limit", func() {
+		connection, err := NewConnectionBuilder().
+			Logger(logger).
+			Tokens(accessToken).
+			URL(apiServer.URL()).
+			MaxConnsPerHost(-1).
+			Build()
+		Expect(err).To(HaveOccurred())
+		Expect(connection).To(BeNil())
+	})
+})
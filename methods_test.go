@@ -32,6 +32,8 @@ import (
 	. "github.com/openshift-online/ocm-sdk-go/testing" // nolint
 
 	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	ocmerrors "github.com/openshift-online/ocm-sdk-go/errors"
+	"github.com/openshift-online/ocm-sdk-go/search"
 )
 
 var _ = Describe("Methods", func() {
@@ -530,6 +532,321 @@ var _ = Describe("Methods", func() {
 			Expect(message).NotTo(ContainSubstring("..."))
 		})
 	})
+
+	Describe("Generated list", func() {
+		It("Sends arbitrary query parameter", func() {
+			// Configure the server:
+			apiServer.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyFormKV("myparameter", "myvalue"),
+					ghttp.VerifyFormKV("size", "10"),
+					RespondWithJSON(http.StatusOK, "{}"),
+				),
+			)
+
+			// Send the request:
+			_, err := connection.ClustersMgmt().V1().Clusters().List().
+				Parameter("myparameter", "myvalue").
+				Size(10).
+				Send()
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("Sends all the values of repeated parameters", func() {
+			// Configure the server:
+			apiServer.AppendHandlers(
+				ghttp.CombineHandlers(
+					func(w http.ResponseWriter, r *http.Request) {
+						query := r.URL.Query()
+						Expect(query["search"]).To(Equal([]string{
+							"name = 'a'",
+							"name = 'b'",
+						}))
+					},
+					RespondWithJSON(http.StatusOK, "{}"),
+				),
+			)
+
+			// Send the request:
+			_, err := connection.ClustersMgmt().V1().Clusters().List().
+				Parameter("search", "name = 'a'").
+				Parameter("search", "name = 'b'").
+				Send()
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+	Describe("Generated get", func() {
+		It("Sends entity tag in the If-None-Match header", func() {
+			// Configure the server:
+			apiServer.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyHeaderKV("If-None-Match", `"456"`),
+					RespondWithJSON(http.StatusOK, `{
+						"id": "123"
+					}`),
+				),
+			)
+
+			// Send the request:
+			response, err := connection.ClustersMgmt().V1().Clusters().Cluster("123").Get().
+				Header(IfNoneMatchHeader, `"456"`).
+				Send()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(NotModified(response)).To(BeFalse())
+			Expect(response.Body().ID()).To(Equal("123"))
+		})
+
+		It("Returns not modified without body", func() {
+			// Configure the server:
+			apiServer.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyHeaderKV("If-None-Match", `"123"`),
+					ghttp.RespondWith(http.StatusNotModified, nil, http.Header{
+						"ETag": []string{`"123"`},
+					}),
+				),
+			)
+
+			// Send the request:
+			response, err := connection.ClustersMgmt().V1().Clusters().Cluster("123").Get().
+				Header(IfNoneMatchHeader, `"123"`).
+				Send()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.Status()).To(Equal(http.StatusNotModified))
+			Expect(NotModified(response)).To(BeTrue())
+			Expect(response.Header().Get("ETag")).To(Equal(`"123"`))
+			Expect(response.Body()).To(BeNil())
+			Expect(response.Error()).To(BeNil())
+		})
+	})
+
+	Describe("Generic get", func() {
+		It("Returns not modified", func() {
+			// Configure the server:
+			apiServer.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyHeaderKV("If-None-Match", `"456"`),
+					ghttp.RespondWith(http.StatusNotModified, nil),
+				),
+			)
+
+			// Send the request:
+			response, err := connection.Get().
+				Path("/api/clusters_mgmt/v1/clusters/123").
+				IfNoneMatch(`"123"`).
+				IfNoneMatch(`"456"`).
+				Send()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.NotModified()).To(BeTrue())
+			Expect(NotModified(response)).To(BeTrue())
+			Expect(response.Bytes()).To(BeEmpty())
+		})
+	})
+
+	Describe("Generic list", func() {
+		It("Sends search expression", func() {
+			// Configure the server:
+			apiServer.AppendHandlers(
+				ghttp.CombineHandlers(
+					func(w http.ResponseWriter, r *http.Request) {
+						query := r.URL.Query()
+						Expect(query["search"]).To(ConsistOf("(multi_az = true and name = 'my')"))
+					},
+					RespondWithJSON(http.StatusOK, "{}"),
+				),
+			)
+
+			// Send the request:
+			expr := search.AllEqual(map[string]interface{}{
+				"name":     "my",
+				"multi_az": true,
+			})
+			_, err := connection.Get().
+				Path("/api/clusters_mgmt/v1/clusters").
+				Parameter("search", "name = 'other'").
+				SearchExpr(expr).
+				Send()
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("Removes search parameter if expression is nil", func() {
+			// Configure the server:
+			apiServer.AppendHandlers(
+				ghttp.CombineHandlers(
+					func(w http.ResponseWriter, r *http.Request) {
+						Expect(r.URL.Query()).ToNot(HaveKey("search"))
+					},
+					RespondWithJSON(http.StatusOK, "{}"),
+				),
+			)
+
+			// Send the request:
+			_, err := connection.Get().
+				Path("/api/clusters_mgmt/v1/clusters").
+				Parameter("search", "name = 'other'").
+				SearchExpr(search.AllEqual(nil)).
+				Send()
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+
+	Describe("Malformed error response", func() {
+		It("Preserves status when content type isn't JSON", func() {
+			// Configure the server:
+			apiServer.AppendHandlers(
+				RespondWithContent(http.StatusBadGateway, "text/html", gatewayError),
+			)
+
+			// Send the request:
+			_, err := connection.ClustersMgmt().V1().Clusters().Cluster("123").Get().
+				Send()
+			Expect(err).To(HaveOccurred())
+			var ocmErr *ocmerrors.Error
+			Expect(errors.As(err, &ocmErr)).To(BeTrue())
+			Expect(ocmErr.Status()).To(Equal(http.StatusBadGateway))
+			Expect(ocmErr.Reason()).To(ContainSubstring("Application is not available"))
+		})
+
+		It("Preserves status when body isn't valid JSON", func() {
+			// Configure the server:
+			apiServer.AppendHandlers(
+				RespondWithJSON(http.StatusServiceUnavailable, `Service not available`),
+			)
+
+			// Send the request:
+			response, err := connection.ClustersMgmt().V1().Clusters().Cluster("123").Get().
+				Send()
+			Expect(err).To(HaveOccurred())
+			var ocmErr *ocmerrors.Error
+			Expect(errors.As(err, &ocmErr)).To(BeTrue())
+			Expect(ocmErr.Status()).To(Equal(http.StatusServiceUnavailable))
+			Expect(ocmErr.Reason()).To(Equal("Service not available"))
+			Expect(response.Status()).To(Equal(http.StatusServiceUnavailable))
+			Expect(response.Error()).To(Equal(ocmErr))
+		})
+	})
+
+	Describe("Rate limit response", func() {
+		It("Returns the response with the rate limit headers", func() {
+			// Configure the server:
+			apiServer.AppendHandlers(
+				ghttp.RespondWith(
+					http.StatusTooManyRequests,
+					`{
+						"kind": "Error",
+						"id": "429",
+						"reason": "Too many requests"
+					}`,
+					http.Header{
+						"Content-Type":          []string{"application/json"},
+						"Retry-After":           []string{"30"},
+						"X-Ratelimit-Limit":     []string{"100"},
+						"X-Ratelimit-Remaining": []string{"0"},
+					},
+				),
+			)
+
+			// Send the request:
+			response, err := connection.ClustersMgmt().V1().Clusters().Cluster("123").Get().
+				Send()
+			Expect(err).To(HaveOccurred())
+			Expect(response).ToNot(BeNil())
+			Expect(response.Status()).To(Equal(http.StatusTooManyRequests))
+			Expect(response.Error()).ToNot(BeNil())
+			Expect(response.Error().Reason()).To(Equal("Too many requests"))
+			info := ocmerrors.ParseRateLimit(response.Header())
+			Expect(info).ToNot(BeNil())
+			Expect(info.HasLimit).To(BeTrue())
+			Expect(info.Limit).To(Equal(100))
+			Expect(info.HasRemaining).To(BeTrue())
+			Expect(info.Remaining).To(BeZero())
+			Expect(info.HasReset).To(BeTrue())
+			Expect(info.RetryIn()).To(BeNumerically("~", 30*time.Second, 5*time.Second))
+		})
+
+		It("Adds rate limit information to the error if body isn't JSON", func() {
+			// Configure the server:
+			apiServer.AppendHandlers(
+				ghttp.RespondWith(
+					http.StatusTooManyRequests,
+					"Too many requests",
+					http.Header{
+						"Content-Type": []string{"text/plain"},
+						"Retry-After":  []string{"30"},
+					},
+				),
+			)
+
+			// Send the request:
+			_, err := connection.ClustersMgmt().V1().Clusters().Cluster("123").Get().
+				Send()
+			Expect(err).To(HaveOccurred())
+			var ocmErr *ocmerrors.Error
+			Expect(errors.As(err, &ocmErr)).To(BeTrue())
+			Expect(ocmErr.Status()).To(Equal(http.StatusTooManyRequests))
+			info := ocmErr.RateLimit()
+			Expect(info).ToNot(BeNil())
+			Expect(info.HasReset).To(BeTrue())
+			Expect(info.RetryIn()).To(BeNumerically("~", 30*time.Second, 5*time.Second))
+		})
+
+		It("Returns the response of other errors with rate limit headers", func() {
+			// Configure the server:
+			apiServer.AppendHandlers(
+				ghttp.RespondWith(
+					http.StatusNotFound,
+					`{
+						"kind": "Error",
+						"id": "404",
+						"code": "CLUSTERS-MGMT-404",
+						"reason": "Cluster not found"
+					}`,
+					http.Header{
+						"Content-Type":          []string{"application/json"},
+						"X-Ratelimit-Remaining": []string{"99"},
+					},
+				),
+			)
+
+			// Send the request:
+			response, err := connection.ClustersMgmt().V1().Clusters().Cluster("123").Get().
+				Send()
+			Expect(err).To(HaveOccurred())
+			Expect(response).ToNot(BeNil())
+			Expect(response.Status()).To(Equal(http.StatusNotFound))
+			var ocmErr *ocmerrors.Error
+			Expect(errors.As(err, &ocmErr)).To(BeTrue())
+			Expect(ocmErr.Code()).To(Equal("CLUSTERS-MGMT-404"))
+			Expect(response.Error()).To(Equal(ocmErr))
+			Expect(ocmErr.RateLimit()).To(BeNil())
+		})
+
+		It("Returns the response of generic requests", func() {
+			// Configure the server:
+			apiServer.AppendHandlers(
+				ghttp.RespondWith(
+					http.StatusTooManyRequests,
+					`{
+						"kind": "Error",
+						"id": "429",
+						"reason": "Too many requests"
+					}`,
+					http.Header{
+						"Content-Type": []string{"application/json"},
+						"Retry-After":  []string{"30"},
+					},
+				),
+			)
+
+			// Send the request:
+			response, err := connection.Get().
+				Path("/api/clusters_mgmt/v1/clusters/123").
+				Send()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.Status()).To(Equal(http.StatusTooManyRequests))
+			Expect(response.String()).To(ContainSubstring("Too many requests"))
+		})
+	})
 })
 
 const gatewayError = `
@@ -0,0 +1,164 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the object that accumulates observations in memory and adds them to the
+// Prometheus collectors periodically.
+
+package metrics
+
+import (
+	"hash/maphash"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// batcher accumulates the observations of the request count and duration metrics in memory and
+// adds them to the collectors periodically. The series are distributed among several shards
+// according to the hash of their label values, each shard protected by its own lock. All the
+// observations of a series go to the same shard, so concurrent requests for different series
+// rarely compete for the same lock, but concurrent requests for the same series always do.
+type batcher struct {
+	names    []string
+	count    *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	shards   []*batchShard
+	seed     maphash.Seed
+	stop     chan struct{}
+	done     chan struct{}
+	once     sync.Once
+}
+
+// batchShard contains the observations accumulated by one of the shards of the batcher, indexed by
+// the values of the labels.
+type batchShard struct {
+	lock   sync.Mutex
+	series map[string]*batchSeries
+}
+
+// batchSeries contains the observations accumulated for one combination of label values.
+type batchSeries struct {
+	labels    prometheus.Labels
+	durations []float64
+}
+
+// newBatcher creates a batcher for the given metrics and starts the goroutine that flushes it with
+// the given interval. The names are the names of the labels of the metrics.
+func newBatcher(names []string, count *prometheus.CounterVec, duration *prometheus.HistogramVec,
+	interval time.Duration) *batcher {
+	shards := make([]*batchShard, runtime.GOMAXPROCS(0))
+	for i := range shards {
+		shards[i] = &batchShard{
+			series: map[string]*batchSeries{},
+		}
+	}
+	result := &batcher{
+		names:    names,
+		count:    count,
+		duration: duration,
+		shards:   shards,
+		seed:     maphash.MakeSeed(),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go result.run(interval)
+	return result
+}
+
+// run flushes the batcher periodically till it is closed, and then flushes it one last time.
+func (b *batcher) run(interval time.Duration) {
+	defer close(b.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			b.flush()
+		case <-b.stop:
+			b.flush()
+			return
+		}
+	}
+}
+
+// observe records a request with the given labels and duration. The labels are retained till the
+// next flush, so they must not be modified after calling this.
+func (b *batcher) observe(labels prometheus.Labels, duration float64) {
+	key := b.key(labels)
+	shard := b.shards[maphash.String(b.seed, key)%uint64(len(b.shards))]
+	shard.lock.Lock()
+	series, ok := shard.series[key]
+	if !ok {
+		series = &batchSeries{
+			labels: labels,
+		}
+		shard.series[key] = series
+	}
+	series.durations = append(series.durations, duration)
+	shard.lock.Unlock()
+}
+
+// flush adds the accumulated observations to the collectors. Note that the lock of each shard is
+// only held while replacing its map, so requests aren't blocked while the collectors are updated.
+func (b *batcher) flush() {
+	for _, shard := range b.shards {
+		shard.lock.Lock()
+		pending := shard.series
+		shard.series = map[string]*batchSeries{}
+		shard.lock.Unlock()
+		for _, series := range pending {
+			b.count.With(series.labels).Add(float64(len(series.durations)))
+			histogram := b.duration.With(series.labels)
+			for _, duration := range series.durations {
+				histogram.Observe(duration)
+			}
+		}
+	}
+}
+
+// discard forgets the accumulated observations without adding them to the collectors.
+func (b *batcher) discard() {
+	for _, shard := range b.shards {
+		shard.lock.Lock()
+		shard.series = map[string]*batchSeries{}
+		shard.lock.Unlock()
+	}
+}
+
+// close stops the goroutine that flushes the batcher, and waits till the last flush is completed.
+// It is safe to call this multiple times.
+func (b *batcher) close() {
+	b.once.Do(func() {
+		close(b.stop)
+	})
+	<-b.done
+}
+
+// key calculates the key used to index the series of the given labels, joining the values in the
+// order of the label names.
+func (b *batcher) key(labels prometheus.Labels) string {
+	var buffer strings.Builder
+	for i, name := range b.names {
+		if i > 0 {
+			buffer.WriteByte(0xff)
+		}
+		buffer.WriteString(labels[name])
+	}
+	return buffer.String()
+}
@@ -0,0 +1,118 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains benchmarks that compare the direct and the batched modes of the transport
+// wrapper when many goroutines send requests concurrently. To run them:
+//
+//	go test ./metrics -run '^$' -bench RoundTrip -cpu 1,8,32
+
+package metrics
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func BenchmarkRoundTripDirect(b *testing.B) {
+	benchmarkRoundTrip(b, 0, 1)
+}
+
+func BenchmarkRoundTripBatched(b *testing.B) {
+	benchmarkRoundTrip(b, time.Second, 1)
+}
+
+func BenchmarkRoundTripDirectSeries(b *testing.B) {
+	benchmarkRoundTrip(b, 0, len(benchmarkPaths))
+}
+
+func BenchmarkRoundTripBatchedSeries(b *testing.B) {
+	benchmarkRoundTrip(b, time.Second, len(benchmarkPaths))
+}
+
+// benchmarkPaths are the paths of the requests sent by the benchmarks. Each generates a different
+// series.
+var benchmarkPaths = []string{
+	"/api/clusters_mgmt/v1/clusters",
+	"/api/clusters_mgmt/v1/versions",
+	"/api/clusters_mgmt/v1/cloud_providers",
+	"/api/clusters_mgmt/v1/flavours",
+	"/api/accounts_mgmt/v1/accounts",
+	"/api/accounts_mgmt/v1/organizations",
+	"/api/accounts_mgmt/v1/subscriptions",
+	"/api/accounts_mgmt/v1/current_account",
+}
+
+// benchmarkRoundTrip measures the round trips of a transport wrapper created with the given flush
+// interval. Each goroutine sends all its requests with the same labels, chosen from the given
+// number of series. When there is only one series all the goroutines use the same labels, which is
+// the worst case for contention.
+func benchmarkRoundTrip(b *testing.B, interval time.Duration, series int) {
+	wrapper, err := NewTransportWrapper().
+		Subsystem("my").
+		Registerer(prometheus.NewRegistry()).
+		FlushInterval(interval).
+		Build()
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer func() {
+		err := wrapper.Close()
+		if err != nil {
+			b.Fatal(err)
+		}
+	}()
+	transport := wrapper.Wrap(benchmarkTransport{})
+	requests := make([]*http.Request, series)
+	for i := range requests {
+		requests[i], err = http.NewRequest(
+			http.MethodGet,
+			"http://api.example.com"+benchmarkPaths[i],
+			nil,
+		)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	var next uint64
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		request := requests[atomic.AddUint64(&next, 1)%uint64(series)]
+		for pb.Next() {
+			_, err := transport.RoundTrip(request)
+			if err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+// benchmarkTransport is a transport that returns an empty response without doing anything else, so
+// that the benchmarks measure only the cost of the metrics.
+type benchmarkTransport struct{}
+
+// RoundTrip is the implementation of the http.RoundTripper interface.
+func (benchmarkTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       http.NoBody,
+	}, nil
+}
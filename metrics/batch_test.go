@@ -0,0 +1,167 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains tests for the mode that accumulates observations in memory.
+
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core" // nolint
+	. "github.com/onsi/gomega"             // nolint
+
+	. "github.com/openshift-online/ocm-sdk-go/testing" // nolint
+)
+
+var _ = Describe("Flush interval", func() {
+	var (
+		registry *prometheus.Registry
+		wrapper  *TransportWrapper
+		client   *http.Client
+	)
+
+	// Build creates the wrapper and the client with the given flush interval.
+	var Build = func(interval time.Duration) {
+		var err error
+		registry = prometheus.NewRegistry()
+		wrapper, err = NewTransportWrapper().
+			Subsystem("my").
+			Registerer(registry).
+			FlushInterval(interval).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		client = &http.Client{
+			Transport: wrapper.Wrap(TextTransport(http.StatusOK, "")),
+		}
+	}
+
+	// Send sends a request to the given path.
+	var Send = func(path string) {
+		response, err := client.Get("http://api.example.com" + path)
+		Expect(err).ToNot(HaveOccurred())
+		err = response.Body.Close()
+		Expect(err).ToNot(HaveOccurred())
+	}
+
+	// Gather returns the value of the request count metric and the number of observations of the
+	// request duration metric, added for all the series.
+	var Gather = func() (count float64, durations uint64) {
+		families, err := registry.Gather()
+		Expect(err).ToNot(HaveOccurred())
+		for _, family := range families {
+			for _, metric := range family.GetMetric() {
+				switch family.GetName() {
+				case "my_request_count":
+					count += metric.GetCounter().GetValue()
+				case "my_request_duration":
+					durations += metric.GetHistogram().GetSampleCount()
+				}
+			}
+		}
+		return
+	}
+
+	AfterEach(func() {
+		err := wrapper.Close()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Adds observations directly by default", func() {
+		Build(0)
+		Send("/api/clusters_mgmt/v1/clusters")
+		count, durations := Gather()
+		Expect(count).To(Equal(1.0))
+		Expect(durations).To(BeEquivalentTo(1))
+	})
+
+	It("Doesn't add observations before the flush", func() {
+		Build(time.Hour)
+		Send("/api/clusters_mgmt/v1/clusters")
+		count, durations := Gather()
+		Expect(count).To(BeZero())
+		Expect(durations).To(BeZero())
+	})
+
+	It("Adds observations when flushed explicitly", func() {
+		Build(time.Hour)
+		Send("/api/clusters_mgmt/v1/clusters")
+		Send("/api/clusters_mgmt/v1/clusters")
+		Send("/api/accounts_mgmt/v1/accounts")
+		wrapper.Flush()
+		count, durations := Gather()
+		Expect(count).To(Equal(3.0))
+		Expect(durations).To(BeEquivalentTo(3))
+		snapshot, err := wrapper.Snapshot(map[string]string{
+			"path": "/api/clusters_mgmt/v1/clusters",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(snapshot.Count).To(Equal(2.0))
+		Expect(snapshot.DurationCount).To(BeEquivalentTo(2))
+	})
+
+	It("Adds observations periodically", func() {
+		Build(10 * time.Millisecond)
+		Send("/api/clusters_mgmt/v1/clusters")
+		Eventually(func() float64 {
+			count, _ := Gather()
+			return count
+		}).Should(Equal(1.0))
+	})
+
+	It("Adds pending observations when closed", func() {
+		Build(time.Hour)
+		Send("/api/clusters_mgmt/v1/clusters")
+		err := wrapper.Close()
+		Expect(err).ToNot(HaveOccurred())
+		count, durations := Gather()
+		Expect(count).To(Equal(1.0))
+		Expect(durations).To(BeEquivalentTo(1))
+	})
+
+	It("Includes pending observations in snapshots", func() {
+		Build(time.Hour)
+		Send("/api/clusters_mgmt/v1/clusters")
+		snapshot, err := wrapper.Snapshot(nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(snapshot.Count).To(Equal(1.0))
+	})
+
+	It("Discards pending observations on reset", func() {
+		Build(time.Hour)
+		Send("/api/clusters_mgmt/v1/clusters")
+		wrapper.Reset()
+		wrapper.Flush()
+		count, durations := Gather()
+		Expect(count).To(BeZero())
+		Expect(durations).To(BeZero())
+	})
+
+	It("Rejects negative interval", func() {
+		Build(0)
+		result, err := NewTransportWrapper().
+			Subsystem("other").
+			Registerer(registry).
+			FlushInterval(-time.Second).
+			Build()
+		Expect(err).To(HaveOccurred())
+		Expect(result).To(BeNil())
+		Expect(err.Error()).To(ContainSubstring("flush interval"))
+	})
+})
@@ -0,0 +1,196 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the implementation of a transport wrapper that reports the duration of
+// requests calling a function, without depending on Prometheus.
+
+package callback
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/openshift-online/ocm-sdk-go/clock"
+	"github.com/openshift-online/ocm-sdk-go/metrics/core"
+)
+
+// Observation contains the details of a request that has been sent.
+type Observation struct {
+	// Service is the name of the API service, for example `ocm-clusters-service`.
+	Service string
+
+	// Method is the HTTP method, for example GET or POST.
+	Method string
+
+	// Path is the request path with the identifiers of the objects replaced by dashes, for
+	// example /api/clusters_mgmt/v1/clusters/-.
+	Path string
+
+	// Code is the HTTP response code. It will be zero when sending the request failed without
+	// a response.
+	Code int
+
+	// Error is the error returned when sending the request failed.
+	Error error
+
+	// Duration is the time from the moment the request was sent till the headers of the
+	// response were received.
+	Duration time.Duration
+
+	// BodyDuration is the time from the moment the headers of the response were received till
+	// the body was closed.
+	BodyDuration time.Duration
+
+	// BodySize is the number of bytes of the response body that have been read.
+	BodySize int64
+}
+
+// Callback is the function that is called with the observation of each request.
+type Callback func(observation Observation)
+
+// TransportWrapperBuilder contains the data and logic needed to build a new transport wrapper that
+// calls a function with the details and durations of each request. This is intended for users
+// that want to feed those durations into their own monitoring system, and don't want to depend
+// on Prometheus. The paths of the requests are transformed in the same way that the metrics
+// package does for the `path` label, so that they can be used as keys with bounded cardinality.
+//
+// The function is called when the response body is closed, or right after sending the request if
+// there is no body, for example if sending the request failed. Note that it is called from the
+// goroutine that closes the body, so it should be fast and safe for concurrent use.
+//
+// Don't create objects of this type directly; use the NewTransportWrapper function instead.
+type TransportWrapperBuilder struct {
+	paths    []string
+	callback Callback
+	clock    clock.Clock
+}
+
+// TransportWrapper contains the data and logic needed to wrap an HTTP round tripper with another
+// one that reports request durations.
+type TransportWrapper struct {
+	paths    core.PathTree
+	callback Callback
+	clock    clock.Clock
+}
+
+// roundTripper is a round tripper that reports request durations.
+type roundTripper struct {
+	owner     *TransportWrapper
+	transport http.RoundTripper
+}
+
+// Make sure that we implement the interface:
+var _ http.RoundTripper = (*roundTripper)(nil)
+
+// NewTransportWrapper creates a new builder that can then be used to configure and create a new
+// callback round tripper.
+func NewTransportWrapper() *TransportWrapperBuilder {
+	return &TransportWrapperBuilder{
+		clock: clock.Real,
+	}
+}
+
+// Path adds a path that will be accepted as a value for the Path field of the observations. By
+// default all the paths of the API are already added. Requests for other paths will have `/-`.
+func (b *TransportWrapperBuilder) Path(value string) *TransportWrapperBuilder {
+	b.paths = append(b.paths, value)
+	return b
+}
+
+// Callback sets the function that will be called for each request. This is mandatory.
+func (b *TransportWrapperBuilder) Callback(value Callback) *TransportWrapperBuilder {
+	b.callback = value
+	return b
+}
+
+// Clock sets the clock that will be used to measure the duration of requests. The default is to
+// use the real clock of the system. This is intended for unit tests.
+func (b *TransportWrapperBuilder) Clock(value clock.Clock) *TransportWrapperBuilder {
+	if value == nil {
+		value = clock.Real
+	}
+	b.clock = value
+	return b
+}
+
+// Build uses the information stored in the builder to create a new transport wrapper.
+func (b *TransportWrapperBuilder) Build() (result *TransportWrapper, err error) {
+	// Check parameters:
+	if b.callback == nil {
+		err = fmt.Errorf("callback is mandatory")
+		return
+	}
+
+	// Create the path tree:
+	paths := core.DefaultPaths()
+	for _, path := range b.paths {
+		paths.Add(path)
+	}
+
+	// Create and populate the object:
+	result = &TransportWrapper{
+		paths:    paths,
+		callback: b.callback,
+		clock:    b.clock,
+	}
+
+	return
+}
+
+// Wrap creates a new round tripper that wraps the given one and reports request durations.
+func (w *TransportWrapper) Wrap(transport http.RoundTripper) http.RoundTripper {
+	return &roundTripper{
+		owner:     w,
+		transport: transport,
+	}
+}
+
+// RoundTrip is the implementation of the round tripper interface.
+func (t *roundTripper) RoundTrip(request *http.Request) (response *http.Response, err error) {
+	// Measure the time that it takes to send the request and receive the response:
+	start := t.owner.clock.Now()
+	response, err = t.transport.RoundTrip(request)
+	elapsed := t.owner.clock.Since(start)
+
+	// Prepare the observation:
+	path := request.URL.Path
+	observation := Observation{
+		Service:  core.ServiceLabel(path),
+		Method:   core.MethodLabel(request.Method),
+		Path:     t.owner.paths.Label(path),
+		Error:    err,
+		Duration: elapsed,
+	}
+	if response != nil {
+		observation.Code = response.StatusCode
+	}
+
+	// If there is no body we can report immediately, otherwise we need to wait till it is
+	// closed:
+	if response == nil || response.Body == nil {
+		t.owner.callback(observation)
+		return
+	}
+	first := t.owner.clock.Now()
+	response.Body = core.WrapBody(response.Body, func(count int64) {
+		observation.BodyDuration = t.owner.clock.Since(first)
+		observation.BodySize = count
+		t.owner.callback(observation)
+	})
+
+	return
+}
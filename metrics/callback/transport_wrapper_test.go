@@ -0,0 +1,116 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains tests for the callback transport wrapper.
+
+package callback
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"             // nolint
+	. "github.com/onsi/gomega"                         // nolint
+	. "github.com/openshift-online/ocm-sdk-go/testing" // nolint
+)
+
+var _ = Describe("Creation", func() {
+	It("Can't be created without a callback", func() {
+		wrapper, err := NewTransportWrapper().
+			Build()
+		Expect(err).To(HaveOccurred())
+		Expect(wrapper).To(BeNil())
+		message := err.Error()
+		Expect(message).To(ContainSubstring("callback"))
+		Expect(message).To(ContainSubstring("mandatory"))
+	})
+})
+
+var _ = Describe("Observations", func() {
+	var clock *FakeClock
+	var observations []Observation
+
+	BeforeEach(func() {
+		clock = NewFakeClock(time.Now())
+		observations = nil
+	})
+
+	// Client creates a client that wraps the given transport, advancing the clock the given
+	// duration before returning the response.
+	var Client = func(transport http.RoundTripper, delay time.Duration) *http.Client {
+		wrapper, err := NewTransportWrapper().
+			Path("/my/path").
+			Callback(func(observation Observation) {
+				observations = append(observations, observation)
+			}).
+			Clock(clock).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		return &http.Client{
+			Transport: wrapper.Wrap(TransportFunc(
+				func(request *http.Request) (*http.Response, error) {
+					clock.Advance(delay)
+					return transport.RoundTrip(request)
+				},
+			)),
+		}
+	}
+
+	It("Reports request after the body is closed", func() {
+		client := Client(JSONTransport(http.StatusOK, `{ "id": "123" }`), 2*time.Second)
+		response, err := client.Get("http://api.example.com/api/clusters_mgmt/v1/clusters/123")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(observations).To(BeEmpty())
+		clock.Advance(time.Second)
+		_, err = io.ReadAll(response.Body)
+		Expect(err).ToNot(HaveOccurred())
+		err = response.Body.Close()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(observations).To(HaveLen(1))
+		observation := observations[0]
+		Expect(observation.Service).To(Equal("ocm-clusters-service"))
+		Expect(observation.Method).To(Equal(http.MethodGet))
+		Expect(observation.Path).To(Equal("/api/clusters_mgmt/v1/clusters/-"))
+		Expect(observation.Code).To(Equal(http.StatusOK))
+		Expect(observation.Error).ToNot(HaveOccurred())
+		Expect(observation.Duration).To(Equal(2 * time.Second))
+		Expect(observation.BodyDuration).To(Equal(time.Second))
+		Expect(observation.BodySize).To(BeNumerically("==", len(`{ "id": "123" }`)))
+	})
+
+	It("Accepts additional paths", func() {
+		client := Client(JSONTransport(http.StatusOK, `{}`), 0)
+		response, err := client.Get("http://api.example.com/my/path")
+		Expect(err).ToNot(HaveOccurred())
+		err = response.Body.Close()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(observations).To(HaveLen(1))
+		Expect(observations[0].Path).To(Equal("/my/path"))
+	})
+
+	It("Reports request that fails without response", func() {
+		client := Client(ErrorTransport(errors.New("connection refused")), time.Second)
+		_, err := client.Get("http://api.example.com/api/clusters_mgmt/v1/clusters")
+		Expect(err).To(HaveOccurred())
+		Expect(observations).To(HaveLen(1))
+		observation := observations[0]
+		Expect(observation.Code).To(BeZero())
+		Expect(observation.Error).To(HaveOccurred())
+		Expect(observation.Duration).To(Equal(time.Second))
+	})
+})
@@ -0,0 +1,124 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the function that calculates the package that sent a request.
+
+package metrics
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// UnknownCaller is the value of the `caller` label used when the calling package can't be found.
+const UnknownCaller = "unknown"
+
+// sdkPackage is the prefix of the packages of the SDK, which are never considered callers.
+const sdkPackage = "github.com/openshift-online/ocm-sdk-go"
+
+// callerMaxDepth is the maximum number of stack frames inspected to find the caller.
+const callerMaxDepth = 64
+
+// callerFrame contains the information about a stack frame that is needed to decide if it is the
+// caller.
+type callerFrame struct {
+	pkg    string
+	caller bool
+}
+
+// callerFrames caches the information about stack frames, indexed by program counter, so that
+// the relatively expensive symbol lookup is done only once for each call site.
+var callerFrames sync.Map
+
+// callerLabel calculates the `caller` label, walking the stack of the current goroutine to find
+// the first frame that isn't in the standard library or in the SDK. Frames in test files are
+// always considered callers, so that the tests of the SDK itself are attributed correctly.
+func callerLabel() string {
+	pcs := make([]uintptr, callerMaxDepth)
+	count := runtime.Callers(2, pcs)
+	for _, pc := range pcs[:count] {
+		frame := lookupCallerFrame(pc)
+		if frame.caller {
+			return frame.pkg
+		}
+	}
+	return UnknownCaller
+}
+
+// lookupCallerFrame returns the information for the frame with the given program counter, from
+// the cache if possible.
+func lookupCallerFrame(pc uintptr) callerFrame {
+	value, ok := callerFrames.Load(pc)
+	if ok {
+		return value.(callerFrame)
+	}
+	frame := resolveCallerFrame(pc)
+	callerFrames.Store(pc, frame)
+	return frame
+}
+
+// resolveCallerFrame calculates the information for the frame with the given program counter.
+func resolveCallerFrame(pc uintptr) callerFrame {
+	// Note that the program counters returned by runtime.Callers are return addresses, so we
+	// need to subtract one to get the address of the call instruction:
+	function := runtime.FuncForPC(pc - 1)
+	if function == nil {
+		return callerFrame{}
+	}
+	pkg := functionPackage(function.Name())
+	file, _ := function.FileLine(pc - 1)
+	caller := strings.HasSuffix(file, "_test.go")
+	if !caller {
+		caller = !isStandardPackage(pkg) && !isSDKPackage(pkg)
+	}
+	return callerFrame{
+		pkg:    pkg,
+		caller: caller,
+	}
+}
+
+// functionPackage extracts the package path from a fully qualified function name like
+// `github.com/my/module/pkg.(*Type).Method.func1`. Note that in these names the dots of the last
+// element of the package path are escaped as `%2e`, so they need to be restored.
+func functionPackage(name string) string {
+	slash := strings.LastIndex(name, "/")
+	dot := strings.Index(name[slash+1:], ".")
+	if dot >= 0 {
+		name = name[:slash+1+dot]
+	}
+	return strings.ReplaceAll(name, "%2e", ".")
+}
+
+// isStandardPackage checks if the given package is part of the Go standard library or of the
+// runtime. Those packages don't have a dot in the first element of the path, with the exception
+// of the main package, which is never part of the standard library.
+func isStandardPackage(pkg string) bool {
+	if pkg == "main" {
+		return false
+	}
+	first := pkg
+	slash := strings.Index(pkg, "/")
+	if slash >= 0 {
+		first = pkg[:slash]
+	}
+	return !strings.Contains(first, ".")
+}
+
+// isSDKPackage checks if the given package is part of the SDK.
+func isSDKPackage(pkg string) bool {
+	return pkg == sdkPackage || strings.HasPrefix(pkg, sdkPackage+"/")
+}
@@ -0,0 +1,79 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the types and functions used to classify requests.
+
+package metrics
+
+import (
+	"net/http"
+	"sync"
+)
+
+// Classifier is a function that calculates the value of the `class` label for a request. This is
+// intended for categories that aren't visible in the URL, for example to distinguish interactive
+// requests from batch requests.
+type Classifier func(request *http.Request) string
+
+// DefaultClassLimit is the default maximum number of distinct values of the `class` label.
+const DefaultClassLimit = 20
+
+// OtherClass is the value of the `class` label used for requests whose class would exceed the
+// maximum number of distinct values. The same value is used for the `organization` label.
+const OtherClass = "other"
+
+// DefaultOrganizationLimit is the default maximum number of distinct values of the `organization`
+// label.
+const DefaultOrganizationLimit = 100
+
+// classSet remembers the classes that have already been used, so that the number of distinct
+// values of the `class` label stays below the limit. It is also used for the `organization`
+// label.
+type classSet struct {
+	limit int
+	mutex sync.Mutex
+	seen  map[string]bool
+}
+
+// newClassSet creates a new set of classes with the given limit.
+func newClassSet(limit int) *classSet {
+	return &classSet{
+		limit: limit,
+		seen:  map[string]bool{},
+	}
+}
+
+// label returns the value of the `class` label for the given class. That will be the class itself
+// if it was already used or if the limit hasn't been reached yet, and OtherClass otherwise.
+func (s *classSet) label(class string) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.seen[class] {
+		return class
+	}
+	if len(s.seen) >= s.limit {
+		return OtherClass
+	}
+	s.seen[class] = true
+	return class
+}
+
+// reset forgets the classes that have been used, so that the limit applies again from scratch.
+func (s *classSet) reset() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.seen = map[string]bool{}
+}
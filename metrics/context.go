@@ -0,0 +1,95 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains functions that store and extract from the context the flag that disables
+// metrics for a request and the values of the labels chosen by the caller.
+
+package metrics
+
+import (
+	"context"
+)
+
+// WithoutMetrics creates a new context that tells the metrics wrappers to not record any metric
+// for the request that uses it. For example, to send a request that doesn't appear in the
+// metrics:
+//
+//	ctx = metrics.WithoutMetrics(ctx)
+//	response, err := connection.Get().Path("/api/clusters_mgmt/v1").SendContext(ctx)
+//
+// This is intended for requests like keep-alive pings that would otherwise pollute the metrics,
+// when the same path is also used by other requests that should be measured.
+func WithoutMetrics(parent context.Context) context.Context {
+	return context.WithValue(parent, disabledKeyValue, true)
+}
+
+// disabledFromContext returns true if metrics have been disabled in the context with the
+// WithoutMetrics function.
+func disabledFromContext(ctx context.Context) bool {
+	disabled, _ := ctx.Value(disabledKeyValue).(bool)
+	return disabled
+}
+
+// WithLabels creates a new context that contains values for labels that the metrics wrapper will
+// add to the metrics of the request that uses it. Only the labels whose names have been declared
+// with the ContextLabel or ContextLabels methods of the builder of the wrapper are used, the rest
+// are ignored, so that the caller can't increase the cardinality of the metrics. For example, if
+// the wrapper was created like this:
+//
+//	wrapper, err := metrics.NewTransportWrapper().
+//		Subsystem("my").
+//		ContextLabels("team", "job").
+//		Build()
+//
+// Then the metrics of this request will have the labels `team="infra"` and `job="sync"`:
+//
+//	ctx = metrics.WithLabels(ctx, map[string]string{
+//		"team": "infra",
+//		"job":  "sync",
+//	})
+//	response, err := connection.Get().Path("/api/clusters_mgmt/v1").SendContext(ctx)
+//
+// If the parent context already contains labels the result contains the union of both, with the
+// values given here taking precedence. The given map isn't modified or retained.
+func WithLabels(parent context.Context, labels map[string]string) context.Context {
+	merged := map[string]string{}
+	for name, value := range labelsFromContext(parent) {
+		merged[name] = value
+	}
+	for name, value := range labels {
+		merged[name] = value
+	}
+	return context.WithValue(parent, labelsKeyValue, merged)
+}
+
+// labelsFromContext returns the labels that have been stored in the context with the WithLabels
+// function, or nil if there are no such labels. The result must not be modified.
+func labelsFromContext(ctx context.Context) map[string]string {
+	labels, _ := ctx.Value(labelsKeyValue).(map[string]string)
+	return labels
+}
+
+// disabledKeyType is the type of the key used to store the disabled flag in the context.
+type disabledKeyType string
+
+// disabledKeyValue is the key used to store the disabled flag in the context:
+const disabledKeyValue disabledKeyType = "disabled"
+
+// labelsKeyType is the type of the key used to store the labels in the context.
+type labelsKeyType string
+
+// labelsKeyValue is the key used to store the labels in the context:
+const labelsKeyValue labelsKeyType = "labels"
@@ -0,0 +1,63 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the implementation of the response body wrapper that is used to generate
+// metrics about the consumption of the body.
+
+package core
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// bodyWrapper wraps the body of a response in order to count the bytes read and to call a function
+// when it is closed.
+type bodyWrapper struct {
+	body    io.ReadCloser
+	count   int64
+	once    sync.Once
+	onClose func(count int64)
+}
+
+// Make sure that we implement the interface:
+var _ io.ReadCloser = (*bodyWrapper)(nil)
+
+// WrapBody creates a new body that reads from the given one and that calls the given function the
+// first time that it is closed, passing the number of bytes that have been read.
+func WrapBody(body io.ReadCloser, onClose func(count int64)) io.ReadCloser {
+	return &bodyWrapper{
+		body:    body,
+		onClose: onClose,
+	}
+}
+
+// Read is the implementation of the io.Reader interface.
+func (b *bodyWrapper) Read(p []byte) (n int, err error) {
+	n, err = b.body.Read(p)
+	atomic.AddInt64(&b.count, int64(n))
+	return
+}
+
+// Close is the implementation of the io.Closer interface.
+func (b *bodyWrapper) Close() error {
+	err := b.body.Close()
+	b.once.Do(func() {
+		b.onClose(atomic.LoadInt64(&b.count))
+	})
+	return err
+}
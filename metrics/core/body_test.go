@@ -0,0 +1,59 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains tests for the response body wrapper.
+
+package core
+
+import (
+	"io"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core" // nolint
+	. "github.com/onsi/gomega"             // nolint
+)
+
+var _ = Describe("Body", func() {
+	It("Counts the bytes read", func() {
+		var count int64
+		calls := 0
+		body := WrapBody(io.NopCloser(strings.NewReader("0123456789")), func(n int64) {
+			count = n
+			calls++
+		})
+		data := make([]byte, 4)
+		_, err := io.ReadFull(body, data)
+		Expect(err).ToNot(HaveOccurred())
+		err = body.Close()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(calls).To(Equal(1))
+		Expect(count).To(BeNumerically("==", 4))
+	})
+
+	It("Calls the function only once", func() {
+		calls := 0
+		body := WrapBody(io.NopCloser(strings.NewReader("{}")), func(int64) {
+			calls++
+		})
+		_, err := io.ReadAll(body)
+		Expect(err).ToNot(HaveOccurred())
+		err = body.Close()
+		Expect(err).ToNot(HaveOccurred())
+		err = body.Close()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(calls).To(Equal(1))
+	})
+})
@@ -0,0 +1,57 @@
+/*
+Copyright (c) 2021 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains functions that calculate the labels included in metrics.
+
+package core
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ServiceLabel calculates the name of the API service, for example `ocm-clusters-service`, from the
+// given URL path. The result will be empty if the path isn't an API path.
+func ServiceLabel(path string) string {
+	if !strings.HasPrefix(path, "/api/") {
+		return ""
+	}
+	if strings.HasPrefix(path, "/api/accounts_mgmt") {
+		return "ocm-accounts-service"
+	} else if strings.HasPrefix(path, "/api/clusters_mgmt") {
+		return "ocm-clusters-service"
+	} else if strings.HasPrefix(path, "/api/authorizations") {
+		return "ocm-authorizations-service"
+	} else if strings.HasPrefix(path, "/api/service_logs") {
+		return "ocm-logs-service"
+	} else {
+		parts := strings.Split(path, "/")
+		if len(parts) > 3 {
+			return "ocm-" + parts[3]
+		}
+		return ""
+	}
+}
+
+// MethodLabel calculates the label for the given HTTP method.
+func MethodLabel(method string) string {
+	return strings.ToUpper(method)
+}
+
+// CodeLabel calculates the label for the given HTTP response code.
+func CodeLabel(code int) string {
+	return strconv.Itoa(code)
+}
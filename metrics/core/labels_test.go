@@ -0,0 +1,34 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains tests for the functions that calculate labels.
+
+package core
+
+import (
+	. "github.com/onsi/ginkgo/v2/dsl/table" // nolint
+	. "github.com/onsi/gomega"              // nolint
+)
+
+var _ = DescribeTable(
+	"Service label",
+	func(path string, expected string) {
+		Expect(ServiceLabel(path)).To(Equal(expected))
+	},
+	Entry("Accounts", "/api/accounts_mgmt/v1", "ocm-accounts-service"),
+	Entry("Clusters", "/api/clusters_mgmt/v1", "ocm-clusters-service"),
+	Entry("Not API", "/auth/realms", ""),
+)
@@ -17,15 +17,14 @@ limitations under the License.
 // This file contains the type that describes trees of URL paths used to translate request paths
 // into labes suitalbe for use as Prometheus labels.
 
-package metrics
+package core
 
 import (
+	"encoding/json"
 	"strings"
-
-	jsoniter "github.com/json-iterator/go"
 )
 
-// pathTree defines a tree of URL paths that will be used to transform request paths into labels
+// PathTree defines a tree of URL paths that will be used to transform request paths into labels
 // suitable for use in Prometheus metrics. For example, a server that has these URL paths:
 //
 //	/api
@@ -38,7 +37,7 @@ import (
 //
 // Will be described with a tree like this:
 //
-//	var pathRoot = pathTree{
+//	var paths = PathTree{
 //		"api": {
 //			"clusters_mgmt": {
 //				"v1": {
@@ -55,22 +54,28 @@ import (
 //	}
 //
 // Path variables are represented with a dash.
-type pathTree map[string]pathTree
+type PathTree map[string]PathTree
+
+// DefaultPaths returns a new tree containing the URL paths of the API. The result is a copy, so it
+// can be modified with the Add method without affecting other callers.
+func DefaultPaths() PathTree {
+	return pathRoot.Copy()
+}
 
-// copy creates a deep copy of this tree.
-func (t pathTree) copy() pathTree {
+// Copy creates a deep copy of this tree.
+func (t PathTree) Copy() PathTree {
 	if t == nil {
 		return nil
 	}
-	tree := pathTree{}
+	tree := PathTree{}
 	for label, child := range t {
-		tree[label] = child.copy()
+		tree[label] = child.Copy()
 	}
 	return tree
 }
 
-// add adds the given branch to this tree.
-func (t pathTree) add(path string) {
+// Add adds the given branch to this tree.
+func (t PathTree) Add(path string) {
 	path = t.clean(path)
 	if len(path) == 0 {
 		return
@@ -79,7 +84,7 @@ func (t pathTree) add(path string) {
 	t.addSegments(segments)
 }
 
-func (t pathTree) addSegments(segments []string) {
+func (t PathTree) addSegments(segments []string) {
 	if len(segments) == 0 {
 		return
 	}
@@ -88,14 +93,14 @@ func (t pathTree) addSegments(segments []string) {
 	next := t[head]
 	if next == nil {
 		if len(tail) > 0 {
-			next = pathTree{}
+			next = PathTree{}
 		}
 		t[head] = next
 	}
 	next.addSegments(tail)
 }
 
-func (t pathTree) clean(path string) string {
+func (t PathTree) clean(path string) string {
 	for len(path) > 0 && strings.HasPrefix(path, "/") {
 		path = path[1:]
 	}
@@ -105,11 +110,38 @@ func (t pathTree) clean(path string) string {
 	return path
 }
 
+// Label calculates the label for the given URL path, replacing the segments that correspond to path
+// variables with a dash. For example, if the path is /api/clusters_mgmt/v1/clusters/123 the result
+// will be /api/clusters_mgmt/v1/clusters/-. Paths that aren't in the tree are replaced by /-, so
+// that the number of distinct labels stays bounded.
+func (t PathTree) Label(path string) string {
+	// Clear segments that correspond to path variables:
+	segments := strings.Split(t.clean(path), "/")
+	current := t
+	for i, segment := range segments {
+		next, ok := current[segment]
+		if ok {
+			current = next
+			continue
+		}
+		next, ok = current["-"]
+		if ok {
+			segments[i] = "-"
+			current = next
+			continue
+		}
+		return "/-"
+	}
+
+	// Reconstruct the path joining the modified segments:
+	return "/" + strings.Join(segments, "/")
+}
+
 // pathRoot is the root of the URL path tree.
-var pathRoot pathTree
+var pathRoot PathTree
 
 func init() {
-	err := jsoniter.Unmarshal([]byte(pathTreeData), &pathRoot)
+	err := json.Unmarshal([]byte(pathTreeData), &pathRoot)
 	if err != nil {
 		panic(err)
 	}
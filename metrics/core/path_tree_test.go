@@ -16,7 +16,7 @@ limitations under the License.
 
 // This file contains tests for the URL path tree.
 
-package metrics
+package core
 
 import (
 	"encoding/json"
@@ -28,11 +28,11 @@ import (
 var _ = DescribeTable(
 	"Add",
 	func(original string, paths []string, expected string) {
-		var tree *pathTree
+		var tree *PathTree
 		err := json.Unmarshal([]byte(original), &tree)
 		Expect(err).ToNot(HaveOccurred())
 		for _, path := range paths {
-			tree.add(path)
+			tree.Add(path)
 		}
 		actual, err := json.Marshal(tree)
 		Expect(err).ToNot(HaveOccurred())
@@ -192,3 +192,37 @@ var _ = DescribeTable(
 		}`,
 	),
 )
+
+var _ = DescribeTable(
+	"Label",
+	func(path string, expected string) {
+		paths := DefaultPaths()
+		paths.Add("/my/custom/path")
+		Expect(paths.Label(path)).To(Equal(expected))
+	},
+	Entry(
+		"Collection",
+		"/api/clusters_mgmt/v1/clusters",
+		"/api/clusters_mgmt/v1/clusters",
+	),
+	Entry(
+		"Object",
+		"/api/clusters_mgmt/v1/clusters/123",
+		"/api/clusters_mgmt/v1/clusters/-",
+	),
+	Entry(
+		"Trailing slash",
+		"/api/clusters_mgmt/v1/clusters/123/",
+		"/api/clusters_mgmt/v1/clusters/-",
+	),
+	Entry(
+		"Custom path",
+		"/my/custom/path",
+		"/my/custom/path",
+	),
+	Entry(
+		"Unknown path",
+		"/junk",
+		"/-",
+	),
+)
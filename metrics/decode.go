@@ -0,0 +1,202 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the functions that count the errors decoding the bodies of responses.
+
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/openshift-online/ocm-sdk-go/helpers"
+)
+
+// Values of the `reason` label of the decode error metric:
+const (
+	// DecodeErrorEOF is used when the body ends before the complete value has been read.
+	DecodeErrorEOF = "eof"
+
+	// DecodeErrorSyntax is used when the body isn't valid JSON.
+	DecodeErrorSyntax = "syntax"
+
+	// DecodeErrorType is used when the body is valid JSON but a value doesn't have the type
+	// that the client expects, for example a string where a number is expected.
+	DecodeErrorType = "type"
+)
+
+// decodeCheckLimit is the maximum size of the response bodies that are checked. Bodies larger than
+// this aren't checked, to avoid keeping large amounts of data in memory.
+const decodeCheckLimit = 1 << 20
+
+// reportDecodeErrors returns a context derived from the given one that contains the reporter that
+// updates the decode error metric for requests to the given service. The returned function updates
+// the metric with the given reason, unless an error has already been reported for the request.
+func (w *TransportWrapper) reportDecodeErrors(ctx context.Context,
+	service string) (result context.Context, report func(reason string)) {
+	var reported int32
+	report = func(reason string) {
+		if !atomic.CompareAndSwapInt32(&reported, 0, 1) {
+			return
+		}
+		labels := prometheus.Labels{
+			serviceLabelName: service,
+			reasonLabelName:  reason,
+		}
+		labels = w.renames.labels(labels)
+		w.decodeErrors.With(labels).Inc()
+	}
+	result = helpers.ContextWithDecodeErrorReporter(ctx, func(err error) {
+		report(decodeErrorReason(err))
+	})
+	return
+}
+
+// checkBody wraps the body of the given response so that the decode error metric is updated when
+// the body is closed and it isn't valid JSON. Only successful responses with the JSON content type
+// are checked, other responses are returned unchanged.
+func (w *TransportWrapper) checkBody(response *http.Response, report func(reason string)) {
+	if response.StatusCode >= http.StatusBadRequest || response.Body == nil ||
+		response.Body == http.NoBody {
+		return
+	}
+	mediaType, _, err := mime.ParseMediaType(response.Header.Get("Content-Type"))
+	if err != nil || !strings.EqualFold(mediaType, "application/json") {
+		return
+	}
+	response.Body = &decodeBody{
+		body:   response.Body,
+		report: report,
+	}
+}
+
+// decodeBody is a response body that keeps a copy of the data read, so that it can check that it is
+// valid JSON when it is closed.
+type decodeBody struct {
+	body     io.ReadCloser
+	buffer   bytes.Buffer
+	failed   bool
+	overflow bool
+	once     sync.Once
+	report   func(reason string)
+}
+
+// Make sure that we implement the interface:
+var _ io.ReadCloser = (*decodeBody)(nil)
+
+// Read is the implementation of the io.Reader interface.
+func (b *decodeBody) Read(p []byte) (n int, err error) {
+	n, err = b.body.Read(p)
+	if !b.overflow {
+		if b.buffer.Len()+n > decodeCheckLimit {
+			b.overflow = true
+			b.buffer = bytes.Buffer{}
+		} else {
+			b.buffer.Write(p[:n])
+		}
+	}
+	if err != nil && err != io.EOF {
+		b.failed = true
+	}
+	return
+}
+
+// Close is the implementation of the io.Closer interface. It reads the part of the body that the
+// caller didn't read, as the decoder stops reading when it finds an error, and then checks it.
+func (b *decodeBody) Close() error {
+	b.once.Do(func() {
+		if !b.failed && !b.overflow {
+			limit := int64(decodeCheckLimit - b.buffer.Len() + 1)
+			_, err := io.Copy(io.Discard, io.LimitReader(b, limit))
+			if err != nil {
+				b.failed = true
+			}
+		}
+		b.check()
+	})
+	return b.body.Close()
+}
+
+// check updates the metric if the body couldn't be read completely or if it isn't valid JSON. Empty
+// bodies aren't counted because the clients accept them.
+func (b *decodeBody) check() {
+	if b.failed {
+		b.report(DecodeErrorEOF)
+		return
+	}
+	if b.overflow {
+		return
+	}
+	data := b.buffer.Bytes()
+	if len(bytes.TrimSpace(data)) == 0 {
+		return
+	}
+	var value json.RawMessage
+	err := json.NewDecoder(bytes.NewReader(data)).Decode(&value)
+	switch {
+	case err == nil:
+		return
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		b.report(DecodeErrorEOF)
+	default:
+		b.report(DecodeErrorSyntax)
+	}
+}
+
+// decodeErrorReason calculates the `reason` label from the error returned by the JSON decoder.
+// The decoder doesn't return typed errors, so this is based on the text of the messages, like
+// `ReadString: expects " or n, but found 1`.
+func decodeErrorReason(err error) string {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return DecodeErrorEOF
+	}
+	message := err.Error()
+	if strings.Contains(message, "unexpected end of input") {
+		return DecodeErrorEOF
+	}
+	index := strings.Index(message, "but found ")
+	if index == -1 {
+		if strings.Contains(message, "unexpected character") {
+			return DecodeErrorType
+		}
+		return DecodeErrorSyntax
+	}
+	found := message[index+len("but found "):]
+	switch {
+	case found == "" || found[0] == 0:
+		return DecodeErrorEOF
+	case strings.ContainsRune(valueStarts, rune(found[0])) &&
+		strings.Contains(message[:index], "or n,"):
+		// The decoder was expecting a value, which may also be null, and found the start of
+		// a value of a different type:
+		return DecodeErrorType
+	default:
+		return DecodeErrorSyntax
+	}
+}
+
+// valueStarts contains the characters that can start a JSON value.
+const valueStarts = "\"{[-0123456789tfn"
@@ -0,0 +1,103 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the functions that measure the DNS lookups done to send requests.
+
+package metrics
+
+import (
+	"context"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// dnsTracer contains the state needed to measure the DNS lookups done while sending one request.
+type dnsTracer struct {
+	owner   *TransportWrapper
+	service string
+	lock    sync.Mutex
+	lookups int
+	start   time.Time
+}
+
+// traceDNS returns a context derived from the given one that contains the trace hooks that
+// update the DNS metrics for a request to the given service.
+//
+// A lookup is considered uncached when the transport calls the DNSStart and DNSDone hooks. A new
+// connection that is opened without those hooks being called is considered a cached lookup,
+// because the address was resolved before reaching the transport, for example by an in-process
+// DNS cache used by the dialer. Requests that reuse an existing connection don't need an address
+// at all, so they aren't counted.
+func (w *TransportWrapper) traceDNS(ctx context.Context, service string) context.Context {
+	tracer := &dnsTracer{
+		owner:   w,
+		service: service,
+	}
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		DNSStart: tracer.dnsStart,
+		DNSDone:  tracer.dnsDone,
+		GotConn:  tracer.gotConn,
+	})
+}
+
+// dnsStart is called by the transport when a DNS lookup starts. Note that this may be called
+// from a different goroutine than the one that sends the request.
+func (t *dnsTracer) dnsStart(info httptrace.DNSStartInfo) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.lookups++
+	t.start = t.owner.clock.Now()
+}
+
+// dnsDone is called by the transport when a DNS lookup finishes.
+func (t *dnsTracer) dnsDone(info httptrace.DNSDoneInfo) {
+	t.lock.Lock()
+	elapsed := t.owner.clock.Since(t.start)
+	t.lock.Unlock()
+	t.owner.dnsCount.With(t.labels(false)).Inc()
+	t.owner.dnsDuration.With(t.owner.renames.labels(prometheus.Labels{
+		serviceLabelName: t.service,
+	})).Observe(t.owner.durationUnit.value(elapsed))
+}
+
+// gotConn is called by the transport when it has obtained the connection that will be used to
+// send the request.
+func (t *dnsTracer) gotConn(info httptrace.GotConnInfo) {
+	if info.Reused {
+		return
+	}
+	t.lock.Lock()
+	lookups := t.lookups
+	t.lock.Unlock()
+	if lookups == 0 {
+		t.owner.dnsCount.With(t.labels(true)).Inc()
+	}
+}
+
+// labels calculates the labels of the DNS lookup count metric.
+func (t *dnsTracer) labels(cached bool) prometheus.Labels {
+	value := "false"
+	if cached {
+		value = "true"
+	}
+	return t.owner.renames.labels(prometheus.Labels{
+		serviceLabelName: t.service,
+		cachedLabelName:  value,
+	})
+}
@@ -21,9 +21,13 @@ package metrics
 import (
 	"fmt"
 	"net/http"
-	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/openshift-online/ocm-sdk-go/internal"
+	"github.com/openshift-online/ocm-sdk-go/metrics/core"
+
+	"github.com/openshift-online/ocm-sdk-go/clock"
 )
 
 // HandlerWrapperBuilder contains the data and logic needed to build a new metrics handler wrapper
@@ -46,6 +50,8 @@ import (
 //	path - Request path, for example /api/clusters_mgmt/v1/clusters.
 //	code - HTTP response code, for example 200 or 500.
 //	apiservice - API service name, for example ocm-clusters-service.
+//	outcome - One of `success`, `client_error` or `server_error`, only when enabled with the
+//	Outcome method.
 //
 // To calculate the average request duration during the last 10 minutes, for example, use a
 // Prometheus expression like this:
@@ -63,6 +69,10 @@ import (
 // The meaning of that is that there were a total of 56 requests to get specific clusters,
 // independently of the specific identifier of the cluster.
 //
+// The path, method, code and service labels are calculated with the functions of the metrics/core
+// package, the same that the transport wrapper uses, so the series generated by clients and
+// servers for the same requests have the same labels and can be compared or joined directly.
+//
 // The value of the `code` label will be zero when sending the request failed without a response
 // code, for example if it wasn't possible to open the connection, or if there was a timeout waiting
 // for the response.
@@ -70,17 +80,41 @@ import (
 // Note that setting this attribute is not enough to have metrics published, you also need to
 // create and start a metrics server, as described in the documentation of the Prometheus library.
 //
+// The metrics are registered with the default Prometheus registerer unless a different one is
+// set with the Registerer method, so when there is no need for a separate registry this is enough:
+//
+//	wrapper, err := metrics.NewHandlerWrapper().
+//		Subsystem("my").
+//		Build()
+//
+// The metrics will then be published by the handler returned by the promhttp.Handler function,
+// usually in the `/metrics` path.
+//
 // Don't create objects of this type directly; use the NewHandlerWrapper function instead.
 type HandlerWrapperBuilder struct {
-	paths      []string
-	subsystem  string
-	registerer prometheus.Registerer
+	paths        []string
+	subsystem    string
+	registerer   prometheus.Registerer
+	exclusive    bool
+	clock        clock.Clock
+	durationUnit DurationUnit
+	openMetrics  bool
+	outcome      bool
+	seriesCount  bool
+	renames      labelRenames
 }
 
 // HandlerWrapper contains the data and logic needed to wrap an HTTP handler with another one that
 // generates Prometheus metrics.
 type HandlerWrapper struct {
-	paths           pathTree
+	paths           core.PathTree
+	metricNames     []string
+	registerer      prometheus.Registerer
+	clock           clock.Clock
+	durationUnit    DurationUnit
+	labelNames      []string
+	outcome         bool
+	renames         labelRenames
 	requestCount    *prometheus.CounterVec
 	requestDuration *prometheus.HistogramVec
 }
@@ -108,6 +142,8 @@ var _ http.ResponseWriter = (*responseWriter)(nil)
 func NewHandlerWrapper() *HandlerWrapperBuilder {
 	return &HandlerWrapperBuilder{
 		registerer: prometheus.DefaultRegisterer,
+		clock:      clock.Real,
+		renames:    labelRenames{},
 	}
 }
 
@@ -138,7 +174,8 @@ func (b *HandlerWrapperBuilder) Subsystem(value string) *HandlerWrapperBuilder {
 // Registerer sets the Prometheus registerer that will be used to register the metrics. The default
 // is to use the default Prometheus registerer and there is usually no need to change that. This is
 // intended for unit tests, where it is convenient to have a registerer that doesn't interfere with
-// the rest of the system.
+// the rest of the system, and for applications that use their own registry. Passing nil restores
+// the default.
 func (b *HandlerWrapperBuilder) Registerer(value prometheus.Registerer) *HandlerWrapperBuilder {
 	if value == nil {
 		value = prometheus.DefaultRegisterer
@@ -147,6 +184,97 @@ func (b *HandlerWrapperBuilder) Registerer(value prometheus.Registerer) *Handler
 	return b
 }
 
+// Exclusive makes the Build method fail if any of the metrics is already registered, for example
+// by another wrapper that uses the same subsystem and registerer. The default is to share the
+// metrics that are already registered with the same labels, which is convenient for applications
+// that create multiple wrappers on purpose, but that silently mixes the series of wrappers that
+// use the same subsystem by mistake.
+func (b *HandlerWrapperBuilder) Exclusive(value bool) *HandlerWrapperBuilder {
+	b.exclusive = value
+	return b
+}
+
+// Clock sets the clock that will be used to measure the duration of requests. The default is to
+// use the real clock of the system. This is intended for unit tests that need to control time in
+// order to check precisely the measured durations.
+func (b *HandlerWrapperBuilder) Clock(value clock.Clock) *HandlerWrapperBuilder {
+	if value == nil {
+		value = clock.Real
+	}
+	b.clock = value
+	return b
+}
+
+// DurationUnit sets the unit used to record the request durations. The default is to use seconds.
+// When a different unit is used the name of the duration metric will have the name of the unit as
+// suffix, and the buckets will be scaled accordingly. For example, if the subsystem is `my` and the
+// unit is DurationUnitMilliseconds then the metric will be `my_request_duration_milliseconds` and
+// the buckets will be 100, 1000, 10000 and 30000.
+func (b *HandlerWrapperBuilder) DurationUnit(value DurationUnit) *HandlerWrapperBuilder {
+	b.durationUnit = value
+	return b
+}
+
+// OpenMetrics selects the naming convention of the OpenMetrics specification. When enabled the
+// names of counters will have the `_total` suffix, for example `my_request_count_total` instead
+// of `my_request_count`, and the names of duration histograms will always have the unit suffix,
+// for example `my_request_duration_seconds` instead of `my_request_duration`. The default is to
+// use the original names, in order to not break existing dashboards and queries.
+func (b *HandlerWrapperBuilder) OpenMetrics(value bool) *HandlerWrapperBuilder {
+	b.openMetrics = value
+	return b
+}
+
+// Outcome adds to the request count and duration metrics an `outcome` label that summarizes the
+// result of the request. The value will be `success` when the response code is less than 400,
+// `client_error` when it is a 4xx code and `server_error` when it is a 5xx code. This is intended
+// to simplify the calculation of success rates. The default is to not add this label.
+func (b *HandlerWrapperBuilder) Outcome(value bool) *HandlerWrapperBuilder {
+	b.outcome = value
+	return b
+}
+
+// SeriesCount enables the gauge that reports the number of series generated by the wrapper:
+//
+//	<subsystem>_metric_series_count - Number of series generated by the request metrics.
+//
+// See the SeriesCount method of the transport wrapper for details. The default is to not generate
+// this metric.
+func (b *HandlerWrapperBuilder) SeriesCount(value bool) *HandlerWrapperBuilder {
+	b.seriesCount = value
+	return b
+}
+
+// APIServiceLabelName sets the name of the label that contains the API service name. The default
+// is `apiservice`. This is intended for teams that have existing dashboards and queries that use
+// different names. The name must be a valid Prometheus label name and it can't be the same as the
+// name of any other label.
+func (b *HandlerWrapperBuilder) APIServiceLabelName(value string) *HandlerWrapperBuilder {
+	b.renames[serviceLabelName] = value
+	return b
+}
+
+// MethodLabelName sets the name of the label that contains the HTTP method. The default is
+// `method`. See the APIServiceLabelName method for details.
+func (b *HandlerWrapperBuilder) MethodLabelName(value string) *HandlerWrapperBuilder {
+	b.renames[methodLabelName] = value
+	return b
+}
+
+// PathLabelName sets the name of the label that contains the request path. The default is `path`.
+// See the APIServiceLabelName method for details.
+func (b *HandlerWrapperBuilder) PathLabelName(value string) *HandlerWrapperBuilder {
+	b.renames[pathLabelName] = value
+	return b
+}
+
+// CodeLabelName sets the name of the label that contains the HTTP response code. The default is
+// `code`. See the APIServiceLabelName method for details.
+func (b *HandlerWrapperBuilder) CodeLabelName(value string) *HandlerWrapperBuilder {
+	b.renames[codeLabelName] = value
+	return b
+}
+
 // Build uses the information stored in the builder to create a new handler wrapper.
 func (b *HandlerWrapperBuilder) Build() (result *HandlerWrapper, err error) {
 	// Check parameters:
@@ -154,62 +282,108 @@ func (b *HandlerWrapperBuilder) Build() (result *HandlerWrapper, err error) {
 		err = fmt.Errorf("subsystem is mandatory")
 		return
 	}
+	err = b.durationUnit.check()
+	if err != nil {
+		return
+	}
+	err = b.renames.check()
+	if err != nil {
+		return
+	}
 
-	// Register the request count metric:
+	// Calculate the names of the labels:
+	renames := labelRenames{}
+	for original, name := range b.renames {
+		renames[original] = name
+	}
+	labelNames := append([]string{}, requestLabelNames...)
+	if b.outcome {
+		labelNames = append(labelNames, outcomeLabelName)
+	}
+	labelNames = renames.names(labelNames)
+
+	// Calculate the names of the metrics:
+	names := metricNames{
+		unit:        b.durationUnit,
+		openMetrics: b.openMetrics,
+	}
+
+	// Use a registerer that rejects metrics that are already registered if exclusive mode has
+	// been requested:
+	registerer := b.registerer
+	if b.exclusive {
+		registerer = internal.ExclusiveRegisterer(registerer)
+	}
+
+	// Register the request count metric, remembering the names of all the metrics:
+	var metricNames []string
 	requestCount := prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Subsystem: b.subsystem,
-			Name:      "request_count",
+			Name:      names.counter("request_count"),
 			Help:      "Number of requests sent.",
 		},
-		requestLabelNames,
+		labelNames,
+	)
+	requestCount, err = internal.RegisterCounterVec(
+		registerer,
+		b.subsystem+"_"+names.counter("request_count"),
+		requestCount,
 	)
-	err = b.registerer.Register(requestCount)
 	if err != nil {
-		registered, ok := err.(prometheus.AlreadyRegisteredError)
-		if ok {
-			requestCount = registered.ExistingCollector.(*prometheus.CounterVec)
-			err = nil
-		} else {
-			return
-		}
+		return
 	}
+	metricNames = append(metricNames, b.subsystem+"_"+names.counter("request_count"))
 
 	// Create the path tree:
-	paths := pathRoot.copy()
+	paths := core.DefaultPaths()
 	for _, path := range b.paths {
-		paths.add(path)
+		paths.Add(path)
 	}
 
 	// Register the request duration metric:
 	requestDuration := prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Subsystem: b.subsystem,
-			Name:      "request_duration",
-			Help:      "Request duration in seconds.",
-			Buckets: []float64{
-				0.1,
-				1.0,
-				10.0,
-				30.0,
-			},
+			Name:      names.duration("request_duration"),
+			Help:      b.durationUnit.help("Request"),
+			Buckets:   b.durationUnit.buckets(),
 		},
-		requestLabelNames,
+		labelNames,
+	)
+	requestDuration, err = internal.RegisterHistogramVec(
+		registerer,
+		b.subsystem+"_"+names.duration("request_duration"),
+		requestDuration,
 	)
-	err = b.registerer.Register(requestDuration)
 	if err != nil {
-		registered, ok := err.(prometheus.AlreadyRegisteredError)
-		if ok {
-			requestDuration = registered.ExistingCollector.(*prometheus.HistogramVec)
-			err = nil
-		} else {
+		return
+	}
+	metricNames = append(metricNames, b.subsystem+"_"+names.duration("request_duration"))
+
+	// Register the series count metric:
+	if b.seriesCount {
+		counter := newSeriesCounter(
+			[]*prometheus.CounterVec{requestCount},
+			[]*prometheus.HistogramVec{requestDuration},
+		)
+		err = registerSeriesCount(registerer, b.subsystem, counter)
+		if err != nil {
 			return
 		}
+		metricNames = append(metricNames, b.subsystem+"_metric_series_count")
 	}
 
 	// Create and populate the object:
 	result = &HandlerWrapper{
 		paths:           paths,
+		metricNames:     metricNames,
+		registerer:      b.registerer,
+		clock:           b.clock,
+		durationUnit:    b.durationUnit,
+		labelNames:      labelNames,
+		outcome:         b.outcome,
+		renames:         renames,
 		requestCount:    requestCount,
 		requestDuration: requestDuration,
 	}
@@ -217,6 +391,30 @@ func (b *HandlerWrapperBuilder) Build() (result *HandlerWrapper, err error) {
 	return
 }
 
+// Registerer returns the Prometheus registerer where the metrics have been registered. This is
+// intended for building a metrics server that publishes them, see the NewHTTPServer function.
+func (w *HandlerWrapper) Registerer() prometheus.Registerer {
+	return w.registerer
+}
+
+// MetricNames returns the fully qualified names of the metrics registered by the wrapper, in the
+// order that they were registered. See the MetricNames method of the transport wrapper for
+// details.
+func (w *HandlerWrapper) MetricNames() []string {
+	result := make([]string, len(w.metricNames))
+	copy(result, w.metricNames)
+	return result
+}
+
+// Reset removes all the series of the metrics generated by the wrapper, so that counters and
+// histograms start again from zero. The metrics stay registered, so there is no need to create a
+// new wrapper or registry. This is intended for test suites that reuse the same wrapper in several
+// test cases. Don't use it in production, as Prometheus interprets the reset counters as restarts.
+func (w *HandlerWrapper) Reset() {
+	w.requestCount.Reset()
+	w.requestDuration.Reset()
+}
+
 // Wrap creates a new handler that wraps the given one and generates the Prometheus metrics.
 func (w *HandlerWrapper) Wrap(h http.Handler) http.Handler {
 	return &handler{
@@ -227,6 +425,12 @@ func (w *HandlerWrapper) Wrap(h http.Handler) http.Handler {
 
 // ServeHTTP is the implementation of the HTTP handler interface.
 func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Don't record anything if metrics have been disabled for this request:
+	if disabledFromContext(r.Context()) {
+		h.handler.ServeHTTP(w, r)
+		return
+	}
+
 	// We need to replace the response writer with a custom one that captures the response code
 	// generated by the next handler:
 	writer := responseWriter{
@@ -235,21 +439,25 @@ func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Measure the time that it takes to process the request and send the response:
-	start := time.Now()
+	start := h.owner.clock.Now()
 	h.handler.ServeHTTP(&writer, r)
-	elapsed := time.Since(start)
+	elapsed := h.owner.clock.Since(start)
 
 	// Update the metrics:
 	path := r.URL.Path
 	method := r.Method
 	labels := prometheus.Labels{
-		serviceLabelName: serviceLabel(path),
-		methodLabelName:  methodLabel(method),
-		pathLabelName:    pathLabel(h.owner.paths, path),
-		codeLabelName:    codeLabel(writer.code),
+		serviceLabelName: core.ServiceLabel(path),
+		methodLabelName:  core.MethodLabel(method),
+		pathLabelName:    h.owner.paths.Label(path),
+		codeLabelName:    core.CodeLabel(writer.code),
+	}
+	if h.owner.outcome {
+		labels[outcomeLabelName] = outcomeLabel(writer.code, nil)
 	}
+	labels = h.owner.renames.labels(labels)
 	h.owner.requestCount.With(labels).Inc()
-	h.owner.requestDuration.With(labels).Observe(elapsed.Seconds())
+	h.owner.requestDuration.With(labels).Observe(h.owner.durationUnit.value(elapsed))
 }
 
 // Header is part of the implementation of the http.ResponseWriter interface.
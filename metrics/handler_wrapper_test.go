@@ -22,6 +22,8 @@ import (
 	"net/http"
 	"net/http/httptest"
 
+	"github.com/prometheus/client_golang/prometheus"
+
 	. "github.com/onsi/ginkgo/v2/dsl/core"  // nolint
 	. "github.com/onsi/ginkgo/v2/dsl/table" // nolint
 	. "github.com/onsi/gomega"              // nolint
@@ -40,6 +42,23 @@ var _ = Describe("Create", func() {
 		Expect(message).To(ContainSubstring("subsystem"))
 		Expect(message).To(ContainSubstring("mandatory"))
 	})
+
+	It("Can't be created in exclusive mode with a subsystem already in use", func() {
+		registry := prometheus.NewRegistry()
+		_, err := NewHandlerWrapper().
+			Subsystem("my").
+			Registerer(registry).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		wrapper, err := NewHandlerWrapper().
+			Subsystem("my").
+			Registerer(registry).
+			Exclusive(true).
+			Build()
+		Expect(err).To(HaveOccurred())
+		Expect(wrapper).To(BeNil())
+		Expect(err.Error()).To(ContainSubstring("already registered"))
+	})
 })
 
 var _ = Describe("Metrics", func() {
@@ -76,6 +95,140 @@ var _ = Describe("Metrics", func() {
 		handler.ServeHTTP(recorder, request)
 	}
 
+	It("Returns snapshot of the metrics", func() {
+		// Prepare the handler:
+		handler = wrapper.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+		}))
+
+		// Send the requests:
+		Send(http.MethodPost, "/api")
+		Send(http.MethodPost, "/api")
+
+		// Verify the snapshot:
+		snapshot, err := wrapper.Snapshot(map[string]string{
+			"method": http.MethodPost,
+			"code":   "201",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(snapshot.Count).To(BeEquivalentTo(2))
+		Expect(snapshot.DurationCount).To(BeEquivalentTo(2))
+	})
+
+	It("Starts from zero after reset", func() {
+		// Prepare the handler:
+		handler = wrapper.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+
+		// Send a request, reset, and send another request:
+		Send(http.MethodGet, "/api")
+		Send(http.MethodGet, "/api")
+		wrapper.Reset()
+		Send(http.MethodGet, "/api")
+
+		// Verify the metrics:
+		metrics := server.Metrics()
+		Expect(metrics).To(MatchLine(`^my_request_count\{.*\} 1$`))
+		Expect(metrics).To(MatchLine(`^my_request_duration_count\{.*\} 1$`))
+	})
+
+	It("Doesn't record requests with metrics disabled in the context", func() {
+		// Prepare the handler:
+		called := false
+		handler = wrapper.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+			w.WriteHeader(http.StatusOK)
+		}))
+
+		// Send a request with metrics disabled:
+		request := httptest.NewRequest(http.MethodGet, "http://localhost/api", nil)
+		request = request.WithContext(WithoutMetrics(request.Context()))
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		Expect(called).To(BeTrue())
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+
+		// Verify the metrics:
+		metrics := server.Metrics()
+		Expect(metrics).ToNot(MatchLine(`^my_request_count.*$`))
+	})
+
+	It("Adds outcome label", func() {
+		// Create a wrapper that adds the outcome label:
+		registry := prometheus.NewRegistry()
+		wrapper, err := NewHandlerWrapper().
+			Subsystem("your").
+			Registerer(registry).
+			Outcome(true).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		handler = wrapper.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+
+		// Send the request:
+		Send(http.MethodGet, "/api")
+
+		// Verify the snapshot:
+		snapshot, err := wrapper.Snapshot(map[string]string{
+			"outcome": OutcomeServerError,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(snapshot.Count).To(BeEquivalentTo(1))
+	})
+
+	It("Uses OpenMetrics names when enabled", func() {
+		// Create a wrapper that uses the OpenMetrics names:
+		registry := prometheus.NewRegistry()
+		wrapper, err := NewHandlerWrapper().
+			Subsystem("your").
+			Registerer(registry).
+			OpenMetrics(true).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		handler = wrapper.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+
+		// Send the request:
+		Send(http.MethodGet, "/api")
+
+		// Verify the names:
+		families, err := registry.Gather()
+		Expect(err).ToNot(HaveOccurred())
+		var names []string
+		for _, family := range families {
+			names = append(names, family.GetName())
+		}
+		Expect(names).To(ConsistOf(
+			"your_request_count_total",
+			"your_request_duration_seconds",
+		))
+		Expect(wrapper.MetricNames()).To(Equal(names))
+	})
+
+	It("Renames labels", func() {
+		// Create a wrapper that uses a different name for the service label:
+		wrapper, err := NewHandlerWrapper().
+			Subsystem("renamed").
+			Registerer(server.Registry()).
+			APIServiceLabelName("service").
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		handler = wrapper.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+
+		// Send the request:
+		Send(http.MethodGet, "/api/clusters_mgmt/v1/clusters")
+
+		// Verify the metrics:
+		metrics := server.Metrics()
+		Expect(metrics).To(MatchLine(`^renamed_request_count\{.*service="ocm-clusters-service".*\} 1$`))
+		Expect(metrics).ToNot(MatchLine(`^renamed_request_count\{.*apiservice=.*\} .*$`))
+	})
+
 	It("Calls wrapped handler", func() {
 		// Prepare the handler:
 		called := false
@@ -684,3 +837,84 @@ var _ = Describe("Metrics", func() {
 		)
 	})
 })
+
+var _ = Describe("Client and server", func() {
+	It("Generate the same labels for the same request", func() {
+		// Create the server:
+		serverWrapper, err := NewHandlerWrapper().
+			Subsystem("server").
+			Registerer(prometheus.NewRegistry()).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		server := httptest.NewServer(serverWrapper.Wrap(http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			},
+		)))
+		defer server.Close()
+
+		// Create the client:
+		clientWrapper, err := NewTransportWrapper().
+			Subsystem("client").
+			Registerer(prometheus.NewRegistry()).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		client := &http.Client{
+			Transport: clientWrapper.Wrap(http.DefaultTransport),
+		}
+		defer client.CloseIdleConnections()
+
+		// Send the request:
+		response, err := client.Get(server.URL + "/api/clusters_mgmt/v1/clusters/123/")
+		Expect(err).ToNot(HaveOccurred())
+		err = response.Body.Close()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Check that both sides have the request with the same labels:
+		labels := map[string]string{
+			"apiservice": "ocm-clusters-service",
+			"method":     http.MethodGet,
+			"path":       "/api/clusters_mgmt/v1/clusters/-",
+			"code":       "200",
+		}
+		clientSnapshot, err := clientWrapper.Snapshot(labels)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(clientSnapshot.Count).To(BeEquivalentTo(1))
+		serverSnapshot, err := serverWrapper.Snapshot(labels)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(serverSnapshot.Count).To(BeEquivalentTo(1))
+	})
+})
+
+var _ = Describe("Series count", func() {
+	It("Counts the series of the request metrics", func() {
+		// Create the server:
+		metricsServer := NewMetricsServer()
+		defer metricsServer.Close()
+		wrapper, err := NewHandlerWrapper().
+			Subsystem("my").
+			Registerer(metricsServer.Registry()).
+			SeriesCount(true).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		handler := wrapper.Wrap(http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			},
+		))
+
+		// Send the requests:
+		for _, path := range []string{
+			"/api/clusters_mgmt/v1/clusters",
+			"/api/clusters_mgmt/v1/clusters",
+			"/api/accounts_mgmt/v1/accounts",
+		} {
+			request := httptest.NewRequest(http.MethodGet, path, nil)
+			handler.ServeHTTP(httptest.NewRecorder(), request)
+		}
+
+		// Verify the metrics:
+		metrics := metricsServer.Metrics()
+		Expect(metrics).To(MatchLine(`^my_metric_series_count 4$`))
+	})
+})
@@ -0,0 +1,202 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the implementation of an HTTP server that publishes the metrics.
+
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/openshift-online/ocm-sdk-go/logging"
+)
+
+// DefaultHTTPServerAddress is the address where the metrics server listens by default.
+const DefaultHTTPServerAddress = ":8000"
+
+// HTTPServerPath is the path where the metrics server publishes the metrics.
+const HTTPServerPath = "/metrics"
+
+// HTTPServerBuilder contains the data and logic needed to create an HTTP server that publishes the
+// metrics in the `/metrics` path. Typical usage, publishing the metrics generated by a transport
+// wrapper, is like this:
+//
+//	server, err := metrics.NewHTTPServer().
+//		Logger(logger).
+//		Address(":8000").
+//		Registerer(wrapper.Registerer()).
+//		Build(ctx)
+//	if err != nil {
+//		...
+//	}
+//	defer server.Shutdown(ctx)
+//
+// Don't create objects of this type directly; use the NewHTTPServer function instead.
+type HTTPServerBuilder struct {
+	logger      logging.Logger
+	address     string
+	registerer  prometheus.Registerer
+	openMetrics bool
+}
+
+// HTTPServer is an HTTP server that publishes the metrics.
+type HTTPServer struct {
+	logger   logging.Logger
+	listener net.Listener
+	server   *http.Server
+	done     chan struct{}
+}
+
+// NewHTTPServer creates a builder that can then be used to configure and create a metrics server.
+func NewHTTPServer() *HTTPServerBuilder {
+	return &HTTPServerBuilder{
+		address:    DefaultHTTPServerAddress,
+		registerer: prometheus.DefaultRegisterer,
+	}
+}
+
+// Logger sets the logger that the server will use to write to the log. This is mandatory.
+func (b *HTTPServerBuilder) Logger(value logging.Logger) *HTTPServerBuilder {
+	b.logger = value
+	return b
+}
+
+// Address sets the address where the server will listen, for example `localhost:8000`. The
+// default is to listen in port 8000 of all the interfaces. Use port zero to select a random
+// port, and then use the Address method of the server to find out which one was selected.
+func (b *HTTPServerBuilder) Address(value string) *HTTPServerBuilder {
+	b.address = value
+	return b
+}
+
+// Registerer sets the Prometheus registerer that contains the metrics that will be published.
+// This should usually be the same registerer that was used to build the metrics wrappers, which
+// can be obtained with their Registerer methods. It also needs to implement the
+// prometheus.Gatherer interface, like the *prometheus.Registry type does. The default is to use
+// the default Prometheus registerer. Passing nil restores the default.
+func (b *HTTPServerBuilder) Registerer(value prometheus.Registerer) *HTTPServerBuilder {
+	if value == nil {
+		value = prometheus.DefaultRegisterer
+	}
+	b.registerer = value
+	return b
+}
+
+// OpenMetrics enables the OpenMetrics exposition format. When enabled the server will use it for
+// clients that request it explicitly with the `Accept` header, and the Prometheus text format for
+// the rest. The default is to always use the Prometheus text format.
+func (b *HTTPServerBuilder) OpenMetrics(value bool) *HTTPServerBuilder {
+	b.openMetrics = value
+	return b
+}
+
+// Build uses the data stored in the builder to create a new metrics server. The server will
+// already be listening and serving requests when this method returns.
+func (b *HTTPServerBuilder) Build(ctx context.Context) (result *HTTPServer, err error) {
+	// Check parameters:
+	if b.logger == nil {
+		err = fmt.Errorf("logger is mandatory")
+		return
+	}
+	if b.address == "" {
+		err = fmt.Errorf("address is mandatory")
+		return
+	}
+
+	// Find the gatherer corresponding to the registerer:
+	gatherer, err := gathererFor(b.registerer)
+	if err != nil {
+		return
+	}
+
+	// Start listening:
+	listener, err := net.Listen("tcp", b.address)
+	if err != nil {
+		err = fmt.Errorf("can't listen on address '%s': %w", b.address, err)
+		return
+	}
+
+	// Create the HTTP server:
+	mux := http.NewServeMux()
+	handler := promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{
+		EnableOpenMetrics: b.openMetrics,
+	})
+	mux.Handle(HTTPServerPath, handler)
+	server := &http.Server{
+		Handler: mux,
+	}
+
+	// Create and populate the object:
+	result = &HTTPServer{
+		logger:   b.logger,
+		listener: listener,
+		server:   server,
+		done:     make(chan struct{}),
+	}
+
+	// Start serving:
+	go result.serve(ctx)
+
+	return
+}
+
+// serve runs the HTTP server till it is shut down.
+func (s *HTTPServer) serve(ctx context.Context) {
+	defer close(s.done)
+	s.logger.Info(
+		ctx,
+		"Metrics server listening on address '%s'",
+		s.listener.Addr(),
+	)
+	err := s.server.Serve(s.listener)
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		s.logger.Error(ctx, "Metrics server failed: %v", err)
+	}
+}
+
+// Address returns the address where the server is listening. This is useful when the server was
+// created with port zero.
+func (s *HTTPServer) Address() string {
+	return s.listener.Addr().String()
+}
+
+// Shutdown stops the server gracefully, waiting for the requests in progress to finish, or till
+// the given context is cancelled.
+func (s *HTTPServer) Shutdown(ctx context.Context) error {
+	err := s.server.Shutdown(ctx)
+	if err != nil {
+		return err
+	}
+	<-s.done
+	return nil
+}
+
+// Close stops the server immediately, without waiting for requests in progress to finish.
+func (s *HTTPServer) Close() error {
+	err := s.server.Close()
+	if err != nil {
+		return err
+	}
+	<-s.done
+	return nil
+}
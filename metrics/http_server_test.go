@@ -0,0 +1,212 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains tests for the metrics HTTP server.
+
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core" // nolint
+	. "github.com/onsi/gomega"             // nolint
+	. "github.com/onsi/gomega/ghttp"       // nolint
+
+	. "github.com/openshift-online/ocm-sdk-go/testing"
+)
+
+var _ = Describe("HTTP server", func() {
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.Background()
+	})
+
+	// Get sends a GET request to the given URL and returns the response code and the lines of
+	// the body.
+	var Get = func(url string) (code int, lines []string) {
+		response, err := http.Get(url)
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err = response.Body.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+		data, err := io.ReadAll(response.Body)
+		Expect(err).ToNot(HaveOccurred())
+		code = response.StatusCode
+		lines = strings.Split(string(data), "\n")
+		return
+	}
+
+	It("Can't be created without a logger", func() {
+		server, err := NewHTTPServer().
+			Address("127.0.0.1:0").
+			Build(ctx)
+		Expect(err).To(HaveOccurred())
+		Expect(server).To(BeNil())
+		message := err.Error()
+		Expect(message).To(ContainSubstring("logger"))
+		Expect(message).To(ContainSubstring("mandatory"))
+	})
+
+	It("Can't be created with a registerer that isn't a gatherer", func() {
+		server, err := NewHTTPServer().
+			Logger(logger).
+			Address("127.0.0.1:0").
+			Registerer(prometheus.WrapRegistererWithPrefix("my_", prometheus.NewRegistry())).
+			Build(ctx)
+		Expect(err).To(HaveOccurred())
+		Expect(server).To(BeNil())
+		Expect(err.Error()).To(ContainSubstring("gather"))
+	})
+
+	It("Can't be created with an address that is in use", func() {
+		first, err := NewHTTPServer().
+			Logger(logger).
+			Address("127.0.0.1:0").
+			Registerer(prometheus.NewRegistry()).
+			Build(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err := first.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+		second, err := NewHTTPServer().
+			Logger(logger).
+			Address(first.Address()).
+			Registerer(prometheus.NewRegistry()).
+			Build(ctx)
+		Expect(err).To(HaveOccurred())
+		Expect(second).To(BeNil())
+		Expect(err.Error()).To(ContainSubstring(first.Address()))
+	})
+
+	It("Publishes the metrics of the wrapper", func() {
+		// Start the API server:
+		apiServer := NewServer()
+		defer apiServer.Close()
+		apiServer.AppendHandlers(RespondWith(http.StatusOK, nil))
+
+		// Create the wrapper:
+		wrapper, err := NewTransportWrapper().
+			Subsystem("my").
+			Registerer(prometheus.NewRegistry()).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Create the metrics server:
+		server, err := NewHTTPServer().
+			Logger(logger).
+			Address("127.0.0.1:0").
+			Registerer(wrapper.Registerer()).
+			Build(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err := server.Shutdown(ctx)
+			Expect(err).ToNot(HaveOccurred())
+		}()
+
+		// Send a request:
+		client := &http.Client{
+			Transport: wrapper.Wrap(http.DefaultTransport),
+		}
+		defer client.CloseIdleConnections()
+		response, err := client.Get(apiServer.URL() + "/api/clusters_mgmt/v1/clusters")
+		Expect(err).ToNot(HaveOccurred())
+		err = response.Body.Close()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Check the metrics:
+		code, metrics := Get("http://" + server.Address() + "/metrics")
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(metrics).To(MatchLine(`^my_request_count\{.*\} 1$`))
+	})
+
+	It("Uses OpenMetrics format when enabled and requested", func() {
+		// Create the metrics server:
+		registry := prometheus.NewRegistry()
+		counter := prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "my_request_count_total",
+			Help: "Number of requests sent.",
+		})
+		registry.MustRegister(counter)
+		counter.Inc()
+		server, err := NewHTTPServer().
+			Logger(logger).
+			Address("127.0.0.1:0").
+			Registerer(registry).
+			OpenMetrics(true).
+			Build(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err := server.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+
+		// Request the OpenMetrics format:
+		request, err := http.NewRequest(
+			http.MethodGet,
+			"http://"+server.Address()+"/metrics",
+			nil,
+		)
+		Expect(err).ToNot(HaveOccurred())
+		request.Header.Set("Accept", "application/openmetrics-text; version=0.0.1")
+		response, err := http.DefaultClient.Do(request)
+		Expect(err).ToNot(HaveOccurred())
+		defer response.Body.Close()
+		Expect(response.Header.Get("Content-Type")).To(HavePrefix("application/openmetrics-text"))
+		data, err := io.ReadAll(response.Body)
+		Expect(err).ToNot(HaveOccurred())
+		lines := strings.Split(string(data), "\n")
+		Expect(lines).To(MatchLine(`^# TYPE my_request_count counter$`))
+		Expect(lines).To(MatchLine(`^my_request_count_total 1\.0$`))
+		Expect(lines).To(MatchLine(`^# EOF$`))
+	})
+
+	It("Doesn't publish metrics in other paths", func() {
+		server, err := NewHTTPServer().
+			Logger(logger).
+			Address("127.0.0.1:0").
+			Registerer(prometheus.NewRegistry()).
+			Build(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err := server.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+		code, _ := Get("http://" + server.Address() + "/junk")
+		Expect(code).To(Equal(http.StatusNotFound))
+	})
+
+	It("Stops serving after shutdown", func() {
+		server, err := NewHTTPServer().
+			Logger(logger).
+			Address("127.0.0.1:0").
+			Registerer(prometheus.NewRegistry()).
+			Build(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		address := server.Address()
+		err = server.Shutdown(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = http.Get("http://" + address + "/metrics")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,36 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the function that calculates if requests are idempotent.
+
+package metrics
+
+import (
+	"net/http"
+)
+
+// idempotentLabel calculates the `idempotent` label from the request method. It is `true` for the
+// methods that are idempotent according to the HTTP specification, like GET, HEAD, PUT or DELETE,
+// and `false` for the rest, like POST or PATCH.
+func idempotentLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete,
+		http.MethodOptions, http.MethodTrace:
+		return "true"
+	default:
+		return "false"
+	}
+}
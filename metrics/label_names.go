@@ -0,0 +1,150 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the types and functions used to rename the labels added to metrics.
+
+package metrics
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// labelRenames maps the default names of labels to the names that will actually be used. Labels
+// that aren't in the map keep the default name.
+type labelRenames map[string]string
+
+// check verifies that the new names are valid Prometheus label names, that they aren't reserved
+// and that no two labels end up with the same name.
+func (r labelRenames) check() error {
+	for _, value := range r {
+		if !labelNameRE.MatchString(value) {
+			return fmt.Errorf(
+				"label name '%s' isn't valid, it must start with a letter or "+
+					"underscore and contain only letters, digits and underscores",
+				value,
+			)
+		}
+		if strings.HasPrefix(value, "__") || value == "le" || value == "quantile" {
+			return fmt.Errorf(
+				"label name '%s' isn't valid, it is reserved by Prometheus",
+				value,
+			)
+		}
+	}
+	used := map[string]string{}
+	for _, original := range allLabelNames {
+		name := r.name(original)
+		previous, ok := used[name]
+		if ok {
+			return fmt.Errorf(
+				"labels '%s' and '%s' can't have the same name '%s'",
+				previous, original, name,
+			)
+		}
+		used[name] = original
+	}
+	return nil
+}
+
+// checkExtra verifies that the names of the given additional labels, for example the ones whose
+// values are taken from the context, are valid and that they don't collide with each other or with
+// the names of the built-in labels.
+func (r labelRenames) checkExtra(names []string) error {
+	used := map[string]bool{}
+	for _, original := range allLabelNames {
+		used[original] = true
+		used[r.name(original)] = true
+	}
+	for _, name := range names {
+		if !labelNameRE.MatchString(name) {
+			return fmt.Errorf(
+				"label name '%s' isn't valid, it must start with a letter or "+
+					"underscore and contain only letters, digits and underscores",
+				name,
+			)
+		}
+		if strings.HasPrefix(name, "__") || name == "le" || name == "quantile" {
+			return fmt.Errorf(
+				"label name '%s' isn't valid, it is reserved by Prometheus",
+				name,
+			)
+		}
+		if used[name] {
+			return fmt.Errorf(
+				"label name '%s' can't be used because it is already used",
+				name,
+			)
+		}
+		used[name] = true
+	}
+	return nil
+}
+
+// name returns the name that will be used for the label with the given default name.
+func (r labelRenames) name(original string) string {
+	name, ok := r[original]
+	if ok {
+		return name
+	}
+	return original
+}
+
+// names returns the names that will be used for the labels with the given default names.
+func (r labelRenames) names(originals []string) []string {
+	result := make([]string, len(originals))
+	for i, original := range originals {
+		result[i] = r.name(original)
+	}
+	return result
+}
+
+// labels returns a copy of the given labels where the default names have been replaced with the
+// names that will be used.
+func (r labelRenames) labels(labels prometheus.Labels) prometheus.Labels {
+	if len(r) == 0 {
+		return labels
+	}
+	result := make(prometheus.Labels, len(labels))
+	for name, value := range labels {
+		result[r.name(name)] = value
+	}
+	return result
+}
+
+// labelNameRE is the regular expression used to check label names.
+var labelNameRE = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// allLabelNames contains the default names of all the labels, used to detect collisions.
+var allLabelNames = []string{
+	serviceLabelName,
+	codeLabelName,
+	methodLabelName,
+	pathLabelName,
+	hopsLabelName,
+	classLabelName,
+	attemptLabelName,
+	outcomeLabelName,
+	callerLabelName,
+	cachedLabelName,
+	idempotentLabelName,
+	reasonLabelName,
+	orgLabelName,
+	resumedLabelName,
+}
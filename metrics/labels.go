@@ -14,91 +14,69 @@ See the License for the specific language governing permissions and
 limitations under the License.
 */
 
-// This file contains functions that calculate the labels included in metrics.
+// This file contains the names of the labels included in metrics.
 
 package metrics
 
-import (
-	"strconv"
-	"strings"
+// Names of the labels added to metrics:
+const (
+	serviceLabelName    = "apiservice"
+	codeLabelName       = "code"
+	methodLabelName     = "method"
+	pathLabelName       = "path"
+	hopsLabelName       = "hops"
+	classLabelName      = "class"
+	attemptLabelName    = "attempt"
+	outcomeLabelName    = "outcome"
+	callerLabelName     = "caller"
+	cachedLabelName     = "cached"
+	idempotentLabelName = "idempotent"
+	reasonLabelName     = "reason"
+	orgLabelName        = "organization"
+	resumedLabelName    = "resumed"
 )
 
-// serviceLabel calculates the `service` for the given URL path.
-func serviceLabel(path string) string {
-	if !strings.HasPrefix(path, "/api/") {
-		return ""
-	}
-	if strings.HasPrefix(path, "/api/accounts_mgmt") {
-		return "ocm-accounts-service"
-	} else if strings.HasPrefix(path, "/api/clusters_mgmt") {
-		return "ocm-clusters-service"
-	} else if strings.HasPrefix(path, "/api/authorizations") {
-		return "ocm-authorizations-service"
-	} else if strings.HasPrefix(path, "/api/service_logs") {
-		return "ocm-logs-service"
-	} else {
-		parts := strings.Split(path, "/")
-		if len(parts) > 3 {
-			return "ocm-" + parts[3]
-		}
-		return ""
-	}
+// Array of labels added to call metrics:
+var requestLabelNames = []string{
+	serviceLabelName,
+	codeLabelName,
+	methodLabelName,
+	pathLabelName,
 }
 
-// methodLabel calculates the `method` label from the given HTTP method.
-func methodLabel(method string) string {
-	return strings.ToUpper(method)
+// Array of labels added to the DNS lookup count metric:
+var dnsCountLabelNames = []string{
+	serviceLabelName,
+	cachedLabelName,
 }
 
-// pathLabel calculates the `path` label from the URL path.
-func pathLabel(paths pathTree, path string) string {
-	// Remove leading and trailing slashes:
-	for len(path) > 0 && strings.HasPrefix(path, "/") {
-		path = path[1:]
-	}
-	for len(path) > 0 && strings.HasSuffix(path, "/") {
-		path = path[0 : len(path)-1]
-	}
-
-	// Clear segments that correspond to path variables:
-	segments := strings.Split(path, "/")
-	current := paths
-	for i, segment := range segments {
-		next, ok := current[segment]
-		if ok {
-			current = next
-			continue
-		}
-		next, ok = current["-"]
-		if ok {
-			segments[i] = "-"
-			current = next
-			continue
-		}
-		return "/-"
-	}
+// Array of labels added to the DNS lookup duration metric:
+var dnsDurationLabelNames = []string{
+	serviceLabelName,
+}
 
-	// Reconstruct the path joining the modified segments:
-	return "/" + strings.Join(segments, "/")
+// Array of labels added to the TLS handshake count metric:
+var tlsHandshakeLabelNames = []string{
+	serviceLabelName,
+	resumedLabelName,
 }
 
-// codeLabel calculates the `code` label from the given HTTP response.
-func codeLabel(code int) string {
-	return strconv.Itoa(code)
+// Array of labels added to the byte count metrics:
+var bytesLabelNames = []string{
+	serviceLabelName,
+	methodLabelName,
+	pathLabelName,
 }
 
-// Names of the labels added to metrics:
-const (
-	serviceLabelName = "apiservice"
-	codeLabelName    = "code"
-	methodLabelName  = "method"
-	pathLabelName    = "path"
-)
+// Array of labels added to the decode error metric:
+var decodeErrorLabelNames = []string{
+	serviceLabelName,
+	reasonLabelName,
+}
 
-// Array of labels added to call metrics:
-var requestLabelNames = []string{
+// Array of labels added to the stuck request metrics:
+var stuckLabelNames = []string{
 	serviceLabelName,
-	codeLabelName,
 	methodLabelName,
 	pathLabelName,
 }
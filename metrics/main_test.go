@@ -19,6 +19,8 @@ package metrics
 import (
 	"testing"
 
+	"github.com/openshift-online/ocm-sdk-go/logging"
+
 	. "github.com/onsi/ginkgo/v2/dsl/core" // nolint
 	. "github.com/onsi/gomega"             // nolint
 )
@@ -27,3 +29,17 @@ func TestMetrics(t *testing.T) {
 	RegisterFailHandler(Fail)
 	RunSpecs(t, "Metrics")
 }
+
+// Logger used for tests:
+var logger logging.Logger
+
+var _ = BeforeSuite(func() {
+	var err error
+
+	// Create the logger that will be used by all the tests:
+	logger, err = logging.NewStdLoggerBuilder().
+		Streams(GinkgoWriter, GinkgoWriter).
+		Debug(true).
+		Build()
+	Expect(err).ToNot(HaveOccurred())
+})
@@ -0,0 +1,45 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the functions used to calculate the names of the metrics.
+
+package metrics
+
+// metricNames calculates the names of the metrics according to the duration unit and to the
+// naming convention selected.
+type metricNames struct {
+	unit        DurationUnit
+	openMetrics bool
+}
+
+// counter returns the name of a counter. When the OpenMetrics naming convention is used the name
+// will have the `_total` suffix.
+func (n metricNames) counter(base string) string {
+	if n.openMetrics {
+		return base + "_total"
+	}
+	return base
+}
+
+// duration returns the name of a duration histogram. The name will have the suffix corresponding
+// to the unit. When the OpenMetrics naming convention is used the name will always have the unit
+// suffix, including `_seconds`.
+func (n metricNames) duration(base string) string {
+	if n.openMetrics && n.unit == DurationUnitSeconds {
+		return base + "_seconds"
+	}
+	return n.unit.name(base)
+}
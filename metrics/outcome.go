@@ -0,0 +1,50 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the function that calculates the outcome of requests.
+
+package metrics
+
+// Values of the `outcome` label:
+const (
+	// OutcomeSuccess is used when the response code is less than 400.
+	OutcomeSuccess = "success"
+
+	// OutcomeClientError is used when the response code is between 400 and 499.
+	OutcomeClientError = "client_error"
+
+	// OutcomeServerError is used when the response code is 500 or greater.
+	OutcomeServerError = "server_error"
+
+	// OutcomeTransportError is used when there is no response code, for example when it wasn't
+	// possible to connect to the server or when there was a timeout waiting for the response.
+	OutcomeTransportError = "transport_error"
+)
+
+// outcomeLabel calculates the `outcome` label from the response code and the error returned when
+// sending the request. A zero code means that there was no response.
+func outcomeLabel(code int, err error) string {
+	switch {
+	case err != nil || code == 0:
+		return OutcomeTransportError
+	case code >= 500:
+		return OutcomeServerError
+	case code >= 400:
+		return OutcomeClientError
+	default:
+		return OutcomeSuccess
+	}
+}
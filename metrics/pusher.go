@@ -0,0 +1,296 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the implementation of the object that pushes the metrics to a Prometheus
+// Pushgateway.
+
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+
+	"github.com/openshift-online/ocm-sdk-go/logging"
+)
+
+// DefaultPushInterval is the default interval between pushes of the metrics.
+const DefaultPushInterval = 15 * time.Second
+
+// PusherBuilder contains the data and logic needed to create an object that periodically pushes
+// the metrics to a Prometheus Pushgateway. This is intended for short lived jobs that exit before
+// Prometheus can scrape them. Typical usage, pushing the metrics generated by a transport wrapper,
+// is like this:
+//
+//	pusher, err := metrics.NewPusher().
+//		Logger(logger).
+//		URL("http://pushgateway:9091").
+//		Job("my_job").
+//		Instance("my_instance").
+//		Registerer(wrapper.Registerer()).
+//		Build(ctx)
+//	if err != nil {
+//		...
+//	}
+//	defer pusher.Shutdown(ctx)
+//
+// The Shutdown method pushes the metrics one last time, so that the values that changed since the
+// last periodic push aren't lost when the job exits.
+//
+// Don't create objects of this type directly; use the NewPusher function instead.
+type PusherBuilder struct {
+	logger     logging.Logger
+	url        string
+	job        string
+	grouping   [][2]string
+	registerer prometheus.Registerer
+	interval   time.Duration
+	client     *http.Client
+}
+
+// Pusher pushes the metrics to a Prometheus Pushgateway.
+type Pusher struct {
+	logger   logging.Logger
+	url      string
+	job      string
+	grouping [][2]string
+	gatherer prometheus.Gatherer
+	client   *http.Client
+	lock     sync.Mutex
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewPusher creates a builder that can then be used to configure and create a metrics pusher.
+func NewPusher() *PusherBuilder {
+	return &PusherBuilder{
+		registerer: prometheus.DefaultRegisterer,
+		interval:   DefaultPushInterval,
+	}
+}
+
+// Logger sets the logger that the pusher will use to write to the log. This is mandatory.
+func (b *PusherBuilder) Logger(value logging.Logger) *PusherBuilder {
+	b.logger = value
+	return b
+}
+
+// URL sets the URL of the Pushgateway, for example `http://pushgateway:9091`. This is mandatory.
+func (b *PusherBuilder) URL(value string) *PusherBuilder {
+	b.url = value
+	return b
+}
+
+// Job sets the value of the `job` label that will be used to group the metrics in the
+// Pushgateway. This is mandatory.
+func (b *PusherBuilder) Job(value string) *PusherBuilder {
+	b.job = value
+	return b
+}
+
+// Instance sets the value of the `instance` label that will be used to group the metrics in the
+// Pushgateway. This is a shorthand for Grouping("instance", value). The default is to not use this
+// label.
+func (b *PusherBuilder) Instance(value string) *PusherBuilder {
+	return b.Grouping("instance", value)
+}
+
+// Grouping adds a label that will be used, together with the `job` label, to group the metrics in
+// the Pushgateway. Pushes replace all the metrics that have the same grouping labels, so jobs that
+// run concurrently should use different values.
+func (b *PusherBuilder) Grouping(name, value string) *PusherBuilder {
+	b.grouping = append(b.grouping, [2]string{name, value})
+	return b
+}
+
+// Registerer sets the Prometheus registerer that contains the metrics that will be pushed. This
+// should usually be the same registerer that was used to build the metrics wrappers, which can be
+// obtained with their Registerer methods. It also needs to implement the prometheus.Gatherer
+// interface, like the *prometheus.Registry type does. The default is to use the default Prometheus
+// registerer. Passing nil restores the default.
+func (b *PusherBuilder) Registerer(value prometheus.Registerer) *PusherBuilder {
+	if value == nil {
+		value = prometheus.DefaultRegisterer
+	}
+	b.registerer = value
+	return b
+}
+
+// Interval sets the interval between pushes. Zero means that the metrics will only be pushed when
+// the Push or Shutdown methods are called. The default is 15 seconds.
+func (b *PusherBuilder) Interval(value time.Duration) *PusherBuilder {
+	b.interval = value
+	return b
+}
+
+// Client sets the HTTP client that will be used to send the metrics to the Pushgateway. The
+// default is to use a new client with the default settings.
+func (b *PusherBuilder) Client(value *http.Client) *PusherBuilder {
+	b.client = value
+	return b
+}
+
+// Build uses the data stored in the builder to create a new metrics pusher. If the interval isn't
+// zero the periodic pushes will already be running when this method returns, and they will use the
+// given context.
+func (b *PusherBuilder) Build(ctx context.Context) (result *Pusher, err error) {
+	// Check parameters:
+	if b.logger == nil {
+		err = fmt.Errorf("logger is mandatory")
+		return
+	}
+	if b.url == "" {
+		err = fmt.Errorf("URL is mandatory")
+		return
+	}
+	if b.job == "" {
+		err = fmt.Errorf("job is mandatory")
+		return
+	}
+	if b.interval < 0 {
+		err = fmt.Errorf("interval should be zero or positive, but it is %s", b.interval)
+		return
+	}
+	for _, label := range b.grouping {
+		if label[0] == "" {
+			err = fmt.Errorf("grouping label name can't be empty")
+			return
+		}
+		if label[1] == "" {
+			err = fmt.Errorf("value of grouping label '%s' can't be empty", label[0])
+			return
+		}
+	}
+
+	// Find the gatherer corresponding to the registerer:
+	gatherer, err := gathererFor(b.registerer)
+	if err != nil {
+		return
+	}
+
+	// Create the default client if needed:
+	client := b.client
+	if client == nil {
+		client = &http.Client{}
+	}
+
+	// Create and populate the object:
+	result = &Pusher{
+		logger:   b.logger,
+		url:      b.url,
+		job:      b.job,
+		grouping: append([][2]string{}, b.grouping...),
+		gatherer: gatherer,
+		client:   client,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	// Start the periodic pushes:
+	if b.interval > 0 {
+		go result.run(ctx, b.interval)
+	} else {
+		close(result.done)
+	}
+
+	return
+}
+
+// run pushes the metrics periodically till the pusher is shut down.
+func (p *Pusher) run(ctx context.Context, interval time.Duration) {
+	defer close(p.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			err := p.Push(ctx)
+			if err != nil {
+				p.logger.Error(ctx, "%v", err)
+			}
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// Push pushes the metrics to the Pushgateway now, replacing the metrics previously pushed with the
+// same grouping labels.
+func (p *Pusher) Push(ctx context.Context) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	pusher := push.New(p.url, p.job).
+		Gatherer(p.gatherer).
+		Client(&contextClient{
+			ctx:    ctx,
+			client: p.client,
+		})
+	for _, label := range p.grouping {
+		pusher.Grouping(label[0], label[1])
+	}
+	err := pusher.Push()
+	if err != nil {
+		return fmt.Errorf("can't push metrics to '%s': %w", p.url, err)
+	}
+	return nil
+}
+
+// Shutdown stops the periodic pushes and then pushes the metrics one last time. Calling it more
+// than once only pushes the metrics again.
+func (p *Pusher) Shutdown(ctx context.Context) error {
+	p.lock.Lock()
+	select {
+	case <-p.stop:
+	default:
+		close(p.stop)
+	}
+	p.lock.Unlock()
+	<-p.done
+	return p.Push(ctx)
+}
+
+// contextClient is an implementation of the push.HTTPDoer interface that adds a context to the
+// requests, as the Pushgateway client doesn't support contexts.
+type contextClient struct {
+	ctx    context.Context
+	client *http.Client
+}
+
+// Do is the implementation of the push.HTTPDoer interface.
+func (c *contextClient) Do(request *http.Request) (*http.Response, error) {
+	return c.client.Do(request.WithContext(c.ctx))
+}
+
+// gathererFor finds the gatherer corresponding to the given registerer.
+func gathererFor(registerer prometheus.Registerer) (result prometheus.Gatherer, err error) {
+	if registerer == prometheus.DefaultRegisterer {
+		result = prometheus.DefaultGatherer
+		return
+	}
+	result, ok := registerer.(prometheus.Gatherer)
+	if !ok {
+		err = fmt.Errorf(
+			"registerer of type '%T' can't be used to gather metrics",
+			registerer,
+		)
+	}
+	return
+}
@@ -0,0 +1,247 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains tests for the metrics pusher.
+
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+
+	dto "github.com/prometheus/client_model/go"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core" // nolint
+	. "github.com/onsi/gomega"             // nolint
+	. "github.com/onsi/gomega/ghttp"       // nolint
+)
+
+var _ = Describe("Pusher", func() {
+	var (
+		ctx      context.Context
+		gateway  *Server
+		registry *prometheus.Registry
+		counter  prometheus.Counter
+	)
+
+	BeforeEach(func() {
+		// Create the context:
+		ctx = context.Background()
+
+		// Create the server that simulates the Pushgateway:
+		gateway = NewServer()
+
+		// Create the registry and a metric:
+		registry = prometheus.NewRegistry()
+		counter = prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "my_count",
+			Help: "My count.",
+		})
+		registry.MustRegister(counter)
+	})
+
+	AfterEach(func() {
+		gateway.Close()
+	})
+
+	// Values returns a handler that decodes the metrics sent in the body of the request and
+	// sends the value of the `my_count` metric to the given channel.
+	var Values = func(values chan<- float64) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			defer GinkgoRecover()
+			decoder := expfmt.NewDecoder(r.Body, expfmt.ResponseFormat(r.Header))
+			for {
+				var family dto.MetricFamily
+				err := decoder.Decode(&family)
+				if err != nil {
+					break
+				}
+				if family.GetName() == "my_count" {
+					values <- family.GetMetric()[0].GetCounter().GetValue()
+				}
+			}
+			w.WriteHeader(http.StatusOK)
+		}
+	}
+
+	It("Can't be created without a logger", func() {
+		pusher, err := NewPusher().
+			URL(gateway.URL()).
+			Job("my_job").
+			Build(ctx)
+		Expect(err).To(HaveOccurred())
+		Expect(pusher).To(BeNil())
+		Expect(err.Error()).To(ContainSubstring("logger is mandatory"))
+	})
+
+	It("Can't be created without a URL", func() {
+		pusher, err := NewPusher().
+			Logger(logger).
+			Job("my_job").
+			Build(ctx)
+		Expect(err).To(HaveOccurred())
+		Expect(pusher).To(BeNil())
+		Expect(err.Error()).To(ContainSubstring("URL is mandatory"))
+	})
+
+	It("Can't be created without a job", func() {
+		pusher, err := NewPusher().
+			Logger(logger).
+			URL(gateway.URL()).
+			Build(ctx)
+		Expect(err).To(HaveOccurred())
+		Expect(pusher).To(BeNil())
+		Expect(err.Error()).To(ContainSubstring("job is mandatory"))
+	})
+
+	It("Can't be created with a negative interval", func() {
+		pusher, err := NewPusher().
+			Logger(logger).
+			URL(gateway.URL()).
+			Job("my_job").
+			Interval(-time.Second).
+			Build(ctx)
+		Expect(err).To(HaveOccurred())
+		Expect(pusher).To(BeNil())
+		Expect(err.Error()).To(ContainSubstring("interval"))
+	})
+
+	It("Can't be created with an empty grouping label value", func() {
+		pusher, err := NewPusher().
+			Logger(logger).
+			URL(gateway.URL()).
+			Job("my_job").
+			Grouping("my_label", "").
+			Build(ctx)
+		Expect(err).To(HaveOccurred())
+		Expect(pusher).To(BeNil())
+		Expect(err.Error()).To(ContainSubstring("my_label"))
+	})
+
+	It("Can't be created with a registerer that isn't a gatherer", func() {
+		pusher, err := NewPusher().
+			Logger(logger).
+			URL(gateway.URL()).
+			Job("my_job").
+			Registerer(prometheus.WrapRegistererWithPrefix("my_", registry)).
+			Build(ctx)
+		Expect(err).To(HaveOccurred())
+		Expect(pusher).To(BeNil())
+		Expect(err.Error()).To(ContainSubstring("gather"))
+	})
+
+	It("Pushes to the path of the job and grouping labels", func() {
+		// Prepare the gateway. Note that the order of the grouping labels in the path isn't
+		// predictable, as the Pushgateway client stores them in a map.
+		values := make(chan float64, 1)
+		gateway.AppendHandlers(CombineHandlers(
+			VerifyRequest(http.MethodPut, HavePrefix("/metrics/job/my_job/")),
+			func(w http.ResponseWriter, r *http.Request) {
+				segments := strings.Split(
+					strings.TrimPrefix(r.URL.Path, "/metrics/job/my_job/"),
+					"/",
+				)
+				Expect(segments).To(HaveLen(4))
+				var pairs []string
+				for i := 0; i < len(segments); i += 2 {
+					pairs = append(pairs, segments[i]+"="+segments[i+1])
+				}
+				Expect(pairs).To(ConsistOf(
+					"instance=my_instance",
+					"my_label=my_value",
+				))
+			},
+			Values(values),
+		))
+
+		// Push the metrics:
+		pusher, err := NewPusher().
+			Logger(logger).
+			URL(gateway.URL()).
+			Job("my_job").
+			Instance("my_instance").
+			Grouping("my_label", "my_value").
+			Registerer(registry).
+			Interval(0).
+			Build(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		counter.Add(42)
+		err = pusher.Push(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(values).To(Receive(Equal(42.0)))
+	})
+
+	It("Pushes periodically and on shutdown", func() {
+		// Prepare the gateway so that it accepts any number of pushes:
+		var lock sync.Mutex
+		values := make(chan float64, 100)
+		handler := Values(values)
+		gateway.RouteToHandler(
+			http.MethodPut,
+			"/metrics/job/my_job",
+			func(w http.ResponseWriter, r *http.Request) {
+				lock.Lock()
+				defer lock.Unlock()
+				handler(w, r)
+			},
+		)
+
+		// Create the pusher:
+		pusher, err := NewPusher().
+			Logger(logger).
+			URL(gateway.URL()).
+			Job("my_job").
+			Registerer(registry).
+			Interval(10 * time.Millisecond).
+			Build(ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		// Wait for a periodic push:
+		counter.Add(1)
+		Eventually(values).Should(Receive(Equal(1.0)))
+
+		// Shut down, and verify that the last value is pushed:
+		counter.Add(1)
+		err = pusher.Shutdown(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		var last float64
+		for len(values) > 0 {
+			last = <-values
+		}
+		Expect(last).To(Equal(2.0))
+	})
+
+	It("Returns error if the gateway rejects the metrics", func() {
+		gateway.AppendHandlers(RespondWith(http.StatusBadRequest, "my error"))
+		pusher, err := NewPusher().
+			Logger(logger).
+			URL(gateway.URL()).
+			Job("my_job").
+			Registerer(registry).
+			Interval(0).
+			Build(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		err = pusher.Push(ctx)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("can't push metrics"))
+	})
+})
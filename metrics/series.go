@@ -0,0 +1,84 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the functions that count the series generated by the wrappers.
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/openshift-online/ocm-sdk-go/internal"
+)
+
+// seriesCounter counts the distinct combinations of label values of a set of metrics.
+type seriesCounter struct {
+	collectors []prometheus.Collector
+}
+
+// newSeriesCounter creates a counter for the given metrics, ignoring the ones that are nil because
+// they haven't been enabled.
+func newSeriesCounter(counters []*prometheus.CounterVec,
+	histograms []*prometheus.HistogramVec) *seriesCounter {
+	var collectors []prometheus.Collector
+	for _, counter := range counters {
+		if counter != nil {
+			collectors = append(collectors, counter)
+		}
+	}
+	for _, histogram := range histograms {
+		if histogram != nil {
+			collectors = append(collectors, histogram)
+		}
+	}
+	return &seriesCounter{
+		collectors: collectors,
+	}
+}
+
+// count returns the current number of series. Each child of a histogram is counted once, not once
+// per bucket. This is called only when the gauge is collected, so the cost is paid by the scrape
+// and not by the requests.
+func (c *seriesCounter) count() float64 {
+	channel := make(chan prometheus.Metric)
+	go func() {
+		defer close(channel)
+		for _, collector := range c.collectors {
+			collector.Collect(channel)
+		}
+	}()
+	total := 0
+	for range channel {
+		total++
+	}
+	return float64(total)
+}
+
+// registerSeriesCount registers the gauge that reports the number of series generated by the given
+// metrics of a wrapper.
+func registerSeriesCount(registerer prometheus.Registerer, subsystem string,
+	counter *seriesCounter) error {
+	gauge := prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      "metric_series_count",
+			Help:      "Number of series generated by the request metrics.",
+		},
+		counter.count,
+	)
+	_, err := internal.RegisterGaugeFunc(registerer, subsystem+"_metric_series_count", gauge)
+	return err
+}
@@ -0,0 +1,141 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the types and functions used to take snapshots of the values of the metrics.
+
+package metrics
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Snapshot contains the values of the request metrics for a set of labels, at the moment that the
+// snapshot was taken.
+type Snapshot struct {
+	// Count is the value of the request count metric.
+	Count float64
+
+	// DurationCount is the number of observations of the request duration metric.
+	DurationCount uint64
+
+	// DurationSum is the sum of the observations of the request duration metric, in the unit
+	// configured with the DurationUnit method of the builder.
+	DurationSum float64
+}
+
+// Snapshot returns the current values of the request count and duration metrics for the requests
+// whose labels contain the given ones. Labels that aren't given match any value, so passing an
+// empty set of labels returns the totals for all requests. For example, to get the number of GET
+// requests that returned 200:
+//
+//	snapshot, err := wrapper.Snapshot(map[string]string{
+//		"method": "GET",
+//		"code":   "200",
+//	})
+//
+// This is intended for unit tests, where it is simpler than scraping the metrics server and
+// parsing the result. Note that wrappers that use the same subsystem and registerer share the
+// metrics, so the values will include the requests processed by all of them. When the
+// FlushInterval option is enabled the pending observations of this wrapper are flushed first.
+func (w *TransportWrapper) Snapshot(labels map[string]string) (result Snapshot, err error) {
+	w.Flush()
+	result, err = snapshot(w.labelNames, w.requestCount, w.requestDuration, labels)
+	return
+}
+
+// Snapshot returns the current values of the request count and duration metrics for the requests
+// whose labels contain the given ones. See the Snapshot method of the transport wrapper for
+// details.
+func (w *HandlerWrapper) Snapshot(labels map[string]string) (result Snapshot, err error) {
+	result, err = snapshot(w.labelNames, w.requestCount, w.requestDuration, labels)
+	return
+}
+
+// snapshot calculates the snapshot of the given metrics.
+func snapshot(names []string, count *prometheus.CounterVec, duration *prometheus.HistogramVec,
+	labels map[string]string) (result Snapshot, err error) {
+	// Check that the labels are known:
+	known := map[string]bool{}
+	for _, name := range names {
+		known[name] = true
+	}
+	for name := range labels {
+		if !known[name] {
+			err = fmt.Errorf("label '%s' isn't used by the request metrics", name)
+			return
+		}
+	}
+
+	// Add the values of the matching series:
+	err = collect(count, labels, func(metric *dto.Metric) {
+		result.Count += metric.GetCounter().GetValue()
+	})
+	if err != nil {
+		return
+	}
+	err = collect(duration, labels, func(metric *dto.Metric) {
+		result.DurationCount += metric.GetHistogram().GetSampleCount()
+		result.DurationSum += metric.GetHistogram().GetSampleSum()
+	})
+	return
+}
+
+// collect calls the given function for each of the series of the collector whose labels contain
+// the given ones.
+func collect(collector prometheus.Collector, labels map[string]string,
+	process func(*dto.Metric)) error {
+	metrics := make(chan prometheus.Metric)
+	go func() {
+		collector.Collect(metrics)
+		close(metrics)
+	}()
+	var err error
+	for metric := range metrics {
+		// Note that we need to continue reading from the channel even after an error,
+		// otherwise the goroutine that writes to it will never finish.
+		if err != nil {
+			continue
+		}
+		data := &dto.Metric{}
+		err = metric.Write(data)
+		if err != nil {
+			continue
+		}
+		if matches(data, labels) {
+			process(data)
+		}
+	}
+	return err
+}
+
+// matches checks if the labels of the given series contain the given ones.
+func matches(metric *dto.Metric, labels map[string]string) bool {
+	count := 0
+	for _, pair := range metric.GetLabel() {
+		value, ok := labels[pair.GetName()]
+		if !ok {
+			continue
+		}
+		if value != pair.GetValue() {
+			return false
+		}
+		count++
+	}
+	return count == len(labels)
+}
@@ -0,0 +1,111 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the functions that measure the reuse of TLS sessions by new connections.
+
+package metrics
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultTLSResumptionWindow is the default number of TLS handshakes used to calculate the session
+// resumption ratio that is compared to the threshold.
+const DefaultTLSResumptionWindow = 100
+
+// tlsResumptionChecker calculates the ratio of TLS handshakes that resumed a session, for
+// consecutive windows of handshakes, and writes a warning to the log when it is below the
+// threshold.
+type tlsResumptionChecker struct {
+	threshold float64
+	window    int
+	lock      sync.Mutex
+	total     int
+	resumed   int
+}
+
+// traceTLS returns a context derived from the given one that contains the trace hook that updates
+// the TLS session resumption metric for a request to the given service. The hook is only called
+// by the transport when it opens a new TLS connection, so requests that reuse an existing
+// connection aren't counted.
+func (w *TransportWrapper) traceTLS(ctx context.Context, service string) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			if err != nil {
+				return
+			}
+			w.countHandshake(ctx, service, state.DidResume)
+		},
+	})
+}
+
+// countHandshake updates the TLS session resumption metric and checks the ratio against the
+// threshold.
+func (w *TransportWrapper) countHandshake(ctx context.Context, service string, resumed bool) {
+	value := "false"
+	if resumed {
+		value = "true"
+	}
+	w.tlsHandshakes.With(w.renames.labels(prometheus.Labels{
+		serviceLabelName: service,
+		resumedLabelName: value,
+	})).Inc()
+	if w.tlsChecker == nil {
+		return
+	}
+	ratio, done := w.tlsChecker.add(resumed)
+	if done && ratio < w.tlsChecker.threshold {
+		w.logger.Warn(
+			ctx,
+			"Only %.0f%% of the last %d TLS handshakes resumed a session, which is "+
+				"below the threshold of %.0f%%, check that the TLS session cache is "+
+				"configured and that the server supports session resumption",
+			ratio*100, w.tlsChecker.window, w.tlsChecker.threshold*100,
+		)
+	}
+}
+
+// add records a handshake. When the handshake completes a window it returns the resumption ratio
+// of that window and true, and starts a new window.
+func (c *tlsResumptionChecker) add(resumed bool) (ratio float64, done bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.total++
+	if resumed {
+		c.resumed++
+	}
+	if c.total < c.window {
+		return
+	}
+	ratio = float64(c.resumed) / float64(c.total)
+	done = true
+	c.total = 0
+	c.resumed = 0
+	return
+}
+
+// reset discards the handshakes of the current window.
+func (c *tlsResumptionChecker) reset() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.total = 0
+	c.resumed = 0
+}
@@ -19,11 +19,22 @@ limitations under the License.
 package metrics
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/openshift-online/ocm-sdk-go/clock"
+	"github.com/openshift-online/ocm-sdk-go/internal"
+	"github.com/openshift-online/ocm-sdk-go/logging"
+	"github.com/openshift-online/ocm-sdk-go/metrics/core"
+	"github.com/openshift-online/ocm-sdk-go/retry"
+	"github.com/openshift-online/ocm-sdk-go/tenancy"
 )
 
 // TransportWrapperBuilder contains the data and logic needed to build a new metrics transport
@@ -46,12 +57,36 @@ import (
 //	path - Request path, for example /api/clusters_mgmt/v1/clusters.
 //	code - HTTP response code, for example 200 or 500.
 //	apiservice - API service name, for example ocm-clusters-service.
+//	class - Request class calculated by the classifier, only when set with the Classifier method.
+//	attempt - Attempt number set by the retry wrapper, only when enabled with the Attempts method.
+//	outcome - One of `success`, `client_error`, `server_error` or `transport_error`, only when
+//	enabled with the Outcome method.
+//	caller - Package that sent the request, only when enabled with the Caller method.
+//	idempotent - `true` if the method of the request is idempotent, for example GET or PUT, and
+//	`false` otherwise, for example POST or PATCH, only when enabled with the Idempotent method.
+//	organization - Organization set with the tenancy.WithOrganization function, or `unknown`,
+//	only when enabled with the Organization method.
+//
+// In addition the metrics will have the labels declared with the ContextLabel and ContextLabels
+// methods, with the values set with the WithLabels function.
 //
 // To calculate the average request duration during the last 10 minutes, for example, use a
 // Prometheus expression like this:
 //
 //	rate(api_outbound_request_duration_sum[10m]) / rate(api_outbound_request_duration_count[10m])
 //
+// To calculate the success rate of requests for each API service it is convenient to enable the
+// `outcome` label with the Outcome method, and then use a Prometheus expression like this:
+//
+//	sum by (apiservice) (rate(api_outbound_request_count{outcome="success"}[10m])) /
+//	sum by (apiservice) (rate(api_outbound_request_count[10m]))
+//
+// Note that requests that fail without a response, for example when the connection can't be
+// established, are included in the total with the `transport_error` outcome, so they reduce the
+// success rate. The same can be done with the `code` label, using a regular expression like
+// `code=~"[1-3].."`, but in that case requests without a response are only counted in the
+// total, with code zero.
+//
 // In order to reduce the cardinality of the metrics the path label is modified to remove the
 // identifiers of the objects. For example, if the original path is .../clusters/123 then it will
 // be replaced by .../clusters/-, and the values will be accumulated. The line returned by the
@@ -70,19 +105,87 @@ import (
 // Note that setting this attribute is not enough to have metrics published, you also need to
 // create and start a metrics server, as described in the documentation of the Prometheus library.
 //
+// The metrics are registered with the default Prometheus registerer unless a different one is
+// set with the Registerer method, so when there is no need for a separate registry this is enough:
+//
+//	wrapper, err := metrics.NewTransportWrapper().
+//		Subsystem("my").
+//		Build()
+//
+// The metrics will then be published by the handler returned by the promhttp.Handler function,
+// usually in the `/metrics` path.
+//
 // Don't create objects of this type directly; use the NewTransportWrapper function instead.
 type TransportWrapperBuilder struct {
-	paths      []string
-	subsystem  string
-	registerer prometheus.Registerer
+	paths        []string
+	subsystem    string
+	registerer   prometheus.Registerer
+	exclusive    bool
+	clock        clock.Clock
+	durationUnit DurationUnit
+	openMetrics  bool
+	redirects    bool
+	redirectHops bool
+	classifier   Classifier
+	classLimit   int
+	org          bool
+	orgLimit     int
+	attempts     bool
+	outcome      bool
+	caller       bool
+	idempotent   bool
+	stuckAfter   time.Duration
+	bodyRead     bool
+	bodyTimeout  time.Duration
+	dns          bool
+	tls          bool
+	tlsThreshold float64
+	tlsWindow    int
+	logger       logging.Logger
+	bytes        bool
+	decodeErrors bool
+	seriesCount  bool
+	flush        time.Duration
+	renames      labelRenames
+	extraLabels  []string
 }
 
 // TransportWrapper contains the data and logic needed to wrap an HTTP round tripper with another
 // one that generates Prometheus metrics.
 type TransportWrapper struct {
-	paths           pathTree
+	paths           core.PathTree
+	metricNames     []string
+	registerer      prometheus.Registerer
+	clock           clock.Clock
+	durationUnit    DurationUnit
+	labelNames      []string
 	requestCount    *prometheus.CounterVec
 	requestDuration *prometheus.HistogramVec
+	redirectCount   *prometheus.CounterVec
+	redirectHops    bool
+	classifier      Classifier
+	classes         *classSet
+	orgs            *classSet
+	attempts        bool
+	outcome         bool
+	caller          bool
+	idempotent      bool
+	stuckAfter      time.Duration
+	stuckCount      *prometheus.CounterVec
+	bodyDuration    *prometheus.HistogramVec
+	bodyTimeout     time.Duration
+	bodyTimeouts    *prometheus.CounterVec
+	dnsCount        *prometheus.CounterVec
+	dnsDuration     *prometheus.HistogramVec
+	tlsHandshakes   *prometheus.CounterVec
+	tlsChecker      *tlsResumptionChecker
+	logger          logging.Logger
+	bytesSent       *prometheus.CounterVec
+	bytesReceived   *prometheus.CounterVec
+	decodeErrors    *prometheus.CounterVec
+	batcher         *batcher
+	renames         labelRenames
+	extraLabels     []string
 }
 
 // roundTripper is a round tripper that generates Prometheus metrics.
@@ -99,6 +202,11 @@ var _ http.RoundTripper = (*roundTripper)(nil)
 func NewTransportWrapper() *TransportWrapperBuilder {
 	return &TransportWrapperBuilder{
 		registerer: prometheus.DefaultRegisterer,
+		clock:      clock.Real,
+		classLimit: DefaultClassLimit,
+		orgLimit:   DefaultOrganizationLimit,
+		tlsWindow:  DefaultTLSResumptionWindow,
+		renames:    labelRenames{},
 	}
 }
 
@@ -129,7 +237,8 @@ func (b *TransportWrapperBuilder) Subsystem(value string) *TransportWrapperBuild
 // Registerer sets the Prometheus registerer that will be used to register the metrics. The default
 // is to use the default Prometheus registerer and there is usually no need to change that. This is
 // intended for unit tests, where it is convenient to have a registerer that doesn't interfere with
-// the rest of the system.
+// the rest of the system, and for applications that use their own registry. Passing nil restores
+// the default.
 func (b *TransportWrapperBuilder) Registerer(value prometheus.Registerer) *TransportWrapperBuilder {
 	if value == nil {
 		value = prometheus.DefaultRegisterer
@@ -138,6 +247,411 @@ func (b *TransportWrapperBuilder) Registerer(value prometheus.Registerer) *Trans
 	return b
 }
 
+// Exclusive makes the Build method fail if any of the metrics is already registered, for example
+// by another wrapper that uses the same subsystem and registerer. The default is to share the
+// metrics that are already registered with the same labels, which is convenient for applications
+// that create multiple wrappers on purpose, but that silently mixes the series of wrappers that
+// use the same subsystem by mistake.
+func (b *TransportWrapperBuilder) Exclusive(value bool) *TransportWrapperBuilder {
+	b.exclusive = value
+	return b
+}
+
+// Redirects enables the metric that counts the redirects followed by the HTTP client:
+//
+//	<subsystem>_redirect_count - Number of redirects followed.
+//
+// The labels of this metric are calculated from the original request, the one that the HTTP client
+// sent before following any redirect, except the `code` label that contains the code of the
+// redirect response, for example 302. The default is to not generate this metric.
+func (b *TransportWrapperBuilder) Redirects(value bool) *TransportWrapperBuilder {
+	b.redirects = value
+	return b
+}
+
+// RedirectHops adds to the redirect count metric a `hops` label containing the position of the
+// redirect in the chain of redirects followed for the original request. For example, the value
+// will be 1 for the first redirect and 2 for the second. This is useful to detect redirect loops
+// and chains of multiple redirects. Note that this has no effect unless the redirect count metric
+// is enabled with the Redirects method.
+func (b *TransportWrapperBuilder) RedirectHops(value bool) *TransportWrapperBuilder {
+	b.redirectHops = value
+	return b
+}
+
+// Clock sets the clock that will be used to measure the duration of requests. The default is to
+// use the real clock of the system. This is intended for unit tests that need to control time in
+// order to check precisely the measured durations.
+func (b *TransportWrapperBuilder) Clock(value clock.Clock) *TransportWrapperBuilder {
+	if value == nil {
+		value = clock.Real
+	}
+	b.clock = value
+	return b
+}
+
+// DurationUnit sets the unit used to record the request durations. The default is to use seconds.
+// When a different unit is used the name of the duration metric will have the name of the unit as
+// suffix, and the buckets will be scaled accordingly. For example, if the subsystem is `my` and the
+// unit is DurationUnitMilliseconds then the metric will be `my_request_duration_milliseconds` and
+// the buckets will be 100, 1000, 10000 and 30000.
+func (b *TransportWrapperBuilder) DurationUnit(value DurationUnit) *TransportWrapperBuilder {
+	b.durationUnit = value
+	return b
+}
+
+// Classifier sets a function that will be called for each request to calculate the value of a
+// `class` label that will be added to the request count and duration metrics. This is intended to
+// make visible categories of requests that aren't visible in the URL. For example, to separate
+// interactive and batch requests using a custom header:
+//
+//	wrapper, err := metrics.NewTransportWrapper().
+//		Subsystem("my").
+//		Classifier(func(request *http.Request) string {
+//			if request.Header.Get("X-Batch") != "" {
+//				return "batch"
+//			}
+//			return "interactive"
+//		}).
+//		Build()
+//
+// The function should return a small set of values. As a safety net the number of distinct values
+// is limited, see the ClassLimit method for details. The default is to not add the `class` label.
+func (b *TransportWrapperBuilder) Classifier(value Classifier) *TransportWrapperBuilder {
+	b.classifier = value
+	return b
+}
+
+// ContextLabel declares a label that will be added to the request count and duration metrics, with
+// the value taken from the context of the request. The value is set with the WithLabels function,
+// and it will be empty for requests whose context doesn't contain it. Labels in the context whose
+// names haven't been declared with this method are ignored. This is intended for attributes of
+// requests that are known by the caller but not visible in the request itself, like the name of
+// the job that sends it. Like for the classifier, the caller is responsible for using a small set
+// of values.
+func (b *TransportWrapperBuilder) ContextLabel(name string) *TransportWrapperBuilder {
+	b.extraLabels = append(b.extraLabels, name)
+	return b
+}
+
+// ContextLabels declares a set of labels whose values will be taken from the context of the
+// request. See the documentation of the ContextLabel method for details.
+func (b *TransportWrapperBuilder) ContextLabels(names ...string) *TransportWrapperBuilder {
+	b.extraLabels = append(b.extraLabels, names...)
+	return b
+}
+
+// ClassLimit sets the maximum number of distinct values of the `class` label. Once this number of
+// values has been used requests with new values will be counted with the value `other`. The
+// default is 20. Note that this has no effect unless a classifier is set with the Classifier method.
+func (b *TransportWrapperBuilder) ClassLimit(value int) *TransportWrapperBuilder {
+	b.classLimit = value
+	return b
+}
+
+// Organization adds to the request count and duration metrics an `organization` label containing
+// the identifier of the organization stored in the context of the request with the
+// tenancy.WithOrganization function, or `unknown` if there is no such organization. This is
+// intended to attribute the requests of multi-tenant applications. As the number of organizations
+// can be large the number of distinct values is limited, see the OrganizationLimit method for
+// details. The default is to not add this label.
+func (b *TransportWrapperBuilder) Organization(value bool) *TransportWrapperBuilder {
+	b.org = value
+	return b
+}
+
+// OrganizationLimit sets the maximum number of distinct values of the `organization` label. Once
+// this number of values has been used requests from new organizations will be counted with the
+// value `other`. The default is 100. Note that this has no effect unless the label is enabled
+// with the Organization method.
+func (b *TransportWrapperBuilder) OrganizationLimit(value int) *TransportWrapperBuilder {
+	b.orgLimit = value
+	return b
+}
+
+// Attempts adds to the request count and duration metrics an `attempt` label containing the
+// attempt number that the retry wrapper stores in the context of the request, for example 1 for
+// the first attempt and 2 for the first retry. This is useful to distinguish the latency of first
+// attempts from the latency of retries. Note that for this to work the metrics wrapper needs to be
+// wrapped by the retry wrapper, otherwise the value of the label will always be zero. The default
+// is to not add this label.
+func (b *TransportWrapperBuilder) Attempts(value bool) *TransportWrapperBuilder {
+	b.attempts = value
+	return b
+}
+
+// Outcome adds to the request count and duration metrics an `outcome` label that summarizes the
+// result of the request. The value will be `success` when the response code is less than 400,
+// `client_error` when it is a 4xx code, `server_error` when it is a 5xx code, and
+// `transport_error` when there is no response at all, for example when it isn't possible to
+// connect to the server. This is intended to simplify the calculation of success rates. The
+// default is to not add this label.
+func (b *TransportWrapperBuilder) Outcome(value bool) *TransportWrapperBuilder {
+	b.outcome = value
+	return b
+}
+
+// Caller adds to the request count and duration metrics a `caller` label that contains the
+// package that sent the request, for example `github.com/my/project/pkg/reconciler`. The package
+// is calculated walking the stack of the goroutine that sends the request, and it is the first
+// one that isn't part of the Go standard library or of the SDK. This is useful to find out which
+// part of a large program is responsible for a certain volume of requests. Walking the stack has
+// a cost, so the information for each call site is cached, but the stack still needs to be
+// captured for each request. The default is to not add this label.
+func (b *TransportWrapperBuilder) Caller(value bool) *TransportWrapperBuilder {
+	b.caller = value
+	return b
+}
+
+// Idempotent adds to the request count and duration metrics an `idempotent` label that is `true`
+// when the method of the request is idempotent according to the HTTP specification, like GET,
+// HEAD, PUT and DELETE, and `false` otherwise, like POST and PATCH. This is useful to know how
+// much of the traffic can't be safely retried. Note that the label depends only on the method, so
+// POST requests that have an idempotency key are still counted as not idempotent. The default is
+// to not add this label.
+func (b *TransportWrapperBuilder) Idempotent(value bool) *TransportWrapperBuilder {
+	b.idempotent = value
+	return b
+}
+
+// StuckAfter enables the metric that counts requests that didn't complete after the given time:
+//
+//	<subsystem>_request_stuck - Number of requests that didn't complete in time.
+//
+// This is intended to detect requests that hang and never complete, and that would otherwise
+// never appear in the other metrics. Each request is counted at most once, and it isn't counted
+// again when it eventually completes. A request is considered complete when the response
+// headers are received or when it fails. The labels of this metric are the same as the labels
+// of the request count metric, except the `code` label, as there is no response code yet.
+//
+// Note that the watchdog uses the real time of the system, not the clock set with the Clock
+// method. The default is zero, which means that this metric isn't generated.
+func (b *TransportWrapperBuilder) StuckAfter(value time.Duration) *TransportWrapperBuilder {
+	b.stuckAfter = value
+	return b
+}
+
+// BodyReadDuration enables the metric that measures the time spent reading response bodies:
+//
+//	<subsystem>_body_read_duration_sum - Total time to read response bodies, in seconds.
+//	<subsystem>_body_read_duration_count - Total number of response bodies measured.
+//	<subsystem>_body_read_duration_bucket - Number of response bodies organized in buckets.
+//
+// The time is measured from the moment that the response headers are received till the moment
+// that the body is closed, so it includes the time that the caller spends processing the body.
+// This is intended to find out if latency is caused by the server or by the code that consumes
+// the response, as the request duration metric only covers the time till the response headers
+// are received. Responses whose body is never closed aren't measured. The labels and the unit of
+// this metric are the same as for the request duration metric. The default is to not generate
+// this metric.
+func (b *TransportWrapperBuilder) BodyReadDuration(value bool) *TransportWrapperBuilder {
+	b.bodyRead = value
+	return b
+}
+
+// BodyReadTimeout sets the maximum time that the wrapper will wait for response bodies to be closed
+// when measuring the time spent reading them, and enables the metric that counts the bodies that
+// weren't closed in time:
+//
+//	<subsystem>_body_read_timeout - Number of response bodies that weren't closed in time.
+//
+// This is a safety net for callers that never close the body, or that stall while reading it,
+// for example because they didn't set a deadline in the context. When the time expires the body
+// read duration metric, if enabled, records the timeout as the duration, and nothing is recorded
+// when the body is eventually closed. The body itself isn't affected, so the caller can still
+// read it. The labels of this metric are the same as the labels of the request count metric.
+//
+// Note that the timer uses the real time of the system, not the clock set with the Clock method.
+// The default is zero, which means that there is no timeout and that this metric isn't
+// generated.
+func (b *TransportWrapperBuilder) BodyReadTimeout(value time.Duration) *TransportWrapperBuilder {
+	b.bodyTimeout = value
+	return b
+}
+
+// DNS enables the metrics that measure the DNS lookups done to open new connections:
+//
+//	<subsystem>_dns_lookup_count - Number of new connections, with a `cached` label.
+//	<subsystem>_dns_lookup_duration_sum - Total time spent in DNS lookups, in seconds.
+//	<subsystem>_dns_lookup_duration_count - Total number of DNS lookups measured.
+//	<subsystem>_dns_lookup_duration_bucket - Number of DNS lookups organized in buckets.
+//
+// The value of the `cached` label is `false` when the transport had to do a DNS lookup to open the
+// connection, and `true` when the connection was opened without a lookup, for example because
+// the dialer uses an in-process DNS cache. The duration metric only includes the lookups actually
+// done by the transport. Requests that reuse an existing connection aren't counted. The
+// information is obtained with the hooks of the net/http/httptrace package, so this only works
+// when the wrapped transport supports them, like the default transport does. Both metrics have
+// the `apiservice` label, and the unit of the duration metric is the same as for the request
+// duration metric. This is intended to evaluate if a DNS cache is effective. The default is to
+// not generate these metrics.
+func (b *TransportWrapperBuilder) DNS(value bool) *TransportWrapperBuilder {
+	b.dns = value
+	return b
+}
+
+// TLSResumption enables the metric that counts the TLS handshakes done to open new connections:
+//
+//	<subsystem>_tls_handshake_count - Number of TLS handshakes, with a `resumed` label.
+//
+// The value of the `resumed` label is `true` when the handshake resumed a previous TLS session,
+// as reported by the DidResume field of the connection state, and `false` when it was a full
+// handshake. The resumption ratio can be calculated with a query like this:
+//
+//	sum(rate(my_tls_handshake_count{resumed="true"}[5m])) /
+//	sum(rate(my_tls_handshake_count[5m]))
+//
+// Handshakes that fail and requests that reuse an existing connection aren't counted. The
+// information is obtained with the hooks of the net/http/httptrace package, so this only works
+// when the wrapped transport supports them, like the default transport does. Note that sessions
+// can only be resumed if the TLS configuration of the transport has a client session cache. The
+// metric has the `apiservice` label. This is intended to detect regressions that silently
+// disable session resumption and increase latency. The default is to not generate this metric.
+func (b *TransportWrapperBuilder) TLSResumption(value bool) *TransportWrapperBuilder {
+	b.tls = value
+	return b
+}
+
+// TLSResumptionThreshold sets the minimum ratio of TLS handshakes that are expected to resume a
+// session, as a number between zero and one. When this is set the wrapper calculates the ratio
+// for each window of handshakes and writes a warning to the log when it is below the threshold.
+// This requires the logger and implies the TLSResumption option. The default is zero, which
+// means that there is no threshold.
+func (b *TransportWrapperBuilder) TLSResumptionThreshold(value float64) *TransportWrapperBuilder {
+	b.tlsThreshold = value
+	return b
+}
+
+// TLSResumptionWindow sets the number of TLS handshakes used to calculate the session resumption
+// ratio that is compared to the threshold. The default is 100.
+func (b *TransportWrapperBuilder) TLSResumptionWindow(value int) *TransportWrapperBuilder {
+	b.tlsWindow = value
+	return b
+}
+
+// Logger sets the logger that the wrapper will use to write warnings, for example when the TLS
+// session resumption ratio is below the threshold. This is mandatory only when the threshold is
+// set.
+func (b *TransportWrapperBuilder) Logger(value logging.Logger) *TransportWrapperBuilder {
+	b.logger = value
+	return b
+}
+
+// Bytes enables the metrics that count the bytes transferred in the bodies of requests and
+// responses:
+//
+//	<subsystem>_bytes_sent - Number of bytes sent in request bodies.
+//	<subsystem>_bytes_received - Number of bytes received in response bodies.
+//
+// The bytes sent are counted when the transport closes the request body, and the bytes received
+// when the caller closes the response body, so bodies that are never closed aren't counted. The
+// headers aren't included. These metrics have the `apiservice`, `method` and `path` labels. This
+// is intended to observe the use of metered network traffic. The default is to not generate
+// these metrics.
+func (b *TransportWrapperBuilder) Bytes(value bool) *TransportWrapperBuilder {
+	b.bytes = value
+	return b
+}
+
+// DecodeErrors enables the metric that counts the responses whose body can't be decoded:
+//
+//	<subsystem>_response_decode_error - Number of response bodies that couldn't be decoded.
+//
+// This metric has the `apiservice` and `reason` labels. The `reason` label is `eof` when the body
+// ends prematurely, `syntax` when it isn't valid JSON and `type` when a value doesn't have the
+// expected type. The wrapper checks that successful JSON response bodies are complete and valid
+// when they are closed, so `eof` and `syntax` errors are counted for all requests. Bodies larger
+// than one MiB aren't checked. Detecting `type` errors requires the schema, so those are only
+// counted when the code that decodes the body reports them with the helpers.ReportDecodeError
+// function. An error is counted at most once per request. This is intended to detect changes in
+// the schema of the server before they cause widespread breakage. The default is to not generate
+// this metric.
+func (b *TransportWrapperBuilder) DecodeErrors(value bool) *TransportWrapperBuilder {
+	b.decodeErrors = value
+	return b
+}
+
+// SeriesCount enables the gauge that reports the number of series generated by the wrapper:
+//
+//	<subsystem>_metric_series_count - Number of series generated by the request metrics.
+//
+// The value is the number of distinct combinations of label values of all the counters and
+// histograms of the wrapper, counting each combination of a histogram only once and not once per
+// bucket. It is calculated when the gauge is collected, so it doesn't add cost to the requests.
+// This is intended to budget the memory used by Prometheus, and to detect growth of cardinality,
+// for example when a classifier or a context label has more values than expected. Note that
+// when wrappers share metrics because they use the same subsystem and registerer the gauge is
+// also shared, and it counts the metrics of the first wrapper. The default is to not generate
+// this metric.
+func (b *TransportWrapperBuilder) SeriesCount(value bool) *TransportWrapperBuilder {
+	b.seriesCount = value
+	return b
+}
+
+// FlushInterval enables a mode where the observations of the request count and duration metrics
+// are accumulated in memory and added to the Prometheus collectors periodically, with the given
+// interval, instead of once per request. This is intended for applications that send a very high
+// number of concurrent requests, where updating the collectors that are shared by all the requests
+// becomes a point of contention. The observations are distributed among several buffers according
+// to their labels, each with its own lock, so concurrent requests with different labels rarely
+// have to wait for each other. Requests with the same labels share a buffer, but its lock is only
+// held while appending the observation.
+//
+// The price is freshness: the values of these metrics can be up to one interval behind the
+// requests that have already completed, so the interval should be much shorter than the scrape
+// interval of Prometheus, for example one second. The memory used by the buffers is proportional
+// to the number of requests completed during one interval. The rest of the metrics, like the
+// redirect count or the bytes sent and received, are always updated directly.
+//
+// When this is enabled the Close method must be called when the wrapper is no longer needed, to
+// stop the goroutine that flushes the buffers and to add the pending observations to the
+// collectors. Note that the goroutine uses the real time of the system, not the clock set with
+// the Clock method. The default is zero, which means that observations are added directly.
+func (b *TransportWrapperBuilder) FlushInterval(value time.Duration) *TransportWrapperBuilder {
+	b.flush = value
+	return b
+}
+
+// OpenMetrics selects the naming convention of the OpenMetrics specification. When enabled the
+// names of counters will have the `_total` suffix, for example `my_request_count_total` instead
+// of `my_request_count`, and the names of duration histograms will always have the unit suffix,
+// for example `my_request_duration_seconds` instead of `my_request_duration`. The default is to
+// use the original names, in order to not break existing dashboards and queries.
+func (b *TransportWrapperBuilder) OpenMetrics(value bool) *TransportWrapperBuilder {
+	b.openMetrics = value
+	return b
+}
+
+// APIServiceLabelName sets the name of the label that contains the API service name. The default
+// is `apiservice`. This is intended for teams that have existing dashboards and queries that use
+// different names. The name must be a valid Prometheus label name and it can't be the same as
+// the name of any other label.
+func (b *TransportWrapperBuilder) APIServiceLabelName(value string) *TransportWrapperBuilder {
+	b.renames[serviceLabelName] = value
+	return b
+}
+
+// MethodLabelName sets the name of the label that contains the HTTP method. The default is
+// `method`. See the APIServiceLabelName method for details.
+func (b *TransportWrapperBuilder) MethodLabelName(value string) *TransportWrapperBuilder {
+	b.renames[methodLabelName] = value
+	return b
+}
+
+// PathLabelName sets the name of the label that contains the request path. The default is `path`.
+// See the APIServiceLabelName method for details.
+func (b *TransportWrapperBuilder) PathLabelName(value string) *TransportWrapperBuilder {
+	b.renames[pathLabelName] = value
+	return b
+}
+
+// CodeLabelName sets the name of the label that contains the HTTP response code. The default is
+// `code`. See the APIServiceLabelName method for details.
+func (b *TransportWrapperBuilder) CodeLabelName(value string) *TransportWrapperBuilder {
+	b.renames[codeLabelName] = value
+	return b
+}
+
 // Build uses the information stored in the builder to create a new transport wrapper.
 func (b *TransportWrapperBuilder) Build() (result *TransportWrapper, err error) {
 	// Check parameters:
@@ -145,69 +659,552 @@ func (b *TransportWrapperBuilder) Build() (result *TransportWrapper, err error)
 		err = fmt.Errorf("subsystem is mandatory")
 		return
 	}
+	err = b.durationUnit.check()
+	if err != nil {
+		return
+	}
+	err = b.renames.check()
+	if err != nil {
+		return
+	}
+	err = b.renames.checkExtra(b.extraLabels)
+	if err != nil {
+		return
+	}
+	if b.stuckAfter < 0 {
+		err = fmt.Errorf(
+			"stuck request time should be zero or positive, but it is %s",
+			b.stuckAfter,
+		)
+		return
+	}
+	if b.flush < 0 {
+		err = fmt.Errorf(
+			"flush interval should be zero or positive, but it is %s",
+			b.flush,
+		)
+		return
+	}
+	if b.classLimit <= 0 {
+		err = fmt.Errorf(
+			"class limit should be greater than zero, but it is %d",
+			b.classLimit,
+		)
+		return
+	}
+	if b.orgLimit <= 0 {
+		err = fmt.Errorf(
+			"organization limit should be greater than zero, but it is %d",
+			b.orgLimit,
+		)
+		return
+	}
+	if b.bodyTimeout < 0 {
+		err = fmt.Errorf(
+			"body read timeout should be zero or positive, but it is %s",
+			b.bodyTimeout,
+		)
+		return
+	}
+	if b.tlsThreshold < 0 || b.tlsThreshold > 1 {
+		err = fmt.Errorf(
+			"TLS resumption threshold should be between zero and one, but it is %g",
+			b.tlsThreshold,
+		)
+		return
+	}
+	if b.tlsWindow <= 0 {
+		err = fmt.Errorf(
+			"TLS resumption window should be greater than zero, but it is %d",
+			b.tlsWindow,
+		)
+		return
+	}
+	if b.tlsThreshold > 0 && b.logger == nil {
+		err = fmt.Errorf("logger is mandatory when the TLS resumption threshold is set")
+		return
+	}
+
+	// Calculate the names of the labels of the request metrics:
+	labelNames := append([]string{}, requestLabelNames...)
+	var classes *classSet
+	if b.classifier != nil {
+		labelNames = append(labelNames, classLabelName)
+		classes = newClassSet(b.classLimit)
+	}
+	if b.attempts {
+		labelNames = append(labelNames, attemptLabelName)
+	}
+	if b.outcome {
+		labelNames = append(labelNames, outcomeLabelName)
+	}
+	if b.caller {
+		labelNames = append(labelNames, callerLabelName)
+	}
+	if b.idempotent {
+		labelNames = append(labelNames, idempotentLabelName)
+	}
+	var orgs *classSet
+	if b.org {
+		labelNames = append(labelNames, orgLabelName)
+		orgs = newClassSet(b.orgLimit)
+	}
+	labelNames = b.renames.names(labelNames)
+	labelNames = append(labelNames, b.extraLabels...)
 
-	// Register the request count metric:
+	// Calculate the names of the metrics:
+	names := metricNames{
+		unit:        b.durationUnit,
+		openMetrics: b.openMetrics,
+	}
+
+	// Use a registerer that rejects metrics that are already registered if exclusive mode has
+	// been requested:
+	registerer := b.registerer
+	if b.exclusive {
+		registerer = internal.ExclusiveRegisterer(registerer)
+	}
+
+	// Register the request count metric, remembering the names of all the metrics:
+	var metricNames []string
 	requestCount := prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Subsystem: b.subsystem,
-			Name:      "request_count",
+			Name:      names.counter("request_count"),
 			Help:      "Number of requests sent.",
 		},
-		requestLabelNames,
+		labelNames,
+	)
+	requestCount, err = internal.RegisterCounterVec(
+		registerer,
+		b.subsystem+"_"+names.counter("request_count"),
+		requestCount,
 	)
-	err = b.registerer.Register(requestCount)
 	if err != nil {
-		registered, ok := err.(prometheus.AlreadyRegisteredError)
-		if ok {
-			requestCount = registered.ExistingCollector.(*prometheus.CounterVec)
-			err = nil
-		} else {
-			return
-		}
+		return
 	}
+	metricNames = append(metricNames, b.subsystem+"_"+names.counter("request_count"))
 
 	// Create the path tree:
-	paths := pathRoot.copy()
+	paths := core.DefaultPaths()
 	for _, path := range b.paths {
-		paths.add(path)
+		paths.Add(path)
 	}
 
 	// Register the request duration metric:
 	requestDuration := prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Subsystem: b.subsystem,
-			Name:      "request_duration",
-			Help:      "Request duration in seconds.",
-			Buckets: []float64{
-				0.1,
-				1.0,
-				10.0,
-				30.0,
-			},
+			Name:      names.duration("request_duration"),
+			Help:      b.durationUnit.help("Request"),
+			Buckets:   b.durationUnit.buckets(),
 		},
-		requestLabelNames,
+		labelNames,
+	)
+	requestDuration, err = internal.RegisterHistogramVec(
+		registerer,
+		b.subsystem+"_"+names.duration("request_duration"),
+		requestDuration,
 	)
-	err = b.registerer.Register(requestDuration)
 	if err != nil {
-		registered, ok := err.(prometheus.AlreadyRegisteredError)
-		if ok {
-			requestDuration = registered.ExistingCollector.(*prometheus.HistogramVec)
-			err = nil
-		} else {
+		return
+	}
+	metricNames = append(metricNames, b.subsystem+"_"+names.duration("request_duration"))
+
+	// Register the redirect count metric:
+	var redirectCount *prometheus.CounterVec
+	if b.redirects {
+		redirectLabels := append([]string{}, requestLabelNames...)
+		if b.redirectHops {
+			redirectLabels = append(redirectLabels, hopsLabelName)
+		}
+		redirectLabels = b.renames.names(redirectLabels)
+		redirectCount = prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Subsystem: b.subsystem,
+				Name:      names.counter("redirect_count"),
+				Help:      "Number of redirects followed.",
+			},
+			redirectLabels,
+		)
+		redirectCount, err = internal.RegisterCounterVec(
+			registerer,
+			b.subsystem+"_"+names.counter("redirect_count"),
+			redirectCount,
+		)
+		if err != nil {
+			return
+		}
+		metricNames = append(metricNames, b.subsystem+"_"+names.counter("redirect_count"))
+	}
+
+	// Register the stuck request count metric:
+	var stuckCount *prometheus.CounterVec
+	if b.stuckAfter > 0 {
+		stuckCount = prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Subsystem: b.subsystem,
+				Name:      names.counter("request_stuck"),
+				Help:      "Number of requests that didn't complete in time.",
+			},
+			b.renames.names(stuckLabelNames),
+		)
+		stuckCount, err = internal.RegisterCounterVec(
+			registerer,
+			b.subsystem+"_"+names.counter("request_stuck"),
+			stuckCount,
+		)
+		if err != nil {
+			return
+		}
+		metricNames = append(metricNames, b.subsystem+"_"+names.counter("request_stuck"))
+	}
+
+	// Register the body read duration metric:
+	var bodyDuration *prometheus.HistogramVec
+	if b.bodyRead {
+		bodyDuration = prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Subsystem: b.subsystem,
+				Name:      names.duration("body_read_duration"),
+				Help:      b.durationUnit.help("Response body read"),
+				Buckets:   b.durationUnit.buckets(),
+			},
+			labelNames,
+		)
+		bodyDuration, err = internal.RegisterHistogramVec(
+			registerer,
+			b.subsystem+"_"+names.duration("body_read_duration"),
+			bodyDuration,
+		)
+		if err != nil {
+			return
+		}
+		metricNames = append(metricNames, b.subsystem+"_"+names.duration("body_read_duration"))
+	}
+
+	// Register the body read timeout count metric:
+	var bodyTimeouts *prometheus.CounterVec
+	if b.bodyTimeout > 0 {
+		bodyTimeouts = prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Subsystem: b.subsystem,
+				Name:      names.counter("body_read_timeout"),
+				Help:      "Number of response bodies that weren't closed in time.",
+			},
+			labelNames,
+		)
+		bodyTimeouts, err = internal.RegisterCounterVec(
+			registerer,
+			b.subsystem+"_"+names.counter("body_read_timeout"),
+			bodyTimeouts,
+		)
+		if err != nil {
+			return
+		}
+		metricNames = append(metricNames, b.subsystem+"_"+names.counter("body_read_timeout"))
+	}
+
+	// Register the DNS metrics:
+	var dnsCount *prometheus.CounterVec
+	var dnsDuration *prometheus.HistogramVec
+	if b.dns {
+		dnsCount = prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Subsystem: b.subsystem,
+				Name:      names.counter("dns_lookup_count"),
+				Help:      "Number of new connections, by DNS lookup cache status.",
+			},
+			b.renames.names(dnsCountLabelNames),
+		)
+		dnsCount, err = internal.RegisterCounterVec(
+			registerer,
+			b.subsystem+"_"+names.counter("dns_lookup_count"),
+			dnsCount,
+		)
+		if err != nil {
 			return
 		}
+		metricNames = append(metricNames, b.subsystem+"_"+names.counter("dns_lookup_count"))
+		dnsDuration = prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Subsystem: b.subsystem,
+				Name:      names.duration("dns_lookup_duration"),
+				Help:      b.durationUnit.help("DNS lookup"),
+				Buckets:   b.durationUnit.buckets(),
+			},
+			b.renames.names(dnsDurationLabelNames),
+		)
+		dnsDuration, err = internal.RegisterHistogramVec(
+			registerer,
+			b.subsystem+"_"+names.duration("dns_lookup_duration"),
+			dnsDuration,
+		)
+		if err != nil {
+			return
+		}
+		metricNames = append(metricNames, b.subsystem+"_"+names.duration("dns_lookup_duration"))
+	}
+
+	// Register the TLS handshake metric:
+	var tlsHandshakes *prometheus.CounterVec
+	var tlsChecker *tlsResumptionChecker
+	if b.tls || b.tlsThreshold > 0 {
+		tlsHandshakes = prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Subsystem: b.subsystem,
+				Name:      names.counter("tls_handshake_count"),
+				Help:      "Number of TLS handshakes, by session resumption status.",
+			},
+			b.renames.names(tlsHandshakeLabelNames),
+		)
+		tlsHandshakes, err = internal.RegisterCounterVec(
+			registerer,
+			b.subsystem+"_"+names.counter("tls_handshake_count"),
+			tlsHandshakes,
+		)
+		if err != nil {
+			return
+		}
+		metricNames = append(metricNames, b.subsystem+"_"+names.counter("tls_handshake_count"))
+		if b.tlsThreshold > 0 {
+			tlsChecker = &tlsResumptionChecker{
+				threshold: b.tlsThreshold,
+				window:    b.tlsWindow,
+			}
+		}
+	}
+
+	// Register the byte count metrics:
+	var bytesSent *prometheus.CounterVec
+	var bytesReceived *prometheus.CounterVec
+	if b.bytes {
+		bytesSent = prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Subsystem: b.subsystem,
+				Name:      names.counter("bytes_sent"),
+				Help:      "Number of bytes sent in request bodies.",
+			},
+			b.renames.names(bytesLabelNames),
+		)
+		bytesSent, err = internal.RegisterCounterVec(
+			registerer,
+			b.subsystem+"_"+names.counter("bytes_sent"),
+			bytesSent,
+		)
+		if err != nil {
+			return
+		}
+		metricNames = append(metricNames, b.subsystem+"_"+names.counter("bytes_sent"))
+		bytesReceived = prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Subsystem: b.subsystem,
+				Name:      names.counter("bytes_received"),
+				Help:      "Number of bytes received in response bodies.",
+			},
+			b.renames.names(bytesLabelNames),
+		)
+		bytesReceived, err = internal.RegisterCounterVec(
+			registerer,
+			b.subsystem+"_"+names.counter("bytes_received"),
+			bytesReceived,
+		)
+		if err != nil {
+			return
+		}
+		metricNames = append(metricNames, b.subsystem+"_"+names.counter("bytes_received"))
+	}
+
+	// Register the decode error metric:
+	var decodeErrors *prometheus.CounterVec
+	if b.decodeErrors {
+		decodeErrors = prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Subsystem: b.subsystem,
+				Name:      names.counter("response_decode_error"),
+				Help:      "Number of response bodies that couldn't be decoded.",
+			},
+			b.renames.names(decodeErrorLabelNames),
+		)
+		decodeErrors, err = internal.RegisterCounterVec(
+			registerer,
+			b.subsystem+"_"+names.counter("response_decode_error"),
+			decodeErrors,
+		)
+		if err != nil {
+			return
+		}
+		metricNames = append(metricNames, b.subsystem+"_"+names.counter("response_decode_error"))
+	}
+
+	// Register the series count metric:
+	if b.seriesCount {
+		counter := newSeriesCounter(
+			[]*prometheus.CounterVec{
+				requestCount,
+				redirectCount,
+				stuckCount,
+				bodyTimeouts,
+				dnsCount,
+				tlsHandshakes,
+				bytesSent,
+				bytesReceived,
+				decodeErrors,
+			},
+			[]*prometheus.HistogramVec{
+				requestDuration,
+				bodyDuration,
+				dnsDuration,
+			},
+		)
+		err = registerSeriesCount(registerer, b.subsystem, counter)
+		if err != nil {
+			return
+		}
+		metricNames = append(metricNames, b.subsystem+"_metric_series_count")
+	}
+
+	// Copy the label names, so that later changes to the builder don't affect the wrapper:
+	renames := labelRenames{}
+	for original, name := range b.renames {
+		renames[original] = name
+	}
+
+	// Start the batcher, only after everything else has succeeded so that there is no need to
+	// stop it if something fails:
+	var batcher *batcher
+	if b.flush > 0 {
+		batcher = newBatcher(labelNames, requestCount, requestDuration, b.flush)
 	}
 
 	// Create and populate the object:
 	result = &TransportWrapper{
 		paths:           paths,
+		metricNames:     metricNames,
+		registerer:      b.registerer,
+		clock:           b.clock,
+		durationUnit:    b.durationUnit,
+		labelNames:      labelNames,
 		requestCount:    requestCount,
 		requestDuration: requestDuration,
+		redirectCount:   redirectCount,
+		redirectHops:    b.redirectHops,
+		classifier:      b.classifier,
+		classes:         classes,
+		orgs:            orgs,
+		attempts:        b.attempts,
+		outcome:         b.outcome,
+		caller:          b.caller,
+		idempotent:      b.idempotent,
+		stuckAfter:      b.stuckAfter,
+		stuckCount:      stuckCount,
+		bodyDuration:    bodyDuration,
+		bodyTimeout:     b.bodyTimeout,
+		bodyTimeouts:    bodyTimeouts,
+		dnsCount:        dnsCount,
+		dnsDuration:     dnsDuration,
+		tlsHandshakes:   tlsHandshakes,
+		tlsChecker:      tlsChecker,
+		logger:          b.logger,
+		bytesSent:       bytesSent,
+		bytesReceived:   bytesReceived,
+		decodeErrors:    decodeErrors,
+		batcher:         batcher,
+		renames:         renames,
+		extraLabels:     append([]string{}, b.extraLabels...),
 	}
 
 	return
 }
 
+// Registerer returns the Prometheus registerer where the metrics have been registered. This is
+// intended for building a metrics server that publishes them, see the NewHTTPServer function.
+func (w *TransportWrapper) Registerer() prometheus.Registerer {
+	return w.registerer
+}
+
+// MetricNames returns the fully qualified names of the metrics registered by the wrapper, including
+// the subsystem prefix and the unit suffix, in the order that they were registered. Only metrics
+// that have been enabled are included. For example, with the default settings and the `my`
+// subsystem the result will be:
+//
+//	my_request_count
+//	my_request_duration
+//
+// Note that for histograms this is the name of the family, and Prometheus adds the `_bucket`,
+// `_sum` and `_count` suffixes to the names of the series. This is intended for generating
+// documentation and for auditing the metrics that are exposed.
+func (w *TransportWrapper) MetricNames() []string {
+	result := make([]string, len(w.metricNames))
+	copy(result, w.metricNames)
+	return result
+}
+
+// Flush adds to the collectors the observations that have been accumulated in memory when the
+// FlushInterval option is enabled. It does nothing when that option isn't enabled.
+func (w *TransportWrapper) Flush() {
+	if w.batcher != nil {
+		w.batcher.flush()
+	}
+}
+
+// Close releases the resources used by the wrapper. When the FlushInterval option is enabled it
+// stops the goroutine that flushes the observations accumulated in memory, and adds the pending
+// ones to the collectors. Requests sent after this is called are still measured, but their
+// observations will be added only when the Flush method is called.
+func (w *TransportWrapper) Close() error {
+	if w.batcher != nil {
+		w.batcher.close()
+	}
+	return nil
+}
+
+// Reset removes all the series of the metrics generated by the wrapper, so that counters and
+// histograms start again from zero, and forgets the classes seen so far. The metrics stay
+// registered, so there is no need to create a new wrapper or registry. This is intended for test
+// suites that reuse the same wrapper in several test cases. Don't use it in production, as
+// Prometheus interprets the reset counters as restarts.
+func (w *TransportWrapper) Reset() {
+	counters := []*prometheus.CounterVec{
+		w.requestCount,
+		w.redirectCount,
+		w.stuckCount,
+		w.bodyTimeouts,
+		w.dnsCount,
+		w.tlsHandshakes,
+		w.bytesSent,
+		w.bytesReceived,
+		w.decodeErrors,
+	}
+	for _, counter := range counters {
+		if counter != nil {
+			counter.Reset()
+		}
+	}
+	histograms := []*prometheus.HistogramVec{
+		w.requestDuration,
+		w.bodyDuration,
+		w.dnsDuration,
+	}
+	for _, histogram := range histograms {
+		if histogram != nil {
+			histogram.Reset()
+		}
+	}
+	if w.classes != nil {
+		w.classes.reset()
+	}
+	if w.orgs != nil {
+		w.orgs.reset()
+	}
+	if w.tlsChecker != nil {
+		w.tlsChecker.reset()
+	}
+	if w.batcher != nil {
+		w.batcher.discard()
+	}
+}
+
 // Wrap creates a new round tripper that wraps the given one and generates the Prometheus metrics.
 func (w *TransportWrapper) Wrap(transport http.RoundTripper) http.RoundTripper {
 	return &roundTripper{
@@ -218,10 +1215,52 @@ func (w *TransportWrapper) Wrap(transport http.RoundTripper) http.RoundTripper {
 
 // RoundTrip is the implementation of the round tripper interface.
 func (t *roundTripper) RoundTrip(request *http.Request) (response *http.Response, err error) {
+	// Don't record anything if metrics have been disabled for this request:
+	if disabledFromContext(request.Context()) {
+		response, err = t.transport.RoundTrip(request)
+		return
+	}
+
+	// Start the watchdog that detects stuck requests:
+	if t.owner.stuckCount != nil {
+		stop := t.owner.watch(request)
+		defer stop()
+	}
+
+	// Count the bytes of the request body:
+	var bytesLabels prometheus.Labels
+	if t.owner.bytesSent != nil {
+		bytesLabels = t.owner.bytesLabels(request)
+		request = t.owner.countSent(request, bytesLabels)
+	}
+
+	// Add the hooks that measure DNS lookups:
+	if t.owner.dnsCount != nil {
+		ctx := t.owner.traceDNS(request.Context(), core.ServiceLabel(request.URL.Path))
+		request = request.WithContext(ctx)
+	}
+
+	// Add the hook that measures TLS session resumption:
+	if t.owner.tlsHandshakes != nil {
+		ctx := t.owner.traceTLS(request.Context(), core.ServiceLabel(request.URL.Path))
+		request = request.WithContext(ctx)
+	}
+
+	// Add the reporter that counts the errors decoding the response body:
+	var reportDecodeError func(reason string)
+	if t.owner.decodeErrors != nil {
+		var ctx context.Context
+		ctx, reportDecodeError = t.owner.reportDecodeErrors(
+			request.Context(),
+			core.ServiceLabel(request.URL.Path),
+		)
+		request = request.WithContext(ctx)
+	}
+
 	// Measure the time that it takes to send the request and receive the response:
-	start := time.Now()
+	start := t.owner.clock.Now()
 	response, err = t.transport.RoundTrip(request)
-	elapsed := time.Since(start)
+	elapsed := t.owner.clock.Since(start)
 
 	// Update the metrics:
 	path := request.URL.Path
@@ -231,13 +1270,183 @@ func (t *roundTripper) RoundTrip(request *http.Request) (response *http.Response
 		code = response.StatusCode
 	}
 	labels := prometheus.Labels{
-		serviceLabelName: serviceLabel(path),
-		methodLabelName:  methodLabel(method),
-		pathLabelName:    pathLabel(t.owner.paths, path),
-		codeLabelName:    codeLabel(code),
+		serviceLabelName: core.ServiceLabel(path),
+		methodLabelName:  core.MethodLabel(method),
+		pathLabelName:    t.owner.paths.Label(path),
+		codeLabelName:    core.CodeLabel(code),
+	}
+	if t.owner.classifier != nil {
+		labels[classLabelName] = t.owner.classes.label(t.owner.classifier(request))
+	}
+	if t.owner.attempts {
+		labels[attemptLabelName] = strconv.Itoa(retry.AttemptFromContext(request.Context()))
+	}
+	if t.owner.outcome {
+		labels[outcomeLabelName] = outcomeLabel(code, err)
+	}
+	if t.owner.caller {
+		labels[callerLabelName] = callerLabel()
+	}
+	if t.owner.idempotent {
+		labels[idempotentLabelName] = idempotentLabel(method)
+	}
+	if t.owner.orgs != nil {
+		org := tenancy.OrganizationFromContext(request.Context())
+		labels[orgLabelName] = t.owner.orgs.label(org)
+	}
+	labels = t.owner.renames.labels(labels)
+	if len(t.owner.extraLabels) > 0 {
+		values := labelsFromContext(request.Context())
+		for _, name := range t.owner.extraLabels {
+			labels[name] = values[name]
+		}
+	}
+	if t.owner.batcher != nil {
+		t.owner.batcher.observe(labels, t.owner.durationUnit.value(elapsed))
+	} else {
+		t.owner.requestCount.With(labels).Inc()
+		t.owner.requestDuration.With(labels).Observe(t.owner.durationUnit.value(elapsed))
+	}
+
+	// Measure the time that it takes to read the body, from now till it is closed:
+	if response != nil && response.Body != nil {
+		response.Body = t.owner.watchBody(response.Body, labels)
+	}
+
+	// Check that the response body is valid JSON:
+	if reportDecodeError != nil && response != nil {
+		t.owner.checkBody(response, reportDecodeError)
+	}
+
+	// Count the bytes of the response body:
+	if t.owner.bytesReceived != nil && response != nil && response.Body != nil {
+		response.Body = core.WrapBody(response.Body, func(count int64) {
+			t.owner.bytesReceived.With(bytesLabels).Add(float64(count))
+		})
+	}
+
+	// When the HTTP client follows a redirect it puts in the new request the response that
+	// caused it, so we can use that to detect and count redirects:
+	if t.owner.redirectCount != nil && request.Response != nil {
+		t.owner.countRedirect(request)
 	}
-	t.owner.requestCount.With(labels).Inc()
-	t.owner.requestDuration.With(labels).Observe(elapsed.Seconds())
 
 	return
 }
+
+// watch starts a timer that will increase the stuck request count metric if the request doesn't
+// complete in time. It returns a function that must be called when the request completes, to stop
+// the timer.
+func (w *TransportWrapper) watch(request *http.Request) func() {
+	path := request.URL.Path
+	labels := prometheus.Labels{
+		serviceLabelName: core.ServiceLabel(path),
+		methodLabelName:  core.MethodLabel(request.Method),
+		pathLabelName:    w.paths.Label(path),
+	}
+	labels = w.renames.labels(labels)
+	timer := time.AfterFunc(w.stuckAfter, func() {
+		w.stuckCount.With(labels).Inc()
+	})
+	return func() {
+		timer.Stop()
+	}
+}
+
+// bytesLabels calculates the labels of the byte count metrics for the given request.
+func (w *TransportWrapper) bytesLabels(request *http.Request) prometheus.Labels {
+	path := request.URL.Path
+	labels := prometheus.Labels{
+		serviceLabelName: core.ServiceLabel(path),
+		methodLabelName:  core.MethodLabel(request.Method),
+		pathLabelName:    w.paths.Label(path),
+	}
+	return w.renames.labels(labels)
+}
+
+// countSent returns a copy of the given request whose body updates the bytes sent metric when the
+// transport closes it. Requests without body are returned unchanged.
+func (w *TransportWrapper) countSent(request *http.Request,
+	labels prometheus.Labels) *http.Request {
+	if request.Body == nil || request.Body == http.NoBody {
+		return request
+	}
+	onClose := func(count int64) {
+		w.bytesSent.With(labels).Add(float64(count))
+	}
+	result := request.Clone(request.Context())
+	result.Body = core.WrapBody(request.Body, onClose)
+	if request.GetBody != nil {
+		result.GetBody = func() (body io.ReadCloser, err error) {
+			body, err = request.GetBody()
+			if err != nil {
+				return
+			}
+			body = core.WrapBody(body, onClose)
+			return
+		}
+	}
+	return result
+}
+
+// watchBody wraps the given response body so that the body read duration metric is updated when it
+// is closed, and so that the body read timeout metric is updated if it isn't closed in time. If
+// none of those metrics are enabled it returns the body unchanged.
+func (w *TransportWrapper) watchBody(body io.ReadCloser, labels prometheus.Labels) io.ReadCloser {
+	if w.bodyDuration == nil && w.bodyTimeouts == nil {
+		return body
+	}
+
+	// The body can be closed or the timer can expire, and only the first of those should
+	// update the metrics:
+	var once sync.Once
+	observe := func(elapsed time.Duration) {
+		if w.bodyDuration != nil {
+			w.bodyDuration.With(labels).Observe(w.durationUnit.value(elapsed))
+		}
+	}
+	var timer *time.Timer
+	if w.bodyTimeouts != nil {
+		timer = time.AfterFunc(w.bodyTimeout, func() {
+			once.Do(func() {
+				w.bodyTimeouts.With(labels).Inc()
+				observe(w.bodyTimeout)
+			})
+		})
+	}
+	first := w.clock.Now()
+	return core.WrapBody(body, func(_ int64) {
+		if timer != nil {
+			timer.Stop()
+		}
+		once.Do(func() {
+			observe(w.clock.Since(first))
+		})
+	})
+}
+
+// countRedirect updates the redirect count metric for a request that was sent by the HTTP client
+// to follow a redirect.
+func (w *TransportWrapper) countRedirect(request *http.Request) {
+	// Find the original request, counting the number of hops:
+	original := request
+	hops := 0
+	for original.Response != nil && original.Response.Request != nil {
+		original = original.Response.Request
+		hops++
+	}
+
+	// Update the metric:
+	path := original.URL.Path
+	labels := prometheus.Labels{
+		serviceLabelName: core.ServiceLabel(path),
+		methodLabelName:  core.MethodLabel(original.Method),
+		pathLabelName:    w.paths.Label(path),
+		codeLabelName:    core.CodeLabel(request.Response.StatusCode),
+	}
+	if w.redirectHops {
+		labels[hopsLabelName] = strconv.Itoa(hops)
+	}
+	labels = w.renames.labels(labels)
+	w.redirectCount.With(labels).Inc()
+}
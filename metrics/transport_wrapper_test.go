@@ -19,14 +19,31 @@ limitations under the License.
 package metrics
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
 	"io"
+	"math"
+	"net"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
 
 	. "github.com/onsi/ginkgo/v2/dsl/core"  // nolint
 	. "github.com/onsi/ginkgo/v2/dsl/table" // nolint
 	. "github.com/onsi/gomega"              // nolint
 	. "github.com/onsi/gomega/ghttp"        // nolint
 
+	"github.com/openshift-online/ocm-sdk-go/helpers"
+	"github.com/openshift-online/ocm-sdk-go/logging"
+	"github.com/openshift-online/ocm-sdk-go/retry"
+	"github.com/openshift-online/ocm-sdk-go/tenancy"
+
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+
 	. "github.com/openshift-online/ocm-sdk-go/testing"
 )
 
@@ -715,3 +732,2389 @@ var _ = Describe("Metrics", func() {
 		)
 	})
 })
+
+var _ = Describe("Redirect metrics", func() {
+	var (
+		apiServer     *Server
+		metricsServer *MetricsServer
+	)
+
+	BeforeEach(func() {
+		apiServer = NewServer()
+		metricsServer = NewMetricsServer()
+	})
+
+	AfterEach(func() {
+		metricsServer.Close()
+		apiServer.Close()
+	})
+
+	// MakeClient creates an HTTP client that uses a metrics transport wrapper configured with the
+	// given redirect options.
+	var MakeClient = func(redirects, hops bool) *http.Client {
+		wrapper, err := NewTransportWrapper().
+			Subsystem("my").
+			Registerer(metricsServer.Registry()).
+			Redirects(redirects).
+			RedirectHops(hops).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		return &http.Client{
+			Transport: wrapper.Wrap(http.DefaultTransport),
+		}
+	}
+
+	// Send sends a GET request to the API server.
+	var Send = func(client *http.Client, path string) {
+		defer client.CloseIdleConnections()
+		response, err := client.Get(apiServer.URL() + path)
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err = response.Body.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+		_, err = io.Copy(io.Discard, response.Body)
+		Expect(err).ToNot(HaveOccurred())
+	}
+
+	// Redirect returns a handler that redirects to the given path of the API server.
+	var Redirect = func(code int, path string) http.HandlerFunc {
+		return RespondWith(code, nil, http.Header{
+			"Location": []string{apiServer.URL() + path},
+		})
+	}
+
+	It("Doesn't generate redirect metric by default", func() {
+		// Prepare the server:
+		apiServer.AppendHandlers(
+			Redirect(http.StatusFound, "/api/clusters_mgmt/v1/clusters"),
+			RespondWith(http.StatusOK, nil),
+		)
+
+		// Send the request:
+		Send(MakeClient(false, false), "/api/clusters_mgmt/v1")
+
+		// Verify the metrics:
+		metrics := metricsServer.Metrics()
+		Expect(metrics).ToNot(MatchLine(`^my_redirect_count.*$`))
+	})
+
+	It("Counts redirects using labels of the original request", func() {
+		// Prepare the server:
+		apiServer.AppendHandlers(
+			Redirect(http.StatusFound, "/api/clusters_mgmt/v1/clusters"),
+			RespondWith(http.StatusOK, nil),
+		)
+
+		// Send the request:
+		Send(MakeClient(true, false), "/api/clusters_mgmt/v1")
+
+		// Verify the metrics:
+		metrics := metricsServer.Metrics()
+		Expect(metrics).To(MatchLine(
+			`^my_redirect_count\{` +
+				`apiservice="ocm-clusters-service",` +
+				`code="302",` +
+				`method="GET",` +
+				`path="/api/clusters_mgmt/v1"` +
+				`\} 1$`,
+		))
+	})
+
+	It("Doesn't count requests without redirect", func() {
+		// Prepare the server:
+		apiServer.AppendHandlers(
+			RespondWith(http.StatusOK, nil),
+		)
+
+		// Send the request:
+		Send(MakeClient(true, false), "/api/clusters_mgmt/v1")
+
+		// Verify the metrics:
+		metrics := metricsServer.Metrics()
+		Expect(metrics).ToNot(MatchLine(`^my_redirect_count\{.*$`))
+	})
+
+	It("Includes hops label", func() {
+		// Prepare the server:
+		apiServer.AppendHandlers(
+			Redirect(http.StatusFound, "/api/clusters_mgmt/v1/clusters"),
+			Redirect(http.StatusMovedPermanently, "/api/clusters_mgmt/v1/clusters/123"),
+			RespondWith(http.StatusOK, nil),
+		)
+
+		// Send the request:
+		Send(MakeClient(true, true), "/api/clusters_mgmt/v1")
+
+		// Verify the metrics:
+		metrics := metricsServer.Metrics()
+		Expect(metrics).To(MatchLine(`^my_redirect_count\{.*code="302",hops="1".*\} 1$`))
+		Expect(metrics).To(MatchLine(`^my_redirect_count\{.*code="301",hops="2".*\} 1$`))
+	})
+})
+
+var _ = Describe("Clock", func() {
+	It("Uses the clock to measure durations", func() {
+		// Start the servers:
+		apiServer := NewServer()
+		defer apiServer.Close()
+		metricsServer := NewMetricsServer()
+		defer metricsServer.Close()
+
+		// Create the wrapper with a fake clock:
+		clock := NewFakeClock(time.Now())
+		wrapper, err := NewTransportWrapper().
+			Subsystem("my").
+			Registerer(metricsServer.Registry()).
+			Clock(clock).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		client := &http.Client{
+			Transport: wrapper.Wrap(http.DefaultTransport),
+		}
+		defer client.CloseIdleConnections()
+
+		// Prepare the server so that the clock advances five seconds while the request is
+		// being processed:
+		apiServer.AppendHandlers(func(w http.ResponseWriter, r *http.Request) {
+			clock.Advance(5 * time.Second)
+			w.WriteHeader(http.StatusOK)
+		})
+
+		// Send the request:
+		response, err := client.Get(apiServer.URL() + "/api")
+		Expect(err).ToNot(HaveOccurred())
+		err = response.Body.Close()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Verify that the duration was placed exactly in the expected buckets:
+		metrics := metricsServer.Metrics()
+		Expect(metrics).To(MatchLine(`^my_request_duration_bucket\{.*,le="1"\} 0$`))
+		Expect(metrics).To(MatchLine(`^my_request_duration_bucket\{.*,le="10"\} 1$`))
+		Expect(metrics).To(MatchLine(`^my_request_duration_sum\{.*\} 5$`))
+	})
+
+	It("Places each duration in the right bucket", func() {
+		// Start the servers:
+		apiServer := NewServer()
+		defer apiServer.Close()
+		metricsServer := NewMetricsServer()
+		defer metricsServer.Close()
+
+		// Create the wrapper with a fake clock:
+		clock := NewFakeClock(time.Now())
+		wrapper, err := NewTransportWrapper().
+			Subsystem("my").
+			Registerer(metricsServer.Registry()).
+			Clock(clock).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		client := &http.Client{
+			Transport: wrapper.Wrap(http.DefaultTransport),
+		}
+		defer client.CloseIdleConnections()
+
+		// Prepare the server so that the clock advances five seconds for the first request and
+		// one minute for the second:
+		apiServer.AppendHandlers(
+			func(w http.ResponseWriter, r *http.Request) {
+				clock.Advance(5 * time.Second)
+				w.WriteHeader(http.StatusOK)
+			},
+			func(w http.ResponseWriter, r *http.Request) {
+				clock.Advance(time.Minute)
+				w.WriteHeader(http.StatusNotFound)
+			},
+		)
+
+		// Send the requests:
+		response, err := client.Get(apiServer.URL() + "/api")
+		Expect(err).ToNot(HaveOccurred())
+		err = response.Body.Close()
+		Expect(err).ToNot(HaveOccurred())
+		response, err = client.Get(apiServer.URL() + "/api")
+		Expect(err).ToNot(HaveOccurred())
+		err = response.Body.Close()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Verify the buckets:
+		metrics := metricsServer.Metrics()
+		ok := map[string]string{"code": "200"}
+		notFound := map[string]string{"code": "404"}
+		Expect(metrics).ToNot(MatchBucket("my_request_duration", 1, ok))
+		Expect(metrics).To(MatchBucket("my_request_duration", 10, ok))
+		Expect(metrics).ToNot(MatchBucket("my_request_duration", 30, ok))
+		Expect(metrics).ToNot(MatchBucket("my_request_duration", math.Inf(1), ok))
+		Expect(metrics).ToNot(MatchBucket("my_request_duration", 30, notFound))
+		Expect(metrics).To(MatchBucket("my_request_duration", math.Inf(1), notFound))
+		Expect(metrics).To(MatchBucket("my_request_duration", 10, nil))
+		Expect(metrics).ToNot(MatchBucket("your_request_duration", 10, nil))
+	})
+})
+
+var _ = Describe("Duration unit", func() {
+	It("Rejects invalid unit", func() {
+		wrapper, err := NewTransportWrapper().
+			Subsystem("my").
+			DurationUnit(DurationUnit(42)).
+			Build()
+		Expect(err).To(HaveOccurred())
+		Expect(wrapper).To(BeNil())
+		Expect(err.Error()).To(ContainSubstring("duration unit"))
+	})
+
+	It("Records durations in milliseconds", func() {
+		// Start the servers:
+		apiServer := NewServer()
+		defer apiServer.Close()
+		metricsServer := NewMetricsServer()
+		defer metricsServer.Close()
+
+		// Create the wrapper:
+		clock := NewFakeClock(time.Now())
+		wrapper, err := NewTransportWrapper().
+			Subsystem("my").
+			Registerer(metricsServer.Registry()).
+			Clock(clock).
+			DurationUnit(DurationUnitMilliseconds).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		client := &http.Client{
+			Transport: wrapper.Wrap(http.DefaultTransport),
+		}
+		defer client.CloseIdleConnections()
+
+		// Prepare the server so that the clock advances five seconds while the request is
+		// being processed:
+		apiServer.AppendHandlers(func(w http.ResponseWriter, r *http.Request) {
+			clock.Advance(5 * time.Second)
+			w.WriteHeader(http.StatusOK)
+		})
+
+		// Send the request:
+		response, err := client.Get(apiServer.URL() + "/api")
+		Expect(err).ToNot(HaveOccurred())
+		err = response.Body.Close()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Verify the name of the metric, the buckets and the value:
+		metrics := metricsServer.Metrics()
+		Expect(metrics).ToNot(MatchLine(`^my_request_duration_bucket.*$`))
+		Expect(metrics).To(MatchLine(`^my_request_duration_milliseconds_bucket\{.*,le="100"\} 0$`))
+		Expect(metrics).To(MatchLine(`^my_request_duration_milliseconds_bucket\{.*,le="1000"\} 0$`))
+		Expect(metrics).To(MatchLine(`^my_request_duration_milliseconds_bucket\{.*,le="10000"\} 1$`))
+		Expect(metrics).To(MatchLine(`^my_request_duration_milliseconds_bucket\{.*,le="30000"\} 1$`))
+		Expect(metrics).To(MatchLine(`^my_request_duration_milliseconds_sum\{.*\} 5000$`))
+	})
+})
+
+var _ = Describe("Classifier", func() {
+	var (
+		apiServer     *Server
+		metricsServer *MetricsServer
+	)
+
+	BeforeEach(func() {
+		apiServer = NewServer()
+		metricsServer = NewMetricsServer()
+	})
+
+	AfterEach(func() {
+		metricsServer.Close()
+		apiServer.Close()
+	})
+
+	// Send sends a GET request with the given class in the `X-Class` header.
+	var Send = func(client *http.Client, class string) {
+		apiServer.AppendHandlers(
+			RespondWith(http.StatusOK, nil),
+		)
+		request, err := http.NewRequest(http.MethodGet, apiServer.URL()+"/api", nil)
+		Expect(err).ToNot(HaveOccurred())
+		request.Header.Set("X-Class", class)
+		response, err := client.Do(request)
+		Expect(err).ToNot(HaveOccurred())
+		err = response.Body.Close()
+		Expect(err).ToNot(HaveOccurred())
+	}
+
+	// Classify returns the value of the `X-Class` header.
+	var Classify = func(request *http.Request) string {
+		return request.Header.Get("X-Class")
+	}
+
+	It("Doesn't add class label by default", func() {
+		wrapper, err := NewTransportWrapper().
+			Subsystem("my").
+			Registerer(metricsServer.Registry()).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		client := &http.Client{
+			Transport: wrapper.Wrap(http.DefaultTransport),
+		}
+		defer client.CloseIdleConnections()
+		Send(client, "batch")
+		metrics := metricsServer.Metrics()
+		Expect(metrics).ToNot(MatchLine(`^my_request_count\{.*class=.*\} .*$`))
+	})
+
+	It("Adds class label", func() {
+		wrapper, err := NewTransportWrapper().
+			Subsystem("my").
+			Registerer(metricsServer.Registry()).
+			Classifier(Classify).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		client := &http.Client{
+			Transport: wrapper.Wrap(http.DefaultTransport),
+		}
+		defer client.CloseIdleConnections()
+		Send(client, "batch")
+		Send(client, "interactive")
+		Send(client, "interactive")
+		metrics := metricsServer.Metrics()
+		Expect(metrics).To(MatchLine(`^my_request_count\{.*class="batch".*\} 1$`))
+		Expect(metrics).To(MatchLine(`^my_request_count\{.*class="interactive".*\} 2$`))
+		Expect(metrics).To(MatchLine(`^my_request_duration_count\{.*class="batch".*\} 1$`))
+	})
+
+	It("Limits the number of classes", func() {
+		wrapper, err := NewTransportWrapper().
+			Subsystem("my").
+			Registerer(metricsServer.Registry()).
+			Classifier(Classify).
+			ClassLimit(2).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		client := &http.Client{
+			Transport: wrapper.Wrap(http.DefaultTransport),
+		}
+		defer client.CloseIdleConnections()
+		Send(client, "a")
+		Send(client, "b")
+		Send(client, "c")
+		Send(client, "d")
+		Send(client, "a")
+		metrics := metricsServer.Metrics()
+		Expect(metrics).To(MatchLine(`^my_request_count\{.*class="a".*\} 2$`))
+		Expect(metrics).To(MatchLine(`^my_request_count\{.*class="b".*\} 1$`))
+		Expect(metrics).To(MatchLine(`^my_request_count\{.*class="other".*\} 2$`))
+		Expect(metrics).ToNot(MatchLine(`^my_request_count\{.*class="c".*\} .*$`))
+	})
+
+	It("Rejects limit that isn't positive", func() {
+		wrapper, err := NewTransportWrapper().
+			Subsystem("my").
+			Classifier(Classify).
+			ClassLimit(0).
+			Build()
+		Expect(err).To(HaveOccurred())
+		Expect(wrapper).To(BeNil())
+		Expect(err.Error()).To(ContainSubstring("class limit"))
+	})
+})
+
+var _ = Describe("Registration", func() {
+	It("Uses the default registerer if none is given", func() {
+		wrapper, err := NewTransportWrapper().
+			Subsystem("default_registerer").
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(prometheus.DefaultRegisterer.Unregister(wrapper.requestCount)).To(BeTrue())
+		Expect(prometheus.DefaultRegisterer.Unregister(wrapper.requestDuration)).To(BeTrue())
+	})
+
+	It("Uses the default registerer if nil is given", func() {
+		wrapper, err := NewTransportWrapper().
+			Subsystem("nil_registerer").
+			Registerer(nil).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(prometheus.DefaultRegisterer.Unregister(wrapper.requestCount)).To(BeTrue())
+		Expect(prometheus.DefaultRegisterer.Unregister(wrapper.requestDuration)).To(BeTrue())
+	})
+
+	It("Can create multiple wrappers with the same subsystem", func() {
+		registry := prometheus.NewRegistry()
+		first, err := NewTransportWrapper().
+			Subsystem("my").
+			Registerer(registry).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		second, err := NewTransportWrapper().
+			Subsystem("my").
+			Registerer(registry).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(second.requestCount).To(BeIdenticalTo(first.requestCount))
+		Expect(second.requestDuration).To(BeIdenticalTo(first.requestDuration))
+	})
+
+	It("Rejects incompatible wrappers with the same subsystem", func() {
+		registry := prometheus.NewRegistry()
+		_, err := NewTransportWrapper().
+			Subsystem("my").
+			Registerer(registry).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		wrapper, err := NewTransportWrapper().
+			Subsystem("my").
+			Registerer(registry).
+			Classifier(func(*http.Request) string { return "" }).
+			Build()
+		Expect(err).To(HaveOccurred())
+		Expect(wrapper).To(BeNil())
+		Expect(err.Error()).To(ContainSubstring("'my_request_count'"))
+	})
+
+	It("Rejects exclusive wrapper with the same subsystem", func() {
+		registry := prometheus.NewRegistry()
+		_, err := NewTransportWrapper().
+			Subsystem("my").
+			Registerer(registry).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		wrapper, err := NewTransportWrapper().
+			Subsystem("my").
+			Registerer(registry).
+			Exclusive(true).
+			Build()
+		Expect(err).To(HaveOccurred())
+		Expect(wrapper).To(BeNil())
+		message := err.Error()
+		Expect(message).To(ContainSubstring("'my_request_count'"))
+		Expect(message).To(ContainSubstring("already registered"))
+	})
+
+	It("Accepts exclusive wrappers with different subsystems", func() {
+		registry := prometheus.NewRegistry()
+		_, err := NewTransportWrapper().
+			Subsystem("my").
+			Registerer(registry).
+			Exclusive(true).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = NewTransportWrapper().
+			Subsystem("your").
+			Registerer(registry).
+			Exclusive(true).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+	})
+})
+
+var _ = Describe("Attempts", func() {
+	It("Adds attempt label", func() {
+		// Start the servers:
+		apiServer := NewServer()
+		defer apiServer.Close()
+		metricsServer := NewMetricsServer()
+		defer metricsServer.Close()
+
+		// Create the metrics wrapper and put it inside a retry wrapper:
+		metricsWrapper, err := NewTransportWrapper().
+			Subsystem("my").
+			Registerer(metricsServer.Registry()).
+			Attempts(true).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		retryWrapper, err := retry.NewTransportWrapper().
+			Logger(logger).
+			Clock(NewFakeClock(time.Now())).
+			Build(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		client := &http.Client{
+			Transport: retryWrapper.Wrap(metricsWrapper.Wrap(http.DefaultTransport)),
+		}
+		defer client.CloseIdleConnections()
+
+		// Prepare the server so that the first attempt fails:
+		apiServer.AppendHandlers(
+			RespondWith(http.StatusServiceUnavailable, nil),
+			RespondWith(http.StatusOK, nil),
+		)
+
+		// Send the request:
+		response, err := client.Get(apiServer.URL() + "/api")
+		Expect(err).ToNot(HaveOccurred())
+		err = response.Body.Close()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Verify the metrics:
+		metrics := metricsServer.Metrics()
+		Expect(metrics).To(MatchLine(`^my_request_count\{.*attempt="1".*code="503".*\} 1$`))
+		Expect(metrics).To(MatchLine(`^my_request_count\{.*attempt="2".*code="200".*\} 1$`))
+	})
+
+	It("Uses zero when there is no retry wrapper", func() {
+		// Start the servers:
+		apiServer := NewServer()
+		defer apiServer.Close()
+		metricsServer := NewMetricsServer()
+		defer metricsServer.Close()
+
+		// Create the metrics wrapper:
+		wrapper, err := NewTransportWrapper().
+			Subsystem("my").
+			Registerer(metricsServer.Registry()).
+			Attempts(true).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		client := &http.Client{
+			Transport: wrapper.Wrap(http.DefaultTransport),
+		}
+		defer client.CloseIdleConnections()
+
+		// Send the request:
+		apiServer.AppendHandlers(
+			RespondWith(http.StatusOK, nil),
+		)
+		response, err := client.Get(apiServer.URL() + "/api")
+		Expect(err).ToNot(HaveOccurred())
+		err = response.Body.Close()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Verify the metrics:
+		metrics := metricsServer.Metrics()
+		Expect(metrics).To(MatchLine(`^my_request_count\{.*attempt="0".*\} 1$`))
+	})
+})
+
+var _ = Describe("Snapshot", func() {
+	var (
+		apiServer *Server
+		wrapper   *TransportWrapper
+		client    *http.Client
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		// Start the server:
+		apiServer = NewServer()
+
+		// Create the wrapper with a fake clock, so that durations are predictable:
+		clock := NewFakeClock(time.Now())
+		wrapper, err = NewTransportWrapper().
+			Subsystem("my").
+			Registerer(prometheus.NewRegistry()).
+			Clock(clock).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		client = &http.Client{
+			Transport: wrapper.Wrap(http.DefaultTransport),
+		}
+
+		// Prepare the server so that each request takes two seconds:
+		handler := func(code int) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				clock.Advance(2 * time.Second)
+				w.WriteHeader(code)
+			}
+		}
+		apiServer.AppendHandlers(
+			handler(http.StatusOK),
+			handler(http.StatusOK),
+			handler(http.StatusNotFound),
+		)
+
+		// Send the requests:
+		for _, path := range []string{"/api", "/api", "/api/clusters_mgmt"} {
+			response, err := client.Get(apiServer.URL() + path)
+			Expect(err).ToNot(HaveOccurred())
+			err = response.Body.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}
+	})
+
+	AfterEach(func() {
+		client.CloseIdleConnections()
+		apiServer.Close()
+	})
+
+	It("Returns totals if no label is given", func() {
+		snapshot, err := wrapper.Snapshot(nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(snapshot.Count).To(BeEquivalentTo(3))
+		Expect(snapshot.DurationCount).To(BeEquivalentTo(3))
+		Expect(snapshot.DurationSum).To(BeEquivalentTo(6))
+	})
+
+	It("Returns values for the given labels", func() {
+		snapshot, err := wrapper.Snapshot(map[string]string{
+			"method": http.MethodGet,
+			"code":   "200",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(snapshot.Count).To(BeEquivalentTo(2))
+		Expect(snapshot.DurationCount).To(BeEquivalentTo(2))
+		Expect(snapshot.DurationSum).To(BeEquivalentTo(4))
+	})
+
+	It("Returns zero if no request matches", func() {
+		snapshot, err := wrapper.Snapshot(map[string]string{
+			"code": "500",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(snapshot).To(BeZero())
+	})
+
+	It("Rejects unknown label", func() {
+		_, err := wrapper.Snapshot(map[string]string{
+			"junk": "value",
+		})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("'junk'"))
+	})
+})
+
+var _ = Describe("Stuck requests", func() {
+	var (
+		apiServer     *Server
+		metricsServer *MetricsServer
+		client        *http.Client
+	)
+
+	BeforeEach(func() {
+		// Start the servers:
+		apiServer = NewServer()
+		metricsServer = NewMetricsServer()
+
+		// Create the client:
+		wrapper, err := NewTransportWrapper().
+			Subsystem("my").
+			Registerer(metricsServer.Registry()).
+			StuckAfter(50 * time.Millisecond).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		client = &http.Client{
+			Transport: wrapper.Wrap(http.DefaultTransport),
+		}
+	})
+
+	AfterEach(func() {
+		client.CloseIdleConnections()
+		metricsServer.Close()
+		apiServer.Close()
+	})
+
+	// Send sends a GET request that the server will process in the given time.
+	var Send = func(delay time.Duration) {
+		apiServer.AppendHandlers(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(delay)
+			w.WriteHeader(http.StatusOK)
+		})
+		response, err := client.Get(apiServer.URL() + "/api/clusters_mgmt/v1/clusters")
+		Expect(err).ToNot(HaveOccurred())
+		err = response.Body.Close()
+		Expect(err).ToNot(HaveOccurred())
+	}
+
+	It("Doesn't count requests that complete in time", func() {
+		Send(0)
+		time.Sleep(100 * time.Millisecond)
+		metrics := metricsServer.Metrics()
+		Expect(metrics).ToNot(MatchLine(`^my_request_stuck.*$`))
+		Expect(metrics).To(MatchLine(`^my_request_count\{.*\} 1$`))
+	})
+
+	It("Counts requests that don't complete in time only once", func() {
+		Send(300 * time.Millisecond)
+		metrics := metricsServer.Metrics()
+		Expect(metrics).To(MatchLine(
+			`^my_request_stuck\{apiservice="ocm-clusters-service",method="GET",` +
+				`path="/api/clusters_mgmt/v1/clusters"\} 1$`,
+		))
+		Expect(metrics).To(MatchLine(`^my_request_count\{.*\} 1$`))
+	})
+
+	It("Rejects negative time", func() {
+		wrapper, err := NewTransportWrapper().
+			Subsystem("my").
+			StuckAfter(-1 * time.Second).
+			Build()
+		Expect(err).To(HaveOccurred())
+		Expect(wrapper).To(BeNil())
+		Expect(err.Error()).To(ContainSubstring("stuck"))
+	})
+})
+
+var _ = Describe("Label names", func() {
+	It("Renames labels", func() {
+		// Start the servers:
+		apiServer := NewServer()
+		defer apiServer.Close()
+		metricsServer := NewMetricsServer()
+		defer metricsServer.Close()
+
+		// Create the wrapper:
+		wrapper, err := NewTransportWrapper().
+			Subsystem("my").
+			Registerer(metricsServer.Registry()).
+			APIServiceLabelName("service").
+			MethodLabelName("verb").
+			PathLabelName("route").
+			CodeLabelName("status").
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		client := &http.Client{
+			Transport: wrapper.Wrap(http.DefaultTransport),
+		}
+		defer client.CloseIdleConnections()
+
+		// Send the request:
+		apiServer.AppendHandlers(
+			RespondWith(http.StatusOK, nil),
+		)
+		response, err := client.Get(apiServer.URL() + "/api/clusters_mgmt/v1/clusters")
+		Expect(err).ToNot(HaveOccurred())
+		err = response.Body.Close()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Verify the metrics:
+		metrics := metricsServer.Metrics()
+		Expect(metrics).To(MatchLine(
+			`^my_request_count\{route="/api/clusters_mgmt/v1/clusters",service="ocm-clusters-service",` +
+				`status="200",verb="GET"\} 1$`,
+		))
+		Expect(metrics).ToNot(MatchLine(`^my_request_count\{.*apiservice=.*\} .*$`))
+
+		// Verify that the snapshot uses the new names:
+		snapshot, err := wrapper.Snapshot(map[string]string{
+			"service": "ocm-clusters-service",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(snapshot.Count).To(BeEquivalentTo(1))
+	})
+
+	DescribeTable(
+		"Rejects invalid names",
+		func(build func(*TransportWrapperBuilder) *TransportWrapperBuilder, expected string) {
+			wrapper, err := build(NewTransportWrapper().Subsystem("my")).Build()
+			Expect(err).To(HaveOccurred())
+			Expect(wrapper).To(BeNil())
+			Expect(err.Error()).To(ContainSubstring(expected))
+		},
+		Entry(
+			"Empty",
+			func(b *TransportWrapperBuilder) *TransportWrapperBuilder {
+				return b.APIServiceLabelName("")
+			},
+			"isn't valid",
+		),
+		Entry(
+			"Invalid characters",
+			func(b *TransportWrapperBuilder) *TransportWrapperBuilder {
+				return b.APIServiceLabelName("api-service")
+			},
+			"label name 'api-service' isn't valid",
+		),
+		Entry(
+			"Reserved prefix",
+			func(b *TransportWrapperBuilder) *TransportWrapperBuilder {
+				return b.PathLabelName("__path")
+			},
+			"reserved",
+		),
+		Entry(
+			"Reserved by histograms",
+			func(b *TransportWrapperBuilder) *TransportWrapperBuilder {
+				return b.CodeLabelName("le")
+			},
+			"reserved",
+		),
+		Entry(
+			"Collision with default name",
+			func(b *TransportWrapperBuilder) *TransportWrapperBuilder {
+				return b.PathLabelName("code")
+			},
+			"same name 'code'",
+		),
+		Entry(
+			"Collision between new names",
+			func(b *TransportWrapperBuilder) *TransportWrapperBuilder {
+				return b.MethodLabelName("x").PathLabelName("x")
+			},
+			"same name 'x'",
+		),
+	)
+})
+
+var _ = Describe("Body read duration", func() {
+	var (
+		apiServer     *Server
+		metricsServer *MetricsServer
+		clock         *FakeClock
+	)
+
+	BeforeEach(func() {
+		// Start the servers:
+		apiServer = NewServer()
+		metricsServer = NewMetricsServer()
+
+		// Create the clock:
+		clock = NewFakeClock(time.Now())
+
+		// Prepare the server so that the clock advances two seconds while the request is being
+		// processed:
+		apiServer.AppendHandlers(func(w http.ResponseWriter, r *http.Request) {
+			clock.Advance(2 * time.Second)
+			w.WriteHeader(http.StatusOK)
+			_, err := w.Write([]byte("{}"))
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+
+	AfterEach(func() {
+		metricsServer.Close()
+		apiServer.Close()
+	})
+
+	// Client creates a client that uses a wrapper with the given body read duration flag.
+	var Client = func(flag bool) *http.Client {
+		wrapper, err := NewTransportWrapper().
+			Subsystem("my").
+			Registerer(metricsServer.Registry()).
+			Clock(clock).
+			BodyReadDuration(flag).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		return &http.Client{
+			Transport: wrapper.Wrap(http.DefaultTransport),
+		}
+	}
+
+	It("Doesn't generate body read metric by default", func() {
+		client := Client(false)
+		defer client.CloseIdleConnections()
+		response, err := client.Get(apiServer.URL() + "/api/clusters_mgmt/v1/clusters")
+		Expect(err).ToNot(HaveOccurred())
+		err = response.Body.Close()
+		Expect(err).ToNot(HaveOccurred())
+		metrics := metricsServer.Metrics()
+		Expect(metrics).ToNot(MatchLine(`^my_body_read_duration.*$`))
+	})
+
+	It("Measures the time from response headers to body close", func() {
+		client := Client(true)
+		defer client.CloseIdleConnections()
+
+		// Send the request, and advance the clock three seconds while reading the body:
+		response, err := client.Get(apiServer.URL() + "/api/clusters_mgmt/v1/clusters")
+		Expect(err).ToNot(HaveOccurred())
+		clock.Advance(3 * time.Second)
+		_, err = io.ReadAll(response.Body)
+		Expect(err).ToNot(HaveOccurred())
+		err = response.Body.Close()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Check that the request and body durations are separate:
+		metrics := metricsServer.Metrics()
+		Expect(metrics).To(MatchLine(`^my_request_duration_sum\{.*\} 2$`))
+		Expect(metrics).To(MatchLine(
+			`^my_body_read_duration_sum\{apiservice="ocm-clusters-service",code="200",` +
+				`method="GET",path="/api/clusters_mgmt/v1/clusters"\} 3$`,
+		))
+		Expect(metrics).To(MatchLine(`^my_body_read_duration_count\{.*\} 1$`))
+	})
+
+	It("Measures the body only once when it is closed multiple times", func() {
+		client := Client(true)
+		defer client.CloseIdleConnections()
+		response, err := client.Get(apiServer.URL() + "/api/clusters_mgmt/v1/clusters")
+		Expect(err).ToNot(HaveOccurred())
+		err = response.Body.Close()
+		Expect(err).ToNot(HaveOccurred())
+		err = response.Body.Close()
+		Expect(err).ToNot(HaveOccurred())
+		metrics := metricsServer.Metrics()
+		Expect(metrics).To(MatchLine(`^my_body_read_duration_count\{.*\} 1$`))
+	})
+
+	It("Doesn't measure the body before it is closed", func() {
+		client := Client(true)
+		defer client.CloseIdleConnections()
+		response, err := client.Get(apiServer.URL() + "/api/clusters_mgmt/v1/clusters")
+		Expect(err).ToNot(HaveOccurred())
+		metrics := metricsServer.Metrics()
+		Expect(metrics).ToNot(MatchLine(`^my_body_read_duration_count.*$`))
+		err = response.Body.Close()
+		Expect(err).ToNot(HaveOccurred())
+	})
+})
+
+var _ = Describe("Body read timeout", func() {
+	var (
+		apiServer *Server
+		registry  *prometheus.Registry
+	)
+
+	BeforeEach(func() {
+		// Start the server:
+		apiServer = NewServer()
+		apiServer.AppendHandlers(RespondWith(http.StatusOK, "{}"))
+
+		// Create the registry:
+		registry = prometheus.NewRegistry()
+	})
+
+	AfterEach(func() {
+		apiServer.Close()
+	})
+
+	// Client creates a client that uses a wrapper with the given body read timeout.
+	var Client = func(timeout time.Duration) *http.Client {
+		wrapper, err := NewTransportWrapper().
+			Subsystem("my").
+			Registerer(registry).
+			BodyReadDuration(true).
+			BodyReadTimeout(timeout).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		return &http.Client{
+			Transport: wrapper.Wrap(http.DefaultTransport),
+		}
+	}
+
+	// Gather returns the number of body read timeouts, and the count and sum of the body read
+	// duration metric.
+	var Gather = func() (timeouts float64, count uint64, sum float64) {
+		families, err := registry.Gather()
+		Expect(err).ToNot(HaveOccurred())
+		for _, family := range families {
+			for _, metric := range family.GetMetric() {
+				switch family.GetName() {
+				case "my_body_read_timeout":
+					timeouts += metric.GetCounter().GetValue()
+				case "my_body_read_duration":
+					count += metric.GetHistogram().GetSampleCount()
+					sum += metric.GetHistogram().GetSampleSum()
+				}
+			}
+		}
+		return
+	}
+
+	It("Rejects negative timeout", func() {
+		wrapper, err := NewTransportWrapper().
+			Subsystem("my").
+			Registerer(registry).
+			BodyReadTimeout(-1 * time.Second).
+			Build()
+		Expect(err).To(HaveOccurred())
+		Expect(wrapper).To(BeNil())
+		Expect(err.Error()).To(ContainSubstring("-1s"))
+	})
+
+	It("Doesn't count bodies closed in time", func() {
+		client := Client(time.Hour)
+		defer client.CloseIdleConnections()
+		response, err := client.Get(apiServer.URL() + "/api/clusters_mgmt/v1/clusters")
+		Expect(err).ToNot(HaveOccurred())
+		err = response.Body.Close()
+		Expect(err).ToNot(HaveOccurred())
+		timeouts, count, _ := Gather()
+		Expect(timeouts).To(BeZero())
+		Expect(count).To(BeNumerically("==", 1))
+	})
+
+	It("Counts bodies that aren't closed in time", func() {
+		client := Client(10 * time.Millisecond)
+		defer client.CloseIdleConnections()
+
+		// Send the request and don't close the body till the timeout expires:
+		response, err := client.Get(apiServer.URL() + "/api/clusters_mgmt/v1/clusters")
+		Expect(err).ToNot(HaveOccurred())
+		Eventually(func() float64 {
+			timeouts, _, _ := Gather()
+			return timeouts
+		}).Should(BeNumerically("==", 1))
+
+		// Check that the timeout has been recorded as the duration:
+		_, count, sum := Gather()
+		Expect(count).To(BeNumerically("==", 1))
+		Expect(sum).To(BeNumerically("==", 0.01))
+
+		// Check that the body can still be read, and that closing it doesn't change the
+		// metrics:
+		data, err := io.ReadAll(response.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(Equal("{}"))
+		err = response.Body.Close()
+		Expect(err).ToNot(HaveOccurred())
+		timeouts, count, _ := Gather()
+		Expect(timeouts).To(BeNumerically("==", 1))
+		Expect(count).To(BeNumerically("==", 1))
+	})
+})
+
+var _ = Describe("Without metrics", func() {
+	It("Doesn't record requests with metrics disabled in the context", func() {
+		// Start the servers:
+		apiServer := NewServer()
+		defer apiServer.Close()
+		metricsServer := NewMetricsServer()
+		defer metricsServer.Close()
+
+		// Create the client:
+		wrapper, err := NewTransportWrapper().
+			Subsystem("my").
+			Registerer(metricsServer.Registry()).
+			StuckAfter(50 * time.Millisecond).
+			BodyReadDuration(true).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		client := &http.Client{
+			Transport: wrapper.Wrap(http.DefaultTransport),
+		}
+		defer client.CloseIdleConnections()
+
+		// Prepare the server so that the first request would be considered stuck:
+		apiServer.AppendHandlers(
+			func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(200 * time.Millisecond)
+				w.WriteHeader(http.StatusOK)
+			},
+			RespondWith(http.StatusOK, nil),
+		)
+
+		// Send a request with metrics disabled:
+		ctx := WithoutMetrics(context.Background())
+		request, err := http.NewRequestWithContext(
+			ctx,
+			http.MethodGet,
+			apiServer.URL()+"/api/clusters_mgmt/v1/clusters",
+			nil,
+		)
+		Expect(err).ToNot(HaveOccurred())
+		response, err := client.Do(request)
+		Expect(err).ToNot(HaveOccurred())
+		err = response.Body.Close()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+		// Send a request to the same path without disabling metrics:
+		response, err = client.Get(apiServer.URL() + "/api/clusters_mgmt/v1/clusters")
+		Expect(err).ToNot(HaveOccurred())
+		err = response.Body.Close()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Check that only the second request was recorded:
+		metrics := metricsServer.Metrics()
+		Expect(metrics).To(MatchLine(`^my_request_count\{.*\} 1$`))
+		Expect(metrics).To(MatchLine(`^my_body_read_duration_count\{.*\} 1$`))
+		Expect(metrics).ToNot(MatchLine(`^my_request_stuck.*$`))
+	})
+})
+
+var _ = Describe("OpenMetrics", func() {
+	var (
+		apiServer     *Server
+		metricsServer *MetricsServer
+	)
+
+	BeforeEach(func() {
+		apiServer = NewServer()
+		apiServer.AppendHandlers(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusFound)
+		})
+		metricsServer = NewMetricsServer()
+	})
+
+	AfterEach(func() {
+		metricsServer.Close()
+		apiServer.Close()
+	})
+
+	// Send creates a wrapper with the given options and uses it to send a request.
+	var Send = func(openMetrics bool, unit DurationUnit) {
+		wrapper, err := NewTransportWrapper().
+			Subsystem("my").
+			Registerer(metricsServer.Registry()).
+			DurationUnit(unit).
+			Redirects(true).
+			BodyReadDuration(true).
+			OpenMetrics(openMetrics).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		client := &http.Client{
+			Transport: wrapper.Wrap(http.DefaultTransport),
+		}
+		defer client.CloseIdleConnections()
+		response, err := client.Get(apiServer.URL() + "/api")
+		Expect(err).ToNot(HaveOccurred())
+		err = response.Body.Close()
+		Expect(err).ToNot(HaveOccurred())
+	}
+
+	It("Uses original names by default", func() {
+		Send(false, DurationUnitSeconds)
+		metrics := metricsServer.Metrics()
+		Expect(metrics).To(MatchLine(`^my_request_count\{.*\} 1$`))
+		Expect(metrics).To(MatchLine(`^my_request_duration_count\{.*\} 1$`))
+		Expect(metrics).To(MatchLine(`^my_body_read_duration_count\{.*\} 1$`))
+		Expect(metrics).ToNot(MatchLine(`^my_request_count_total.*$`))
+	})
+
+	It("Uses OpenMetrics names when enabled", func() {
+		Send(true, DurationUnitSeconds)
+		metrics := metricsServer.Metrics()
+		Expect(metrics).To(MatchLine(`^# TYPE my_request_count_total counter$`))
+		Expect(metrics).To(MatchLine(`^my_request_count_total\{.*\} 1$`))
+		Expect(metrics).To(MatchLine(`^my_request_duration_seconds_count\{.*\} 1$`))
+		Expect(metrics).To(MatchLine(`^my_body_read_duration_seconds_count\{.*\} 1$`))
+		Expect(metrics).ToNot(MatchLine(`^my_request_count\{.*$`))
+		Expect(metrics).ToNot(MatchLine(`^my_request_duration_count\{.*$`))
+	})
+
+	It("Doesn't add seconds suffix to milliseconds", func() {
+		Send(true, DurationUnitMilliseconds)
+		metrics := metricsServer.Metrics()
+		Expect(metrics).To(MatchLine(`^my_request_duration_milliseconds_count\{.*\} 1$`))
+		Expect(metrics).ToNot(MatchLine(`^my_request_duration_milliseconds_seconds.*$`))
+	})
+})
+
+var _ = Describe("Outcome", func() {
+	var (
+		apiServer     *Server
+		metricsServer *MetricsServer
+		client        *http.Client
+	)
+
+	BeforeEach(func() {
+		// Start the servers:
+		apiServer = NewServer()
+		metricsServer = NewMetricsServer()
+
+		// Create the client:
+		wrapper, err := NewTransportWrapper().
+			Subsystem("my").
+			Registerer(metricsServer.Registry()).
+			Outcome(true).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		client = &http.Client{
+			Transport: wrapper.Wrap(http.DefaultTransport),
+		}
+	})
+
+	AfterEach(func() {
+		client.CloseIdleConnections()
+		metricsServer.Close()
+		apiServer.Close()
+	})
+
+	DescribeTable(
+		"Calculates outcome from response code",
+		func(code int, expected string) {
+			apiServer.AppendHandlers(RespondWith(code, nil))
+			response, err := client.Get(apiServer.URL() + "/api")
+			Expect(err).ToNot(HaveOccurred())
+			err = response.Body.Close()
+			Expect(err).ToNot(HaveOccurred())
+			metrics := metricsServer.Metrics()
+			Expect(metrics).To(MatchLine(`^my_request_count\{.*,outcome="%s".*\} 1$`, expected))
+		},
+		Entry("200", http.StatusOK, OutcomeSuccess),
+		Entry("204", http.StatusNoContent, OutcomeSuccess),
+		Entry("304", http.StatusNotModified, OutcomeSuccess),
+		Entry("400", http.StatusBadRequest, OutcomeClientError),
+		Entry("404", http.StatusNotFound, OutcomeClientError),
+		Entry("429", http.StatusTooManyRequests, OutcomeClientError),
+		Entry("500", http.StatusInternalServerError, OutcomeServerError),
+		Entry("503", http.StatusServiceUnavailable, OutcomeServerError),
+	)
+
+	It("Uses transport error when there is no response", func() {
+		// Close the server so that the connection fails:
+		address := apiServer.URL()
+		apiServer.Close()
+
+		// Send the request:
+		_, err := client.Get(address + "/api")
+		Expect(err).To(HaveOccurred())
+
+		// Verify the metrics:
+		metrics := metricsServer.Metrics()
+		Expect(metrics).To(MatchLine(
+			`^my_request_count\{.*code="0".*,outcome="transport_error".*\} 1$`,
+		))
+	})
+
+	It("Doesn't add outcome label by default", func() {
+		// Create a client without the outcome label:
+		registry := prometheus.NewPedanticRegistry()
+		wrapper, err := NewTransportWrapper().
+			Subsystem("your").
+			Registerer(registry).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		client := &http.Client{
+			Transport: wrapper.Wrap(http.DefaultTransport),
+		}
+		defer client.CloseIdleConnections()
+
+		// Send the request:
+		apiServer.AppendHandlers(RespondWith(http.StatusOK, nil))
+		response, err := client.Get(apiServer.URL() + "/api")
+		Expect(err).ToNot(HaveOccurred())
+		err = response.Body.Close()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Verify that the label isn't present:
+		families, err := registry.Gather()
+		Expect(err).ToNot(HaveOccurred())
+		for _, family := range families {
+			for _, metric := range family.GetMetric() {
+				for _, label := range metric.GetLabel() {
+					Expect(label.GetName()).ToNot(Equal("outcome"))
+				}
+			}
+		}
+	})
+})
+
+var _ = Describe("Caller", func() {
+	var (
+		apiServer     *Server
+		metricsServer *MetricsServer
+	)
+
+	BeforeEach(func() {
+		apiServer = NewServer()
+		metricsServer = NewMetricsServer()
+	})
+
+	AfterEach(func() {
+		metricsServer.Close()
+		apiServer.Close()
+	})
+
+	It("Uses the package of the test as the caller", func() {
+		// Create the client:
+		wrapper, err := NewTransportWrapper().
+			Subsystem("my").
+			Registerer(metricsServer.Registry()).
+			Caller(true).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		client := &http.Client{
+			Transport: wrapper.Wrap(http.DefaultTransport),
+		}
+		defer client.CloseIdleConnections()
+
+		// Send the request:
+		apiServer.AppendHandlers(RespondWith(http.StatusOK, nil))
+		response, err := client.Get(apiServer.URL() + "/api")
+		Expect(err).ToNot(HaveOccurred())
+		err = response.Body.Close()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Verify the metrics:
+		metrics := metricsServer.Metrics()
+		Expect(metrics).To(MatchLine(
+			`^my_request_count\{.*caller="github.com/openshift-online/ocm-sdk-go/metrics".*\} 1$`,
+		))
+	})
+
+	It("Doesn't add caller label by default", func() {
+		// Create the client:
+		wrapper, err := NewTransportWrapper().
+			Subsystem("my").
+			Registerer(metricsServer.Registry()).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		client := &http.Client{
+			Transport: wrapper.Wrap(http.DefaultTransport),
+		}
+		defer client.CloseIdleConnections()
+
+		// Send the request:
+		apiServer.AppendHandlers(RespondWith(http.StatusOK, nil))
+		response, err := client.Get(apiServer.URL() + "/api")
+		Expect(err).ToNot(HaveOccurred())
+		err = response.Body.Close()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Verify the metrics:
+		metrics := metricsServer.Metrics()
+		Expect(metrics).To(MatchLine(`^my_request_count\{.*\} 1$`))
+		Expect(metrics).ToNot(MatchLine(`^my_request_count\{.*caller=.*\} 1$`))
+	})
+})
+
+var _ = Describe("Caller package", func() {
+	DescribeTable(
+		"Extracts the package from the function name",
+		func(function, expected string) {
+			Expect(functionPackage(function)).To(Equal(expected))
+		},
+		Entry("Function", "main.main", "main"),
+		Entry("Standard", "net/http.(*Client).Do", "net/http"),
+		Entry(
+			"Method",
+			"github.com/my/project/pkg.(*Type).Method.func1",
+			"github.com/my/project/pkg",
+		),
+		Entry(
+			"Dotted path",
+			"gopkg.in/yaml%2ev3.Unmarshal",
+			"gopkg.in/yaml.v3",
+		),
+	)
+
+	DescribeTable(
+		"Detects standard packages",
+		func(pkg string, expected bool) {
+			Expect(isStandardPackage(pkg)).To(Equal(expected))
+		},
+		Entry("Runtime", "runtime", true),
+		Entry("Nested", "net/http", true),
+		Entry("Main", "main", false),
+		Entry("Module", "github.com/my/project", false),
+	)
+})
+
+var _ = Describe("DNS", func() {
+	var (
+		apiServer     *Server
+		metricsServer *MetricsServer
+		wrapper       *TransportWrapper
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		// Start the servers:
+		apiServer = NewServer()
+		apiServer.AppendHandlers(RespondWith(http.StatusOK, nil))
+		metricsServer = NewMetricsServer()
+
+		// Create the wrapper:
+		wrapper, err = NewTransportWrapper().
+			Subsystem("my").
+			Registerer(metricsServer.Registry()).
+			DNS(true).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		metricsServer.Close()
+		apiServer.Close()
+	})
+
+	// Send sends a request with the given client to the API server, replacing the host name with
+	// the given one.
+	var Send = func(client *http.Client, host string) {
+		address, err := url.Parse(apiServer.URL())
+		Expect(err).ToNot(HaveOccurred())
+		address.Host = net.JoinHostPort(host, address.Port())
+		address.Path = "/api/clusters_mgmt/v1/clusters"
+		response, err := client.Get(address.String())
+		Expect(err).ToNot(HaveOccurred())
+		err = response.Body.Close()
+		Expect(err).ToNot(HaveOccurred())
+	}
+
+	It("Counts lookups done by the transport as uncached", func() {
+		transport := &http.Transport{}
+		client := &http.Client{
+			Transport: wrapper.Wrap(transport),
+		}
+		defer client.CloseIdleConnections()
+		Send(client, "localhost")
+		metrics := metricsServer.Metrics()
+		Expect(metrics).To(MatchLine(
+			`^my_dns_lookup_count\{apiservice="ocm-clusters-service",cached="false"\} 1$`,
+		))
+		Expect(metrics).To(MatchLine(
+			`^my_dns_lookup_duration_count\{apiservice="ocm-clusters-service"\} 1$`,
+		))
+	})
+
+	It("Counts connections opened without lookup as cached", func() {
+		// Create a transport that resolves names itself, like a dialer with a DNS cache:
+		dialer := &net.Dialer{}
+		transport := &http.Transport{
+			DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+				_, port, err := net.SplitHostPort(address)
+				if err != nil {
+					return nil, err
+				}
+				return dialer.DialContext(ctx, network, net.JoinHostPort("127.0.0.1", port))
+			},
+		}
+		client := &http.Client{
+			Transport: wrapper.Wrap(transport),
+		}
+		defer client.CloseIdleConnections()
+		Send(client, "localhost")
+		metrics := metricsServer.Metrics()
+		Expect(metrics).To(MatchLine(
+			`^my_dns_lookup_count\{apiservice="ocm-clusters-service",cached="true"\} 1$`,
+		))
+		Expect(metrics).ToNot(MatchLine(`^my_dns_lookup_duration_count.*$`))
+	})
+
+	It("Doesn't count requests that reuse connections", func() {
+		apiServer.AppendHandlers(RespondWith(http.StatusOK, nil))
+		transport := &http.Transport{}
+		client := &http.Client{
+			Transport: wrapper.Wrap(transport),
+		}
+		defer client.CloseIdleConnections()
+		Send(client, "localhost")
+		Send(client, "localhost")
+		metrics := metricsServer.Metrics()
+		Expect(metrics).To(MatchLine(
+			`^my_dns_lookup_count\{apiservice="ocm-clusters-service",cached="false"\} 1$`,
+		))
+	})
+})
+
+var _ = Describe("TLS resumption", func() {
+	var (
+		apiServer     *Server
+		metricsServer *MetricsServer
+	)
+
+	BeforeEach(func() {
+		apiServer = NewTLSServer()
+		metricsServer = NewMetricsServer()
+	})
+
+	AfterEach(func() {
+		metricsServer.Close()
+		apiServer.Close()
+	})
+
+	// Send sends the given number of requests with the given client.
+	var Send = func(client *http.Client, count int) {
+		for i := 0; i < count; i++ {
+			apiServer.AppendHandlers(RespondWith(http.StatusOK, nil))
+			response, err := client.Get(apiServer.URL() + "/api/clusters_mgmt/v1/clusters")
+			Expect(err).ToNot(HaveOccurred())
+			_, err = io.Copy(io.Discard, response.Body)
+			Expect(err).ToNot(HaveOccurred())
+			err = response.Body.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}
+	}
+
+	// MakeClient creates a client that uses the given wrapper and that trusts the certificate of
+	// the server, optionally with a TLS session cache. Keep alive is disabled so that each request
+	// needs a new TLS handshake.
+	var MakeClient = func(wrapper *TransportWrapper, cache bool) *http.Client {
+		config := &tls.Config{
+			InsecureSkipVerify: true, // nolint
+		}
+		if cache {
+			config.ClientSessionCache = tls.NewLRUClientSessionCache(10)
+		}
+		return &http.Client{
+			Transport: wrapper.Wrap(&http.Transport{
+				TLSClientConfig:   config,
+				DisableKeepAlives: true,
+			}),
+		}
+	}
+
+	It("Counts full and resumed handshakes", func() {
+		wrapper, err := NewTransportWrapper().
+			Subsystem("my").
+			Registerer(metricsServer.Registry()).
+			TLSResumption(true).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		client := MakeClient(wrapper, true)
+		Send(client, 3)
+		metrics := metricsServer.Metrics()
+		Expect(metrics).To(MatchLine(
+			`^my_tls_handshake_count\{apiservice="ocm-clusters-service",resumed="false"\} 1$`,
+		))
+		Expect(metrics).To(MatchLine(
+			`^my_tls_handshake_count\{apiservice="ocm-clusters-service",resumed="true"\} 2$`,
+		))
+	})
+
+	It("Doesn't count resumed handshakes without session cache", func() {
+		wrapper, err := NewTransportWrapper().
+			Subsystem("my").
+			Registerer(metricsServer.Registry()).
+			TLSResumption(true).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		client := MakeClient(wrapper, false)
+		Send(client, 2)
+		metrics := metricsServer.Metrics()
+		Expect(metrics).To(MatchLine(
+			`^my_tls_handshake_count\{apiservice="ocm-clusters-service",resumed="false"\} 2$`,
+		))
+		Expect(metrics).ToNot(MatchLine(`^my_tls_handshake_count\{.*resumed="true".*$`))
+	})
+
+	It("Writes warning when the ratio is below the threshold", func() {
+		buffer := &bytes.Buffer{}
+		logger, err := logging.NewStdLoggerBuilder().
+			Streams(buffer, buffer).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		wrapper, err := NewTransportWrapper().
+			Subsystem("my").
+			Registerer(metricsServer.Registry()).
+			Logger(logger).
+			TLSResumptionThreshold(0.5).
+			TLSResumptionWindow(2).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		client := MakeClient(wrapper, false)
+		Send(client, 2)
+		Expect(buffer.String()).To(ContainSubstring(
+			"Only 0% of the last 2 TLS handshakes resumed a session",
+		))
+	})
+
+	It("Doesn't write warning when the ratio is above the threshold", func() {
+		buffer := &bytes.Buffer{}
+		logger, err := logging.NewStdLoggerBuilder().
+			Streams(buffer, buffer).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		wrapper, err := NewTransportWrapper().
+			Subsystem("my").
+			Registerer(metricsServer.Registry()).
+			Logger(logger).
+			TLSResumptionThreshold(0.5).
+			TLSResumptionWindow(4).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		client := MakeClient(wrapper, true)
+		Send(client, 4)
+		Expect(buffer.String()).ToNot(ContainSubstring("TLS handshakes"))
+	})
+
+	It("Can't be created with threshold but without logger", func() {
+		wrapper, err := NewTransportWrapper().
+			Subsystem("my").
+			Registerer(metricsServer.Registry()).
+			TLSResumptionThreshold(0.5).
+			Build()
+		Expect(err).To(HaveOccurred())
+		Expect(wrapper).To(BeNil())
+		Expect(err.Error()).To(ContainSubstring("logger is mandatory"))
+	})
+
+	It("Can't be created with threshold greater than one", func() {
+		wrapper, err := NewTransportWrapper().
+			Subsystem("my").
+			Registerer(metricsServer.Registry()).
+			Logger(logger).
+			TLSResumptionThreshold(1.5).
+			Build()
+		Expect(err).To(HaveOccurred())
+		Expect(wrapper).To(BeNil())
+		Expect(err.Error()).To(ContainSubstring("between zero and one"))
+	})
+})
+
+var _ = Describe("Context labels", func() {
+	var (
+		apiServer     *Server
+		metricsServer *MetricsServer
+		client        *http.Client
+	)
+
+	BeforeEach(func() {
+		// Start the servers:
+		apiServer = NewServer()
+		metricsServer = NewMetricsServer()
+
+		// Create the client:
+		wrapper, err := NewTransportWrapper().
+			Subsystem("my").
+			Registerer(metricsServer.Registry()).
+			ContextLabel("team").
+			ContextLabels("job").
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		client = &http.Client{
+			Transport: wrapper.Wrap(http.DefaultTransport),
+		}
+	})
+
+	AfterEach(func() {
+		client.CloseIdleConnections()
+		metricsServer.Close()
+		apiServer.Close()
+	})
+
+	// Send sends a request with the given context.
+	var Send = func(ctx context.Context) {
+		apiServer.AppendHandlers(RespondWith(http.StatusOK, nil))
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, apiServer.URL()+"/api", nil)
+		Expect(err).ToNot(HaveOccurred())
+		response, err := client.Do(request)
+		Expect(err).ToNot(HaveOccurred())
+		err = response.Body.Close()
+		Expect(err).ToNot(HaveOccurred())
+	}
+
+	It("Adds the labels from the context", func() {
+		ctx := WithLabels(context.Background(), map[string]string{
+			"team": "infra",
+			"job":  "sync",
+		})
+		Send(ctx)
+		metrics := metricsServer.Metrics()
+		Expect(metrics).To(MatchLine(`^my_request_count\{.*,job="sync",.*,team="infra"\} 1$`))
+	})
+
+	It("Merges labels from parent contexts", func() {
+		ctx := WithLabels(context.Background(), map[string]string{
+			"team": "infra",
+			"job":  "sync",
+		})
+		ctx = WithLabels(ctx, map[string]string{
+			"job": "cleanup",
+		})
+		Send(ctx)
+		metrics := metricsServer.Metrics()
+		Expect(metrics).To(MatchLine(`^my_request_count\{.*,job="cleanup",.*,team="infra"\} 1$`))
+	})
+
+	It("Uses empty values when the context doesn't contain the labels", func() {
+		Send(context.Background())
+		metrics := metricsServer.Metrics()
+		Expect(metrics).To(MatchLine(`^my_request_count\{.*,job="",.*,team=""\} 1$`))
+	})
+
+	It("Ignores labels that haven't been declared", func() {
+		ctx := WithLabels(context.Background(), map[string]string{
+			"team": "infra",
+			"user": "joe",
+		})
+		Send(ctx)
+		metrics := metricsServer.Metrics()
+		Expect(metrics).To(MatchLine(`^my_request_count\{.*,team="infra"\} 1$`))
+		Expect(metrics).ToNot(MatchLine(`^.*user=.*$`))
+	})
+
+	DescribeTable(
+		"Rejects invalid label names",
+		func(name, expected string) {
+			_, err := NewTransportWrapper().
+				Subsystem("your").
+				Registerer(prometheus.NewPedanticRegistry()).
+				ContextLabels("team", name).
+				Build()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(expected))
+		},
+		Entry("Syntax", "my-label", "isn't valid"),
+		Entry("Reserved", "le", "reserved"),
+		Entry("Built-in", "code", "already used"),
+		Entry("Duplicated", "team", "already used"),
+	)
+})
+
+var _ = Describe("Bytes", func() {
+	var (
+		apiServer     *Server
+		metricsServer *MetricsServer
+		client        *http.Client
+	)
+
+	BeforeEach(func() {
+		// Start the servers:
+		apiServer = NewServer()
+		metricsServer = NewMetricsServer()
+
+		// Create the client:
+		wrapper, err := NewTransportWrapper().
+			Subsystem("my").
+			Registerer(metricsServer.Registry()).
+			Bytes(true).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		client = &http.Client{
+			Transport: wrapper.Wrap(http.DefaultTransport),
+		}
+	})
+
+	AfterEach(func() {
+		client.CloseIdleConnections()
+		metricsServer.Close()
+		apiServer.Close()
+	})
+
+	It("Counts bytes of request and response bodies", func() {
+		// Send the request:
+		apiServer.AppendHandlers(RespondWith(http.StatusOK, "0123456789"))
+		response, err := client.Post(
+			apiServer.URL()+"/api/clusters_mgmt/v1/clusters",
+			"application/json",
+			strings.NewReader("01234"),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = io.ReadAll(response.Body)
+		Expect(err).ToNot(HaveOccurred())
+		err = response.Body.Close()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Verify the metrics:
+		metrics := metricsServer.Metrics()
+		Expect(metrics).To(MatchLine(
+			`^my_bytes_sent\{apiservice="ocm-clusters-service",method="POST",` +
+				`path="/api/clusters_mgmt/v1/clusters"\} 5$`,
+		))
+		Expect(metrics).To(MatchLine(
+			`^my_bytes_received\{apiservice="ocm-clusters-service",method="POST",` +
+				`path="/api/clusters_mgmt/v1/clusters"\} 10$`,
+		))
+	})
+
+	It("Doesn't count bytes of requests without body", func() {
+		// Send the request:
+		apiServer.AppendHandlers(RespondWith(http.StatusOK, "0123456789"))
+		response, err := client.Get(apiServer.URL() + "/api/clusters_mgmt/v1/clusters")
+		Expect(err).ToNot(HaveOccurred())
+		_, err = io.ReadAll(response.Body)
+		Expect(err).ToNot(HaveOccurred())
+		err = response.Body.Close()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Verify the metrics:
+		metrics := metricsServer.Metrics()
+		Expect(metrics).ToNot(MatchLine(`^my_bytes_sent\{.*$`))
+		Expect(metrics).To(MatchLine(`^my_bytes_received\{.*\} 10$`))
+	})
+})
+
+var _ = Describe("Decode errors", func() {
+	var (
+		apiServer     *Server
+		metricsServer *MetricsServer
+		client        *http.Client
+	)
+
+	BeforeEach(func() {
+		// Start the servers:
+		apiServer = NewServer()
+		metricsServer = NewMetricsServer()
+
+		// Create the client:
+		wrapper, err := NewTransportWrapper().
+			Subsystem("my").
+			Registerer(metricsServer.Registry()).
+			DecodeErrors(true).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		client = &http.Client{
+			Transport: wrapper.Wrap(http.DefaultTransport),
+		}
+	})
+
+	AfterEach(func() {
+		client.CloseIdleConnections()
+		metricsServer.Close()
+		apiServer.Close()
+	})
+
+	It("Counts errors reported for the response", func() {
+		// Send the request:
+		apiServer.AppendHandlers(RespondWith(http.StatusOK, `{"id":`))
+		response, err := client.Get(apiServer.URL() + "/api/clusters_mgmt/v1/clusters/123")
+		Expect(err).ToNot(HaveOccurred())
+		defer response.Body.Close()
+
+		// Report the error:
+		_, err = cmv1.UnmarshalCluster(response.Body)
+		Expect(err).To(HaveOccurred())
+		helpers.ReportDecodeError(response, err)
+
+		// Verify the metrics:
+		metrics := metricsServer.Metrics()
+		Expect(metrics).To(MatchLine(
+			`^my_response_decode_error\{apiservice="ocm-clusters-service",` +
+				`reason="eof"\} 1$`,
+		))
+	})
+
+	It("Doesn't count responses without errors", func() {
+		// Send the request:
+		apiServer.AppendHandlers(RespondWith(http.StatusOK, `{"id":"123"}`))
+		response, err := client.Get(apiServer.URL() + "/api/clusters_mgmt/v1/clusters/123")
+		Expect(err).ToNot(HaveOccurred())
+		defer response.Body.Close()
+
+		// Report the result:
+		_, err = cmv1.UnmarshalCluster(response.Body)
+		Expect(err).ToNot(HaveOccurred())
+		helpers.ReportDecodeError(response, err)
+
+		// Verify the metrics:
+		metrics := metricsServer.Metrics()
+		Expect(metrics).ToNot(MatchLine(`^my_response_decode_error\{.*$`))
+	})
+
+	// RespondWithJSON creates a handler that responds with the given status code and body, and
+	// with the JSON content type.
+	var RespondWithJSON = func(status int, body string) http.HandlerFunc {
+		return RespondWith(status, body, http.Header{
+			"Content-Type": []string{"application/json"},
+		})
+	}
+
+	DescribeTable(
+		"Checks body when closed",
+		func(body string, expected string) {
+			// Send the request:
+			apiServer.AppendHandlers(RespondWithJSON(http.StatusOK, body))
+			response, err := client.Get(apiServer.URL() + "/api/clusters_mgmt/v1/clusters/123")
+			Expect(err).ToNot(HaveOccurred())
+
+			// Read part of the body, like the decoder does when it finds an error:
+			_, err = response.Body.Read(make([]byte, 1))
+			Expect(err).ToNot(HaveOccurred())
+			err = response.Body.Close()
+			Expect(err).ToNot(HaveOccurred())
+
+			// Verify the metrics:
+			metrics := metricsServer.Metrics()
+			if expected == "" {
+				Expect(metrics).ToNot(MatchLine(`^my_response_decode_error\{.*$`))
+			} else {
+				Expect(metrics).To(MatchLine(
+					`^my_response_decode_error\{apiservice="ocm-clusters-service",`+
+						`reason="%s"\} 1$`,
+					expected,
+				))
+			}
+		},
+		Entry("Valid", `{"id":"123"}`, ""),
+		Entry("Wrong type", `{"id":123}`, ""),
+		Entry("Truncated string", `{"id":"12`, DecodeErrorEOF),
+		Entry("Truncated object", `{"id":`, DecodeErrorEOF),
+		Entry("Missing colon", `{"id" "123"}`, DecodeErrorSyntax),
+		Entry("Junk", `{junk}`, DecodeErrorSyntax),
+	)
+
+	It("Counts reported errors only once", func() {
+		// Send the request:
+		apiServer.AppendHandlers(RespondWithJSON(http.StatusOK, `{"id":`))
+		response, err := client.Get(apiServer.URL() + "/api/clusters_mgmt/v1/clusters/123")
+		Expect(err).ToNot(HaveOccurred())
+
+		// Report the error and close the body:
+		_, err = cmv1.UnmarshalCluster(response.Body)
+		Expect(err).To(HaveOccurred())
+		helpers.ReportDecodeError(response, err)
+		err = response.Body.Close()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Verify the metrics:
+		metrics := metricsServer.Metrics()
+		Expect(metrics).To(MatchLine(
+			`^my_response_decode_error\{apiservice="ocm-clusters-service",` +
+				`reason="eof"\} 1$`,
+		))
+	})
+
+	It("Doesn't check error responses", func() {
+		// Send the request:
+		apiServer.AppendHandlers(RespondWithJSON(http.StatusNotFound, `{"id":`))
+		response, err := client.Get(apiServer.URL() + "/api/clusters_mgmt/v1/clusters/123")
+		Expect(err).ToNot(HaveOccurred())
+		err = response.Body.Close()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Verify the metrics:
+		metrics := metricsServer.Metrics()
+		Expect(metrics).ToNot(MatchLine(`^my_response_decode_error\{.*$`))
+	})
+
+	It("Doesn't check other content types", func() {
+		// Send the request:
+		apiServer.AppendHandlers(RespondWith(http.StatusOK, `{"id":`, http.Header{
+			"Content-Type": []string{"text/plain"},
+		}))
+		response, err := client.Get(apiServer.URL() + "/api/clusters_mgmt/v1/clusters/123")
+		Expect(err).ToNot(HaveOccurred())
+		err = response.Body.Close()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Verify the metrics:
+		metrics := metricsServer.Metrics()
+		Expect(metrics).ToNot(MatchLine(`^my_response_decode_error\{.*$`))
+	})
+
+	DescribeTable(
+		"Calculates reason from error",
+		func(body string, expected string) {
+			_, err := cmv1.UnmarshalCluster(body)
+			Expect(err).To(HaveOccurred())
+			Expect(decodeErrorReason(err)).To(Equal(expected))
+		},
+		Entry("Truncated string", `{"id":"12`, DecodeErrorEOF),
+		Entry("Truncated object", `{"id":`, DecodeErrorEOF),
+		Entry("Empty object", `{`, DecodeErrorEOF),
+		Entry("Missing colon", `{"id" "123"}`, DecodeErrorSyntax),
+		Entry("Junk", `junk`, DecodeErrorSyntax),
+		Entry("Number instead of string", `{"id":123}`, DecodeErrorType),
+		Entry("Boolean instead of string", `{"id":true}`, DecodeErrorType),
+		Entry("Array instead of object", `{"nodes":[1]}`, DecodeErrorType),
+		Entry("String instead of number", `{"nodes":{"compute":"1"}}`, DecodeErrorType),
+	)
+
+	It("Calculates reason from unexpected end of file", func() {
+		Expect(decodeErrorReason(io.EOF)).To(Equal(DecodeErrorEOF))
+		Expect(decodeErrorReason(io.ErrUnexpectedEOF)).To(Equal(DecodeErrorEOF))
+	})
+})
+
+var _ = Describe("Idempotent", func() {
+	var (
+		apiServer     *Server
+		metricsServer *MetricsServer
+		client        *http.Client
+	)
+
+	BeforeEach(func() {
+		// Start the servers:
+		apiServer = NewServer()
+		metricsServer = NewMetricsServer()
+
+		// Create the client:
+		wrapper, err := NewTransportWrapper().
+			Subsystem("my").
+			Registerer(metricsServer.Registry()).
+			Idempotent(true).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		client = &http.Client{
+			Transport: wrapper.Wrap(http.DefaultTransport),
+		}
+	})
+
+	AfterEach(func() {
+		client.CloseIdleConnections()
+		metricsServer.Close()
+		apiServer.Close()
+	})
+
+	DescribeTable(
+		"Calculates label from method",
+		func(method string, expected string) {
+			apiServer.AppendHandlers(RespondWith(http.StatusOK, nil))
+			request, err := http.NewRequest(method, apiServer.URL()+"/api", nil)
+			Expect(err).ToNot(HaveOccurred())
+			response, err := client.Do(request)
+			Expect(err).ToNot(HaveOccurred())
+			err = response.Body.Close()
+			Expect(err).ToNot(HaveOccurred())
+			metrics := metricsServer.Metrics()
+			Expect(metrics).To(MatchLine(
+				`^my_request_count\{.*,idempotent="%s",method="%s".*\} 1$`,
+				expected, method,
+			))
+		},
+		Entry("GET", http.MethodGet, "true"),
+		Entry("HEAD", http.MethodHead, "true"),
+		Entry("PUT", http.MethodPut, "true"),
+		Entry("DELETE", http.MethodDelete, "true"),
+		Entry("POST", http.MethodPost, "false"),
+		Entry("PATCH", http.MethodPatch, "false"),
+	)
+})
+
+var _ = Describe("Organization", func() {
+	var (
+		apiServer     *Server
+		metricsServer *MetricsServer
+		client        *http.Client
+	)
+
+	BeforeEach(func() {
+		// Start the servers:
+		apiServer = NewServer()
+		metricsServer = NewMetricsServer()
+
+		// Create the client:
+		wrapper, err := NewTransportWrapper().
+			Subsystem("my").
+			Registerer(metricsServer.Registry()).
+			Organization(true).
+			OrganizationLimit(2).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		client = &http.Client{
+			Transport: wrapper.Wrap(http.DefaultTransport),
+		}
+	})
+
+	AfterEach(func() {
+		client.CloseIdleConnections()
+		metricsServer.Close()
+		apiServer.Close()
+	})
+
+	send := func(ctx context.Context) {
+		apiServer.AppendHandlers(RespondWith(http.StatusOK, nil))
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, apiServer.URL()+"/api", nil)
+		Expect(err).ToNot(HaveOccurred())
+		response, err := client.Do(request)
+		Expect(err).ToNot(HaveOccurred())
+		err = response.Body.Close()
+		Expect(err).ToNot(HaveOccurred())
+	}
+
+	It("Adds the organization from the context", func() {
+		send(tenancy.WithOrganization(context.Background(), "123"))
+		metrics := metricsServer.Metrics()
+		Expect(metrics).To(MatchLine(`^my_request_count\{.*,organization="123",.*\} 1$`))
+	})
+
+	It("Uses 'unknown' when there is no organization in the context", func() {
+		send(context.Background())
+		metrics := metricsServer.Metrics()
+		Expect(metrics).To(MatchLine(`^my_request_count\{.*,organization="unknown",.*\} 1$`))
+	})
+
+	It("Uses 'other' when the limit is exceeded", func() {
+		send(tenancy.WithOrganization(context.Background(), "123"))
+		send(tenancy.WithOrganization(context.Background(), "456"))
+		send(tenancy.WithOrganization(context.Background(), "789"))
+		metrics := metricsServer.Metrics()
+		Expect(metrics).To(MatchLine(`^my_request_count\{.*,organization="123",.*\} 1$`))
+		Expect(metrics).To(MatchLine(`^my_request_count\{.*,organization="456",.*\} 1$`))
+		Expect(metrics).To(MatchLine(`^my_request_count\{.*,organization="other",.*\} 1$`))
+	})
+
+	It("Rejects zero limit", func() {
+		_, err := NewTransportWrapper().
+			Subsystem("my").
+			Registerer(metricsServer.Registry()).
+			Organization(true).
+			OrganizationLimit(0).
+			Build()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("organization limit"))
+	})
+})
+
+var _ = Describe("Series count", func() {
+	var (
+		apiServer     *Server
+		metricsServer *MetricsServer
+		wrapper       *TransportWrapper
+		client        *http.Client
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		// Start the servers:
+		apiServer = NewServer()
+		apiServer.SetAllowUnhandledRequests(true)
+		apiServer.SetUnhandledRequestStatusCode(http.StatusOK)
+		metricsServer = NewMetricsServer()
+
+		// Create the client:
+		wrapper, err = NewTransportWrapper().
+			Subsystem("my").
+			Registerer(metricsServer.Registry()).
+			SeriesCount(true).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		client = &http.Client{
+			Transport: wrapper.Wrap(http.DefaultTransport),
+		}
+	})
+
+	AfterEach(func() {
+		client.CloseIdleConnections()
+		metricsServer.Close()
+		apiServer.Close()
+	})
+
+	// Send sends a request with the given path to the API server.
+	var Send = func(path string) {
+		response, err := client.Get(apiServer.URL() + path)
+		Expect(err).ToNot(HaveOccurred())
+		err = response.Body.Close()
+		Expect(err).ToNot(HaveOccurred())
+	}
+
+	It("Is zero before sending requests", func() {
+		metrics := metricsServer.Metrics()
+		Expect(metrics).To(MatchLine(`^my_metric_series_count 0$`))
+	})
+
+	It("Counts each combination of labels once per metric", func() {
+		Send("/api/clusters_mgmt/v1/clusters")
+		Send("/api/clusters_mgmt/v1/clusters")
+		Send("/api/accounts_mgmt/v1/accounts")
+		metrics := metricsServer.Metrics()
+		Expect(metrics).To(MatchLine(`^my_metric_series_count 4$`))
+	})
+
+	It("Counts the enabled optional metrics", func() {
+		// Create a client with additional metrics:
+		registry := prometheus.NewRegistry()
+		wrapper, err := NewTransportWrapper().
+			Subsystem("other").
+			Registerer(registry).
+			Bytes(true).
+			SeriesCount(true).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		client := &http.Client{
+			Transport: wrapper.Wrap(http.DefaultTransport),
+		}
+		defer client.CloseIdleConnections()
+
+		// Send the request:
+		response, err := client.Post(apiServer.URL()+"/api", "text/plain", strings.NewReader("0"))
+		Expect(err).ToNot(HaveOccurred())
+		err = response.Body.Close()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Verify the gauge, one series for the count, the duration and the bytes sent and
+		// received:
+		families, err := registry.Gather()
+		Expect(err).ToNot(HaveOccurred())
+		var value float64
+		for _, family := range families {
+			if family.GetName() == "other_metric_series_count" {
+				value = family.GetMetric()[0].GetGauge().GetValue()
+			}
+		}
+		Expect(value).To(Equal(4.0))
+	})
+
+	It("Decreases after reset", func() {
+		Send("/api/clusters_mgmt/v1/clusters")
+		wrapper.Reset()
+		metrics := metricsServer.Metrics()
+		Expect(metrics).To(MatchLine(`^my_metric_series_count 0$`))
+	})
+
+	It("Isn't generated by default", func() {
+		registry := prometheus.NewRegistry()
+		_, err := NewTransportWrapper().
+			Subsystem("other").
+			Registerer(registry).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		families, err := registry.Gather()
+		Expect(err).ToNot(HaveOccurred())
+		for _, family := range families {
+			Expect(family.GetName()).ToNot(Equal("other_metric_series_count"))
+		}
+	})
+})
+
+var _ = Describe("Reset", func() {
+	var (
+		apiServer     *Server
+		metricsServer *MetricsServer
+		wrapper       *TransportWrapper
+		client        *http.Client
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		// Start the servers:
+		apiServer = NewServer()
+		apiServer.SetAllowUnhandledRequests(true)
+		apiServer.SetUnhandledRequestStatusCode(http.StatusOK)
+		metricsServer = NewMetricsServer()
+
+		// Create the client:
+		wrapper, err = NewTransportWrapper().
+			Subsystem("my").
+			Registerer(metricsServer.Registry()).
+			Bytes(true).
+			Classifier(func(request *http.Request) string {
+				return request.URL.Query().Get("class")
+			}).
+			ClassLimit(1).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		client = &http.Client{
+			Transport: wrapper.Wrap(http.DefaultTransport),
+		}
+	})
+
+	AfterEach(func() {
+		client.CloseIdleConnections()
+		metricsServer.Close()
+		apiServer.Close()
+	})
+
+	// Send sends a request with the given class to the API server.
+	var Send = func(class string) {
+		response, err := client.Post(
+			apiServer.URL()+"/api?class="+class,
+			"text/plain",
+			strings.NewReader("01234"),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = io.Copy(io.Discard, response.Body)
+		Expect(err).ToNot(HaveOccurred())
+		err = response.Body.Close()
+		Expect(err).ToNot(HaveOccurred())
+	}
+
+	It("Starts counters and histograms from zero", func() {
+		Send("a")
+		Send("a")
+		wrapper.Reset()
+		Send("a")
+		metrics := metricsServer.Metrics()
+		Expect(metrics).To(MatchLine(`^my_request_count\{.*\} 1$`))
+		Expect(metrics).To(MatchLine(`^my_request_duration_count\{.*\} 1$`))
+		Expect(metrics).To(MatchLine(`^my_bytes_sent\{.*\} 5$`))
+	})
+
+	It("Forgets the classes seen", func() {
+		Send("a")
+		wrapper.Reset()
+		Send("b")
+		metrics := metricsServer.Metrics()
+		Expect(metrics).To(MatchLine(`^my_request_count\{.*class="b".*\} 1$`))
+		Expect(metrics).ToNot(MatchLine(`^my_request_count\{.*class="a".*$`))
+		Expect(metrics).ToNot(MatchLine(`^my_request_count\{.*class="%s".*$`, OtherClass))
+	})
+})
+
+var _ = Describe("Metric names", func() {
+	It("Returns the default metrics", func() {
+		wrapper, err := NewTransportWrapper().
+			Subsystem("my").
+			Registerer(prometheus.NewRegistry()).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(wrapper.MetricNames()).To(Equal([]string{
+			"my_request_count",
+			"my_request_duration",
+		}))
+	})
+
+	It("Returns the optional metrics when enabled", func() {
+		wrapper, err := NewTransportWrapper().
+			Subsystem("my").
+			Registerer(prometheus.NewRegistry()).
+			Redirects(true).
+			StuckAfter(time.Minute).
+			BodyReadDuration(true).
+			DNS(true).
+			TLSResumption(true).
+			Bytes(true).
+			DecodeErrors(true).
+			SeriesCount(true).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(wrapper.MetricNames()).To(Equal([]string{
+			"my_request_count",
+			"my_request_duration",
+			"my_redirect_count",
+			"my_request_stuck",
+			"my_body_read_duration",
+			"my_dns_lookup_count",
+			"my_dns_lookup_duration",
+			"my_tls_handshake_count",
+			"my_bytes_sent",
+			"my_bytes_received",
+			"my_response_decode_error",
+			"my_metric_series_count",
+		}))
+	})
+
+	It("Returns the OpenMetrics names when enabled", func() {
+		wrapper, err := NewTransportWrapper().
+			Subsystem("my").
+			Registerer(prometheus.NewRegistry()).
+			OpenMetrics(true).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(wrapper.MetricNames()).To(Equal([]string{
+			"my_request_count_total",
+			"my_request_duration_seconds",
+		}))
+	})
+
+	It("Returns the OpenMetrics names of the optional counters", func() {
+		wrapper, err := NewTransportWrapper().
+			Subsystem("my").
+			Registerer(prometheus.NewRegistry()).
+			OpenMetrics(true).
+			StuckAfter(time.Minute).
+			BodyReadTimeout(time.Minute).
+			Bytes(true).
+			DecodeErrors(true).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(wrapper.MetricNames()).To(Equal([]string{
+			"my_request_count_total",
+			"my_request_duration_seconds",
+			"my_request_stuck_total",
+			"my_body_read_timeout_total",
+			"my_bytes_sent_total",
+			"my_bytes_received_total",
+			"my_response_decode_error_total",
+		}))
+	})
+
+	It("Returns names that match the registered metrics", func() {
+		// Create the wrapper:
+		registry := prometheus.NewRegistry()
+		wrapper, err := NewTransportWrapper().
+			Subsystem("my").
+			Registerer(registry).
+			Redirects(true).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Increase the metrics, as otherwise the registry doesn't return them:
+		apiServer := NewServer()
+		defer apiServer.Close()
+		apiServer.AppendHandlers(
+			RespondWith(http.StatusFound, nil, http.Header{
+				"Location": []string{"/api"},
+			}),
+			RespondWith(http.StatusOK, nil),
+		)
+		client := &http.Client{
+			Transport: wrapper.Wrap(http.DefaultTransport),
+		}
+		defer client.CloseIdleConnections()
+		response, err := client.Get(apiServer.URL() + "/api/clusters_mgmt")
+		Expect(err).ToNot(HaveOccurred())
+		err = response.Body.Close()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Verify the names:
+		families, err := registry.Gather()
+		Expect(err).ToNot(HaveOccurred())
+		var names []string
+		for _, family := range families {
+			names = append(names, family.GetName())
+		}
+		Expect(wrapper.MetricNames()).To(ConsistOf(names))
+	})
+
+	It("Returns a copy", func() {
+		wrapper, err := NewTransportWrapper().
+			Subsystem("my").
+			Registerer(prometheus.NewRegistry()).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		names := wrapper.MetricNames()
+		names[0] = "junk"
+		Expect(wrapper.MetricNames()[0]).To(Equal("my_request_count"))
+	})
+})
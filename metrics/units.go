@@ -0,0 +1,93 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the types and functions used to select the unit of duration metrics.
+
+package metrics
+
+import (
+	"fmt"
+	"time"
+)
+
+// DurationUnit is the unit used to record durations in histograms.
+type DurationUnit int
+
+// Supported duration units:
+const (
+	// DurationUnitSeconds records durations in seconds. This is the default, and the name of
+	// the metric doesn't have any suffix, for example `api_outbound_request_duration`.
+	DurationUnitSeconds DurationUnit = iota
+
+	// DurationUnitMilliseconds records durations in milliseconds. The name of the metric has
+	// the `_milliseconds` suffix, for example `api_outbound_request_duration_milliseconds`.
+	DurationUnitMilliseconds
+)
+
+// check returns an error if the unit isn't one of the supported ones.
+func (u DurationUnit) check() error {
+	switch u {
+	case DurationUnitSeconds, DurationUnitMilliseconds:
+		return nil
+	default:
+		return fmt.Errorf("duration unit %d isn't valid", int(u))
+	}
+}
+
+// name adds to the given metric name the suffix corresponding to the unit.
+func (u DurationUnit) name(base string) string {
+	if u == DurationUnitMilliseconds {
+		return base + "_milliseconds"
+	}
+	return base
+}
+
+// help returns the description of the duration metric using the given subject and the name of the
+// unit, for example `Request duration in seconds.`.
+func (u DurationUnit) help(subject string) string {
+	if u == DurationUnitMilliseconds {
+		return subject + " duration in milliseconds."
+	}
+	return subject + " duration in seconds."
+}
+
+// buckets returns the default histogram buckets expressed in the unit.
+func (u DurationUnit) buckets() []float64 {
+	seconds := []float64{
+		0.1,
+		1.0,
+		10.0,
+		30.0,
+	}
+	result := make([]float64, len(seconds))
+	for i, bucket := range seconds {
+		result[i] = bucket * u.scale()
+	}
+	return result
+}
+
+// value converts the given duration into a value expressed in the unit.
+func (u DurationUnit) value(d time.Duration) float64 {
+	return d.Seconds() * u.scale()
+}
+
+// scale returns the number of units contained in one second.
+func (u DurationUnit) scale() float64 {
+	if u == DurationUnitMilliseconds {
+		return 1000
+	}
+	return 1
+}
@@ -23,8 +23,10 @@ import (
 	"time"
 
 	"github.com/onsi/gomega/ghttp"
+	"github.com/prometheus/client_golang/prometheus"
 
 	. "github.com/onsi/ginkgo/v2/dsl/core"             // nolint
+	. "github.com/onsi/ginkgo/v2/dsl/table"            // nolint
 	. "github.com/onsi/gomega"                         // nolint
 	. "github.com/openshift-online/ocm-sdk-go/testing" // nolint
 )
@@ -223,3 +225,230 @@ var _ = Describe("Metrics disabled", func() {
 		Expect(metrics).To(ConsistOf(""))
 	})
 })
+
+var _ = Describe("Metrics with attempts", func() {
+	It("Measures each attempt separately", func() {
+		// Create the tokens:
+		accessToken := MakeTokenString("Bearer", 5*time.Minute)
+
+		// Create the API server so that the first attempt fails:
+		apiServer := MakeTCPServer()
+		defer apiServer.Close()
+		apiServer.AppendHandlers(
+			RespondWithJSON(http.StatusServiceUnavailable, "{}"),
+			RespondWithJSON(http.StatusOK, "{}"),
+		)
+
+		// Create the metrics server:
+		metricsServer := NewMetricsServer()
+		defer metricsServer.Close()
+
+		// Create the connection:
+		connection, err := NewConnectionBuilder().
+			Logger(logger).
+			URL(apiServer.URL()).
+			Tokens(accessToken).
+			RetryInterval(10 * time.Millisecond).
+			MetricsSubsystem("my").
+			MetricsRegisterer(metricsServer.Registry()).
+			MetricsAttempts(true).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err = connection.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+
+		// Send the request:
+		response, err := connection.Get().
+			Path("/api/clusters_mgmt/v1/clusters").
+			Send()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.Status()).To(Equal(http.StatusOK))
+
+		// Verify the metrics:
+		metrics := metricsServer.Metrics()
+		Expect(metrics).To(MatchLine(`^my_request_count\{.*attempt="1".*code="503".*\} 1$`))
+		Expect(metrics).To(MatchLine(`^my_request_count\{.*attempt="2".*code="200".*\} 1$`))
+	})
+})
+
+var _ = Describe("Metrics with decode errors", func() {
+	DescribeTable(
+		"Counts errors decoding type safe responses",
+		func(body string, reason string) {
+			// Create the tokens:
+			accessToken := MakeTokenString("Bearer", 5*time.Minute)
+
+			// Create the API server:
+			apiServer := MakeTCPServer()
+			defer apiServer.Close()
+			apiServer.AppendHandlers(
+				RespondWithJSON(http.StatusOK, body),
+			)
+
+			// Create the metrics server:
+			metricsServer := NewMetricsServer()
+			defer metricsServer.Close()
+
+			// Create the connection:
+			connection, err := NewConnectionBuilder().
+				Logger(logger).
+				URL(apiServer.URL()).
+				Tokens(accessToken).
+				MetricsSubsystem("my").
+				MetricsRegisterer(metricsServer.Registry()).
+				MetricsDecodeErrors(true).
+				Build()
+			Expect(err).ToNot(HaveOccurred())
+			defer func() {
+				err = connection.Close()
+				Expect(err).ToNot(HaveOccurred())
+			}()
+
+			// Send the request:
+			_, err = connection.ClustersMgmt().V1().Clusters().Cluster("123").Get().
+				Send()
+			Expect(err).To(HaveOccurred())
+
+			// Verify the metrics:
+			metrics := metricsServer.Metrics()
+			Expect(metrics).To(MatchLine(
+				`^my_response_decode_error\{apiservice="ocm-clusters-service",`+
+					`reason="%s"\} 1$`,
+				reason,
+			))
+		},
+		Entry("Truncated", `{"id":"12`, "eof"),
+		Entry("Invalid", `{"id" "123"}`, "syntax"),
+	)
+
+	It("Doesn't count valid responses", func() {
+		// Create the tokens:
+		accessToken := MakeTokenString("Bearer", 5*time.Minute)
+
+		// Create the API server:
+		apiServer := MakeTCPServer()
+		defer apiServer.Close()
+		apiServer.AppendHandlers(
+			RespondWithJSON(http.StatusOK, `{"id":"123"}`),
+		)
+
+		// Create the metrics server:
+		metricsServer := NewMetricsServer()
+		defer metricsServer.Close()
+
+		// Create the connection:
+		connection, err := NewConnectionBuilder().
+			Logger(logger).
+			URL(apiServer.URL()).
+			Tokens(accessToken).
+			MetricsSubsystem("my").
+			MetricsRegisterer(metricsServer.Registry()).
+			MetricsDecodeErrors(true).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err = connection.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+
+		// Send the request:
+		_, err = connection.ClustersMgmt().V1().Clusters().Cluster("123").Get().
+			Send()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Verify the metrics:
+		metrics := metricsServer.Metrics()
+		Expect(metrics).ToNot(MatchLine(`^my_response_decode_error\{.*$`))
+	})
+})
+
+var _ = Describe("Metrics exclusive", func() {
+	It("Rejects connection with a subsystem already in use", func() {
+		// Create the tokens:
+		accessToken := MakeTokenString("Bearer", 5*time.Minute)
+
+		// Create the metrics server:
+		metricsServer := NewMetricsServer()
+		defer metricsServer.Close()
+
+		// Create the first connection:
+		first, err := NewConnectionBuilder().
+			Logger(logger).
+			Tokens(accessToken).
+			MetricsSubsystem("my").
+			MetricsRegisterer(metricsServer.Registry()).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err = first.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+
+		// Try to create the second connection:
+		second, err := NewConnectionBuilder().
+			Logger(logger).
+			Tokens(accessToken).
+			MetricsSubsystem("my").
+			MetricsRegisterer(metricsServer.Registry()).
+			MetricsExclusive(true).
+			Build()
+		Expect(err).To(HaveOccurred())
+		Expect(second).To(BeNil())
+		Expect(err.Error()).To(ContainSubstring("already registered"))
+	})
+})
+
+var _ = Describe("Metrics flush interval", func() {
+	It("Flushes pending metrics when the connection is closed", func() {
+		// Create the tokens:
+		accessToken := MakeTokenString("Bearer", 5*time.Minute)
+
+		// Create the server:
+		apiServer := MakeTCPServer()
+		defer apiServer.Close()
+		apiServer.AppendHandlers(
+			RespondWithJSON(http.StatusOK, ""),
+		)
+
+		// Create the connection:
+		registry := prometheus.NewRegistry()
+		connection, err := NewConnectionBuilder().
+			Logger(logger).
+			URL(apiServer.URL()).
+			Tokens(accessToken).
+			MetricsSubsystem("my").
+			MetricsRegisterer(registry).
+			MetricsFlushInterval(time.Hour).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Count returns the value of the request count metric:
+		Count := func() float64 {
+			families, err := registry.Gather()
+			Expect(err).ToNot(HaveOccurred())
+			var result float64
+			for _, family := range families {
+				if family.GetName() == "my_request_count" {
+					for _, metric := range family.GetMetric() {
+						result += metric.GetCounter().GetValue()
+					}
+				}
+			}
+			return result
+		}
+
+		// Send the request:
+		_, err = connection.Get().
+			Path("/api/clusters_mgmt/v1/clusters/123").
+			Send()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Verify that the metrics are added only when the connection is closed:
+		Expect(Count()).To(BeZero())
+		err = connection.Close()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(Count()).To(Equal(1.0))
+	})
+})
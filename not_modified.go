@@ -0,0 +1,49 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the functions that help sending conditional requests.
+
+package sdk
+
+import (
+	"net/http"
+)
+
+// IfNoneMatchHeader is the name of the request header that contains the entity tag of the version
+// of the object that the client already has. When the object hasn't changed the server responds
+// with 304 Not Modified and without body. It can be used with the Header method of the generated
+// get requests, and with the NotModified function to check the response. For example:
+//
+//	response, err := connection.ClustersMgmt().V1().Clusters().Cluster(id).Get().
+//		Header(sdk.IfNoneMatchHeader, etag).
+//		Send()
+//	if err != nil {
+//		return err
+//	}
+//	if sdk.NotModified(response) {
+//		// Use the copy that was saved before.
+//	}
+//
+// The connection doesn't try to decode the body of 304 responses, so in that case the Body method
+// of the generated response returns nil.
+const IfNoneMatchHeader = "If-None-Match"
+
+// NotModified checks if the given response has the 304 Not Modified status code, which the server
+// returns when the object matches the entity tag sent in the `If-None-Match` header. It accepts
+// the responses of the generated clients and of the generic requests.
+func NotModified(response interface{ Status() int }) bool {
+	return response != nil && response.Status() == http.StatusNotModified
+}
@@ -0,0 +1,181 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the builder that creates contexts containing the values that the wrappers of
+// the connection read for each request.
+
+package ocmctx
+
+import (
+	"context"
+	"time"
+
+	"github.com/openshift-online/ocm-sdk-go/headers"
+	"github.com/openshift-online/ocm-sdk-go/metrics"
+	"github.com/openshift-online/ocm-sdk-go/retry"
+	"github.com/openshift-online/ocm-sdk-go/tenancy"
+)
+
+// Builder contains the data and logic needed to create a context that combines several of the
+// per request values that the wrappers of the connection read from the context. For example, to
+// send a request with a timeout of ten seconds, a metrics label and without retries:
+//
+//	ctx, cancel := ocmctx.New(ctx).
+//		Timeout(10 * time.Second).
+//		Labels(map[string]string{
+//			"job": "sync",
+//		}).
+//		NoRetry().
+//		Build()
+//	defer cancel()
+//	response, err := connection.ClustersMgmt().V1().Clusters().List().SendContext(ctx)
+//
+// This is equivalent to calling the context.WithTimeout, metrics.WithLabels and retry.WithPolicy
+// functions one after the other. Values that aren't set in the builder are inherited from the
+// parent context.
+//
+// Don't create objects of this type directly; use the New function instead.
+type Builder struct {
+	parent         context.Context
+	timeout        time.Duration
+	deadline       time.Time
+	labels         map[string]string
+	policy         *retry.Policy
+	noMetrics      bool
+	organization   string
+	idempotencyKey string
+}
+
+// New creates a builder that can then be used to configure and create a context derived from the
+// given parent.
+func New(parent context.Context) *Builder {
+	return &Builder{
+		parent: parent,
+	}
+}
+
+// Timeout sets the maximum time that the requests sent with the context can take. Note that the
+// retry wrapper doesn't start a new attempt if there isn't enough time left for it. If both a
+// timeout and a deadline are set the one that expires first is used.
+func (b *Builder) Timeout(value time.Duration) *Builder {
+	b.timeout = value
+	return b
+}
+
+// Deadline sets the time when the requests sent with the context will be cancelled. If both a
+// timeout and a deadline are set the one that expires first is used.
+func (b *Builder) Deadline(value time.Time) *Builder {
+	b.deadline = value
+	return b
+}
+
+// Labels adds values for the labels that the metrics wrapper adds to the metrics of the requests.
+// See the metrics.WithLabels function for details. Calling this method multiple times merges the
+// labels, with the values given later taking precedence. The given map isn't modified or
+// retained.
+func (b *Builder) Labels(values map[string]string) *Builder {
+	if b.labels == nil {
+		b.labels = map[string]string{}
+	}
+	for name, value := range values {
+		b.labels[name] = value
+	}
+	return b
+}
+
+// Label adds the value of one of the labels that the metrics wrapper adds to the metrics of the
+// requests. See the Labels method for details.
+func (b *Builder) Label(name, value string) *Builder {
+	return b.Labels(map[string]string{
+		name: value,
+	})
+}
+
+// RetryPolicy sets the retry policy that overrides the configuration of the retry wrapper. See the
+// retry.WithPolicy function for details.
+func (b *Builder) RetryPolicy(value retry.Policy) *Builder {
+	b.policy = &value
+	return b
+}
+
+// NoRetry disables retries for the requests sent with the context. This is equivalent to setting
+// the zero retry policy.
+func (b *Builder) NoRetry() *Builder {
+	return b.RetryPolicy(retry.Policy{})
+}
+
+// NoMetrics tells the metrics wrappers to not record any metric for the requests sent with the
+// context. See the metrics.WithoutMetrics function for details.
+func (b *Builder) NoMetrics() *Builder {
+	b.noMetrics = true
+	return b
+}
+
+// Organization sets the identifier of the organization that originates the requests. See the
+// tenancy.WithOrganization function for details.
+func (b *Builder) Organization(value string) *Builder {
+	b.organization = value
+	return b
+}
+
+// IdempotencyKey sets the idempotency key that will be sent with the requests. See the
+// headers.ContextWithIdempotencyKey function for details.
+func (b *Builder) IdempotencyKey(value string) *Builder {
+	b.idempotencyKey = value
+	return b
+}
+
+// Build creates the context and the function that releases the resources associated to it. The
+// cancel function must always be called when the context is no longer needed, even if no timeout
+// or deadline was set.
+func (b *Builder) Build() (result context.Context, cancel context.CancelFunc) {
+	result = b.parent
+	if result == nil {
+		result = context.Background()
+	}
+	cancel = func() {}
+
+	// Add the deadline, using the earliest of the timeout and the explicit deadline:
+	deadline := b.deadline
+	if b.timeout > 0 {
+		timeout := time.Now().Add(b.timeout)
+		if deadline.IsZero() || timeout.Before(deadline) {
+			deadline = timeout
+		}
+	}
+	if !deadline.IsZero() {
+		result, cancel = context.WithDeadline(result, deadline)
+	}
+
+	// Add the rest of the values:
+	if len(b.labels) > 0 {
+		result = metrics.WithLabels(result, b.labels)
+	}
+	if b.noMetrics {
+		result = metrics.WithoutMetrics(result)
+	}
+	if b.policy != nil {
+		result = retry.WithPolicy(result, *b.policy)
+	}
+	if b.organization != "" {
+		result = tenancy.WithOrganization(result, b.organization)
+	}
+	if b.idempotencyKey != "" {
+		result = headers.ContextWithIdempotencyKey(result, b.idempotencyKey)
+	}
+
+	return
+}
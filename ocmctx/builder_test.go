@@ -0,0 +1,191 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains tests for the context builder.
+
+package ocmctx
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core" // nolint
+	. "github.com/onsi/gomega"             // nolint
+
+	"github.com/openshift-online/ocm-sdk-go/headers"
+	"github.com/openshift-online/ocm-sdk-go/metrics"
+	"github.com/openshift-online/ocm-sdk-go/retry"
+	"github.com/openshift-online/ocm-sdk-go/tenancy"
+
+	. "github.com/openshift-online/ocm-sdk-go/testing" // nolint
+)
+
+var _ = Describe("Builder", func() {
+	It("Returns the parent if nothing is set", func() {
+		parent := context.Background()
+		ctx, cancel := New(parent).Build()
+		defer cancel()
+		Expect(ctx).To(Equal(parent))
+	})
+
+	It("Uses the background context if the parent is nil", func() {
+		ctx, cancel := New(nil).Build() // nolint
+		defer cancel()
+		Expect(ctx).ToNot(BeNil())
+	})
+
+	It("Sets the timeout", func() {
+		before := time.Now()
+		ctx, cancel := New(context.Background()).
+			Timeout(time.Minute).
+			Build()
+		defer cancel()
+		deadline, ok := ctx.Deadline()
+		Expect(ok).To(BeTrue())
+		Expect(deadline).To(BeTemporally("~", before.Add(time.Minute), time.Second))
+	})
+
+	It("Uses the earliest of the timeout and the deadline", func() {
+		deadline := time.Now().Add(time.Second)
+		ctx, cancel := New(context.Background()).
+			Timeout(time.Hour).
+			Deadline(deadline).
+			Build()
+		defer cancel()
+		actual, ok := ctx.Deadline()
+		Expect(ok).To(BeTrue())
+		Expect(actual).To(Equal(deadline))
+	})
+
+	It("Cancels the context when the cancel function is called", func() {
+		ctx, cancel := New(context.Background()).
+			Timeout(time.Hour).
+			Build()
+		cancel()
+		Expect(ctx.Err()).To(MatchError(context.Canceled))
+	})
+
+	It("Disables retries", func() {
+		ctx, cancel := New(context.Background()).
+			NoRetry().
+			Build()
+		defer cancel()
+		policy, ok := retry.PolicyFromContext(ctx)
+		Expect(ok).To(BeTrue())
+		Expect(policy).To(Equal(retry.Policy{}))
+	})
+
+	It("Sets the retry policy", func() {
+		ctx, cancel := New(context.Background()).
+			RetryPolicy(retry.Policy{
+				Limit: 5,
+			}).
+			Build()
+		defer cancel()
+		policy, ok := retry.PolicyFromContext(ctx)
+		Expect(ok).To(BeTrue())
+		Expect(policy.Limit).To(Equal(5))
+	})
+
+	It("Sets the organization", func() {
+		ctx, cancel := New(context.Background()).
+			Organization("123").
+			Build()
+		defer cancel()
+		Expect(tenancy.OrganizationFromContext(ctx)).To(Equal("123"))
+	})
+
+	It("Sets the idempotency key", func() {
+		ctx, cancel := New(context.Background()).
+			IdempotencyKey("abc").
+			Build()
+		defer cancel()
+		Expect(headers.IdempotencyKeyFromContext(ctx)).To(Equal("abc"))
+	})
+
+	It("Inherits the values of the parent", func() {
+		parent := tenancy.WithOrganization(context.Background(), "123")
+		ctx, cancel := New(parent).
+			NoRetry().
+			Build()
+		defer cancel()
+		Expect(tenancy.OrganizationFromContext(ctx)).To(Equal("123"))
+	})
+
+	Describe("Metrics", func() {
+		var (
+			metricsServer *MetricsServer
+			client        *http.Client
+		)
+
+		BeforeEach(func() {
+			metricsServer = NewMetricsServer()
+			wrapper, err := metrics.NewTransportWrapper().
+				Subsystem("my").
+				Registerer(metricsServer.Registry()).
+				ContextLabels("team", "job").
+				Build()
+			Expect(err).ToNot(HaveOccurred())
+			client = &http.Client{
+				Transport: wrapper.Wrap(JSONTransport(http.StatusOK, `{}`)),
+			}
+		})
+
+		AfterEach(func() {
+			metricsServer.Close()
+		})
+
+		// Send sends a request with the given context.
+		var Send = func(ctx context.Context) {
+			request, err := http.NewRequestWithContext(
+				ctx,
+				http.MethodGet,
+				"http://api.example.com/api/clusters_mgmt/v1/clusters",
+				nil,
+			)
+			Expect(err).ToNot(HaveOccurred())
+			response, err := client.Do(request)
+			Expect(err).ToNot(HaveOccurred())
+			err = response.Body.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		It("Merges the labels", func() {
+			ctx, cancel := New(context.Background()).
+				Labels(map[string]string{
+					"team": "infra",
+					"job":  "old",
+				}).
+				Label("job", "sync").
+				Build()
+			defer cancel()
+			Send(ctx)
+			Expect(metricsServer.Metrics()).To(MatchLine(
+				`^my_request_count\{.*job="sync".*team="infra"\} 1$`,
+			))
+		})
+
+		It("Disables metrics", func() {
+			ctx, cancel := New(context.Background()).
+				NoMetrics().
+				Build()
+			defer cancel()
+			Send(ctx)
+			Expect(metricsServer.Metrics()).ToNot(MatchLine(`^my_request_count.*$`))
+		})
+	})
+})
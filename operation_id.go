@@ -0,0 +1,108 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the implementation of the transport wrapper that extracts the operation
+// identifier that the server assigns to each request.
+
+package sdk
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/openshift-online/ocm-sdk-go/logging"
+)
+
+// OperationIDHeader is the name of the response header that contains the identifier that the
+// server assigns to each request. This is the identifier that the support team needs in order to
+// find the request in the logs of the server.
+const OperationIDHeader = "X-Operation-ID"
+
+// CaptureOperationID creates a new context that tells the connection to store in the given
+// variable the operation identifier returned by the server. For example:
+//
+//	var operationID string
+//	ctx = sdk.CaptureOperationID(ctx, &operationID)
+//	response, err := connection.ClustersMgmt().V1().Clusters().Cluster(id).Get().
+//		SendContext(ctx)
+//	if err != nil {
+//		fmt.Printf("Request with operation identifier '%s' failed: %v\n", operationID, err)
+//	}
+//
+// The variable is updated when the response headers are received, so it is available even when
+// the request fails or the body can't be decoded. When the request is retried the variable will
+// contain the identifier of the last attempt. It will be empty if the server didn't return the
+// header. The variable isn't protected by any lock, so the context shouldn't be used to send
+// requests concurrently.
+func CaptureOperationID(parent context.Context, target *string) context.Context {
+	return context.WithValue(parent, operationIDKeyValue, target)
+}
+
+// operationIDKeyType is the type of the key used to store the operation identifier target in the
+// context.
+type operationIDKeyType string
+
+// operationIDKeyValue is the key used to store the operation identifier target in the context.
+const operationIDKeyValue operationIDKeyType = "operationID"
+
+// operationIDTransportWrapper is a transport wrapper that creates round trippers that extract the
+// operation identifier from the responses.
+type operationIDTransportWrapper struct {
+	logger logging.Logger
+}
+
+// operationIDRoundTripper is a round tripper that extracts the operation identifier from the
+// responses.
+type operationIDRoundTripper struct {
+	logger logging.Logger
+	next   http.RoundTripper
+}
+
+// Make sure that we implement the http.RoundTripper interface:
+var _ http.RoundTripper = &operationIDRoundTripper{}
+
+// Wrap creates a round tripper on top of the given one that extracts the operation identifier from
+// the responses.
+func (w *operationIDTransportWrapper) Wrap(transport http.RoundTripper) http.RoundTripper {
+	return &operationIDRoundTripper{
+		logger: w.logger,
+		next:   transport,
+	}
+}
+
+// RoundTrip is the implementation of the http.RoundTripper interface.
+func (t *operationIDRoundTripper) RoundTrip(request *http.Request) (response *http.Response,
+	err error) {
+	response, err = t.next.RoundTrip(request)
+	if err != nil || response == nil {
+		return
+	}
+	ctx := request.Context()
+	operationID := response.Header.Get(OperationIDHeader)
+	target, ok := ctx.Value(operationIDKeyValue).(*string)
+	if ok && target != nil {
+		*target = operationID
+	}
+	if t.logger != nil && operationID != "" {
+		t.logger.Info(
+			ctx,
+			"Server assigned operation identifier '%s' to request for method %s and "+
+				"URL '%s', response code is %d",
+			operationID, request.Method, request.URL, response.StatusCode,
+		)
+	}
+	return
+}
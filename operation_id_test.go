@@ -0,0 +1,202 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains tests for the extraction of operation identifiers from responses.
+
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/onsi/gomega/ghttp"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core" // nolint
+	. "github.com/onsi/gomega"             // nolint
+
+	"github.com/openshift-online/ocm-sdk-go/logging"
+	. "github.com/openshift-online/ocm-sdk-go/testing" // nolint
+)
+
+var _ = Describe("Operation identifier", func() {
+	var apiServer *ghttp.Server
+
+	BeforeEach(func() {
+		apiServer = MakeTCPServer()
+	})
+
+	AfterEach(func() {
+		apiServer.Close()
+	})
+
+	// RespondWithOperationID creates a handler that responds with the given status code and
+	// operation identifier.
+	var RespondWithOperationID = func(status int, operationID string) http.HandlerFunc {
+		return ghttp.RespondWith(
+			status,
+			`{}`,
+			http.Header{
+				"Content-Type":    []string{"application/json"},
+				OperationIDHeader: []string{operationID},
+			},
+		)
+	}
+
+	It("Stores the identifier in the variable of the context", func() {
+		// Create the connection:
+		connection, err := NewConnectionBuilder().
+			Logger(logger).
+			URL(apiServer.URL()).
+			Tokens(MakeTokenString("Bearer", 5*time.Minute)).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err = connection.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+
+		// Send the request:
+		apiServer.AppendHandlers(RespondWithOperationID(http.StatusOK, "123"))
+		var operationID string
+		ctx := CaptureOperationID(context.Background(), &operationID)
+		_, err = connection.Get().
+			Path("/api/clusters_mgmt/v1/clusters").
+			SendContext(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(operationID).To(Equal("123"))
+	})
+
+	It("Stores the identifier when the request fails", func() {
+		// Create the connection:
+		connection, err := NewConnectionBuilder().
+			Logger(logger).
+			URL(apiServer.URL()).
+			Tokens(MakeTokenString("Bearer", 5*time.Minute)).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err = connection.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+
+		// Send the request:
+		apiServer.AppendHandlers(RespondWithOperationID(http.StatusNotFound, "456"))
+		var operationID string
+		ctx := CaptureOperationID(context.Background(), &operationID)
+		_, err = connection.ClustersMgmt().V1().Clusters().Cluster("123").Get().
+			SendContext(ctx)
+		Expect(err).To(HaveOccurred())
+		Expect(operationID).To(Equal("456"))
+	})
+
+	It("Stores the identifier of the last attempt", func() {
+		// Create the connection:
+		connection, err := NewConnectionBuilder().
+			Logger(logger).
+			URL(apiServer.URL()).
+			Tokens(MakeTokenString("Bearer", 5*time.Minute)).
+			RetryInterval(10 * time.Millisecond).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err = connection.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+
+		// Send the request:
+		apiServer.AppendHandlers(
+			RespondWithOperationID(http.StatusServiceUnavailable, "123"),
+			RespondWithOperationID(http.StatusOK, "456"),
+		)
+		var operationID string
+		ctx := CaptureOperationID(context.Background(), &operationID)
+		_, err = connection.Get().
+			Path("/api/clusters_mgmt/v1/clusters").
+			SendContext(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(operationID).To(Equal("456"))
+	})
+
+	It("Doesn't write the identifier to the log by default", func() {
+		// Create a logger that writes to a buffer:
+		buffer := &bytes.Buffer{}
+		bufferLogger, err := logging.NewStdLoggerBuilder().
+			Streams(buffer, buffer).
+			Info(true).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Create the connection:
+		connection, err := NewConnectionBuilder().
+			Logger(bufferLogger).
+			URL(apiServer.URL()).
+			Tokens(MakeTokenString("Bearer", 5*time.Minute)).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err = connection.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+
+		// Send the request:
+		apiServer.AppendHandlers(RespondWithOperationID(http.StatusOK, "123"))
+		_, err = connection.Get().
+			Path("/api/clusters_mgmt/v1/clusters").
+			Send()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Verify the log:
+		Expect(buffer.String()).ToNot(ContainSubstring("operation identifier"))
+	})
+
+	It("Writes the identifier to the log when enabled", func() {
+		// Create a logger that writes to a buffer:
+		buffer := &bytes.Buffer{}
+		bufferLogger, err := logging.NewStdLoggerBuilder().
+			Streams(buffer, buffer).
+			Info(true).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Create the connection:
+		connection, err := NewConnectionBuilder().
+			Logger(bufferLogger).
+			URL(apiServer.URL()).
+			Tokens(MakeTokenString("Bearer", 5*time.Minute)).
+			LogOperationID(true).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err = connection.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+
+		// Send the request:
+		apiServer.AppendHandlers(RespondWithOperationID(http.StatusOK, "123"))
+		_, err = connection.Get().
+			Path("/api/clusters_mgmt/v1/clusters").
+			Send()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Verify the log:
+		text := buffer.String()
+		Expect(text).To(ContainSubstring("operation identifier '123'"))
+		Expect(text).To(ContainSubstring("/api/clusters_mgmt/v1/clusters"))
+		Expect(text).To(ContainSubstring("response code is 200"))
+	})
+})
@@ -0,0 +1,435 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the implementation of the object that fetches all the pages of a collection.
+
+package paging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultSize is the default number of items requested in each page.
+const DefaultSize = 100
+
+// DefaultConcurrency is the default maximum number of pages fetched at the same time.
+const DefaultConcurrency = 4
+
+// ErrTruncated is the error returned by the Fetch method when the collection has more pages than
+// the maximum configured with the MaxPages method. The items of the pages that were fetched are
+// returned together with this error, so use errors.Is to check for it.
+var ErrTruncated = errors.New("collection has more pages than the maximum")
+
+// PageError is the error returned for a page that couldn't be fetched.
+type PageError struct {
+	// Page is the number of the page, starting with one.
+	Page int
+
+	// Err is the error returned by the function that fetches the page.
+	Err error
+}
+
+// Make sure that we implement the interface:
+var _ error = (*PageError)(nil)
+
+// Error is the implementation of the error interface.
+func (e *PageError) Error() string {
+	return fmt.Sprintf("can't fetch page %d: %v", e.Page, e.Err)
+}
+
+// Unwrap returns the error returned by the function that fetches the page.
+func (e *PageError) Unwrap() error {
+	return e.Err
+}
+
+// PartialError is the error returned by the Fetch method in partial mode when some of the pages
+// couldn't be fetched. The items of the pages that were fetched are returned together with this
+// error. It supports the Is and As functions of the standard errors package, which will check the
+// errors of the individual pages, and the ErrTruncated error if the collection was also truncated.
+type PartialError struct {
+	// Failures contains the errors of the pages that couldn't be fetched, sorted by page
+	// number.
+	Failures []*PageError
+
+	// Truncated indicates if the collection had also more pages than the configured maximum.
+	Truncated bool
+}
+
+// Make sure that we implement the interface:
+var _ error = (*PartialError)(nil)
+
+// Error is the implementation of the error interface.
+func (e *PartialError) Error() string {
+	messages := make([]string, len(e.Failures))
+	for i, failure := range e.Failures {
+		messages[i] = failure.Error()
+	}
+	result := fmt.Sprintf(
+		"%d pages couldn't be fetched: %s",
+		len(e.Failures), strings.Join(messages, "; "),
+	)
+	if e.Truncated {
+		result = fmt.Sprintf("%s; %v", result, ErrTruncated)
+	}
+	return result
+}
+
+// Unwrap returns the errors of the individual pages, and the ErrTruncated error if the collection
+// was truncated, so that the Is and As functions of the standard errors package can check them.
+func (e *PartialError) Unwrap() []error {
+	result := make([]error, 0, len(e.Failures)+1)
+	for _, failure := range e.Failures {
+		result = append(result, failure)
+	}
+	if e.Truncated {
+		result = append(result, ErrTruncated)
+	}
+	return result
+}
+
+// Page contains the items of one page of a collection, and the total number of items of the
+// collection, if known.
+type Page[T any] struct {
+	// Items contains the items of the page.
+	Items []T
+
+	// Total is the total number of items of the collection. It is only meaningful when
+	// HasTotal is true.
+	Total int
+
+	// HasTotal indicates if the total number of items is known.
+	HasTotal bool
+}
+
+// Function is the function that fetches one page of a collection. The page number starts with one.
+type Function[T any] func(ctx context.Context, page, size int) (result Page[T], err error)
+
+// FetcherBuilder contains the data and logic needed to create a fetcher that retrieves all the
+// items of a collection. The fetcher first retrieves the first page in order to learn the total
+// number of items, and then it retrieves the rest of the pages in parallel, with a limit to the
+// number of pages that are retrieved at the same time. The items are returned in the same order
+// that they would have if the pages were retrieved sequentially. For example, to retrieve all
+// the clusters:
+//
+//	fetcher, err := paging.NewFetcher[*cmv1.Cluster]().
+//		Function(func(ctx context.Context, page, size int) (result paging.Page[*cmv1.Cluster],
+//			err error) {
+//			response, err := collection.List().
+//				Page(page).
+//				Size(size).
+//				SendContext(ctx)
+//			if err != nil {
+//				return
+//			}
+//			result.Items = response.Items().Slice()
+//			result.Total, result.HasTotal = response.GetTotal()
+//			return
+//		}).
+//		Build()
+//	if err != nil {
+//		...
+//	}
+//	clusters, err := fetcher.Fetch(ctx)
+//
+// If the total number of items isn't known the rest of the pages are retrieved sequentially,
+// till a page that has less items than requested.
+//
+// To protect against accidentally iterating very large collections the number of pages can be
+// limited with the MaxPages method. When the limit is reached the Fetch method returns the items
+// of the pages fetched so far and the ErrTruncated error.
+//
+// By default if fetching any page fails the Fetch method returns that error and no items. For
+// best-effort jobs that prefer mostly complete results to nothing the Partial method enables a
+// mode where the failures are collected and the items of the rest of the pages are returned
+// together with a PartialError that describes the failures.
+//
+// Note that the pages are retrieved at different times, so if the collection changes while they
+// are being retrieved the result may miss items or contain duplicates.
+//
+// Don't create objects of this type directly; use the NewFetcher function instead.
+type FetcherBuilder[T any] struct {
+	function    Function[T]
+	size        int
+	concurrency int
+	maxPages    int
+	partial     bool
+}
+
+// Fetcher knows how to retrieve all the items of a collection. Don't create objects of this type
+// directly; use the NewFetcher function instead.
+type Fetcher[T any] struct {
+	function    Function[T]
+	size        int
+	concurrency int
+	maxPages    int
+	partial     bool
+}
+
+// NewFetcher creates a builder that can then be used to configure and create a fetcher.
+func NewFetcher[T any]() *FetcherBuilder[T] {
+	return &FetcherBuilder[T]{
+		size:        DefaultSize,
+		concurrency: DefaultConcurrency,
+	}
+}
+
+// Function sets the function that will be used to fetch each page. This is mandatory.
+func (b *FetcherBuilder[T]) Function(value Function[T]) *FetcherBuilder[T] {
+	b.function = value
+	return b
+}
+
+// Size sets the number of items requested in each page. The default is 100.
+func (b *FetcherBuilder[T]) Size(value int) *FetcherBuilder[T] {
+	b.size = value
+	return b
+}
+
+// Concurrency sets the maximum number of pages that will be fetched at the same time. The default
+// is four.
+func (b *FetcherBuilder[T]) Concurrency(value int) *FetcherBuilder[T] {
+	b.concurrency = value
+	return b
+}
+
+// MaxPages sets the maximum number of pages that will be fetched. If the collection has more pages
+// the Fetch method will return the items of the first pages and the ErrTruncated error. The
+// default is zero, which means that there is no limit.
+func (b *FetcherBuilder[T]) MaxPages(value int) *FetcherBuilder[T] {
+	b.maxPages = value
+	return b
+}
+
+// Partial enables the mode where the failure to fetch a page doesn't abort the rest. Instead the
+// Fetch method returns the items of the pages that could be fetched and a PartialError containing
+// the errors of the pages that couldn't. Note that when the total number of items isn't known the
+// pages are fetched sequentially, and then a failure stops the iteration, as it isn't possible to
+// know if there are more pages. The default is to abort when any page fails.
+func (b *FetcherBuilder[T]) Partial(value bool) *FetcherBuilder[T] {
+	b.partial = value
+	return b
+}
+
+// Build uses the information stored in the builder to create a new fetcher.
+func (b *FetcherBuilder[T]) Build() (result *Fetcher[T], err error) {
+	// Check parameters:
+	if b.function == nil {
+		err = fmt.Errorf("function is mandatory")
+		return
+	}
+	if b.size <= 0 {
+		err = fmt.Errorf("size should be greater than zero, but it is %d", b.size)
+		return
+	}
+	if b.concurrency <= 0 {
+		err = fmt.Errorf(
+			"concurrency should be greater than zero, but it is %d",
+			b.concurrency,
+		)
+		return
+	}
+	if b.maxPages < 0 {
+		err = fmt.Errorf(
+			"maximum number of pages should be zero or positive, but it is %d",
+			b.maxPages,
+		)
+		return
+	}
+
+	// Create and populate the object:
+	result = &Fetcher[T]{
+		function:    b.function,
+		size:        b.size,
+		concurrency: b.concurrency,
+		maxPages:    b.maxPages,
+		partial:     b.partial,
+	}
+
+	return
+}
+
+// Fetch retrieves all the items of the collection. If fetching any of the pages fails the rest
+// of the pages are cancelled and the first error is returned. If the collection has more pages
+// than the configured maximum the items of the first pages are returned together with the
+// ErrTruncated error. In partial mode the failures don't stop the rest of the pages; instead the
+// items of the pages that could be fetched are returned together with a PartialError.
+func (f *Fetcher[T]) Fetch(ctx context.Context) (result []T, err error) {
+	// Fetch the first page, to find out the total:
+	first, err := f.function(ctx, 1, f.size)
+	if err != nil {
+		err = f.pageError(1, err)
+		return
+	}
+
+	// If we don't know the total then we need to fetch the rest of the pages sequentially:
+	if !first.HasTotal {
+		result, err = f.fetchSequential(ctx, first)
+		return
+	}
+
+	// Calculate the number of pages. Note that the server may return less items than requested
+	// if the requested size is larger than the maximum that it supports, so we use the number
+	// of items actually returned in that case.
+	size := f.size
+	count := len(first.Items)
+	if count > 0 && count < size && count < first.Total {
+		size = count
+	}
+	pages := (first.Total + size - 1) / size
+	truncated := f.maxPages > 0 && pages > f.maxPages
+	if truncated {
+		pages = f.maxPages
+	}
+	var failures []*PageError
+	if pages <= 1 {
+		result = first.Items
+	} else {
+		result, failures, err = f.fetchConcurrent(ctx, first, pages)
+		if err != nil {
+			return
+		}
+	}
+	err = f.finalError(failures, truncated)
+	return
+}
+
+// fetchSequential fetches the pages that come after the given first one, one after the other,
+// till a page that has less items than requested. Note that when the maximum number of pages is
+// reached we can't know if there are more items, so it is assumed that there are.
+func (f *Fetcher[T]) fetchSequential(ctx context.Context, first Page[T]) (result []T, err error) {
+	result = first.Items
+	last := first
+	for page := 2; len(last.Items) >= f.size; page++ {
+		if f.maxPages > 0 && page > f.maxPages {
+			err = ErrTruncated
+			return
+		}
+		last, err = f.function(ctx, page, f.size)
+		if err != nil {
+			err = f.pageError(page, err)
+			return
+		}
+		result = append(result, last.Items...)
+	}
+	return
+}
+
+// fetchConcurrent fetches the pages that come after the given first one in parallel, and then
+// joins them in order. In partial mode the pages that fail are returned as failures, sorted by
+// page number, and their items are omitted.
+func (f *Fetcher[T]) fetchConcurrent(ctx context.Context, first Page[T],
+	pages int) (result []T, failures []*PageError, err error) {
+	// Create a context that we can use to cancel the rest of the requests when one fails:
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Start the goroutines that fetch the pages, using a channel as a semaphore to limit the
+	// number of pages that are fetched at the same time:
+	items := make([][]T, pages)
+	items[0] = first.Items
+	semaphore := make(chan struct{}, f.concurrency)
+	var lock sync.Mutex
+	var group sync.WaitGroup
+	var failure error
+	for page := 2; page <= pages; page++ {
+		select {
+		case semaphore <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		group.Add(1)
+		go func(page int) {
+			defer group.Done()
+			defer func() {
+				<-semaphore
+			}()
+			current, err := f.function(ctx, page, f.size)
+			lock.Lock()
+			defer lock.Unlock()
+			if err != nil {
+				if f.partial {
+					failures = append(failures, &PageError{
+						Page: page,
+						Err:  err,
+					})
+				} else if failure == nil {
+					failure = fmt.Errorf("can't fetch page %d: %w", page, err)
+					cancel()
+				}
+				return
+			}
+			items[page-1] = current.Items
+		}(page)
+	}
+	group.Wait()
+
+	// Return the first error, or the error of the context if it was cancelled by the caller
+	// before all the pages were started:
+	if failure != nil {
+		err = failure
+		return
+	}
+	err = ctx.Err()
+	if err != nil {
+		return
+	}
+
+	// Join the pages:
+	for _, current := range items {
+		result = append(result, current...)
+	}
+	sort.Slice(failures, func(i, j int) bool {
+		return failures[i].Page < failures[j].Page
+	})
+	return
+}
+
+// pageError returns the error for a page that couldn't be fetched. In partial mode that is a
+// partial error containing only that page, otherwise it is the original error wrapped with the
+// page number.
+func (f *Fetcher[T]) pageError(page int, err error) error {
+	if f.partial {
+		return &PartialError{
+			Failures: []*PageError{{
+				Page: page,
+				Err:  err,
+			}},
+		}
+	}
+	return fmt.Errorf("can't fetch page %d: %w", page, err)
+}
+
+// finalError returns the error that should be returned together with the items, if any, combining
+// the failures of the pages and the truncation.
+func (f *Fetcher[T]) finalError(failures []*PageError, truncated bool) error {
+	if len(failures) > 0 {
+		return &PartialError{
+			Failures:  failures,
+			Truncated: truncated,
+		}
+	}
+	if truncated {
+		return ErrTruncated
+	}
+	return nil
+}
@@ -0,0 +1,458 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains tests for the fetcher.
+
+package paging
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core" // nolint
+	. "github.com/onsi/gomega"             // nolint
+)
+
+var _ = Describe("Creation", func() {
+	// Function is a function that returns an empty page.
+	var Function = func(ctx context.Context, page, size int) (result Page[int], err error) {
+		return
+	}
+
+	It("Can't be created without a function", func() {
+		fetcher, err := NewFetcher[int]().
+			Build()
+		Expect(err).To(HaveOccurred())
+		Expect(fetcher).To(BeNil())
+		message := err.Error()
+		Expect(message).To(ContainSubstring("function"))
+		Expect(message).To(ContainSubstring("mandatory"))
+	})
+
+	It("Can't be created with zero size", func() {
+		fetcher, err := NewFetcher[int]().
+			Function(Function).
+			Size(0).
+			Build()
+		Expect(err).To(HaveOccurred())
+		Expect(fetcher).To(BeNil())
+		Expect(err.Error()).To(ContainSubstring("size"))
+	})
+
+	It("Can't be created with zero concurrency", func() {
+		fetcher, err := NewFetcher[int]().
+			Function(Function).
+			Concurrency(0).
+			Build()
+		Expect(err).To(HaveOccurred())
+		Expect(fetcher).To(BeNil())
+		Expect(err.Error()).To(ContainSubstring("concurrency"))
+	})
+
+	It("Can't be created with negative maximum number of pages", func() {
+		fetcher, err := NewFetcher[int]().
+			Function(Function).
+			MaxPages(-1).
+			Build()
+		Expect(err).To(HaveOccurred())
+		Expect(fetcher).To(BeNil())
+		Expect(err.Error()).To(ContainSubstring("maximum number of pages"))
+	})
+})
+
+var _ = Describe("Fetch", func() {
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.Background()
+	})
+
+	// Collection simulates a collection containing the given number of items, with values
+	// from zero to the number of items minus one. The server returns at most the given maximum
+	// number of items per page, and the total only if requested. It returns a function that
+	// fetches pages and a function that returns the pages requested, in order.
+	var Collection = func(count, max int, total bool) (function Function[int],
+		requested func() []int) {
+		var lock sync.Mutex
+		var pages []int
+		function = func(ctx context.Context, page, size int) (result Page[int], err error) {
+			lock.Lock()
+			pages = append(pages, page)
+			lock.Unlock()
+			if size > max {
+				size = max
+			}
+			for i := (page - 1) * size; i < page*size && i < count; i++ {
+				result.Items = append(result.Items, i)
+			}
+			result.Total = count
+			result.HasTotal = total
+			return
+		}
+		requested = func() []int {
+			lock.Lock()
+			defer lock.Unlock()
+			return append([]int{}, pages...)
+		}
+		return
+	}
+
+	// Sequence returns the numbers from zero to the given number minus one.
+	var Sequence = func(count int) []int {
+		result := make([]int, count)
+		for i := range result {
+			result[i] = i
+		}
+		return result
+	}
+
+	It("Returns empty result for empty collection", func() {
+		function, requested := Collection(0, 100, true)
+		fetcher, err := NewFetcher[int]().
+			Function(function).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		items, err := fetcher.Fetch(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(items).To(BeEmpty())
+		Expect(requested()).To(Equal([]int{1}))
+	})
+
+	It("Fetches only one page if it contains all the items", func() {
+		function, requested := Collection(10, 100, true)
+		fetcher, err := NewFetcher[int]().
+			Function(function).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		items, err := fetcher.Fetch(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(items).To(Equal(Sequence(10)))
+		Expect(requested()).To(Equal([]int{1}))
+	})
+
+	It("Returns items in order when pages complete in random order", func() {
+		function, requested := Collection(1050, 100, true)
+		delayed := func(ctx context.Context, page, size int) (Page[int], error) {
+			time.Sleep(time.Duration(rand.Intn(10)) * time.Millisecond)
+			return function(ctx, page, size)
+		}
+		fetcher, err := NewFetcher[int]().
+			Function(delayed).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		items, err := fetcher.Fetch(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(items).To(Equal(Sequence(1050)))
+		Expect(requested()).To(ConsistOf(1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11))
+	})
+
+	It("Honours the concurrency limit", func() {
+		function, _ := Collection(1000, 10, true)
+		var current, max int32
+		limited := func(ctx context.Context, page, size int) (Page[int], error) {
+			value := atomic.AddInt32(&current, 1)
+			defer atomic.AddInt32(&current, -1)
+			for {
+				previous := atomic.LoadInt32(&max)
+				if value <= previous || atomic.CompareAndSwapInt32(&max, previous, value) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			return function(ctx, page, size)
+		}
+		fetcher, err := NewFetcher[int]().
+			Function(limited).
+			Size(10).
+			Concurrency(3).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		items, err := fetcher.Fetch(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(items).To(HaveLen(1000))
+		Expect(atomic.LoadInt32(&max)).To(BeNumerically("<=", 3))
+	})
+
+	It("Uses the size returned by the server if it is smaller", func() {
+		function, requested := Collection(120, 50, true)
+		fetcher, err := NewFetcher[int]().
+			Function(function).
+			Size(100).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		items, err := fetcher.Fetch(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(items).To(Equal(Sequence(120)))
+		Expect(requested()).To(ConsistOf(1, 2, 3))
+	})
+
+	It("Fetches sequentially when the total isn't known", func() {
+		function, requested := Collection(250, 100, false)
+		fetcher, err := NewFetcher[int]().
+			Function(function).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		items, err := fetcher.Fetch(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(items).To(Equal(Sequence(250)))
+		Expect(requested()).To(Equal([]int{1, 2, 3}))
+	})
+
+	It("Stops with an empty page when the total isn't known", func() {
+		function, requested := Collection(200, 100, false)
+		fetcher, err := NewFetcher[int]().
+			Function(function).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		items, err := fetcher.Fetch(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(items).To(Equal(Sequence(200)))
+		Expect(requested()).To(Equal([]int{1, 2, 3}))
+	})
+
+	It("Returns the error of the first page", func() {
+		failure := errors.New("my error")
+		fetcher, err := NewFetcher[int]().
+			Function(func(ctx context.Context, page, size int) (Page[int], error) {
+				return Page[int]{}, failure
+			}).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		items, err := fetcher.Fetch(ctx)
+		Expect(err).To(HaveOccurred())
+		Expect(errors.Is(err, failure)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("page 1"))
+		Expect(items).To(BeNil())
+	})
+
+	It("Returns the first error and cancels the rest of the pages", func() {
+		function, _ := Collection(1000, 10, true)
+		failure := errors.New("my error")
+		var cancelled int32
+		failing := func(ctx context.Context, page, size int) (Page[int], error) {
+			if page == 3 {
+				return Page[int]{}, failure
+			}
+			if page > 3 {
+				select {
+				case <-ctx.Done():
+					atomic.AddInt32(&cancelled, 1)
+					return Page[int]{}, ctx.Err()
+				case <-time.After(time.Second):
+				}
+			}
+			return function(ctx, page, size)
+		}
+		fetcher, err := NewFetcher[int]().
+			Function(failing).
+			Size(10).
+			Concurrency(4).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		items, err := fetcher.Fetch(ctx)
+		Expect(err).To(HaveOccurred())
+		Expect(errors.Is(err, failure)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("page 3"))
+		Expect(items).To(BeNil())
+		Expect(atomic.LoadInt32(&cancelled)).To(BeNumerically("<=", 3))
+	})
+
+	It("Returns the error of the context if it is cancelled", func() {
+		function, _ := Collection(1000, 10, true)
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		cancelling := func(ctx context.Context, page, size int) (Page[int], error) {
+			if page == 2 {
+				cancel()
+			}
+			return function(ctx, page, size)
+		}
+		fetcher, err := NewFetcher[int]().
+			Function(cancelling).
+			Size(10).
+			Concurrency(1).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		items, err := fetcher.Fetch(ctx)
+		Expect(err).To(HaveOccurred())
+		Expect(errors.Is(err, context.Canceled)).To(BeTrue())
+		Expect(items).To(BeNil())
+	})
+
+	It("Stops at the maximum number of pages when the total is known", func() {
+		function, requested := Collection(250, 100, true)
+		fetcher, err := NewFetcher[int]().
+			Function(function).
+			MaxPages(2).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		items, err := fetcher.Fetch(ctx)
+		Expect(errors.Is(err, ErrTruncated)).To(BeTrue())
+		Expect(items).To(Equal(Sequence(200)))
+		Expect(requested()).To(ConsistOf(1, 2))
+	})
+
+	It("Stops at the maximum number of pages when the total isn't known", func() {
+		function, requested := Collection(250, 100, false)
+		fetcher, err := NewFetcher[int]().
+			Function(function).
+			MaxPages(2).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		items, err := fetcher.Fetch(ctx)
+		Expect(errors.Is(err, ErrTruncated)).To(BeTrue())
+		Expect(items).To(Equal(Sequence(200)))
+		Expect(requested()).To(Equal([]int{1, 2}))
+	})
+
+	It("Stops at the maximum number of pages when it is one", func() {
+		function, requested := Collection(250, 100, true)
+		fetcher, err := NewFetcher[int]().
+			Function(function).
+			MaxPages(1).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		items, err := fetcher.Fetch(ctx)
+		Expect(errors.Is(err, ErrTruncated)).To(BeTrue())
+		Expect(items).To(Equal(Sequence(100)))
+		Expect(requested()).To(Equal([]int{1}))
+	})
+
+	It("Doesn't return error if the collection fits in the maximum", func() {
+		function, requested := Collection(200, 100, true)
+		fetcher, err := NewFetcher[int]().
+			Function(function).
+			MaxPages(2).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		items, err := fetcher.Fetch(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(items).To(Equal(Sequence(200)))
+		Expect(requested()).To(ConsistOf(1, 2))
+	})
+
+	It("Returns the items of the rest of the pages in partial mode", func() {
+		function, requested := Collection(100, 10, true)
+		failure := errors.New("my error")
+		failing := func(ctx context.Context, page, size int) (Page[int], error) {
+			if page == 3 || page == 7 {
+				return Page[int]{}, failure
+			}
+			return function(ctx, page, size)
+		}
+		fetcher, err := NewFetcher[int]().
+			Function(failing).
+			Size(10).
+			Partial(true).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		items, err := fetcher.Fetch(ctx)
+		Expect(err).To(HaveOccurred())
+		Expect(errors.Is(err, failure)).To(BeTrue())
+		Expect(errors.Is(err, ErrTruncated)).To(BeFalse())
+		var partial *PartialError
+		Expect(errors.As(err, &partial)).To(BeTrue())
+		Expect(partial.Failures).To(HaveLen(2))
+		Expect(partial.Failures[0].Page).To(Equal(3))
+		Expect(partial.Failures[1].Page).To(Equal(7))
+		Expect(err.Error()).To(ContainSubstring("page 3"))
+		Expect(err.Error()).To(ContainSubstring("page 7"))
+		expected := append(Sequence(20), Sequence(100)[30:60]...)
+		expected = append(expected, Sequence(100)[70:]...)
+		Expect(items).To(Equal(expected))
+		Expect(requested()).To(HaveLen(8))
+	})
+
+	It("Returns partial error for the first page in partial mode", func() {
+		failure := errors.New("my error")
+		fetcher, err := NewFetcher[int]().
+			Function(func(ctx context.Context, page, size int) (Page[int], error) {
+				return Page[int]{}, failure
+			}).
+			Partial(true).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		items, err := fetcher.Fetch(ctx)
+		Expect(err).To(HaveOccurred())
+		Expect(errors.Is(err, failure)).To(BeTrue())
+		var partial *PartialError
+		Expect(errors.As(err, &partial)).To(BeTrue())
+		Expect(partial.Failures).To(HaveLen(1))
+		Expect(partial.Failures[0].Page).To(Equal(1))
+		Expect(items).To(BeEmpty())
+	})
+
+	It("Returns the items fetched before the failure when the total isn't known", func() {
+		function, requested := Collection(250, 100, false)
+		failure := errors.New("my error")
+		failing := func(ctx context.Context, page, size int) (Page[int], error) {
+			if page == 2 {
+				return Page[int]{}, failure
+			}
+			return function(ctx, page, size)
+		}
+		fetcher, err := NewFetcher[int]().
+			Function(failing).
+			Partial(true).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		items, err := fetcher.Fetch(ctx)
+		Expect(errors.Is(err, failure)).To(BeTrue())
+		var partial *PartialError
+		Expect(errors.As(err, &partial)).To(BeTrue())
+		Expect(partial.Failures).To(HaveLen(1))
+		Expect(partial.Failures[0].Page).To(Equal(2))
+		Expect(items).To(Equal(Sequence(100)))
+		Expect(requested()).To(Equal([]int{1}))
+	})
+
+	It("Reports truncation together with the failures in partial mode", func() {
+		function, _ := Collection(250, 100, true)
+		failure := errors.New("my error")
+		failing := func(ctx context.Context, page, size int) (Page[int], error) {
+			if page == 2 {
+				return Page[int]{}, failure
+			}
+			return function(ctx, page, size)
+		}
+		fetcher, err := NewFetcher[int]().
+			Function(failing).
+			MaxPages(2).
+			Partial(true).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		items, err := fetcher.Fetch(ctx)
+		Expect(errors.Is(err, failure)).To(BeTrue())
+		Expect(errors.Is(err, ErrTruncated)).To(BeTrue())
+		Expect(items).To(Equal(Sequence(100)))
+	})
+
+	It("Doesn't return error in partial mode if all the pages succeed", func() {
+		function, _ := Collection(250, 100, true)
+		fetcher, err := NewFetcher[int]().
+			Function(function).
+			Partial(true).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		items, err := fetcher.Fetch(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(items).To(Equal(Sequence(250)))
+	})
+})
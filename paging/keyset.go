@@ -0,0 +1,267 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the implementation of the iterator that retrieves the items of a collection
+// using keyset pagination.
+
+package paging
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/openshift-online/ocm-sdk-go/search"
+)
+
+// KeysetFunction is the function that fetches one batch of items of a collection. It receives the
+// search expression and the order criteria that should be sent to the server, and the maximum
+// number of items to return. The search expression will be empty for the first batch if no base
+// search has been configured.
+type KeysetFunction[T any] func(ctx context.Context, search, order string,
+	size int) (items []T, err error)
+
+// KeysetIteratorBuilder contains the data and logic needed to create an iterator that retrieves
+// the items of a collection in batches using keyset pagination. Instead of requesting pages by
+// number, the iterator sorts the collection by a field and uses the `search` parameter to request
+// the items whose value for that field is greater than the value of the last item retrieved. This
+// is more robust than page numbers when the collection changes while it is being iterated, as
+// adding or removing items doesn't shift the rest. For example, to process all the clusters:
+//
+//	iterator, err := paging.NewKeysetIterator[*cmv1.Cluster]().
+//		Function(func(ctx context.Context, search, order string,
+//			size int) (items []*cmv1.Cluster, err error) {
+//			response, err := collection.List().
+//				Search(search).
+//				Order(order).
+//				Size(size).
+//				SendContext(ctx)
+//			if err != nil {
+//				return
+//			}
+//			items = response.Items().Slice()
+//			return
+//		}).
+//		Field("id").
+//		Key((*cmv1.Cluster).ID).
+//		Build()
+//	if err != nil {
+//		...
+//	}
+//	for !iterator.Done() {
+//		clusters, err := iterator.Next(ctx)
+//		if err != nil {
+//			...
+//		}
+//		...
+//	}
+//
+// The value returned by the Cursor method of the iterator can be saved, and passed to the Cursor
+// method of the builder to resume the iteration after an interruption.
+//
+// The field should be unique, like `id`. If it isn't, items that have the same value as the
+// last item of a batch will be skipped.
+//
+// Don't create objects of this type directly; use the NewKeysetIterator function instead.
+type KeysetIteratorBuilder[T any] struct {
+	function KeysetFunction[T]
+	field    string
+	key      func(T) string
+	search   string
+	size     int
+	cursor   string
+}
+
+// KeysetIterator knows how to retrieve the items of a collection in batches using keyset
+// pagination. Don't create objects of this type directly; use the NewKeysetIterator function
+// instead.
+//
+// Iterators aren't safe for concurrent use.
+type KeysetIterator[T any] struct {
+	function KeysetFunction[T]
+	field    string
+	key      func(T) string
+	search   search.Node
+	order    string
+	size     int
+	cursor   string
+	done     bool
+}
+
+// NewKeysetIterator creates a builder that can then be used to configure and create a keyset
+// iterator.
+func NewKeysetIterator[T any]() *KeysetIteratorBuilder[T] {
+	return &KeysetIteratorBuilder[T]{
+		size: DefaultSize,
+	}
+}
+
+// Function sets the function that will be used to fetch each batch. This is mandatory.
+func (b *KeysetIteratorBuilder[T]) Function(value KeysetFunction[T]) *KeysetIteratorBuilder[T] {
+	b.function = value
+	return b
+}
+
+// Field sets the name of the field that will be used to sort the collection and to select the
+// items of each batch. This is mandatory.
+func (b *KeysetIteratorBuilder[T]) Field(value string) *KeysetIteratorBuilder[T] {
+	b.field = value
+	return b
+}
+
+// Key sets the function that returns the value of the field for an item. This is mandatory.
+func (b *KeysetIteratorBuilder[T]) Key(value func(T) string) *KeysetIteratorBuilder[T] {
+	b.key = value
+	return b
+}
+
+// Search sets a search expression that will be combined with the conditions generated by the
+// iterator, so that only the items that match it are retrieved. The default is to retrieve all
+// the items.
+func (b *KeysetIteratorBuilder[T]) Search(value string) *KeysetIteratorBuilder[T] {
+	b.search = value
+	return b
+}
+
+// Size sets the maximum number of items requested in each batch. The default is 100.
+func (b *KeysetIteratorBuilder[T]) Size(value int) *KeysetIteratorBuilder[T] {
+	b.size = value
+	return b
+}
+
+// Cursor sets the value of the field after which the iteration will start. This is intended to
+// resume an iteration that was interrupted, using the value returned by the Cursor method of the
+// iterator. The default is to start with the first item.
+func (b *KeysetIteratorBuilder[T]) Cursor(value string) *KeysetIteratorBuilder[T] {
+	b.cursor = value
+	return b
+}
+
+// Build uses the information stored in the builder to create a new keyset iterator.
+func (b *KeysetIteratorBuilder[T]) Build() (result *KeysetIterator[T], err error) {
+	// Check parameters:
+	if b.function == nil {
+		err = fmt.Errorf("function is mandatory")
+		return
+	}
+	if b.field == "" {
+		err = fmt.Errorf("field is mandatory")
+		return
+	}
+	if b.key == nil {
+		err = fmt.Errorf("key is mandatory")
+		return
+	}
+	if b.size <= 0 {
+		err = fmt.Errorf("size should be greater than zero, but it is %d", b.size)
+		return
+	}
+
+	// Check that the field can be used to sort the collection, and calculate the order:
+	terms, err := search.ParseOrder(b.field)
+	if err != nil {
+		err = fmt.Errorf("field '%s' isn't valid: %w", b.field, err)
+		return
+	}
+	if len(terms) != 1 || terms[0].Field != b.field {
+		err = fmt.Errorf("field '%s' isn't valid", b.field)
+		return
+	}
+	order := search.OrderTerm{
+		Field:     b.field,
+		Direction: search.DirectionAscending,
+	}
+
+	// Parse the base search expression:
+	var node search.Node
+	if b.search != "" {
+		node, err = search.Parse(b.search)
+		if err != nil {
+			err = fmt.Errorf("can't parse search expression '%s': %w", b.search, err)
+			return
+		}
+	}
+
+	// Create and populate the object:
+	result = &KeysetIterator[T]{
+		function: b.function,
+		field:    b.field,
+		key:      b.key,
+		search:   node,
+		order:    order.String(),
+		size:     b.size,
+		cursor:   b.cursor,
+	}
+
+	return
+}
+
+// Next retrieves the next batch of items. When the batch contains less items than requested the
+// iterator is done, and then the Done method returns true and further calls return no items.
+func (i *KeysetIterator[T]) Next(ctx context.Context) (items []T, err error) {
+	if i.done {
+		return
+	}
+	items, err = i.function(ctx, i.expression(), i.order, i.size)
+	if err != nil {
+		err = fmt.Errorf("can't fetch items after cursor '%s': %w", i.cursor, err)
+		return
+	}
+	if len(items) < i.size {
+		i.done = true
+	}
+	if len(items) > 0 {
+		i.cursor = i.key(items[len(items)-1])
+	}
+	return
+}
+
+// Done returns true if all the items have already been retrieved.
+func (i *KeysetIterator[T]) Done() bool {
+	return i.done
+}
+
+// Cursor returns the value of the field for the last item retrieved, or the initial cursor if
+// no item has been retrieved yet. Save it and pass it to the Cursor method of the builder to
+// resume the iteration.
+func (i *KeysetIterator[T]) Cursor() string {
+	return i.cursor
+}
+
+// expression generates the search expression for the next batch, combining the base search with
+// the condition that selects the items after the cursor.
+func (i *KeysetIterator[T]) expression() string {
+	result := i.search
+	if i.cursor != "" {
+		condition := &search.Condition{
+			Field:    i.field,
+			Operator: search.OperatorGreaterThan,
+			Values:   []interface{}{i.cursor},
+		}
+		if result == nil {
+			result = condition
+		} else {
+			result = &search.Logical{
+				Connector: search.ConnectorAnd,
+				Left:      result,
+				Right:     condition,
+			}
+		}
+	}
+	if result == nil {
+		return ""
+	}
+	return result.String()
+}
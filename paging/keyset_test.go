@@ -0,0 +1,311 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains tests for the keyset iterator.
+
+package paging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core" // nolint
+	. "github.com/onsi/gomega"             // nolint
+
+	"github.com/openshift-online/ocm-sdk-go/search"
+)
+
+var _ = Describe("Keyset iterator creation", func() {
+	// Function is a function that returns an empty batch.
+	var Function = func(ctx context.Context, search, order string, size int) (items []string,
+		err error) {
+		return
+	}
+
+	// Key is a function that returns the item itself.
+	var Key = func(item string) string {
+		return item
+	}
+
+	It("Can't be created without a function", func() {
+		iterator, err := NewKeysetIterator[string]().
+			Field("id").
+			Key(Key).
+			Build()
+		Expect(err).To(HaveOccurred())
+		Expect(iterator).To(BeNil())
+		Expect(err.Error()).To(ContainSubstring("function is mandatory"))
+	})
+
+	It("Can't be created without a field", func() {
+		iterator, err := NewKeysetIterator[string]().
+			Function(Function).
+			Key(Key).
+			Build()
+		Expect(err).To(HaveOccurred())
+		Expect(iterator).To(BeNil())
+		Expect(err.Error()).To(ContainSubstring("field is mandatory"))
+	})
+
+	It("Can't be created without a key", func() {
+		iterator, err := NewKeysetIterator[string]().
+			Function(Function).
+			Field("id").
+			Build()
+		Expect(err).To(HaveOccurred())
+		Expect(iterator).To(BeNil())
+		Expect(err.Error()).To(ContainSubstring("key is mandatory"))
+	})
+
+	It("Can't be created with zero size", func() {
+		iterator, err := NewKeysetIterator[string]().
+			Function(Function).
+			Field("id").
+			Key(Key).
+			Size(0).
+			Build()
+		Expect(err).To(HaveOccurred())
+		Expect(iterator).To(BeNil())
+		Expect(err.Error()).To(ContainSubstring("size"))
+	})
+
+	It("Can't be created with invalid field", func() {
+		iterator, err := NewKeysetIterator[string]().
+			Function(Function).
+			Field("id desc").
+			Key(Key).
+			Build()
+		Expect(err).To(HaveOccurred())
+		Expect(iterator).To(BeNil())
+		Expect(err.Error()).To(ContainSubstring("isn't valid"))
+	})
+
+	It("Can't be created with invalid search", func() {
+		iterator, err := NewKeysetIterator[string]().
+			Function(Function).
+			Field("id").
+			Key(Key).
+			Search("name =").
+			Build()
+		Expect(err).To(HaveOccurred())
+		Expect(iterator).To(BeNil())
+		Expect(err.Error()).To(ContainSubstring("can't parse search"))
+	})
+})
+
+var _ = Describe("Keyset iterator", func() {
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.Background()
+	})
+
+	// Key is a function that returns the item itself.
+	var Key = func(item string) string {
+		return item
+	}
+
+	// Collection simulates a collection containing the given number of items, with values from
+	// `000` to the number of items minus one. The server understands only the `id > '...'`
+	// condition, and ignores other conditions. It returns a function that fetches batches and a
+	// function that returns the search expressions received, in order.
+	var Collection = func(count int) (function KeysetFunction[string],
+		received func() []string) {
+		var expressions []string
+		function = func(ctx context.Context, expression, order string,
+			size int) (items []string, err error) {
+			Expect(order).To(Equal("id asc"))
+			expressions = append(expressions, expression)
+			after := ""
+			if expression != "" {
+				var node search.Node
+				node, err = search.Parse(expression)
+				if err != nil {
+					return
+				}
+				if logical, ok := node.(*search.Logical); ok {
+					node = logical.Right
+				}
+				condition := node.(*search.Condition)
+				if condition.Field == "id" {
+					Expect(condition.Operator).To(Equal(search.OperatorGreaterThan))
+					after = condition.Values[0].(string)
+				}
+			}
+			for i := 0; i < count && len(items) < size; i++ {
+				item := fmt.Sprintf("%03d", i)
+				if item > after {
+					items = append(items, item)
+				}
+			}
+			return
+		}
+		received = func() []string {
+			return append([]string{}, expressions...)
+		}
+		return
+	}
+
+	// Iterate calls the Next method of the iterator till it is done, and returns the batches.
+	var Iterate = func(iterator *KeysetIterator[string]) (batches [][]string) {
+		for !iterator.Done() {
+			batch, err := iterator.Next(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			batches = append(batches, batch)
+		}
+		return
+	}
+
+	It("Returns one empty batch for empty collection", func() {
+		function, received := Collection(0)
+		iterator, err := NewKeysetIterator[string]().
+			Function(function).
+			Field("id").
+			Key(Key).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(Iterate(iterator)).To(Equal([][]string{nil}))
+		Expect(received()).To(Equal([]string{""}))
+		Expect(iterator.Cursor()).To(BeEmpty())
+	})
+
+	It("Uses the last item of each batch as the cursor of the next", func() {
+		function, received := Collection(5)
+		iterator, err := NewKeysetIterator[string]().
+			Function(function).
+			Field("id").
+			Key(Key).
+			Size(2).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(Iterate(iterator)).To(Equal([][]string{
+			{"000", "001"},
+			{"002", "003"},
+			{"004"},
+		}))
+		Expect(received()).To(Equal([]string{
+			"",
+			"id > '001'",
+			"id > '003'",
+		}))
+		Expect(iterator.Cursor()).To(Equal("004"))
+	})
+
+	It("Sends an additional request when the last batch is full", func() {
+		function, received := Collection(4)
+		iterator, err := NewKeysetIterator[string]().
+			Function(function).
+			Field("id").
+			Key(Key).
+			Size(2).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(Iterate(iterator)).To(Equal([][]string{
+			{"000", "001"},
+			{"002", "003"},
+			nil,
+		}))
+		Expect(received()).To(HaveLen(3))
+		Expect(iterator.Cursor()).To(Equal("003"))
+	})
+
+	It("Combines the cursor with the base search", func() {
+		function, received := Collection(3)
+		iterator, err := NewKeysetIterator[string]().
+			Function(function).
+			Field("id").
+			Key(Key).
+			Search("name like 'my%'").
+			Size(2).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		Iterate(iterator)
+		Expect(received()).To(Equal([]string{
+			"name like 'my%'",
+			"(name like 'my%' and id > '001')",
+		}))
+	})
+
+	It("Resumes from the given cursor", func() {
+		function, received := Collection(5)
+		iterator, err := NewKeysetIterator[string]().
+			Function(function).
+			Field("id").
+			Key(Key).
+			Size(2).
+			Cursor("002").
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(iterator.Cursor()).To(Equal("002"))
+		Expect(Iterate(iterator)).To(Equal([][]string{
+			{"003", "004"},
+			nil,
+		}))
+		Expect(received()[0]).To(Equal("id > '002'"))
+	})
+
+	It("Quotes the cursor", func() {
+		var expressions []string
+		iterator, err := NewKeysetIterator[string]().
+			Function(func(ctx context.Context, expression, order string,
+				size int) (items []string, err error) {
+				expressions = append(expressions, expression)
+				return
+			}).
+			Field("name").
+			Key(Key).
+			Cursor("it's").
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = iterator.Next(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(expressions).To(Equal([]string{"name > 'it''s'"}))
+	})
+
+	It("Keeps the cursor when fetching a batch fails", func() {
+		function, _ := Collection(5)
+		fail := false
+		iterator, err := NewKeysetIterator[string]().
+			Function(func(ctx context.Context, expression, order string,
+				size int) (items []string, err error) {
+				if fail {
+					err = errors.New("my error")
+					return
+				}
+				return function(ctx, expression, order, size)
+			}).
+			Field("id").
+			Key(Key).
+			Size(2).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = iterator.Next(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		fail = true
+		items, err := iterator.Next(ctx)
+		Expect(err).To(HaveOccurred())
+		Expect(items).To(BeEmpty())
+		Expect(err.Error()).To(ContainSubstring("my error"))
+		Expect(err.Error()).To(ContainSubstring("001"))
+		Expect(iterator.Cursor()).To(Equal("001"))
+		Expect(iterator.Done()).To(BeFalse())
+		fail = false
+		items, err = iterator.Next(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(items).To(Equal([]string{"002", "003"}))
+	})
+})
@@ -26,6 +26,8 @@ import (
 	"net/url"
 
 	"github.com/openshift-online/ocm-sdk-go/internal"
+	"github.com/openshift-online/ocm-sdk-go/retry"
+	"github.com/openshift-online/ocm-sdk-go/search"
 )
 
 // Request contains the information and logic needed to perform an HTTP request.
@@ -36,6 +38,9 @@ type Request struct {
 	query     url.Values
 	header    http.Header
 	body      []byte
+	reader    io.Reader
+	length    int64
+	getBody   func() (io.ReadCloser, error)
 }
 
 // GetMethod returns the request method (GET/POST/PATCH/PUT/DELETE).
@@ -61,12 +66,37 @@ func (r *Request) Parameter(name string, value interface{}) *Request {
 	return r
 }
 
+// SearchExpr sets the `search` query parameter to the text of the given search expression,
+// replacing any previous value. If the expression is nil the parameter is removed.
+func (r *Request) SearchExpr(value search.Expr) *Request {
+	if value == nil {
+		r.query.Del("search")
+		return r
+	}
+	if r.query == nil {
+		r.query = url.Values{}
+	}
+	r.query.Set("search", value.String())
+	return r
+}
+
 // Header adds a request header.
 func (r *Request) Header(name string, value interface{}) *Request {
 	internal.AddHeader(&r.header, name, value)
 	return r
 }
 
+// IfNoneMatch sets the `If-None-Match` header to the given entity tag, replacing any previous
+// value, so that the server can respond with 304 Not Modified if the object hasn't changed. Use
+// the NotModified method of the response to check that.
+func (r *Request) IfNoneMatch(etag string) *Request {
+	if r.header == nil {
+		r.header = http.Header{}
+	}
+	r.header.Set(IfNoneMatchHeader, etag)
+	return r
+}
+
 // Bytes sets the request body from an slice of bytes.
 func (r *Request) Bytes(value []byte) *Request {
 	if value != nil {
@@ -75,12 +105,46 @@ func (r *Request) Bytes(value []byte) *Request {
 	} else {
 		r.body = nil
 	}
+	r.reader = nil
 	return r
 }
 
 // String sets the request body from an string.
 func (r *Request) String(value string) *Request {
 	r.body = []byte(value)
+	r.reader = nil
+	return r
+}
+
+// Reader sets the request body from a reader. The content is streamed to the server as it is read,
+// without copying it completely to memory, so this is intended for large payloads that have
+// already been serialized. The length should be the number of bytes that will be read, or -1 if it
+// isn't known, and then the body will be sent using chunked transfer encoding. If the reader is
+// also an io.Closer it will be closed after sending the request.
+//
+// A streamed body can only be read once, so requests that use it aren't retried unless a function
+// to create a new copy of the body is provided with the GetBody method. To do so the SendContext
+// method replaces the retry policy of the connection with one that disables retries. This isn't
+// done if the context already contains a policy set with the retry.WithPolicy function, and then
+// the retry wrapper reads the body completely into memory in order to be able to send it again.
+// Note that when debug logging is enabled, or when failover is configured without that function,
+// the body will be read completely into memory anyhow.
+//
+// This is only available for the generic requests created with methods like the Post method of
+// the connection. The add requests of the generated clients always marshal a model object. To
+// stream a pre-serialized payload to the same endpoint use a generic request with the same path.
+func (r *Request) Reader(value io.Reader, length int64) *Request {
+	r.reader = value
+	r.length = length
+	r.body = nil
+	return r
+}
+
+// GetBody sets a function that returns a new copy of the body set with the Reader method. When it
+// is provided the request will be retried as usual, calling this function to rewind the body
+// before each retry. It has no effect if the body isn't set with the Reader method.
+func (r *Request) GetBody(value func() (io.ReadCloser, error)) *Request {
+	r.getBody = value
 	return r
 }
 
@@ -107,14 +171,38 @@ func (r *Request) SendContext(ctx context.Context) (result *Response, err error)
 		RawQuery: query.Encode(),
 	}
 	var body io.ReadCloser
-	if r.body != nil {
+	var length int64
+	var getBody func() (io.ReadCloser, error)
+	switch {
+	case r.reader != nil:
+		var ok bool
+		body, ok = r.reader.(io.ReadCloser)
+		if !ok {
+			body = io.NopCloser(r.reader)
+		}
+		length = r.length
+		getBody = r.getBody
+		if getBody == nil {
+			// The body can only be read once, so disable retries for this request, unless
+			// the caller explicitly asked for a policy:
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			_, ok := retry.PolicyFromContext(ctx)
+			if !ok {
+				ctx = retry.WithPolicy(ctx, retry.Policy{})
+			}
+		}
+	case r.body != nil:
 		body = io.NopCloser(bytes.NewBuffer(r.body))
 	}
 	request := &http.Request{
-		Method: r.method,
-		URL:    uri,
-		Header: header,
-		Body:   body,
+		Method:        r.method,
+		URL:           uri,
+		Header:        header,
+		Body:          body,
+		ContentLength: length,
+		GetBody:       getBody,
 	}
 	if ctx != nil {
 		request = request.WithContext(ctx)
@@ -0,0 +1,198 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the implementation of the transport wrapper that checks that responses
+// contain the fields required by the caller.
+
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/openshift-online/ocm-sdk-go/logging"
+)
+
+// WithRequiredFields creates a new context that tells the connection to check that the body of
+// the response contains the given fields, and to return a *MissingFieldsError if it doesn't. A
+// field is considered missing if it isn't present, if it is null or if it is an empty string.
+// Nested fields are separated with dots, and when a field is an array the rest of the path is
+// checked for each of its elements. For example:
+//
+//	ctx = sdk.WithRequiredFields(ctx, "id", "status.state")
+//	response, err := connection.ClustersMgmt().V1().Clusters().Cluster(id).Get().
+//		SendContext(ctx)
+//	var missing *sdk.MissingFieldsError
+//	if errors.As(err, &missing) {
+//		...
+//	}
+//
+// For a list response use the `items` prefix, for example `items.id` checks that all the items
+// have an identifier. Only successful responses with a JSON body are checked. This is intended
+// to fail loudly when a server bug returns objects without critical fields, instead of passing
+// empty values to the rest of the application.
+func WithRequiredFields(parent context.Context, fields ...string) context.Context {
+	merged := append(RequiredFieldsFromContext(parent), fields...)
+	return context.WithValue(parent, requiredFieldsKeyValue, merged)
+}
+
+// RequiredFieldsFromContext extracts the required fields that were stored in the context with the
+// WithRequiredFields function. If there are no such fields the result will be nil.
+func RequiredFieldsFromContext(ctx context.Context) []string {
+	value, _ := ctx.Value(requiredFieldsKeyValue).([]string)
+	if value == nil {
+		return nil
+	}
+	return append([]string{}, value...)
+}
+
+// requiredFieldsKeyType is the type of the key used to store the required fields in the context.
+type requiredFieldsKeyType string
+
+// requiredFieldsKeyValue is the key used to store the required fields in the context:
+const requiredFieldsKeyValue requiredFieldsKeyType = "requiredFields"
+
+// MissingFieldsError is the error returned when the body of a response doesn't contain some of the
+// fields required with the WithRequiredFields function.
+type MissingFieldsError struct {
+	// Method is the method of the request.
+	Method string
+
+	// Path is the path of the request.
+	Path string
+
+	// Fields contains the names of the missing fields, in the order they were required.
+	Fields []string
+}
+
+// Error is the implementation of the error interface.
+func (e *MissingFieldsError) Error() string {
+	noun := "field"
+	if len(e.Fields) > 1 {
+		noun = "fields"
+	}
+	return fmt.Sprintf(
+		"required %s %s missing from response to %s '%s'",
+		noun, logging.All(e.Fields), e.Method, e.Path,
+	)
+}
+
+// requiredFieldsTransportWrapper is a transport wrapper that creates round trippers that check that
+// responses contain the fields required in the context.
+type requiredFieldsTransportWrapper struct {
+}
+
+// requiredFieldsRoundTripper is a round tripper that checks that responses contain the fields
+// required in the context.
+type requiredFieldsRoundTripper struct {
+	next http.RoundTripper
+}
+
+// Make sure that we implement the http.RoundTripper interface:
+var _ http.RoundTripper = &requiredFieldsRoundTripper{}
+
+// Wrap creates a round tripper on top of the given one that checks the required fields.
+func (w *requiredFieldsTransportWrapper) Wrap(transport http.RoundTripper) http.RoundTripper {
+	return &requiredFieldsRoundTripper{
+		next: transport,
+	}
+}
+
+// RoundTrip is the implementation of the http.RoundTripper interface.
+func (t *requiredFieldsRoundTripper) RoundTrip(request *http.Request) (response *http.Response,
+	err error) {
+	// Send the request:
+	response, err = t.next.RoundTrip(request)
+	if err != nil {
+		return
+	}
+
+	// Do nothing if there are no required fields, or if the response isn't a successful one
+	// with a JSON body:
+	fields := RequiredFieldsFromContext(request.Context())
+	if len(fields) == 0 || response.Body == nil {
+		return
+	}
+	if response.StatusCode < 200 || response.StatusCode >= 300 ||
+		response.StatusCode == http.StatusNoContent {
+		return
+	}
+	if !strings.HasPrefix(response.Header.Get("Content-Type"), "application/json") {
+		return
+	}
+
+	// Read the complete body, and replace it with a reader that reads it from memory:
+	body, err := io.ReadAll(response.Body)
+	response.Body.Close()
+	if err != nil {
+		response = nil
+		return
+	}
+	response.Body = io.NopCloser(bytes.NewReader(body))
+
+	// Check the fields. Note that if the body can't be parsed we let it pass, so that the
+	// problem is reported by the code that decodes it.
+	var document interface{}
+	if json.Unmarshal(body, &document) != nil {
+		return
+	}
+	var missing []string
+	for _, field := range fields {
+		if !fieldPresent(document, strings.Split(field, ".")) {
+			missing = append(missing, field)
+		}
+	}
+	if len(missing) > 0 {
+		err = &MissingFieldsError{
+			Method: request.Method,
+			Path:   request.URL.Path,
+			Fields: missing,
+		}
+		response = nil
+	}
+	return
+}
+
+// fieldPresent checks if the given value contains the field with the given path. When the value is
+// an array the rest of the path is checked for each of its elements.
+func fieldPresent(value interface{}, path []string) bool {
+	switch typed := value.(type) {
+	case nil:
+		return false
+	case string:
+		return len(path) == 0 && typed != ""
+	case map[string]interface{}:
+		if len(path) == 0 {
+			return true
+		}
+		child, ok := typed[path[0]]
+		return ok && fieldPresent(child, path[1:])
+	case []interface{}:
+		for _, item := range typed {
+			if !fieldPresent(item, path) {
+				return false
+			}
+		}
+		return true
+	default:
+		return len(path) == 0
+	}
+}
@@ -0,0 +1,209 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains tests for the check of the fields required in responses.
+
+package sdk
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"  // nolint
+	. "github.com/onsi/ginkgo/v2/dsl/table" // nolint
+	. "github.com/onsi/gomega"              // nolint
+
+	"github.com/onsi/gomega/ghttp"
+
+	. "github.com/openshift-online/ocm-sdk-go/testing" // nolint
+)
+
+var _ = Describe("Required fields", func() {
+	var ctx context.Context
+	var server *ghttp.Server
+	var connection *Connection
+
+	BeforeEach(func() {
+		var err error
+
+		// Create the context:
+		ctx = context.Background()
+
+		// Create the server:
+		server = MakeTCPServer()
+
+		// Create the connection:
+		connection, err = NewConnectionBuilder().
+			Logger(logger).
+			URL(server.URL()).
+			Tokens(MakeTokenString("Bearer", 5*time.Minute)).
+			RetryInterval(10 * time.Millisecond).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		// Close the connection:
+		err := connection.Close()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Stop the server:
+		server.Close()
+	})
+
+	It("Accepts object that contains the required fields", func() {
+		server.AppendHandlers(
+			RespondWithJSON(http.StatusOK, `{"id":"123","state":"ready"}`),
+		)
+		ctx = WithRequiredFields(ctx, "id", "state")
+		response, err := connection.ClustersMgmt().V1().Clusters().Cluster("123").Get().
+			SendContext(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.Body().ID()).To(Equal("123"))
+		Expect(response.Body().State()).To(BeEquivalentTo("ready"))
+	})
+
+	It("Rejects object that doesn't contain a required field", func() {
+		server.AppendHandlers(
+			RespondWithJSON(http.StatusOK, `{"id":"","name":"my"}`),
+		)
+		ctx = WithRequiredFields(ctx, "id", "name", "state")
+		_, err := connection.ClustersMgmt().V1().Clusters().Cluster("123").Get().
+			SendContext(ctx)
+		Expect(err).To(HaveOccurred())
+		var missing *MissingFieldsError
+		Expect(errors.As(err, &missing)).To(BeTrue())
+		Expect(missing.Method).To(Equal(http.MethodGet))
+		Expect(missing.Path).To(Equal("/api/clusters_mgmt/v1/clusters/123"))
+		Expect(missing.Fields).To(Equal([]string{"id", "state"}))
+		Expect(err.Error()).To(ContainSubstring(
+			"required fields 'id' and 'state' missing from response to GET " +
+				"'/api/clusters_mgmt/v1/clusters/123'",
+		))
+	})
+
+	It("Doesn't retry request when a required field is missing", func() {
+		server.AppendHandlers(
+			RespondWithJSON(http.StatusOK, `{}`),
+			RespondWithJSON(http.StatusOK, `{}`),
+		)
+		ctx = WithRequiredFields(ctx, "id")
+		_, err := connection.ClustersMgmt().V1().Clusters().Cluster("123").Get().
+			SendContext(ctx)
+		Expect(err).To(HaveOccurred())
+		Expect(server.ReceivedRequests()).To(HaveLen(1))
+	})
+
+	It("Doesn't check fields when not required", func() {
+		server.AppendHandlers(
+			RespondWithJSON(http.StatusOK, `{}`),
+		)
+		response, err := connection.ClustersMgmt().V1().Clusters().Cluster("123").Get().
+			SendContext(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.Body().ID()).To(BeEmpty())
+	})
+
+	It("Doesn't check fields of error responses", func() {
+		server.AppendHandlers(
+			RespondWithJSON(http.StatusNotFound, `{"kind":"Error","reason":"Not found"}`),
+		)
+		ctx = WithRequiredFields(ctx, "id")
+		response, err := connection.ClustersMgmt().V1().Clusters().Cluster("123").Get().
+			SendContext(ctx)
+		Expect(err).To(HaveOccurred())
+		var missing *MissingFieldsError
+		Expect(errors.As(err, &missing)).To(BeFalse())
+		Expect(response.Status()).To(Equal(http.StatusNotFound))
+	})
+
+	It("Checks fields of all the items of a list", func() {
+		server.AppendHandlers(
+			RespondWithJSON(
+				http.StatusOK,
+				`{"kind":"ClusterList","items":[{"id":"123"},{"name":"my"}]}`,
+			),
+		)
+		ctx = WithRequiredFields(ctx, "items.id")
+		_, err := connection.ClustersMgmt().V1().Clusters().List().
+			SendContext(ctx)
+		var missing *MissingFieldsError
+		Expect(errors.As(err, &missing)).To(BeTrue())
+		Expect(missing.Fields).To(Equal([]string{"items.id"}))
+	})
+
+	It("Merges fields of nested contexts", func() {
+		ctx = WithRequiredFields(ctx, "id")
+		ctx = WithRequiredFields(ctx, "name")
+		Expect(RequiredFieldsFromContext(ctx)).To(Equal([]string{"id", "name"}))
+	})
+
+	DescribeTable(
+		"Checks presence of field",
+		func(document interface{}, field string, expected bool) {
+			Expect(fieldPresent(document, strings.Split(field, "."))).To(Equal(expected))
+		},
+		Entry(
+			"Present string",
+			map[string]interface{}{"id": "123"}, "id", true,
+		),
+		Entry(
+			"Empty string",
+			map[string]interface{}{"id": ""}, "id", false,
+		),
+		Entry(
+			"Null",
+			map[string]interface{}{"id": nil}, "id", false,
+		),
+		Entry(
+			"Absent",
+			map[string]interface{}{}, "id", false,
+		),
+		Entry(
+			"False boolean",
+			map[string]interface{}{"multi_az": false}, "multi_az", true,
+		),
+		Entry(
+			"Zero number",
+			map[string]interface{}{"count": 0.0}, "count", true,
+		),
+		Entry(
+			"Nested",
+			map[string]interface{}{
+				"status": map[string]interface{}{"state": "ready"},
+			},
+			"status.state", true,
+		),
+		Entry(
+			"Nested absent",
+			map[string]interface{}{
+				"status": map[string]interface{}{},
+			},
+			"status.state", false,
+		),
+		Entry(
+			"Path through string",
+			map[string]interface{}{"status": "ready"}, "status.state", false,
+		),
+		Entry(
+			"Empty array",
+			map[string]interface{}{"items": []interface{}{}}, "items.id", true,
+		),
+	)
+})
@@ -34,6 +34,12 @@ func (r *Response) Status() int {
 	return r.status
 }
 
+// NotModified returns true if the server responded with 304 Not Modified because the object
+// matches the entity tag set with the IfNoneMatch method of the request.
+func (r *Response) NotModified() bool {
+	return r.status == http.StatusNotModified
+}
+
 // Bytes returns an slice of bytes containing the response body. Not that this will never return
 // nil; if the response body is empty it will return an empty slice.
 func (r *Response) Bytes() []byte {
@@ -52,3 +58,11 @@ func (r *Response) Header(name string) string {
 	}
 	return r.header.Get(name)
 }
+
+// OperationID returns the identifier that the server assigned to the request, from the
+// `X-Operation-ID` header. This is useful when asking for support, as it can be used to find the
+// request in the logs of the server. In case there's no such header, an empty string will be
+// returned.
+func (r *Response) OperationID() string {
+	return r.Header(OperationIDHeader)
+}
@@ -0,0 +1,43 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains functions that store and extract the attempt number from the context.
+
+package retry
+
+import (
+	"context"
+)
+
+// ContextWithAttempt creates a new context containing the given attempt number. The retry round
+// tripper uses this to tell the round trippers that it wraps which attempt they are sending.
+func ContextWithAttempt(parent context.Context, attempt int) context.Context {
+	return context.WithValue(parent, attemptKeyValue, attempt)
+}
+
+// AttemptFromContext extracts the attempt number from the context. The first attempt is number
+// one. If the context doesn't contain an attempt number, for example because the request isn't
+// being sent by a retry round tripper, the result will be zero.
+func AttemptFromContext(ctx context.Context) int {
+	attempt, _ := ctx.Value(attemptKeyValue).(int)
+	return attempt
+}
+
+// attemptKeyType is the type of the key used to store the attempt number in the context.
+type attemptKeyType string
+
+// attemptKeyValue is the key used to store the attempt number in the context:
+const attemptKeyValue attemptKeyType = "attempt"
@@ -0,0 +1,72 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the functions used to detect the responses that the server sends when it is
+// in maintenance mode.
+
+package retry
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultMaintenanceInterval is the default time to wait before retrying a request that failed
+// because the server is in maintenance mode.
+const DefaultMaintenanceInterval = 30 * time.Second
+
+// ErrMaintenance is the error returned by the round trippers created by the retry wrapper when the
+// server is still in maintenance mode after the last attempt. Use errors.Is to check for it, as it
+// is usually wrapped by other errors.
+var ErrMaintenance = errors.New("server is in maintenance mode")
+
+// MaintenanceMatcher is a function that checks if a response indicates that the server is in
+// maintenance mode. Matchers that need to inspect the body must leave it ready to be read again
+// from the beginning, as the response may be returned to the caller.
+type MaintenanceMatcher func(response *http.Response) bool
+
+// DefaultMaintenanceMatcher is the matcher used by default by the retry wrapper. It considers that
+// the server is in maintenance mode when the response code is 503 and the body contains the word
+// `maintenance`, ignoring case. Only the first bytes of the body are checked.
+func DefaultMaintenanceMatcher(response *http.Response) bool {
+	if response.StatusCode != http.StatusServiceUnavailable || response.Body == nil {
+		return false
+	}
+	prefix, err := io.ReadAll(io.LimitReader(response.Body, maintenanceBodyLimit))
+	response.Body = &prefixedBody{
+		Reader: io.MultiReader(bytes.NewReader(prefix), response.Body),
+		Closer: response.Body,
+	}
+	if err != nil {
+		return false
+	}
+	return strings.Contains(strings.ToLower(string(prefix)), "maintenance")
+}
+
+// maintenanceBodyLimit is the maximum number of bytes of the body checked by the default
+// maintenance matcher.
+const maintenanceBodyLimit = 64 * 1024
+
+// prefixedBody is a response body where the bytes already read by the matcher are put back in
+// front of the rest of the original body.
+type prefixedBody struct {
+	io.Reader
+	io.Closer
+}
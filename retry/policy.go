@@ -0,0 +1,95 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the types and functions used to override the retry policy of individual
+// requests using the context.
+
+package retry
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Policy contains the retry settings that the retry round tripper will use for a request instead of
+// the ones configured in the transport wrapper. Use the WithPolicy function to put it in the
+// context of the request.
+type Policy struct {
+	// Limit is the maximum number of retries. When this is zero, or negative, no retries will
+	// be performed. Note that this means that the zero value of the policy disables retries.
+	Limit int
+
+	// Interval is the time to wait before the first retry, doubled for each subsequent retry.
+	// When this is zero the interval configured in the transport wrapper will be used.
+	Interval time.Duration
+
+	// Jitter is the factor used to randomize the retry intervals. When this is zero, or isn't
+	// between zero and one, the jitter configured in the transport wrapper will be used.
+	Jitter float64
+
+	// Statuses contains the HTTP status codes of the responses that will be retried. Responses
+	// with codes 429 and 503 are retried for any method, as in those cases the server didn't
+	// process the request. Responses with other codes are retried only for GET requests and
+	// for requests that have an idempotency key. When this is nil the default is to retry 429
+	// and all the 5xx codes.
+	Statuses []int
+}
+
+// WithPolicy creates a new context that contains the given retry policy. The retry round tripper
+// will use it instead of the configuration of the transport wrapper for the requests that use the
+// context. For example, to disable retries for a latency critical request:
+//
+//	ctx = retry.WithPolicy(ctx, retry.Policy{})
+//	response, err := connection.ClustersMgmt().V1().Clusters().List().SendContext(ctx)
+func WithPolicy(parent context.Context, policy Policy) context.Context {
+	return context.WithValue(parent, policyKeyValue, policy)
+}
+
+// PolicyFromContext extracts the retry policy that was stored in the context with the WithPolicy
+// function. The second result will be false if the context doesn't contain a policy.
+func PolicyFromContext(ctx context.Context) (policy Policy, ok bool) {
+	policy, ok = ctx.Value(policyKeyValue).(Policy)
+	return
+}
+
+// retryable checks if a response with the given status code should be retried according to the
+// policy. The method and idempotent flag are used to decide for codes that don't guarantee that
+// the server didn't process the request.
+func (p *Policy) retryable(code int, method string, idempotent bool) bool {
+	if p.Statuses == nil {
+		if code == http.StatusServiceUnavailable || code == http.StatusTooManyRequests {
+			return true
+		}
+		return code >= 500 && (method == http.MethodGet || idempotent)
+	}
+	for _, status := range p.Statuses {
+		if status != code {
+			continue
+		}
+		if code == http.StatusServiceUnavailable || code == http.StatusTooManyRequests {
+			return true
+		}
+		return method == http.MethodGet || idempotent
+	}
+	return false
+}
+
+// policyKeyType is the type of the key used to store the retry policy in the context.
+type policyKeyType string
+
+// policyKeyValue is the key used to store the retry policy in the context:
+const policyKeyValue policyKeyType = "policy"
@@ -30,6 +30,8 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/openshift-online/ocm-sdk-go/clock"
+	"github.com/openshift-online/ocm-sdk-go/headers"
 	"github.com/openshift-online/ocm-sdk-go/logging"
 )
 
@@ -43,28 +45,37 @@ const (
 // TransportWrapperBuilder contains the data and logic needed to create a new retry transport
 // wrapper.
 type TransportWrapperBuilder struct {
-	logger   logging.Logger
-	limit    int
-	interval time.Duration
-	jitter   float64
+	logger      logging.Logger
+	clock       clock.Clock
+	limit       int
+	interval    time.Duration
+	jitter      float64
+	maintenance MaintenanceMatcher
+	pause       time.Duration
 }
 
 // TransportWrapper contains the data and logic needed to wrap an HTTP round tripper with another
 // one that adds retry capability.
 type TransportWrapper struct {
-	logger   logging.Logger
-	limit    int
-	interval time.Duration
-	jitter   float64
+	logger      logging.Logger
+	clock       clock.Clock
+	limit       int
+	interval    time.Duration
+	jitter      float64
+	maintenance MaintenanceMatcher
+	pause       time.Duration
 }
 
 // roundTripper is a round tripper that adds retry logic.
 type roundTripper struct {
-	logger    logging.Logger
-	limit     int
-	interval  time.Duration
-	jitter    float64
-	transport http.RoundTripper
+	logger      logging.Logger
+	clock       clock.Clock
+	limit       int
+	interval    time.Duration
+	jitter      float64
+	maintenance MaintenanceMatcher
+	pause       time.Duration
+	transport   http.RoundTripper
 }
 
 // Make sure that we implement the interface:
@@ -74,9 +85,11 @@ var _ http.RoundTripper = (*roundTripper)(nil)
 // retry round tripper.
 func NewTransportWrapper() *TransportWrapperBuilder {
 	return &TransportWrapperBuilder{
+		clock:    clock.Real,
 		limit:    DefaultLimit,
 		interval: DefaultInterval,
 		jitter:   DefaultJitter,
+		pause:    DefaultMaintenanceInterval,
 	}
 }
 
@@ -87,8 +100,21 @@ func (b *TransportWrapperBuilder) Logger(value logging.Logger) *TransportWrapper
 	return b
 }
 
+// Clock sets the clock that will be used to wait between retries. The default is to use the real
+// clock of the system. This is intended for unit tests that need to check the retry intervals
+// without actually waiting.
+func (b *TransportWrapperBuilder) Clock(value clock.Clock) *TransportWrapperBuilder {
+	if value == nil {
+		value = clock.Real
+	}
+	b.clock = value
+	return b
+}
+
 // Limit sets the maximum number of retries for a request. When this is zero no retries will be
-// performed. The default value is two.
+// performed. The default value is two. Note that retries are also stopped when the context of the
+// request has a deadline and there isn't enough time left to wait and do another attempt, assuming
+// that it will take as long as the previous one.
 func (b *TransportWrapperBuilder) Limit(value int) *TransportWrapperBuilder {
 	b.limit = value
 	return b
@@ -112,6 +138,32 @@ func (b *TransportWrapperBuilder) Jitter(value float64) *TransportWrapperBuilder
 	return b
 }
 
+// MaintenanceMatcher sets the function used to detect the responses that indicate that the server
+// is in maintenance mode, for example DefaultMaintenanceMatcher. Those requests are retried
+// waiting the maintenance interval instead of the normal one, so that clients don't add load to
+// the server while it is in maintenance. This only applies to GET requests and to requests that
+// have an idempotency key. Other requests are handled like any other response with the same
+// status code, so for example a 503 response is still retried with the normal interval. If the
+// server is still in maintenance after the last retry the response is discarded and the round
+// tripper returns an error that wraps ErrMaintenance. When no retry is done, because the retry
+// limit is zero or because there isn't time left before the deadline, the response is returned
+// unchanged. The default is to not detect maintenance mode, and then those responses are handled
+// like any other.
+func (b *TransportWrapperBuilder) MaintenanceMatcher(
+	value MaintenanceMatcher) *TransportWrapperBuilder {
+	b.maintenance = value
+	return b
+}
+
+// MaintenanceInterval sets the time to wait before retrying a request that failed because the
+// server is in maintenance mode. This interval isn't doubled for each retry, but the jitter factor
+// is applied to it. The default is 30 seconds.
+func (b *TransportWrapperBuilder) MaintenanceInterval(
+	value time.Duration) *TransportWrapperBuilder {
+	b.pause = value
+	return b
+}
+
 // Build uses the information stored in the builder to create a new transport wrapper.
 func (b *TransportWrapperBuilder) Build(ctx context.Context) (result *TransportWrapper, err error) {
 	// Check parameters:
@@ -140,13 +192,23 @@ func (b *TransportWrapperBuilder) Build(ctx context.Context) (result *TransportW
 		)
 		return
 	}
+	if b.pause <= 0 {
+		err = fmt.Errorf(
+			"maintenance interval %s isn't valid, it should be greater than zero",
+			b.pause,
+		)
+		return
+	}
 
 	// Create and populate the object:
 	result = &TransportWrapper{
-		logger:   b.logger,
-		limit:    b.limit,
-		interval: b.interval,
-		jitter:   b.jitter,
+		logger:      b.logger,
+		clock:       b.clock,
+		limit:       b.limit,
+		interval:    b.interval,
+		jitter:      b.jitter,
+		maintenance: b.maintenance,
+		pause:       b.pause,
 	}
 
 	return
@@ -155,11 +217,14 @@ func (b *TransportWrapperBuilder) Build(ctx context.Context) (result *TransportW
 // Wrap creates a new round tripper that wraps the given one and implements the retry logic.
 func (w *TransportWrapper) Wrap(transport http.RoundTripper) http.RoundTripper {
 	return &roundTripper{
-		logger:    w.logger,
-		limit:     w.limit,
-		interval:  w.interval,
-		jitter:    w.jitter,
-		transport: transport,
+		logger:      w.logger,
+		clock:       w.clock,
+		limit:       w.limit,
+		interval:    w.interval,
+		jitter:      w.jitter,
+		maintenance: w.maintenance,
+		pause:       w.pause,
+		transport:   transport,
 	}
 }
 
@@ -178,6 +243,12 @@ func (w *TransportWrapper) Jitter() float64 {
 	return w.jitter
 }
 
+// MaintenanceInterval returns the time to wait before retrying a request that failed because the
+// server is in maintenance mode.
+func (w *TransportWrapper) MaintenanceInterval() time.Duration {
+	return w.pause
+}
+
 // Close releases all the resources used by the wrapper.
 func (w *TransportWrapper) Close() error {
 	return nil
@@ -185,18 +256,21 @@ func (w *TransportWrapper) Close() error {
 
 // RoundTrip is the implementation of the round tripper interface.
 func (t *roundTripper) RoundTrip(request *http.Request) (response *http.Response, err error) {
-	// Get the context:
+	// Get the context and the retry policy, which may have been overridden for this request:
 	ctx := request.Context()
+	policy := t.policy(ctx)
 
-	// If the request has a body then we need to read it fully and copy it in memory, so that we
-	// can later use that copy to retry the request. We also need to restore the old body before
-	// returning because the caller my rely on the type of body that it passed, for example.
+	// If the request has a body and may be retried then we need to read it fully and copy it in
+	// memory, so that we can later use that copy to retry the request. That isn't necessary if
+	// the request has a function to get a new copy of the body, as that is used instead. We also
+	// need to restore the old body before returning because the caller my rely on the type of
+	// body that it passed, for example.
 	originalBody := request.Body
 	defer func() {
 		request.Body = originalBody
 	}()
 	var bodyCopy []byte
-	if originalBody != nil {
+	if originalBody != nil && policy.Limit > 0 && request.GetBody == nil {
 		bodyCopy, err = io.ReadAll(originalBody)
 		if err != nil {
 			return
@@ -205,24 +279,94 @@ func (t *roundTripper) RoundTrip(request *http.Request) (response *http.Response
 
 	// Try to send the request till it succeeds or else the retry limit is exceeded:
 	attempt := 0
+	var delay time.Duration
 	for {
 		// If this is not the first attempt then we should wait:
 		if attempt > 0 {
-			t.sleep(ctx, attempt)
+			t.logger.Debug(ctx, "Wating %s before next attempt", delay)
+			err = t.wait(ctx, delay)
+			if err != nil {
+				// The body of the previous response has already been closed, so it can't
+				// be returned to the caller:
+				response = nil
+				return
+			}
 		}
 
 		// Each time that we retry the request we need to rewind the request body:
-		if bodyCopy != nil {
+		switch {
+		case bodyCopy != nil:
 			request.Body = io.NopCloser(bytes.NewBuffer(bodyCopy))
+		case attempt > 0 && originalBody != nil && request.GetBody != nil:
+			request.Body, err = request.GetBody()
+			if err != nil {
+				err = fmt.Errorf("can't rewind request body: %w", err)
+				return
+			}
 		}
 
-		// Do an attempt, and return inmediately if this is the last one:
-		response, err = t.transport.RoundTrip(request)
+		// Do an attempt, and return inmediately if this is the last one. Note that we put the
+		// attempt number in the context so that the round trippers that we wrap can use it.
 		attempt++
-		if attempt > t.limit {
+		start := t.clock.Now()
+		response, err = t.transport.RoundTrip(
+			request.WithContext(ContextWithAttempt(ctx, attempt)),
+		)
+		elapsed := t.clock.Since(start)
+
+		// Only requests that can be safely sent again are handled as maintenance, other
+		// requests are handled like any other response with the same status code:
+		method := request.Method
+		idempotent := request.Header.Get(headers.IdempotencyKeyHeader) != ""
+		maintenance := err == nil && t.maintenance != nil &&
+			(method == http.MethodGet || idempotent) && t.maintenance(response)
+		if attempt > policy.Limit {
+			if maintenance && attempt > 1 {
+				response, err = t.maintenanceError(ctx, request, response)
+			}
+			return
+		}
+
+		// Calculate the time to wait before the next attempt. If this attempt failed and
+		// there isn't enough time left before the deadline of the context to wait and to do
+		// another attempt, assuming that it will take as long as this one, then return
+		// inmediately. This way the caller gets the result of this attempt instead of the
+		// error caused by the deadline.
+		delay = t.delay(attempt, &policy)
+		if maintenance {
+			delay = t.maintenanceDelay(&policy)
+		}
+		failed := maintenance || err != nil ||
+			policy.retryable(response.StatusCode, method, idempotent)
+		if failed && !t.fits(ctx, delay+elapsed) {
+			t.logger.Debug(
+				ctx,
+				"Request for method %s and URL '%s' won't be retried because "+
+					"there isn't enough time left before the deadline",
+				request.Method, request.URL,
+			)
+			if err != nil {
+				err = fmt.Errorf("can't send request: %w", err)
+			}
+			if maintenance && attempt > 1 {
+				response, err = t.maintenanceError(ctx, request, response)
+			}
 			return
 		}
 
+		// Handle responses that indicate that the server is in maintenance mode. We already
+		// checked that these requests can be safely sent again.
+		if maintenance {
+			t.logger.Warn(
+				ctx,
+				"Request for method %s and URL '%s' failed because the server is in "+
+					"maintenance mode, will try again in %s",
+				request.Method, request.URL, delay,
+			)
+			t.closeBody(ctx, request, response)
+			continue
+		}
+
 		// Handle errors without HTTP response:
 		if err != nil {
 			message := err.Error()
@@ -266,71 +410,113 @@ func (t *roundTripper) RoundTrip(request *http.Request) (response *http.Response
 			}
 		}
 
-		// Handle HTTP responses with error codes:
-		method := request.Method
+		// Handle HTTP responses with error codes. For 429 and 503 we know that the server
+		// didn't process the request, so we can safely retry regardless of the method. For any
+		// other 5xx status code we can't be sure if the server processed the request, so we
+		// retry only GET requests, as those don't have side effects, and requests that have
+		// an idempotency key, as the server can use it to discard duplicates. The policy may
+		// change the set of codes that are retried. For any other status code we just return
+		// the result to the caller.
 		code := response.StatusCode
-		switch {
-		case code == http.StatusServiceUnavailable || code == http.StatusTooManyRequests:
-			// For 429 and 503 we know that the server didn't process the request, so we
-			// can safely retry regardless of the method.
-			t.logger.Warn(
-				ctx,
-				"Request for method %s and URL '%s' failed with code %d, "+
-					"will try again",
-				request.Method, request.URL, code,
-			)
-			err = response.Body.Close()
-			if err != nil {
-				t.logger.Error(
-					ctx,
-					"Failed to close response body for method '%s' and URL '%s'",
-					request.Method, request.URL,
-				)
-			}
-			continue
-		case code >= 500 && method == http.MethodGet:
-			// For any other 5xx status code we can't be sure if the server processed
-			// the request, so we retry only GET requests, as those don't have side
-			// effects.
-			t.logger.Warn(
-				ctx,
-				"Request for method %s and URL '%s' failed with code %d, "+
-					"will try again",
-				request.Method, request.URL, code,
-			)
-			err = response.Body.Close()
-			if err != nil {
-				t.logger.Error(
-					ctx,
-					"Failed to close response body for method '%s' and URL '%s'",
-					request.Method, request.URL,
-				)
-			}
-			continue
-		default:
-			// For any other status code we can't be sure if the server processed the
-			// request, so we just return the result to the caller.
+		if !policy.retryable(code, method, idempotent) {
 			return
 		}
+		t.logger.Warn(
+			ctx,
+			"Request for method %s and URL '%s' failed with code %d, will try again",
+			request.Method, request.URL, code,
+		)
+		t.closeBody(ctx, request, response)
 	}
 }
 
-// sleep calculates a retry interval taking into account the configured interval and jitter factor
-// and then waits that time.
-func (t *roundTripper) sleep(ctx context.Context, attempt int) {
+// wait waits the given duration, or till the context is cancelled. In that case it returns the
+// error of the context.
+func (t *roundTripper) wait(ctx context.Context, delay time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.clock.After(delay):
+		return nil
+	}
+}
+
+// closeBody closes the body of a response that won't be returned to the caller.
+func (t *roundTripper) closeBody(ctx context.Context, request *http.Request,
+	response *http.Response) {
+	err := response.Body.Close()
+	if err != nil {
+		t.logger.Error(
+			ctx,
+			"Failed to close response body for method '%s' and URL '%s'",
+			request.Method, request.URL,
+		)
+	}
+}
+
+// maintenanceError discards a response that indicates that the server is in maintenance mode and
+// returns the error that should be returned to the caller instead.
+func (t *roundTripper) maintenanceError(ctx context.Context, request *http.Request,
+	response *http.Response) (*http.Response, error) {
+	t.closeBody(ctx, request, response)
+	return nil, fmt.Errorf(
+		"request failed with code %d: %w",
+		response.StatusCode, ErrMaintenance,
+	)
+}
+
+// policy returns the retry policy for a request with the given context. That is the policy stored
+// in the context with the WithPolicy function, if any, completed with the interval and jitter
+// configured in the wrapper. Otherwise it is the configuration of the wrapper.
+func (t *roundTripper) policy(ctx context.Context) Policy {
+	policy, ok := PolicyFromContext(ctx)
+	if !ok {
+		return Policy{
+			Limit:    t.limit,
+			Interval: t.interval,
+			Jitter:   t.jitter,
+		}
+	}
+	if policy.Interval <= 0 {
+		policy.Interval = t.interval
+	}
+	if policy.Jitter <= 0 || policy.Jitter > 1 {
+		policy.Jitter = t.jitter
+	}
+	return policy
+}
+
+// fits checks if the given duration fits in the time left before the deadline of the given context.
+// It always returns true if the context has no deadline.
+func (t *roundTripper) fits(ctx context.Context, duration time.Duration) bool {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return true
+	}
+	return t.clock.Now().Add(duration).Before(deadline)
+}
+
+// maintenanceDelay calculates the time to wait before the next attempt when the server is in
+// maintenance mode, taking into account the jitter factor of the given policy.
+func (t *roundTripper) maintenanceDelay(policy *Policy) time.Duration {
+	factor := policy.Jitter * (1 - 2*rand.Float64())
+	return t.pause + time.Duration(float64(t.pause)*factor)
+}
+
+// delay calculates the time to wait before the next attempt taking into account the interval and
+// jitter factor of the given policy.
+func (t *roundTripper) delay(attempt int, policy *Policy) time.Duration {
 	// Start with the configured interval:
-	interval := t.interval
+	interval := policy.Interval
 
 	// Double the interval for each attempt:
 	interval *= 1 << (attempt - 1)
 
 	// Adjust the interval adding or subtracting a random amount. For example, if the jitter
 	// factor given in the configuration is 0.1 will add or sustract up to a 10%.
-	factor := t.jitter * (1 - 2*rand.Float64())
+	factor := policy.Jitter * (1 - 2*rand.Float64())
 	delta := time.Duration(float64(interval) * factor)
 	interval += delta
 
-	// Go sleep for a while:
-	t.logger.Debug(ctx, "Wating %s before next attempt", interval)
-	time.Sleep(interval)
+	return interval
 }
@@ -21,6 +21,7 @@ package retry
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"io"
 	"net"
 	"net/http"
@@ -30,6 +31,8 @@ import (
 
 	"golang.org/x/net/http2"
 
+	"github.com/openshift-online/ocm-sdk-go/headers"
+
 	. "github.com/onsi/ginkgo/v2/dsl/core"             // nolint
 	. "github.com/onsi/gomega"                         // nolint
 	. "github.com/openshift-online/ocm-sdk-go/testing" // nolint
@@ -171,6 +174,18 @@ var _ = Describe("Creation", func() {
 		Expect(message).To(ContainSubstring("2"))
 		Expect(message).To(ContainSubstring("between zero and one"))
 	})
+
+	It("Can't be created with zero maintenance interval", func() {
+		wrapper, err := NewTransportWrapper().
+			Logger(logger).
+			MaintenanceInterval(0).
+			Build(ctx)
+		Expect(err).To(HaveOccurred())
+		Expect(wrapper).To(BeNil())
+		message := err.Error()
+		Expect(message).To(ContainSubstring("maintenance interval"))
+		Expect(message).To(ContainSubstring("greater than zero"))
+	})
 })
 
 var _ = Describe("Server error", func() {
@@ -817,6 +832,272 @@ var _ = It("Tolerates unepected EOF", func() {
 	Expect(body).To(MatchJSON("{}"))
 })
 
+var _ = Describe("Clock", func() {
+	It("Uses the clock to wait between retries", func() {
+		// Create a transport that fails twice and then succeeds:
+		transport := CombineTransports(
+			TextTransport(http.StatusServiceUnavailable, `ko`),
+			TextTransport(http.StatusServiceUnavailable, `ko`),
+			JSONTransport(http.StatusOK, `{ "ok": true }`),
+		)
+
+		// Wrap the transport using a fake clock and a long interval, so that the test
+		// would take long if the real clock were used:
+		start := time.Now()
+		clock := NewFakeClock(start)
+		wrapper, err := NewTransportWrapper().
+			Logger(logger).
+			Clock(clock).
+			Limit(2).
+			Interval(10 * time.Second).
+			Jitter(0).
+			Build(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err = wrapper.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+
+		// Send the request:
+		client := &http.Client{
+			Transport: wrapper.Wrap(transport),
+		}
+		response, err := client.Get("http://api.example.com/mypath")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+		// Verify that the clock was advanced the first interval plus the second, which is
+		// the double of the first:
+		Expect(clock.Since(start)).To(Equal(30 * time.Second))
+		Expect(time.Since(start)).To(BeNumerically("<", 10*time.Second))
+	})
+})
+
+var _ = Describe("Deadline", func() {
+	// Send sends a request with a context that has the given timeout, using a transport that
+	// always fails with a 503 error and that takes one second for each attempt, according to
+	// the fake clock. It returns the response and the number of attempts.
+	var Send = func(timeout time.Duration) (response *http.Response, attempts int) {
+		// Create the transport:
+		start := time.Now()
+		clock := NewFakeClock(start)
+		transport := TransportFunc(func(request *http.Request) (*http.Response, error) {
+			attempts++
+			clock.Advance(time.Second)
+			return TextTransport(http.StatusServiceUnavailable, `ko`).RoundTrip(request)
+		})
+
+		// Wrap the transport:
+		wrapper, err := NewTransportWrapper().
+			Logger(logger).
+			Clock(clock).
+			Limit(2).
+			Interval(10 * time.Second).
+			Jitter(0).
+			Build(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err = wrapper.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+
+		// Send the request:
+		ctx, cancel := context.WithDeadline(context.Background(), start.Add(timeout))
+		defer cancel()
+		request, err := http.NewRequestWithContext(
+			ctx,
+			http.MethodGet,
+			"http://api.example.com/mypath",
+			nil,
+		)
+		Expect(err).ToNot(HaveOccurred())
+		client := &http.Client{
+			Transport: wrapper.Wrap(transport),
+		}
+		response, err = client.Do(request)
+		Expect(err).ToNot(HaveOccurred())
+		return
+	}
+
+	It("Doesn't retry if there isn't enough time before the deadline", func() {
+		response, attempts := Send(5 * time.Second)
+		defer response.Body.Close()
+		Expect(attempts).To(Equal(1))
+		Expect(response.StatusCode).To(Equal(http.StatusServiceUnavailable))
+		body, err := io.ReadAll(response.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(body)).To(Equal("ko"))
+	})
+
+	It("Stops retrying when the time left isn't enough for the next attempt", func() {
+		// The first retry needs 10 seconds of wait plus one of attempt, and the second 20
+		// plus one, so only the first fits:
+		response, attempts := Send(20 * time.Second)
+		defer response.Body.Close()
+		Expect(attempts).To(Equal(2))
+		Expect(response.StatusCode).To(Equal(http.StatusServiceUnavailable))
+	})
+
+	It("Retries if there is enough time before the deadline", func() {
+		response, attempts := Send(time.Hour)
+		defer response.Body.Close()
+		Expect(attempts).To(Equal(3))
+	})
+
+	It("Wraps the error if there isn't enough time before the deadline", func() {
+		// Create a transport that fails with a retryable error:
+		start := time.Now()
+		clock := NewFakeClock(start)
+		attempts := 0
+		transport := TransportFunc(func(request *http.Request) (*http.Response, error) {
+			attempts++
+			clock.Advance(time.Second)
+			return nil, io.EOF
+		})
+
+		// Wrap the transport:
+		wrapper, err := NewTransportWrapper().
+			Logger(logger).
+			Clock(clock).
+			Limit(2).
+			Interval(10 * time.Second).
+			Jitter(0).
+			Build(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err = wrapper.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+
+		// Send the request:
+		ctx, cancel := context.WithDeadline(context.Background(), start.Add(5*time.Second))
+		defer cancel()
+		request, err := http.NewRequestWithContext(
+			ctx,
+			http.MethodGet,
+			"http://api.example.com/mypath",
+			nil,
+		)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = wrapper.Wrap(transport).RoundTrip(request)
+		Expect(err).To(HaveOccurred())
+		Expect(errors.Is(err, io.EOF)).To(BeTrue())
+		Expect(err.Error()).To(HavePrefix("can't send request: "))
+		Expect(attempts).To(Equal(1))
+	})
+})
+
+var _ = Describe("Attempt", func() {
+	It("Puts the attempt number in the context", func() {
+		// Create a transport that fails twice and then succeeds, remembering the attempt
+		// numbers that it finds in the context:
+		var attempts []int
+		responses := CombineTransports(
+			TextTransport(http.StatusServiceUnavailable, `ko`),
+			TextTransport(http.StatusServiceUnavailable, `ko`),
+			JSONTransport(http.StatusOK, `{ "ok": true }`),
+		)
+		transport := TransportFunc(func(request *http.Request) (*http.Response, error) {
+			attempts = append(attempts, AttemptFromContext(request.Context()))
+			return responses.RoundTrip(request)
+		})
+
+		// Wrap the transport:
+		wrapper, err := NewTransportWrapper().
+			Logger(logger).
+			Clock(NewFakeClock(time.Now())).
+			Build(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err = wrapper.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+
+		// Send the request:
+		client := &http.Client{
+			Transport: wrapper.Wrap(transport),
+		}
+		response, err := client.Get("http://api.example.com/mypath")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+		// Verify the attempt numbers:
+		Expect(attempts).To(Equal([]int{1, 2, 3}))
+	})
+
+	It("Returns zero if there is no attempt in the context", func() {
+		Expect(AttemptFromContext(context.Background())).To(BeZero())
+	})
+})
+
+var _ = Describe("Idempotency key", func() {
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.Background()
+	})
+
+	// Post sends a POST request with the given headers using a transport that fails with a 500
+	// error the first time and succeeds the second time. It returns the response status code and
+	// the idempotency keys received by the transport.
+	var Post = func(header http.Header) (code int, keys []string) {
+		// Create a transport that returns a 500 error for the first request and 200 for the
+		// second, remembering the keys that it receives:
+		responses := CombineTransports(
+			TextTransport(http.StatusInternalServerError, `ko`),
+			JSONTransport(http.StatusOK, `{ "ok": true }`),
+		)
+		transport := TransportFunc(func(request *http.Request) (*http.Response, error) {
+			keys = append(keys, request.Header.Get(headers.IdempotencyKeyHeader))
+			return responses.RoundTrip(request)
+		})
+
+		// Wrap the transport:
+		wrapper, err := NewTransportWrapper().
+			Logger(logger).
+			Clock(NewFakeClock(time.Now())).
+			Build(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err = wrapper.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+
+		// Send the request:
+		client := &http.Client{
+			Transport: wrapper.Wrap(transport),
+		}
+		request, err := http.NewRequest(
+			http.MethodPost,
+			"http://api.example.com/mypath",
+			strings.NewReader(`{}`),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		for name, values := range header {
+			request.Header[name] = values
+		}
+		response, err := client.Do(request)
+		Expect(err).ToNot(HaveOccurred())
+		defer response.Body.Close()
+		code = response.StatusCode
+		return
+	}
+
+	It("Retries 500 for POST with idempotency key", func() {
+		code, keys := Post(http.Header{
+			headers.IdempotencyKeyHeader: []string{"my-key"},
+		})
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(keys).To(Equal([]string{"my-key", "my-key"}))
+	})
+
+	It("Doesn't retry 500 for POST without idempotency key", func() {
+		code, keys := Post(nil)
+		Expect(code).To(Equal(http.StatusInternalServerError))
+		Expect(keys).To(HaveLen(1))
+	})
+})
+
 // Listen creates an HTTP/2 listener.
 func Listen() (listener net.Listener, address string) {
 	// Create a TLS listener that will be used to process incoming requests
@@ -877,3 +1158,610 @@ func Serve(conn net.Conn, handler http.HandlerFunc) {
 		Handler: handler,
 	})
 }
+
+var _ = Describe("Policy", func() {
+	var clock *FakeClock
+	var start time.Time
+
+	BeforeEach(func() {
+		start = time.Now()
+		clock = NewFakeClock(start)
+	})
+
+	// Get sends a GET request with the given context using a transport that returns the given
+	// responses and a wrapper configured with two retries and an interval of one second. It
+	// returns the response status code and the number of attempts.
+	var Get = func(ctx context.Context, responses ...http.RoundTripper) (code, attempts int) {
+		// Create a transport that counts the attempts:
+		combined := CombineTransports(responses...)
+		transport := TransportFunc(func(request *http.Request) (*http.Response, error) {
+			attempts++
+			return combined.RoundTrip(request)
+		})
+
+		// Wrap the transport:
+		wrapper, err := NewTransportWrapper().
+			Logger(logger).
+			Clock(clock).
+			Limit(2).
+			Interval(1 * time.Second).
+			Jitter(0).
+			Build(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err = wrapper.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+
+		// Send the request:
+		client := &http.Client{
+			Transport: wrapper.Wrap(transport),
+		}
+		request, err := http.NewRequestWithContext(
+			ctx,
+			http.MethodGet,
+			"http://api.example.com/mypath",
+			nil,
+		)
+		Expect(err).ToNot(HaveOccurred())
+		response, err := client.Do(request)
+		Expect(err).ToNot(HaveOccurred())
+		code = response.StatusCode
+		return
+	}
+
+	It("Uses the configuration of the wrapper if there is no policy", func() {
+		code, attempts := Get(
+			context.Background(),
+			TextTransport(http.StatusServiceUnavailable, `ko`),
+			TextTransport(http.StatusServiceUnavailable, `ko`),
+			JSONTransport(http.StatusOK, `{ "ok": true }`),
+		)
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(attempts).To(Equal(3))
+		Expect(clock.Since(start)).To(Equal(3 * time.Second))
+	})
+
+	It("Disables retries with the zero policy", func() {
+		ctx := WithPolicy(context.Background(), Policy{})
+		code, attempts := Get(
+			ctx,
+			TextTransport(http.StatusServiceUnavailable, `ko`),
+			JSONTransport(http.StatusOK, `{ "ok": true }`),
+		)
+		Expect(code).To(Equal(http.StatusServiceUnavailable))
+		Expect(attempts).To(Equal(1))
+	})
+
+	It("Overrides the limit", func() {
+		ctx := WithPolicy(context.Background(), Policy{
+			Limit: 3,
+		})
+		code, attempts := Get(
+			ctx,
+			TextTransport(http.StatusServiceUnavailable, `ko`),
+			TextTransport(http.StatusServiceUnavailable, `ko`),
+			TextTransport(http.StatusServiceUnavailable, `ko`),
+			JSONTransport(http.StatusOK, `{ "ok": true }`),
+		)
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(attempts).To(Equal(4))
+	})
+
+	It("Overrides the interval", func() {
+		ctx := WithPolicy(context.Background(), Policy{
+			Limit:    2,
+			Interval: 10 * time.Second,
+		})
+		code, _ := Get(
+			ctx,
+			TextTransport(http.StatusServiceUnavailable, `ko`),
+			TextTransport(http.StatusServiceUnavailable, `ko`),
+			JSONTransport(http.StatusOK, `{ "ok": true }`),
+		)
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(clock.Since(start)).To(Equal(30 * time.Second))
+	})
+
+	It("Uses the interval of the wrapper if the policy doesn't have one", func() {
+		ctx := WithPolicy(context.Background(), Policy{
+			Limit: 1,
+		})
+		code, _ := Get(
+			ctx,
+			TextTransport(http.StatusServiceUnavailable, `ko`),
+			JSONTransport(http.StatusOK, `{ "ok": true }`),
+		)
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(clock.Since(start)).To(Equal(1 * time.Second))
+	})
+
+	It("Doesn't retry statuses that aren't in the policy", func() {
+		ctx := WithPolicy(context.Background(), Policy{
+			Limit:    2,
+			Statuses: []int{http.StatusTooManyRequests},
+		})
+		code, attempts := Get(
+			ctx,
+			TextTransport(http.StatusServiceUnavailable, `ko`),
+			JSONTransport(http.StatusOK, `{ "ok": true }`),
+		)
+		Expect(code).To(Equal(http.StatusServiceUnavailable))
+		Expect(attempts).To(Equal(1))
+	})
+
+	It("Retries statuses that are in the policy", func() {
+		ctx := WithPolicy(context.Background(), Policy{
+			Limit:    2,
+			Statuses: []int{http.StatusBadGateway},
+		})
+		code, attempts := Get(
+			ctx,
+			TextTransport(http.StatusBadGateway, `ko`),
+			JSONTransport(http.StatusOK, `{ "ok": true }`),
+		)
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(attempts).To(Equal(2))
+	})
+
+	It("Returns false if there is no policy in the context", func() {
+		_, ok := PolicyFromContext(context.Background())
+		Expect(ok).To(BeFalse())
+	})
+})
+
+var _ = Describe("Get body", func() {
+	It("Uses the get body function to rewind the body", func() {
+		// Create a transport that fails once and then succeeds, remembering the bodies that
+		// it receives:
+		var bodies []string
+		responses := CombineTransports(
+			TextTransport(http.StatusServiceUnavailable, `ko`),
+			JSONTransport(http.StatusOK, `{ "ok": true }`),
+		)
+		transport := TransportFunc(func(request *http.Request) (*http.Response, error) {
+			body, err := io.ReadAll(request.Body)
+			Expect(err).ToNot(HaveOccurred())
+			bodies = append(bodies, string(body))
+			return responses.RoundTrip(request)
+		})
+
+		// Wrap the transport:
+		wrapper, err := NewTransportWrapper().
+			Logger(logger).
+			Clock(NewFakeClock(time.Now())).
+			Build(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err = wrapper.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+
+		// Send the request with a body that can be read only once, and a function to get
+		// new copies:
+		calls := 0
+		request, err := http.NewRequest(
+			http.MethodPost,
+			"http://api.example.com/mypath",
+			io.LimitReader(strings.NewReader(`{}`), 2),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		request.GetBody = func() (io.ReadCloser, error) {
+			calls++
+			return io.NopCloser(strings.NewReader(`{}`)), nil
+		}
+		client := &http.Client{
+			Transport: wrapper.Wrap(transport),
+		}
+		response, err := client.Do(request)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+		// Verify that the function was called for the retry:
+		Expect(calls).To(Equal(1))
+		Expect(bodies).To(Equal([]string{`{}`, `{}`}))
+	})
+
+	It("Copies the body if there is no get body function", func() {
+		// Create a transport that fails once and then succeeds, remembering the bodies that
+		// it receives:
+		var bodies []string
+		responses := CombineTransports(
+			TextTransport(http.StatusServiceUnavailable, `ko`),
+			JSONTransport(http.StatusOK, `{ "ok": true }`),
+		)
+		transport := TransportFunc(func(request *http.Request) (*http.Response, error) {
+			body, err := io.ReadAll(request.Body)
+			Expect(err).ToNot(HaveOccurred())
+			bodies = append(bodies, string(body))
+			return responses.RoundTrip(request)
+		})
+
+		// Wrap the transport:
+		wrapper, err := NewTransportWrapper().
+			Logger(logger).
+			Clock(NewFakeClock(time.Now())).
+			Build(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err = wrapper.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+
+		// Send the request with a body that can be read only once:
+		request, err := http.NewRequest(
+			http.MethodPost,
+			"http://api.example.com/mypath",
+			io.LimitReader(strings.NewReader(`{}`), 2),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(request.GetBody).To(BeNil())
+		client := &http.Client{
+			Transport: wrapper.Wrap(transport),
+		}
+		response, err := client.Do(request)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+		Expect(bodies).To(Equal([]string{`{}`, `{}`}))
+	})
+
+	It("Doesn't read the body if retries are disabled", func() {
+		// Create a transport that checks that it receives the original body:
+		body := io.NopCloser(strings.NewReader(`{}`))
+		transport := TransportFunc(func(request *http.Request) (*http.Response, error) {
+			Expect(request.Body).To(BeIdenticalTo(body))
+			return JSONTransport(http.StatusOK, `{ "ok": true }`).RoundTrip(request)
+		})
+
+		// Wrap the transport:
+		wrapper, err := NewTransportWrapper().
+			Logger(logger).
+			Build(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err = wrapper.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+
+		// Send the request directly to the round tripper, as the HTTP client would replace
+		// the body:
+		ctx := WithPolicy(context.Background(), Policy{})
+		request, err := http.NewRequestWithContext(
+			ctx,
+			http.MethodPost,
+			"http://api.example.com/mypath",
+			nil,
+		)
+		Expect(err).ToNot(HaveOccurred())
+		request.Body = body
+		response, err := wrapper.Wrap(transport).RoundTrip(request)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+	})
+})
+
+var _ = Describe("Maintenance", func() {
+	var (
+		clock *FakeClock
+		start time.Time
+		ctx   context.Context
+		key   string
+	)
+
+	BeforeEach(func() {
+		start = time.Now()
+		clock = NewFakeClock(start)
+		ctx = context.Background()
+		key = ""
+	})
+
+	// Maintenance is the transport that simulates a server in maintenance mode.
+	var Maintenance = func() http.RoundTripper {
+		return JSONTransport(http.StatusServiceUnavailable, `{
+			"kind": "Error",
+			"id": "503",
+			"reason": "The service is under maintenance"
+		}`)
+	}
+
+	// Detect enables the default maintenance matcher.
+	var Detect = func(builder *TransportWrapperBuilder) {
+		builder.MaintenanceMatcher(DefaultMaintenanceMatcher)
+	}
+
+	// Send sends a request with the given method using a transport that returns the given
+	// responses and a wrapper configured with two retries, an interval of one second and the
+	// default maintenance interval. The wrapper builder can be adjusted with the given function.
+	// The request uses the context and idempotency key of the test. It returns the response, the
+	// number of attempts and the error.
+	var Send = func(method string, adjust func(*TransportWrapperBuilder),
+		responses ...http.RoundTripper) (response *http.Response, attempts int, err error) {
+		// Create a transport that counts the attempts:
+		combined := CombineTransports(responses...)
+		transport := TransportFunc(func(request *http.Request) (*http.Response, error) {
+			attempts++
+			return combined.RoundTrip(request)
+		})
+
+		// Wrap the transport:
+		builder := NewTransportWrapper().
+			Logger(logger).
+			Clock(clock).
+			Limit(2).
+			Interval(1 * time.Second).
+			Jitter(0)
+		if adjust != nil {
+			adjust(builder)
+		}
+		wrapper, err := builder.Build(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err := wrapper.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+
+		// Send the request:
+		client := &http.Client{
+			Transport: wrapper.Wrap(transport),
+		}
+		request, err := http.NewRequestWithContext(ctx, method, "http://api.example.com/mypath", nil)
+		Expect(err).ToNot(HaveOccurred())
+		if key != "" {
+			request.Header.Set(headers.IdempotencyKeyHeader, key)
+		}
+		response, err = client.Do(request)
+		return
+	}
+
+	It("Waits the maintenance interval before retrying", func() {
+		response, attempts, err := Send(
+			http.MethodGet, Detect,
+			Maintenance(),
+			JSONTransport(http.StatusOK, `{}`),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer response.Body.Close()
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+		Expect(attempts).To(Equal(2))
+		Expect(clock.Since(start)).To(Equal(DefaultMaintenanceInterval))
+	})
+
+	It("Uses the configured maintenance interval", func() {
+		response, _, err := Send(
+			http.MethodGet,
+			func(builder *TransportWrapperBuilder) {
+				Detect(builder)
+				builder.MaintenanceInterval(5 * time.Minute)
+			},
+			Maintenance(),
+			Maintenance(),
+			JSONTransport(http.StatusOK, `{}`),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer response.Body.Close()
+		Expect(clock.Since(start)).To(Equal(10 * time.Minute))
+	})
+
+	It("Returns maintenance error after the last attempt", func() {
+		response, attempts, err := Send(
+			http.MethodGet, Detect,
+			Maintenance(),
+			Maintenance(),
+			Maintenance(),
+		)
+		Expect(err).To(HaveOccurred())
+		Expect(errors.Is(err, ErrMaintenance)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("503"))
+		Expect(response).To(BeNil())
+		Expect(attempts).To(Equal(3))
+	})
+
+	It("Returns the response when retries are disabled", func() {
+		response, attempts, err := Send(
+			http.MethodGet,
+			func(builder *TransportWrapperBuilder) {
+				Detect(builder)
+				builder.Limit(0)
+			},
+			Maintenance(),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer response.Body.Close()
+		Expect(response.StatusCode).To(Equal(http.StatusServiceUnavailable))
+		body, err := io.ReadAll(response.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(body)).To(ContainSubstring("maintenance"))
+		Expect(attempts).To(Equal(1))
+	})
+
+	It("Retries non idempotent requests like other 503 responses", func() {
+		response, attempts, err := Send(
+			http.MethodPost, Detect,
+			Maintenance(),
+			JSONTransport(http.StatusOK, `{}`),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer response.Body.Close()
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+		Expect(attempts).To(Equal(2))
+		Expect(clock.Since(start)).To(Equal(1 * time.Second))
+	})
+
+	It("Doesn't retry non idempotent requests for other codes", func() {
+		response, attempts, err := Send(
+			http.MethodPost,
+			func(builder *TransportWrapperBuilder) {
+				builder.MaintenanceMatcher(func(response *http.Response) bool {
+					return response.StatusCode == http.StatusBadGateway
+				})
+			},
+			TextTransport(http.StatusBadGateway, `maintenance`),
+			JSONTransport(http.StatusOK, `{}`),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer response.Body.Close()
+		Expect(response.StatusCode).To(Equal(http.StatusBadGateway))
+		Expect(attempts).To(Equal(1))
+	})
+
+	It("Doesn't return non idempotent requests as maintenance errors", func() {
+		response, attempts, err := Send(
+			http.MethodPost, Detect,
+			Maintenance(),
+			Maintenance(),
+			Maintenance(),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer response.Body.Close()
+		Expect(response.StatusCode).To(Equal(http.StatusServiceUnavailable))
+		Expect(attempts).To(Equal(3))
+	})
+
+	It("Retries requests with idempotency key", func() {
+		key = "123"
+		response, attempts, err := Send(
+			http.MethodPost,
+			func(builder *TransportWrapperBuilder) {
+				builder.MaintenanceMatcher(func(response *http.Response) bool {
+					return response.StatusCode == http.StatusBadGateway
+				})
+			},
+			TextTransport(http.StatusBadGateway, `maintenance`),
+			JSONTransport(http.StatusOK, `{}`),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer response.Body.Close()
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+		Expect(attempts).To(Equal(2))
+		Expect(clock.Since(start)).To(Equal(DefaultMaintenanceInterval))
+	})
+
+	It("Stops waiting when the context is cancelled", func() {
+		var cancel func()
+		ctx, cancel = context.WithCancel(ctx)
+		cancel()
+		response, attempts, err := Send(
+			http.MethodGet,
+			func(builder *TransportWrapperBuilder) {
+				Detect(builder)
+				builder.Clock(blockingClock{clock})
+			},
+			Maintenance(),
+			JSONTransport(http.StatusOK, `{}`),
+		)
+		Expect(err).To(HaveOccurred())
+		Expect(errors.Is(err, context.Canceled)).To(BeTrue())
+		Expect(response).To(BeNil())
+		Expect(attempts).To(Equal(1))
+	})
+
+	It("Doesn't return closed response when cancelled during the wait", func() {
+		// Create a wrapper that never finishes waiting:
+		wrapper, err := NewTransportWrapper().
+			Logger(logger).
+			Clock(blockingClock{clock}).
+			Limit(2).
+			Interval(1 * time.Second).
+			MaintenanceMatcher(DefaultMaintenanceMatcher).
+			Build(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err := wrapper.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+
+		// Cancel the context when the first attempt finishes, so that the round tripper is
+		// waiting before the second:
+		var cancel func()
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+		maintenance := Maintenance()
+		transport := TransportFunc(func(request *http.Request) (*http.Response, error) {
+			defer cancel()
+			return maintenance.RoundTrip(request)
+		})
+
+		// Call the round tripper directly, as the HTTP client discards the response when
+		// there is an error:
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet,
+			"http://api.example.com/mypath", nil)
+		Expect(err).ToNot(HaveOccurred())
+		response, err := wrapper.Wrap(transport).RoundTrip(request)
+		Expect(err).To(HaveOccurred())
+		Expect(errors.Is(err, context.Canceled)).To(BeTrue())
+		Expect(response).To(BeNil())
+	})
+
+	It("Uses the custom matcher", func() {
+		response, attempts, err := Send(
+			http.MethodGet,
+			func(builder *TransportWrapperBuilder) {
+				builder.MaintenanceMatcher(func(response *http.Response) bool {
+					return response.StatusCode == http.StatusServiceUnavailable
+				})
+			},
+			TextTransport(http.StatusServiceUnavailable, `ko`),
+			JSONTransport(http.StatusOK, `{}`),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer response.Body.Close()
+		Expect(attempts).To(Equal(2))
+		Expect(clock.Since(start)).To(Equal(DefaultMaintenanceInterval))
+	})
+
+	It("Handles maintenance responses like others by default", func() {
+		response, attempts, err := Send(
+			http.MethodGet, nil,
+			Maintenance(),
+			Maintenance(),
+			Maintenance(),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer response.Body.Close()
+		Expect(response.StatusCode).To(Equal(http.StatusServiceUnavailable))
+		Expect(attempts).To(Equal(3))
+		Expect(clock.Since(start)).To(Equal(3 * time.Second))
+	})
+
+	It("Uses the normal interval for other errors", func() {
+		response, _, err := Send(
+			http.MethodGet, Detect,
+			TextTransport(http.StatusServiceUnavailable, `ko`),
+			JSONTransport(http.StatusOK, `{}`),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer response.Body.Close()
+		Expect(clock.Since(start)).To(Equal(1 * time.Second))
+	})
+
+	It("Preserves the body in the default matcher", func() {
+		response := &http.Response{
+			StatusCode: http.StatusServiceUnavailable,
+			Body:       io.NopCloser(strings.NewReader(`Down for MAINTENANCE`)),
+		}
+		Expect(DefaultMaintenanceMatcher(response)).To(BeTrue())
+		body, err := io.ReadAll(response.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(body)).To(Equal(`Down for MAINTENANCE`))
+	})
+
+	It("Ignores other codes in the default matcher", func() {
+		response := &http.Response{
+			StatusCode: http.StatusInternalServerError,
+			Body:       io.NopCloser(strings.NewReader(`maintenance`)),
+		}
+		Expect(DefaultMaintenanceMatcher(response)).To(BeFalse())
+	})
+})
+
+// blockingClock is a fake clock where waits never finish, used to check that the round tripper
+// stops waiting when the context is cancelled.
+type blockingClock struct {
+	*FakeClock
+}
+
+// After is part of the implementation of the clock interface. It returns a channel that never
+// receives anything.
+func (c blockingClock) After(d time.Duration) <-chan time.Time {
+	return nil
+}
@@ -23,6 +23,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/openshift-online/ocm-sdk-go/headers"
 	"github.com/openshift-online/ocm-sdk-go/logging"
 
 	. "github.com/onsi/ginkgo/v2/dsl/core"             // nolint
@@ -208,6 +209,72 @@ var _ = Describe("Retry", func() {
 		})
 	})
 
+	Describe("Post with idempotency key", func() {
+		It("Retries for 500 with the same key", func() {
+			// Create a connection with a transport wrapper that returns 500 for the
+			// first request and 200 for the second, remembering the keys.
+			var keys []string
+			responses := CombineTransports(
+				JSONTransport(http.StatusInternalServerError, "{}"),
+				JSONTransport(http.StatusOK, "{}"),
+			)
+			connection, err := NewConnectionBuilder().
+				Logger(logger).
+				Tokens(token).
+				IdempotencyKeys(true).
+				TransportWrapper(func(_ http.RoundTripper) http.RoundTripper {
+					return TransportFunc(func(request *http.Request) (*http.Response, error) {
+						key := request.Header.Get(headers.IdempotencyKeyHeader)
+						keys = append(keys, key)
+						return responses.RoundTrip(request)
+					})
+				}).
+				RetryInterval(10 * time.Millisecond).
+				BuildContext(ctx)
+			Expect(err).ToNot(HaveOccurred())
+
+			// Send the request:
+			response, err := connection.Post().
+				Path("/mypath").
+				String(`{}`).
+				Send()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response).ToNot(BeNil())
+			Expect(keys).To(HaveLen(2))
+			Expect(keys[0]).ToNot(BeEmpty())
+			Expect(keys[1]).To(Equal(keys[0]))
+		})
+
+		It("Doesn't add key when disabled", func() {
+			// Create a connection with a transport wrapper that returns 500,
+			// remembering the keys.
+			var keys []string
+			connection, err := NewConnectionBuilder().
+				Logger(logger).
+				Tokens(token).
+				TransportWrapper(func(_ http.RoundTripper) http.RoundTripper {
+					return TransportFunc(func(request *http.Request) (*http.Response, error) {
+						key := request.Header.Get(headers.IdempotencyKeyHeader)
+						keys = append(keys, key)
+						return JSONTransport(http.StatusInternalServerError, "{}").
+							RoundTrip(request)
+					})
+				}).
+				RetryInterval(10 * time.Millisecond).
+				BuildContext(ctx)
+			Expect(err).ToNot(HaveOccurred())
+
+			// Send the request:
+			response, err := connection.Post().
+				Path("/mypath").
+				String(`{}`).
+				Send()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.Status()).To(Equal(http.StatusInternalServerError))
+			Expect(keys).To(Equal([]string{""}))
+		})
+	})
+
 	It("Writes error to the debug log", func() {
 		// Create a logger that allows us to inspect the messages written to the log:
 		var buffer bytes.Buffer
@@ -0,0 +1,209 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the implementation of a transport wrapper that rewrites the paths of
+// requests, intended to keep working old code that uses deprecated paths.
+
+package rewrite
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/openshift-online/ocm-sdk-go/logging"
+)
+
+// TransportWrapperBuilder contains the data and logic needed to create a new transport wrapper
+// that rewrites the paths of requests. Each rule replaces a path prefix with another one. A prefix
+// matches a path when it is equal to the path or when it is followed by a slash, so the prefix
+// `/api/foo` matches `/api/foo/bar` but not `/api/foobar`. When several prefixes match a path the
+// longest one is used. The method, the query and the rest of the request aren't changed, and when
+// no rule matches the request is sent unchanged. For example, to send the requests that use an
+// old path to the new one:
+//
+//	wrapper, err := rewrite.NewTransportWrapper().
+//		Logger(logger).
+//		Rule("/api/clusters_mgmt/v1/old_things", "/api/clusters_mgmt/v1/things").
+//		Build(ctx)
+//	if err != nil {
+//		...
+//	}
+//	connection, err := sdk.NewConnectionBuilder().
+//		...
+//		TransportWrapper(wrapper.Wrap).
+//		Build()
+//
+// Each rewrite is written to the log with the info level, so that the code that still uses the
+// old paths can be found and updated.
+//
+// Don't create objects of this type directly; use the NewTransportWrapper function instead.
+type TransportWrapperBuilder struct {
+	logger logging.Logger
+	rules  [][2]string
+}
+
+// TransportWrapper contains the data and logic needed to wrap an HTTP round tripper with another
+// one that rewrites the paths of requests. Don't create objects of this type directly; use the
+// NewTransportWrapper function instead.
+type TransportWrapper struct {
+	logger logging.Logger
+	rules  []rule
+}
+
+// rule contains an old path prefix and the new prefix that replaces it.
+type rule struct {
+	from string
+	to   string
+}
+
+// roundTripper is a round tripper that rewrites the paths of requests.
+type roundTripper struct {
+	owner     *TransportWrapper
+	transport http.RoundTripper
+}
+
+// Make sure that we implement the interface:
+var _ http.RoundTripper = (*roundTripper)(nil)
+
+// NewTransportWrapper creates a new builder that can then be used to configure and create a new
+// path rewriting round tripper.
+func NewTransportWrapper() *TransportWrapperBuilder {
+	return &TransportWrapperBuilder{}
+}
+
+// Logger sets the logger that will be used by the wrapper and by the round trippers that it
+// creates.
+func (b *TransportWrapperBuilder) Logger(value logging.Logger) *TransportWrapperBuilder {
+	b.logger = value
+	return b
+}
+
+// Rule adds a rule that replaces the given old path prefix with the given new one.
+func (b *TransportWrapperBuilder) Rule(from, to string) *TransportWrapperBuilder {
+	b.rules = append(b.rules, [2]string{from, to})
+	return b
+}
+
+// Build uses the information stored in the builder to create a new transport wrapper.
+func (b *TransportWrapperBuilder) Build(ctx context.Context) (result *TransportWrapper, err error) {
+	// Check parameters:
+	if b.logger == nil {
+		err = fmt.Errorf("logger is mandatory")
+		return
+	}
+	froms := map[string]bool{}
+	rules := make([]rule, len(b.rules))
+	for i, item := range b.rules {
+		from := strings.TrimSuffix(item[0], "/")
+		to := strings.TrimSuffix(item[1], "/")
+		if !strings.HasPrefix(item[0], "/") || from == "" {
+			err = fmt.Errorf(
+				"old prefix '%s' isn't valid, it should start with a slash and "+
+					"contain at least one segment",
+				item[0],
+			)
+			return
+		}
+		if !strings.HasPrefix(item[1], "/") {
+			err = fmt.Errorf(
+				"new prefix '%s' for old prefix '%s' isn't valid, it should start "+
+					"with a slash",
+				item[1], item[0],
+			)
+			return
+		}
+		if froms[from] {
+			err = fmt.Errorf("old prefix '%s' is duplicated", item[0])
+			return
+		}
+		froms[from] = true
+		rules[i] = rule{
+			from: from,
+			to:   to,
+		}
+	}
+
+	// Sort the rules in descending order of the length of the old prefix, so that the longest
+	// prefix that matches is found first:
+	sort.SliceStable(rules, func(i, j int) bool {
+		return len(rules[i].from) > len(rules[j].from)
+	})
+
+	// Create and populate the object:
+	result = &TransportWrapper{
+		logger: b.logger,
+		rules:  rules,
+	}
+
+	return
+}
+
+// Wrap creates a new round tripper that wraps the given one and rewrites the paths of requests.
+func (w *TransportWrapper) Wrap(transport http.RoundTripper) http.RoundTripper {
+	return &roundTripper{
+		owner:     w,
+		transport: transport,
+	}
+}
+
+// Rewrite returns the path that results from applying the rules to the given one, and a flag
+// indicating if any rule matched.
+func (w *TransportWrapper) Rewrite(path string) (result string, ok bool) {
+	for _, rule := range w.rules {
+		if path == rule.from {
+			result, ok = rule.to, true
+			if result == "" {
+				result = "/"
+			}
+			return
+		}
+		if strings.HasPrefix(path, rule.from+"/") {
+			result, ok = rule.to+path[len(rule.from):], true
+			return
+		}
+	}
+	result = path
+	return
+}
+
+// RoundTrip is the implementation of the round tripper interface.
+func (t *roundTripper) RoundTrip(request *http.Request) (response *http.Response, err error) {
+	// Do nothing if no rule matches:
+	path, ok := t.owner.Rewrite(request.URL.Path)
+	if !ok {
+		response, err = t.transport.RoundTrip(request)
+		return
+	}
+
+	// Round trippers shouldn't modify the request, so we need to work with a copy. Note that
+	// the raw path is cleared so that it is recalculated from the new path.
+	ctx := request.Context()
+	t.owner.logger.Info(
+		ctx,
+		"Rewrote path of %s request from '%s' to '%s'",
+		request.Method, request.URL.Path, path,
+	)
+	request = request.Clone(ctx)
+	request.URL.Path = path
+	request.URL.RawPath = ""
+
+	// Send the modified request:
+	response, err = t.transport.RoundTrip(request)
+	return
+}
@@ -0,0 +1,178 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains tests for the path rewriting transport wrapper.
+
+package rewrite
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+
+	"github.com/openshift-online/ocm-sdk-go/logging"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"             // nolint
+	. "github.com/onsi/ginkgo/v2/dsl/table"            // nolint
+	. "github.com/onsi/gomega"                         // nolint
+	. "github.com/openshift-online/ocm-sdk-go/testing" // nolint
+)
+
+var _ = Describe("Rewrite transport wrapper", func() {
+	var ctx context.Context
+	var received *http.Request
+	var backend http.RoundTripper
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		received = nil
+		backend = TransportFunc(func(request *http.Request) (*http.Response, error) {
+			received = request
+			return JSONTransport(http.StatusOK, `{}`).RoundTrip(request)
+		})
+	})
+
+	Describe("Build", func() {
+		It("Can't be created without a logger", func() {
+			_, err := NewTransportWrapper().Build(ctx)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("logger"))
+		})
+
+		It("Can't be created with an old prefix that doesn't start with slash", func() {
+			_, err := NewTransportWrapper().
+				Logger(logger).
+				Rule("api/old", "/api/new").
+				Build(ctx)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("api/old"))
+		})
+
+		It("Can't be created with the root as old prefix", func() {
+			_, err := NewTransportWrapper().
+				Logger(logger).
+				Rule("/", "/api/new").
+				Build(ctx)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("segment"))
+		})
+
+		It("Can't be created with a new prefix that doesn't start with slash", func() {
+			_, err := NewTransportWrapper().
+				Logger(logger).
+				Rule("/api/old", "api/new").
+				Build(ctx)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("api/new"))
+		})
+
+		It("Can't be created with duplicated old prefixes", func() {
+			_, err := NewTransportWrapper().
+				Logger(logger).
+				Rule("/api/old", "/api/new").
+				Rule("/api/old/", "/api/other").
+				Build(ctx)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("duplicated"))
+		})
+	})
+
+	DescribeTable(
+		"Rewrite",
+		func(path, expected string, matched bool) {
+			wrapper, err := NewTransportWrapper().
+				Logger(logger).
+				Rule("/api/old", "/api/new").
+				Rule("/api/old/special", "/api/special").
+				Rule("/api/flat/", "/api/nested/v1/").
+				Build(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			actual, ok := wrapper.Rewrite(path)
+			Expect(ok).To(Equal(matched))
+			Expect(actual).To(Equal(expected))
+		},
+		Entry("Exact prefix", "/api/old", "/api/new", true),
+		Entry("Longer path", "/api/old/v1/things", "/api/new/v1/things", true),
+		Entry("Trailing slash", "/api/old/", "/api/new/", true),
+		Entry("Longest prefix wins", "/api/old/special/123", "/api/special/123", true),
+		Entry("Prefix with trailing slash", "/api/flat/x", "/api/nested/v1/x", true),
+		Entry("Partial segment doesn't match", "/api/older", "/api/older", false),
+		Entry("Unrelated path", "/api/other", "/api/other", false),
+	)
+
+	It("Preserves method, query and body", func() {
+		wrapper, err := NewTransportWrapper().
+			Logger(logger).
+			Rule("/api/old", "/api/new").
+			Build(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		request, err := http.NewRequestWithContext(
+			ctx, http.MethodPost, "http://api.example.com/api/old/things?page=2&size=10",
+			bytes.NewBufferString(`{}`),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		response, err := wrapper.Wrap(backend).RoundTrip(request)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+		Expect(received).ToNot(BeNil())
+		Expect(received.Method).To(Equal(http.MethodPost))
+		Expect(received.URL.Path).To(Equal("/api/new/things"))
+		Expect(received.URL.RawQuery).To(Equal("page=2&size=10"))
+		Expect(received.URL.Host).To(Equal("api.example.com"))
+		Expect(received.Body).ToNot(BeNil())
+
+		// The original request should not be modified:
+		Expect(request.URL.Path).To(Equal("/api/old/things"))
+	})
+
+	It("Sends the original request when no rule matches", func() {
+		wrapper, err := NewTransportWrapper().
+			Logger(logger).
+			Rule("/api/old", "/api/new").
+			Build(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		request, err := http.NewRequestWithContext(
+			ctx, http.MethodGet, "http://api.example.com/api/current?x=y", nil,
+		)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = wrapper.Wrap(backend).RoundTrip(request)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(received).To(BeIdenticalTo(request))
+	})
+
+	It("Writes rewrites to the log", func() {
+		buffer := &bytes.Buffer{}
+		bufferLogger, err := logging.NewStdLoggerBuilder().
+			Streams(buffer, buffer).
+			Info(true).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		wrapper, err := NewTransportWrapper().
+			Logger(bufferLogger).
+			Rule("/api/old", "/api/new").
+			Build(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		request, err := http.NewRequestWithContext(
+			ctx, http.MethodDelete, "http://api.example.com/api/old/123", nil,
+		)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = wrapper.Wrap(backend).RoundTrip(request)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(buffer.String()).To(ContainSubstring(
+			"Rewrote path of DELETE request from '/api/old/123' to '/api/new/123'",
+		))
+	})
+})
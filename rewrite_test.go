@@ -0,0 +1,100 @@
+/*
+Copyright (c) 2018 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains tests for the rewriting of legacy paths.
+
+package sdk
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/onsi/gomega/ghttp"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"             // nolint
+	. "github.com/onsi/gomega"                         // nolint
+	. "github.com/openshift-online/ocm-sdk-go/testing" // nolint
+)
+
+var _ = Describe("Path rewrite", func() {
+	var (
+		accessToken string
+		apiServer   *ghttp.Server
+	)
+
+	BeforeEach(func() {
+		// Create the token:
+		accessToken = MakeTokenString("Bearer", 5*time.Minute)
+
+		// Create the API server:
+		apiServer = MakeTCPServer()
+	})
+
+	AfterEach(func() {
+		// Stop the server:
+		apiServer.Close()
+	})
+
+	It("Sends requests for legacy paths to the new paths", func() {
+		// Configure the server:
+		apiServer.AppendHandlers(
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest(
+					http.MethodGet,
+					"/api/clusters_mgmt/v1/clusters",
+					"search=name%3D'my'",
+				),
+				RespondWithJSON(http.StatusOK, "{}"),
+			),
+		)
+
+		// Create the connection:
+		connection, err := NewConnectionBuilder().
+			Logger(logger).
+			Tokens(accessToken).
+			URL(apiServer.URL()).
+			RewritePath("/api/clusters_mgmt/v0", "/api/clusters_mgmt/v1").
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err = connection.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+		Expect(connection.ConfigSummary().PathRewrites).To(ConsistOf(
+			"/api/clusters_mgmt/v0 -> /api/clusters_mgmt/v1",
+		))
+
+		// Send the request:
+		response, err := connection.Get().
+			Path("/api/clusters_mgmt/v0/clusters").
+			Parameter("search", "name='my'").
+			Send()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.Status()).To(Equal(http.StatusOK))
+	})
+
+	It("Can't be created with an invalid rule", func() {
+		connection, err := NewConnectionBuilder().
+			Logger(logger).
+			Tokens(accessToken).
+			URL(apiServer.URL()).
+			RewritePath("junk", "/api/clusters_mgmt/v1").
+			Build()
+		Expect(err).To(HaveOccurred())
+		Expect(connection).To(BeNil())
+		Expect(err.Error()).To(ContainSubstring("junk"))
+	})
+})
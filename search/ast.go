@@ -0,0 +1,149 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the types used to represent the syntax tree of search expressions.
+
+package search
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Node is the interface implemented by all the nodes of the syntax tree of a search expression.
+// The concrete types are *Condition, *Logical and *Negation, handlers are expected to use a type switch
+// to walk the tree.
+type Node interface {
+	// String generates the text of the search expression corresponding to this node.
+	String() string
+}
+
+// Expr is a complete search expression, represented by the root node of its syntax tree. It is an
+// alias of Node, so the results of Parse and of the builder functions like AllEqual can be used
+// interchangeably.
+type Expr = Node
+
+// Operator is the operator used in a condition.
+type Operator string
+
+// Supported operators:
+const (
+	OperatorEqual          Operator = "="
+	OperatorNotEqual       Operator = "<>"
+	OperatorLessThan       Operator = "<"
+	OperatorLessOrEqual    Operator = "<="
+	OperatorGreaterThan    Operator = ">"
+	OperatorGreaterOrEqual Operator = ">="
+	OperatorLike           Operator = "like"
+	OperatorNotLike        Operator = "not like"
+	OperatorILike          Operator = "ilike"
+	OperatorNotILike       Operator = "not ilike"
+	OperatorIn             Operator = "in"
+	OperatorNotIn          Operator = "not in"
+	OperatorIsNull         Operator = "is null"
+	OperatorIsNotNull      Operator = "is not null"
+)
+
+// Connector is the logical connector used to join two expressions.
+type Connector string
+
+// Supported connectors:
+const (
+	ConnectorAnd Connector = "and"
+	ConnectorOr  Connector = "or"
+)
+
+// Condition is a node of the syntax tree that compares a field with a set of values. The number of
+// values depends on the operator: the `in` and `not in` operators have one or more, the `is null`
+// and `is not null` operators have none, and the rest have exactly one. Values are of type string,
+// int64, float64 or bool.
+type Condition struct {
+	Field    string
+	Operator Operator
+	Values   []interface{}
+}
+
+// Logical is a node of the syntax tree that joins two expressions with a logical connector.
+type Logical struct {
+	Connector Connector
+	Left      Node
+	Right     Node
+}
+
+// Negation is a node of the syntax tree that negates an expression.
+type Negation struct {
+	Operand Node
+}
+
+// Make sure that we implement the interface:
+var (
+	_ Node = (*Condition)(nil)
+	_ Node = (*Logical)(nil)
+	_ Node = (*Negation)(nil)
+)
+
+// String is the implementation of the fmt.Stringer interface.
+func (c *Condition) String() string {
+	buffer := &strings.Builder{}
+	buffer.WriteString(c.Field)
+	buffer.WriteString(" ")
+	buffer.WriteString(string(c.Operator))
+	switch c.Operator {
+	case OperatorIsNull, OperatorIsNotNull:
+	case OperatorIn, OperatorNotIn:
+		buffer.WriteString(" (")
+		for i, value := range c.Values {
+			if i > 0 {
+				buffer.WriteString(", ")
+			}
+			buffer.WriteString(valueText(value))
+		}
+		buffer.WriteString(")")
+	default:
+		for _, value := range c.Values {
+			buffer.WriteString(" ")
+			buffer.WriteString(valueText(value))
+		}
+	}
+	return buffer.String()
+}
+
+// String is the implementation of the fmt.Stringer interface.
+func (l *Logical) String() string {
+	return fmt.Sprintf("(%s %s %s)", l.Left, l.Connector, l.Right)
+}
+
+// String is the implementation of the fmt.Stringer interface.
+func (n *Negation) String() string {
+	return fmt.Sprintf("not (%s)", n.Operand)
+}
+
+// valueText generates the text that represents the given value inside a search expression.
+func valueText(value interface{}) string {
+	switch typed := value.(type) {
+	case string:
+		return "'" + strings.ReplaceAll(typed, "'", "''") + "'"
+	case int64:
+		return strconv.FormatInt(typed, 10)
+	case float64:
+		return strconv.FormatFloat(typed, 'g', -1, 64)
+	case bool:
+		return strconv.FormatBool(typed)
+	default:
+		return fmt.Sprintf("%v", value)
+	}
+}
@@ -0,0 +1,119 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains functions that build search expressions for common cases.
+
+package search
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// AllEqual builds a search expression that checks that each of the given fields is equal to the
+// corresponding value, joining the conditions with the `and` connector. For example:
+//
+//	expr := search.AllEqual(map[string]interface{}{
+//		"name":     "my",
+//		"multi_az": true,
+//	})
+//	response, err := connection.Get().
+//		Path("/api/clusters_mgmt/v1/clusters").
+//		SearchExpr(expr).
+//		Send()
+//
+// Will send the `(multi_az = true and name = 'my')` search expression. The generated list requests
+// accept the text of the expression instead, so use `Search(expr.String())` with them, checking
+// first that the expression isn't nil. The conditions are sorted by field name, so the result is
+// always the same for the same map. Strings are quoted, numbers and booleans aren't, and nil
+// values generate an `is null` condition. Values of other types, and unsigned integers that don't
+// fit in an int64, are converted to strings. The result will be nil if the map is empty.
+func AllEqual(values map[string]interface{}) Expr {
+	fields := make([]string, 0, len(values))
+	for field := range values {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	var result Expr
+	for _, field := range fields {
+		condition := equalCondition(field, values[field])
+		if result == nil {
+			result = condition
+			continue
+		}
+		result = &Logical{
+			Connector: ConnectorAnd,
+			Left:      result,
+			Right:     condition,
+		}
+	}
+	return result
+}
+
+// equalCondition creates the condition that checks that the given field is equal to the given
+// value, converting the value to one of the types supported in conditions.
+func equalCondition(field string, value interface{}) *Condition {
+	if value == nil {
+		return &Condition{
+			Field:    field,
+			Operator: OperatorIsNull,
+		}
+	}
+	return &Condition{
+		Field:    field,
+		Operator: OperatorEqual,
+		Values:   []interface{}{conditionValue(value)},
+	}
+}
+
+// conditionValue converts the given value to one of the types supported in conditions: string,
+// int64, float64 or bool.
+func conditionValue(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case string, int64, float64, bool:
+		return typed
+	case int:
+		return int64(typed)
+	case int8:
+		return int64(typed)
+	case int16:
+		return int64(typed)
+	case int32:
+		return int64(typed)
+	case uint8:
+		return int64(typed)
+	case uint16:
+		return int64(typed)
+	case uint32:
+		return int64(typed)
+	case uint:
+		if uint64(typed) <= math.MaxInt64 {
+			return int64(typed)
+		}
+		return strconv.FormatUint(uint64(typed), 10)
+	case uint64:
+		if typed <= math.MaxInt64 {
+			return int64(typed)
+		}
+		return strconv.FormatUint(typed, 10)
+	case float32:
+		return float64(typed)
+	default:
+		return fmt.Sprintf("%v", value)
+	}
+}
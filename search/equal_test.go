@@ -0,0 +1,88 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains tests for the functions that build search expressions.
+
+package search
+
+import (
+	. "github.com/onsi/ginkgo/v2/dsl/core"  // nolint
+	. "github.com/onsi/ginkgo/v2/dsl/table" // nolint
+	. "github.com/onsi/gomega"              // nolint
+)
+
+var _ = Describe("All equal", func() {
+	It("Returns nil for empty map", func() {
+		Expect(AllEqual(nil)).To(BeNil())
+		Expect(AllEqual(map[string]interface{}{})).To(BeNil())
+	})
+
+	It("Returns condition for one field", func() {
+		result := AllEqual(map[string]interface{}{
+			"name": "my",
+		})
+		Expect(result).To(Equal(&Condition{
+			Field:    "name",
+			Operator: OperatorEqual,
+			Values:   []interface{}{"my"},
+		}))
+	})
+
+	It("Sorts conditions by field name", func() {
+		result := AllEqual(map[string]interface{}{
+			"state":    "ready",
+			"name":     "my",
+			"multi_az": true,
+		})
+		Expect(result.String()).To(Equal(
+			"((multi_az = true and name = 'my') and state = 'ready')",
+		))
+	})
+
+	It("Generates expression that can be parsed", func() {
+		original := AllEqual(map[string]interface{}{
+			"name":  "it's mine",
+			"nodes": 3,
+			"ratio": 0.5,
+			"owner": nil,
+		})
+		parsed, err := Parse(original.String())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(parsed).To(Equal(original))
+	})
+
+	DescribeTable(
+		"Value",
+		func(value interface{}, expected string) {
+			result := AllEqual(map[string]interface{}{
+				"field": value,
+			})
+			Expect(result.String()).To(Equal(expected))
+		},
+		Entry("String", "my", "field = 'my'"),
+		Entry("String with quote", "it's", "field = 'it''s'"),
+		Entry("Int", 42, "field = 42"),
+		Entry("Int32", int32(-42), "field = -42"),
+		Entry("Int64", int64(42), "field = 42"),
+		Entry("Uint64", uint64(42), "field = 42"),
+		Entry("Large uint64", uint64(1<<63), "field = '9223372036854775808'"),
+		Entry("Float32", float32(0.5), "field = 0.5"),
+		Entry("Float64", 1.5, "field = 1.5"),
+		Entry("True", true, "field = true"),
+		Entry("False", false, "field = false"),
+		Entry("Nil", nil, "field is null"),
+	)
+})
@@ -0,0 +1,158 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the lexical scanner used to split search expressions into tokens.
+
+package search
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// tokenKind is the type of a token.
+type tokenKind int
+
+// Kinds of tokens:
+const (
+	tokenEOF tokenKind = iota
+	tokenIdentifier
+	tokenString
+	tokenNumber
+	tokenSymbol
+	tokenLeftParen
+	tokenRightParen
+	tokenComma
+)
+
+// token is one of the lexical units of a search expression.
+type token struct {
+	kind     tokenKind
+	text     string
+	position int
+}
+
+// keyword checks if the token is an identifier that matches the given keyword, ignoring case.
+func (t token) keyword(value string) bool {
+	return t.kind == tokenIdentifier && strings.EqualFold(t.text, value)
+}
+
+// describe returns a description of the token suitable for error messages.
+func (t token) describe() string {
+	if t.kind == tokenEOF {
+		return "end of expression"
+	}
+	return fmt.Sprintf("'%s' at position %d", t.text, t.position)
+}
+
+// scan splits the given text into tokens. The last token returned is always of kind tokenEOF.
+func scan(text string) (tokens []token, err error) {
+	runes := []rune(text)
+	i := 0
+	for i < len(runes) {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenLeftParen, text: "(", position: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenRightParen, text: ")", position: i})
+			i++
+		case r == ',':
+			tokens = append(tokens, token{kind: tokenComma, text: ",", position: i})
+			i++
+		case r == '\'':
+			start := i
+			buffer := &strings.Builder{}
+			i++
+			closed := false
+			for i < len(runes) {
+				if runes[i] == '\'' {
+					if i+1 < len(runes) && runes[i+1] == '\'' {
+						buffer.WriteRune('\'')
+						i += 2
+						continue
+					}
+					i++
+					closed = true
+					break
+				}
+				buffer.WriteRune(runes[i])
+				i++
+			}
+			if !closed {
+				err = fmt.Errorf("string starting at position %d isn't terminated", start)
+				return
+			}
+			tokens = append(tokens, token{kind: tokenString, text: buffer.String(), position: start})
+		case r == '-' || unicode.IsDigit(r):
+			start := i
+			i++
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{
+				kind:     tokenNumber,
+				text:     string(runes[start:i]),
+				position: start,
+			})
+		case isIdentifierStart(r):
+			start := i
+			i++
+			for i < len(runes) && isIdentifierPart(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, token{
+				kind:     tokenIdentifier,
+				text:     string(runes[start:i]),
+				position: start,
+			})
+		case strings.ContainsRune(symbolChars, r):
+			start := i
+			i++
+			for i < len(runes) && strings.ContainsRune(symbolChars, runes[i]) {
+				i++
+			}
+			tokens = append(tokens, token{
+				kind:     tokenSymbol,
+				text:     string(runes[start:i]),
+				position: start,
+			})
+		default:
+			err = fmt.Errorf("unexpected character '%c' at position %d", r, i)
+			return
+		}
+	}
+	tokens = append(tokens, token{kind: tokenEOF, position: len(runes)})
+	return
+}
+
+// isIdentifierStart checks if the given character can be the first character of an identifier.
+func isIdentifierStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+// isIdentifierPart checks if the given character can be part of an identifier. Dots are accepted
+// so that nested fields like `aws.region` are scanned as a single identifier.
+func isIdentifierPart(r rune) bool {
+	return r == '_' || r == '.' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// symbolChars contains the characters that are used to build comparison operators.
+const symbolChars = "=<>!~"
@@ -0,0 +1,138 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the parser for order criteria.
+
+package search
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Direction is the direction used to sort the results by a field.
+type Direction string
+
+// Supported directions:
+const (
+	DirectionAscending  Direction = "asc"
+	DirectionDescending Direction = "desc"
+)
+
+// OrderTerm is one of the terms of an order criteria.
+type OrderTerm struct {
+	Field     string
+	Direction Direction
+}
+
+// String is the implementation of the fmt.Stringer interface.
+func (t OrderTerm) String() string {
+	return t.Field + " " + string(t.Direction)
+}
+
+// ParseOrder parses the given order criteria, for example `name desc, created_at asc`, and returns
+// the corresponding list of terms. Terms that don't explicitly specify a direction will be sorted in
+// ascending order. If the text is empty, or contains only white space, the result will be empty and
+// no error will be returned.
+//
+// If a list of allowed fields is given then fields that aren't in that list will be rejected. This is
+// intended to make it safe to use the field names when building database queries.
+//
+// The error messages returned describe the problem found in terms of the text of the criteria, so
+// they are suitable to be returned to the client in a bad request response.
+func ParseOrder(text string, allowed ...string) (result []OrderTerm, err error) {
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+	for i, chunk := range strings.Split(text, ",") {
+		var term OrderTerm
+		term, err = parseOrderTerm(chunk, allowed)
+		if err != nil {
+			result = nil
+			err = fmt.Errorf(
+				"can't parse term %d of order criteria '%s': %w",
+				i+1, text, err,
+			)
+			return
+		}
+		result = append(result, term)
+	}
+	return
+}
+
+// parseOrderTerm parses one of the comma separated terms of an order criteria.
+func parseOrderTerm(text string, allowed []string) (result OrderTerm, err error) {
+	words := strings.Fields(text)
+	switch len(words) {
+	case 1:
+		result.Direction = DirectionAscending
+	case 2:
+		switch strings.ToLower(words[1]) {
+		case string(DirectionAscending):
+			result.Direction = DirectionAscending
+		case string(DirectionDescending):
+			result.Direction = DirectionDescending
+		default:
+			err = fmt.Errorf(
+				"direction '%s' isn't valid, it should be 'asc' or 'desc'",
+				words[1],
+			)
+			return
+		}
+	case 0:
+		err = fmt.Errorf("field name is mandatory")
+		return
+	default:
+		err = fmt.Errorf(
+			"expected field name optionally followed by direction but got '%s'",
+			strings.TrimSpace(text),
+		)
+		return
+	}
+	result.Field = words[0]
+	if !isFieldName(result.Field) {
+		err = fmt.Errorf("field name '%s' isn't valid", result.Field)
+		return
+	}
+	if len(allowed) > 0 && !containsField(allowed, result.Field) {
+		err = fmt.Errorf(
+			"field '%s' can't be used for sorting, allowed fields are '%s'",
+			result.Field, strings.Join(allowed, "', '"),
+		)
+		return
+	}
+	return
+}
+
+// isFieldName checks if the given text is a syntactically valid field name.
+func isFieldName(text string) bool {
+	for i, r := range text {
+		if i == 0 && !isIdentifierStart(r) || i > 0 && !isIdentifierPart(r) {
+			return false
+		}
+	}
+	return text != "" && !isKeyword(text)
+}
+
+// containsField checks if the given list of fields contains the given field.
+func containsField(fields []string, field string) bool {
+	for _, current := range fields {
+		if current == field {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains tests for the order criteria parser.
+
+package search
+
+import (
+	. "github.com/onsi/ginkgo/v2/dsl/core"  // nolint
+	. "github.com/onsi/ginkgo/v2/dsl/table" // nolint
+	. "github.com/onsi/gomega"              // nolint
+)
+
+var _ = Describe("Order parser", func() {
+	It("Returns empty list for empty criteria", func() {
+		result, err := ParseOrder(" ")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(BeEmpty())
+	})
+
+	It("Parses multiple terms", func() {
+		result, err := ParseOrder("name desc, created_at asc")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(Equal([]OrderTerm{
+			{
+				Field:     "name",
+				Direction: DirectionDescending,
+			},
+			{
+				Field:     "created_at",
+				Direction: DirectionAscending,
+			},
+		}))
+	})
+
+	It("Uses ascending order by default", func() {
+		result, err := ParseOrder("aws.region")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(Equal([]OrderTerm{{
+			Field:     "aws.region",
+			Direction: DirectionAscending,
+		}}))
+	})
+
+	It("Ignores case of direction", func() {
+		result, err := ParseOrder("name DESC")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(HaveLen(1))
+		Expect(result[0].Direction).To(Equal(DirectionDescending))
+	})
+
+	It("Accepts allowed fields", func() {
+		result, err := ParseOrder("name, id desc", "id", "name")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(HaveLen(2))
+	})
+
+	It("Rejects fields that aren't allowed", func() {
+		result, err := ParseOrder("name, password desc", "id", "name")
+		Expect(err).To(HaveOccurred())
+		Expect(result).To(BeNil())
+		message := err.Error()
+		Expect(message).To(ContainSubstring("term 2"))
+		Expect(message).To(ContainSubstring("'password'"))
+		Expect(message).To(ContainSubstring("'id', 'name'"))
+	})
+
+	DescribeTable(
+		"Rejects invalid criteria",
+		func(text string, substring string) {
+			result, err := ParseOrder(text)
+			Expect(err).To(HaveOccurred())
+			Expect(result).To(BeNil())
+			Expect(err.Error()).To(ContainSubstring(substring))
+		},
+		Entry("Invalid direction", "name up", "direction 'up' isn't valid"),
+		Entry("Empty term", "name,,id", "field name is mandatory"),
+		Entry("Too many words", "name asc desc", "expected field name"),
+		Entry("Invalid field name", "name; drop table", "expected field name"),
+		Entry("Invalid characters", "na-me", "field name 'na-me' isn't valid"),
+		Entry("Keyword", "and desc", "field name 'and' isn't valid"),
+	)
+})
@@ -0,0 +1,343 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the parser that converts the text of search expressions into syntax trees.
+
+package search
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Parse parses the given search expression, for example `name like 'my%' and state = 'ready'`,
+// and returns the corresponding syntax tree. If the text is empty, or contains only white space,
+// the result will be nil and no error will be returned, meaning that there is no search criteria.
+//
+// The error messages returned describe the problem found in terms of the text of the expression, so
+// they are suitable to be returned to the client in a bad request response.
+func Parse(text string) (result Node, err error) {
+	tokens, err := scan(text)
+	if err != nil {
+		err = fmt.Errorf("can't parse search expression '%s': %w", text, err)
+		return
+	}
+	if len(tokens) == 1 {
+		return
+	}
+	p := &parser{
+		tokens: tokens,
+	}
+	result, err = p.parseOr()
+	if err == nil && p.current().kind != tokenEOF {
+		err = fmt.Errorf("unexpected %s", p.current().describe())
+	}
+	if err != nil {
+		result = nil
+		err = fmt.Errorf("can't parse search expression '%s': %w", text, err)
+	}
+	return
+}
+
+// parser contains the state of the parser.
+type parser struct {
+	tokens []token
+	index  int
+}
+
+// current returns the token that is currently being processed.
+func (p *parser) current() token {
+	return p.tokens[p.index]
+}
+
+// next advances to the next token, and returns the one that was current before advancing.
+func (p *parser) next() token {
+	result := p.tokens[p.index]
+	if result.kind != tokenEOF {
+		p.index++
+	}
+	return result
+}
+
+// parseOr parses a sequence of expressions separated by the `or` connector.
+func (p *parser) parseOr() (result Node, err error) {
+	result, err = p.parseAnd()
+	if err != nil {
+		return
+	}
+	for p.current().keyword("or") {
+		p.next()
+		var right Node
+		right, err = p.parseAnd()
+		if err != nil {
+			return
+		}
+		result = &Logical{
+			Connector: ConnectorOr,
+			Left:      result,
+			Right:     right,
+		}
+	}
+	return
+}
+
+// parseAnd parses a sequence of expressions separated by the `and` connector.
+func (p *parser) parseAnd() (result Node, err error) {
+	result, err = p.parseNot()
+	if err != nil {
+		return
+	}
+	for p.current().keyword("and") {
+		p.next()
+		var right Node
+		right, err = p.parseNot()
+		if err != nil {
+			return
+		}
+		result = &Logical{
+			Connector: ConnectorAnd,
+			Left:      result,
+			Right:     right,
+		}
+	}
+	return
+}
+
+// parseNot parses an expression optionally preceded by the `not` operator.
+func (p *parser) parseNot() (result Node, err error) {
+	if p.current().keyword("not") {
+		p.next()
+		var operand Node
+		operand, err = p.parseNot()
+		if err != nil {
+			return
+		}
+		result = &Negation{
+			Operand: operand,
+		}
+		return
+	}
+	return p.parsePrimary()
+}
+
+// parsePrimary parses a condition or an expression inside parenthesis.
+func (p *parser) parsePrimary() (result Node, err error) {
+	current := p.current()
+	switch {
+	case current.kind == tokenLeftParen:
+		p.next()
+		result, err = p.parseOr()
+		if err != nil {
+			return
+		}
+		if p.current().kind != tokenRightParen {
+			err = fmt.Errorf("expected ')' but got %s", p.current().describe())
+			return
+		}
+		p.next()
+	case current.kind == tokenIdentifier && !isKeyword(current.text):
+		result, err = p.parseCondition()
+	default:
+		err = fmt.Errorf("expected field name or '(' but got %s", current.describe())
+	}
+	return
+}
+
+// parseCondition parses a condition, a field name followed by an operator and the values.
+func (p *parser) parseCondition() (result Node, err error) {
+	field := p.next().text
+	operator, err := p.parseOperator()
+	if err != nil {
+		return
+	}
+	condition := &Condition{
+		Field:    field,
+		Operator: operator,
+	}
+	switch operator {
+	case OperatorIsNull, OperatorIsNotNull:
+	case OperatorIn, OperatorNotIn:
+		condition.Values, err = p.parseList()
+	case OperatorLike, OperatorNotLike, OperatorILike, OperatorNotILike:
+		current := p.current()
+		if current.kind != tokenString {
+			err = fmt.Errorf(
+				"expected string after operator '%s' but got %s",
+				operator, current.describe(),
+			)
+			return
+		}
+		p.next()
+		condition.Values = []interface{}{current.text}
+	default:
+		var value interface{}
+		value, err = p.parseValue()
+		condition.Values = []interface{}{value}
+	}
+	if err != nil {
+		return
+	}
+	result = condition
+	return
+}
+
+// parseOperator parses the operator of a condition. Note that some operators are composed of
+// multiple words, like `not like` or `is not null`.
+func (p *parser) parseOperator() (result Operator, err error) {
+	current := p.next()
+	switch current.kind {
+	case tokenSymbol:
+		switch current.text {
+		case "=":
+			result = OperatorEqual
+		case "<>", "!=":
+			result = OperatorNotEqual
+		case "<":
+			result = OperatorLessThan
+		case "<=":
+			result = OperatorLessOrEqual
+		case ">":
+			result = OperatorGreaterThan
+		case ">=":
+			result = OperatorGreaterOrEqual
+		default:
+			err = unsupportedOperator(current)
+		}
+	case tokenIdentifier:
+		switch strings.ToLower(current.text) {
+		case "like":
+			result = OperatorLike
+		case "ilike":
+			result = OperatorILike
+		case "in":
+			result = OperatorIn
+		case "not":
+			following := p.next()
+			switch {
+			case following.keyword("like"):
+				result = OperatorNotLike
+			case following.keyword("ilike"):
+				result = OperatorNotILike
+			case following.keyword("in"):
+				result = OperatorNotIn
+			default:
+				err = fmt.Errorf(
+					"expected 'like', 'ilike' or 'in' after 'not' but got %s",
+					following.describe(),
+				)
+			}
+		case "is":
+			following := p.next()
+			if following.keyword("not") {
+				result = OperatorIsNotNull
+				following = p.next()
+			} else {
+				result = OperatorIsNull
+			}
+			if !following.keyword("null") {
+				err = fmt.Errorf("expected 'null' but got %s", following.describe())
+			}
+		default:
+			err = unsupportedOperator(current)
+		}
+	case tokenEOF:
+		err = fmt.Errorf("expected operator but got %s", current.describe())
+	default:
+		err = unsupportedOperator(current)
+	}
+	return
+}
+
+// parseList parses a list of values inside parenthesis, as used by the `in` operator.
+func (p *parser) parseList() (result []interface{}, err error) {
+	current := p.next()
+	if current.kind != tokenLeftParen {
+		err = fmt.Errorf("expected '(' but got %s", current.describe())
+		return
+	}
+	for {
+		var value interface{}
+		value, err = p.parseValue()
+		if err != nil {
+			return
+		}
+		result = append(result, value)
+		current = p.next()
+		if current.kind == tokenRightParen {
+			return
+		}
+		if current.kind != tokenComma {
+			err = fmt.Errorf("expected ',' or ')' but got %s", current.describe())
+			return
+		}
+	}
+}
+
+// parseValue parses a literal value: a string, a number or a boolean.
+func (p *parser) parseValue() (result interface{}, err error) {
+	current := p.next()
+	switch {
+	case current.kind == tokenString:
+		result = current.text
+	case current.kind == tokenNumber:
+		result, err = strconv.ParseInt(current.text, 10, 64)
+		if err != nil {
+			result, err = strconv.ParseFloat(current.text, 64)
+		}
+		if err != nil {
+			err = fmt.Errorf("number %s isn't valid", current.describe())
+		}
+	case current.keyword("true"):
+		result = true
+	case current.keyword("false"):
+		result = false
+	default:
+		err = fmt.Errorf(
+			"expected string, number or boolean value but got %s",
+			current.describe(),
+		)
+	}
+	return
+}
+
+// unsupportedOperator creates the error returned when a condition uses an operator that isn't
+// supported.
+func unsupportedOperator(current token) error {
+	return fmt.Errorf(
+		"operator %s isn't supported, supported operators are %s",
+		current.describe(), supportedOperators,
+	)
+}
+
+// isKeyword checks if the given text is one of the reserved words of the search syntax.
+func isKeyword(text string) bool {
+	switch strings.ToLower(text) {
+	case "and", "or", "not", "like", "ilike", "in", "is", "null", "true", "false":
+		return true
+	default:
+		return false
+	}
+}
+
+// supportedOperators is the list of supported operators used in error messages.
+var supportedOperators = strings.Join(
+	[]string{
+		"'='", "'<>'", "'!='", "'<'", "'<='", "'>'", "'>='", "'like'", "'not like'",
+		"'ilike'", "'not ilike'", "'in'", "'not in'", "'is null'", "'is not null'",
+	},
+	", ",
+)
@@ -0,0 +1,177 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains tests for the search expression parser.
+
+package search
+
+import (
+	. "github.com/onsi/ginkgo/v2/dsl/core"  // nolint
+	. "github.com/onsi/ginkgo/v2/dsl/table" // nolint
+	. "github.com/onsi/gomega"              // nolint
+)
+
+var _ = Describe("Parser", func() {
+	It("Returns nil for empty expression", func() {
+		result, err := Parse("  ")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(BeNil())
+	})
+
+	It("Parses simple condition", func() {
+		result, err := Parse("name = 'my'")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(Equal(&Condition{
+			Field:    "name",
+			Operator: OperatorEqual,
+			Values:   []interface{}{"my"},
+		}))
+	})
+
+	It("Parses conditions joined with and", func() {
+		result, err := Parse("name like 'my%' and state = 'ready'")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(Equal(&Logical{
+			Connector: ConnectorAnd,
+			Left: &Condition{
+				Field:    "name",
+				Operator: OperatorLike,
+				Values:   []interface{}{"my%"},
+			},
+			Right: &Condition{
+				Field:    "state",
+				Operator: OperatorEqual,
+				Values:   []interface{}{"ready"},
+			},
+		}))
+	})
+
+	It("Gives and precedence over or", func() {
+		result, err := Parse("a = 1 or b = 2 and c = 3")
+		Expect(err).ToNot(HaveOccurred())
+		logical, ok := result.(*Logical)
+		Expect(ok).To(BeTrue())
+		Expect(logical.Connector).To(Equal(ConnectorOr))
+		right, ok := logical.Right.(*Logical)
+		Expect(ok).To(BeTrue())
+		Expect(right.Connector).To(Equal(ConnectorAnd))
+	})
+
+	It("Honours parenthesis", func() {
+		result, err := Parse("(a = 1 or b = 2) and c = 3")
+		Expect(err).ToNot(HaveOccurred())
+		logical, ok := result.(*Logical)
+		Expect(ok).To(BeTrue())
+		Expect(logical.Connector).To(Equal(ConnectorAnd))
+		left, ok := logical.Left.(*Logical)
+		Expect(ok).To(BeTrue())
+		Expect(left.Connector).To(Equal(ConnectorOr))
+	})
+
+	It("Parses negation", func() {
+		result, err := Parse("not managed = true")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(Equal(&Negation{
+			Operand: &Condition{
+				Field:    "managed",
+				Operator: OperatorEqual,
+				Values:   []interface{}{true},
+			},
+		}))
+	})
+
+	It("Parses list of values", func() {
+		result, err := Parse("region.id in ('us-east-1', 'us-west-2')")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(Equal(&Condition{
+			Field:    "region.id",
+			Operator: OperatorIn,
+			Values:   []interface{}{"us-east-1", "us-west-2"},
+		}))
+	})
+
+	It("Unescapes quotes in strings", func() {
+		result, err := Parse("name = 'it''s'")
+		Expect(err).ToNot(HaveOccurred())
+		condition, ok := result.(*Condition)
+		Expect(ok).To(BeTrue())
+		Expect(condition.Values).To(ConsistOf("it's"))
+	})
+
+	It("Ignores case of keywords", func() {
+		result, err := Parse("name NOT ILIKE 'x%' AND expiration IS NOT NULL")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.String()).To(Equal(
+			"(name not ilike 'x%' and expiration is not null)",
+		))
+	})
+
+	DescribeTable(
+		"Operators",
+		func(text string, operator Operator, values ...interface{}) {
+			result, err := Parse(text)
+			Expect(err).ToNot(HaveOccurred())
+			condition, ok := result.(*Condition)
+			Expect(ok).To(BeTrue())
+			Expect(condition.Operator).To(Equal(operator))
+			if len(values) == 0 {
+				Expect(condition.Values).To(BeEmpty())
+			} else {
+				Expect(condition.Values).To(Equal(values))
+			}
+		},
+		Entry("Equal", "a = 1", OperatorEqual, int64(1)),
+		Entry("Not equal", "a <> 1", OperatorNotEqual, int64(1)),
+		Entry("Not equal alternative", "a != 1", OperatorNotEqual, int64(1)),
+		Entry("Less than", "a < -1", OperatorLessThan, int64(-1)),
+		Entry("Less or equal", "a <= 1.5", OperatorLessOrEqual, 1.5),
+		Entry("Greater than", "a > 1", OperatorGreaterThan, int64(1)),
+		Entry("Greater or equal", "a >= 1", OperatorGreaterOrEqual, int64(1)),
+		Entry("Like", "a like 'x'", OperatorLike, "x"),
+		Entry("Not like", "a not like 'x'", OperatorNotLike, "x"),
+		Entry("Case insensitive like", "a ilike 'x'", OperatorILike, "x"),
+		Entry("Not case insensitive like", "a not ilike 'x'", OperatorNotILike, "x"),
+		Entry("In", "a in (1, 2)", OperatorIn, int64(1), int64(2)),
+		Entry("Not in", "a not in (false)", OperatorNotIn, false),
+		Entry("Is null", "a is null", OperatorIsNull),
+		Entry("Is not null", "a is not null", OperatorIsNotNull),
+	)
+
+	DescribeTable(
+		"Rejects invalid expressions",
+		func(text string, substrings ...string) {
+			result, err := Parse(text)
+			Expect(err).To(HaveOccurred())
+			Expect(result).To(BeNil())
+			message := err.Error()
+			Expect(message).To(ContainSubstring(text))
+			for _, substring := range substrings {
+				Expect(message).To(ContainSubstring(substring))
+			}
+		},
+		Entry("Unsupported symbol", "a ~ 'x'", "operator '~'", "isn't supported"),
+		Entry("Unsupported word", "a between 1", "operator 'between'", "isn't supported"),
+		Entry("Missing operator", "a", "expected operator"),
+		Entry("Missing value", "a =", "expected string, number or boolean"),
+		Entry("Like with number", "a like 1", "expected string"),
+		Entry("Unterminated string", "a = 'x", "isn't terminated"),
+		Entry("Unbalanced parenthesis", "(a = 1", "expected ')'"),
+		Entry("Trailing tokens", "a = 1 b = 2", "unexpected 'b'"),
+		Entry("Missing null", "a is not 1", "expected 'null'"),
+		Entry("Keyword as field", "and = 1", "expected field name"),
+		Entry("Invalid character", "a = #", "unexpected character '#'"),
+	)
+})
@@ -0,0 +1,102 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the functions that check the fields used in search expressions and order
+// criteria.
+
+package search
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Fields returns the names of the fields referenced by the given search expression, sorted and
+// without duplicates. The result will be empty if the node is nil.
+func Fields(node Node) []string {
+	set := map[string]bool{}
+	collectFields(node, set)
+	result := make([]string, 0, len(set))
+	for field := range set {
+		result = append(result, field)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// collectFields adds to the given set the fields referenced by the given node.
+func collectFields(node Node, set map[string]bool) {
+	switch typed := node.(type) {
+	case *Condition:
+		set[typed.Field] = true
+	case *Logical:
+		collectFields(typed.Left, set)
+		collectFields(typed.Right, set)
+	case *Negation:
+		collectFields(typed.Operand, set)
+	}
+}
+
+// CheckFields checks that the given search expression only references fields that are in the given
+// list of allowed fields. The error message returned describes the first field that isn't
+// allowed, in alphabetical order. As in the ParseOrder function, an empty list means that all
+// fields are allowed.
+func CheckFields(node Node, allowed ...string) error {
+	if len(allowed) == 0 {
+		return nil
+	}
+	for _, field := range Fields(node) {
+		if !containsField(allowed, field) {
+			return fmt.Errorf(
+				"field '%s' can't be used in search expression, allowed fields are '%s'",
+				field, strings.Join(allowed, "', '"),
+			)
+		}
+	}
+	return nil
+}
+
+// Validate parses the given search expression and order criteria and checks that they only
+// reference fields that are in the given list of allowed fields. This is intended for programs
+// that forward search and order parameters received from their own users to the API, so that only
+// the fields that they decide to expose can be used. For example:
+//
+//	err := search.Validate(query.Get("search"), query.Get("order"), "name", "state")
+//	if err != nil {
+//		http.Error(w, err.Error(), http.StatusBadRequest)
+//		return
+//	}
+//
+// Empty search expressions and order criteria are accepted. Note that if the list of allowed
+// fields is empty only the syntax is checked. The error messages returned describe
+// the problem found in terms of the text received, so they are suitable to be returned to the
+// client in a bad request response.
+func Validate(search, order string, allowed ...string) error {
+	node, err := Parse(search)
+	if err != nil {
+		return err
+	}
+	err = CheckFields(node, allowed...)
+	if err != nil {
+		return fmt.Errorf("search expression '%s' isn't valid: %w", search, err)
+	}
+	_, err = ParseOrder(order, allowed...)
+	if err != nil {
+		return err
+	}
+	return nil
+}
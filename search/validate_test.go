@@ -0,0 +1,98 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains tests for the functions that check the fields of search expressions and
+// order criteria.
+
+package search
+
+import (
+	. "github.com/onsi/ginkgo/v2/dsl/core"  // nolint
+	. "github.com/onsi/ginkgo/v2/dsl/table" // nolint
+	. "github.com/onsi/gomega"              // nolint
+)
+
+var _ = Describe("Fields", func() {
+	It("Returns empty list for nil node", func() {
+		Expect(Fields(nil)).To(BeEmpty())
+	})
+
+	It("Returns sorted fields without duplicates", func() {
+		node, err := Parse(
+			"state = 'ready' and (name like 'my%' or not (name = 'your' or aws.region = 'x'))",
+		)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(Fields(node)).To(Equal([]string{
+			"aws.region",
+			"name",
+			"state",
+		}))
+	})
+})
+
+var _ = Describe("Check fields", func() {
+	It("Accepts allowed fields", func() {
+		node, err := Parse("name = 'my' and state = 'ready'")
+		Expect(err).ToNot(HaveOccurred())
+		err = CheckFields(node, "name", "state")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Rejects fields that aren't allowed", func() {
+		node, err := Parse("name = 'my' and password = 'secret'")
+		Expect(err).ToNot(HaveOccurred())
+		err = CheckFields(node, "id", "name")
+		Expect(err).To(HaveOccurred())
+		message := err.Error()
+		Expect(message).To(ContainSubstring("'password'"))
+		Expect(message).To(ContainSubstring("'id', 'name'"))
+	})
+
+	It("Accepts all fields if the list is empty", func() {
+		node, err := Parse("password = 'secret'")
+		Expect(err).ToNot(HaveOccurred())
+		err = CheckFields(node)
+		Expect(err).ToNot(HaveOccurred())
+	})
+})
+
+var _ = Describe("Validate", func() {
+	DescribeTable(
+		"Accepts valid parameters",
+		func(search, order string) {
+			err := Validate(search, order, "name", "state")
+			Expect(err).ToNot(HaveOccurred())
+		},
+		Entry("Empty", "", ""),
+		Entry("Only search", "name = 'my'", ""),
+		Entry("Only order", "", "name desc"),
+		Entry("Both", "state = 'ready'", "name desc, state"),
+	)
+
+	DescribeTable(
+		"Rejects invalid parameters",
+		func(search, order, expected string) {
+			err := Validate(search, order, "name", "state")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(expected))
+		},
+		Entry("Search syntax", "name = ", "", "can't parse search expression"),
+		Entry("Search field", "password = 'secret'", "", "'password'"),
+		Entry("Nested search field", "name = 'my' or aws.secret = 'x'", "", "'aws.secret'"),
+		Entry("Order syntax", "", "name sideways", "'sideways'"),
+		Entry("Order field", "", "password", "'password'"),
+	)
+})
@@ -25,6 +25,7 @@ import (
 	"net/http"
 	"path"
 
+	"github.com/openshift-online/ocm-sdk-go/errors"
 	"github.com/openshift-online/ocm-sdk-go/internal"
 )
 
@@ -98,15 +99,37 @@ func (c *Connection) RoundTrip(request *http.Request) (response *http.Response,
 		return
 	}
 
-	// Check that the response content type is JSON:
-	err = internal.CheckContentType(response)
-	if err != nil {
-		return
+	// Check that the response content type is JSON. Note that responses with the 304 code don't
+	// have a body, so there is nothing to check in that case:
+	if response.StatusCode != http.StatusNotModified {
+		err = internal.CheckContentType(response)
+		if err != nil {
+			err = contentTypeError(response, err)
+			return
+		}
 	}
 
 	return
 }
 
+// contentTypeError converts the error returned when the content type of the given response isn't
+// JSON into an error that preserves the status code, if the response is an error response. This
+// is needed because errors generated by proxies and load balancers are usually HTML or plain
+// text, and otherwise the caller wouldn't be able to find out the status.
+func contentTypeError(response *http.Response, cause error) error {
+	if response.StatusCode < http.StatusBadRequest {
+		return cause
+	}
+	result, err := errors.NewError().
+		Status(response.StatusCode).
+		Reason(cause.Error()).
+		Build()
+	if err != nil {
+		return cause
+	}
+	return errors.WithRateLimit(result, response.Header)
+}
+
 // selectServer selects the server that should be used for the given request, according its path and
 // the alternative URLs configured when the connection was created.
 func (c *Connection) selectServer(ctx context.Context,
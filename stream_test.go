@@ -0,0 +1,194 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains tests for the support for request bodies streamed from a reader.
+
+package sdk
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core" // nolint
+	. "github.com/onsi/gomega"             // nolint
+
+	"github.com/onsi/gomega/ghttp"
+
+	"github.com/openshift-online/ocm-sdk-go/retry"
+	. "github.com/openshift-online/ocm-sdk-go/testing" // nolint
+)
+
+var _ = Describe("Streamed request body", func() {
+	var (
+		server     *ghttp.Server
+		connection *Connection
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		// Create the tokens:
+		token := MakeTokenString("Bearer", 5*time.Minute)
+
+		// Create the server:
+		server = MakeTCPServer()
+
+		// Create the connection, with retries enabled and a short interval so that the tests
+		// that need retries don't take long:
+		connection, err = NewConnectionBuilder().
+			Logger(logger).
+			URL(server.URL()).
+			Tokens(token).
+			RetryLimit(1).
+			RetryInterval(10 * time.Millisecond).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		// Close the connection:
+		err := connection.Close()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Stop the server:
+		server.Close()
+	})
+
+	// VerifyBody creates a handler that checks that the request has the given body and content
+	// length.
+	var VerifyBody = func(body string, length int64) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.ContentLength).To(Equal(length))
+			data, err := io.ReadAll(r.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(data)).To(Equal(body))
+		}
+	}
+
+	It("Sends body with known length", func() {
+		// Prepare the server:
+		server.AppendHandlers(
+			ghttp.CombineHandlers(
+				VerifyBody(`{ "name": "my" }`, 16),
+				RespondWithJSON(http.StatusCreated, `{}`),
+			),
+		)
+
+		// Send the request:
+		response, err := connection.Post().
+			Path("/api/clusters_mgmt/v1/clusters").
+			Reader(strings.NewReader(`{ "name": "my" }`), 16).
+			Send()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.Status()).To(Equal(http.StatusCreated))
+	})
+
+	It("Sends body with unknown length using chunked encoding", func() {
+		// Prepare the server:
+		server.AppendHandlers(
+			ghttp.CombineHandlers(
+				func(w http.ResponseWriter, r *http.Request) {
+					Expect(r.TransferEncoding).To(ConsistOf("chunked"))
+				},
+				VerifyBody(`{ "name": "my" }`, -1),
+				RespondWithJSON(http.StatusCreated, `{}`),
+			),
+		)
+
+		// Send the request:
+		response, err := connection.Post().
+			Path("/api/clusters_mgmt/v1/clusters").
+			Reader(strings.NewReader(`{ "name": "my" }`), -1).
+			Send()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.Status()).To(Equal(http.StatusCreated))
+	})
+
+	It("Doesn't retry without get body function", func() {
+		// Prepare the server:
+		server.AppendHandlers(
+			ghttp.CombineHandlers(
+				VerifyBody(`{ "name": "my" }`, 16),
+				RespondWithJSON(http.StatusServiceUnavailable, `{}`),
+			),
+		)
+
+		// Send the request:
+		response, err := connection.Post().
+			Path("/api/clusters_mgmt/v1/clusters").
+			Reader(strings.NewReader(`{ "name": "my" }`), 16).
+			Send()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.Status()).To(Equal(http.StatusServiceUnavailable))
+		Expect(server.ReceivedRequests()).To(HaveLen(1))
+	})
+
+	It("Retries with get body function", func() {
+		// Prepare the server:
+		server.AppendHandlers(
+			ghttp.CombineHandlers(
+				VerifyBody(`{ "name": "my" }`, 16),
+				RespondWithJSON(http.StatusServiceUnavailable, `{}`),
+			),
+			ghttp.CombineHandlers(
+				VerifyBody(`{ "name": "my" }`, 16),
+				RespondWithJSON(http.StatusCreated, `{}`),
+			),
+		)
+
+		// Send the request:
+		response, err := connection.Post().
+			Path("/api/clusters_mgmt/v1/clusters").
+			Reader(strings.NewReader(`{ "name": "my" }`), 16).
+			GetBody(func() (io.ReadCloser, error) {
+				return io.NopCloser(strings.NewReader(`{ "name": "my" }`)), nil
+			}).
+			Send()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.Status()).To(Equal(http.StatusCreated))
+		Expect(server.ReceivedRequests()).To(HaveLen(2))
+	})
+
+	It("Retries with the policy of the context", func() {
+		// Prepare the server:
+		server.AppendHandlers(
+			ghttp.CombineHandlers(
+				VerifyBody(`{ "name": "my" }`, 16),
+				RespondWithJSON(http.StatusServiceUnavailable, `{}`),
+			),
+			ghttp.CombineHandlers(
+				VerifyBody(`{ "name": "my" }`, 16),
+				RespondWithJSON(http.StatusCreated, `{}`),
+			),
+		)
+
+		// Send the request:
+		ctx := retry.WithPolicy(context.Background(), retry.Policy{
+			Limit:    1,
+			Interval: 10 * time.Millisecond,
+		})
+		response, err := connection.Post().
+			Path("/api/clusters_mgmt/v1/clusters").
+			Reader(strings.NewReader(`{ "name": "my" }`), 16).
+			SendContext(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.Status()).To(Equal(http.StatusCreated))
+		Expect(server.ReceivedRequests()).To(HaveLen(2))
+	})
+})
@@ -0,0 +1,57 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains functions that store and extract from the context the organization that
+// originates a request, so that it can be used to tag the metrics and the logs.
+
+package tenancy
+
+import (
+	"context"
+)
+
+// UnknownOrganization is the value returned by the OrganizationFromContext function when the
+// context doesn't contain an organization.
+const UnknownOrganization = "unknown"
+
+// WithOrganization creates a new context that contains the identifier of the organization that
+// originates the requests that use it. The metrics and logging wrappers use it to tag the metrics
+// and the log messages of those requests. For example:
+//
+//	ctx = tenancy.WithOrganization(ctx, "1a2b3c")
+//	response, err := connection.ClustersMgmt().V1().Clusters().List().SendContext(ctx)
+//
+// Passing an empty identifier is equivalent to not setting the organization.
+func WithOrganization(parent context.Context, id string) context.Context {
+	return context.WithValue(parent, organizationKeyValue, id)
+}
+
+// OrganizationFromContext extracts the identifier of the organization that was stored in the
+// context with the WithOrganization function. If the context doesn't contain an organization the
+// result will be UnknownOrganization, so that the values used in metrics stay stable.
+func OrganizationFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(organizationKeyValue).(string)
+	if id == "" {
+		return UnknownOrganization
+	}
+	return id
+}
+
+// organizationKeyType is the type of the key used to store the organization in the context.
+type organizationKeyType string
+
+// organizationKeyValue is the key used to store the organization in the context:
+const organizationKeyValue organizationKeyType = "organization"
@@ -0,0 +1,46 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tenancy
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core" // nolint
+	. "github.com/onsi/gomega"             // nolint
+)
+
+var _ = Describe("Context", func() {
+	It("Returns the organization stored in the context", func() {
+		ctx := WithOrganization(context.Background(), "123")
+		Expect(OrganizationFromContext(ctx)).To(Equal("123"))
+	})
+
+	It("Returns unknown when there is no organization", func() {
+		Expect(OrganizationFromContext(context.Background())).To(Equal(UnknownOrganization))
+	})
+
+	It("Returns unknown when the organization is empty", func() {
+		ctx := WithOrganization(context.Background(), "")
+		Expect(OrganizationFromContext(ctx)).To(Equal(UnknownOrganization))
+	})
+
+	It("Replaces the organization of the parent context", func() {
+		ctx := WithOrganization(context.Background(), "123")
+		ctx = WithOrganization(ctx, "456")
+		Expect(OrganizationFromContext(ctx)).To(Equal("456"))
+	})
+})
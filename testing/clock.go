@@ -0,0 +1,78 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains a clock that can be controlled by tests.
+
+package testing
+
+import (
+	"sync"
+	"time"
+
+	"github.com/openshift-online/ocm-sdk-go/clock"
+)
+
+// FakeClock is a clock that only moves when it is explicitly advanced, or when something sleeps
+// using it. Don't create objects of this type directly, use the NewFakeClock function instead.
+type FakeClock struct {
+	lock *sync.Mutex
+	now  time.Time
+}
+
+// Make sure that we implement the interface:
+var _ clock.Clock = (*FakeClock)(nil)
+
+// NewFakeClock creates a new fake clock that starts at the given time.
+func NewFakeClock(start time.Time) *FakeClock {
+	return &FakeClock{
+		lock: &sync.Mutex{},
+		now:  start,
+	}
+}
+
+// Now is part of the implementation of the clock interface.
+func (c *FakeClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now
+}
+
+// Since is part of the implementation of the clock interface.
+func (c *FakeClock) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+// Sleep is part of the implementation of the clock interface. It doesn't block, it just advances
+// the clock the given duration.
+func (c *FakeClock) Sleep(d time.Duration) {
+	c.Advance(d)
+}
+
+// After is part of the implementation of the clock interface. Like Sleep it doesn't block, it
+// advances the clock the given duration and returns a channel that already contains the new time.
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	c.Advance(d)
+	result := make(chan time.Time, 1)
+	result <- c.Now()
+	return result
+}
+
+// Advance moves the clock forward the given duration.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.now = c.now.Add(d)
+}
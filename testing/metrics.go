@@ -19,12 +19,18 @@ limitations under the License.
 package testing
 
 import (
+	"fmt"
 	"io"
+	"math"
 	"net/http"
+	"sort"
 	"strings"
 
+	"github.com/onsi/gomega/types"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
 
 	. "github.com/onsi/gomega"       // nolint
 	. "github.com/onsi/gomega/ghttp" // nolint
@@ -84,3 +90,123 @@ func (s *MetricsServer) Close() {
 func MatchLine(regexp string, args ...interface{}) OmegaMatcher {
 	return ContainElement(MatchRegexp(regexp, args...))
 }
+
+// MatchBucket succeeds if actual is an slice of strings in the Prometheus exposition format, like
+// the one returned by the Metrics method of the metrics server, that contains a histogram with the
+// given name that recorded at least one observation in the bucket with the given upper bound. Note
+// that buckets in the exposition format are cumulative, so this checks that the count of the
+// bucket is greater than the count of the previous one, which means that there was an observation
+// greater than the previous upper bound and less or equal than this one. Use math.Inf(1) for the
+// last bucket. Only the series that have all the given labels are considered, for example:
+//
+//	Expect(metrics).To(MatchBucket("my_request_duration", 0.1, map[string]string{
+//		"method": "GET",
+//		"code":   "200",
+//	}))
+func MatchBucket(name string, le float64, labels map[string]string) types.GomegaMatcher {
+	return &bucketMatcher{
+		name:   name,
+		le:     le,
+		labels: labels,
+	}
+}
+
+type bucketMatcher struct {
+	name   string
+	le     float64
+	labels map[string]string
+	found  []string
+}
+
+func (m *bucketMatcher) Match(actual interface{}) (success bool, err error) {
+	// Parse the metrics:
+	lines, ok := actual.([]string)
+	if !ok {
+		err = fmt.Errorf("expected slice of strings but got '%T'", actual)
+		return
+	}
+	parser := expfmt.TextParser{}
+	families, err := parser.TextToMetricFamilies(strings.NewReader(
+		strings.Join(lines, "\n"),
+	))
+	if err != nil {
+		err = fmt.Errorf("can't parse metrics: %w", err)
+		return
+	}
+
+	// Find the histogram:
+	m.found = nil
+	family, ok := families[m.name]
+	if !ok || family.GetType() != dto.MetricType_HISTOGRAM {
+		return
+	}
+
+	// Check the buckets of the series that have the labels:
+	for _, metric := range family.GetMetric() {
+		if !m.matchLabels(metric) {
+			continue
+		}
+		histogram := metric.GetHistogram()
+		var previous uint64
+		var count uint64
+		var exists bool
+		for _, bucket := range histogram.GetBucket() {
+			m.found = append(m.found, fmt.Sprintf(
+				"le=\"%g\" %d", bucket.GetUpperBound(), bucket.GetCumulativeCount(),
+			))
+			if bucket.GetUpperBound() == m.le {
+				count = bucket.GetCumulativeCount()
+				exists = true
+				break
+			}
+			previous = bucket.GetCumulativeCount()
+		}
+		if !exists && math.IsInf(m.le, 1) {
+			count = histogram.GetSampleCount()
+			exists = true
+		}
+		if exists && count > previous {
+			success = true
+			return
+		}
+	}
+	return
+}
+
+func (m *bucketMatcher) matchLabels(metric *dto.Metric) bool {
+	values := map[string]string{}
+	for _, pair := range metric.GetLabel() {
+		values[pair.GetName()] = pair.GetValue()
+	}
+	for name, value := range m.labels {
+		if values[name] != value {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *bucketMatcher) FailureMessage(actual interface{}) string {
+	return fmt.Sprintf(
+		"Expected histogram '%s' with labels %s to have an observation in the bucket with "+
+			"upper bound %g, but the buckets found are\n\t%s\n",
+		m.name, m.describeLabels(), m.le, strings.Join(m.found, "\n\t"),
+	)
+}
+
+func (m *bucketMatcher) NegatedFailureMessage(actual interface{}) string {
+	return fmt.Sprintf(
+		"Expected histogram '%s' with labels %s to not have an observation in the bucket "+
+			"with upper bound %g",
+		m.name, m.describeLabels(), m.le,
+	)
+}
+
+func (m *bucketMatcher) describeLabels() string {
+	pairs := make([]string, 0, len(m.labels))
+	for name, value := range m.labels {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", name, value))
+	}
+	sort.Strings(pairs)
+	return "{" + strings.Join(pairs, ",") + "}"
+}
@@ -0,0 +1,154 @@
+/*
+Copyright (c) 2018 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains tests for the TLS version and cipher suite options.
+
+package sdk
+
+import (
+	"crypto/tls"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/onsi/gomega/ghttp"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"             // nolint
+	. "github.com/onsi/gomega"                         // nolint
+	. "github.com/openshift-online/ocm-sdk-go/testing" // nolint
+)
+
+var _ = Describe("TLS", func() {
+	var (
+		accessToken string
+		apiServer   *ghttp.Server
+		apiCA       string
+	)
+
+	BeforeEach(func() {
+		// Create the token:
+		accessToken = MakeTokenString("Bearer", 5*time.Minute)
+
+		// Create the API server:
+		apiServer, apiCA = MakeTCPTLSServer()
+	})
+
+	AfterEach(func() {
+		// Stop the server:
+		apiServer.Close()
+
+		// Remove the temporary CA file:
+		err := os.Remove(apiCA)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Uses the Go defaults when not set", func() {
+		connection, err := NewConnectionBuilder().
+			Logger(logger).
+			Tokens(accessToken).
+			URL(apiServer.URL()).
+			TrustedCAFile(apiCA).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err = connection.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+		Expect(connection.MinTLSVersion()).To(BeZero())
+		Expect(connection.CipherSuites()).To(BeNil())
+	})
+
+	It("Honours the minimum TLS version", func() {
+		// Configure the server so that it checks the TLS version:
+		apiServer.AppendHandlers(
+			ghttp.CombineHandlers(
+				func(w http.ResponseWriter, r *http.Request) {
+					Expect(r.TLS).ToNot(BeNil())
+					Expect(r.TLS.Version).To(BeNumerically("==", tls.VersionTLS13))
+				},
+				RespondWithJSON(http.StatusOK, "{}"),
+			),
+		)
+
+		// Create the connection:
+		connection, err := NewConnectionBuilder().
+			Logger(logger).
+			Tokens(accessToken).
+			URL(apiServer.URL()).
+			TrustedCAFile(apiCA).
+			MinTLSVersion(tls.VersionTLS13).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err = connection.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+		Expect(connection.MinTLSVersion()).To(BeNumerically("==", tls.VersionTLS13))
+
+		// Send the request:
+		_, err = connection.Get().
+			Path("/api/clusters_mgmt").
+			Send()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Returns the configured cipher suites", func() {
+		connection, err := NewConnectionBuilder().
+			Logger(logger).
+			Tokens(accessToken).
+			URL(apiServer.URL()).
+			TrustedCAFile(apiCA).
+			MinTLSVersion(tls.VersionTLS12).
+			CipherSuites(tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384).
+			CipherSuites(tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err = connection.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+		Expect(connection.CipherSuites()).To(Equal([]uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+		}))
+	})
+
+	It("Can't be created with minimum TLS version less than 1.2", func() {
+		connection, err := NewConnectionBuilder().
+			Logger(logger).
+			Tokens(accessToken).
+			URL(apiServer.URL()).
+			TrustedCAFile(apiCA).
+			MinTLSVersion(tls.VersionTLS10).
+			Build()
+		Expect(err).To(HaveOccurred())
+		Expect(connection).To(BeNil())
+		Expect(err.Error()).To(ContainSubstring("TLS 1.2"))
+	})
+
+	It("Can't be created with insecure cipher suites", func() {
+		connection, err := NewConnectionBuilder().
+			Logger(logger).
+			Tokens(accessToken).
+			URL(apiServer.URL()).
+			TrustedCAFile(apiCA).
+			CipherSuites(tls.TLS_RSA_WITH_3DES_EDE_CBC_SHA).
+			Build()
+		Expect(err).To(HaveOccurred())
+		Expect(connection).To(BeNil())
+		Expect(err.Error()).To(ContainSubstring("insecure"))
+	})
+})
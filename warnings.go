@@ -0,0 +1,215 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains the implementation of the transport wrapper that extracts warnings from the
+// `Warning` and `Deprecation` headers of responses.
+
+package sdk
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/openshift-online/ocm-sdk-go/logging"
+)
+
+// Warning contains the details of a warning sent by the server in the `Warning` or `Deprecation`
+// header of a response.
+type Warning struct {
+	// Method is the HTTP method of the request.
+	Method string
+
+	// URL is the URL of the request.
+	URL string
+
+	// Header is the name of the header that contained the warning, either `Warning` or
+	// `Deprecation`.
+	Header string
+
+	// Code is the warning code, for example 299 for a miscellaneous persistent warning. It is
+	// zero for the `Deprecation` header.
+	Code int
+
+	// Agent is the name of the agent that added the warning, or `-` if it is unknown. It is
+	// empty for the `Deprecation` header.
+	Agent string
+
+	// Text is the text of the warning, or the value of the `Deprecation` header.
+	Text string
+}
+
+// WarningHandler is a function that will be called for each warning received by the connection.
+type WarningHandler func(ctx context.Context, warning Warning)
+
+// warningTransportWrapper is a transport wrapper that creates round trippers that extract the
+// warnings from the responses and pass them to a handler.
+type warningTransportWrapper struct {
+	handler WarningHandler
+}
+
+// warningRoundTripper is a round tripper that extracts the warnings from the responses and passes
+// them to a handler.
+type warningRoundTripper struct {
+	handler WarningHandler
+	next    http.RoundTripper
+}
+
+// Make sure that we implement the http.RoundTripper interface:
+var _ http.RoundTripper = &warningRoundTripper{}
+
+// Wrap creates a round tripper on top of the given one that extracts the warnings from the
+// responses.
+func (w *warningTransportWrapper) Wrap(transport http.RoundTripper) http.RoundTripper {
+	return &warningRoundTripper{
+		handler: w.handler,
+		next:    transport,
+	}
+}
+
+// RoundTrip is he implementation of the http.RoundTripper interface.
+func (t *warningRoundTripper) RoundTrip(request *http.Request) (response *http.Response,
+	err error) {
+	response, err = t.next.RoundTrip(request)
+	if err != nil || response == nil {
+		return
+	}
+	ctx := request.Context()
+	method := request.Method
+	url := request.URL.String()
+	for _, value := range response.Header.Values("Warning") {
+		for _, warning := range parseWarnings(value) {
+			warning.Method = method
+			warning.URL = url
+			t.handler(ctx, warning)
+		}
+	}
+	for _, value := range response.Header.Values("Deprecation") {
+		t.handler(ctx, Warning{
+			Method: method,
+			URL:    url,
+			Header: "Deprecation",
+			Text:   value,
+		})
+	}
+	return
+}
+
+// logWarningHandler returns a warning handler that writes the warnings to the given logger.
+func logWarningHandler(logger logging.Logger) WarningHandler {
+	return func(ctx context.Context, warning Warning) {
+		switch warning.Header {
+		case "Deprecation":
+			logger.Warn(
+				ctx,
+				"Server says that request for method %s and URL '%s' is deprecated "+
+					"since '%s'",
+				warning.Method, warning.URL, warning.Text,
+			)
+		default:
+			logger.Warn(
+				ctx,
+				"Server sent warning for method %s and URL '%s': %s",
+				warning.Method, warning.URL, warning.Text,
+			)
+		}
+	}
+}
+
+// parseWarnings parses the value of a `Warning` header, as described in section 5.5 of RFC 7234.
+// The value can contain multiple comma separated warnings, each with the following format:
+//
+//	warn-code SP warn-agent SP warn-text [ SP warn-date ]
+//
+// Values that can't be parsed are returned as a single warning containing the complete value as
+// text, so that they aren't lost.
+func parseWarnings(value string) (result []Warning) {
+	rest := strings.TrimSpace(value)
+	for rest != "" {
+		warning, next, ok := parseWarning(rest)
+		if !ok {
+			result = append(result, Warning{
+				Header: "Warning",
+				Text:   rest,
+			})
+			return
+		}
+		result = append(result, warning)
+		rest = strings.TrimSpace(next)
+		rest = strings.TrimPrefix(rest, ",")
+		rest = strings.TrimSpace(rest)
+	}
+	return
+}
+
+// parseWarning parses one warning from the beginning of the given text and returns the rest of
+// the text.
+func parseWarning(text string) (warning Warning, rest string, ok bool) {
+	// Code:
+	fields := strings.SplitN(text, " ", 3)
+	if len(fields) != 3 {
+		return
+	}
+	code, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return
+	}
+
+	// Text, which is a quoted string where double quotes and backslashes are escaped with
+	// backslashes:
+	quoted := fields[2]
+	if !strings.HasPrefix(quoted, `"`) {
+		return
+	}
+	var buffer strings.Builder
+	i := 1
+	for ; i < len(quoted); i++ {
+		c := quoted[i]
+		if c == '\\' && i+1 < len(quoted) {
+			i++
+			buffer.WriteByte(quoted[i])
+			continue
+		}
+		if c == '"' {
+			break
+		}
+		buffer.WriteByte(c)
+	}
+	if i >= len(quoted) {
+		return
+	}
+	rest = quoted[i+1:]
+
+	// Skip the optional date, which is also a quoted string:
+	trimmed := strings.TrimSpace(rest)
+	if strings.HasPrefix(trimmed, `"`) {
+		end := strings.Index(trimmed[1:], `"`)
+		if end < 0 {
+			return
+		}
+		rest = trimmed[end+2:]
+	}
+
+	warning = Warning{
+		Header: "Warning",
+		Code:   code,
+		Agent:  fields[1],
+		Text:   buffer.String(),
+	}
+	ok = true
+	return
+}
@@ -0,0 +1,203 @@
+/*
+Copyright (c) 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains tests for the extraction of warnings from responses.
+
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/onsi/gomega/ghttp"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"  // nolint
+	. "github.com/onsi/ginkgo/v2/dsl/table" // nolint
+	. "github.com/onsi/gomega"              // nolint
+
+	"github.com/openshift-online/ocm-sdk-go/logging"
+	. "github.com/openshift-online/ocm-sdk-go/testing" // nolint
+)
+
+var _ = DescribeTable(
+	"Warning header parsing",
+	func(value string, expected []Warning) {
+		Expect(parseWarnings(value)).To(Equal(expected))
+	},
+	Entry(
+		"Empty",
+		"",
+		nil,
+	),
+	Entry(
+		"Simple",
+		`299 - "Deprecated field"`,
+		[]Warning{{
+			Header: "Warning",
+			Code:   299,
+			Agent:  "-",
+			Text:   "Deprecated field",
+		}},
+	),
+	Entry(
+		"With date",
+		`299 api.openshift.com "Deprecated" "Sat, 25 Aug 2012 23:34:45 GMT"`,
+		[]Warning{{
+			Header: "Warning",
+			Code:   299,
+			Agent:  "api.openshift.com",
+			Text:   "Deprecated",
+		}},
+	),
+	Entry(
+		"Escaped quotes",
+		`299 - "Use \"name\" instead"`,
+		[]Warning{{
+			Header: "Warning",
+			Code:   299,
+			Agent:  "-",
+			Text:   `Use "name" instead`,
+		}},
+	),
+	Entry(
+		"Multiple",
+		`299 - "First, with comma", 199 - "Second"`,
+		[]Warning{
+			{
+				Header: "Warning",
+				Code:   299,
+				Agent:  "-",
+				Text:   "First, with comma",
+			},
+			{
+				Header: "Warning",
+				Code:   199,
+				Agent:  "-",
+				Text:   "Second",
+			},
+		},
+	),
+	Entry(
+		"Invalid",
+		`Something is deprecated`,
+		[]Warning{{
+			Header: "Warning",
+			Text:   "Something is deprecated",
+		}},
+	),
+)
+
+var _ = Describe("Warnings", func() {
+	var apiServer *ghttp.Server
+
+	BeforeEach(func() {
+		apiServer = MakeTCPServer()
+		apiServer.AppendHandlers(
+			ghttp.CombineHandlers(
+				ghttp.RespondWith(
+					http.StatusOK,
+					"{}",
+					http.Header{
+						"Content-Type": []string{"application/json"},
+						"Warning":      []string{`299 - "Field 'foo' is deprecated"`},
+						"Deprecation":  []string{"@1688169599"},
+					},
+				),
+			),
+		)
+	})
+
+	AfterEach(func() {
+		apiServer.Close()
+	})
+
+	It("Calls the handler", func() {
+		// Create the connection:
+		var warnings []Warning
+		connection, err := NewConnectionBuilder().
+			Logger(logger).
+			URL(apiServer.URL()).
+			Tokens(MakeTokenString("Bearer", 5*time.Minute)).
+			WarningHandler(func(ctx context.Context, warning Warning) {
+				warnings = append(warnings, warning)
+			}).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err = connection.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+
+		// Send the request:
+		_, err = connection.Get().
+			Path("/api/clusters_mgmt/v1/clusters").
+			Send()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Verify the warnings:
+		url := apiServer.URL() + "/api/clusters_mgmt/v1/clusters"
+		Expect(warnings).To(ConsistOf(
+			Warning{
+				Method: http.MethodGet,
+				URL:    url,
+				Header: "Warning",
+				Code:   299,
+				Agent:  "-",
+				Text:   "Field 'foo' is deprecated",
+			},
+			Warning{
+				Method: http.MethodGet,
+				URL:    url,
+				Header: "Deprecation",
+				Text:   "@1688169599",
+			},
+		))
+	})
+
+	It("Writes warnings to the log by default", func() {
+		// Create a logger that writes to a buffer:
+		buffer := &bytes.Buffer{}
+		bufferLogger, err := logging.NewStdLoggerBuilder().
+			Streams(buffer, buffer).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Create the connection:
+		connection, err := NewConnectionBuilder().
+			Logger(bufferLogger).
+			URL(apiServer.URL()).
+			Tokens(MakeTokenString("Bearer", 5*time.Minute)).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err = connection.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}()
+
+		// Send the request:
+		_, err = connection.Get().
+			Path("/api/clusters_mgmt/v1/clusters").
+			Send()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Verify the log:
+		text := buffer.String()
+		Expect(text).To(ContainSubstring("Field 'foo' is deprecated"))
+		Expect(text).To(ContainSubstring("deprecated since '@1688169599'"))
+	})
+})